@@ -0,0 +1,89 @@
+package admin
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+
+	"github.com/DrmagicE/gmqtt/plugin/auth"
+)
+
+var accountPage pageFlags
+
+// Account is the command for managing the accounts of the auth plugin.
+var Account = &cobra.Command{
+	Use:   "account",
+	Short: "List, create, update and delete the accounts of the auth plugin via the admin gRPC API",
+}
+
+var accountList = &cobra.Command{
+	Use:     "list",
+	Short:   "List the accounts",
+	Example: "gmqctl account list -o json",
+	Args:    cobra.NoArgs,
+}
+
+var accountSet = &cobra.Command{
+	Use:     "set <username> <password>",
+	Short:   "Create the account, or update the password if the account exists",
+	Example: "gmqctl account set user1 password1",
+	Args:    cobra.ExactArgs(2),
+}
+
+var accountRm = &cobra.Command{
+	Use:     "rm <username>",
+	Short:   "Delete the account",
+	Example: "gmqctl account rm user1",
+	Args:    cobra.ExactArgs(1),
+}
+
+func init() {
+	api.register(Account.PersistentFlags())
+	accountList.Run = run(func(ctx context.Context, cc grpc.ClientConnInterface, args []string) (*result, error) {
+		return listAccounts(ctx, cc, accountPage)
+	})
+	accountPage.register(accountList)
+	accountSet.Run = run(func(ctx context.Context, cc grpc.ClientConnInterface, args []string) (*result, error) {
+		return nil, setAccount(ctx, cc, args[0], args[1])
+	})
+	accountRm.Run = run(func(ctx context.Context, cc grpc.ClientConnInterface, args []string) (*result, error) {
+		return nil, removeAccount(ctx, cc, args[0])
+	})
+	Account.AddCommand(accountList, accountSet, accountRm)
+}
+
+func listAccounts(ctx context.Context, cc grpc.ClientConnInterface, f pageFlags) (*result, error) {
+	resp, err := auth.NewAccountServiceClient(cc).List(ctx, &auth.ListAccountsRequest{
+		PageSize: f.pageSize,
+		Page:     f.page,
+	})
+	if err != nil {
+		return nil, err
+	}
+	rs := &result{
+		msg:    resp,
+		header: []string{"USERNAME", "PASSWORD", "SCRAM"},
+		footer: totalFooter(len(resp.Accounts), resp.TotalCount),
+	}
+	for _, v := range resp.Accounts {
+		rs.rows = append(rs.rows, []string{v.Username, v.Password, strconv.FormatBool(len(v.Scram) != 0)})
+	}
+	return rs, nil
+}
+
+func setAccount(ctx context.Context, cc grpc.ClientConnInterface, username, password string) error {
+	_, err := auth.NewAccountServiceClient(cc).Update(ctx, &auth.UpdateAccountRequest{
+		Username: username,
+		Password: password,
+	})
+	return err
+}
+
+func removeAccount(ctx context.Context, cc grpc.ClientConnInterface, username string) error {
+	_, err := auth.NewAccountServiceClient(cc).Delete(ctx, &auth.DeleteAccountRequest{
+		Username: username,
+	})
+	return err
+}
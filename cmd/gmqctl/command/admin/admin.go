@@ -0,0 +1,124 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// apiFlags are the flags of calling the admin gRPC API, shared by all admin commands.
+type apiFlags struct {
+	addr    string
+	timeout time.Duration
+	output  string
+}
+
+var api apiFlags
+
+func (f *apiFlags) register(fs *pflag.FlagSet) {
+	fs.StringVarP(&f.addr, "addr", "a", "127.0.0.1:8084", "The gRPC address of the admin plugin.")
+	fs.DurationVar(&f.timeout, "timeout", 5*time.Second, "The timeout of the request.")
+	fs.StringVarP(&f.output, "output", "o", outputTable, "The output format, table or json.")
+}
+
+// result is the response of an admin command.
+// An empty result prints nothing.
+type result struct {
+	msg    proto.Message
+	header []string
+	rows   [][]string
+	// footer is printed after the table, such as the total count.
+	footer string
+}
+
+func (r *result) print(out io.Writer, format string) error {
+	if r == nil || r.msg == nil {
+		return nil
+	}
+	switch format {
+	case outputJSON:
+		b, err := protojson.MarshalOptions{
+			Multiline:       true,
+			UseProtoNames:   true,
+			EmitUnpopulated: true,
+		}.Marshal(r.msg)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(b))
+		return err
+	case outputTable:
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, strings.Join(r.header, "\t"))
+		for _, v := range r.rows {
+			fmt.Fprintln(w, strings.Join(v, "\t"))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if r.footer != "" {
+			fmt.Fprintln(out, r.footer)
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid output format: %s, must be table or json", format)
+	}
+}
+
+// call dials the admin gRPC API, calls fn and prints the result to out.
+func (f *apiFlags) call(out io.Writer, fn func(ctx context.Context, cc grpc.ClientConnInterface) (*result, error)) error {
+	if f.output != outputTable && f.output != outputJSON {
+		return fmt.Errorf("invalid output format: %s, must be table or json", f.output)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), f.timeout)
+	defer cancel()
+	cc, err := grpc.DialContext(ctx, f.addr, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("failed to connect to %s: %w", f.addr, err)
+		}
+		return err
+	}
+	defer cc.Close()
+	rs, err := fn(ctx, cc)
+	if err != nil {
+		if s, ok := status.FromError(err); ok {
+			return fmt.Errorf("%s: %s", s.Code(), s.Message())
+		}
+		return err
+	}
+	return rs.print(out, f.output)
+}
+
+// run returns the cobra Run function which calls fn with the command arguments.
+func run(fn func(ctx context.Context, cc grpc.ClientConnInterface, args []string) (*result, error)) func(cmd *cobra.Command, args []string) {
+	return func(cmd *cobra.Command, args []string) {
+		must(api.call(os.Stdout, func(ctx context.Context, cc grpc.ClientConnInterface) (*result, error) {
+			return fn(ctx, cc, args)
+		}))
+	}
+}
+
+func must(err error) {
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,304 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/config"
+	_ "github.com/DrmagicE/gmqtt/persistence"
+	"github.com/DrmagicE/gmqtt/pkg/client"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/plugin/admin"
+	"github.com/DrmagicE/gmqtt/plugin/auth"
+	"github.com/DrmagicE/gmqtt/server"
+	_ "github.com/DrmagicE/gmqtt/topicalias/fifo"
+)
+
+// runServer runs the broker with the admin and auth plugins,
+// and returns the MQTT address and the gRPC address.
+func runServer(t *testing.T) (string, string) {
+	dir, err := ioutil.TempDir("", "gmqctl_admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	// get a free port for the gRPC server.
+	grpcLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcAddr := grpcLn.Addr().String()
+	grpcLn.Close()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	adminCfg := admin.DefaultConfig
+	adminCfg.HTTP.Enable = false
+	adminCfg.GRPC.Addr = grpcAddr
+	cfg.Plugins[admin.Name] = &adminCfg
+	authCfg := auth.DefaultConfig
+	authCfg.PasswordFile = path.Join(dir, "gmqtt_password.yml")
+	authCfg.Hash = auth.Plain
+	cfg.Plugins[auth.Name] = &authCfg
+	adm, err := admin.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	au, err := auth.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := server.New(
+		server.WithConfig(cfg),
+		server.WithTCPListener(ln),
+		server.WithPlugin(au, adm),
+	)
+	if err := srv.Run(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = srv.Stop(context.Background())
+	})
+	return ln.Addr().String(), grpcAddr
+}
+
+func dial(t *testing.T, addr string) *grpc.ClientConn {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	cc, err := grpc.DialContext(ctx, addr, grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cc.Close()
+	})
+	return cc
+}
+
+// connect connects to the broker with a new account created by the auth plugin.
+func connect(t *testing.T, addr string, cc grpc.ClientConnInterface, opts ...client.Options) *client.Client {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := setAccount(ctx, cc, "user", "password"); err != nil {
+		t.Fatal(err)
+	}
+	c := client.New(append([]client.Options{
+		client.WithAddress(addr),
+		client.WithUsernamePassword("user", []byte("password")),
+	}, opts...)...)
+	if _, err := c.Connect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = c.Disconnect()
+	})
+	return c
+}
+
+func TestResult_print(t *testing.T) {
+	a := assert.New(t)
+	rs := &result{
+		msg:    &admin.GetClientRequest{ClientId: "c1"},
+		header: []string{"A", "LONG HEADER"},
+		rows:   [][]string{{"long value", "1"}},
+		footer: "footer",
+	}
+	out := &bytes.Buffer{}
+	a.Nil(rs.print(out, outputTable))
+	a.Equal("A           LONG HEADER\nlong value  1\nfooter\n", out.String())
+
+	out.Reset()
+	a.Nil(rs.print(out, outputJSON))
+	var m map[string]interface{}
+	a.Nil(json.Unmarshal(out.Bytes(), &m))
+	a.Equal(map[string]interface{}{"client_id": "c1"}, m)
+
+	a.NotNil(rs.print(out, "yaml"))
+
+	out.Reset()
+	var empty *result
+	a.Nil(empty.print(out, outputTable))
+	a.Zero(out.Len())
+}
+
+func TestFilterFlags_filterRequest(t *testing.T) {
+	a := assert.New(t)
+	req, err := filterFlags{
+		clientID: "c1",
+		types:    []string{"sys", "non-shared"},
+		match:    "name",
+		topic:    "a/b",
+		limit:    10,
+	}.filterRequest()
+	a.Nil(err)
+	a.Equal("c1", req.ClientId)
+	a.Equal("1,3", req.FilterType)
+	a.Equal(admin.SubMatchType_SUB_MATCH_TYPE_MATCH_NAME, req.MatchType)
+	a.Equal("a/b", req.TopicName)
+	a.EqualValues(10, req.Limit)
+
+	req, err = filterFlags{match: "filter"}.filterRequest()
+	a.Nil(err)
+	a.Equal(admin.SubMatchType_SUB_MATCH_TYPE_MATCH_UNSPECIFIED, req.MatchType)
+
+	_, err = filterFlags{types: []string{"unknown"}}.filterRequest()
+	a.NotNil(err)
+	_, err = filterFlags{topic: "a", match: "unknown"}.filterRequest()
+	a.NotNil(err)
+}
+
+func TestAPIFlags_call(t *testing.T) {
+	a := assert.New(t)
+	_, grpcAddr := runServer(t)
+	f := &apiFlags{addr: grpcAddr, timeout: 5 * time.Second, output: outputTable}
+	out := &bytes.Buffer{}
+	err := f.call(out, func(ctx context.Context, cc grpc.ClientConnInterface) (*result, error) {
+		return getClient(ctx, cc, "not-exist")
+	})
+	a.EqualError(err, "NotFound: not found")
+
+	f.output = "yaml"
+	a.NotNil(f.call(out, nil))
+}
+
+func TestClient(t *testing.T) {
+	a := assert.New(t)
+	addr, grpcAddr := runServer(t)
+	cc := dial(t, grpcAddr)
+	ctx := context.Background()
+	connect(t, addr, cc, client.WithClientID("c1"))
+
+	rs, err := listClients(ctx, cc, pageFlags{page: 1, pageSize: 20})
+	a.Nil(err)
+	a.Len(rs.rows, 1)
+	a.Equal("c1", rs.rows[0][0])
+	a.Equal("user", rs.rows[0][1])
+	a.Equal("showing 1 of 1", rs.footer)
+
+	rs, err = getClient(ctx, cc, "c1")
+	a.Nil(err)
+	a.Equal([]string{"client_id", "c1"}, rs.rows[0])
+
+	a.Nil(kickClient(ctx, cc, "c1", true))
+	a.Eventually(func() bool {
+		rs, err = listClients(ctx, cc, pageFlags{page: 1, pageSize: 20})
+		return err == nil && len(rs.rows) == 0
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestSubscriptionAndPublish(t *testing.T) {
+	a := assert.New(t)
+	addr, grpcAddr := runServer(t)
+	cc := dial(t, grpcAddr)
+	ctx := context.Background()
+	msgs := make(chan *gmqtt.Message, 1)
+	c := connect(t, addr, cc, client.WithClientID("c1"), client.WithMessageHandler(func(c *client.Client, msg *gmqtt.Message) {
+		msgs <- msg
+	}))
+
+	rs, err := addSubscriptions(ctx, cc, "c1", subscribeFlags{topics: []string{"a/+", "b"}, qos: 1})
+	a.Nil(err)
+	a.Equal([][]string{{"a/+", "true"}, {"b", "true"}}, rs.rows)
+
+	// the list API only returns the subscriptions made by the clients.
+	_, err = c.Subscribe(ctx, packets.Topic{Name: "c", SubOptions: packets.SubOptions{Qos: 2}})
+	a.Nil(err)
+	rs, err = listSubscriptions(ctx, cc, pageFlags{page: 1, pageSize: 20})
+	a.Nil(err)
+	a.Equal([][]string{{"c1", "c", "2", "0", "false", "false", "0"}}, rs.rows)
+	a.Equal("showing 1 of 1", rs.footer)
+
+	rs, err = filterSubscriptions(ctx, cc, filterFlags{clientID: "c1", topic: "a/b", match: "filter", limit: 10})
+	a.Nil(err)
+	a.Equal([][]string{{"c1", "a/+", "1", "0", "false", "false", "0"}}, rs.rows)
+
+	a.Nil(publishMessage(ctx, cc, publishFlags{
+		topic:          "a/b",
+		message:        "hello",
+		qos:            1,
+		userProperties: []string{"k=v"},
+	}))
+	select {
+	case msg := <-msgs:
+		a.Equal("a/b", msg.Topic)
+		a.Equal([]byte("hello"), msg.Payload)
+		a.Equal([]packets.UserProperty{{K: []byte("k"), V: []byte("v")}}, msg.UserProperties)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+	a.NotNil(publishMessage(ctx, cc, publishFlags{topic: "a/b", userProperties: []string{"k"}}))
+
+	a.Nil(removeSubscriptions(ctx, cc, "c1", []string{"a/+"}))
+	rs, err = filterSubscriptions(ctx, cc, filterFlags{clientID: "c1", topic: "b", match: "name", limit: 10})
+	a.Nil(err)
+	a.Len(rs.rows, 1)
+	rs, err = filterSubscriptions(ctx, cc, filterFlags{clientID: "c1", topic: "a/+", match: "name", limit: 10})
+	a.Nil(err)
+	a.Len(rs.rows, 0)
+}
+
+func TestDelayed(t *testing.T) {
+	a := assert.New(t)
+	addr, grpcAddr := runServer(t)
+	cc := dial(t, grpcAddr)
+	ctx := context.Background()
+	c := connect(t, addr, cc, client.WithClientID("c1"))
+
+	for _, v := range []string{"$delayed/3600/a", "$delayed/60/b"} {
+		a.Nil(c.Publish(ctx, &gmqtt.Message{Topic: v, QoS: 1, Payload: []byte("hello")}))
+	}
+	rs, err := listDelayed(ctx, cc, pageFlags{page: 1, pageSize: 20})
+	a.Nil(err)
+	a.Len(rs.rows, 2)
+	a.Equal("showing 2 of 2", rs.footer)
+	// in the order of the delivery time.
+	a.Equal([]string{"c1", "b", "1", "false"}, rs.rows[0][1:5])
+	a.Equal([]string{"c1", "a", "1", "false"}, rs.rows[1][1:5])
+	id := rs.rows[0][0]
+
+	rs, err = getDelayed(ctx, cc, id)
+	a.Nil(err)
+	a.Contains(rs.rows, []string{"payload", "hello"})
+
+	a.Nil(cancelDelayed(ctx, cc, id))
+	a.NotNil(cancelDelayed(ctx, cc, id))
+	rs, err = listDelayed(ctx, cc, pageFlags{page: 1, pageSize: 20})
+	a.Nil(err)
+	a.Len(rs.rows, 1)
+	a.Equal("a", rs.rows[0][2])
+}
+
+func TestAccount(t *testing.T) {
+	a := assert.New(t)
+	_, grpcAddr := runServer(t)
+	cc := dial(t, grpcAddr)
+	ctx := context.Background()
+
+	a.Nil(setAccount(ctx, cc, "u1", "p1"))
+	a.Nil(setAccount(ctx, cc, "u2", "p2"))
+	rs, err := listAccounts(ctx, cc, pageFlags{page: 1, pageSize: 20})
+	a.Nil(err)
+	a.Equal([][]string{{"u1", "p1", "false"}, {"u2", "p2", "false"}}, rs.rows)
+	a.Equal("showing 2 of 2", rs.footer)
+
+	a.Nil(removeAccount(ctx, cc, "u1"))
+	rs, err = listAccounts(ctx, cc, pageFlags{page: 1, pageSize: 20})
+	a.Nil(err)
+	a.Equal([][]string{{"u2", "p2", "false"}}, rs.rows)
+}
@@ -0,0 +1,159 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+
+	"github.com/DrmagicE/gmqtt/plugin/admin"
+)
+
+// pageFlags are the paging flags of the list commands.
+type pageFlags struct {
+	page     uint32
+	pageSize uint32
+}
+
+func (f *pageFlags) register(cmd *cobra.Command) {
+	cmd.Flags().Uint32Var(&f.page, "page", 1, "The page number.")
+	cmd.Flags().Uint32Var(&f.pageSize, "page-size", 20, "The page size.")
+}
+
+func totalFooter(n int, total uint32) string {
+	return fmt.Sprintf("showing %d of %d", n, total)
+}
+
+var (
+	clientPage   pageFlags
+	cleanSession bool
+)
+
+// Client is the command for managing the clients.
+var Client = &cobra.Command{
+	Use:   "client",
+	Short: "List, inspect and kick the clients via the admin gRPC API",
+}
+
+var clientList = &cobra.Command{
+	Use:     "list",
+	Short:   "List the clients, including the disconnected clients whose session is not expired",
+	Example: "gmqctl client list --page 2 --page-size 50 -o json",
+	Args:    cobra.NoArgs,
+}
+
+var clientGet = &cobra.Command{
+	Use:     "get <client_id>",
+	Short:   "Show the details of the client",
+	Example: "gmqctl client get client1",
+	Args:    cobra.ExactArgs(1),
+}
+
+var clientKick = &cobra.Command{
+	Use:     "kick <client_id>",
+	Short:   "Disconnect the client",
+	Example: "gmqctl client kick client1 --clean-session",
+	Args:    cobra.ExactArgs(1),
+}
+
+func init() {
+	api.register(Client.PersistentFlags())
+	clientList.Run = run(func(ctx context.Context, cc grpc.ClientConnInterface, args []string) (*result, error) {
+		return listClients(ctx, cc, clientPage)
+	})
+	clientPage.register(clientList)
+	clientGet.Run = run(func(ctx context.Context, cc grpc.ClientConnInterface, args []string) (*result, error) {
+		return getClient(ctx, cc, args[0])
+	})
+	clientKick.Run = run(func(ctx context.Context, cc grpc.ClientConnInterface, args []string) (*result, error) {
+		return nil, kickClient(ctx, cc, args[0], cleanSession)
+	})
+	clientKick.Flags().BoolVar(&cleanSession, "clean-session", false, "Also remove the session of the client.")
+	Client.AddCommand(clientList, clientGet, clientKick)
+}
+
+func formatTime(t *timestamp.Timestamp) string {
+	if t == nil {
+		return "-"
+	}
+	return t.AsTime().Local().Format(time.RFC3339)
+}
+
+func formatUint(i uint64) string {
+	return strconv.FormatUint(i, 10)
+}
+
+func listClients(ctx context.Context, cc grpc.ClientConnInterface, f pageFlags) (*result, error) {
+	resp, err := admin.NewClientServiceClient(cc).List(ctx, &admin.ListClientRequest{
+		PageSize: f.pageSize,
+		Page:     f.page,
+	})
+	if err != nil {
+		return nil, err
+	}
+	rs := &result{
+		msg:    resp,
+		header: []string{"CLIENT ID", "USERNAME", "VERSION", "REMOTE ADDR", "CONNECTED AT", "DISCONNECTED AT", "SUBSCRIPTIONS", "INFLIGHT", "QUEUE"},
+		footer: totalFooter(len(resp.Clients), resp.TotalCount),
+	}
+	for _, v := range resp.Clients {
+		rs.rows = append(rs.rows, []string{
+			v.ClientId,
+			v.Username,
+			strconv.Itoa(int(v.Version)),
+			v.RemoteAddr,
+			formatTime(v.ConnectedAt),
+			formatTime(v.DisconnectedAt),
+			formatUint(uint64(v.SubscriptionsCurrent)),
+			fmt.Sprintf("%d/%d", v.InflightLen, v.MaxInflight),
+			fmt.Sprintf("%d/%d", v.QueueLen, v.MaxQueue),
+		})
+	}
+	return rs, nil
+}
+
+func getClient(ctx context.Context, cc grpc.ClientConnInterface, clientID string) (*result, error) {
+	resp, err := admin.NewClientServiceClient(cc).Get(ctx, &admin.GetClientRequest{
+		ClientId: clientID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	c := resp.Client
+	return &result{
+		msg:    resp,
+		header: []string{"FIELD", "VALUE"},
+		rows: [][]string{
+			{"client_id", c.ClientId},
+			{"username", c.Username},
+			{"version", strconv.Itoa(int(c.Version))},
+			{"keep_alive", strconv.Itoa(int(c.KeepAlive))},
+			{"remote_addr", c.RemoteAddr},
+			{"local_addr", c.LocalAddr},
+			{"connected_at", formatTime(c.ConnectedAt)},
+			{"disconnected_at", formatTime(c.DisconnectedAt)},
+			{"session_expiry", formatUint(uint64(c.SessionExpiry))},
+			{"inflight", fmt.Sprintf("%d/%d", c.InflightLen, c.MaxInflight)},
+			{"queue", fmt.Sprintf("%d/%d", c.QueueLen, c.MaxQueue)},
+			{"subscriptions_current", formatUint(uint64(c.SubscriptionsCurrent))},
+			{"subscriptions_total", formatUint(uint64(c.SubscriptionsTotal))},
+			{"packets_received_bytes", formatUint(c.PacketsReceivedBytes)},
+			{"packets_received_nums", formatUint(c.PacketsReceivedNums)},
+			{"packets_send_bytes", formatUint(c.PacketsSendBytes)},
+			{"packets_send_nums", formatUint(c.PacketsSendNums)},
+			{"message_dropped", formatUint(c.MessageDropped)},
+		},
+	}, nil
+}
+
+func kickClient(ctx context.Context, cc grpc.ClientConnInterface, clientID string, cleanSession bool) error {
+	_, err := admin.NewClientServiceClient(cc).Delete(ctx, &admin.DeleteClientRequest{
+		ClientId:     clientID,
+		CleanSession: cleanSession,
+	})
+	return err
+}
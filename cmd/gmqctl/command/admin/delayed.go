@@ -0,0 +1,122 @@
+package admin
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+
+	"github.com/DrmagicE/gmqtt/plugin/admin"
+)
+
+var delayedPage pageFlags
+
+// Delayed is the command for managing the pending messages published to $delayed/{seconds}/{topic}.
+var Delayed = &cobra.Command{
+	Use:   "delayed",
+	Short: "List, inspect and cancel the pending delayed messages via the admin gRPC API",
+}
+
+var delayedList = &cobra.Command{
+	Use:     "list",
+	Short:   "List the pending delayed messages in the order of the delivery time",
+	Example: "gmqctl delayed list --page-size 50",
+	Args:    cobra.NoArgs,
+}
+
+var delayedGet = &cobra.Command{
+	Use:     "get <id>",
+	Short:   "Show the details of the pending delayed message",
+	Example: "gmqctl delayed get 5f0c3a1e9b7d4c2a8e6f1b3d5a7c9e0f -o json",
+	Args:    cobra.ExactArgs(1),
+}
+
+var delayedCancel = &cobra.Command{
+	Use:     "cancel <id>",
+	Short:   "Cancel the pending delayed message, it will not be delivered",
+	Example: "gmqctl delayed cancel 5f0c3a1e9b7d4c2a8e6f1b3d5a7c9e0f",
+	Args:    cobra.ExactArgs(1),
+}
+
+func init() {
+	api.register(Delayed.PersistentFlags())
+	delayedList.Run = run(func(ctx context.Context, cc grpc.ClientConnInterface, args []string) (*result, error) {
+		return listDelayed(ctx, cc, delayedPage)
+	})
+	delayedPage.register(delayedList)
+	delayedGet.Run = run(func(ctx context.Context, cc grpc.ClientConnInterface, args []string) (*result, error) {
+		return getDelayed(ctx, cc, args[0])
+	})
+	delayedCancel.Run = run(func(ctx context.Context, cc grpc.ClientConnInterface, args []string) (*result, error) {
+		return nil, cancelDelayed(ctx, cc, args[0])
+	})
+	Delayed.AddCommand(delayedList, delayedGet, delayedCancel)
+}
+
+func listDelayed(ctx context.Context, cc grpc.ClientConnInterface, f pageFlags) (*result, error) {
+	resp, err := admin.NewDelayedServiceClient(cc).List(ctx, &admin.ListDelayedRequest{
+		PageSize: f.pageSize,
+		Page:     f.page,
+	})
+	if err != nil {
+		return nil, err
+	}
+	rs := &result{
+		msg:    resp,
+		header: []string{"ID", "CLIENT ID", "TOPIC", "QOS", "RETAINED", "PUBLISHED AT", "DELIVER AT"},
+		footer: totalFooter(len(resp.Messages), resp.TotalCount),
+	}
+	for _, v := range resp.Messages {
+		rs.rows = append(rs.rows, []string{
+			v.Id,
+			v.ClientId,
+			v.TopicName,
+			formatUint(uint64(v.Qos)),
+			strconv.FormatBool(v.Retained),
+			formatTime(v.PublishedAt),
+			formatTime(v.DeliverAt),
+		})
+	}
+	return rs, nil
+}
+
+func getDelayed(ctx context.Context, cc grpc.ClientConnInterface, id string) (*result, error) {
+	resp, err := admin.NewDelayedServiceClient(cc).Get(ctx, &admin.GetDelayedRequest{
+		Id: id,
+	})
+	if err != nil {
+		return nil, err
+	}
+	m := resp.Message
+	rs := &result{
+		msg:    resp,
+		header: []string{"FIELD", "VALUE"},
+		rows: [][]string{
+			{"id", m.Id},
+			{"client_id", m.ClientId},
+			{"topic_name", m.TopicName},
+			{"payload", m.Payload},
+			{"qos", formatUint(uint64(m.Qos))},
+			{"retained", strconv.FormatBool(m.Retained)},
+			{"content_type", m.ContentType},
+			{"correlation_data", m.CorrelationData},
+			{"message_expiry", formatUint(uint64(m.MessageExpiry))},
+			{"payload_format", formatUint(uint64(m.PayloadFormat))},
+			{"response_topic", m.ResponseTopic},
+			{"published_at", formatTime(m.PublishedAt)},
+			{"deliver_at", formatTime(m.DeliverAt)},
+		},
+	}
+	for _, v := range m.UserProperties {
+		rs.rows = append(rs.rows, []string{"user_property", string(v.K) + "=" + string(v.V)})
+	}
+	return rs, nil
+}
+
+func cancelDelayed(ctx context.Context, cc grpc.ClientConnInterface, id string) error {
+	_, err := admin.NewDelayedServiceClient(cc).Delete(ctx, &admin.DeleteDelayedRequest{
+		Id: id,
+	})
+	return err
+}
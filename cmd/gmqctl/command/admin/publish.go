@@ -0,0 +1,91 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+
+	"github.com/DrmagicE/gmqtt/plugin/admin"
+)
+
+type publishFlags struct {
+	topic           string
+	message         string
+	qos             uint32
+	retain          bool
+	userProperties  []string
+	contentType     string
+	responseTopic   string
+	correlationData string
+	messageExpiry   uint32
+	utf8Payload     bool
+}
+
+var publish publishFlags
+
+// Publish is the command for publishing a message via the admin gRPC API.
+// Unlike the pub command, the message is published by the broker itself, without connecting as a MQTT client.
+var Publish = &cobra.Command{
+	Use:     "publish",
+	Short:   "Publish a message via the admin gRPC API",
+	Example: "gmqctl publish -t topic/a -m hello -q 1 --user-property k1=v1",
+	Args:    cobra.NoArgs,
+}
+
+func init() {
+	api.register(Publish.Flags())
+	Publish.Run = run(func(ctx context.Context, cc grpc.ClientConnInterface, args []string) (*result, error) {
+		return nil, publishMessage(ctx, cc, publish)
+	})
+	fs := Publish.Flags()
+	fs.StringVarP(&publish.topic, "topic", "t", "", "The topic to publish to.")
+	fs.StringVarP(&publish.message, "message", "m", "", "The message payload.")
+	fs.Uint32VarP(&publish.qos, "qos", "q", 0, "The QoS level.")
+	fs.BoolVarP(&publish.retain, "retain", "r", false, "Whether the message is retained.")
+	fs.StringArrayVar(&publish.userProperties, "user-property", nil, "The user property in key=value format, can be repeated.")
+	fs.StringVar(&publish.contentType, "content-type", "", "The content type.")
+	fs.StringVar(&publish.responseTopic, "response-topic", "", "The response topic.")
+	fs.StringVar(&publish.correlationData, "correlation-data", "", "The correlation data.")
+	fs.Uint32Var(&publish.messageExpiry, "message-expiry", 0, "The message expiry interval in seconds.")
+	fs.BoolVar(&publish.utf8Payload, "utf8-payload", false, "Set the payload format indicator to UTF-8.")
+	_ = Publish.MarkFlagRequired("topic")
+}
+
+func (f publishFlags) publishRequest() (*admin.PublishRequest, error) {
+	req := &admin.PublishRequest{
+		TopicName:       f.topic,
+		Payload:         f.message,
+		Qos:             f.qos,
+		Retained:        f.retain,
+		ContentType:     f.contentType,
+		CorrelationData: f.correlationData,
+		MessageExpiry:   f.messageExpiry,
+		ResponseTopic:   f.responseTopic,
+	}
+	if f.utf8Payload {
+		req.PayloadFormat = 1
+	}
+	for _, v := range f.userProperties {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid user property: %s, must be in key=value format", v)
+		}
+		req.UserProperties = append(req.UserProperties, &admin.UserProperties{
+			K: []byte(kv[0]),
+			V: []byte(kv[1]),
+		})
+	}
+	return req, nil
+}
+
+func publishMessage(ctx context.Context, cc grpc.ClientConnInterface, f publishFlags) error {
+	req, err := f.publishRequest()
+	if err != nil {
+		return err
+	}
+	_, err = admin.NewPublishServiceClient(cc).Publish(ctx, req)
+	return err
+}
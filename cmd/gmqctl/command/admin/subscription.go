@@ -0,0 +1,229 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+
+	"github.com/DrmagicE/gmqtt/plugin/admin"
+)
+
+type filterFlags struct {
+	clientID string
+	types    []string
+	match    string
+	topic    string
+	limit    int32
+}
+
+type subscribeFlags struct {
+	topics            []string
+	qos               uint32
+	id                uint32
+	noLocal           bool
+	retainAsPublished bool
+	retainHandling    uint32
+}
+
+var (
+	subPage     pageFlags
+	filter      filterFlags
+	subscribe   subscribeFlags
+	unsubTopics []string
+)
+
+var subList = &cobra.Command{
+	Use:     "list",
+	Short:   "List the subscriptions via the admin gRPC API",
+	Example: "gmqctl sub list --page 1 --page-size 50",
+	Args:    cobra.NoArgs,
+}
+
+var subFilter = &cobra.Command{
+	Use:   "filter",
+	Short: "Filter the subscriptions via the admin gRPC API",
+	Example: "gmqctl sub filter --client-id client1\n" +
+		"gmqctl sub filter --type shared,non-shared -t a/b --match filter --limit 100",
+	Args: cobra.NoArgs,
+}
+
+var subAdd = &cobra.Command{
+	Use:     "add <client_id>",
+	Short:   "Make subscriptions for the client via the admin gRPC API",
+	Example: "gmqctl sub add client1 -t topic/a -t topic/b -q 1",
+	Args:    cobra.ExactArgs(1),
+}
+
+var subRm = &cobra.Command{
+	Use:     "rm <client_id>",
+	Short:   "Unsubscribe topics for the client via the admin gRPC API",
+	Example: "gmqctl sub rm client1 -t topic/a",
+	Args:    cobra.ExactArgs(1),
+}
+
+// SubscriptionCommands are the commands for managing the subscriptions.
+// They are added as the subcommands of the sub command.
+var SubscriptionCommands = []*cobra.Command{subList, subFilter, subAdd, subRm}
+
+func init() {
+	for _, v := range SubscriptionCommands {
+		api.register(v.Flags())
+	}
+	subList.Run = run(func(ctx context.Context, cc grpc.ClientConnInterface, args []string) (*result, error) {
+		return listSubscriptions(ctx, cc, subPage)
+	})
+	subPage.register(subList)
+
+	subFilter.Run = run(func(ctx context.Context, cc grpc.ClientConnInterface, args []string) (*result, error) {
+		return filterSubscriptions(ctx, cc, filter)
+	})
+	fs := subFilter.Flags()
+	fs.StringVar(&filter.clientID, "client-id", "", "Only return the subscriptions of the client.")
+	fs.StringSliceVar(&filter.types, "type", nil, "The topic types to return, separated by comma: sys, shared or non-shared.")
+	fs.StringVar(&filter.match, "match", "filter", "How to match the topic: name returns the subscriptions with the same topic filter, "+
+		"filter returns the subscriptions matching the topic name.")
+	fs.StringVarP(&filter.topic, "topic", "t", "", "The topic to match.")
+	fs.Int32Var(&filter.limit, "limit", 20, "The maximum number of subscriptions to return, at most 1000.")
+
+	subAdd.Run = run(func(ctx context.Context, cc grpc.ClientConnInterface, args []string) (*result, error) {
+		return addSubscriptions(ctx, cc, args[0], subscribe)
+	})
+	fs = subAdd.Flags()
+	fs.StringArrayVarP(&subscribe.topics, "topic", "t", nil, "The topic filter to subscribe, can be repeated.")
+	fs.Uint32VarP(&subscribe.qos, "qos", "q", 0, "The QoS level of the subscriptions.")
+	fs.Uint32Var(&subscribe.id, "id", 0, "The subscription identifier.")
+	fs.BoolVar(&subscribe.noLocal, "no-local", false, "Do not forward the messages published by the client itself.")
+	fs.BoolVar(&subscribe.retainAsPublished, "retain-as-published", false, "Keep the retain flag of the forwarded messages.")
+	fs.Uint32Var(&subscribe.retainHandling, "retain-handling", 0, "The retain handling option, 0, 1 or 2.")
+	_ = subAdd.MarkFlagRequired("topic")
+
+	subRm.Run = run(func(ctx context.Context, cc grpc.ClientConnInterface, args []string) (*result, error) {
+		return nil, removeSubscriptions(ctx, cc, args[0], unsubTopics)
+	})
+	subRm.Flags().StringArrayVarP(&unsubTopics, "topic", "t", nil, "The topic filter to unsubscribe, can be repeated.")
+	_ = subRm.MarkFlagRequired("topic")
+}
+
+func subscriptionsResult(subs []*admin.Subscription) *result {
+	rs := &result{
+		header: []string{"CLIENT ID", "TOPIC", "QOS", "ID", "NO LOCAL", "RETAIN AS PUBLISHED", "RETAIN HANDLING"},
+	}
+	for _, v := range subs {
+		rs.rows = append(rs.rows, []string{
+			v.ClientId,
+			v.TopicName,
+			formatUint(uint64(v.Qos)),
+			formatUint(uint64(v.Id)),
+			strconv.FormatBool(v.NoLocal),
+			strconv.FormatBool(v.RetainAsPublished),
+			formatUint(uint64(v.RetainHandling)),
+		})
+	}
+	return rs
+}
+
+func listSubscriptions(ctx context.Context, cc grpc.ClientConnInterface, f pageFlags) (*result, error) {
+	resp, err := admin.NewSubscriptionServiceClient(cc).List(ctx, &admin.ListSubscriptionRequest{
+		PageSize: f.pageSize,
+		Page:     f.page,
+	})
+	if err != nil {
+		return nil, err
+	}
+	rs := subscriptionsResult(resp.Subscriptions)
+	rs.msg = resp
+	rs.footer = totalFooter(len(resp.Subscriptions), resp.TotalCount)
+	return rs, nil
+}
+
+// filterRequest converts the filter flags to the gRPC request.
+func (f filterFlags) filterRequest() (*admin.FilterSubscriptionRequest, error) {
+	req := &admin.FilterSubscriptionRequest{
+		ClientId: f.clientID,
+		Limit:    f.limit,
+	}
+	var types []string
+	for _, v := range f.types {
+		var t admin.SubFilterType
+		switch v {
+		case "sys":
+			t = admin.SubFilterType_SUB_FILTER_TYPE_SYS
+		case "shared":
+			t = admin.SubFilterType_SUB_FILTER_TYPE_SHARED
+		case "non-shared":
+			t = admin.SubFilterType_SUB_FILTER_TYPE_NON_SHARED
+		default:
+			return nil, fmt.Errorf("invalid type: %s, must be sys, shared or non-shared", v)
+		}
+		types = append(types, strconv.Itoa(int(t)))
+	}
+	req.FilterType = strings.Join(types, ",")
+	if f.topic != "" {
+		req.TopicName = f.topic
+		switch f.match {
+		case "name":
+			req.MatchType = admin.SubMatchType_SUB_MATCH_TYPE_MATCH_NAME
+		case "filter":
+			req.MatchType = admin.SubMatchType_SUB_MATCH_TYPE_MATCH_FILTER
+		default:
+			return nil, fmt.Errorf("invalid match: %s, must be name or filter", f.match)
+		}
+	}
+	return req, nil
+}
+
+func filterSubscriptions(ctx context.Context, cc grpc.ClientConnInterface, f filterFlags) (*result, error) {
+	req, err := f.filterRequest()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := admin.NewSubscriptionServiceClient(cc).Filter(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	rs := subscriptionsResult(resp.Subscriptions)
+	rs.msg = resp
+	return rs, nil
+}
+
+func addSubscriptions(ctx context.Context, cc grpc.ClientConnInterface, clientID string, f subscribeFlags) (*result, error) {
+	req := &admin.SubscribeRequest{
+		ClientId: clientID,
+	}
+	for _, v := range f.topics {
+		req.Subscriptions = append(req.Subscriptions, &admin.Subscription{
+			TopicName:         v,
+			Id:                f.id,
+			Qos:               f.qos,
+			NoLocal:           f.noLocal,
+			RetainAsPublished: f.retainAsPublished,
+			RetainHandling:    f.retainHandling,
+		})
+	}
+	resp, err := admin.NewSubscriptionServiceClient(cc).Subscribe(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	rs := &result{
+		msg:    resp,
+		header: []string{"TOPIC", "NEW"},
+	}
+	for k, v := range resp.New {
+		if k < len(f.topics) {
+			rs.rows = append(rs.rows, []string{f.topics[k], strconv.FormatBool(v)})
+		}
+	}
+	return rs, nil
+}
+
+func removeSubscriptions(ctx context.Context, cc grpc.ClientConnInterface, clientID string, topics []string) error {
+	_, err := admin.NewSubscriptionServiceClient(cc).Unsubscribe(ctx, &admin.UnsubscribeRequest{
+		ClientId: clientID,
+		Topics:   topics,
+	})
+	return err
+}
@@ -0,0 +1,269 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/pkg/client"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+// connectConcurrency is the maximum number of concurrent connecting clients.
+const connectConcurrency = 64
+
+// timestampSize is the size of the send timestamp at the beginning of the payload.
+const timestampSize = 8
+
+type benchFlags struct {
+	conn        connFlags
+	publishers  int
+	subscribers int
+	count       int
+	rate        float64
+	size        int
+	qos         uint8
+	topic       string
+	timeout     time.Duration
+}
+
+var bench benchFlags
+
+// Bench is the command for benchmarking the broker.
+var Bench = &cobra.Command{
+	Use:   "bench",
+	Short: "Benchmark the broker with multiple publishing and subscribing connections",
+	Long: "Bench connects the publishers and subscribers to the broker as real MQTT clients over TCP/TLS. " +
+		"Each publisher publishes to {topic}/{index}, and each subscriber subscribes to {topic}/#. " +
+		"It reports the publish and delivery throughput, and the latency percentiles of publishing " +
+		"(until acknowledged, or written for QoS 0) " +
+		"and delivery (from publishing to receiving by the subscribers).",
+	Example: "gmqctl bench -a 127.0.0.1:1883 --publishers 100 --subscribers 1 --count 1000 --qos 1 --size 128",
+}
+
+func init() {
+	Bench.Run = func(cmd *cobra.Command, args []string) {
+		ctx, cancel := signalContext()
+		defer cancel()
+		must(runBench(ctx, os.Stdout, &bench))
+	}
+	bench.conn.register(Bench)
+	fs := Bench.Flags()
+	fs.IntVar(&bench.publishers, "publishers", 10, "The number of publishing connections.")
+	fs.IntVar(&bench.subscribers, "subscribers", 1, "The number of subscribing connections.")
+	fs.IntVarP(&bench.count, "count", "C", 100, "The number of messages published by each publisher.")
+	fs.Float64Var(&bench.rate, "rate", 0, "The messages per second of each publisher, 0 means as fast as possible.")
+	fs.IntVar(&bench.size, "size", 64, "The payload size in bytes, the minimum is 8.")
+	fs.Uint8VarP(&bench.qos, "qos", "q", 0, "The QoS level of the messages and subscriptions.")
+	fs.StringVarP(&bench.topic, "topic", "t", "gmqctl/bench", "The topic prefix.")
+	fs.DurationVar(&bench.timeout, "timeout", 10*time.Second, "The maximum time to wait for the deliveries after publishing.")
+}
+
+func (f *benchFlags) validate() error {
+	if f.publishers <= 0 || f.subscribers < 0 || f.count <= 0 {
+		return errors.New("publishers and count must be greater than 0, subscribers cannot be negative")
+	}
+	if f.size < timestampSize {
+		return fmt.Errorf("size must be at least %d", timestampSize)
+	}
+	if f.rate < 0 {
+		return errors.New("rate cannot be negative")
+	}
+	if !packets.ValidTopicName(true, []byte(f.topic)) {
+		return errors.New("invalid topic: " + f.topic)
+	}
+	return validateQoS(f.qos)
+}
+
+// latencies collects the latencies concurrently.
+type latencies struct {
+	mu sync.Mutex
+	d  []time.Duration
+}
+
+func (l *latencies) add(d time.Duration) {
+	l.mu.Lock()
+	l.d = append(l.d, d)
+	l.mu.Unlock()
+}
+
+// percentiles returns the given percentiles of the latencies.
+func (l *latencies) percentiles(ps ...float64) []time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	sort.Slice(l.d, func(i, j int) bool {
+		return l.d[i] < l.d[j]
+	})
+	rs := make([]time.Duration, len(ps))
+	if len(l.d) == 0 {
+		return rs
+	}
+	for k, p := range ps {
+		i := int(math.Ceil(p/100*float64(len(l.d)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		rs[k] = l.d[i]
+	}
+	return rs
+}
+
+func (l *latencies) String() string {
+	ps := l.percentiles(50, 90, 99, 100)
+	return fmt.Sprintf("p50 %s, p90 %s, p99 %s, max %s", ps[0], ps[1], ps[2], ps[3])
+}
+
+// connectAll connects n clients concurrently.
+// It returns the connected clients, the number of failures and the last connect error.
+func connectAll(ctx context.Context, f *benchFlags, role string, prefix string, n int, extra ...client.Options) (clients []*client.Client, failed int, lastErr error) {
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	sem := make(chan struct{}, connectConcurrency)
+	for i := 0; i < n; i++ {
+		// the options have been validated in runBench.
+		opts, _ := f.conn.options(fmt.Sprintf("%s-%s-%d", prefix, role, i))
+		c := client.New(append(opts, extra...)...)
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			_, err := c.Connect(ctx)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed++
+				lastErr = err
+				return
+			}
+			clients = append(clients, c)
+		}()
+	}
+	wg.Wait()
+	return clients, failed, lastErr
+}
+
+func disconnectAll(clients []*client.Client) {
+	for _, c := range clients {
+		_ = c.Disconnect()
+	}
+}
+
+func printConnected(out io.Writer, role string, connected, failed int, err error) {
+	if err != nil {
+		fmt.Fprintf(out, "%s: %d connected, %d failed, last error: %s\n", role, connected, failed, err)
+		return
+	}
+	fmt.Fprintf(out, "%s: %d connected\n", role, connected)
+}
+
+// runBench runs the benchmark and writes the report to out.
+func runBench(ctx context.Context, out io.Writer, f *benchFlags) error {
+	if err := f.validate(); err != nil {
+		return err
+	}
+	if _, err := f.conn.options(""); err != nil {
+		return err
+	}
+	prefix := f.conn.clientID
+	if prefix == "" {
+		prefix = fmt.Sprintf("gmqctl-bench-%d", time.Now().UnixNano())
+	}
+	var (
+		delivered       int64
+		deliveryLatency = &latencies{}
+		publishLatency  = &latencies{}
+	)
+	subs, subFailed, subErr := connectAll(ctx, f, "sub", prefix, f.subscribers,
+		client.WithMessageHandler(func(c *client.Client, msg *gmqtt.Message) {
+			if len(msg.Payload) >= timestampSize {
+				sent := int64(binary.BigEndian.Uint64(msg.Payload))
+				deliveryLatency.add(time.Duration(time.Now().UnixNano() - sent))
+			}
+			atomic.AddInt64(&delivered, 1)
+		}))
+	defer disconnectAll(subs)
+	for _, c := range subs {
+		if err := subscribe(ctx, c, []packets.Topic{{
+			Name:       f.topic + "/#",
+			SubOptions: packets.SubOptions{Qos: f.qos},
+		}}); err != nil {
+			return err
+		}
+	}
+	pubs, pubFailed, pubErr := connectAll(ctx, f, "pub", prefix, f.publishers)
+	defer disconnectAll(pubs)
+	printConnected(out, "publishers", len(pubs), pubFailed, pubErr)
+	printConnected(out, "subscribers", len(subs), subFailed, subErr)
+
+	var (
+		published int64
+		pubErrors int64
+		wg        sync.WaitGroup
+	)
+	start := time.Now()
+	for i, c := range pubs {
+		wg.Add(1)
+		go func(i int, c *client.Client) {
+			defer wg.Done()
+			var interval time.Duration
+			if f.rate > 0 {
+				interval = time.Duration(float64(time.Second) / f.rate)
+			}
+			next := time.Now()
+			for n := 0; n < f.count && ctx.Err() == nil; n++ {
+				if interval > 0 {
+					time.Sleep(time.Until(next))
+					next = next.Add(interval)
+				}
+				payload := make([]byte, f.size)
+				now := time.Now()
+				binary.BigEndian.PutUint64(payload, uint64(now.UnixNano()))
+				err := c.Publish(ctx, &gmqtt.Message{
+					QoS:     f.qos,
+					Topic:   fmt.Sprintf("%s/%d", f.topic, i),
+					Payload: payload,
+				})
+				if err != nil {
+					atomic.AddInt64(&pubErrors, 1)
+					continue
+				}
+				publishLatency.add(time.Since(now))
+				atomic.AddInt64(&published, 1)
+			}
+		}(i, c)
+	}
+	wg.Wait()
+	pubElapsed := time.Since(start)
+
+	expected := published * int64(len(subs))
+	deadline := time.Now().Add(f.timeout)
+	for atomic.LoadInt64(&delivered) < expected && time.Now().Before(deadline) && ctx.Err() == nil {
+		time.Sleep(10 * time.Millisecond)
+	}
+	deliveryElapsed := time.Since(start)
+	received := atomic.LoadInt64(&delivered)
+
+	fmt.Fprintf(out, "publish: %d ok, %d failed in %s, %.2f msg/s\n",
+		published, pubErrors, pubElapsed.Round(time.Millisecond), float64(published)/pubElapsed.Seconds())
+	fmt.Fprintf(out, "publish latency: %s\n", publishLatency)
+	fmt.Fprintf(out, "delivery: %d/%d in %s, %.2f msg/s\n",
+		received, expected, deliveryElapsed.Round(time.Millisecond), float64(received)/deliveryElapsed.Seconds())
+	fmt.Fprintf(out, "delivery latency: %s\n", deliveryLatency)
+	return nil
+}
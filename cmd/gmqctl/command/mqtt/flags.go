@@ -0,0 +1,167 @@
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DrmagicE/gmqtt/pkg/client"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+func must(err error) {
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// signalContext returns a context which is canceled on SIGINT or SIGTERM.
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-ch:
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(ch)
+	}()
+	return ctx, cancel
+}
+
+// connFlags are the flags of connecting to the broker, shared by pub, sub and bench.
+type connFlags struct {
+	addr           string
+	version        int
+	clientID       string
+	username       string
+	password       string
+	keepAlive      uint16
+	cleanStart     bool
+	sessionExpiry  uint32
+	connectTimeout time.Duration
+	tls            bool
+	caFile         string
+	certFile       string
+	keyFile        string
+	insecure       bool
+}
+
+func (f *connFlags) register(cmd *cobra.Command) {
+	fs := cmd.Flags()
+	fs.StringVarP(&f.addr, "addr", "a", "127.0.0.1:1883", "The TCP address of the broker.")
+	fs.IntVarP(&f.version, "protocol-version", "V", 5, "The MQTT protocol version, 4 for v3.1.1 and 5 for v5.")
+	fs.StringVarP(&f.clientID, "client-id", "i", "", "The client id, a random id is used if empty.")
+	fs.StringVarP(&f.username, "username", "u", "", "The username.")
+	fs.StringVarP(&f.password, "password", "P", "", "The password.")
+	fs.Uint16VarP(&f.keepAlive, "keepalive", "k", 60, "The keep alive in seconds.")
+	fs.BoolVar(&f.cleanStart, "clean-start", true, "The clean start (clean session in v3.1.1) flag.")
+	fs.Uint32Var(&f.sessionExpiry, "session-expiry", 0, "The session expiry interval in seconds (v5 only).")
+	fs.DurationVar(&f.connectTimeout, "connect-timeout", 5*time.Second, "The timeout of connecting to the broker.")
+	fs.BoolVar(&f.tls, "tls", false, "Whether to connect with TLS.")
+	fs.StringVar(&f.caFile, "cafile", "", "The CA certificate file to verify the broker, implies --tls.")
+	fs.StringVar(&f.certFile, "cert", "", "The client certificate file, implies --tls.")
+	fs.StringVar(&f.keyFile, "key", "", "The client key file.")
+	fs.BoolVar(&f.insecure, "insecure", false, "Do not verify the broker certificate.")
+}
+
+func (f *connFlags) tlsConfig() (*tls.Config, error) {
+	if !f.tls && f.caFile == "" && f.certFile == "" {
+		return nil, nil
+	}
+	cfg := &tls.Config{
+		InsecureSkipVerify: f.insecure,
+	}
+	if f.caFile != "" {
+		b, err := ioutil.ReadFile(f.caFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("invalid ca file: %s", f.caFile)
+		}
+	}
+	if f.certFile != "" {
+		cert, err := tls.LoadX509KeyPair(f.certFile, f.keyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// options returns the client options, clientID overrides the client id flag if not empty.
+func (f *connFlags) options(clientID string) ([]client.Options, error) {
+	if f.version != int(packets.Version311) && f.version != int(packets.Version5) {
+		return nil, errors.New("invalid protocol version, must be 4 or 5")
+	}
+	if clientID == "" {
+		clientID = f.clientID
+	}
+	if clientID == "" {
+		clientID = fmt.Sprintf("gmqctl-%d", time.Now().UnixNano())
+	}
+	tlsConfig, err := f.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	opts := []client.Options{
+		client.WithAddress(f.addr),
+		client.WithVersion(packets.Version(f.version)),
+		client.WithClientID(clientID),
+		client.WithKeepAlive(f.keepAlive),
+		client.WithCleanStart(f.cleanStart),
+		client.WithSessionExpiry(f.sessionExpiry),
+		client.WithConnectTimeout(f.connectTimeout),
+		client.WithMaxPacketSize(0),
+	}
+	if f.username != "" || f.password != "" {
+		var password []byte
+		if f.password != "" {
+			password = []byte(f.password)
+		}
+		opts = append(opts, client.WithUsernamePassword(f.username, password))
+	}
+	if tlsConfig != nil {
+		opts = append(opts, client.WithTLSConfig(tlsConfig))
+	}
+	return opts, nil
+}
+
+// parseUserProperties parses the user properties in key=value format.
+func parseUserProperties(props []string) ([]packets.UserProperty, error) {
+	var rs []packets.UserProperty
+	for _, v := range props {
+		kv := strings.SplitN(v, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid user property: %s, must be in key=value format", v)
+		}
+		rs = append(rs, packets.UserProperty{
+			K: []byte(kv[0]),
+			V: []byte(kv[1]),
+		})
+	}
+	return rs, nil
+}
+
+func validateQoS(qos uint8) error {
+	if qos > packets.Qos2 {
+		return fmt.Errorf("invalid qos: %d", qos)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package mqtt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt/config"
+	_ "github.com/DrmagicE/gmqtt/persistence"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+	_ "github.com/DrmagicE/gmqtt/topicalias/fifo"
+)
+
+func runServer(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := server.New(
+		server.WithConfig(config.DefaultConfig()),
+		server.WithTCPListener(ln),
+	)
+	if err := srv.Run(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = srv.Stop(context.Background())
+	})
+	return ln.Addr().String()
+}
+
+func newConnFlags(addr string) connFlags {
+	return connFlags{
+		addr:           addr,
+		version:        int(packets.Version5),
+		keepAlive:      60,
+		cleanStart:     true,
+		connectTimeout: time.Second,
+	}
+}
+
+func TestParseUserProperties(t *testing.T) {
+	a := assert.New(t)
+	rs, err := parseUserProperties([]string{"k1=v1", "k2=", "k3=a=b"})
+	a.Nil(err)
+	a.Equal([]packets.UserProperty{
+		{K: []byte("k1"), V: []byte("v1")},
+		{K: []byte("k2"), V: []byte("")},
+		{K: []byte("k3"), V: []byte("a=b")},
+	}, rs)
+	_, err = parseUserProperties([]string{"k1"})
+	a.NotNil(err)
+	_, err = parseUserProperties([]string{"=v"})
+	a.NotNil(err)
+}
+
+func TestLatencies_percentiles(t *testing.T) {
+	a := assert.New(t)
+	l := &latencies{}
+	a.Equal([]time.Duration{0}, l.percentiles(50))
+	for i := 100; i > 0; i-- {
+		l.add(time.Duration(i) * time.Millisecond)
+	}
+	a.Equal([]time.Duration{
+		50 * time.Millisecond,
+		99 * time.Millisecond,
+		100 * time.Millisecond,
+	}, l.percentiles(50, 99, 100))
+}
+
+func TestPubSub(t *testing.T) {
+	a := assert.New(t)
+	addr := runServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	a.Nil(runPub(ctx, &pubFlags{
+		conn:           newConnFlags(addr),
+		topic:          "a/b",
+		message:        "hello",
+		qos:            packets.Qos1,
+		retain:         true,
+		count:          1,
+		userProperties: []string{"k=v"},
+		contentType:    "text/plain",
+	}))
+	a.NotNil(runPub(ctx, &pubFlags{
+		conn:  newConnFlags(addr),
+		topic: "a/+",
+		count: 1,
+	}))
+
+	out := &bytes.Buffer{}
+	a.Nil(runSub(ctx, out, &subFlags{
+		conn:              newConnFlags(addr),
+		topics:            []string{"a/#"},
+		qos:               packets.Qos1,
+		retainAsPublished: true,
+		count:             1,
+		verbose:           true,
+	}))
+	var msg message
+	a.Nil(json.Unmarshal(out.Bytes(), &msg))
+	a.Equal(message{
+		Topic:          "a/b",
+		QoS:            packets.Qos1,
+		Retained:       true,
+		Payload:        "hello",
+		ContentType:    "text/plain",
+		UserProperties: map[string]string{"k": "v"},
+	}, msg)
+
+	out.Reset()
+	f := &subFlags{
+		conn:   newConnFlags(addr),
+		topics: []string{"a/#"},
+		count:  1,
+	}
+	f.conn.version = int(packets.Version311)
+	a.Nil(runSub(ctx, out, f))
+	a.Equal("hello\n", out.String())
+}
+
+func TestBench(t *testing.T) {
+	a := assert.New(t)
+	addr := runServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	out := &bytes.Buffer{}
+	a.Nil(runBench(ctx, out, &benchFlags{
+		conn:        newConnFlags(addr),
+		publishers:  3,
+		subscribers: 2,
+		count:       10,
+		size:        16,
+		qos:         packets.Qos1,
+		topic:       "bench",
+		timeout:     5 * time.Second,
+	}))
+	rs := out.String()
+	a.True(strings.Contains(rs, "publishers: 3 connected\n"), rs)
+	a.True(strings.Contains(rs, "subscribers: 2 connected\n"), rs)
+	a.True(strings.Contains(rs, "publish: 30 ok, 0 failed"), rs)
+	a.True(strings.Contains(rs, "delivery: 60/60"), rs)
+
+	a.NotNil(runBench(ctx, out, &benchFlags{
+		conn:       newConnFlags(addr),
+		publishers: 1,
+		count:      1,
+		size:       4,
+		topic:      "bench",
+	}))
+}
@@ -0,0 +1,127 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/pkg/client"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+type pubFlags struct {
+	conn            connFlags
+	topic           string
+	message         string
+	file            string
+	qos             uint8
+	retain          bool
+	count           int
+	userProperties  []string
+	contentType     string
+	responseTopic   string
+	correlationData string
+	messageExpiry   uint32
+	utf8Payload     bool
+}
+
+var pub pubFlags
+
+// Pub is the command for publishing a message.
+var Pub = &cobra.Command{
+	Use:   "pub",
+	Short: "Publish a message to the broker",
+	Example: "gmqctl pub -a 127.0.0.1:1883 -t topic/a -m hello -q 1\n" +
+		"gmqctl pub -t topic/a -f payload.json --content-type application/json --user-property k1=v1 --user-property k2=v2",
+}
+
+func init() {
+	Pub.Run = func(cmd *cobra.Command, args []string) {
+		ctx, cancel := signalContext()
+		defer cancel()
+		must(runPub(ctx, &pub))
+	}
+	pub.conn.register(Pub)
+	fs := Pub.Flags()
+	fs.StringVarP(&pub.topic, "topic", "t", "", "The topic to publish to.")
+	fs.StringVarP(&pub.message, "message", "m", "", "The message payload.")
+	fs.StringVarP(&pub.file, "file", "f", "", "Read the message payload from the file, '-' means stdin.")
+	fs.Uint8VarP(&pub.qos, "qos", "q", 0, "The QoS level.")
+	fs.BoolVarP(&pub.retain, "retain", "r", false, "Whether the message is retained.")
+	fs.IntVarP(&pub.count, "count", "C", 1, "The number of times to publish the message.")
+	fs.StringArrayVar(&pub.userProperties, "user-property", nil, "The user property in key=value format, can be repeated (v5 only).")
+	fs.StringVar(&pub.contentType, "content-type", "", "The content type (v5 only).")
+	fs.StringVar(&pub.responseTopic, "response-topic", "", "The response topic (v5 only).")
+	fs.StringVar(&pub.correlationData, "correlation-data", "", "The correlation data (v5 only).")
+	fs.Uint32Var(&pub.messageExpiry, "message-expiry", 0, "The message expiry interval in seconds (v5 only).")
+	fs.BoolVar(&pub.utf8Payload, "utf8-payload", false, "Set the payload format indicator to UTF-8 (v5 only).")
+	_ = Pub.MarkFlagRequired("topic")
+}
+
+func (f *pubFlags) newMessage() (*gmqtt.Message, error) {
+	if err := validateQoS(f.qos); err != nil {
+		return nil, err
+	}
+	if !packets.ValidTopicName(true, []byte(f.topic)) {
+		return nil, errors.New("invalid topic name: " + f.topic)
+	}
+	payload := []byte(f.message)
+	if f.file != "" {
+		var err error
+		if f.file == "-" {
+			payload, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			payload, err = ioutil.ReadFile(f.file)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	userProperties, err := parseUserProperties(f.userProperties)
+	if err != nil {
+		return nil, err
+	}
+	msg := &gmqtt.Message{
+		QoS:            f.qos,
+		Retained:       f.retain,
+		Topic:          f.topic,
+		Payload:        payload,
+		ContentType:    f.contentType,
+		ResponseTopic:  f.responseTopic,
+		MessageExpiry:  f.messageExpiry,
+		UserProperties: userProperties,
+	}
+	if f.correlationData != "" {
+		msg.CorrelationData = []byte(f.correlationData)
+	}
+	if f.utf8Payload {
+		msg.PayloadFormat = packets.PayloadFormatString
+	}
+	return msg, nil
+}
+
+func runPub(ctx context.Context, f *pubFlags) error {
+	msg, err := f.newMessage()
+	if err != nil {
+		return err
+	}
+	opts, err := f.conn.options("")
+	if err != nil {
+		return err
+	}
+	c := client.New(opts...)
+	if _, err = c.Connect(ctx); err != nil {
+		return err
+	}
+	defer c.Disconnect()
+	for i := 0; i < f.count; i++ {
+		if err = c.Publish(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/pkg/client"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+type subFlags struct {
+	conn              connFlags
+	topics            []string
+	qos               uint8
+	noLocal           bool
+	retainAsPublished bool
+	retainHandling    uint8
+	count             int
+	verbose           bool
+}
+
+var sub subFlags
+
+// Sub is the command for subscribing topics and printing the received messages.
+var Sub = &cobra.Command{
+	Use:   "sub",
+	Short: "Subscribe topics and print the received messages",
+	Long: "Sub subscribes topics as a MQTT client and prints the received messages. " +
+		"The list, filter, add and rm subcommands manage the subscriptions in the broker via the admin gRPC API.",
+	Args: cobra.NoArgs,
+	Example: "gmqctl sub -a 127.0.0.1:1883 -t topic/# -q 1\n" +
+		"gmqctl sub -t topic/a -t topic/b -v",
+}
+
+func init() {
+	Sub.Run = func(cmd *cobra.Command, args []string) {
+		ctx, cancel := signalContext()
+		defer cancel()
+		must(runSub(ctx, os.Stdout, &sub))
+	}
+	sub.conn.register(Sub)
+	fs := Sub.Flags()
+	fs.StringArrayVarP(&sub.topics, "topic", "t", nil, "The topic filter to subscribe, can be repeated.")
+	fs.Uint8VarP(&sub.qos, "qos", "q", 0, "The maximum QoS level of the subscriptions.")
+	fs.BoolVar(&sub.noLocal, "no-local", false, "Do not receive the messages published by this client (v5 only).")
+	fs.BoolVar(&sub.retainAsPublished, "retain-as-published", false, "Keep the retain flag of the forwarded messages (v5 only).")
+	fs.Uint8Var(&sub.retainHandling, "retain-handling", 0, "The retain handling option, 0, 1 or 2 (v5 only).")
+	fs.IntVarP(&sub.count, "count", "C", 0, "Exit after receiving the number of messages, 0 means never exit.")
+	fs.BoolVarP(&sub.verbose, "verbose", "v", false, "Print the messages in JSON with the topic, flags and properties, instead of the payload only.")
+	_ = Sub.MarkFlagRequired("topic")
+}
+
+// message is the JSON format of the received message in verbose mode.
+type message struct {
+	Topic                  string            `json:"topic"`
+	QoS                    uint8             `json:"qos"`
+	Retained               bool              `json:"retained"`
+	Payload                string            `json:"payload"`
+	ContentType            string            `json:"content_type,omitempty"`
+	CorrelationData        string            `json:"correlation_data,omitempty"`
+	MessageExpiry          uint32            `json:"message_expiry,omitempty"`
+	PayloadFormat          uint8             `json:"payload_format,omitempty"`
+	ResponseTopic          string            `json:"response_topic,omitempty"`
+	SubscriptionIdentifier []uint32          `json:"subscription_identifier,omitempty"`
+	UserProperties         map[string]string `json:"user_properties,omitempty"`
+}
+
+func formatMessage(msg *gmqtt.Message, verbose bool) string {
+	if !verbose {
+		return string(msg.Payload)
+	}
+	m := message{
+		Topic:                  msg.Topic,
+		QoS:                    msg.QoS,
+		Retained:               msg.Retained,
+		Payload:                string(msg.Payload),
+		ContentType:            msg.ContentType,
+		CorrelationData:        string(msg.CorrelationData),
+		MessageExpiry:          msg.MessageExpiry,
+		PayloadFormat:          msg.PayloadFormat,
+		ResponseTopic:          msg.ResponseTopic,
+		SubscriptionIdentifier: msg.SubscriptionIdentifier,
+	}
+	if len(msg.UserProperties) != 0 {
+		m.UserProperties = make(map[string]string)
+		for _, v := range msg.UserProperties {
+			m.UserProperties[string(v.K)] = string(v.V)
+		}
+	}
+	b, _ := json.Marshal(m)
+	return string(b)
+}
+
+func (f *subFlags) subscriptions() ([]packets.Topic, error) {
+	if err := validateQoS(f.qos); err != nil {
+		return nil, err
+	}
+	if f.retainHandling > 2 {
+		return nil, fmt.Errorf("invalid retain handling: %d", f.retainHandling)
+	}
+	var topics []packets.Topic
+	for _, v := range f.topics {
+		topics = append(topics, packets.Topic{
+			Name: v,
+			SubOptions: packets.SubOptions{
+				Qos:               f.qos,
+				RetainHandling:    f.retainHandling,
+				NoLocal:           f.noLocal,
+				RetainAsPublished: f.retainAsPublished,
+			},
+		})
+	}
+	return topics, nil
+}
+
+func subscribe(ctx context.Context, c *client.Client, topics []packets.Topic) error {
+	suback, err := c.Subscribe(ctx, topics...)
+	if err != nil {
+		return err
+	}
+	for k, v := range suback.Payload {
+		if v >= codes.UnspecifiedError && k < len(topics) {
+			return fmt.Errorf("failed to subscribe %s, code: %d", topics[k].Name, v)
+		}
+	}
+	return nil
+}
+
+// runSub subscribes the topics and writes the received messages to out, one message per line.
+// The client reconnects automatically and subscribes again if the session is lost.
+func runSub(ctx context.Context, out io.Writer, f *subFlags) error {
+	topics, err := f.subscriptions()
+	if err != nil {
+		return err
+	}
+	opts, err := f.conn.options("")
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		mu         sync.Mutex
+		received   int
+		subscribed bool
+	)
+	opts = append(opts,
+		client.WithAutoReconnect(time.Second, 30*time.Second),
+		client.WithMessageHandler(func(c *client.Client, msg *gmqtt.Message) {
+			mu.Lock()
+			defer mu.Unlock()
+			if f.count > 0 && received >= f.count {
+				return
+			}
+			received++
+			fmt.Fprintln(out, formatMessage(msg, f.verbose))
+			if f.count > 0 && received >= f.count {
+				cancel()
+			}
+		}),
+		client.WithConnectHandler(func(c *client.Client, connack *packets.Connack) {
+			mu.Lock()
+			resubscribe := subscribed && !connack.SessionPresent
+			mu.Unlock()
+			if resubscribe {
+				go func() {
+					if err := subscribe(ctx, c, topics); err != nil && !errors.Is(err, context.Canceled) {
+						fmt.Fprintln(os.Stderr, err)
+					}
+				}()
+			}
+		}),
+		client.WithConnectionLostHandler(func(c *client.Client, err error) {
+			fmt.Fprintln(os.Stderr, "connection lost:", err)
+		}),
+	)
+	c := client.New(opts...)
+	if _, err = c.Connect(ctx); err != nil {
+		return err
+	}
+	defer c.Disconnect()
+	if err = subscribe(ctx, c, topics); err != nil {
+		return err
+	}
+	mu.Lock()
+	subscribed = true
+	mu.Unlock()
+	<-ctx.Done()
+	return nil
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/DrmagicE/gmqtt/cmd/gmqctl/command"
+	"github.com/DrmagicE/gmqtt/cmd/gmqctl/command/admin"
+	"github.com/DrmagicE/gmqtt/cmd/gmqctl/command/mqtt"
 )
 
 var (
@@ -19,6 +21,9 @@ var (
 
 func init() {
 	rootCmd.AddCommand(command.Gen)
+	rootCmd.AddCommand(mqtt.Pub, mqtt.Sub, mqtt.Bench)
+	mqtt.Sub.AddCommand(admin.SubscriptionCommands...)
+	rootCmd.AddCommand(admin.Client, admin.Publish, admin.Delayed, admin.Account)
 }
 
 func must(err error) {
@@ -0,0 +1,34 @@
+package command
+
+import (
+	"errors"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// NewDrainCommand creates a *cobra.Command object for drain command.
+func NewDrainCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "drain",
+		Short: "Drain gmqtt broker",
+		Long: "Drain stops accepting new connections, disconnects the connected clients over the drain window, " +
+			"and then stops the broker.",
+		Run: func(cmd *cobra.Command, args []string) {
+			if drainSignal == nil {
+				must(errors.New("drain is not supported on this platform"))
+			}
+			must(signalBroker(drainSignal))
+		},
+	}
+	return cmd
+}
+
+// signalBroker sends the signal to the running broker of the pid file.
+func signalBroker(sig os.Signal) error {
+	p, err := findBroker()
+	if err != nil {
+		return err
+	}
+	return p.Signal(sig)
+}
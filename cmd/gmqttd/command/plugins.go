@@ -0,0 +1,27 @@
+package command
+
+// The plugins, persistence, shared subscription strategies and topic alias managers built into gmqttd.
+
+import (
+	// The database/sql drivers for the sql sink of the archive plugin: mysql, postgres and sqlite3.
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+
+	_ "github.com/DrmagicE/gmqtt/persistence"
+	_ "github.com/DrmagicE/gmqtt/plugin/acl"
+	_ "github.com/DrmagicE/gmqtt/plugin/admin"
+	_ "github.com/DrmagicE/gmqtt/plugin/archive"
+	_ "github.com/DrmagicE/gmqtt/plugin/auth"
+	_ "github.com/DrmagicE/gmqtt/plugin/bridge"
+	_ "github.com/DrmagicE/gmqtt/plugin/cluster"
+	_ "github.com/DrmagicE/gmqtt/plugin/connlimit"
+	_ "github.com/DrmagicE/gmqtt/plugin/extauth"
+	_ "github.com/DrmagicE/gmqtt/plugin/jwt"
+	_ "github.com/DrmagicE/gmqtt/plugin/prometheus"
+	_ "github.com/DrmagicE/gmqtt/plugin/scram"
+	_ "github.com/DrmagicE/gmqtt/plugin/sys"
+	_ "github.com/DrmagicE/gmqtt/plugin/webhook"
+	_ "github.com/DrmagicE/gmqtt/sharedsub"
+	_ "github.com/DrmagicE/gmqtt/topicalias/fifo"
+)
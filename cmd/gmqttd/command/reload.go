@@ -18,23 +18,28 @@ func NewReloadCommand() *cobra.Command {
 		Use:   "reload",
 		Short: "Reload gmqtt broker",
 		Run: func(cmd *cobra.Command, args []string) {
-			var c config.Config
-			var err error
-			c, err = config.ParseConfig(ConfigFile)
-			if os.IsNotExist(err) {
-				c = config.DefaultConfig()
-			} else {
-				must(err)
-			}
-			b, err := ioutil.ReadFile(c.PidFile)
-			must(errors.Wrap(err, "read pid file error"))
-			pid, err := strconv.Atoi(string(b))
-			must(errors.Wrap(err, "read pid file error"))
-			p, err := os.FindProcess(pid)
-			must(errors.Wrap(err, "find process error"))
-			err = p.Signal(syscall.SIGHUP)
-			must(err)
+			must(signalBroker(syscall.SIGHUP))
 		},
 	}
 	return cmd
 }
+
+// findBroker finds the running broker process by the pid file.
+func findBroker() (*os.Process, error) {
+	c, err := config.ParseConfig(ConfigFile)
+	if os.IsNotExist(err) {
+		c = config.DefaultConfig()
+	} else if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadFile(c.PidFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "read pid file error")
+	}
+	pid, err := strconv.Atoi(string(b))
+	if err != nil {
+		return nil, errors.Wrap(err, "read pid file error")
+	}
+	p, err := os.FindProcess(pid)
+	return p, errors.Wrap(err, "find process error")
+}
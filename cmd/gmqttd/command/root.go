@@ -0,0 +1,25 @@
+package command
+
+import (
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+)
+
+// NewRootCmd creates the gmqttd root command with the start, reload and drain sub commands.
+// It is shared by the gmqttd binaries with different listener modules, e.g. github.com/DrmagicE/gmqtt/quic/cmd/gmqttd.
+func NewRootCmd(version string) *cobra.Command {
+	rootCmd := &cobra.Command{
+		Use:     "gmqttd",
+		Long:    "Gmqtt is a MQTT broker that fully implements MQTT V5.0 and V3.1.1 protocol",
+		Version: version,
+	}
+	d, err := homedir.Dir()
+	must(err)
+	DefaultConfigFile = d + "/gmqtt.yml"
+	rootCmd.PersistentFlags().StringVarP(&ConfigFile, "config", "c", DefaultConfigFile, "The configuration file path")
+
+	rootCmd.AddCommand(NewStartCmd())
+	rootCmd.AddCommand(NewReloadCommand())
+	rootCmd.AddCommand(NewDrainCommand())
+	return rootCmd
+}
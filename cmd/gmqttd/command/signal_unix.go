@@ -0,0 +1,11 @@
+// +build !windows
+
+package command
+
+import (
+	"os"
+	"syscall"
+)
+
+// drainSignal triggers Server.Drain.
+var drainSignal os.Signal = syscall.SIGUSR1
@@ -0,0 +1,10 @@
+// +build windows
+
+package command
+
+import (
+	"os"
+)
+
+// drainSignal is not supported on windows.
+var drainSignal os.Signal
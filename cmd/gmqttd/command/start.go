@@ -2,10 +2,7 @@ package command
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
-	"net"
-	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -40,6 +37,12 @@ func installSignal(srv server.Server) {
 	stopSignalCh := make(chan os.Signal, 1)
 	signal.Notify(stopSignalCh, os.Interrupt, syscall.SIGTERM)
 
+	// drain
+	drainSignalCh := make(chan os.Signal, 1)
+	if drainSignal != nil {
+		signal.Notify(drainSignalCh, drainSignal)
+	}
+
 	for {
 		select {
 		case <-reloadSignalCh:
@@ -48,50 +51,34 @@ func installSignal(srv server.Server) {
 			c, err = config.ParseConfig(ConfigFile)
 			if err != nil {
 				logger.Error("reload error", zap.Error(err))
-				return
+				continue
+			}
+			rs, err := srv.ApplyConfig(c)
+			if err != nil {
+				logger.Error("reload error", zap.Error(err))
+			}
+			if len(rs.RestartRequired) != 0 {
+				logger.Warn("config changes require restart", zap.Strings("fields", rs.RestartRequired))
+			}
+			if len(rs.NewConnectionsOnly) != 0 {
+				logger.Warn("config changes only apply to new connections", zap.Strings("fields", rs.NewConnectionsOnly))
 			}
-			srv.ApplyConfig(c)
 			logger.Info("gmqtt reloaded")
+		case <-drainSignalCh:
+			if err := srv.Drain(); err != nil {
+				logger.Error("drain error", zap.Error(err))
+			}
 		case <-stopSignalCh:
 			srv.Stop(context.Background())
 			return
+		case <-srv.Done():
+			// stopped by Drain, e.g. triggered by the admin API.
+			return
 		}
 	}
 
 }
 
-func GetListeners(c config.Config) (tcpListeners []net.Listener, websockets []*server.WsServer, err error) {
-	for _, v := range c.Listeners {
-		var ln net.Listener
-		if v.Websocket != nil {
-			ws := &server.WsServer{
-				Server: &http.Server{Addr: v.Address},
-				Path:   v.Websocket.Path,
-			}
-			if v.TLSOptions != nil {
-				ws.KeyFile = v.KeyFile
-				ws.CertFile = v.CertFile
-			}
-			websockets = append(websockets, ws)
-			continue
-		}
-		if v.TLSOptions != nil {
-			var cert tls.Certificate
-			cert, err = tls.LoadX509KeyPair(v.CertFile, v.KeyFile)
-			if err != nil {
-				return
-			}
-			ln, err = tls.Listen("tcp", v.Address, &tls.Config{
-				Certificates: []tls.Certificate{cert},
-			})
-		} else {
-			ln, err = net.Listen("tcp", v.Address)
-		}
-		tcpListeners = append(tcpListeners, ln)
-	}
-	return
-}
-
 // NewStartCmd creates a *cobra.Command object for start command.
 func NewStartCmd() *cobra.Command {
 	cfg := config.DefaultConfig()
@@ -119,8 +106,6 @@ func NewStartCmd() *cobra.Command {
 			pid, err := pidfile.New(c.PidFile)
 			must(err)
 			defer pid.Remove()
-			tcpListeners, websockets, err := GetListeners(c)
-			must(err)
 			l, err := c.GetLogger(c.Log)
 			must(err)
 			logger = l
@@ -129,8 +114,7 @@ func NewStartCmd() *cobra.Command {
 			}
 			s := server.New(
 				server.WithConfig(c),
-				server.WithTCPListener(tcpListeners...),
-				server.WithWebsocketServer(websockets...),
+				server.WithConfigListeners(),
 				server.WithLogger(l),
 			)
 			err = s.Init()
@@ -6,40 +6,9 @@ import (
 	_ "net/http/pprof"
 	"os"
 
-	"github.com/mitchellh/go-homedir"
-	"github.com/spf13/cobra"
-
 	"github.com/DrmagicE/gmqtt/cmd/gmqttd/command"
-	_ "github.com/DrmagicE/gmqtt/persistence"
-	_ "github.com/DrmagicE/gmqtt/plugin/prometheus"
-	_ "github.com/DrmagicE/gmqtt/topicalias/fifo"
-)
-
-var (
-	rootCmd = &cobra.Command{
-		Use:     "gmqttd",
-		Long:    "Gmqtt is a MQTT broker that fully implements MQTT V5.0 and V3.1.1 protocol",
-		Version: Version,
-	}
 )
 
-func must(err error) {
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
-}
-
-func init() {
-	d, err := homedir.Dir()
-	must(err)
-	command.DefaultConfigFile = d + "/gmqtt.yml"
-	rootCmd.PersistentFlags().StringVarP(&command.ConfigFile, "config", "c", command.DefaultConfigFile, "The configuration file path")
-
-	rootCmd.AddCommand(command.NewStartCmd())
-	rootCmd.AddCommand(command.NewReloadCommand())
-}
-
 func main() {
 	//	f, err := os.Create("cpu.profile")
 	//	if err != nil {
@@ -50,7 +19,7 @@ func main() {
 	go func() {
 		http.ListenAndServe(":6060", nil)
 	}()
-	if err := rootCmd.Execute(); err != nil {
+	if err := command.NewRootCmd(Version).Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
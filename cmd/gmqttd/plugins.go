@@ -1,7 +0,0 @@
-package main
-
-import (
-	_ "github.com/DrmagicE/gmqtt/plugin/admin"
-	_ "github.com/DrmagicE/gmqtt/plugin/auth"
-	_ "github.com/DrmagicE/gmqtt/plugin/prometheus"
-)
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"reflect"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -24,6 +25,13 @@ type Configuration interface {
 	yaml.Unmarshaler
 }
 
+// ConfigValidator is an optional interface of the Configuration, which validates the plugin configuration
+// against the whole config, e.g. to reject the mqtt settings that are not supported by the plugin.
+// It is only called for the plugins in PluginOrder.
+type ConfigValidator interface {
+	ValidateConfig(c Config) error
+}
+
 // RegisterDefaultPluginConfig registers the default configuration for the given plugin.
 func RegisterDefaultPluginConfig(name string, config Configuration) {
 	if _, ok := defaultPluginConfig[name]; ok {
@@ -47,14 +55,27 @@ func DefaultConfig() Config {
 		Plugins:           make(pluginConfig),
 		Persistence:       DefaultPersistenceConfig,
 		TopicAliasManager: DefaultTopicAliasManager,
+		Drain:             DefaultDrain,
 	}
 
 	for name, v := range defaultPluginConfig {
-		c.Plugins[name] = v
+		c.Plugins[name] = copyPluginConfig(v)
 	}
 	return c
 }
 
+// copyPluginConfig returns a shallow copy of the plugin config,
+// so that parsing the config file will not modify the registered default config and the config in use.
+func copyPluginConfig(c Configuration) Configuration {
+	v := reflect.ValueOf(c)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return c
+	}
+	cp := reflect.New(v.Elem().Type())
+	cp.Elem().Set(v.Elem())
+	return cp.Interface().(Configuration)
+}
+
 var DefaultListeners = []*ListenerConfig{
 	{
 		Address:    "0.0.0.0:1883",
@@ -64,7 +85,8 @@ var DefaultListeners = []*ListenerConfig{
 	{
 		Address: "0.0.0.0:8883",
 		Websocket: &WebsocketOptions{
-			Path: "/",
+			Path:         "/",
+			Subprotocols: DefaultWebsocketSubprotocols,
 		},
 	},
 }
@@ -116,20 +138,67 @@ type Config struct {
 	PluginOrder       []string          `yaml:"plugin_order"`
 	Persistence       Persistence       `yaml:"persistence"`
 	TopicAliasManager TopicAliasManager `yaml:"topic_alias_manager"`
+	Drain             Drain             `yaml:"drain"`
 }
 
 type TLSOptions struct {
 	CertFile string `yaml:"cert_file"`
 	KeyFile  string `yaml:"key_file"`
+	// ClientCAFile is the CA bundle to verify the client certificates.
+	ClientCAFile string `yaml:"client_ca_file"`
+	// VerifyMode is the client certificate verification mode.
+	// Possible values: none | optional | required
+	VerifyMode string `yaml:"verify_mode"`
+	// MinVersion is the minimum TLS version, empty means the Go default.
+	// Possible values: 1.0 | 1.1 | 1.2 | 1.3
+	MinVersion string `yaml:"min_version"`
+	// CipherSuites is the list of enabled cipher suite names, empty means the Go default.
+	// It is not configurable for TLS 1.3.
+	CipherSuites []string `yaml:"cipher_suites"`
+	// CertIdentity indicates whether to use the common name of the verified client certificate
+	// as the username or client id of the client.
+	// Possible values: username | client_id, empty means not to use.
+	CertIdentity string `yaml:"cert_identity"`
 }
 type ListenerConfig struct {
 	Address     string `yaml:"address"`
 	*TLSOptions `yaml:"tls"`
 	Websocket   *WebsocketOptions `yaml:"websocket"`
+	// ProxyProtocol is the PROXY protocol setting, nil means disabled.
+	ProxyProtocol *ProxyProtocolOptions `yaml:"proxy_protocol"`
+	// QUIC is the QUIC setting, the listener listens on the UDP address if it is set.
+	QUIC *QUICOptions `yaml:"quic"`
+	// UnixSocket is the permission and ownership setting of the unix domain socket listener,
+	// whose address is unix:///path/to/socket.
+	UnixSocket *UnixSocketOptions `yaml:"unix_socket"`
+}
+
+// Network returns the network of the listener, which is "udp" for QUIC, "unix" for unix domain socket or "tcp".
+// The listeners on the same address with different networks can coexist.
+func (l *ListenerConfig) Network() string {
+	if l.QUIC != nil {
+		return "udp"
+	}
+	if _, ok := UnixSocketPath(l.Address); ok {
+		return "unix"
+	}
+	return "tcp"
 }
 
 type WebsocketOptions struct {
 	Path string `yaml:"path"`
+	// AllowedOrigins is the list of the allowed Origin header values, e.g. https://dashboard.example.com.
+	// "*" matches any sequence of non-/ characters, e.g. https://*.example.com. Empty means to allow all origins.
+	AllowedOrigins []string `yaml:"allowed_origins"`
+	// Compression indicates whether to negotiate the permessage-deflate compression with the clients.
+	Compression bool `yaml:"compression"`
+	// MaxMessageSize is the maximum size in bytes of a websocket message read from the clients, 0 means unlimited.
+	MaxMessageSize int64 `yaml:"max_message_size"`
+	// Subprotocols is the list of the supported subprotocols in the order of preference.
+	Subprotocols []string `yaml:"subprotocols"`
+	// ReadBufferSize and WriteBufferSize are the I/O buffer sizes in bytes, 0 means the default size.
+	ReadBufferSize  int `yaml:"read_buffer_size"`
+	WriteBufferSize int `yaml:"write_buffer_size"`
 }
 
 func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
@@ -139,18 +208,18 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		return err
 	}
 	emptyMQTT := MQTT{}
-	if raw.MQTT == emptyMQTT {
+	if reflect.DeepEqual(raw.MQTT, emptyMQTT) {
 		raw.MQTT = DefaultMQTTConfig
 	}
 	if len(raw.Plugins) == 0 {
 		raw.Plugins = make(pluginConfig)
 		for name, v := range defaultPluginConfig {
-			raw.Plugins[name] = v
+			raw.Plugins[name] = copyPluginConfig(v)
 		}
 	} else {
 		for name, v := range raw.Plugins {
 			if v == nil {
-				raw.Plugins[name] = defaultPluginConfig[name]
+				raw.Plugins[name] = copyPluginConfig(defaultPluginConfig[name])
 			}
 		}
 	}
@@ -171,12 +240,60 @@ func (c Config) Validate() (err error) {
 	if err != nil {
 		return err
 	}
+	err = c.Drain.Validate()
+	if err != nil {
+		return err
+	}
+	listeners := make(map[string]struct{})
+	for _, l := range c.Listeners {
+		key := l.Network() + " " + l.Address
+		if _, ok := listeners[key]; ok {
+			return fmt.Errorf("duplicated %s listener %s", l.Network(), l.Address)
+		}
+		listeners[key] = struct{}{}
+		if l.TLSOptions != nil {
+			err = l.TLSOptions.Validate()
+			if err != nil {
+				return fmt.Errorf("invalid tls options of listener %s: %s", l.Address, err)
+			}
+		}
+		if l.Websocket != nil {
+			err = l.Websocket.Validate()
+			if err != nil {
+				return fmt.Errorf("invalid websocket options of listener %s: %s", l.Address, err)
+			}
+		}
+		if l.ProxyProtocol != nil {
+			err = l.ProxyProtocol.Validate()
+			if err != nil {
+				return fmt.Errorf("invalid proxy_protocol options of listener %s: %s", l.Address, err)
+			}
+		}
+		if l.QUIC != nil {
+			err = l.validateQUIC()
+			if err != nil {
+				return fmt.Errorf("invalid quic options of listener %s: %s", l.Address, err)
+			}
+		}
+		err = l.validateUnixSocket()
+		if err != nil {
+			return fmt.Errorf("invalid unix_socket options of listener %s: %s", l.Address, err)
+		}
+	}
 	for _, conf := range c.Plugins {
 		err := conf.Validate()
 		if err != nil {
 			return err
 		}
 	}
+	for _, name := range c.PluginOrder {
+		if v, ok := c.Plugins[name].(ConfigValidator); ok {
+			err := v.ValidateConfig(c)
+			if err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
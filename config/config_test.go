@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
 )
 
 func TestParseConfig(t *testing.T) {
@@ -34,3 +35,40 @@ func TestParseConfig(t *testing.T) {
 		})
 	}
 }
+
+type testPluginConfig struct {
+	Value string `yaml:"value"`
+}
+
+func (t *testPluginConfig) Validate() error {
+	return nil
+}
+
+func (t *testPluginConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type cfg testPluginConfig
+	var v = &struct {
+		Test cfg `yaml:"test_plugin"`
+	}{}
+	if err := unmarshal(v); err != nil {
+		return err
+	}
+	*t = testPluginConfig(v.Test)
+	return nil
+}
+
+func TestDefaultConfig_copyPluginConfig(t *testing.T) {
+	a := assert.New(t)
+	defaultCfg := &testPluginConfig{Value: "default"}
+	RegisterDefaultPluginConfig("test_plugin", defaultCfg)
+	defer delete(defaultPluginConfig, "test_plugin")
+
+	c1 := DefaultConfig()
+	a.Equal(defaultCfg, c1.Plugins["test_plugin"])
+	a.False(defaultCfg == c1.Plugins["test_plugin"])
+
+	c2 := DefaultConfig()
+	a.Nil(yaml.Unmarshal([]byte("plugins:\n  test_plugin:\n    value: changed\n"), &c2))
+	a.Equal("changed", c2.Plugins["test_plugin"].(*testPluginConfig).Value)
+	a.Equal("default", defaultCfg.Value)
+	a.Equal("default", c1.Plugins["test_plugin"].(*testPluginConfig).Value)
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+var (
+	// DefaultDelayedPublish is the default value of DelayedPublish
+	DefaultDelayedPublish = DelayedPublish{
+		Enable:      true,
+		MaxDelay:    24 * time.Hour,
+		MaxMessages: 100000,
+	}
+)
+
+// DelayedPublish is the config of the delayed publish.
+// The messages published to $delayed/{seconds}/{topic} are delivered to {topic} after the given seconds.
+type DelayedPublish struct {
+	// Enable indicates whether to enable the delayed publish.
+	// If disabled, the $delayed/ topics are treated as normal topics.
+	Enable bool `yaml:"enable"`
+	// MaxDelay is the maximum delay interval, 0 means unlimited.
+	MaxDelay time.Duration `yaml:"max_delay"`
+	// MaxMessages is the maximum number of pending delayed messages, 0 means unlimited.
+	MaxMessages int `yaml:"max_messages"`
+}
+
+func (d DelayedPublish) Validate() error {
+	if d.MaxDelay < 0 {
+		return fmt.Errorf("invalid max_delay: %s", d.MaxDelay)
+	}
+	if d.MaxMessages < 0 {
+		return fmt.Errorf("invalid max_messages: %d", d.MaxMessages)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	DrainCodeUseAnotherServer = "use_another_server"
+	DrainCodeServerMoved      = "server_moved"
+)
+
+// DefaultDrain is the default drain setting.
+var DefaultDrain = Drain{
+	Window:          30 * time.Second,
+	InflightTimeout: 5 * time.Second,
+	Code:            DrainCodeUseAnotherServer,
+}
+
+// Drain is the setting of draining the server, see server.Server.Drain for details.
+type Drain struct {
+	// Window is the time window over which the connected clients are disconnected, 0 means all at once.
+	Window time.Duration `yaml:"window"`
+	// InflightTimeout is the maximum time to wait for the in-flight QoS 1/2 messages of a client to be acknowledged
+	// before disconnecting it.
+	InflightTimeout time.Duration `yaml:"inflight_timeout"`
+	// Code is the reason code of the DISCONNECT packet sent to the v5 clients.
+	// Possible values: use_another_server | server_moved
+	Code string `yaml:"code"`
+	// ServerReference is the server reference property of the DISCONNECT packet sent to the v5 clients,
+	// e.g. "mqtt2.example.com:1883". Empty means not to send.
+	ServerReference string `yaml:"server_reference"`
+}
+
+func (d Drain) Validate() error {
+	if d.Window < 0 {
+		return errors.New("invalid drain.window")
+	}
+	if d.InflightTimeout < 0 {
+		return errors.New("invalid drain.inflight_timeout")
+	}
+	if d.Code != DrainCodeUseAnotherServer && d.Code != DrainCodeServerMoved {
+		return fmt.Errorf("invalid drain.code: %s", d.Code)
+	}
+	return nil
+}
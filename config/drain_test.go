@@ -0,0 +1,24 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrain_Validate(t *testing.T) {
+	a := assert.New(t)
+	d := DefaultDrain
+	a.Nil(d.Validate())
+	d.Code = DrainCodeServerMoved
+	a.Nil(d.Validate())
+	d.Code = "server_shutting_down"
+	a.NotNil(d.Validate())
+
+	d = DefaultDrain
+	d.Window = -1
+	a.NotNil(d.Validate())
+	d = DefaultDrain
+	d.InflightTimeout = -1
+	a.NotNil(d.Validate())
+}
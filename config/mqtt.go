@@ -32,6 +32,8 @@ var (
 		QueueQos0Msg:               true,
 		DeliveryMode:               OnlyOnce,
 		AllowZeroLenClientID:       true,
+		SharedSubscription:         DefaultSharedSubscription,
+		DelayedPublish:             DefaultDelayedPublish,
 	}
 )
 
@@ -53,6 +55,12 @@ type MQTT struct {
 	QueueQos0Msg               bool          `yaml:"queue_qos0_messages"`
 	DeliveryMode               string        `yaml:"delivery_mode"`
 	AllowZeroLenClientID       bool          `yaml:"allow_zero_length_clientid"`
+	// SharedSubscription is the load balancing setting of shared subscriptions.
+	SharedSubscription SharedSubscription `yaml:"shared_subscription"`
+	// RateLimit is the publish rate limiting setting.
+	RateLimit RateLimit `yaml:"rate_limit"`
+	// DelayedPublish is the setting of the messages published to $delayed/{seconds}/{topic}.
+	DelayedPublish DelayedPublish `yaml:"delayed_publish"`
 }
 
 func (c MQTT) Validate() error {
@@ -74,5 +82,14 @@ func (c MQTT) Validate() error {
 	if c.DeliveryMode != Overlap && c.DeliveryMode != OnlyOnce {
 		return fmt.Errorf("invalid delivery_mode: %s", c.DeliveryMode)
 	}
+	if c.SharedSubscription.Strategy == "" {
+		return fmt.Errorf("shared_subscription.strategy cannot be empty")
+	}
+	if err := c.RateLimit.Validate(); err != nil {
+		return fmt.Errorf("invalid rate_limit: %s", err)
+	}
+	if err := c.DelayedPublish.Validate(); err != nil {
+		return fmt.Errorf("invalid delayed_publish: %s", err)
+	}
 	return nil
 }
@@ -12,6 +12,16 @@ type PersistenceType = string
 const (
 	PersistenceTypeMemory PersistenceType = "memory"
 	PersistenceTypeRedis  PersistenceType = "redis"
+	PersistenceTypeBolt   PersistenceType = "bolt"
+)
+
+type RetainedType = string
+
+const (
+	RetainedTypeMemory RetainedType = "memory"
+	RetainedTypeRedis  RetainedType = "redis"
+	RetainedTypeFile   RetainedType = "file"
+	RetainedTypeBolt   RetainedType = "bolt"
 )
 
 var (
@@ -28,6 +38,15 @@ var (
 			MaxActive:   &defaultMaxActive,
 			IdleTimeout: 240 * time.Second,
 		},
+		Bolt: BoltPersistence{
+			Path:    "gmqtt.db",
+			Timeout: time.Second,
+		},
+		Retained: RetainedPersistence{
+			File: FileRetained{
+				Path: "gmqtt_retained.db",
+			},
+		},
 	}
 )
 
@@ -38,6 +57,37 @@ type Persistence struct {
 	Type PersistenceType `yaml:"type"`
 	// Redis is the redis configuration and must be set when Type ==  "redis".
 	Redis RedisPersistence `yaml:"redis"`
+	// Bolt is the bolt configuration and must be set when Type == "bolt".
+	Bolt BoltPersistence `yaml:"bolt"`
+	// Retained is the configuration of the retained message store.
+	Retained RetainedPersistence `yaml:"retained"`
+}
+
+// RetainedPersistence is the configuration of the retained message store.
+type RetainedPersistence struct {
+	// Type is the retained message store type, possible values: "memory", "redis", "file", "bolt".
+	// If empty, use the persistence Type as default.
+	// The "redis" and "bolt" types are only available when the persistence Type is the same.
+	Type RetainedType `yaml:"type"`
+	// File is the file configuration and must be set when Type == "file".
+	File FileRetained `yaml:"file"`
+}
+
+// FileRetained is the configuration of the file retained message store.
+type FileRetained struct {
+	// Path is the path of the file that stores the retained messages.
+	// If empty, use "gmqtt_retained.db" as default.
+	Path string `yaml:"path"`
+}
+
+// BoltPersistence is the configuration of bolt persistence.
+type BoltPersistence struct {
+	// Path is the path of the database file.
+	// If empty, use "gmqtt.db" as default.
+	Path string `yaml:"path"`
+	// Timeout is the amount of time to wait to obtain the file lock of the database file.
+	// If zero, it will wait indefinitely.
+	Timeout time.Duration `yaml:"timeout"`
 }
 
 // RedisPersistence is the configuration of redis persistence.
@@ -67,7 +117,13 @@ type RedisPersistence struct {
 }
 
 func (p *Persistence) Validate() error {
-	if p.Type != PersistenceTypeMemory && p.Type != PersistenceTypeRedis {
+	switch p.Type {
+	case PersistenceTypeMemory, PersistenceTypeRedis:
+	case PersistenceTypeBolt:
+		if p.Bolt.Path == "" {
+			return errors.New("invalid bolt path")
+		}
+	default:
 		return errors.New("invalid persistence type")
 	}
 	_, _, err := net.SplitHostPort(p.Redis.Addr)
@@ -77,5 +133,22 @@ func (p *Persistence) Validate() error {
 	if p.Redis.Database < 0 {
 		return errors.New("invalid redis database number")
 	}
+	switch p.Retained.Type {
+	case "", RetainedTypeMemory:
+	case RetainedTypeRedis:
+		if p.Type != PersistenceTypeRedis {
+			return errors.New("redis retained store requires redis persistence")
+		}
+	case RetainedTypeBolt:
+		if p.Type != PersistenceTypeBolt {
+			return errors.New("bolt retained store requires bolt persistence")
+		}
+	case RetainedTypeFile:
+		if p.Retained.File.Path == "" {
+			return errors.New("invalid retained file path")
+		}
+	default:
+		return errors.New("invalid retained type")
+	}
 	return nil
 }
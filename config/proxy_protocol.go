@@ -0,0 +1,73 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// DefaultProxyHeaderTimeout is the default timeout of reading the PROXY protocol header.
+const DefaultProxyHeaderTimeout = 5 * time.Second
+
+// ProxyProtocolOptions is the PROXY protocol setting of a listener.
+// TCP listeners parse the PROXY protocol v1 and v2 headers sent by the trusted proxies,
+// websocket listeners honor the X-Forwarded-For header sent by the trusted proxies.
+type ProxyProtocolOptions struct {
+	// TrustedProxies is the list of IP addresses or CIDRs of the trusted proxies, it must not be empty.
+	// Use 0.0.0.0/0 and ::/0 to trust all addresses.
+	// The connections from other addresses are served with their peer addresses.
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// HeaderTimeout is the timeout of reading the PROXY protocol header.
+	HeaderTimeout time.Duration `yaml:"header_timeout"`
+}
+
+func (p *ProxyProtocolOptions) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type options ProxyProtocolOptions
+	raw := options{
+		HeaderTimeout: DefaultProxyHeaderTimeout,
+	}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	*p = ProxyProtocolOptions(raw)
+	return nil
+}
+
+// Validate validates the PROXY protocol options, and return an error if it is invalid.
+func (p *ProxyProtocolOptions) Validate() error {
+	if p.HeaderTimeout <= 0 {
+		return errors.New("header_timeout must be greater than 0")
+	}
+	if len(p.TrustedProxies) == 0 {
+		return errors.New("trusted_proxies must not be empty")
+	}
+	_, err := p.TrustedNets()
+	return err
+}
+
+// TrustedNets returns the networks of the trusted proxies.
+func (p *ProxyProtocolOptions) TrustedNets() ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, v := range p.TrustedProxies {
+		if !strings.Contains(v, "/") {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy: %s", v)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy: %s", v)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
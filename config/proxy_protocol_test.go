@@ -0,0 +1,42 @@
+package config
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestProxyProtocolOptions(t *testing.T) {
+	a := assert.New(t)
+	var l ListenerConfig
+	a.Nil(yaml.Unmarshal([]byte(`
+address: ":1883"
+proxy_protocol:
+  trusted_proxies:
+    - 10.0.0.0/8
+    - 192.168.1.1
+    - "::1"
+`), &l))
+	p := l.ProxyProtocol
+	a.Equal(DefaultProxyHeaderTimeout, p.HeaderTimeout)
+	a.Nil(p.Validate())
+	nets, err := p.TrustedNets()
+	a.Nil(err)
+	a.Len(nets, 3)
+	a.True(nets[0].Contains(net.ParseIP("10.1.2.3")))
+	a.True(nets[1].Contains(net.ParseIP("192.168.1.1")))
+	a.False(nets[1].Contains(net.ParseIP("192.168.1.2")))
+	a.True(nets[2].Contains(net.ParseIP("::1")))
+
+	p.TrustedProxies = []string{"10.0.0.0/33"}
+	a.NotNil(p.Validate())
+	p.TrustedProxies = []string{"localhost"}
+	a.NotNil(p.Validate())
+	p.TrustedProxies = nil
+	a.NotNil(p.Validate())
+	p.TrustedProxies = []string{"10.0.0.0/8"}
+	p.HeaderTimeout = 0
+	a.NotNil(p.Validate())
+}
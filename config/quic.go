@@ -0,0 +1,47 @@
+package config
+
+import (
+	"errors"
+	"time"
+)
+
+// QUICOptions is the setting of the QUIC listener.
+// Each QUIC connection serves one MQTT connection on the first bidirectional stream opened by the client.
+// QUIC listeners require the tls setting, and the clients must negotiate the "mqtt" ALPN protocol.
+type QUICOptions struct {
+	// HandshakeIdleTimeout is the idle timeout before the handshake completes, 0 means the default (5s).
+	HandshakeIdleTimeout time.Duration `yaml:"handshake_idle_timeout"`
+	// MaxIdleTimeout is the maximum time without any network activity before the connection is closed,
+	// 0 means the default (30s).
+	// It should be longer than the MQTT keep alive, or the clients should enable the QUIC keep alive.
+	MaxIdleTimeout time.Duration `yaml:"max_idle_timeout"`
+	// KeepAlivePeriod is the period of sending keep-alive packets, 0 means disabled.
+	KeepAlivePeriod time.Duration `yaml:"keep_alive_period"`
+}
+
+func (q *QUICOptions) Validate() error {
+	if q.HandshakeIdleTimeout < 0 {
+		return errors.New("handshake_idle_timeout must not be negative")
+	}
+	if q.MaxIdleTimeout < 0 {
+		return errors.New("max_idle_timeout must not be negative")
+	}
+	if q.KeepAlivePeriod < 0 {
+		return errors.New("keep_alive_period must not be negative")
+	}
+	return nil
+}
+
+// validateQUIC validates the QUIC listener config.
+func (l *ListenerConfig) validateQUIC() error {
+	if l.TLSOptions == nil {
+		return errors.New("quic requires the tls setting")
+	}
+	if l.Websocket != nil {
+		return errors.New("quic can not be used with websocket")
+	}
+	if l.ProxyProtocol != nil {
+		return errors.New("quic can not be used with proxy_protocol")
+	}
+	return l.QUIC.Validate()
+}
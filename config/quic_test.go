@@ -0,0 +1,42 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListenerConfig_validateQUIC(t *testing.T) {
+	a := assert.New(t)
+	tlsOpts := &TLSOptions{CertFile: "cert.pem", KeyFile: "key.pem"}
+	l := &ListenerConfig{Address: ":14567", TLSOptions: tlsOpts, QUIC: &QUICOptions{}}
+	a.Nil(l.validateQUIC())
+
+	var tt = []*ListenerConfig{
+		{QUIC: &QUICOptions{}},
+		{TLSOptions: tlsOpts, Websocket: &WebsocketOptions{Path: "/"}, QUIC: &QUICOptions{}},
+		{TLSOptions: tlsOpts, ProxyProtocol: &ProxyProtocolOptions{}, QUIC: &QUICOptions{}},
+		{TLSOptions: tlsOpts, QUIC: &QUICOptions{MaxIdleTimeout: -1}},
+	}
+	for _, v := range tt {
+		a.NotNil(v.validateQUIC())
+	}
+}
+
+func TestConfig_Validate_listenerNetwork(t *testing.T) {
+	a := assert.New(t)
+	tlsOpts := &TLSOptions{CertFile: "cert.pem", KeyFile: "key.pem"}
+	tcp := &ListenerConfig{Address: ":1883"}
+	quic := &ListenerConfig{Address: ":1883", TLSOptions: tlsOpts, QUIC: &QUICOptions{}}
+	a.Equal("tcp", tcp.Network())
+	a.Equal("udp", quic.Network())
+	a.Equal("unix", (&ListenerConfig{Address: "unix:///tmp/gmqtt.sock"}).Network())
+
+	// the tcp and QUIC listeners can listen on the same address.
+	c := DefaultConfig()
+	c.Listeners = []*ListenerConfig{tcp, quic}
+	a.Nil(c.Validate())
+
+	c.Listeners = []*ListenerConfig{tcp, {Address: ":1883", Websocket: &WebsocketOptions{Path: "/"}}}
+	a.EqualError(c.Validate(), "duplicated tcp listener :1883")
+}
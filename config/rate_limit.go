@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RateLimit is the config of the publish rate limiting.
+// The limits are token buckets, a message is allowed only if all the matched limits are not exceeded.
+type RateLimit struct {
+	// Client is the limit for each client. It can be overridden by server.AuthOptions.RateLimit.
+	Client RateLimitRule `yaml:"client"`
+	// Username is the limit shared by all clients with the same username.
+	// The clients without username are not limited.
+	Username RateLimitRule `yaml:"username"`
+	// Topics are the limits shared by all clients publishing to the topics with the given prefixes.
+	Topics []TopicRateLimit `yaml:"topics"`
+	// Backpressure indicates whether to stop reading from the client until the message is allowed,
+	// instead of rejecting the message.
+	Backpressure bool `yaml:"backpressure"`
+	// V3AckAndDrop indicates whether to acknowledge and drop the rejected messages from v3 clients,
+	// instead of disconnecting them. v3 does not support negative acknowledgements,
+	// so the client will not know that the message is dropped.
+	V3AckAndDrop bool `yaml:"v3_ack_and_drop"`
+}
+
+// RateLimitRule is a pair of token buckets that limit the publishing messages and the payload bytes.
+type RateLimitRule struct {
+	// MessageRate is the number of messages allowed per second, 0 means unlimited.
+	MessageRate float64 `yaml:"message_rate"`
+	// MessageBurst is the maximum number of messages allowed at once, default to MessageRate.
+	MessageBurst int `yaml:"message_burst"`
+	// ByteRate is the number of payload bytes allowed per second, 0 means unlimited.
+	ByteRate float64 `yaml:"byte_rate"`
+	// ByteBurst is the maximum number of payload bytes allowed at once, default to ByteRate.
+	ByteBurst int `yaml:"byte_burst"`
+}
+
+// TopicRateLimit is the limit for the topics with the given prefix.
+type TopicRateLimit struct {
+	// Prefix is the topic name prefix, such as "sensors/".
+	Prefix        string `yaml:"prefix"`
+	RateLimitRule `yaml:",inline"`
+}
+
+func (r RateLimitRule) Validate() error {
+	if r.MessageRate < 0 || r.MessageBurst < 0 || r.ByteRate < 0 || r.ByteBurst < 0 {
+		return fmt.Errorf("rate and burst cannot be negative")
+	}
+	return nil
+}
+
+func (r RateLimit) Validate() error {
+	if err := r.Client.Validate(); err != nil {
+		return fmt.Errorf("invalid client rate limit: %s", err)
+	}
+	if err := r.Username.Validate(); err != nil {
+		return fmt.Errorf("invalid username rate limit: %s", err)
+	}
+	for _, v := range r.Topics {
+		if v.Prefix == "" || strings.ContainsAny(v.Prefix, "#+") {
+			return fmt.Errorf("invalid topic rate limit prefix: %s", v.Prefix)
+		}
+		if err := v.RateLimitRule.Validate(); err != nil {
+			return fmt.Errorf("invalid topic rate limit of %s: %s", v.Prefix, err)
+		}
+	}
+	return nil
+}
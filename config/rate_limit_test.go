@@ -0,0 +1,27 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit_Validate(t *testing.T) {
+	a := assert.New(t)
+	r := RateLimit{
+		Client: RateLimitRule{MessageRate: 10, ByteRate: 1024},
+		Topics: []TopicRateLimit{
+			{Prefix: "sensors/", RateLimitRule: RateLimitRule{MessageRate: 100}},
+		},
+	}
+	a.Nil(r.Validate())
+
+	r.Topics[0].Prefix = "sensors/#"
+	a.NotNil(r.Validate())
+	r.Topics[0].Prefix = ""
+	a.NotNil(r.Validate())
+	r.Topics = nil
+
+	r.Username.ByteBurst = -1
+	a.NotNil(r.Validate())
+}
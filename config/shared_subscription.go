@@ -0,0 +1,39 @@
+package config
+
+type SharedSubStrategy = string
+
+const (
+	SharedSubStrategyRandom          SharedSubStrategy = "random"
+	SharedSubStrategyRoundRobin      SharedSubStrategy = "round_robin"
+	SharedSubStrategySticky          SharedSubStrategy = "sticky"
+	SharedSubStrategyLeastQueue      SharedSubStrategy = "least_queue"
+	SharedSubStrategyPreferConnected SharedSubStrategy = "prefer_connected"
+)
+
+var (
+	// DefaultSharedSubscription is the default value of SharedSubscription
+	DefaultSharedSubscription = SharedSubscription{
+		Strategy: SharedSubStrategyRandom,
+	}
+)
+
+// SharedSubscription is the config of the shared subscription load balancing.
+type SharedSubscription struct {
+	// Strategy is the default load balancing strategy for all share names.
+	// Possible values: random | round_robin | sticky | least_queue | prefer_connected,
+	// or the name of any strategy registered by server.RegisterSharedSubBalancerFactory.
+	Strategy SharedSubStrategy `yaml:"strategy"`
+	// StickyUserProperty is the user property key that the sticky strategy hashes on.
+	// If empty or the message does not carry the property, the topic name is used.
+	StickyUserProperty string `yaml:"sticky_user_property"`
+	// ShareNames overrides the strategy for the given share names, key by share name.
+	ShareNames map[string]SharedSubStrategy `yaml:"share_names"`
+}
+
+// GetStrategy returns the strategy for the given share name.
+func (s SharedSubscription) GetStrategy(shareName string) SharedSubStrategy {
+	if st, ok := s.ShareNames[shareName]; ok && st != "" {
+		return st
+	}
+	return s.Strategy
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+const (
+	VerifyModeNone     = "none"
+	VerifyModeOptional = "optional"
+	VerifyModeRequired = "required"
+)
+
+const (
+	CertIdentityUsername = "username"
+	CertIdentityClientID = "client_id"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func cipherSuiteID(name string) (uint16, bool) {
+	for _, v := range tls.CipherSuites() {
+		if v.Name == name {
+			return v.ID, true
+		}
+	}
+	for _, v := range tls.InsecureCipherSuites() {
+		if v.Name == name {
+			return v.ID, true
+		}
+	}
+	return 0, false
+}
+
+// Validate validates the TLS options, and return an error if it is invalid.
+func (t *TLSOptions) Validate() error {
+	if t.CertFile == "" || t.KeyFile == "" {
+		return errors.New("cert_file and key_file must be set")
+	}
+	switch t.VerifyMode {
+	case "", VerifyModeNone:
+	case VerifyModeOptional, VerifyModeRequired:
+		if t.ClientCAFile == "" {
+			return fmt.Errorf("client_ca_file must be set when verify_mode is %s", t.VerifyMode)
+		}
+	default:
+		return fmt.Errorf("invalid verify_mode: %s", t.VerifyMode)
+	}
+	if t.MinVersion != "" {
+		if _, ok := tlsVersions[t.MinVersion]; !ok {
+			return fmt.Errorf("invalid min_version: %s", t.MinVersion)
+		}
+	}
+	for _, v := range t.CipherSuites {
+		if _, ok := cipherSuiteID(v); !ok {
+			return fmt.Errorf("invalid cipher suite: %s", v)
+		}
+	}
+	switch t.CertIdentity {
+	case "":
+	case CertIdentityUsername, CertIdentityClientID:
+		if t.VerifyMode == "" || t.VerifyMode == VerifyModeNone {
+			return errors.New("cert_identity requires the client certificate verification")
+		}
+	default:
+		return fmt.Errorf("invalid cert_identity: %s", t.CertIdentity)
+	}
+	return nil
+}
+
+// TLSConfig returns the *tls.Config for the listener.
+func (t *TLSOptions) TLSConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	c := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tlsVersions[t.MinVersion],
+	}
+	for _, v := range t.CipherSuites {
+		id, _ := cipherSuiteID(v)
+		c.CipherSuites = append(c.CipherSuites, id)
+	}
+	if t.ClientCAFile != "" {
+		b, err := ioutil.ReadFile(t.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		c.ClientCAs = x509.NewCertPool()
+		if !c.ClientCAs.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no valid certificates found in %s", t.ClientCAFile)
+		}
+	}
+	switch t.VerifyMode {
+	case VerifyModeOptional:
+		c.ClientAuth = tls.VerifyClientCertIfGiven
+	case VerifyModeRequired:
+		c.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return c, nil
+}
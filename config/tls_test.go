@@ -0,0 +1,117 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeSelfSignedCert writes a self-signed certificate and its private key to dir.
+func writeSelfSignedCert(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "gmqtt"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = path.Join(dir, "cert.pem")
+	keyFile = path.Join(dir, "key.pem")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestTLSOptions_Validate(t *testing.T) {
+	var tt = []struct {
+		name   string
+		opts   TLSOptions
+		hasErr bool
+	}{
+		{name: "cert_only", opts: TLSOptions{CertFile: "cert", KeyFile: "key"}},
+		{name: "missing_key", opts: TLSOptions{CertFile: "cert"}, hasErr: true},
+		{name: "required", opts: TLSOptions{CertFile: "cert", KeyFile: "key", ClientCAFile: "ca", VerifyMode: VerifyModeRequired, CertIdentity: CertIdentityUsername}},
+		{name: "missing_client_ca", opts: TLSOptions{CertFile: "cert", KeyFile: "key", VerifyMode: VerifyModeOptional}, hasErr: true},
+		{name: "invalid_verify_mode", opts: TLSOptions{CertFile: "cert", KeyFile: "key", VerifyMode: "always"}, hasErr: true},
+		{name: "cert_identity_without_verify", opts: TLSOptions{CertFile: "cert", KeyFile: "key", CertIdentity: CertIdentityClientID}, hasErr: true},
+		{name: "invalid_cert_identity", opts: TLSOptions{CertFile: "cert", KeyFile: "key", ClientCAFile: "ca", VerifyMode: VerifyModeRequired, CertIdentity: "email"}, hasErr: true},
+		{name: "min_version", opts: TLSOptions{CertFile: "cert", KeyFile: "key", MinVersion: "1.2"}},
+		{name: "invalid_min_version", opts: TLSOptions{CertFile: "cert", KeyFile: "key", MinVersion: "1.4"}, hasErr: true},
+		{name: "cipher_suites", opts: TLSOptions{CertFile: "cert", KeyFile: "key", CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"}}},
+		{name: "invalid_cipher_suites", opts: TLSOptions{CertFile: "cert", KeyFile: "key", CipherSuites: []string{"TLS_NULL"}}, hasErr: true},
+	}
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			a := assert.New(t)
+			err := v.opts.Validate()
+			if v.hasErr {
+				a.NotNil(err)
+			} else {
+				a.Nil(err)
+			}
+		})
+	}
+}
+
+func TestTLSOptions_TLSConfig(t *testing.T) {
+	a := assert.New(t)
+	dir, err := ioutil.TempDir("", "gmqtt_tls")
+	a.Nil(err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeSelfSignedCert(t, dir)
+
+	opts := &TLSOptions{
+		CertFile:     certFile,
+		KeyFile:      keyFile,
+		ClientCAFile: certFile,
+		VerifyMode:   VerifyModeOptional,
+		MinVersion:   "1.2",
+		CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"},
+	}
+	c, err := opts.TLSConfig()
+	a.Nil(err)
+	a.Len(c.Certificates, 1)
+	a.NotNil(c.ClientCAs)
+	a.Equal(tls.VerifyClientCertIfGiven, c.ClientAuth)
+	a.EqualValues(tls.VersionTLS12, c.MinVersion)
+	a.Equal([]uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}, c.CipherSuites)
+
+	opts.VerifyMode = VerifyModeRequired
+	c, err = opts.TLSConfig()
+	a.Nil(err)
+	a.Equal(tls.RequireAndVerifyClientCert, c.ClientAuth)
+
+	opts.ClientCAFile = keyFile
+	_, err = opts.TLSConfig()
+	a.NotNil(err)
+}
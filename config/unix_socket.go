@@ -0,0 +1,74 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// UnixAddressPrefix is the prefix of the unix domain socket listener address, e.g. unix:///run/gmqtt.sock.
+const UnixAddressPrefix = "unix://"
+
+// UnixSocketPath returns the socket path of the unix domain socket listener address.
+// The second return value is false if the address is not a unix domain socket address.
+func UnixSocketPath(address string) (string, bool) {
+	if !strings.HasPrefix(address, UnixAddressPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(address, UnixAddressPrefix), true
+}
+
+// UnixSocketOptions is the permission and ownership setting of the unix domain socket file.
+type UnixSocketOptions struct {
+	// Mode is the file mode of the socket in octal, e.g. "0660", empty means the default mode which depends on umask.
+	Mode string `yaml:"mode"`
+	// User is the user name or uid of the socket owner, empty means not to change.
+	User string `yaml:"user"`
+	// Group is the group name or gid of the socket group, empty means not to change.
+	Group string `yaml:"group"`
+}
+
+// FileMode returns the file mode of the socket, 0 means the default mode.
+func (u *UnixSocketOptions) FileMode() (os.FileMode, error) {
+	if u.Mode == "" {
+		return 0, nil
+	}
+	m, err := strconv.ParseUint(u.Mode, 8, 32)
+	if err != nil || m == 0 || m > 0777 {
+		return 0, errors.New("invalid mode: " + u.Mode)
+	}
+	return os.FileMode(m), nil
+}
+
+func (u *UnixSocketOptions) Validate() error {
+	_, err := u.FileMode()
+	return err
+}
+
+// validateUnixSocket validates the unix domain socket listener config.
+func (l *ListenerConfig) validateUnixSocket() error {
+	path, ok := UnixSocketPath(l.Address)
+	if !ok {
+		if l.UnixSocket != nil {
+			return errors.New("unix_socket requires the unix:// address")
+		}
+		return nil
+	}
+	if path == "" {
+		return errors.New("empty socket path")
+	}
+	if l.TLSOptions != nil {
+		return errors.New("unix domain socket can not be used with tls")
+	}
+	if l.QUIC != nil {
+		return errors.New("unix domain socket can not be used with quic")
+	}
+	if l.ProxyProtocol != nil {
+		return errors.New("unix domain socket can not be used with proxy_protocol")
+	}
+	if l.UnixSocket != nil {
+		return l.UnixSocket.Validate()
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnixSocketOptions_FileMode(t *testing.T) {
+	a := assert.New(t)
+	m, err := (&UnixSocketOptions{Mode: "0660"}).FileMode()
+	a.Nil(err)
+	a.Equal(os.FileMode(0660), m)
+	m, err = (&UnixSocketOptions{}).FileMode()
+	a.Nil(err)
+	a.Equal(os.FileMode(0), m)
+	for _, v := range []string{"660a", "0", "01000", "-1"} {
+		_, err = (&UnixSocketOptions{Mode: v}).FileMode()
+		a.NotNil(err, v)
+	}
+}
+
+func TestListenerConfig_validateUnixSocket(t *testing.T) {
+	a := assert.New(t)
+	a.Nil((&ListenerConfig{Address: ":1883"}).validateUnixSocket())
+	a.Nil((&ListenerConfig{Address: "unix:///run/gmqtt.sock", UnixSocket: &UnixSocketOptions{Mode: "0660"}}).validateUnixSocket())
+
+	var tt = []*ListenerConfig{
+		{Address: ":1883", UnixSocket: &UnixSocketOptions{}},
+		{Address: "unix://"},
+		{Address: "unix:///run/gmqtt.sock", TLSOptions: &TLSOptions{}},
+		{Address: "unix:///run/gmqtt.sock", QUIC: &QUICOptions{}},
+		{Address: "unix:///run/gmqtt.sock", ProxyProtocol: &ProxyProtocolOptions{}},
+		{Address: "unix:///run/gmqtt.sock", UnixSocket: &UnixSocketOptions{Mode: "rw"}},
+	}
+	for _, v := range tt {
+		a.NotNil(v.validateUnixSocket(), v.Address)
+	}
+}
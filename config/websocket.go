@@ -0,0 +1,47 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// DefaultWebsocketSubprotocols is the default subprotocols of the websocket listeners, see MQTT v5 section 6.
+var DefaultWebsocketSubprotocols = []string{"mqtt", "mqttv3.1"}
+
+func (w *WebsocketOptions) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type options WebsocketOptions
+	raw := options{
+		Subprotocols: DefaultWebsocketSubprotocols,
+	}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	*w = WebsocketOptions(raw)
+	return nil
+}
+
+// Validate validates the websocket options, and return an error if it is invalid.
+func (w *WebsocketOptions) Validate() error {
+	if w.Path == "" || w.Path[0] != '/' {
+		return errors.New("path must start with /")
+	}
+	if w.MaxMessageSize < 0 {
+		return errors.New("max_message_size must not be negative")
+	}
+	if w.ReadBufferSize < 0 || w.WriteBufferSize < 0 {
+		return errors.New("read_buffer_size and write_buffer_size must not be negative")
+	}
+	for _, v := range w.AllowedOrigins {
+		if _, err := path.Match(v, ""); v == "" || err != nil {
+			return fmt.Errorf("invalid allowed origin: %s", v)
+		}
+	}
+	for _, v := range w.Subprotocols {
+		if v == "" || strings.ContainsAny(v, ", ") {
+			return fmt.Errorf("invalid subprotocol: %s", v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestWebsocketOptions_UnmarshalYAML(t *testing.T) {
+	a := assert.New(t)
+	w := &WebsocketOptions{}
+	a.Nil(yaml.Unmarshal([]byte("path: /mqtt\nallowed_origins: [\"https://*.example.com\"]\ncompression: true\n"), w))
+	a.Equal(&WebsocketOptions{
+		Path:           "/mqtt",
+		AllowedOrigins: []string{"https://*.example.com"},
+		Compression:    true,
+		Subprotocols:   DefaultWebsocketSubprotocols,
+	}, w)
+	a.Nil(w.Validate())
+}
+
+func TestWebsocketOptions_Validate(t *testing.T) {
+	a := assert.New(t)
+	var tt = []*WebsocketOptions{
+		{Path: ""},
+		{Path: "mqtt"},
+		{Path: "/", MaxMessageSize: -1},
+		{Path: "/", ReadBufferSize: -1},
+		{Path: "/", AllowedOrigins: []string{""}},
+		{Path: "/", AllowedOrigins: []string{"https://[a.example.com"}},
+		{Path: "/", Subprotocols: []string{"mqtt, mqttv3.1"}},
+	}
+	for _, v := range tt {
+		a.NotNil(v.Validate(), v)
+	}
+}
@@ -0,0 +1,85 @@
+package persistence
+
+import (
+	"go.etcd.io/bbolt"
+
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/persistence/delayed"
+	bolt_delayed "github.com/DrmagicE/gmqtt/persistence/delayed/bolt"
+	"github.com/DrmagicE/gmqtt/persistence/queue"
+	bolt_queue "github.com/DrmagicE/gmqtt/persistence/queue/bolt"
+	"github.com/DrmagicE/gmqtt/persistence/session"
+	bolt_sess "github.com/DrmagicE/gmqtt/persistence/session/bolt"
+	"github.com/DrmagicE/gmqtt/persistence/subscription"
+	bolt_sub "github.com/DrmagicE/gmqtt/persistence/subscription/bolt"
+	"github.com/DrmagicE/gmqtt/persistence/unack"
+	bolt_unack "github.com/DrmagicE/gmqtt/persistence/unack/bolt"
+	"github.com/DrmagicE/gmqtt/retained"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func init() {
+	server.RegisterPersistenceFactory("bolt", NewBolt)
+}
+
+// NewBolt returns the bolt persistence which stores all data in a single local database file.
+func NewBolt(config config.Config, hooks server.Hooks) (server.Persistence, error) {
+	return &bolt{
+		onMsgDropped: hooks.OnMsgDropped,
+		config:       config,
+	}, nil
+}
+
+type bolt struct {
+	db           *bbolt.DB
+	config       config.Config
+	onMsgDropped server.OnMsgDropped
+	retained     retained.Store
+}
+
+func (b *bolt) Open() error {
+	var err error
+	b.db, err = bbolt.Open(b.config.Persistence.Bolt.Path, 0600, &bbolt.Options{
+		Timeout: b.config.Persistence.Bolt.Timeout,
+	})
+	return err
+}
+
+func (b *bolt) NewQueueStore(config config.Config, clientID string) (queue.Store, error) {
+	return bolt_queue.New(bolt_queue.Options{
+		MaxQueuedMsg: config.MQTT.MaxQueuedMsg,
+		ClientID:     clientID,
+		DropHandler:  b.onMsgDropped,
+		DB:           b.db,
+	})
+}
+
+func (b *bolt) NewSubscriptionStore(config config.Config) (subscription.Store, error) {
+	return bolt_sub.New(b.db)
+}
+
+func (b *bolt) NewSessionStore(config config.Config) (session.Store, error) {
+	return bolt_sess.New(b.db)
+}
+
+func (b *bolt) NewUnackStore(config config.Config, clientID string) (unack.Store, error) {
+	return bolt_unack.New(bolt_unack.Options{
+		ClientID: clientID,
+		DB:       b.db,
+	}), nil
+}
+
+func (b *bolt) NewRetainedStore(config config.Config) (retained.Store, error) {
+	var err error
+	b.retained, err = newRetainedStore(config, nil, b.db)
+	return b.retained, err
+}
+
+func (b *bolt) NewDelayedStore(config config.Config) (delayed.Store, error) {
+	return bolt_delayed.New(b.db)
+}
+
+func (b *bolt) Close() error {
+	_ = closeRetainedStore(b.retained)
+	return b.db.Close()
+}
@@ -0,0 +1,168 @@
+package persistence
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/config"
+	delayed_test "github.com/DrmagicE/gmqtt/persistence/delayed/test"
+	queue_test "github.com/DrmagicE/gmqtt/persistence/queue/test"
+	sess_test "github.com/DrmagicE/gmqtt/persistence/session/test"
+	sub_test "github.com/DrmagicE/gmqtt/persistence/subscription/test"
+	unack_test "github.com/DrmagicE/gmqtt/persistence/unack/test"
+	"github.com/DrmagicE/gmqtt/retained"
+	retained_test "github.com/DrmagicE/gmqtt/retained/test"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+type BoltSuite struct {
+	suite.Suite
+	dir string
+	p   server.Persistence
+}
+
+func boltConfig(path string) config.Config {
+	return config.Config{
+		Persistence: config.Persistence{
+			Type: config.PersistenceTypeBolt,
+			Bolt: config.BoltPersistence{
+				Path:    path,
+				Timeout: time.Second,
+			},
+		},
+	}
+}
+
+func openBolt(t *testing.T, path string) server.Persistence {
+	p, err := NewBolt(boltConfig(path), queue_test.TestHooks)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if err = p.Open(); err != nil {
+		t.Fatal(err.Error())
+	}
+	return p
+}
+
+func (s *BoltSuite) SetupTest() {
+	var err error
+	s.dir, err = ioutil.TempDir("", "gmqtt_bolt")
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	s.p = openBolt(s.T(), filepath.Join(s.dir, "gmqtt.db"))
+}
+
+func (s *BoltSuite) TearDownTest() {
+	_ = s.p.Close()
+	_ = os.RemoveAll(s.dir)
+}
+
+func (s *BoltSuite) TestQueue() {
+	a := assert.New(s.T())
+	qs, err := s.p.NewQueueStore(queue_test.TestServerConfig, queue_test.TestClientID)
+	a.Nil(err)
+	queue_test.TestQueue(s.T(), qs)
+}
+
+func (s *BoltSuite) TestSubscription() {
+	a := assert.New(s.T())
+	st, err := s.p.NewSubscriptionStore(config.Config{})
+	a.Nil(err)
+	sub_test.TestSuite(s.T(), st)
+}
+
+func (s *BoltSuite) TestSession() {
+	a := assert.New(s.T())
+	st, err := s.p.NewSessionStore(config.Config{})
+	a.Nil(err)
+	sess_test.TestSuite(s.T(), st)
+}
+
+func (s *BoltSuite) TestDelayed() {
+	a := assert.New(s.T())
+	st, err := s.p.(server.DelayedPersistence).NewDelayedStore(config.Config{})
+	a.Nil(err)
+	delayed_test.TestSuite(s.T(), st)
+}
+
+func (s *BoltSuite) TestUnack() {
+	a := assert.New(s.T())
+	st, err := s.p.NewUnackStore(unack_test.TestServerConfig, unack_test.TestClientID)
+	a.Nil(err)
+	unack_test.TestSuite(s.T(), st)
+}
+
+func (s *BoltSuite) TestRetained() {
+	a := assert.New(s.T())
+	st, err := s.p.(server.RetainedPersistence).NewRetainedStore(boltConfig(""))
+	a.Nil(err)
+	retained_test.TestSuite(s.T(), st)
+}
+
+func (s *BoltSuite) TestRetainedPersistence() {
+	path := filepath.Join(s.dir, "gmqtt.db")
+	retained_test.TestPersistence(s.T(), func() retained.Store {
+		_ = s.p.Close()
+		s.p = openBolt(s.T(), path)
+		st, err := s.p.(server.RetainedPersistence).NewRetainedStore(boltConfig(path))
+		if err != nil {
+			s.T().Fatal(err)
+		}
+		return st
+	})
+}
+
+// TestReopen tests whether the sessions and subscriptions can be restored after restarting.
+func (s *BoltSuite) TestReopen() {
+	a := assert.New(s.T())
+	sess := &gmqtt.Session{
+		ClientID:       "client",
+		ConnectedAt:    time.Unix(1, 0),
+		ExpiryInterval: 10,
+	}
+	sessStore, err := s.p.NewSessionStore(config.Config{})
+	a.Nil(err)
+	a.Nil(sessStore.Set(sess))
+	a.Nil(sessStore.SetSessionExpiry("client", 20))
+	subStore, err := s.p.NewSubscriptionStore(config.Config{})
+	a.Nil(err)
+	_, err = subStore.Subscribe("client", &gmqtt.Subscription{
+		TopicFilter: "a/b",
+		QoS:         1,
+	}, &gmqtt.Subscription{
+		ShareName:   "share",
+		TopicFilter: "a/#",
+	})
+	a.Nil(err)
+	a.Nil(subStore.Unsubscribe("client", "a/b"))
+
+	a.Nil(s.p.Close())
+	s.p = openBolt(s.T(), filepath.Join(s.dir, "gmqtt.db"))
+
+	sessStore, err = s.p.NewSessionStore(config.Config{})
+	a.Nil(err)
+	rs, err := sessStore.Get("client")
+	a.Nil(err)
+	sess.ExpiryInterval = 20
+	a.Equal(sess, rs)
+	rs, err = sessStore.Get("not_exist")
+	a.Nil(err)
+	a.Nil(rs)
+
+	subStore, err = s.p.NewSubscriptionStore(config.Config{})
+	a.Nil(err)
+	a.Nil(subStore.Init([]string{"client"}))
+	a.Equal(uint64(1), subStore.GetStats().SubscriptionsCurrent)
+}
+
+func TestBolt(t *testing.T) {
+	suite.Run(t, &BoltSuite{})
+}
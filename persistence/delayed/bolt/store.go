@@ -0,0 +1,58 @@
+package bolt
+
+import (
+	"go.etcd.io/bbolt"
+
+	"github.com/DrmagicE/gmqtt/persistence/delayed"
+	"github.com/DrmagicE/gmqtt/persistence/encoding"
+)
+
+// delayedBucket is the bucket that stores all delayed messages, the key is the message id.
+var delayedBucket = []byte("delayed")
+
+var _ delayed.Store = (*Store)(nil)
+
+type Store struct {
+	db *bbolt.DB
+}
+
+func New(db *bbolt.DB) (*Store, error) {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(delayedBucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{
+		db: db,
+	}, nil
+}
+
+func (s *Store) Add(msg *delayed.Message) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(delayedBucket).Put([]byte(msg.ID), encoding.EncodeDelayedMessage(msg))
+	})
+}
+
+func (s *Store) Remove(id string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(delayedBucket).Delete([]byte(id))
+	})
+}
+
+func (s *Store) Iterate(fn delayed.IterateFn) error {
+	return s.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(delayedBucket).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			msg, err := encoding.DecodeDelayedMessage(v)
+			if err != nil {
+				return err
+			}
+			if !fn(msg) {
+				return nil
+			}
+		}
+		return nil
+	})
+}
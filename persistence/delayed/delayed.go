@@ -0,0 +1,35 @@
+package delayed
+
+import (
+	"time"
+
+	"github.com/DrmagicE/gmqtt"
+)
+
+// Message is a message published to $delayed/{seconds}/{topic} which is waiting to be delivered.
+type Message struct {
+	// ID is the unique identifier of the delayed message.
+	ID string
+	// ClientID is the client id of the publisher.
+	ClientID string
+	// Message is the message to be delivered, the topic is the real topic without the $delayed/{seconds}/ prefix.
+	Message *gmqtt.Message
+	// PublishedAt is the time when the message is received.
+	PublishedAt time.Time
+	// DeliverAt is the time when the message should be delivered.
+	DeliverAt time.Time
+}
+
+// IterateFn is the callback function used by Iterate()
+// Return false means to stop the iteration.
+type IterateFn func(msg *Message) bool
+
+// Store is the durable storage of the pending delayed messages.
+type Store interface {
+	// Add adds the delayed message into the store.
+	Add(msg *Message) error
+	// Remove removes the delayed message for the given id.
+	Remove(id string) error
+	// Iterate iterates all delayed messages in the store. The iteration order is undefined.
+	Iterate(fn IterateFn) error
+}
@@ -0,0 +1,46 @@
+package mem
+
+import (
+	"sync"
+
+	"github.com/DrmagicE/gmqtt/persistence/delayed"
+)
+
+var _ delayed.Store = (*Store)(nil)
+
+func New() *Store {
+	return &Store{
+		mu:   sync.Mutex{},
+		msgs: make(map[string]*delayed.Message),
+	}
+}
+
+type Store struct {
+	mu   sync.Mutex
+	msgs map[string]*delayed.Message
+}
+
+func (s *Store) Add(msg *delayed.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.msgs[msg.ID] = msg
+	return nil
+}
+
+func (s *Store) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.msgs, id)
+	return nil
+}
+
+func (s *Store) Iterate(fn delayed.IterateFn) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, v := range s.msgs {
+		if !fn(v) {
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package redis
+
+import (
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/DrmagicE/gmqtt/persistence/delayed"
+	"github.com/DrmagicE/gmqtt/persistence/encoding"
+)
+
+// delayedKey is the hash that stores all delayed messages, the field is the message id.
+const delayedKey = "delayed"
+
+var _ delayed.Store = (*Store)(nil)
+
+type Store struct {
+	pool *redis.Pool
+}
+
+func New(pool *redis.Pool) *Store {
+	return &Store{
+		pool: pool,
+	}
+}
+
+func (s *Store) Add(msg *delayed.Message) error {
+	c := s.pool.Get()
+	defer c.Close()
+	_, err := c.Do("hset", delayedKey, msg.ID, encoding.EncodeDelayedMessage(msg))
+	return err
+}
+
+func (s *Store) Remove(id string) error {
+	c := s.pool.Get()
+	defer c.Close()
+	_, err := c.Do("hdel", delayedKey, id)
+	return err
+}
+
+func (s *Store) Iterate(fn delayed.IterateFn) error {
+	c := s.pool.Get()
+	defer c.Close()
+	iter := 0
+	for {
+		arr, err := redis.Values(c.Do("HSCAN", delayedKey, iter))
+		if err != nil {
+			return err
+		}
+		iter, err = redis.Int(arr[0], nil)
+		if err != nil {
+			return err
+		}
+		kv, err := redis.ByteSlices(arr[1], nil)
+		if err != nil {
+			return err
+		}
+		// the reply is a flat list of field and value pairs.
+		for i := 1; i < len(kv); i += 2 {
+			msg, err := encoding.DecodeDelayedMessage(kv[i])
+			if err != nil {
+				return err
+			}
+			if !fn(msg) {
+				return nil
+			}
+		}
+		if iter == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/persistence/delayed"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+func TestSuite(t *testing.T, store delayed.Store) {
+	a := assert.New(t)
+	var tt = []*delayed.Message{
+		{
+			ID:       "id1",
+			ClientID: "client",
+			Message: &gmqtt.Message{
+				QoS:             packets.Qos1,
+				Retained:        true,
+				Topic:           "topicA",
+				Payload:         []byte("abc"),
+				ContentType:     "text/plain",
+				CorrelationData: []byte("cd"),
+				MessageExpiry:   10,
+				ResponseTopic:   "response",
+				UserProperties: []packets.UserProperty{
+					{K: []byte("K"), V: []byte("V")},
+				},
+			},
+			PublishedAt: time.Unix(1, 0),
+			DeliverAt:   time.Unix(31, 0),
+		}, {
+			ID:       "id2",
+			ClientID: "client2",
+			Message: &gmqtt.Message{
+				Topic:   "topicB",
+				Payload: make([]byte, 70000),
+			},
+			PublishedAt: time.Unix(2, 0),
+			DeliverAt:   time.Unix(3, 0),
+		},
+	}
+	for _, v := range tt {
+		a.Nil(store.Add(v))
+	}
+	var msgs []*delayed.Message
+	iterate := func() {
+		msgs = nil
+		a.Nil(store.Iterate(func(msg *delayed.Message) bool {
+			msgs = append(msgs, msg)
+			return true
+		}))
+	}
+	iterate()
+	a.ElementsMatch(tt, msgs)
+
+	a.Nil(store.Remove("id1"))
+	a.Nil(store.Remove("not_exist"))
+	iterate()
+	a.Equal(tt[1:], msgs)
+
+	a.Nil(store.Remove("id2"))
+	iterate()
+	a.Len(msgs, 0)
+}
@@ -0,0 +1,51 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/DrmagicE/gmqtt/persistence/delayed"
+)
+
+// EncodeDelayedMessage encodes the delayed message into bytes.
+// The message is encoded by EncodeRetainedMessage, so that the payload larger than 65535 bytes can be encoded.
+func EncodeDelayedMessage(msg *delayed.Message) []byte {
+	b := &bytes.Buffer{}
+	WriteString(b, []byte(msg.ID))
+	WriteString(b, []byte(msg.ClientID))
+	t := make([]byte, 8)
+	binary.BigEndian.PutUint64(t, uint64(msg.PublishedAt.UnixNano()))
+	b.Write(t)
+	binary.BigEndian.PutUint64(t, uint64(msg.DeliverAt.UnixNano()))
+	b.Write(t)
+	b.Write(EncodeRetainedMessage(msg.Message))
+	return b.Bytes()
+}
+
+// DecodeDelayedMessage decodes the delayed message from bytes which is encoded by EncodeDelayedMessage.
+func DecodeDelayedMessage(b []byte) (*delayed.Message, error) {
+	r := bytes.NewBuffer(b)
+	msg := &delayed.Message{}
+	id, err := ReadString(r)
+	if err != nil {
+		return nil, err
+	}
+	msg.ID = string(id)
+	cid, err := ReadString(r)
+	if err != nil {
+		return nil, err
+	}
+	msg.ClientID = string(cid)
+	if r.Len() < 16 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	msg.PublishedAt = time.Unix(0, int64(binary.BigEndian.Uint64(r.Next(8))))
+	msg.DeliverAt = time.Unix(0, int64(binary.BigEndian.Uint64(r.Next(8))))
+	msg.Message, err = DecodeRetainedMessage(r.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
@@ -150,13 +150,18 @@ func EncodeSession(sess *gmqtt.Session, b *bytes.Buffer) {
 	WriteString(b, []byte(sess.ClientID))
 	if sess.Will != nil {
 		b.WriteByte(1)
-		EncodeMessage(sess.Will, b)
+		// DecodeMessage reads until EOF, so the will message is prefixed with its length.
+		will := &bytes.Buffer{}
+		EncodeMessage(sess.Will, will)
+		WriteUint32(b, uint32(will.Len()))
+		b.Write(will.Bytes())
 		WriteUint32(b, sess.WillDelayInterval)
 	} else {
 		b.WriteByte(0)
 	}
 	time := make([]byte, 8)
 	binary.BigEndian.PutUint64(time, uint64(sess.ConnectedAt.Unix()))
+	b.Write(time)
 	WriteUint32(b, sess.ExpiryInterval)
 }
 
@@ -172,7 +177,15 @@ func DecodeSession(b *bytes.Buffer) (sess *gmqtt.Session, err error) {
 		return
 	}
 	if willPresent == 1 {
-		sess.Will, err = DecodeMessage(b)
+		var l uint32
+		l, err = ReadUint32(b)
+		if err != nil {
+			return
+		}
+		if uint32(b.Len()) < l {
+			return nil, io.ErrUnexpectedEOF
+		}
+		sess.Will, err = DecodeMessage(bytes.NewBuffer(b.Next(int(l))))
 		if err != nil {
 			return
 		}
@@ -0,0 +1,41 @@
+package encoding
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/DrmagicE/gmqtt"
+)
+
+// EncodeRetainedMessage encodes the retained message into bytes.
+// Unlike EncodeMessage, the payload is prefixed with a 4 bytes length,
+// so that the payload larger than 65535 bytes can be encoded.
+func EncodeRetainedMessage(msg *gmqtt.Message) []byte {
+	b := &bytes.Buffer{}
+	WriteUint32(b, uint32(len(msg.Payload)))
+	b.Write(msg.Payload)
+	m := *msg
+	m.Payload = nil
+	EncodeMessage(&m, b)
+	return b.Bytes()
+}
+
+// DecodeRetainedMessage decodes the retained message from bytes which is encoded by EncodeRetainedMessage.
+func DecodeRetainedMessage(b []byte) (*gmqtt.Message, error) {
+	r := bytes.NewBuffer(b)
+	l, err := ReadUint32(r)
+	if err != nil {
+		return nil, err
+	}
+	if uint32(r.Len()) < l {
+		return nil, io.ErrUnexpectedEOF
+	}
+	payload := make([]byte, l)
+	copy(payload, r.Next(int(l)))
+	msg, err := DecodeMessage(r)
+	if err != nil {
+		return nil, err
+	}
+	msg.Payload = payload
+	return msg, nil
+}
@@ -0,0 +1,57 @@
+package encoding
+
+import (
+	"bytes"
+
+	"github.com/DrmagicE/gmqtt"
+)
+
+// EncodeSubscription encodes the subscription into bytes.
+func EncodeSubscription(sub *gmqtt.Subscription) []byte {
+	w := &bytes.Buffer{}
+	WriteString(w, []byte(sub.ShareName))
+	WriteString(w, []byte(sub.TopicFilter))
+	WriteUint32(w, sub.ID)
+	w.WriteByte(sub.QoS)
+	WriteBool(w, sub.NoLocal)
+	WriteBool(w, sub.RetainAsPublished)
+	w.WriteByte(sub.RetainHandling)
+	return w.Bytes()
+}
+
+// DecodeSubscription decodes the subscription from bytes which is encoded by EncodeSubscription.
+func DecodeSubscription(b []byte) (*gmqtt.Subscription, error) {
+	sub := &gmqtt.Subscription{}
+	r := bytes.NewBuffer(b)
+	share, err := ReadString(r)
+	if err != nil {
+		return &gmqtt.Subscription{}, err
+	}
+	sub.ShareName = string(share)
+	topic, err := ReadString(r)
+	if err != nil {
+		return &gmqtt.Subscription{}, err
+	}
+	sub.TopicFilter = string(topic)
+	sub.ID, err = ReadUint32(r)
+	if err != nil {
+		return &gmqtt.Subscription{}, err
+	}
+	sub.QoS, err = r.ReadByte()
+	if err != nil {
+		return &gmqtt.Subscription{}, err
+	}
+	sub.NoLocal, err = ReadBool(r)
+	if err != nil {
+		return &gmqtt.Subscription{}, err
+	}
+	sub.RetainAsPublished, err = ReadBool(r)
+	if err != nil {
+		return &gmqtt.Subscription{}, err
+	}
+	sub.RetainHandling, err = r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
@@ -2,6 +2,8 @@ package persistence
 
 import (
 	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/persistence/delayed"
+	mem_delayed "github.com/DrmagicE/gmqtt/persistence/delayed/mem"
 	"github.com/DrmagicE/gmqtt/persistence/queue"
 	mem_queue "github.com/DrmagicE/gmqtt/persistence/queue/mem"
 	"github.com/DrmagicE/gmqtt/persistence/session"
@@ -10,6 +12,7 @@ import (
 	mem_sub "github.com/DrmagicE/gmqtt/persistence/subscription/mem"
 	"github.com/DrmagicE/gmqtt/persistence/unack"
 	mem_unack "github.com/DrmagicE/gmqtt/persistence/unack/mem"
+	"github.com/DrmagicE/gmqtt/retained"
 	"github.com/DrmagicE/gmqtt/server"
 )
 
@@ -25,6 +28,7 @@ func NewMemory(config config.Config, hooks server.Hooks) (server.Persistence, er
 
 type memory struct {
 	onMsgDropped server.OnMsgDropped
+	retained     retained.Store
 }
 
 func (m *memory) NewUnackStore(config config.Config, clientID string) (unack.Store, error) {
@@ -52,6 +56,16 @@ func (m *memory) NewSubscriptionStore(config config.Config) (subscription.Store,
 	return mem_sub.NewStore(), nil
 }
 
+func (m *memory) NewRetainedStore(config config.Config) (retained.Store, error) {
+	var err error
+	m.retained, err = newRetainedStore(config, nil, nil)
+	return m.retained, err
+}
+
+func (m *memory) NewDelayedStore(config config.Config) (delayed.Store, error) {
+	return mem_delayed.New(), nil
+}
+
 func (m *memory) Close() error {
-	return nil
+	return closeRetainedStore(m.retained)
 }
@@ -7,10 +7,12 @@ import (
 	"github.com/stretchr/testify/suite"
 
 	"github.com/DrmagicE/gmqtt/config"
+	delayed_test "github.com/DrmagicE/gmqtt/persistence/delayed/test"
 	queue_test "github.com/DrmagicE/gmqtt/persistence/queue/test"
 	sess_test "github.com/DrmagicE/gmqtt/persistence/session/test"
 	sub_test "github.com/DrmagicE/gmqtt/persistence/subscription/test"
 	unack_test "github.com/DrmagicE/gmqtt/persistence/unack/test"
+	retained_test "github.com/DrmagicE/gmqtt/retained/test"
 	"github.com/DrmagicE/gmqtt/server"
 )
 
@@ -40,6 +42,13 @@ func (s *MemorySuite) TestSession() {
 	sess_test.TestSuite(s.T(), st)
 }
 
+func (s *MemorySuite) TestDelayed() {
+	a := assert.New(s.T())
+	st, err := s.p.(server.DelayedPersistence).NewDelayedStore(queue_test.TestServerConfig)
+	a.Nil(err)
+	delayed_test.TestSuite(s.T(), st)
+}
+
 func (s *MemorySuite) TestUnack() {
 	a := assert.New(s.T())
 	st, err := s.p.NewUnackStore(unack_test.TestServerConfig, unack_test.TestClientID)
@@ -47,6 +56,13 @@ func (s *MemorySuite) TestUnack() {
 	unack_test.TestSuite(s.T(), st)
 }
 
+func (s *MemorySuite) TestRetained() {
+	a := assert.New(s.T())
+	st, err := s.p.(server.RetainedPersistence).NewRetainedStore(config.Config{})
+	a.Nil(err)
+	retained_test.TestSuite(s.T(), st)
+}
+
 func TestMemory(t *testing.T) {
 	p, err := NewMemory(config.Config{}, queue_test.TestHooks)
 	if err != nil {
@@ -0,0 +1,417 @@
+package bolt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"go.etcd.io/bbolt"
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+
+	"github.com/DrmagicE/gmqtt/persistence/queue"
+)
+
+// queueBucket is the bucket that stores the queues,
+// each client has a nested bucket named by the client id.
+// The key of the nested bucket is a big endian sequence number, so the elems are sorted by the entry order.
+var queueBucket = []byte("queue")
+
+var _ queue.Store = (*Queue)(nil)
+
+type Options struct {
+	MaxQueuedMsg int
+	ClientID     string
+	DropHandler  server.OnMsgDropped
+	DB           *bbolt.DB
+}
+
+type Queue struct {
+	cond           *sync.Cond
+	clientID       string
+	version        packets.Version
+	readBytesLimit uint32
+	max            int
+	db             *bbolt.DB
+	// keys is the keys of the elems in the queue in order.
+	keys            [][]byte
+	closed          bool
+	inflightDrained bool
+	current         int // the current read index of keys.
+	readCache       map[packets.PacketID][]byte
+	onMsgDropped    server.OnMsgDropped
+	log             *zap.Logger
+}
+
+// dropped is a message that has been dropped, the drop handler will be called after the transaction committed.
+type dropped struct {
+	msg *gmqtt.Message
+	err error
+}
+
+func New(opts Options) (*Queue, error) {
+	return &Queue{
+		cond:            sync.NewCond(&sync.Mutex{}),
+		clientID:        opts.ClientID,
+		max:             opts.MaxQueuedMsg,
+		db:              opts.DB,
+		closed:          false,
+		inflightDrained: false,
+		current:         0,
+		log:             server.LoggerWithField(zap.String("queue", "bolt")),
+		onMsgDropped:    opts.DropHandler,
+	}, nil
+}
+
+func wrapError(err error) *codes.Error {
+	return &codes.Error{
+		Code: codes.UnspecifiedError,
+		ErrorDetails: codes.ErrorDetails{
+			ReasonString:   []byte(err.Error()),
+			UserProperties: nil,
+		},
+	}
+}
+
+// bucket returns the queue bucket of the client, creates it if not exists.
+func (q *Queue) bucket(tx *bbolt.Tx) (*bbolt.Bucket, error) {
+	root, err := tx.CreateBucketIfNotExists(queueBucket)
+	if err != nil {
+		return nil, err
+	}
+	return root.CreateBucketIfNotExists([]byte(q.clientID))
+}
+
+func (q *Queue) drop(ds []dropped) {
+	for _, v := range ds {
+		queue.Drop(q.onMsgDropped, q.log, q.clientID, v.msg, v.err)
+	}
+}
+
+// removeKeyLocked removes the i-th key, must call under q.cond.L.Lock.
+func (q *Queue) removeKeyLocked(i int) {
+	q.keys = append(q.keys[:i], q.keys[i+1:]...)
+}
+
+func (q *Queue) Close() error {
+	q.cond.L.Lock()
+	defer func() {
+		q.cond.L.Unlock()
+		q.cond.Signal()
+	}()
+	q.closed = true
+	return nil
+}
+
+func (q *Queue) Init(opts *queue.InitOptions) error {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	var keys [][]byte
+	err := q.db.Update(func(tx *bbolt.Tx) error {
+		if opts.CleanStart {
+			root, err := tx.CreateBucketIfNotExists(queueBucket)
+			if err != nil {
+				return err
+			}
+			if err = root.DeleteBucket([]byte(q.clientID)); err != nil && err != bbolt.ErrBucketNotFound {
+				return err
+			}
+		}
+		bucket, err := q.bucket(tx)
+		if err != nil {
+			return err
+		}
+		c := bucket.Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			// the key is only valid during the transaction.
+			keys = append(keys, append([]byte(nil), k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return wrapError(err)
+	}
+	q.version = opts.Version
+	q.readBytesLimit = opts.ReadBytesLimit
+	q.keys = keys
+	q.closed = false
+	q.inflightDrained = false
+	q.current = 0
+	q.readCache = make(map[packets.PacketID][]byte)
+	q.cond.Signal()
+	return nil
+}
+
+func (q *Queue) Clean() error {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	err := q.db.Update(func(tx *bbolt.Tx) error {
+		root := tx.Bucket(queueBucket)
+		if root == nil {
+			return nil
+		}
+		err := root.DeleteBucket([]byte(q.clientID))
+		if err == bbolt.ErrBucketNotFound {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	q.keys = nil
+	q.current = 0
+	return nil
+}
+
+func (q *Queue) Add(elem *queue.Elem) (err error) {
+	now := time.Now()
+	q.cond.L.Lock()
+	var ds []dropped
+	defer func() {
+		q.cond.L.Unlock()
+		q.cond.Signal()
+		q.drop(ds)
+	}()
+	return q.db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := q.bucket(tx)
+		if err != nil {
+			return err
+		}
+		if len(q.keys) >= q.max {
+			// set default drop error
+			dropErr := queue.ErrDropQueueFull
+			dropIndex := -1
+			var dropElem *queue.Elem
+			// drop the current elem if there is no more non-inflight messages.
+			if !q.inflightDrained || q.current < len(q.keys) {
+				frontIndex := -1
+				var frontElem *queue.Elem
+				for i := q.current; i < len(q.keys); i++ {
+					e := &queue.Elem{}
+					if err = e.Decode(bucket.Get(q.keys[i])); err != nil {
+						return err
+					}
+					pub := e.MessageWithID.(*queue.Publish)
+					if pub.ID() != 0 {
+						continue
+					}
+					// drop the front message
+					if i == q.current {
+						frontIndex = i
+						frontElem = e
+					}
+					// drop expired message
+					if queue.ElemExpiry(now, e) {
+						dropErr = queue.ErrDropExpired
+						dropIndex = i
+						dropElem = e
+						break
+					}
+					if pub.QoS == packets.Qos0 && dropElem == nil {
+						dropIndex = i
+						dropElem = e
+					}
+				}
+				// drop qos0 message in the queue
+				if dropElem == nil && elem.MessageWithID.(*queue.Publish).QoS != packets.Qos0 && frontElem != nil {
+					dropIndex = frontIndex
+					dropElem = frontElem
+				}
+			}
+			if dropElem == nil {
+				// the messages in the queue are all inflight messages, drop the current elem
+				ds = append(ds, dropped{msg: elem.MessageWithID.(*queue.Publish).Message, err: dropErr})
+				return nil
+			}
+			if err = bucket.Delete(q.keys[dropIndex]); err != nil {
+				return err
+			}
+			q.removeKeyLocked(dropIndex)
+			ds = append(ds, dropped{msg: dropElem.MessageWithID.(*queue.Publish).Message, err: dropErr})
+		}
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+		if err = bucket.Put(key, elem.Encode()); err != nil {
+			return err
+		}
+		q.keys = append(q.keys, key)
+		return nil
+	})
+}
+
+func (q *Queue) Replace(elem *queue.Elem) (replaced bool, err error) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	id := elem.ID()
+	err = q.db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := q.bucket(tx)
+		if err != nil {
+			return err
+		}
+		for _, k := range q.keys[:q.current] {
+			e := &queue.Elem{}
+			if err = e.Decode(bucket.Get(k)); err != nil {
+				return err
+			}
+			if e.ID() == id {
+				if err = bucket.Put(k, elem.Encode()); err != nil {
+					return err
+				}
+				q.readCache[id] = k
+				replaced = true
+				return nil
+			}
+		}
+		return nil
+	})
+	return
+}
+
+func (q *Queue) Read(pids []packets.PacketID) (elems []*queue.Elem, err error) {
+	now := time.Now()
+	q.cond.L.Lock()
+	var ds []dropped
+	defer func() {
+		q.cond.L.Unlock()
+		q.drop(ds)
+	}()
+	if !q.inflightDrained {
+		panic("must call ReadInflight to drain all inflight messages before Read")
+	}
+	for (q.current >= len(q.keys)) && !q.closed {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return nil, queue.ErrClosed
+	}
+	n := len(q.keys) - q.current
+	if n > len(pids) {
+		n = len(pids)
+	}
+	err = q.db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := q.bucket(tx)
+		if err != nil {
+			return err
+		}
+		var pflag int
+		for i := 0; i < n; i++ {
+			k := q.keys[q.current]
+			e := &queue.Elem{}
+			if err = e.Decode(bucket.Get(k)); err != nil {
+				return err
+			}
+			pub := e.MessageWithID.(*queue.Publish)
+			// remove expired message
+			if queue.ElemExpiry(now, e) {
+				if err = bucket.Delete(k); err != nil {
+					return err
+				}
+				q.removeKeyLocked(q.current)
+				ds = append(ds, dropped{msg: pub.Message, err: queue.ErrDropExpired})
+				continue
+			}
+			// remove message which exceeds maximum packet size
+			if size := pub.TotalBytes(q.version); size > q.readBytesLimit {
+				if err = bucket.Delete(k); err != nil {
+					return err
+				}
+				q.removeKeyLocked(q.current)
+				ds = append(ds, dropped{msg: pub.Message, err: queue.ErrDropExceedsMaxPacketSize})
+				continue
+			}
+			if pub.QoS == packets.Qos0 {
+				if err = bucket.Delete(k); err != nil {
+					return err
+				}
+				q.removeKeyLocked(q.current)
+			} else {
+				e.MessageWithID.SetID(pids[pflag])
+				pflag++
+				if err = bucket.Put(k, e.Encode()); err != nil {
+					return err
+				}
+				q.current++
+				q.readCache[e.MessageWithID.ID()] = k
+			}
+			elems = append(elems, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	return
+}
+
+func (q *Queue) ReadInflight(maxSize uint) (elems []*queue.Elem, err error) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	err = q.db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := q.bucket(tx)
+		if err != nil {
+			return err
+		}
+		if q.current >= len(q.keys) {
+			q.inflightDrained = true
+			return nil
+		}
+		for i := uint(0); i < maxSize && q.current < len(q.keys); i++ {
+			k := q.keys[q.current]
+			e := &queue.Elem{}
+			if err = e.Decode(bucket.Get(k)); err != nil {
+				return err
+			}
+			id := e.MessageWithID.ID()
+			if id == 0 {
+				q.inflightDrained = true
+				return nil
+			}
+			elems = append(elems, e)
+			q.readCache[id] = k
+			q.current++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	return
+}
+
+func (q *Queue) Remove(pid packets.PacketID) error {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	k, ok := q.readCache[pid]
+	if !ok {
+		return nil
+	}
+	err := q.db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := q.bucket(tx)
+		if err != nil {
+			return err
+		}
+		return bucket.Delete(k)
+	})
+	if err != nil {
+		return err
+	}
+	delete(q.readCache, pid)
+	for i := 0; i < q.current; i++ {
+		if bytes.Equal(q.keys[i], k) {
+			q.removeKeyLocked(i)
+			q.current--
+			break
+		}
+	}
+	return nil
+}
@@ -4,6 +4,8 @@ import (
 	redigo "github.com/gomodule/redigo/redis"
 
 	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/persistence/delayed"
+	redis_delayed "github.com/DrmagicE/gmqtt/persistence/delayed/redis"
 	"github.com/DrmagicE/gmqtt/persistence/queue"
 	redis_queue "github.com/DrmagicE/gmqtt/persistence/queue/redis"
 	"github.com/DrmagicE/gmqtt/persistence/session"
@@ -12,6 +14,7 @@ import (
 	redis_sub "github.com/DrmagicE/gmqtt/persistence/subscription/redis"
 	"github.com/DrmagicE/gmqtt/persistence/unack"
 	redis_unack "github.com/DrmagicE/gmqtt/persistence/unack/redis"
+	"github.com/DrmagicE/gmqtt/retained"
 	"github.com/DrmagicE/gmqtt/server"
 )
 
@@ -30,6 +33,7 @@ type redis struct {
 	pool         *redigo.Pool
 	config       config.Config
 	onMsgDropped server.OnMsgDropped
+	retained     retained.Store
 }
 
 func (r *redis) NewUnackStore(config config.Config, clientID string) (unack.Store, error) {
@@ -91,6 +95,17 @@ func (r *redis) NewSubscriptionStore(config config.Config) (subscription.Store,
 	return redis_sub.New(r.pool), nil
 }
 
+func (r *redis) NewRetainedStore(config config.Config) (retained.Store, error) {
+	var err error
+	r.retained, err = newRetainedStore(config, r.pool, nil)
+	return r.retained, err
+}
+
+func (r *redis) NewDelayedStore(config config.Config) (delayed.Store, error) {
+	return redis_delayed.New(r.pool), nil
+}
+
 func (r *redis) Close() error {
+	_ = closeRetainedStore(r.retained)
 	return r.pool.Close()
 }
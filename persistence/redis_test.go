@@ -9,10 +9,13 @@ import (
 	"github.com/stretchr/testify/suite"
 
 	"github.com/DrmagicE/gmqtt/config"
+	delayed_test "github.com/DrmagicE/gmqtt/persistence/delayed/test"
 	queue_test "github.com/DrmagicE/gmqtt/persistence/queue/test"
 	sess_test "github.com/DrmagicE/gmqtt/persistence/session/test"
 	sub_test "github.com/DrmagicE/gmqtt/persistence/subscription/test"
 	unack_test "github.com/DrmagicE/gmqtt/persistence/unack/test"
+	"github.com/DrmagicE/gmqtt/retained"
+	retained_test "github.com/DrmagicE/gmqtt/retained/test"
 	"github.com/DrmagicE/gmqtt/server"
 )
 
@@ -86,6 +89,13 @@ func (s *RedisSuite) TestSession() {
 	sess_test.TestSuite(s.T(), st)
 }
 
+func (s *RedisSuite) TestDelayed() {
+	a := assert.New(s.T())
+	st, err := s.p.(server.DelayedPersistence).NewDelayedStore(config.Config{})
+	a.Nil(err)
+	delayed_test.TestSuite(s.T(), st)
+}
+
 func (s *RedisSuite) TestUnack() {
 	a := assert.New(s.T())
 	st, err := s.p.NewUnackStore(unack_test.TestServerConfig, unack_test.TestClientID)
@@ -93,6 +103,26 @@ func (s *RedisSuite) TestUnack() {
 	unack_test.TestSuite(s.T(), st)
 }
 
+func (s *RedisSuite) TestRetained() {
+	a := assert.New(s.T())
+	st, err := s.p.(server.RetainedPersistence).NewRetainedStore(config.Config{
+		Persistence: config.Persistence{
+			Type: config.PersistenceTypeRedis,
+		},
+	})
+	a.Nil(err)
+	retained_test.TestSuite(s.T(), st)
+	retained_test.TestPersistence(s.T(), func() retained.Store {
+		st, err := s.p.(server.RetainedPersistence).NewRetainedStore(config.Config{
+			Persistence: config.Persistence{
+				Type: config.PersistenceTypeRedis,
+			},
+		})
+		a.Nil(err)
+		return st
+	})
+}
+
 func TestRedis(t *testing.T) {
 	suite.Run(t, &RedisSuite{})
 }
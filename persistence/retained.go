@@ -0,0 +1,50 @@
+package persistence
+
+import (
+	"errors"
+	"io"
+
+	redigo "github.com/gomodule/redigo/redis"
+	"go.etcd.io/bbolt"
+
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/retained"
+	retained_bolt "github.com/DrmagicE/gmqtt/retained/bolt"
+	retained_file "github.com/DrmagicE/gmqtt/retained/file"
+	retained_redis "github.com/DrmagicE/gmqtt/retained/redis"
+	retained_trie "github.com/DrmagicE/gmqtt/retained/trie"
+)
+
+// newRetainedStore creates the retained store according to config.Persistence.Retained.
+// The pool is nil if the persistence type is not redis, and the db is nil if the persistence type is not bolt.
+func newRetainedStore(cfg config.Config, pool *redigo.Pool, db *bbolt.DB) (retained.Store, error) {
+	typ := cfg.Persistence.Retained.Type
+	if typ == "" {
+		typ = cfg.Persistence.Type
+	}
+	switch typ {
+	case "", config.RetainedTypeMemory:
+		return retained_trie.NewStore(), nil
+	case config.RetainedTypeRedis:
+		if pool == nil {
+			return nil, errors.New("redis retained store requires redis persistence")
+		}
+		return retained_redis.New(pool)
+	case config.RetainedTypeBolt:
+		if db == nil {
+			return nil, errors.New("bolt retained store requires bolt persistence")
+		}
+		return retained_bolt.New(db)
+	case config.RetainedTypeFile:
+		return retained_file.New(cfg.Persistence.Retained.File.Path)
+	}
+	return nil, errors.New("invalid retained type: " + typ)
+}
+
+// closeRetainedStore closes the retained store if it holds any resources.
+func closeRetainedStore(store retained.Store) error {
+	if c, ok := store.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
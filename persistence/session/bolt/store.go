@@ -0,0 +1,94 @@
+package bolt
+
+import (
+	"bytes"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/persistence/encoding"
+	"github.com/DrmagicE/gmqtt/persistence/session"
+)
+
+// sessionBucket is the bucket that stores all sessions, the key is the client id.
+var sessionBucket = []byte("session")
+
+var _ session.Store = (*Store)(nil)
+
+type Store struct {
+	db *bbolt.DB
+}
+
+func New(db *bbolt.DB) (*Store, error) {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(sessionBucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{
+		db: db,
+	}, nil
+}
+
+func (s *Store) Set(session *gmqtt.Session) error {
+	b := &bytes.Buffer{}
+	encoding.EncodeSession(session, b)
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(sessionBucket).Put([]byte(session.ClientID), b.Bytes())
+	})
+}
+
+func (s *Store) Remove(clientID string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(sessionBucket).Delete([]byte(clientID))
+	})
+}
+
+// Get returns the session of the given client id, returns nil if the session not exists.
+func (s *Store) Get(clientID string) (sess *gmqtt.Session, err error) {
+	err = s.db.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(sessionBucket).Get([]byte(clientID))
+		if v == nil {
+			return nil
+		}
+		sess, err = encoding.DecodeSession(bytes.NewBuffer(v))
+		return err
+	})
+	return
+}
+
+func (s *Store) SetSessionExpiry(clientID string, expiry uint32) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(sessionBucket)
+		v := bucket.Get([]byte(clientID))
+		if v == nil {
+			return nil
+		}
+		sess, err := encoding.DecodeSession(bytes.NewBuffer(v))
+		if err != nil {
+			return err
+		}
+		sess.ExpiryInterval = expiry
+		b := &bytes.Buffer{}
+		encoding.EncodeSession(sess, b)
+		return bucket.Put([]byte(clientID), b.Bytes())
+	})
+}
+
+func (s *Store) Iterate(fn session.IterateFn) error {
+	return s.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(sessionBucket).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			sess, err := encoding.DecodeSession(bytes.NewBuffer(v))
+			if err != nil {
+				return err
+			}
+			if !fn(sess) {
+				return nil
+			}
+		}
+		return nil
+	})
+}
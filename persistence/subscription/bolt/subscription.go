@@ -0,0 +1,154 @@
+package bolt
+
+import (
+	"sync"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/persistence/encoding"
+	"github.com/DrmagicE/gmqtt/persistence/subscription"
+	"github.com/DrmagicE/gmqtt/persistence/subscription/mem"
+)
+
+// subBucket is the bucket that stores the subscriptions,
+// each client has a nested bucket named by the client id, the key of the nested bucket is the full topic name.
+var subBucket = []byte("subscription")
+
+var _ subscription.Store = (*sub)(nil)
+
+func New(db *bbolt.DB) (*sub, error) {
+	err := db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(subBucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sub{
+		mu:       &sync.Mutex{},
+		memStore: mem.NewStore(),
+		db:       db,
+	}, nil
+}
+
+type sub struct {
+	mu       *sync.Mutex
+	memStore *mem.TrieDB
+	db       *bbolt.DB
+}
+
+// Init loads the subscriptions of given clientIDs from backend into memory.
+func (s *sub) Init(clientIDs []string) error {
+	if len(clientIDs) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db.View(func(tx *bbolt.Tx) error {
+		root := tx.Bucket(subBucket)
+		for _, v := range clientIDs {
+			bucket := root.Bucket([]byte(v))
+			if bucket == nil {
+				continue
+			}
+			err := bucket.ForEach(func(k, b []byte) error {
+				sub, err := encoding.DecodeSubscription(b)
+				if err != nil {
+					return err
+				}
+				s.memStore.SubscribeLocked(v, sub)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Close closes the in-memory store, the database is closed by the persistence.
+func (s *sub) Close() error {
+	return s.memStore.Close()
+}
+
+func (s *sub) Subscribe(clientID string, subscriptions ...*gmqtt.Subscription) (rs subscription.SubscribeResult, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err = s.db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.Bucket(subBucket).CreateBucketIfNotExists([]byte(clientID))
+		if err != nil {
+			return err
+		}
+		for _, v := range subscriptions {
+			err = bucket.Put([]byte(subscription.GetFullTopicName(v.ShareName, v.TopicFilter)), encoding.EncodeSubscription(v))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	rs = s.memStore.SubscribeLocked(clientID, subscriptions...)
+	return rs, nil
+}
+
+func (s *sub) Unsubscribe(clientID string, topics ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(subBucket).Bucket([]byte(clientID))
+		if bucket == nil {
+			return nil
+		}
+		for _, v := range topics {
+			if err := bucket.Delete([]byte(v)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.memStore.UnsubscribeLocked(clientID, topics...)
+	return nil
+}
+
+func (s *sub) UnsubscribeAll(clientID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		err := tx.Bucket(subBucket).DeleteBucket([]byte(clientID))
+		if err == bbolt.ErrBucketNotFound {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	s.memStore.UnsubscribeAllLocked(clientID)
+	return nil
+}
+
+func (s *sub) Iterate(fn subscription.IterateFn, options subscription.IterationOptions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.memStore.IterateLocked(fn, options)
+}
+
+func (s *sub) GetStats() subscription.Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.memStore.GetStatusLocked()
+}
+
+func (s *sub) GetClientStats(clientID string) (subscription.Stats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.memStore.GetClientStatsLocked(clientID)
+}
@@ -1,7 +1,6 @@
 package redis
 
 import (
-	"bytes"
 	"strings"
 	"sync"
 
@@ -19,52 +18,14 @@ const (
 
 var _ subscription.Store = (*sub)(nil)
 
+// EncodeSubscription is an alias of encoding.EncodeSubscription.
 func EncodeSubscription(sub *gmqtt.Subscription) []byte {
-	w := &bytes.Buffer{}
-	encoding.WriteString(w, []byte(sub.ShareName))
-	encoding.WriteString(w, []byte(sub.TopicFilter))
-	encoding.WriteUint32(w, sub.ID)
-	w.WriteByte(sub.QoS)
-	encoding.WriteBool(w, sub.NoLocal)
-	encoding.WriteBool(w, sub.RetainAsPublished)
-	w.WriteByte(sub.RetainHandling)
-	return w.Bytes()
+	return encoding.EncodeSubscription(sub)
 }
 
+// DecodeSubscription is an alias of encoding.DecodeSubscription.
 func DecodeSubscription(b []byte) (*gmqtt.Subscription, error) {
-	sub := &gmqtt.Subscription{}
-	r := bytes.NewBuffer(b)
-	share, err := encoding.ReadString(r)
-	if err != nil {
-		return &gmqtt.Subscription{}, err
-	}
-	sub.ShareName = string(share)
-	topic, err := encoding.ReadString(r)
-	if err != nil {
-		return &gmqtt.Subscription{}, err
-	}
-	sub.TopicFilter = string(topic)
-	sub.ID, err = encoding.ReadUint32(r)
-	if err != nil {
-		return &gmqtt.Subscription{}, err
-	}
-	sub.QoS, err = r.ReadByte()
-	if err != nil {
-		return &gmqtt.Subscription{}, err
-	}
-	sub.NoLocal, err = encoding.ReadBool(r)
-	if err != nil {
-		return &gmqtt.Subscription{}, err
-	}
-	sub.RetainAsPublished, err = encoding.ReadBool(r)
-	if err != nil {
-		return &gmqtt.Subscription{}, err
-	}
-	sub.RetainHandling, err = r.ReadByte()
-	if err != nil {
-		return nil, err
-	}
-	return sub, nil
+	return encoding.DecodeSubscription(b)
 }
 
 func New(pool *redigo.Pool) *sub {
@@ -0,0 +1,108 @@
+package bolt
+
+import (
+	"encoding/binary"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/DrmagicE/gmqtt/persistence/unack"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+// unackBucket is the bucket that stores the unack packet ids,
+// each client has a nested bucket named by the client id.
+var unackBucket = []byte("unack")
+
+var _ unack.Store = (*Store)(nil)
+
+type Store struct {
+	clientID     string
+	db           *bbolt.DB
+	unackpublish map[packets.PacketID]struct{}
+}
+
+type Options struct {
+	ClientID string
+	DB       *bbolt.DB
+}
+
+func New(opts Options) *Store {
+	return &Store{
+		clientID:     opts.ClientID,
+		db:           opts.DB,
+		unackpublish: make(map[packets.PacketID]struct{}),
+	}
+}
+
+func getKey(id packets.PacketID) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, id)
+	return b
+}
+
+func (s *Store) Init(cleanStart bool) error {
+	s.unackpublish = make(map[packets.PacketID]struct{})
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		root, err := tx.CreateBucketIfNotExists(unackBucket)
+		if err != nil {
+			return err
+		}
+		if cleanStart {
+			err = root.DeleteBucket([]byte(s.clientID))
+			if err == bbolt.ErrBucketNotFound {
+				return nil
+			}
+			return err
+		}
+		bucket := root.Bucket([]byte(s.clientID))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			s.unackpublish[binary.BigEndian.Uint16(k)] = struct{}{}
+			return nil
+		})
+	})
+}
+
+func (s *Store) Set(id packets.PacketID) (bool, error) {
+	// from cache
+	if _, ok := s.unackpublish[id]; ok {
+		return true, nil
+	}
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		root, err := tx.CreateBucketIfNotExists(unackBucket)
+		if err != nil {
+			return err
+		}
+		bucket, err := root.CreateBucketIfNotExists([]byte(s.clientID))
+		if err != nil {
+			return err
+		}
+		return bucket.Put(getKey(id), []byte{1})
+	})
+	if err != nil {
+		return false, err
+	}
+	s.unackpublish[id] = struct{}{}
+	return false, nil
+}
+
+func (s *Store) Remove(id packets.PacketID) error {
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		root := tx.Bucket(unackBucket)
+		if root == nil {
+			return nil
+		}
+		bucket := root.Bucket([]byte(s.clientID))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.Delete(getKey(id))
+	})
+	if err != nil {
+		return err
+	}
+	delete(s.unackpublish, id)
+	return nil
+}
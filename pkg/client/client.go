@@ -0,0 +1,616 @@
+// Package client implements a MQTT v3.1.1 and v5 client on top of the packets package.
+package client
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+var (
+	// ErrNotConnected is returned when the client is not connected.
+	ErrNotConnected = errors.New("client: not connected")
+	// ErrAlreadyConnected is returned when calling Connect on a connected client.
+	ErrAlreadyConnected = errors.New("client: already connected")
+	// ErrClosed is returned when the client has been disconnected by Disconnect.
+	ErrClosed = errors.New("client: closed")
+	// ErrConnectionLost is returned to the pending operations when the connection is lost
+	// and the automatic reconnection is disabled.
+	ErrConnectionLost = errors.New("client: connection lost")
+	// ErrKeepAliveTimeout is the error of the connection when the broker does not respond the PINGREQ in time.
+	ErrKeepAliveTimeout = errors.New("client: keep alive timeout")
+	// ErrNoPacketID is returned when all the packet identifiers are in use.
+	ErrNoPacketID = errors.New("client: no available packet id")
+	// ErrInvalidQoS is returned when publishing a message with invalid QoS level.
+	ErrInvalidQoS = errors.New("client: invalid qos")
+)
+
+// request is an in-flight PUBLISH, SUBSCRIBE or UNSUBSCRIBE which is waiting for the acknowledgement.
+type request struct {
+	// seq is used to resend the requests in order.
+	seq    uint64
+	packet packets.Packet
+	// pubrel is true if the PUBREC of the QoS 2 publish has been received.
+	pubrel bool
+	resp   packets.Packet
+	err    error
+	done   chan struct{}
+}
+
+// Client is a MQTT client.
+// The in-flight messages are kept in memory and resent after reconnecting,
+// so the session survives reconnections as long as the broker keeps the session.
+type Client struct {
+	opts options
+
+	mu sync.Mutex
+	// clientID is the client id in CONNECT, it is replaced by the client id assigned by the broker.
+	clientID string
+	// conn is the current connection, it is nil if the client is not connected.
+	conn *conn
+	// running is true between a successful Connect and the end of the connection (including reconnections).
+	running bool
+	closed  bool
+	nextID  packets.PacketID
+	seq     uint64
+	pending map[packets.PacketID]*request
+	// received stores the packet identifiers of the QoS 2 messages which are waiting for the PUBREL.
+	received map[packets.PacketID]struct{}
+	// inflight is the number of QoS 1 and QoS 2 publishes waiting for the acknowledgement.
+	inflight int
+	// sendQuota is the receive maximum of the broker.
+	sendQuota int
+	// quotaCh is closed and replaced when the inflight or sendQuota changes.
+	quotaCh chan struct{}
+	exit    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// New returns a new Client.
+func New(opts ...Options) *Client {
+	c := &Client{
+		opts:      defaultOptions(),
+		pending:   make(map[packets.PacketID]*request),
+		received:  make(map[packets.PacketID]struct{}),
+		sendQuota: int(packets.MaxPacketID),
+		quotaCh:   make(chan struct{}),
+		exit:      make(chan struct{}),
+	}
+	for _, fn := range opts {
+		fn(&c.opts)
+	}
+	c.clientID = c.opts.clientID
+	return c
+}
+
+// ClientID returns the client id, which is the assigned client id if the broker assigned one.
+func (c *Client) ClientID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.clientID
+}
+
+func (c *Client) setClientID(clientID string) {
+	c.mu.Lock()
+	c.clientID = clientID
+	c.mu.Unlock()
+}
+
+// IsConnected reports whether the client is connected to the broker.
+func (c *Client) IsConnected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn != nil
+}
+
+// Connect connects to the broker and returns the CONNACK packet.
+// If the connection is refused by the broker, the returned error is a *codes.Error with the reason code.
+func (c *Client) Connect(ctx context.Context) (*packets.Connack, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrClosed
+	}
+	if c.running {
+		c.mu.Unlock()
+		return nil, ErrAlreadyConnected
+	}
+	c.running = true
+	c.mu.Unlock()
+	cn, connack, err := c.connect(ctx, c.opts.cleanStart)
+	if err == nil && !c.established(cn, connack) {
+		err = ErrClosed
+	}
+	if err != nil {
+		c.mu.Lock()
+		c.running = false
+		c.mu.Unlock()
+		return nil, err
+	}
+	c.wg.Add(1)
+	go c.run(cn)
+	return connack, nil
+}
+
+// established sets the connection as the current connection and resends the in-flight requests.
+// It returns false if the client has been closed.
+func (c *Client) established(cn *conn, connack *packets.Connack) bool {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		cn.closeWithErr(ErrClosed)
+		return false
+	}
+	if !connack.SessionPresent {
+		c.received = make(map[packets.PacketID]struct{})
+	}
+	c.conn = cn
+	c.sendQuota = int(cn.receiveMax)
+	c.notifyQuotaLocked()
+	reqs := make([]*request, 0, len(c.pending))
+	for _, r := range c.pending {
+		reqs = append(reqs, r)
+	}
+	sort.Slice(reqs, func(i, j int) bool {
+		return reqs[i].seq < reqs[j].seq
+	})
+	var resend []packets.Packet
+	for _, r := range reqs {
+		switch p := r.packet.(type) {
+		case *packets.Publish:
+			if r.pubrel {
+				if !connack.SessionPresent {
+					// The broker has received the message, and the session state is discarded.
+					c.completeLocked(p.PacketID, nil, nil)
+					continue
+				}
+				resend = append(resend, &packets.Pubrel{PacketID: p.PacketID})
+				continue
+			}
+			pub := *p
+			pub.Dup = connack.SessionPresent
+			resend = append(resend, &pub)
+		default:
+			resend = append(resend, p)
+		}
+	}
+	c.mu.Unlock()
+	cn.start()
+	for _, p := range resend {
+		if cn.write(p) != nil {
+			break
+		}
+	}
+	if h := c.opts.onConnected; h != nil {
+		h(c, connack)
+	}
+	return true
+}
+
+// run waits for the connection to be closed, and reconnects if the automatic reconnection is enabled.
+func (c *Client) run(cn *conn) {
+	defer c.wg.Done()
+	for {
+		err := cn.wait()
+		c.mu.Lock()
+		c.conn = nil
+		closed := c.closed
+		c.mu.Unlock()
+		if closed {
+			return
+		}
+		if h := c.opts.onConnectionLost; h != nil {
+			h(c, err)
+		}
+		if !c.opts.autoReconnect {
+			c.mu.Lock()
+			c.running = false
+			c.failPendingLocked(ErrConnectionLost)
+			c.mu.Unlock()
+			return
+		}
+		if cn = c.reconnect(); cn == nil {
+			return
+		}
+	}
+}
+
+// reconnect reconnects to the broker until success, or returns nil if the client is closed.
+func (c *Client) reconnect() *conn {
+	interval := c.opts.reconnectInterval
+	// v5 clients resume the session by setting clean start to false.
+	// v3 clients with clean session cannot resume the session.
+	cleanStart := c.opts.version != packets.Version5 && c.opts.cleanStart
+	for {
+		select {
+		case <-c.exit:
+			return nil
+		case <-time.After(interval):
+		}
+		cn, connack, err := c.connect(context.Background(), cleanStart)
+		if err == nil {
+			if c.established(cn, connack) {
+				return cn
+			}
+			return nil
+		}
+		if interval *= 2; interval > c.opts.maxReconnectInterval {
+			interval = c.opts.maxReconnectInterval
+		}
+	}
+}
+
+// Disconnect sends the DISCONNECT packet and closes the connection.
+// The pending operations return ErrClosed, and the client cannot be used after Disconnect.
+func (c *Client) Disconnect() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	close(c.exit)
+	cn := c.conn
+	c.failPendingLocked(ErrClosed)
+	c.mu.Unlock()
+	if cn != nil {
+		_ = cn.write(&packets.Disconnect{
+			Version: c.opts.version,
+			Code:    codes.NormalDisconnection,
+		})
+		cn.closeWithErr(ErrClosed)
+	}
+	c.wg.Wait()
+	return nil
+}
+
+func (c *Client) notifyQuotaLocked() {
+	close(c.quotaCh)
+	c.quotaCh = make(chan struct{})
+}
+
+// acquireQuota waits until the number of in-flight publishes is less than the receive maximum of the broker.
+func (c *Client) acquireQuota(ctx context.Context) error {
+	for {
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			return ErrClosed
+		}
+		if c.inflight < c.sendQuota {
+			c.inflight++
+			c.mu.Unlock()
+			return nil
+		}
+		ch := c.quotaCh
+		c.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ch:
+		}
+	}
+}
+
+func (c *Client) releaseQuotaLocked() {
+	c.inflight--
+	c.notifyQuotaLocked()
+}
+
+// register allocates the packet identifier for the packet and adds it to the pending requests.
+// It returns the current connection, which is nil if the client is reconnecting.
+func (c *Client) register(p packets.Packet) (*request, *conn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, nil, ErrClosed
+	}
+	if !c.running {
+		return nil, nil, ErrNotConnected
+	}
+	var id packets.PacketID
+	for i := 0; i < int(packets.MaxPacketID); i++ {
+		if c.nextID++; c.nextID == 0 {
+			c.nextID = packets.MinPacketID
+		}
+		if _, ok := c.pending[c.nextID]; !ok {
+			id = c.nextID
+			break
+		}
+	}
+	if id == 0 {
+		return nil, nil, ErrNoPacketID
+	}
+	switch p := p.(type) {
+	case *packets.Publish:
+		p.PacketID = id
+	case *packets.Subscribe:
+		p.PacketID = id
+	case *packets.Unsubscribe:
+		p.PacketID = id
+	}
+	c.seq++
+	r := &request{
+		seq:    c.seq,
+		packet: p,
+		done:   make(chan struct{}),
+	}
+	c.pending[id] = r
+	return r, c.conn, nil
+}
+
+func (c *Client) completeLocked(id packets.PacketID, resp packets.Packet, err error) {
+	r, ok := c.pending[id]
+	if !ok {
+		return
+	}
+	delete(c.pending, id)
+	if _, ok := r.packet.(*packets.Publish); ok {
+		c.releaseQuotaLocked()
+	}
+	r.resp = resp
+	r.err = err
+	close(r.done)
+}
+
+func (c *Client) failPendingLocked(err error) {
+	for id := range c.pending {
+		c.completeLocked(id, nil, err)
+	}
+}
+
+// send registers the request, sends it and waits for the acknowledgement.
+func (c *Client) send(ctx context.Context, p packets.Packet) (packets.Packet, error) {
+	r, cn, err := c.register(p)
+	if err != nil {
+		return nil, err
+	}
+	return c.wait(ctx, r, cn)
+}
+
+// wait sends the registered request if the client is connected, and waits for the acknowledgement.
+// If the client is reconnecting, the request will be sent after reconnected.
+func (c *Client) wait(ctx context.Context, r *request, cn *conn) (packets.Packet, error) {
+	if cn != nil {
+		// The request is resent after reconnecting if the write fails.
+		_ = cn.write(r.packet)
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-r.done:
+		return r.resp, r.err
+	}
+}
+
+// Publish publishes the message.
+// For QoS 0 messages, it returns after the message is written to the connection.
+// For QoS 1 and QoS 2 messages, it waits for the PUBACK or PUBCOMP, and returns a *codes.Error
+// if the broker responds with an error reason code. If the context is done before the acknowledgement,
+// the message is still in flight and will be retried on reconnection.
+func (c *Client) Publish(ctx context.Context, msg *gmqtt.Message) error {
+	res, err := c.PublishAsync(ctx, msg)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-res.Done():
+		return res.Err()
+	}
+}
+
+// PublishResult is the result of an asynchronous publish.
+type PublishResult struct {
+	r *request
+}
+
+// Done returns a channel that is closed when the publish is completed.
+func (p *PublishResult) Done() <-chan struct{} {
+	return p.r.done
+}
+
+// Err returns the error of the publish after Done is closed.
+func (p *PublishResult) Err() error {
+	<-p.r.done
+	return p.r.err
+}
+
+// PublishAsync publishes the message without waiting for the acknowledgement.
+// The messages are sent in the order of the calls. It blocks only when the number of in-flight messages reaches
+// the receive maximum of the broker, and returns the error of ctx if ctx is done before the message is sent.
+// For QoS 0 messages, the returned result is completed after the message is written to the connection.
+func (c *Client) PublishAsync(ctx context.Context, msg *gmqtt.Message) (*PublishResult, error) {
+	if msg.QoS > packets.Qos2 {
+		return nil, ErrInvalidQoS
+	}
+	pub := gmqtt.MessageToPublish(msg, c.opts.version)
+	pub.Dup = false
+	pub.PacketID = 0
+	if pub.Qos == packets.Qos0 {
+		c.mu.Lock()
+		cn, closed := c.conn, c.closed
+		c.mu.Unlock()
+		if closed {
+			return nil, ErrClosed
+		}
+		if cn == nil {
+			return nil, ErrNotConnected
+		}
+		if err := cn.write(pub); err != nil {
+			return nil, err
+		}
+		r := &request{packet: pub, done: make(chan struct{})}
+		close(r.done)
+		return &PublishResult{r: r}, nil
+	}
+	if err := c.acquireQuota(ctx); err != nil {
+		return nil, err
+	}
+	r, cn, err := c.register(pub)
+	if err != nil {
+		c.mu.Lock()
+		c.releaseQuotaLocked()
+		c.mu.Unlock()
+		return nil, err
+	}
+	if cn != nil {
+		// The request is resent after reconnecting if the write fails.
+		_ = cn.write(r.packet)
+	}
+	return &PublishResult{r: r}, nil
+}
+
+// Subscribe subscribes the topics and returns the SUBACK packet.
+func (c *Client) Subscribe(ctx context.Context, topics ...packets.Topic) (*packets.Suback, error) {
+	resp, err := c.send(ctx, &packets.Subscribe{
+		Version: c.opts.version,
+		Topics:  topics,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*packets.Suback), nil
+}
+
+// Unsubscribe unsubscribes the topics and returns the UNSUBACK packet.
+func (c *Client) Unsubscribe(ctx context.Context, topics ...string) (*packets.Unsuback, error) {
+	resp, err := c.send(ctx, &packets.Unsubscribe{
+		Version: c.opts.version,
+		Topics:  topics,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*packets.Unsuback), nil
+}
+
+// ack completes the pending request if the acknowledgement matches the request.
+func (c *Client) ack(id packets.PacketID, resp packets.Packet, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, ok := c.pending[id]
+	if !ok {
+		return
+	}
+	var matched bool
+	switch resp.(type) {
+	case *packets.Puback:
+		p, ok := r.packet.(*packets.Publish)
+		matched = ok && p.Qos == packets.Qos1
+	case *packets.Pubrec:
+		p, ok := r.packet.(*packets.Publish)
+		matched = ok && p.Qos == packets.Qos2 && !r.pubrel
+	case *packets.Pubcomp:
+		_, ok := r.packet.(*packets.Publish)
+		matched = ok && r.pubrel
+	case *packets.Suback:
+		_, matched = r.packet.(*packets.Subscribe)
+	case *packets.Unsuback:
+		_, matched = r.packet.(*packets.Unsubscribe)
+	}
+	if matched {
+		c.completeLocked(id, resp, err)
+	}
+}
+
+func (c *Client) handlePacket(cn *conn, packet packets.Packet) error {
+	switch p := packet.(type) {
+	case *packets.Publish:
+		return c.handlePublish(cn, p)
+	case *packets.Pubrel:
+		c.mu.Lock()
+		delete(c.received, p.PacketID)
+		c.mu.Unlock()
+		return cn.write(p.NewPubcomp())
+	case *packets.Puback:
+		var err error
+		if p.Code >= codes.UnspecifiedError {
+			err = codeError(p.Code, p.Properties)
+		}
+		c.ack(p.PacketID, p, err)
+	case *packets.Pubrec:
+		if p.Code >= codes.UnspecifiedError {
+			c.ack(p.PacketID, p, codeError(p.Code, p.Properties))
+			return nil
+		}
+		c.mu.Lock()
+		if r, ok := c.pending[p.PacketID]; ok {
+			if pub, ok := r.packet.(*packets.Publish); ok && pub.Qos == packets.Qos2 {
+				r.pubrel = true
+			}
+		}
+		c.mu.Unlock()
+		return cn.write(p.NewPubrel())
+	case *packets.Pubcomp:
+		c.ack(p.PacketID, p, nil)
+	case *packets.Suback:
+		c.ack(p.PacketID, p, nil)
+	case *packets.Unsuback:
+		c.ack(p.PacketID, p, nil)
+	case *packets.Pingresp:
+		atomic.StoreInt32(&cn.pingOutstanding, 0)
+	case *packets.Disconnect:
+		return codeError(p.Code, p.Properties)
+	default:
+		return codes.ErrProtocol
+	}
+	return nil
+}
+
+func (c *Client) handlePublish(cn *conn, p *packets.Publish) error {
+	if cn.version == packets.Version5 && p.Properties != nil && p.Properties.TopicAlias != nil {
+		alias := *p.Properties.TopicAlias
+		if alias == 0 || alias > c.opts.topicAliasMax {
+			return codes.NewError(codes.TopicAliasInvalid)
+		}
+		if len(p.TopicName) == 0 {
+			topic, ok := cn.inAliases[alias]
+			if !ok {
+				return codes.ErrProtocol
+			}
+			p.TopicName = []byte(topic)
+		} else {
+			cn.inAliases[alias] = string(p.TopicName)
+		}
+	} else if len(p.TopicName) == 0 {
+		return codes.ErrProtocol
+	}
+	switch p.Qos {
+	case packets.Qos0:
+		c.deliver(p)
+	case packets.Qos1:
+		c.deliver(p)
+		return cn.write(p.NewPuback(codes.Success, nil))
+	case packets.Qos2:
+		c.mu.Lock()
+		_, ok := c.received[p.PacketID]
+		c.received[p.PacketID] = struct{}{}
+		c.mu.Unlock()
+		if !ok {
+			c.deliver(p)
+		}
+		return cn.write(p.NewPubrec(codes.Success, nil))
+	}
+	return nil
+}
+
+func (c *Client) deliver(p *packets.Publish) {
+	if c.opts.onMessage == nil {
+		return
+	}
+	msg := gmqtt.MessageFromPublish(p)
+	msg.PacketID = p.PacketID
+	if p.Version == packets.Version5 {
+		msg.SubscriptionIdentifier = p.Properties.SubscriptionIdentifier
+	}
+	c.opts.onMessage(c, msg)
+}
@@ -0,0 +1,351 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/config"
+	_ "github.com/DrmagicE/gmqtt/persistence"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+	_ "github.com/DrmagicE/gmqtt/topicalias/fifo"
+)
+
+// testAuth is a plugin that implements a challenge-response enhanced authentication for testing.
+type testAuth struct {
+}
+
+func (t *testAuth) Load(service server.Server) error {
+	return nil
+}
+
+func (t *testAuth) Unload() error {
+	return nil
+}
+
+func (t *testAuth) Name() string {
+	return "test_auth"
+}
+
+func (t *testAuth) HookWrapper() server.HookWrapper {
+	return server.HookWrapper{
+		OnEnhancedAuthWrapper: func(pre server.OnEnhancedAuth) server.OnEnhancedAuth {
+			return func(ctx context.Context, client server.Client, req *server.ConnectRequest) (*server.EnhancedAuthResponse, error) {
+				if !bytes.Equal(req.Connect.Properties.AuthData, []byte("hello")) {
+					return nil, codes.NewError(codes.NotAuthorized)
+				}
+				return &server.EnhancedAuthResponse{
+					Continue: true,
+					AuthData: []byte("challenge"),
+					OnAuth: func(ctx context.Context, client server.Client, req *server.AuthRequest) (*server.AuthResponse, error) {
+						if !bytes.Equal(req.Auth.Properties.AuthData, []byte("response")) {
+							return nil, codes.NewError(codes.NotAuthorized)
+						}
+						return &server.AuthResponse{}, nil
+					},
+				}, nil
+			}
+		},
+	}
+}
+
+type testAuthenticator struct {
+	challenge []byte
+	finished  bool
+}
+
+func (a *testAuthenticator) Method() string {
+	return "test"
+}
+
+func (a *testAuthenticator) Start() ([]byte, error) {
+	return []byte("hello"), nil
+}
+
+func (a *testAuthenticator) Continue(data []byte) ([]byte, error) {
+	a.challenge = data
+	return []byte("response"), nil
+}
+
+func (a *testAuthenticator) Finish(data []byte) error {
+	a.finished = true
+	return nil
+}
+
+func runServer(t *testing.T, plugins ...server.Plugin) (srv server.Server, addr string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	opts := []server.Options{
+		server.WithConfig(cfg),
+		server.WithTCPListener(ln),
+	}
+	for _, v := range plugins {
+		opts = append(opts, server.WithPlugin(v))
+	}
+	s := server.New(opts...)
+	if err := s.Run(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = s.Stop(context.Background())
+	})
+	return s, ln.Addr().String()
+}
+
+func newTestContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func readMessage(t *testing.T, ch chan *gmqtt.Message) *gmqtt.Message {
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the message")
+	}
+	return nil
+}
+
+func TestClient_PublishSubscribe(t *testing.T) {
+	_, addr := runServer(t)
+	for _, version := range []packets.Version{packets.Version311, packets.Version5} {
+		a := assert.New(t)
+		ctx := newTestContext(t)
+		msgs := make(chan *gmqtt.Message, 10)
+		sub := New(
+			WithAddress(addr),
+			WithVersion(version),
+			WithClientID("sub"),
+			WithMessageHandler(func(client *Client, msg *gmqtt.Message) {
+				msgs <- msg
+			}))
+		_, err := sub.Connect(ctx)
+		a.Nil(err)
+		suback, err := sub.Subscribe(ctx, packets.Topic{Name: "a/+", SubOptions: packets.SubOptions{Qos: packets.Qos2}})
+		a.Nil(err)
+		a.Equal([]codes.Code{codes.GrantedQoS2}, suback.Payload)
+
+		pub := New(WithAddress(addr), WithVersion(version), WithClientID("pub"))
+		connack, err := pub.Connect(ctx)
+		a.Nil(err)
+		a.False(connack.SessionPresent)
+		for qos := packets.Qos0; qos <= packets.Qos2; qos++ {
+			a.Nil(pub.Publish(ctx, &gmqtt.Message{
+				QoS:     qos,
+				Topic:   "a/b",
+				Payload: []byte{qos},
+			}))
+			msg := readMessage(t, msgs)
+			a.Equal("a/b", msg.Topic)
+			a.Equal(qos, msg.QoS)
+			a.Equal([]byte{qos}, msg.Payload)
+		}
+
+		unsuback, err := sub.Unsubscribe(ctx, "a/+")
+		a.Nil(err)
+		a.NotNil(unsuback)
+		a.Nil(pub.Publish(ctx, &gmqtt.Message{QoS: packets.Qos1, Topic: "a/b"}))
+		select {
+		case <-msgs:
+			t.Fatal("unexpected message")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		a.Nil(pub.Disconnect())
+		a.Nil(sub.Disconnect())
+		a.Equal(ErrClosed, pub.Publish(ctx, &gmqtt.Message{Topic: "a/b"}))
+	}
+}
+
+func TestClient_PublishAsync(t *testing.T) {
+	a := assert.New(t)
+	_, addr := runServer(t)
+	ctx := newTestContext(t)
+	msgs := make(chan *gmqtt.Message, 10)
+	sub := New(
+		WithAddress(addr),
+		WithClientID("sub"),
+		WithMessageHandler(func(client *Client, msg *gmqtt.Message) {
+			msgs <- msg
+		}))
+	_, err := sub.Connect(ctx)
+	a.Nil(err)
+	defer sub.Disconnect()
+	_, err = sub.Subscribe(ctx, packets.Topic{Name: "a", SubOptions: packets.SubOptions{Qos: packets.Qos2}})
+	a.Nil(err)
+
+	pub := New(WithAddress(addr), WithClientID("pub"))
+	_, err = pub.Connect(ctx)
+	a.Nil(err)
+	var results []*PublishResult
+	for i := 0; i < 9; i++ {
+		res, err := pub.PublishAsync(ctx, &gmqtt.Message{
+			QoS:     uint8(i % 3),
+			Topic:   "a",
+			Payload: []byte{byte(i)},
+		})
+		a.Nil(err)
+		results = append(results, res)
+	}
+	for _, v := range results {
+		<-v.Done()
+		a.Nil(v.Err())
+	}
+	// the messages are sent in order.
+	for i := 0; i < 9; i++ {
+		a.Equal([]byte{byte(i)}, readMessage(t, msgs).Payload)
+	}
+
+	a.Nil(pub.Disconnect())
+	_, err = pub.PublishAsync(ctx, &gmqtt.Message{QoS: packets.Qos1, Topic: "a"})
+	a.Equal(ErrClosed, err)
+}
+
+func TestClient_EnhancedAuth(t *testing.T) {
+	a := assert.New(t)
+	_, addr := runServer(t, &testAuth{})
+	ctx := newTestContext(t)
+
+	auth := &testAuthenticator{}
+	c := New(WithAddress(addr), WithAuthenticator(auth))
+	_, err := c.Connect(ctx)
+	a.Nil(err)
+	a.Equal([]byte("challenge"), auth.challenge)
+	a.True(auth.finished)
+	// the broker assigns the client id
+	a.NotEmpty(c.ClientID())
+	a.Nil(c.Disconnect())
+
+	// refused by the broker
+	c = New(WithAddress(addr), WithAuthenticator(&badAuthenticator{}))
+	_, err = c.Connect(ctx)
+	a.Equal(codes.NotAuthorized, err.(*codes.Error).Code)
+	a.False(c.IsConnected())
+}
+
+type badAuthenticator struct {
+	testAuthenticator
+}
+
+func (b *badAuthenticator) Continue(data []byte) ([]byte, error) {
+	return []byte("bad response"), nil
+}
+
+func TestClient_TopicAlias(t *testing.T) {
+	a := assert.New(t)
+	_, addr := runServer(t)
+	ctx := newTestContext(t)
+	msgs := make(chan *gmqtt.Message, 10)
+	sub := New(
+		WithAddress(addr),
+		WithTopicAliasMax(5),
+		WithMessageHandler(func(client *Client, msg *gmqtt.Message) {
+			msgs <- msg
+		}))
+	_, err := sub.Connect(ctx)
+	a.Nil(err)
+	_, err = sub.Subscribe(ctx, packets.Topic{Name: "#", SubOptions: packets.SubOptions{Qos: packets.Qos1}})
+	a.Nil(err)
+
+	pub := New(WithAddress(addr))
+	_, err = pub.Connect(ctx)
+	a.Nil(err)
+	topics := []string{"a", "b", "a", "b", "a"}
+	for _, v := range topics {
+		a.Nil(pub.Publish(ctx, &gmqtt.Message{QoS: packets.Qos1, Topic: v}))
+	}
+	for _, v := range topics {
+		a.Equal(v, readMessage(t, msgs).Topic)
+	}
+	pub.mu.Lock()
+	a.Len(pub.conn.aliases, 2)
+	pub.mu.Unlock()
+	a.Nil(pub.Disconnect())
+	a.Nil(sub.Disconnect())
+}
+
+func TestClient_Reconnect(t *testing.T) {
+	a := assert.New(t)
+	srv, addr := runServer(t)
+	ctx := newTestContext(t)
+
+	msgs := make(chan *gmqtt.Message, 10)
+	connacks := make(chan *packets.Connack, 10)
+	lost := make(chan error, 10)
+	sub := New(
+		WithAddress(addr),
+		WithClientID("sub"),
+		WithSessionExpiry(60),
+		WithAutoReconnect(10*time.Millisecond, 100*time.Millisecond),
+		WithMessageHandler(func(client *Client, msg *gmqtt.Message) {
+			msgs <- msg
+		}),
+		WithConnectHandler(func(client *Client, connack *packets.Connack) {
+			connacks <- connack
+		}),
+		WithConnectionLostHandler(func(client *Client, err error) {
+			lost <- err
+		}))
+	_, err := sub.Connect(ctx)
+	a.Nil(err)
+	a.False((<-connacks).SessionPresent)
+	_, err = sub.Subscribe(ctx, packets.Topic{Name: "a", SubOptions: packets.SubOptions{Qos: packets.Qos1}})
+	a.Nil(err)
+
+	srv.ClientService().GetClient("sub").Close()
+	<-lost
+	// the session is resumed
+	a.True((<-connacks).SessionPresent)
+
+	pub := New(WithAddress(addr), WithClientID("pub"), WithSessionExpiry(60),
+		WithAutoReconnect(10*time.Millisecond, 100*time.Millisecond))
+	_, err = pub.Connect(ctx)
+	a.Nil(err)
+	srv.ClientService().GetClient("pub").Close()
+	// the message is sent after reconnected
+	a.Nil(pub.Publish(ctx, &gmqtt.Message{QoS: packets.Qos1, Topic: "a", Payload: []byte("msg")}))
+	a.Equal([]byte("msg"), readMessage(t, msgs).Payload)
+
+	a.Nil(pub.Disconnect())
+	a.Nil(sub.Disconnect())
+	_, err = sub.Connect(ctx)
+	a.Equal(ErrClosed, err)
+}
+
+func TestClient_ConnectionLost(t *testing.T) {
+	a := assert.New(t)
+	srv, addr := runServer(t)
+	ctx := newTestContext(t)
+	lost := make(chan error, 1)
+	c := New(WithAddress(addr), WithClientID("c"), WithConnectionLostHandler(func(client *Client, err error) {
+		lost <- err
+	}))
+	_, err := c.Connect(ctx)
+	a.Nil(err)
+	_, err = c.Connect(ctx)
+	a.Equal(ErrAlreadyConnected, err)
+
+	srv.ClientService().GetClient("c").Close()
+	<-lost
+	a.Eventually(func() bool {
+		return errors.Is(c.Publish(ctx, &gmqtt.Message{QoS: packets.Qos1, Topic: "a"}), ErrNotConnected)
+	}, time.Second, 10*time.Millisecond)
+	// connect again
+	_, err = c.Connect(ctx)
+	a.Nil(err)
+	a.Nil(c.Publish(ctx, &gmqtt.Message{QoS: packets.Qos1, Topic: "a"}))
+	a.Nil(c.Disconnect())
+}
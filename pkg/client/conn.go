@@ -0,0 +1,367 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+// conn is a network connection to the broker.
+type conn struct {
+	c       *Client
+	nc      net.Conn
+	reader  *packets.Reader
+	wmu     sync.Mutex
+	writer  *packets.Writer
+	version packets.Version
+
+	keepAlive uint16
+	// receiveMax is the receive maximum of the broker.
+	receiveMax uint16
+	// aliasMax is the topic alias maximum of the broker.
+	aliasMax uint16
+	// aliases stores the topic aliases sent to the broker, guarded by wmu.
+	aliases map[string]uint16
+	// inAliases stores the topic aliases sent by the broker, only accessed in readLoop.
+	inAliases map[uint16]string
+	// pingOutstanding is set to 1 when a PINGREQ is sent and reset to 0 when the PINGRESP is received.
+	pingOutstanding int32
+
+	closeOnce sync.Once
+	done      chan struct{}
+	err       error
+	wg        sync.WaitGroup
+}
+
+func (c *Client) dial(ctx context.Context) (net.Conn, error) {
+	if c.opts.dial != nil {
+		return c.opts.dial(ctx)
+	}
+	d := &net.Dialer{}
+	nc, err := d.DialContext(ctx, "tcp", c.opts.address)
+	if err != nil {
+		return nil, err
+	}
+	if c.opts.tlsConfig == nil {
+		return nc, nil
+	}
+	cfg := c.opts.tlsConfig.Clone()
+	if cfg.ServerName == "" {
+		cfg.ServerName, _, _ = net.SplitHostPort(c.opts.address)
+	}
+	tc := tls.Client(nc, cfg)
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = tc.SetDeadline(deadline)
+	}
+	if err = tc.Handshake(); err != nil {
+		_ = nc.Close()
+		return nil, err
+	}
+	return tc, nil
+}
+
+// connect dials a new connection and completes the MQTT handshake.
+func (c *Client) connect(ctx context.Context, cleanStart bool) (*conn, *packets.Connack, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.connectTimeout)
+	defer cancel()
+	nc, err := c.dial(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	deadline, _ := ctx.Deadline()
+	_ = nc.SetDeadline(deadline)
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			// interrupt the blocking read and write.
+			_ = nc.SetDeadline(time.Unix(1, 0))
+		case <-stop:
+		}
+	}()
+	cn := &conn{
+		c:          c,
+		nc:         nc,
+		reader:     packets.NewReader(nc),
+		writer:     packets.NewWriter(nc),
+		version:    c.opts.version,
+		keepAlive:  c.opts.keepAlive,
+		receiveMax: packets.MaxPacketID,
+		aliases:    make(map[string]uint16),
+		inAliases:  make(map[uint16]string),
+		done:       make(chan struct{}),
+	}
+	cn.reader.SetVersion(cn.version)
+	cn.reader.SetMaxPacketSize(c.opts.maxPacketSize)
+	connack, err := cn.handshake(cleanStart)
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		_ = nc.Close()
+		return nil, nil, err
+	}
+	_ = nc.SetDeadline(time.Time{})
+	return cn, connack, nil
+}
+
+func (cn *conn) newConnect(cleanStart bool) (*packets.Connect, error) {
+	opts := cn.c.opts
+	connect := &packets.Connect{
+		Version:       cn.version,
+		ProtocolName:  []byte("MQTT"),
+		ProtocolLevel: cn.version,
+		CleanStart:    cleanStart,
+		KeepAlive:     opts.keepAlive,
+		ClientID:      []byte(cn.c.ClientID()),
+	}
+	if opts.username != "" {
+		connect.UsernameFlag = true
+		connect.Username = []byte(opts.username)
+	}
+	if opts.password != nil {
+		connect.PasswordFlag = true
+		connect.Password = opts.password
+	}
+	if will := opts.will; will != nil {
+		connect.WillFlag = true
+		connect.WillTopic = []byte(will.Topic)
+		connect.WillMsg = will.Payload
+		connect.WillQos = will.QoS
+		connect.WillRetain = will.Retained
+		if cn.version == packets.Version5 {
+			connect.WillProperties = gmqtt.MessageToPublish(will, cn.version).Properties
+		}
+	}
+	if cn.version != packets.Version5 {
+		return connect, nil
+	}
+	connect.Properties = &packets.Properties{}
+	if opts.sessionExpiry != 0 {
+		connect.Properties.SessionExpiryInterval = &opts.sessionExpiry
+	}
+	if opts.receiveMax != 0 {
+		connect.Properties.ReceiveMaximum = &opts.receiveMax
+	}
+	if opts.topicAliasMax != 0 {
+		connect.Properties.TopicAliasMaximum = &opts.topicAliasMax
+	}
+	if opts.maxPacketSize != 0 {
+		connect.Properties.MaximumPacketSize = &opts.maxPacketSize
+	}
+	if a := opts.authenticator; a != nil {
+		data, err := a.Start()
+		if err != nil {
+			return nil, err
+		}
+		connect.Properties.AuthMethod = []byte(a.Method())
+		connect.Properties.AuthData = data
+	}
+	return connect, nil
+}
+
+// handshake sends the CONNECT packet, performs the enhanced authentication if enabled and waits for the CONNACK.
+func (cn *conn) handshake(cleanStart bool) (*packets.Connack, error) {
+	connect, err := cn.newConnect(cleanStart)
+	if err != nil {
+		return nil, err
+	}
+	if err = cn.writer.WriteAndFlush(connect); err != nil {
+		return nil, err
+	}
+	authenticator := cn.c.opts.authenticator
+	for {
+		p, err := cn.reader.ReadPacket()
+		if err != nil {
+			return nil, err
+		}
+		switch p := p.(type) {
+		case *packets.Connack:
+			return p, cn.connack(p)
+		case *packets.Auth:
+			if authenticator == nil || cn.version != packets.Version5 || p.Code != codes.ContinueAuthentication ||
+				string(p.Properties.AuthMethod) != authenticator.Method() {
+				return nil, codes.ErrProtocol
+			}
+			data, err := authenticator.Continue(p.Properties.AuthData)
+			if err != nil {
+				return nil, err
+			}
+			err = cn.writer.WriteAndFlush(&packets.Auth{
+				Code: codes.ContinueAuthentication,
+				Properties: &packets.Properties{
+					AuthMethod: []byte(authenticator.Method()),
+					AuthData:   data,
+				},
+			})
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unexpected packet: %s", p)
+		}
+	}
+}
+
+// connack applies the CONNACK packet to the connection.
+func (cn *conn) connack(p *packets.Connack) error {
+	if p.Code != codes.Success {
+		return codeError(p.Code, p.Properties)
+	}
+	if cn.version != packets.Version5 || p.Properties == nil {
+		return nil
+	}
+	ppt := p.Properties
+	if ppt.ServerKeepAlive != nil {
+		cn.keepAlive = *ppt.ServerKeepAlive
+	}
+	if ppt.ReceiveMaximum != nil {
+		cn.receiveMax = *ppt.ReceiveMaximum
+	}
+	if ppt.TopicAliasMaximum != nil {
+		cn.aliasMax = *ppt.TopicAliasMaximum
+	}
+	if len(ppt.AssignedClientID) != 0 {
+		cn.c.setClientID(string(ppt.AssignedClientID))
+	}
+	if a := cn.c.opts.authenticator; a != nil {
+		return a.Finish(ppt.AuthData)
+	}
+	return nil
+}
+
+// codeError returns the error of the reason code and the reason string in the properties.
+func codeError(code codes.Code, ppt *packets.Properties) error {
+	err := &codes.Error{Code: code}
+	if ppt != nil {
+		err.ReasonString = ppt.ReasonString
+	}
+	return err
+}
+
+func (cn *conn) start() {
+	cn.wg.Add(2)
+	go cn.readLoop()
+	go cn.keepAliveLoop()
+}
+
+// write writes the packet to the broker, the connection is closed if any error occurs.
+func (cn *conn) write(p packets.Packet) error {
+	cn.wmu.Lock()
+	defer cn.wmu.Unlock()
+	// Pack modifies the FixHeader of the packet, so the packets shared with the pending requests are copied.
+	switch pp := p.(type) {
+	case *packets.Publish:
+		p = cn.aliasPublish(pp)
+	case *packets.Subscribe:
+		cp := *pp
+		p = &cp
+	case *packets.Unsubscribe:
+		cp := *pp
+		p = &cp
+	}
+	_ = cn.nc.SetWriteDeadline(time.Now().Add(cn.c.opts.writeTimeout))
+	err := cn.writer.WriteAndFlush(p)
+	if err != nil {
+		cn.closeWithErr(err)
+	}
+	return err
+}
+
+// aliasPublish returns a copy of the publish packet, with the topic replaced by the topic alias if possible.
+// The aliases are assigned in the order of the topics until the topic alias maximum of the broker is reached.
+func (cn *conn) aliasPublish(pub *packets.Publish) *packets.Publish {
+	cp := *pub
+	if cn.version != packets.Version5 || cn.aliasMax == 0 {
+		return &cp
+	}
+	topic := string(pub.TopicName)
+	alias, ok := cn.aliases[topic]
+	if !ok {
+		if len(cn.aliases) >= int(cn.aliasMax) {
+			return &cp
+		}
+		alias = uint16(len(cn.aliases) + 1)
+		cn.aliases[topic] = alias
+	}
+	ppt := packets.Properties{}
+	if pub.Properties != nil {
+		ppt = *pub.Properties
+	}
+	ppt.TopicAlias = &alias
+	cp.Properties = &ppt
+	if ok {
+		cp.TopicName = nil
+	}
+	return &cp
+}
+
+func (cn *conn) closeWithErr(err error) {
+	cn.closeOnce.Do(func() {
+		cn.err = err
+		close(cn.done)
+		_ = cn.nc.Close()
+	})
+}
+
+// wait waits for the connection to be closed and returns the reason.
+func (cn *conn) wait() error {
+	<-cn.done
+	cn.wg.Wait()
+	return cn.err
+}
+
+func (cn *conn) readLoop() {
+	defer cn.wg.Done()
+	for {
+		p, err := cn.reader.ReadPacket()
+		if err != nil {
+			cn.closeWithErr(err)
+			return
+		}
+		if err = cn.c.handlePacket(cn, p); err != nil {
+			var ce *codes.Error
+			if _, ok := p.(*packets.Disconnect); !ok && cn.version == packets.Version5 && errors.As(err, &ce) {
+				_ = cn.write(&packets.Disconnect{
+					Version: cn.version,
+					Code:    ce.Code,
+				})
+			}
+			cn.closeWithErr(err)
+			return
+		}
+	}
+}
+
+func (cn *conn) keepAliveLoop() {
+	defer cn.wg.Done()
+	if cn.keepAlive == 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(cn.keepAlive) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-cn.done:
+			return
+		case <-ticker.C:
+			if !atomic.CompareAndSwapInt32(&cn.pingOutstanding, 0, 1) {
+				cn.closeWithErr(ErrKeepAliveTimeout)
+				return
+			}
+			if cn.write(&packets.Pingreq{}) != nil {
+				return
+			}
+		}
+	}
+}
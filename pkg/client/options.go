@@ -0,0 +1,225 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+// DialFunc dials the connection to the broker.
+type DialFunc func(ctx context.Context) (net.Conn, error)
+
+// Authenticator performs the MQTT v5 enhanced authentication.
+type Authenticator interface {
+	// Method returns the authentication method.
+	Method() string
+	// Start returns the initial authentication data sent in the CONNECT packet.
+	Start() ([]byte, error)
+	// Continue returns the response to the authentication data of the AUTH packet sent by the broker.
+	Continue(data []byte) ([]byte, error)
+	// Finish is called with the authentication data of the successful CONNACK packet.
+	Finish(data []byte) error
+}
+
+// MessageHandler is called when an application message is received.
+// The client reads the next packet after the handler returns, so the handler should not block for a long time,
+// and must not wait for the acknowledgements of the client, e.g. publishing a QoS 1 message and waiting for the PUBACK.
+type MessageHandler func(client *Client, msg *gmqtt.Message)
+
+// ConnectHandler is called when the client connects or reconnects to the broker.
+// If the SessionPresent of the connack is false, the subscriptions have been lost,
+// and the handler is the place to subscribe again.
+type ConnectHandler func(client *Client, connack *packets.Connack)
+
+// ConnectionLostHandler is called when the connection is lost unexpectedly.
+type ConnectionLostHandler func(client *Client, err error)
+
+type options struct {
+	address              string
+	tlsConfig            *tls.Config
+	dial                 DialFunc
+	version              packets.Version
+	clientID             string
+	username             string
+	password             []byte
+	cleanStart           bool
+	keepAlive            uint16
+	sessionExpiry        uint32
+	receiveMax           uint16
+	topicAliasMax        uint16
+	maxPacketSize        uint32
+	will                 *gmqtt.Message
+	authenticator        Authenticator
+	connectTimeout       time.Duration
+	writeTimeout         time.Duration
+	autoReconnect        bool
+	reconnectInterval    time.Duration
+	maxReconnectInterval time.Duration
+	onMessage            MessageHandler
+	onConnected          ConnectHandler
+	onConnectionLost     ConnectionLostHandler
+}
+
+func defaultOptions() options {
+	return options{
+		address:              "127.0.0.1:1883",
+		version:              packets.Version5,
+		cleanStart:           true,
+		keepAlive:            60,
+		connectTimeout:       5 * time.Second,
+		writeTimeout:         5 * time.Second,
+		reconnectInterval:    time.Second,
+		maxReconnectInterval: time.Minute,
+	}
+}
+
+type Options func(opts *options)
+
+// WithAddress sets the TCP address of the broker, default to 127.0.0.1:1883.
+func WithAddress(address string) Options {
+	return func(opts *options) {
+		opts.address = address
+	}
+}
+
+// WithTLSConfig enables TLS with the given tls config.
+func WithTLSConfig(cfg *tls.Config) Options {
+	return func(opts *options) {
+		opts.tlsConfig = cfg
+	}
+}
+
+// WithDialer sets the function to dial the connection, the address and tls config are ignored if it is set.
+func WithDialer(dial DialFunc) Options {
+	return func(opts *options) {
+		opts.dial = dial
+	}
+}
+
+// WithVersion sets the protocol version, default to v5.
+func WithVersion(version packets.Version) Options {
+	return func(opts *options) {
+		opts.version = version
+	}
+}
+
+// WithClientID sets the client id. For v5, the broker assigns a client id if it is empty.
+func WithClientID(clientID string) Options {
+	return func(opts *options) {
+		opts.clientID = clientID
+	}
+}
+
+// WithUsernamePassword sets the username and password.
+func WithUsernamePassword(username string, password []byte) Options {
+	return func(opts *options) {
+		opts.username = username
+		opts.password = password
+	}
+}
+
+// WithCleanStart sets the clean start (clean session in v3) flag of the first connection, default to true.
+// The reconnections of v5 clients always set the flag to false to resume the session.
+func WithCleanStart(cleanStart bool) Options {
+	return func(opts *options) {
+		opts.cleanStart = cleanStart
+	}
+}
+
+// WithKeepAlive sets the keep alive in seconds, default to 60.
+func WithKeepAlive(keepAlive uint16) Options {
+	return func(opts *options) {
+		opts.keepAlive = keepAlive
+	}
+}
+
+// WithSessionExpiry sets the session expiry interval in seconds (v5 only).
+func WithSessionExpiry(sessionExpiry uint32) Options {
+	return func(opts *options) {
+		opts.sessionExpiry = sessionExpiry
+	}
+}
+
+// WithReceiveMax sets the receive maximum (v5 only).
+func WithReceiveMax(receiveMax uint16) Options {
+	return func(opts *options) {
+		opts.receiveMax = receiveMax
+	}
+}
+
+// WithTopicAliasMax sets the topic alias maximum that the client accepts from the broker (v5 only).
+func WithTopicAliasMax(topicAliasMax uint16) Options {
+	return func(opts *options) {
+		opts.topicAliasMax = topicAliasMax
+	}
+}
+
+// WithMaxPacketSize sets the maximum packet size that the client accepts, 0 means unlimited.
+func WithMaxPacketSize(maxPacketSize uint32) Options {
+	return func(opts *options) {
+		opts.maxPacketSize = maxPacketSize
+	}
+}
+
+// WithWill sets the will message.
+func WithWill(will *gmqtt.Message) Options {
+	return func(opts *options) {
+		opts.will = will
+	}
+}
+
+// WithAuthenticator enables the enhanced authentication (v5 only).
+func WithAuthenticator(authenticator Authenticator) Options {
+	return func(opts *options) {
+		opts.authenticator = authenticator
+	}
+}
+
+// WithConnectTimeout sets the timeout of dialing and waiting for the CONNACK, default to 5s.
+func WithConnectTimeout(timeout time.Duration) Options {
+	return func(opts *options) {
+		opts.connectTimeout = timeout
+	}
+}
+
+// WithWriteTimeout sets the timeout of writing a packet, default to 5s.
+func WithWriteTimeout(timeout time.Duration) Options {
+	return func(opts *options) {
+		opts.writeTimeout = timeout
+	}
+}
+
+// WithAutoReconnect enables the automatic reconnection.
+// The reconnect interval starts from interval and doubles after each failure until maxInterval.
+func WithAutoReconnect(interval, maxInterval time.Duration) Options {
+	return func(opts *options) {
+		opts.autoReconnect = true
+		opts.reconnectInterval = interval
+		opts.maxReconnectInterval = maxInterval
+	}
+}
+
+// WithMessageHandler sets the handler of the received application messages.
+func WithMessageHandler(handler MessageHandler) Options {
+	return func(opts *options) {
+		opts.onMessage = handler
+	}
+}
+
+// WithConnectHandler sets the handler which is called after each successful connection.
+func WithConnectHandler(handler ConnectHandler) Options {
+	return func(opts *options) {
+		opts.onConnected = handler
+	}
+}
+
+// WithConnectionLostHandler sets the handler which is called when the connection is lost.
+func WithConnectionLostHandler(handler ConnectionLostHandler) Options {
+	return func(opts *options) {
+		opts.onConnectionLost = handler
+	}
+}
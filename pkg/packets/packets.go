@@ -127,10 +127,15 @@ type SubOptions struct {
 	RetainAsPublished bool
 }
 
+// defaultMaxPacketSize is the default maximum packet size that the Reader accepts.
+const defaultMaxPacketSize = 1234
+
 // Reader is used to read data from bufio.Reader and create MQTT packet instance.
 type Reader struct {
 	bufr    *bufio.Reader
 	version Version
+	// maxPacketSize is the maximum packet size that the Reader accepts, 0 means unlimited.
+	maxPacketSize uint32
 }
 
 // Writer is used to encode MQTT packet into bytes and write it to bufio.Writer.
@@ -152,15 +157,20 @@ type ReadWriter struct {
 // NewReader returns a new Reader.
 func NewReader(r io.Reader) *Reader {
 	if bufr, ok := r.(*bufio.Reader); ok {
-		return &Reader{bufr: bufr, version: Version311}
+		return &Reader{bufr: bufr, version: Version311, maxPacketSize: defaultMaxPacketSize}
 	}
-	return &Reader{bufr: bufio.NewReaderSize(r, 2048), version: Version311}
+	return &Reader{bufr: bufio.NewReaderSize(r, 2048), version: Version311, maxPacketSize: defaultMaxPacketSize}
 }
 
 func (r *Reader) SetVersion(version Version) {
 	r.version = version
 }
 
+// SetMaxPacketSize sets the maximum packet size that the Reader accepts, 0 means unlimited.
+func (r *Reader) SetMaxPacketSize(size uint32) {
+	r.maxPacketSize = size
+}
+
 // NewWriter returns a new Writer.
 func NewWriter(w io.Writer) *Writer {
 	if bufw, ok := w.(*bufio.Writer); ok {
@@ -178,7 +188,9 @@ func (r *Reader) ReadPacket() (Packet, error) {
 	}
 	fh := &FixHeader{PacketType: first >> 4, Flags: first & 15} //设置FixHeader
 	length, err := EncodeRemainLength(r.bufr)
-
+	if err != nil {
+		return nil, err
+	}
 	var headerLen int
 	if length <= 127 {
 		headerLen = 2
@@ -189,13 +201,9 @@ func (r *Reader) ReadPacket() (Packet, error) {
 	} else if length <= 268435455 {
 		headerLen = 5
 	}
-	if headerLen+length > 1234 {
+	if r.maxPacketSize != 0 && uint32(headerLen+length) > r.maxPacketSize {
 		return nil, codes.NewError(codes.RecvMaxExceeded)
 	}
-
-	if err != nil {
-		return nil, err
-	}
 	fh.RemainLength = length
 	packet, err := NewPacket(fh, r.version, r.bufr)
 	if err != nil {
@@ -240,3 +240,28 @@ func TestTopicMatch(t *testing.T) {
 		}
 	}
 }
+
+func TestReader_SetMaxPacketSize(t *testing.T) {
+	b := &bytes.Buffer{}
+	pub := &Publish{
+		Version:   Version311,
+		TopicName: []byte("a"),
+		Payload:   make([]byte, 2000),
+	}
+	if err := NewWriter(b).WriteAndFlush(pub); err != nil {
+		t.Fatal(err)
+	}
+	raw := b.Bytes()
+	if _, err := NewReader(bytes.NewReader(raw)).ReadPacket(); err == nil {
+		t.Fatalf("ReadPacket() error, want error, but nil")
+	}
+	r := NewReader(bytes.NewReader(raw))
+	r.SetMaxPacketSize(0)
+	p, err := r.ReadPacket()
+	if err != nil {
+		t.Fatalf("ReadPacket() error, want nil, but %s", err)
+	}
+	if l := len(p.(*Publish).Payload); l != 2000 {
+		t.Fatalf("ReadPacket() error, want payload length 2000, but %d", l)
+	}
+}
@@ -0,0 +1,158 @@
+package acl
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"gopkg.in/yaml.v2"
+
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+var _ server.Plugin = (*ACL)(nil)
+var _ server.Reloadable = (*ACL)(nil)
+
+const Name = "acl"
+
+func init() {
+	server.RegisterPlugin(Name, New)
+	config.RegisterDefaultPluginConfig(Name, &DefaultConfig)
+}
+
+func New(config config.Config) (server.Plugin, error) {
+	a := &ACL{
+		config: config.Plugins[Name].(*Config),
+	}
+	a.saveFile = a.saveFileHandler
+	return a, nil
+}
+
+var log *zap.Logger
+
+// ACL provides the topic-level access control for gmqtt.
+// The rules are evaluated in order and the first matched rule is applied.
+// The rules are persist in config.ACLFile.
+type ACL struct {
+	// guard config & rules
+	mu     sync.RWMutex
+	config *Config
+	rules  []*rule
+	// saveFile persists the rules to the acl file.
+	saveFile func() error
+}
+
+// readFile reads and validates the rules from the acl file.
+func readFile(aclFile string) ([]*rule, error) {
+	f, err := os.OpenFile(aclFile, os.O_CREATE|os.O_RDONLY, 0666)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	var rules []*rule
+	err = yaml.Unmarshal(b, &rules)
+	if err != nil {
+		return nil, err
+	}
+	return rules, validateRules(rules)
+}
+
+// load loads the rules from the acl file.
+func (a *ACL) load() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.loadConfigLocked(a.config)
+}
+
+// loadConfigLocked loads the rules from the acl file of the given config, and replaces the current config and rules,
+// must call after acl.mu is locked.
+// The current config and rules will be kept if the acl file is invalid.
+func (a *ACL) loadConfigLocked(cfg *Config) error {
+	rules, err := readFile(cfg.ACLFile)
+	if err != nil {
+		return err
+	}
+	a.config = cfg
+	a.rules = rules
+	log.Info("acl rules loaded",
+		zap.Int("rule_nums", len(rules)),
+		zap.String("acl_file", cfg.ACLFile))
+	return nil
+}
+
+// saveFileHandler is the default handler for acl.saveFile, must call after acl.mu is locked
+func (a *ACL) saveFileHandler() error {
+	tmpfile, err := ioutil.TempFile("", "gmqtt_acl")
+	if err != nil {
+		return err
+	}
+	defer tmpfile.Close()
+
+	w := bufio.NewWriter(tmpfile)
+	b, err := yaml.Marshal(a.rules)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	if err != nil {
+		return err
+	}
+	err = w.Flush()
+	if err != nil {
+		return err
+	}
+	// replace the old acl file.
+	return os.Rename(tmpfile.Name(), a.config.ACLFile)
+}
+
+// check returns whether the action on the topic is allowed and the maximum QoS level that is allowed.
+// The returned maxQoS is nil if there is no limit.
+func (a *ACL) check(action, username, clientID, topic string) (allowed bool, maxQoS *uint8) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for _, v := range a.rules {
+		if v.match(action, username, clientID, topic) {
+			return v.Permission == Allow, v.MaxQoS
+		}
+	}
+	return a.config.NoMatch == Allow, nil
+}
+
+func (a *ACL) RegisterGRPC(s grpc.ServiceRegistrar) {
+	RegisterACLServiceServer(s, &ruleService{a: a})
+}
+
+func (a *ACL) RegisterHTTP(ctx context.Context, mux *runtime.ServeMux, endpoint string, opts []grpc.DialOption) (err error) {
+	return RegisterACLServiceHandlerFromEndpoint(ctx, mux, endpoint, opts)
+}
+
+func (a *ACL) Load(service server.Server) error {
+	log = server.LoggerWithField(zap.String("plugin", Name))
+	return a.load()
+}
+
+// Reload applies the new config and reloads the rules from the acl file.
+// The current config and rules will be kept if the acl file is invalid.
+func (a *ACL) Reload(config config.Config) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.loadConfigLocked(config.Plugins[Name].(*Config))
+}
+
+func (a *ACL) Unload() error {
+	return nil
+}
+
+func (a *ACL) Name() string {
+	return Name
+}
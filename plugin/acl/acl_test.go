@@ -0,0 +1,158 @@
+package acl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt/config"
+)
+
+func newTestACL(t *testing.T, path string) *ACL {
+	cfg := DefaultConfig
+	cfg.ACLFile = path
+	p, err := New(config.Config{
+		Plugins: map[string]config.Configuration{
+			Name: &cfg,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Load(nil); err != nil {
+		t.Fatal(err)
+	}
+	return p.(*ACL)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	a := assert.New(t)
+	c := DefaultConfig
+	a.Nil(c.Validate())
+	c.NoMatch = "abc"
+	a.Error(c.Validate())
+	c = DefaultConfig
+	c.ACLFile = ""
+	a.Error(c.Validate())
+}
+
+func TestRule_validate(t *testing.T) {
+	a := assert.New(t)
+	qos := uint8(3)
+	for _, v := range []*rule{
+		{Permission: "abc", Action: Publish, Topics: []string{"a"}},
+		{Permission: Allow, Action: "abc", Topics: []string{"a"}},
+		{Permission: Allow, Action: Publish},
+		{Permission: Allow, Action: Publish, Topics: []string{"a/#/b"}},
+		{Permission: Allow, Action: Publish, Topics: []string{"a"}, MaxQoS: &qos},
+	} {
+		a.Error(v.validate())
+	}
+}
+
+func TestExpand(t *testing.T) {
+	a := assert.New(t)
+	rs, ok := expand("users/%u/%c/#", "user", "client")
+	a.True(ok)
+	a.Equal("users/user/client/#", rs)
+
+	rs, ok = expand("a/b", "", "")
+	a.True(ok)
+	a.Equal("a/b", rs)
+
+	for _, v := range []string{"", "+", "#", "a/b"} {
+		_, ok = expand("users/%u", v, "client")
+		a.False(ok, v)
+	}
+}
+
+func TestCovers(t *testing.T) {
+	a := assert.New(t)
+	for _, v := range []struct {
+		pattern string
+		filter  string
+		rs      bool
+	}{
+		{"a/b", "a/b", true},
+		{"a/b", "a/c", false},
+		{"a/+", "a/b", true},
+		{"a/+", "a/+", true},
+		{"a/+", "a/#", false},
+		{"a/+", "a/b/c", false},
+		{"a/#", "a", true},
+		{"a/#", "a/b/c", true},
+		{"a/#", "a/#", true},
+		{"a/b", "a/+", false},
+		{"#", "a/b", true},
+		{"#", "$SYS/a", false},
+		{"+/a", "$SYS/a", false},
+		{"$SYS/#", "$SYS/a", true},
+	} {
+		a.Equal(v.rs, covers(v.pattern, v.filter), v.pattern+" "+v.filter)
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	a := assert.New(t)
+	for _, v := range []struct {
+		pattern string
+		filter  string
+		rs      bool
+	}{
+		{"a/b", "a/b", true},
+		{"a/b", "a/c", false},
+		{"a/b", "a/+", true},
+		{"a/b", "a/#", true},
+		{"a/b", "#", true},
+		{"a/b/c", "a/+", false},
+		{"a/#", "a", true},
+		{"a", "a/#", true},
+		{"a/+", "+/b", true},
+		{"secret/#", "$SYS/#", false},
+		{"#", "$SYS/a", false},
+		{"$SYS/a", "#", false},
+		{"$SYS/#", "$SYS/+", true},
+	} {
+		a.Equal(v.rs, overlaps(v.pattern, v.filter), v.pattern+" "+v.filter)
+	}
+}
+
+func TestACL_check(t *testing.T) {
+	a := assert.New(t)
+	acl := newTestACL(t, "./testdata/gmqtt_acl.yml")
+	for _, v := range []struct {
+		action   string
+		username string
+		clientID string
+		topic    string
+		allowed  bool
+		maxQoS   *uint8
+	}{
+		{action: Subscribe, username: "admin", topic: "secret/a", allowed: true},
+		{action: Subscribe, username: "user", topic: "secret/a", allowed: false},
+		// the deny rule overlaps the filter.
+		{action: Subscribe, username: "user", topic: "#", allowed: false},
+		{action: Publish, username: "user", topic: "users/user/a", allowed: true},
+		{action: Publish, username: "user", topic: "users/other/a", allowed: false},
+		{action: Subscribe, clientID: "client", topic: "clients/client/#", allowed: true},
+		{action: Subscribe, clientID: "client", topic: "clients/+/#", allowed: false},
+		// no username
+		{action: Subscribe, clientID: "client", topic: "users//a", allowed: false},
+		{action: Subscribe, topic: "public/a", allowed: true, maxQoS: uint8Ptr(1)},
+		{action: Publish, topic: "public/a", allowed: false},
+		{action: Publish, topic: "telemetry/a", allowed: true, maxQoS: uint8Ptr(0)},
+		{action: Subscribe, topic: "telemetry/a", allowed: false},
+	} {
+		allowed, maxQoS := acl.check(v.action, v.username, v.clientID, v.topic)
+		a.Equal(v.allowed, allowed, v)
+		a.Equal(v.maxQoS, maxQoS, v)
+	}
+
+	acl.config.NoMatch = Allow
+	allowed, _ := acl.check(Publish, "user", "client", "not/match")
+	a.True(allowed)
+}
+
+func uint8Ptr(i uint8) *uint8 {
+	return &i
+}
@@ -0,0 +1,60 @@
+package acl
+
+import (
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+func init() {
+	d, err := homedir.Dir()
+	if err != nil {
+		panic(fmt.Sprintf("cannot get home dir: %s", err))
+	}
+	DefaultConfig.ACLFile = path.Join(d, "gmqtt_acl.yml")
+}
+
+// Config is the configuration for the acl plugin.
+type Config struct {
+	// ACLFile is the file to store the acl rules.
+	ACLFile string `yaml:"acl_file"`
+	// NoMatch is the permission when no rule matches.
+	// Possible values: allow | deny
+	NoMatch string `yaml:"no_match"`
+}
+
+// Validate validates the configuration, and return an error if it is invalid.
+func (c *Config) Validate() error {
+	if c.ACLFile == "" {
+		return errors.New("acl_file must be set")
+	}
+	if c.NoMatch != Allow && c.NoMatch != Deny {
+		return fmt.Errorf("invalid no_match: %s", c.NoMatch)
+	}
+	return nil
+}
+
+// DefaultConfig is the default configuration.
+var DefaultConfig = Config{
+	NoMatch: Deny,
+}
+
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type cfg Config
+	var v = &struct {
+		ACL cfg `yaml:"acl"`
+	}{
+		ACL: cfg(DefaultConfig),
+	}
+	if err := unmarshal(v); err != nil {
+		return err
+	}
+	empty := cfg(Config{})
+	if v.ACL == empty {
+		v.ACL = cfg(DefaultConfig)
+	}
+	*c = Config(v.ACL)
+	return nil
+}
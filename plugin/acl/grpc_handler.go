@@ -0,0 +1,115 @@
+package acl
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt/plugin/admin"
+)
+
+// ruleService is the gRPC handler of the ACLService.
+type ruleService struct {
+	a *ACL
+}
+
+func (s *ruleService) mustEmbedUnimplementedACLServiceServer() {
+	return
+}
+
+// List lists the rules in order.
+func (s *ruleService) List(ctx context.Context, req *ListRulesRequest) (resp *ListRulesResponse, err error) {
+	page, pageSize := admin.GetPage(req.Page, req.PageSize)
+	offset, n := admin.GetOffsetN(page, pageSize)
+	s.a.mu.RLock()
+	defer s.a.mu.RUnlock()
+	resp = &ListRulesResponse{
+		Rules:      []*Rule{},
+		TotalCount: uint32(len(s.a.rules)),
+	}
+	for i := offset; i < offset+n && i < uint(len(s.a.rules)); i++ {
+		resp.Rules = append(resp.Rules, ruleToProto(s.a.rules[i]))
+	}
+	return resp, nil
+}
+
+// setRulesLocked replaces the rules and persists them to the acl file, must call after acl.mu is locked.
+// The rules will be rollback if failed to persist to file.
+func (a *ACL) setRulesLocked(rules []*rule) error {
+	old := a.rules
+	a.rules = rules
+	if err := a.saveFile(); err != nil {
+		a.rules = old
+		return err
+	}
+	return nil
+}
+
+// Add adds a rule to the end of the rule list.
+// Add will persist the rules to the acl file.
+func (s *ruleService) Add(ctx context.Context, req *AddRuleRequest) (resp *empty.Empty, err error) {
+	if req.Rule == nil {
+		return nil, admin.ErrInvalidArgument("rule", "cannot be empty")
+	}
+	r, err := ruleFromProto(req.Rule)
+	if err != nil {
+		return nil, admin.ErrInvalidArgument("rule", err.Error())
+	}
+	s.a.mu.Lock()
+	defer s.a.mu.Unlock()
+	rules := make([]*rule, len(s.a.rules), len(s.a.rules)+1)
+	copy(rules, s.a.rules)
+	if err = s.a.setRulesLocked(append(rules, r)); err != nil {
+		return &empty.Empty{}, err
+	}
+	log.Info("acl rule added", zap.Int("rule_nums", len(s.a.rules)))
+	return &empty.Empty{}, nil
+}
+
+// Set replaces all rules with the given rules.
+// Set will persist the rules to the acl file.
+func (s *ruleService) Set(ctx context.Context, req *SetRulesRequest) (resp *empty.Empty, err error) {
+	rules := make([]*rule, 0, len(req.Rules))
+	for _, v := range req.Rules {
+		r, err := ruleFromProto(v)
+		if err != nil {
+			return nil, admin.ErrInvalidArgument("rules", err.Error())
+		}
+		rules = append(rules, r)
+	}
+	s.a.mu.Lock()
+	defer s.a.mu.Unlock()
+	if err = s.a.setRulesLocked(rules); err != nil {
+		return &empty.Empty{}, err
+	}
+	log.Info("acl rules updated", zap.Int("rule_nums", len(rules)))
+	return &empty.Empty{}, nil
+}
+
+// Delete deletes the rule at the given index.
+// Return NotFound error when the index is out of range.
+func (s *ruleService) Delete(ctx context.Context, req *DeleteRuleRequest) (resp *empty.Empty, err error) {
+	s.a.mu.Lock()
+	defer s.a.mu.Unlock()
+	if int(req.Index) >= len(s.a.rules) {
+		return nil, admin.ErrNotFound
+	}
+	rules := make([]*rule, 0, len(s.a.rules)-1)
+	rules = append(rules, s.a.rules[:req.Index]...)
+	rules = append(rules, s.a.rules[req.Index+1:]...)
+	if err = s.a.setRulesLocked(rules); err != nil {
+		return &empty.Empty{}, err
+	}
+	log.Info("acl rule deleted", zap.Uint32("index", req.Index))
+	return &empty.Empty{}, nil
+}
+
+// Reload reloads the rules from the acl file.
+// The current rules will be kept if the acl file is invalid.
+func (s *ruleService) Reload(ctx context.Context, req *empty.Empty) (resp *empty.Empty, err error) {
+	if err = s.a.load(); err != nil {
+		return nil, admin.ErrInvalidArgument("acl file", err.Error())
+	}
+	return &empty.Empty{}, nil
+}
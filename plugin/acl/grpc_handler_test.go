@@ -0,0 +1,183 @@
+package acl
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DrmagicE/gmqtt/config"
+)
+
+func TestACL_List(t *testing.T) {
+	a := assert.New(t)
+	acl := newTestACL(t, "./testdata/gmqtt_acl.yml")
+	svc := &ruleService{a: acl}
+	resp, err := svc.List(context.Background(), &ListRulesRequest{})
+	a.Nil(err)
+	a.EqualValues(5, resp.TotalCount)
+	a.Len(resp.Rules, 5)
+	a.Equal(&Rule{
+		Permission: Allow,
+		Action:     Subscribe,
+		Topics:     []string{"public/#"},
+		MaxQos:     &wrappers.UInt32Value{Value: 1},
+	}, resp.Rules[3])
+
+	resp, err = svc.List(context.Background(), &ListRulesRequest{
+		PageSize: 2,
+		Page:     3,
+	})
+	a.Nil(err)
+	a.EqualValues(5, resp.TotalCount)
+	a.Len(resp.Rules, 1)
+	a.Equal("telemetry/+", resp.Rules[0].Topics[0])
+}
+
+func TestACL_Add_Set_Delete(t *testing.T) {
+	a := assert.New(t)
+	dir, err := ioutil.TempDir("", "gmqtt_acl")
+	a.Nil(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "acl.yml")
+	acl := newTestACL(t, path)
+	svc := &ruleService{a: acl}
+
+	_, err = svc.Add(context.Background(), &AddRuleRequest{
+		Rule: &Rule{
+			Permission: Allow,
+			Action:     Publish,
+			Topics:     []string{"a/#"},
+		},
+	})
+	a.Nil(err)
+	allowed, _ := acl.check(Publish, "", "client", "a/b")
+	a.True(allowed)
+
+	_, err = svc.Add(context.Background(), &AddRuleRequest{
+		Rule: &Rule{
+			Permission: "abc",
+			Action:     Publish,
+			Topics:     []string{"a/#"},
+		},
+	})
+	s, ok := status.FromError(err)
+	a.True(ok)
+	a.Equal(codes.InvalidArgument, s.Code())
+
+	_, err = svc.Set(context.Background(), &SetRulesRequest{
+		Rules: []*Rule{
+			{
+				Permission: Deny,
+				Action:     Publish,
+				Topics:     []string{"a/b"},
+			}, {
+				Permission: Allow,
+				Action:     PubSub,
+				Topics:     []string{"#"},
+				MaxQos:     &wrappers.UInt32Value{Value: 1},
+			},
+		},
+	})
+	a.Nil(err)
+	allowed, _ = acl.check(Publish, "", "client", "a/b")
+	a.False(allowed)
+
+	_, err = svc.Delete(context.Background(), &DeleteRuleRequest{Index: 2})
+	s, ok = status.FromError(err)
+	a.True(ok)
+	a.Equal(codes.NotFound, s.Code())
+	_, err = svc.Delete(context.Background(), &DeleteRuleRequest{Index: 0})
+	a.Nil(err)
+	allowed, maxQoS := acl.check(Publish, "", "client", "a/b")
+	a.True(allowed)
+	a.EqualValues(1, *maxQoS)
+
+	// the rules are persisted to the file.
+	acl = newTestACL(t, path)
+	svc = &ruleService{a: acl}
+	resp, err := svc.List(context.Background(), &ListRulesRequest{})
+	a.Nil(err)
+	a.Len(resp.Rules, 1)
+	a.Equal([]string{"#"}, resp.Rules[0].Topics)
+	a.EqualValues(1, resp.Rules[0].MaxQos.Value)
+
+	// rollback if failed to persist to file.
+	acl.saveFile = func() error {
+		return errors.New("error")
+	}
+	_, err = svc.Delete(context.Background(), &DeleteRuleRequest{Index: 0})
+	a.NotNil(err)
+	resp, err = svc.List(context.Background(), &ListRulesRequest{})
+	a.Nil(err)
+	a.Len(resp.Rules, 1)
+}
+
+func TestACL_Reload(t *testing.T) {
+	a := assert.New(t)
+	dir, err := ioutil.TempDir("", "gmqtt_acl")
+	a.Nil(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "acl.yml")
+	acl := newTestACL(t, path)
+	svc := &ruleService{a: acl}
+	allowed, _ := acl.check(Publish, "", "client", "a/b")
+	a.False(allowed)
+
+	a.Nil(ioutil.WriteFile(path, []byte("- {permission: allow, action: publish, topics: [a/b]}"), 0666))
+	_, err = svc.Reload(context.Background(), &empty.Empty{})
+	a.Nil(err)
+	allowed, _ = acl.check(Publish, "", "client", "a/b")
+	a.True(allowed)
+
+	// keep the current rules if the file is invalid.
+	a.Nil(ioutil.WriteFile(path, []byte("- {permission: abc, action: publish, topics: [a/b]}"), 0666))
+	_, err = svc.Reload(context.Background(), &empty.Empty{})
+	a.NotNil(err)
+	allowed, _ = acl.check(Publish, "", "client", "a/b")
+	a.True(allowed)
+}
+
+func TestACL_Reload_config(t *testing.T) {
+	a := assert.New(t)
+	dir, err := ioutil.TempDir("", "gmqtt_acl")
+	a.Nil(err)
+	defer os.RemoveAll(dir)
+	acl := newTestACL(t, filepath.Join(dir, "acl.yml"))
+	allowed, _ := acl.check(Publish, "", "client", "a/b")
+	a.False(allowed)
+
+	// the new acl file and no_match are applied.
+	path := filepath.Join(dir, "acl2.yml")
+	a.Nil(ioutil.WriteFile(path, []byte("- {permission: allow, action: publish, topics: [a/b]}"), 0666))
+	cfg := config.Config{
+		Plugins: map[string]config.Configuration{
+			Name: &Config{ACLFile: path, NoMatch: Allow},
+		},
+	}
+	a.Nil(acl.Reload(cfg))
+	allowed, _ = acl.check(Publish, "", "client", "a/b")
+	a.True(allowed)
+	allowed, _ = acl.check(Publish, "", "client", "c")
+	a.True(allowed)
+
+	// keep the current config and rules if the file is invalid.
+	invalid := filepath.Join(dir, "invalid.yml")
+	a.Nil(ioutil.WriteFile(invalid, []byte("- {permission: abc, action: publish, topics: [a/b]}"), 0666))
+	a.NotNil(acl.Reload(config.Config{
+		Plugins: map[string]config.Configuration{
+			Name: &Config{ACLFile: invalid, NoMatch: Deny},
+		},
+	}))
+	a.Equal(path, acl.config.ACLFile)
+	allowed, _ = acl.check(Publish, "", "client", "c")
+	a.True(allowed)
+}
@@ -0,0 +1,74 @@
+package acl
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func (a *ACL) HookWrapper() server.HookWrapper {
+	return server.HookWrapper{
+		OnSubscribeWrapper:  a.OnSubscribeWrapper,
+		OnMsgArrivedWrapper: a.OnMsgArrivedWrapper,
+	}
+}
+
+func (a *ACL) OnSubscribeWrapper(pre server.OnSubscribe) server.OnSubscribe {
+	return func(ctx context.Context, client server.Client, req *server.SubscribeRequest) error {
+		err := pre(ctx, client, req)
+		if err != nil {
+			return err
+		}
+		opts := client.ClientOptions()
+		for k, v := range req.Subscriptions {
+			if v.Error != nil {
+				continue
+			}
+			allowed, maxQoS := a.check(Subscribe, opts.Username, opts.ClientID, v.Sub.TopicFilter)
+			if !allowed {
+				log.Debug("subscription not authorized",
+					zap.String("client_id", opts.ClientID),
+					zap.String("username", opts.Username),
+					zap.String("topic", k))
+				req.Reject(k, &codes.Error{
+					Code: codes.NotAuthorized,
+				})
+				continue
+			}
+			if maxQoS != nil && v.Sub.QoS > *maxQoS {
+				req.GrantQoS(k, *maxQoS)
+			}
+		}
+		return nil
+	}
+}
+
+func (a *ACL) OnMsgArrivedWrapper(pre server.OnMsgArrived) server.OnMsgArrived {
+	return func(ctx context.Context, client server.Client, req *server.MsgArrivedRequest) error {
+		err := pre(ctx, client, req)
+		if err != nil || req.Message == nil {
+			return err
+		}
+		opts := client.ClientOptions()
+		allowed, maxQoS := a.check(Publish, opts.Username, opts.ClientID, req.Message.Topic)
+		if allowed && (maxQoS == nil || req.Message.QoS <= *maxQoS) {
+			return nil
+		}
+		log.Debug("publish not authorized",
+			zap.String("client_id", opts.ClientID),
+			zap.String("username", opts.Username),
+			zap.String("topic", req.Message.Topic),
+			zap.Uint8("qos", req.Message.QoS))
+		req.Drop()
+		if client.Version() == packets.Version5 {
+			return &codes.Error{
+				Code: codes.NotAuthorized,
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,114 @@
+package acl
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/persistence/subscription"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func newSubscribeRequest(topics ...packets.Topic) *server.SubscribeRequest {
+	req := &server.SubscribeRequest{
+		Subscribe: &packets.Subscribe{
+			Topics: topics,
+		},
+		Subscriptions: make(map[string]*struct {
+			Sub   *gmqtt.Subscription
+			Error error
+		}),
+	}
+	for _, v := range topics {
+		req.Subscriptions[v.Name] = &struct {
+			Sub   *gmqtt.Subscription
+			Error error
+		}{Sub: subscription.FromTopic(v, 0)}
+	}
+	return req
+}
+
+func TestACL_OnSubscribeWrapper(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	acl := newTestACL(t, "./testdata/gmqtt_acl.yml")
+	client := server.NewMockClient(ctrl)
+	client.EXPECT().ClientOptions().Return(&server.ClientOptions{
+		ClientID: "client",
+		Username: "user",
+	}).AnyTimes()
+	client.EXPECT().Version().Return(packets.Version5).AnyTimes()
+
+	var preCalled bool
+	fn := acl.OnSubscribeWrapper(func(ctx context.Context, client server.Client, req *server.SubscribeRequest) error {
+		preCalled = true
+		return nil
+	})
+	req := newSubscribeRequest(
+		packets.Topic{Name: "users/user/#", SubOptions: packets.SubOptions{Qos: packets.Qos2}},
+		packets.Topic{Name: "$share/g/public/a", SubOptions: packets.SubOptions{Qos: packets.Qos2}},
+		packets.Topic{Name: "secret/a", SubOptions: packets.SubOptions{Qos: packets.Qos1}},
+	)
+	a.Nil(fn(context.Background(), client, req))
+	a.True(preCalled)
+
+	a.Nil(req.Subscriptions["users/user/#"].Error)
+	a.EqualValues(packets.Qos2, req.Subscriptions["users/user/#"].Sub.QoS)
+	// shared subscription is checked by the topic filter.
+	a.Nil(req.Subscriptions["$share/g/public/a"].Error)
+	a.EqualValues(packets.Qos1, req.Subscriptions["$share/g/public/a"].Sub.QoS)
+	a.Equal(&codes.Error{Code: codes.NotAuthorized}, req.Subscriptions["secret/a"].Error)
+}
+
+func TestACL_OnMsgArrivedWrapper(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	acl := newTestACL(t, "./testdata/gmqtt_acl.yml")
+	fn := acl.OnMsgArrivedWrapper(func(ctx context.Context, client server.Client, req *server.MsgArrivedRequest) error {
+		return nil
+	})
+	for _, v := range []struct {
+		version packets.Version
+		topic   string
+		qos     uint8
+		allowed bool
+	}{
+		{version: packets.Version5, topic: "users/user/a", qos: packets.Qos2, allowed: true},
+		{version: packets.Version5, topic: "users/other/a", allowed: false},
+		{version: packets.Version311, topic: "users/other/a", allowed: false},
+		{version: packets.Version5, topic: "telemetry/a", qos: packets.Qos0, allowed: true},
+		{version: packets.Version5, topic: "telemetry/a", qos: packets.Qos1, allowed: false},
+	} {
+		client := server.NewMockClient(ctrl)
+		client.EXPECT().ClientOptions().Return(&server.ClientOptions{
+			ClientID: "client",
+			Username: "user",
+		}).AnyTimes()
+		client.EXPECT().Version().Return(v.version).AnyTimes()
+		req := &server.MsgArrivedRequest{
+			Message: &gmqtt.Message{
+				Topic: v.topic,
+				QoS:   v.qos,
+			},
+		}
+		err := fn(context.Background(), client, req)
+		if v.allowed {
+			a.Nil(err, v)
+			a.NotNil(req.Message, v)
+			continue
+		}
+		a.Nil(req.Message, v)
+		if v.version == packets.Version5 {
+			a.Equal(&codes.Error{Code: codes.NotAuthorized}, err, v)
+		} else {
+			a.Nil(err, v)
+		}
+	}
+}
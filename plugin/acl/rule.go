@@ -0,0 +1,227 @@
+package acl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+// The permissions of the rules.
+const (
+	Allow = "allow"
+	Deny  = "deny"
+)
+
+// The actions of the rules.
+const (
+	Publish   = "publish"
+	Subscribe = "subscribe"
+	PubSub    = "pubsub"
+)
+
+// The placeholders that can be used in the topic patterns.
+const (
+	placeholderUsername = "%u"
+	placeholderClientID = "%c"
+)
+
+// rule is the acl rule which is stored in the acl file.
+type rule struct {
+	// Permission is the permission of the rule, possible values: allow | deny
+	Permission string `yaml:"permission"`
+	// Action is the action of the rule, possible values: publish | subscribe | pubsub
+	Action string `yaml:"action"`
+	// Username is the username that the rule applies to, empty means any username.
+	Username string `yaml:"username,omitempty"`
+	// ClientID is the client id that the rule applies to, empty means any client id.
+	ClientID string `yaml:"client_id,omitempty"`
+	// Topics is the topic patterns, %u and %c will be replaced by the username and client id.
+	Topics []string `yaml:"topics"`
+	// MaxQoS is the maximum QoS level that is allowed, nil means no limit.
+	// It only takes effect on the allow rules.
+	MaxQoS *uint8 `yaml:"max_qos,omitempty"`
+}
+
+func (r *rule) validate() error {
+	if r.Permission != Allow && r.Permission != Deny {
+		return fmt.Errorf("invalid permission: %s", r.Permission)
+	}
+	if r.Action != Publish && r.Action != Subscribe && r.Action != PubSub {
+		return fmt.Errorf("invalid action: %s", r.Action)
+	}
+	if len(r.Topics) == 0 {
+		return errors.New("topics cannot be empty")
+	}
+	for _, v := range r.Topics {
+		if !packets.ValidTopicFilter(true, []byte(v)) {
+			return fmt.Errorf("invalid topic: %s", v)
+		}
+	}
+	if r.MaxQoS != nil && *r.MaxQoS > packets.Qos2 {
+		return fmt.Errorf("invalid max_qos: %d", *r.MaxQoS)
+	}
+	return nil
+}
+
+func validateRules(rules []*rule) error {
+	for k, v := range rules {
+		if err := v.validate(); err != nil {
+			return fmt.Errorf("rule %d: %s", k, err)
+		}
+	}
+	return nil
+}
+
+// expand replaces the placeholders in the pattern with the username and client id.
+// It returns false if the placeholder can not be replaced safely,
+// e.g. the username is empty or contains the wildcard characters.
+func expand(pattern, username, clientID string) (string, bool) {
+	for _, v := range []struct {
+		placeholder string
+		value       string
+	}{
+		{placeholderUsername, username},
+		{placeholderClientID, clientID},
+	} {
+		if !strings.Contains(pattern, v.placeholder) {
+			continue
+		}
+		if v.value == "" || strings.ContainsAny(v.value, "+#/") {
+			return "", false
+		}
+		pattern = strings.Replace(pattern, v.placeholder, v.value, -1)
+	}
+	return pattern, true
+}
+
+func isWildcard(level string) bool {
+	return level == "+" || level == "#"
+}
+
+// covers returns whether all topics that match the filter also match the pattern.
+func covers(pattern, filter string) bool {
+	p := strings.Split(pattern, "/")
+	f := strings.Split(filter, "/")
+	// wildcards at the first level do not match the topics that start with '$'.
+	if strings.HasPrefix(filter, "$") && isWildcard(p[0]) {
+		return false
+	}
+	for i, v := range p {
+		if v == "#" {
+			return true
+		}
+		if i >= len(f) {
+			return false
+		}
+		if v == "+" {
+			if f[i] == "#" {
+				return false
+			}
+			continue
+		}
+		if v != f[i] {
+			return false
+		}
+	}
+	return len(p) == len(f)
+}
+
+// overlaps returns whether there is any topic that matches both the pattern and the filter.
+func overlaps(pattern, filter string) bool {
+	p := strings.Split(pattern, "/")
+	f := strings.Split(filter, "/")
+	if (strings.HasPrefix(filter, "$") && isWildcard(p[0])) ||
+		(strings.HasPrefix(pattern, "$") && isWildcard(f[0])) {
+		return false
+	}
+	for i := 0; ; i++ {
+		switch {
+		case i == len(p) && i == len(f):
+			return true
+		case i == len(p):
+			return f[i] == "#"
+		case i == len(f):
+			return p[i] == "#"
+		case p[i] == "#" || f[i] == "#":
+			return true
+		case p[i] == "+" || f[i] == "+" || p[i] == f[i]:
+			continue
+		default:
+			return false
+		}
+	}
+}
+
+// match returns whether the rule applies to the given request.
+// For publish, the topic is the topic name of the message.
+// For subscribe, the topic is the topic filter of the subscription,
+// the allow rules match if the patterns cover the topic filter,
+// and the deny rules match if the patterns overlap the topic filter.
+func (r *rule) match(action, username, clientID, topic string) bool {
+	if r.Action != PubSub && r.Action != action {
+		return false
+	}
+	if r.Username != "" && r.Username != username {
+		return false
+	}
+	if r.ClientID != "" && r.ClientID != clientID {
+		return false
+	}
+	for _, v := range r.Topics {
+		pattern, ok := expand(v, username, clientID)
+		if !ok {
+			continue
+		}
+		switch {
+		case action == Publish:
+			if packets.TopicMatch([]byte(topic), []byte(pattern)) {
+				return true
+			}
+		case r.Permission == Allow:
+			if covers(pattern, topic) {
+				return true
+			}
+		default:
+			if overlaps(pattern, topic) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func ruleToProto(r *rule) *Rule {
+	rs := &Rule{
+		Permission: r.Permission,
+		Action:     r.Action,
+		Username:   r.Username,
+		ClientId:   r.ClientID,
+		Topics:     r.Topics,
+	}
+	if r.MaxQoS != nil {
+		rs.MaxQos = &wrappers.UInt32Value{Value: uint32(*r.MaxQoS)}
+	}
+	return rs
+}
+
+func ruleFromProto(r *Rule) (*rule, error) {
+	rs := &rule{
+		Permission: r.Permission,
+		Action:     r.Action,
+		Username:   r.Username,
+		ClientID:   r.ClientId,
+		Topics:     r.Topics,
+	}
+	if r.MaxQos != nil {
+		if r.MaxQos.Value > uint32(packets.Qos2) {
+			return nil, fmt.Errorf("invalid max_qos: %d", r.MaxQos.Value)
+		}
+		qos := uint8(r.MaxQos.Value)
+		rs.MaxQoS = &qos
+	}
+	return rs, rs.validate()
+}
@@ -55,13 +55,15 @@ type HTTPRegister interface {
 
 // Admin providers gRPC and HTTP API that enables the external system to interact with the broker.
 type Admin struct {
-	config        Config
-	httpServer    *http.Server
-	grpcServer    *grpc.Server
-	statsReader   server.StatsReader
-	publisher     server.Publisher
-	clientService server.ClientService
-	store         *store
+	config         Config
+	httpServer     *http.Server
+	grpcServer     *grpc.Server
+	statsReader    server.StatsReader
+	publisher      server.Publisher
+	clientService  server.ClientService
+	delayedService server.DelayedService
+	drainer        server.Server
+	store          *store
 }
 
 func (a *Admin) registerHTTP(mux *runtime.ServeMux) (err error) {
@@ -91,6 +93,24 @@ func (a *Admin) registerHTTP(mux *runtime.ServeMux) (err error) {
 		[]grpc.DialOption{grpc.WithInsecure()},
 	)
 
+	if err != nil {
+		return err
+	}
+	err = RegisterDelayedServiceHandlerFromEndpoint(
+		context.Background(),
+		mux,
+		a.config.GRPC.Addr,
+		[]grpc.DialOption{grpc.WithInsecure()},
+	)
+	if err != nil {
+		return err
+	}
+	err = RegisterBrokerServiceHandlerFromEndpoint(
+		context.Background(),
+		mux,
+		a.config.GRPC.Addr,
+		[]grpc.DialOption{grpc.WithInsecure()},
+	)
 	if err != nil {
 		return err
 	}
@@ -125,6 +145,8 @@ func (a *Admin) Load(service server.Server) error {
 	RegisterClientServiceServer(s, &clientService{a: a})
 	RegisterSubscriptionServiceServer(s, &subscriptionService{a: a})
 	RegisterPublishServiceServer(s, &publisher{a: a})
+	RegisterDelayedServiceServer(s, &delayedService{a: a})
+	RegisterBrokerServiceServer(s, &brokerService{a: a})
 	mux := runtime.NewServeMux(runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{OrigName: true, EmitDefaults: true}))
 	if a.config.HTTP.Enable {
 		err := a.registerHTTP(mux)
@@ -159,6 +181,8 @@ func (a *Admin) Load(service server.Server) error {
 	a.store.subscriptionService = service.SubscriptionService()
 	a.publisher = service.Publisher()
 	a.clientService = service.ClientService()
+	a.delayedService = service.DelayedService()
+	a.drainer = service
 	go func() {
 		err := s.Serve(l)
 		if err != nil {
@@ -0,0 +1,31 @@
+package admin
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+type brokerService struct {
+	a *Admin
+}
+
+func (b *brokerService) mustEmbedUnimplementedBrokerServiceServer() {
+	return
+}
+
+// Drain starts draining the broker, the broker stops after all clients are disconnected.
+func (b *brokerService) Drain(ctx context.Context, req *DrainRequest) (*empty.Empty, error) {
+	err := b.a.drainer.Drain()
+	if err == server.ErrDraining || err == server.ErrNotRunning {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &empty.Empty{}, nil
+}
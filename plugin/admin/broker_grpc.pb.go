@@ -0,0 +1,112 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.13.0
+// source: broker.proto
+
+package admin
+
+import (
+	context "context"
+	empty "github.com/golang/protobuf/ptypes/empty"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// BrokerServiceClient is the client API for BrokerService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type BrokerServiceClient interface {
+	// Drain stops accepting new connections, disconnects the connected clients over the configured drain window,
+	// and then stops the broker. It returns immediately after the drain starts.
+	// Return FailedPrecondition error when the broker is already draining or not running.
+	Drain(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+}
+
+type brokerServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBrokerServiceClient(cc grpc.ClientConnInterface) BrokerServiceClient {
+	return &brokerServiceClient{cc}
+}
+
+func (c *brokerServiceClient) Drain(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/gmqtt.admin.api.BrokerService/Drain", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BrokerServiceServer is the server API for BrokerService service.
+// All implementations must embed UnimplementedBrokerServiceServer
+// for forward compatibility
+type BrokerServiceServer interface {
+	// Drain stops accepting new connections, disconnects the connected clients over the configured drain window,
+	// and then stops the broker. It returns immediately after the drain starts.
+	// Return FailedPrecondition error when the broker is already draining or not running.
+	Drain(context.Context, *DrainRequest) (*empty.Empty, error)
+	mustEmbedUnimplementedBrokerServiceServer()
+}
+
+// UnimplementedBrokerServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedBrokerServiceServer struct {
+}
+
+func (UnimplementedBrokerServiceServer) Drain(context.Context, *DrainRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Drain not implemented")
+}
+func (UnimplementedBrokerServiceServer) mustEmbedUnimplementedBrokerServiceServer() {}
+
+// UnsafeBrokerServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BrokerServiceServer will
+// result in compilation errors.
+type UnsafeBrokerServiceServer interface {
+	mustEmbedUnimplementedBrokerServiceServer()
+}
+
+func RegisterBrokerServiceServer(s grpc.ServiceRegistrar, srv BrokerServiceServer) {
+	s.RegisterService(&BrokerService_ServiceDesc, srv)
+}
+
+func _BrokerService_Drain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DrainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BrokerServiceServer).Drain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gmqtt.admin.api.BrokerService/Drain",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BrokerServiceServer).Drain(ctx, req.(*DrainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// BrokerService_ServiceDesc is the grpc.ServiceDesc for BrokerService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BrokerService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gmqtt.admin.api.BrokerService",
+	HandlerType: (*BrokerServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Drain",
+			Handler:    _BrokerService_Drain_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "broker.proto",
+}
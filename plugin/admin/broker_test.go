@@ -0,0 +1,42 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func TestBrokerService_Drain(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	srv := server.NewMockServer(ctrl)
+	b := &brokerService{
+		a: &Admin{
+			drainer: srv,
+		},
+	}
+	srv.EXPECT().Drain().Return(nil)
+	_, err := b.Drain(context.Background(), &DrainRequest{})
+	a.Nil(err)
+
+	srv.EXPECT().Drain().Return(server.ErrDraining)
+	_, err = b.Drain(context.Background(), &DrainRequest{})
+	s, ok := status.FromError(err)
+	a.True(ok)
+	a.Equal(codes.FailedPrecondition, s.Code())
+
+	srv.EXPECT().Drain().Return(errors.New("error"))
+	_, err = b.Drain(context.Background(), &DrainRequest{})
+	s, ok = status.FromError(err)
+	a.True(ok)
+	a.Equal(codes.Internal, s.Code())
+}
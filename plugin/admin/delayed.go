@@ -0,0 +1,98 @@
+package admin
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/DrmagicE/gmqtt/persistence/delayed"
+)
+
+type delayedService struct {
+	a *Admin
+}
+
+func (d *delayedService) mustEmbedUnimplementedDelayedServiceServer() {
+	return
+}
+
+func delayedMessageToProto(dm *delayed.Message) *DelayedMessage {
+	msg := dm.Message
+	var userPpt []*UserProperties
+	for _, v := range msg.UserProperties {
+		userPpt = append(userPpt, &UserProperties{
+			K: v.K,
+			V: v.V,
+		})
+	}
+	return &DelayedMessage{
+		Id:              dm.ID,
+		ClientId:        dm.ClientID,
+		TopicName:       msg.Topic,
+		Payload:         string(msg.Payload),
+		Qos:             uint32(msg.QoS),
+		Retained:        msg.Retained,
+		ContentType:     msg.ContentType,
+		CorrelationData: string(msg.CorrelationData),
+		MessageExpiry:   msg.MessageExpiry,
+		PayloadFormat:   uint32(msg.PayloadFormat),
+		ResponseTopic:   msg.ResponseTopic,
+		UserProperties:  userPpt,
+		PublishedAt:     timestamppb.New(dm.PublishedAt),
+		DeliverAt:       timestamppb.New(dm.DeliverAt),
+	}
+}
+
+// List lists the pending delayed messages in the order of the delivery time.
+func (d *delayedService) List(ctx context.Context, req *ListDelayedRequest) (*ListDelayedResponse, error) {
+	page, pageSize := GetPage(req.Page, req.PageSize)
+	offset, n := GetOffsetN(page, pageSize)
+	var msgs []*DelayedMessage
+	var i uint
+	d.a.delayedService.Iterate(func(msg *delayed.Message) bool {
+		if i >= offset+n {
+			return false
+		}
+		if i >= offset {
+			msgs = append(msgs, delayedMessageToProto(msg))
+		}
+		i++
+		return true
+	})
+	return &ListDelayedResponse{
+		Messages:   msgs,
+		TotalCount: uint32(d.a.delayedService.Len()),
+	}, nil
+}
+
+// Get returns the pending delayed message for given request id.
+func (d *delayedService) Get(ctx context.Context, req *GetDelayedRequest) (*GetDelayedResponse, error) {
+	if req.Id == "" {
+		return nil, ErrInvalidArgument("id", "")
+	}
+	msg := d.a.delayedService.Get(req.Id)
+	if msg == nil {
+		return nil, ErrNotFound
+	}
+	return &GetDelayedResponse{
+		Message: delayedMessageToProto(msg),
+	}, nil
+}
+
+// Delete cancels the pending delayed message, the message will not be delivered.
+func (d *delayedService) Delete(ctx context.Context, req *DeleteDelayedRequest) (*empty.Empty, error) {
+	if req.Id == "" {
+		return nil, ErrInvalidArgument("id", "")
+	}
+	ok, err := d.a.delayedService.Cancel(req.Id)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &empty.Empty{}, nil
+}
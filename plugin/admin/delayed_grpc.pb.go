@@ -0,0 +1,188 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.13.0
+// source: delayed.proto
+
+package admin
+
+import (
+	context "context"
+	empty "github.com/golang/protobuf/ptypes/empty"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// DelayedServiceClient is the client API for DelayedService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type DelayedServiceClient interface {
+	// List the pending delayed messages in the order of the delivery time.
+	List(ctx context.Context, in *ListDelayedRequest, opts ...grpc.CallOption) (*ListDelayedResponse, error)
+	// Get the pending delayed message for given id.
+	// Return NotFound error when the message not found.
+	Get(ctx context.Context, in *GetDelayedRequest, opts ...grpc.CallOption) (*GetDelayedResponse, error)
+	// Cancel the pending delayed message for given id.
+	// Return NotFound error when the message not found.
+	Delete(ctx context.Context, in *DeleteDelayedRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+}
+
+type delayedServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDelayedServiceClient(cc grpc.ClientConnInterface) DelayedServiceClient {
+	return &delayedServiceClient{cc}
+}
+
+func (c *delayedServiceClient) List(ctx context.Context, in *ListDelayedRequest, opts ...grpc.CallOption) (*ListDelayedResponse, error) {
+	out := new(ListDelayedResponse)
+	err := c.cc.Invoke(ctx, "/gmqtt.admin.api.DelayedService/List", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *delayedServiceClient) Get(ctx context.Context, in *GetDelayedRequest, opts ...grpc.CallOption) (*GetDelayedResponse, error) {
+	out := new(GetDelayedResponse)
+	err := c.cc.Invoke(ctx, "/gmqtt.admin.api.DelayedService/Get", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *delayedServiceClient) Delete(ctx context.Context, in *DeleteDelayedRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/gmqtt.admin.api.DelayedService/Delete", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DelayedServiceServer is the server API for DelayedService service.
+// All implementations must embed UnimplementedDelayedServiceServer
+// for forward compatibility
+type DelayedServiceServer interface {
+	// List the pending delayed messages in the order of the delivery time.
+	List(context.Context, *ListDelayedRequest) (*ListDelayedResponse, error)
+	// Get the pending delayed message for given id.
+	// Return NotFound error when the message not found.
+	Get(context.Context, *GetDelayedRequest) (*GetDelayedResponse, error)
+	// Cancel the pending delayed message for given id.
+	// Return NotFound error when the message not found.
+	Delete(context.Context, *DeleteDelayedRequest) (*empty.Empty, error)
+	mustEmbedUnimplementedDelayedServiceServer()
+}
+
+// UnimplementedDelayedServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedDelayedServiceServer struct {
+}
+
+func (UnimplementedDelayedServiceServer) List(context.Context, *ListDelayedRequest) (*ListDelayedResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedDelayedServiceServer) Get(context.Context, *GetDelayedRequest) (*GetDelayedResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedDelayedServiceServer) Delete(context.Context, *DeleteDelayedRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedDelayedServiceServer) mustEmbedUnimplementedDelayedServiceServer() {}
+
+// UnsafeDelayedServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DelayedServiceServer will
+// result in compilation errors.
+type UnsafeDelayedServiceServer interface {
+	mustEmbedUnimplementedDelayedServiceServer()
+}
+
+func RegisterDelayedServiceServer(s grpc.ServiceRegistrar, srv DelayedServiceServer) {
+	s.RegisterService(&DelayedService_ServiceDesc, srv)
+}
+
+func _DelayedService_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDelayedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DelayedServiceServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gmqtt.admin.api.DelayedService/List",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DelayedServiceServer).List(ctx, req.(*ListDelayedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DelayedService_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDelayedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DelayedServiceServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gmqtt.admin.api.DelayedService/Get",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DelayedServiceServer).Get(ctx, req.(*GetDelayedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DelayedService_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteDelayedRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DelayedServiceServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gmqtt.admin.api.DelayedService/Delete",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DelayedServiceServer).Delete(ctx, req.(*DeleteDelayedRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// DelayedService_ServiceDesc is the grpc.ServiceDesc for DelayedService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var DelayedService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gmqtt.admin.api.DelayedService",
+	HandlerType: (*DelayedServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "List",
+			Handler:    _DelayedService_List_Handler,
+		},
+		{
+			MethodName: "Get",
+			Handler:    _DelayedService_Get_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _DelayedService_Delete_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "delayed.proto",
+}
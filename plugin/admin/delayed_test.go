@@ -0,0 +1,173 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/persistence/delayed"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func newDelayedMessages(n int) []*delayed.Message {
+	now := time.Unix(100, 0)
+	var msgs []*delayed.Message
+	for i := 0; i < n; i++ {
+		msgs = append(msgs, &delayed.Message{
+			ID:          string(rune('a' + i)),
+			ClientID:    "cid",
+			Message:     &gmqtt.Message{Topic: "topic", Payload: []byte("abc")},
+			PublishedAt: now,
+			DeliverAt:   now.Add(time.Duration(i) * time.Second),
+		})
+	}
+	return msgs
+}
+
+func TestDelayedService_List(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ds := server.NewMockDelayedService(ctrl)
+	d := &delayedService{
+		a: &Admin{
+			delayedService: ds,
+		},
+	}
+	msgs := newDelayedMessages(5)
+	ds.EXPECT().Iterate(gomock.Any()).DoAndReturn(func(fn delayed.IterateFn) {
+		for _, v := range msgs {
+			if !fn(v) {
+				return
+			}
+		}
+	}).Times(2)
+	ds.EXPECT().Len().Return(len(msgs)).Times(2)
+
+	resp, err := d.List(context.Background(), &ListDelayedRequest{
+		PageSize: 2,
+		Page:     2,
+	})
+	a.Nil(err)
+	a.EqualValues(5, resp.TotalCount)
+	a.Len(resp.Messages, 2)
+	a.Equal("c", resp.Messages[0].Id)
+	a.Equal("d", resp.Messages[1].Id)
+
+	resp, err = d.List(context.Background(), &ListDelayedRequest{
+		PageSize: 2,
+		Page:     3,
+	})
+	a.Nil(err)
+	a.Len(resp.Messages, 1)
+	a.Equal("e", resp.Messages[0].Id)
+}
+
+func TestDelayedService_Get(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ds := server.NewMockDelayedService(ctrl)
+	d := &delayedService{
+		a: &Admin{
+			delayedService: ds,
+		},
+	}
+	dm := &delayed.Message{
+		ID:       "id",
+		ClientID: "cid",
+		Message: &gmqtt.Message{
+			QoS:             1,
+			Retained:        true,
+			Topic:           "topic",
+			Payload:         []byte("abc"),
+			ContentType:     "ct",
+			CorrelationData: []byte("co"),
+			MessageExpiry:   1,
+			PayloadFormat:   1,
+			ResponseTopic:   "resp",
+			UserProperties: []packets.UserProperty{
+				{
+					K: []byte("K"),
+					V: []byte("V"),
+				},
+			},
+		},
+		PublishedAt: time.Unix(1, 0),
+		DeliverAt:   time.Unix(11, 0),
+	}
+	ds.EXPECT().Get("id").Return(dm)
+	ds.EXPECT().Get("not_exist").Return(nil)
+
+	resp, err := d.Get(context.Background(), &GetDelayedRequest{Id: "id"})
+	a.Nil(err)
+	a.Equal(&DelayedMessage{
+		Id:              "id",
+		ClientId:        "cid",
+		TopicName:       "topic",
+		Payload:         "abc",
+		Qos:             1,
+		Retained:        true,
+		ContentType:     "ct",
+		CorrelationData: "co",
+		MessageExpiry:   1,
+		PayloadFormat:   1,
+		ResponseTopic:   "resp",
+		UserProperties: []*UserProperties{
+			{
+				K: []byte("K"),
+				V: []byte("V"),
+			},
+		},
+		PublishedAt: timestamppb.New(time.Unix(1, 0)),
+		DeliverAt:   timestamppb.New(time.Unix(11, 0)),
+	}, resp.Message)
+
+	_, err = d.Get(context.Background(), &GetDelayedRequest{Id: "not_exist"})
+	a.Equal(ErrNotFound, err)
+
+	_, err = d.Get(context.Background(), &GetDelayedRequest{})
+	s, ok := status.FromError(err)
+	a.True(ok)
+	a.Equal(codes.InvalidArgument, s.Code())
+}
+
+func TestDelayedService_Delete(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ds := server.NewMockDelayedService(ctrl)
+	d := &delayedService{
+		a: &Admin{
+			delayedService: ds,
+		},
+	}
+	ds.EXPECT().Cancel("id").Return(true, nil)
+	ds.EXPECT().Cancel("not_exist").Return(false, nil)
+	ds.EXPECT().Cancel("error").Return(false, errors.New("store error"))
+
+	_, err := d.Delete(context.Background(), &DeleteDelayedRequest{Id: "id"})
+	a.Nil(err)
+	_, err = d.Delete(context.Background(), &DeleteDelayedRequest{Id: "not_exist"})
+	a.Equal(ErrNotFound, err)
+	_, err = d.Delete(context.Background(), &DeleteDelayedRequest{Id: "error"})
+	s, ok := status.FromError(err)
+	a.True(ok)
+	a.Equal(codes.Internal, s.Code())
+	_, err = d.Delete(context.Background(), &DeleteDelayedRequest{})
+	s, ok = status.FromError(err)
+	a.True(ok)
+	a.Equal(codes.InvalidArgument, s.Code())
+}
@@ -0,0 +1,184 @@
+package archive
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+var _ server.Plugin = (*Archive)(nil)
+
+const Name = "archive"
+
+func init() {
+	server.RegisterPlugin(Name, New)
+	config.RegisterDefaultPluginConfig(Name, &DefaultConfig)
+	RegisterSink(SinkFile, newFileSink)
+	RegisterSink(SinkSQL, newSQLSink)
+}
+
+// Record is an archived message.
+type Record struct {
+	// Time is the time when the message arrived.
+	Time     time.Time
+	ClientID string
+	Username string
+	Message  *gmqtt.Message
+}
+
+// Sink writes the batches of records to the storage.
+// The methods are called from a single goroutine.
+type Sink interface {
+	// Write writes the batch, the batch will be retried if an error is returned.
+	Write(records []*Record) error
+	// Close flushes and closes the sink.
+	Close() error
+}
+
+// NewSink creates a Sink with the plugin configuration.
+type NewSink func(config Config) (Sink, error)
+
+var sinks = make(map[string]NewSink)
+
+// RegisterSink registers a sink, which can be used by setting the sink name in the configuration.
+// It must be called in init() function.
+func RegisterSink(name string, new NewSink) {
+	if _, ok := sinks[name]; ok {
+		panic(fmt.Sprintf("duplicated sink: %s", name))
+	}
+	sinks[name] = new
+}
+
+func New(config config.Config) (server.Plugin, error) {
+	cfg := config.Plugins[Name].(*Config)
+	return &Archive{
+		config: *cfg,
+	}, nil
+}
+
+var log *zap.Logger
+
+// Archive writes the messages that match the configured topic filters to the sink in batches.
+type Archive struct {
+	// dropped is the number of messages dropped since the last flush, accessed atomically.
+	dropped uint64
+	config  Config
+	sink    Sink
+	queue   chan *Record
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func (a *Archive) Load(service server.Server) error {
+	log = server.LoggerWithField(zap.String("plugin", Name))
+	sink, err := sinks[a.config.Sink](a.config)
+	if err != nil {
+		return err
+	}
+	a.sink = sink
+	a.queue = make(chan *Record, a.config.QueueSize)
+	a.done = make(chan struct{})
+	a.wg.Add(1)
+	go a.run()
+	return nil
+}
+
+func (a *Archive) Unload() error {
+	close(a.done)
+	a.wg.Wait()
+	return a.sink.Close()
+}
+
+func (a *Archive) Name() string {
+	return Name
+}
+
+// match reports whether the topic matches any configured topic filters.
+func (a *Archive) match(topic string) bool {
+	for _, v := range a.config.Topics {
+		if packets.TopicMatch([]byte(topic), []byte(v)) {
+			return true
+		}
+	}
+	return false
+}
+
+// add puts the record into the queue without blocking, the record is dropped if the queue is full.
+func (a *Archive) add(r *Record) {
+	select {
+	case a.queue <- r:
+	default:
+		atomic.AddUint64(&a.dropped, 1)
+	}
+}
+
+// run collects the records into batches and writes them to the sink.
+func (a *Archive) run() {
+	defer a.wg.Done()
+	batch := make([]*Record, 0, a.config.BatchSize)
+	ticker := time.NewTicker(a.config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case r := <-a.queue:
+			batch = append(batch, r)
+			if len(batch) >= a.config.BatchSize {
+				batch = a.flush(batch)
+			}
+		case <-ticker.C:
+			batch = a.flush(batch)
+		case <-a.done:
+			// write the remaining records before exit.
+			for {
+				select {
+				case r := <-a.queue:
+					batch = append(batch, r)
+					if len(batch) >= a.config.BatchSize {
+						batch = a.flush(batch)
+					}
+				default:
+					a.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush writes the batch to the sink and returns the emptied batch for reuse.
+func (a *Archive) flush(batch []*Record) []*Record {
+	if dropped := atomic.SwapUint64(&a.dropped, 0); dropped != 0 {
+		log.Warn("messages dropped, the queue is full", zap.Uint64("dropped", dropped))
+	}
+	if len(batch) == 0 {
+		return batch
+	}
+	var err error
+	for i := 0; i <= a.config.MaxRetries; i++ {
+		if i != 0 {
+			select {
+			case <-time.After(a.config.RetryInterval):
+			case <-a.done:
+			}
+		}
+		if err = a.sink.Write(batch); err == nil {
+			break
+		}
+		log.Error("failed to write messages", zap.Int("retry", i), zap.Error(err))
+	}
+	if err != nil {
+		log.Error("messages dropped, all retries failed", zap.Int("dropped", len(batch)))
+	}
+	for k := range batch {
+		batch[k] = nil
+	}
+	return batch[:0]
+}
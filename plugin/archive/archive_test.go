@@ -0,0 +1,219 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+// testSink records the written batches, and fails the first failures writes.
+type testSink struct {
+	mu       sync.Mutex
+	batches  [][]string
+	failures int
+	block    chan struct{}
+	closed   bool
+}
+
+func (s *testSink) Write(records []*Record) error {
+	if s.block != nil {
+		<-s.block
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("write error")
+	}
+	var topics []string
+	for _, v := range records {
+		topics = append(topics, v.Message.Topic)
+	}
+	s.batches = append(s.batches, topics)
+	return nil
+}
+
+func (s *testSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func (s *testSink) getBatches() [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.batches
+}
+
+func newTestArchive(t *testing.T, cfg Config, sink Sink) *Archive {
+	log = zap.NewNop()
+	a := &Archive{
+		config: cfg,
+		sink:   sink,
+		queue:  make(chan *Record, cfg.QueueSize),
+		done:   make(chan struct{}),
+	}
+	a.wg.Add(1)
+	go a.run()
+	return a
+}
+
+func newRecord(topic string) *Record {
+	return &Record{
+		Time:    time.Now(),
+		Message: &gmqtt.Message{Topic: topic},
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	a := assert.New(t)
+	cfg := DefaultConfig
+	a.Nil(cfg.Validate())
+
+	cfg.Topics = []string{"a/#", "a/#/b"}
+	a.NotNil(cfg.Validate())
+
+	cfg = DefaultConfig
+	cfg.Sink = "unknown"
+	a.NotNil(cfg.Validate())
+
+	cfg = DefaultConfig
+	cfg.File.Format = "xml"
+	a.NotNil(cfg.Validate())
+
+	cfg = DefaultConfig
+	cfg.Sink = SinkSQL
+	a.NotNil(cfg.Validate())
+	cfg.SQL.Driver = "sqlite3"
+	cfg.SQL.DSN = ":memory:"
+	a.Nil(cfg.Validate())
+	cfg.SQL.Placeholder = "colon"
+	a.NotNil(cfg.Validate())
+}
+
+func TestArchive_batch(t *testing.T) {
+	a := assert.New(t)
+	cfg := DefaultConfig
+	cfg.BatchSize = 2
+	cfg.FlushInterval = 50 * time.Millisecond
+	sink := &testSink{}
+	arc := newTestArchive(t, cfg, sink)
+
+	arc.add(newRecord("a"))
+	arc.add(newRecord("b"))
+	arc.add(newRecord("c"))
+	// a and b are flushed by batch size, c is flushed by the interval.
+	a.Eventually(func() bool {
+		return len(sink.getBatches()) == 2
+	}, time.Second, 10*time.Millisecond)
+	a.Equal([][]string{{"a", "b"}, {"c"}}, sink.getBatches())
+
+	arc.add(newRecord("d"))
+	a.Nil(arc.Unload())
+	a.Equal([][]string{{"a", "b"}, {"c"}, {"d"}}, sink.getBatches())
+	a.True(sink.closed)
+}
+
+func TestArchive_retry(t *testing.T) {
+	a := assert.New(t)
+	cfg := DefaultConfig
+	cfg.BatchSize = 1
+	cfg.MaxRetries = 1
+	cfg.RetryInterval = time.Millisecond
+	sink := &testSink{failures: 3}
+	arc := newTestArchive(t, cfg, sink)
+
+	// the first batch fails twice and is dropped, the second batch succeeds after a retry.
+	arc.add(newRecord("a"))
+	arc.add(newRecord("b"))
+	a.Eventually(func() bool {
+		return len(sink.getBatches()) == 1
+	}, time.Second, 10*time.Millisecond)
+	a.Nil(arc.Unload())
+	a.Equal([][]string{{"b"}}, sink.getBatches())
+}
+
+func TestArchive_dropWhenFull(t *testing.T) {
+	a := assert.New(t)
+	cfg := DefaultConfig
+	cfg.BatchSize = 1
+	cfg.QueueSize = 1
+	sink := &testSink{block: make(chan struct{})}
+	arc := newTestArchive(t, cfg, sink)
+
+	arc.add(newRecord("a"))
+	// wait until a is taken by the blocked sink.
+	a.Eventually(func() bool {
+		return len(arc.queue) == 0
+	}, time.Second, time.Millisecond)
+	arc.add(newRecord("b"))
+	done := make(chan struct{})
+	go func() {
+		// the queue is full, c must be dropped without blocking.
+		arc.add(newRecord("c"))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("add blocked")
+	}
+	a.EqualValues(1, atomic.LoadUint64(&arc.dropped))
+	close(sink.block)
+	a.Nil(arc.Unload())
+	a.Equal([][]string{{"a"}, {"b"}}, sink.getBatches())
+}
+
+func TestArchive_OnMsgArrivedWrapper(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cfg := DefaultConfig
+	cfg.Topics = []string{"a/+", "b/#"}
+	arc := &Archive{
+		config: cfg,
+		queue:  make(chan *Record, cfg.QueueSize),
+	}
+
+	client := server.NewMockClient(ctrl)
+	client.EXPECT().ClientOptions().Return(&server.ClientOptions{
+		ClientID: "cid",
+		Username: "user",
+	}).AnyTimes()
+	preErr := errors.New("pre error")
+	fn := arc.OnMsgArrivedWrapper(func(ctx context.Context, client server.Client, req *server.MsgArrivedRequest) error {
+		switch req.Message.Topic {
+		case "a/err":
+			return preErr
+		case "a/drop":
+			req.Message = nil
+		}
+		return nil
+	})
+	for _, v := range []string{"a/b", "a/b/c", "b", "c", "a/drop"} {
+		a.Nil(fn(context.Background(), client, &server.MsgArrivedRequest{
+			Message: &gmqtt.Message{Topic: v},
+		}))
+	}
+	a.Equal(preErr, fn(context.Background(), client, &server.MsgArrivedRequest{
+		Message: &gmqtt.Message{Topic: "a/err"},
+	}))
+	a.Len(arc.queue, 2)
+	r := <-arc.queue
+	a.Equal("cid", r.ClientID)
+	a.Equal("user", r.Username)
+	a.Equal("a/b", r.Message.Topic)
+	r = <-arc.queue
+	a.Equal("b", r.Message.Topic)
+}
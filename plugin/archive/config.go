@@ -0,0 +1,171 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+func init() {
+	d, err := homedir.Dir()
+	if err != nil {
+		panic(fmt.Sprintf("cannot get home dir: %s", err))
+	}
+	DefaultConfig.File.Path = path.Join(d, "gmqtt_archive.log")
+}
+
+const (
+	SinkFile = "file"
+	SinkSQL  = "sql"
+)
+
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// Config is the configuration for the archive plugin.
+type Config struct {
+	// Topics is the topic filters of the messages to archive.
+	Topics []string `yaml:"topics"`
+	// Sink is the name of the sink to write the messages to.
+	// Possible values: file | sql
+	Sink string `yaml:"sink"`
+	// QueueSize is the maximum number of messages waiting to be written.
+	// Messages will be dropped if the queue is full.
+	QueueSize int `yaml:"queue_size"`
+	// BatchSize is the maximum number of messages in a batch.
+	BatchSize int `yaml:"batch_size"`
+	// FlushInterval is the maximum time that a message waits in an incomplete batch.
+	FlushInterval time.Duration `yaml:"flush_interval"`
+	// MaxRetries is the number of retries when failed to write a batch, the batch is dropped after all retries failed.
+	MaxRetries int `yaml:"max_retries"`
+	// RetryInterval is the interval between retries.
+	RetryInterval time.Duration `yaml:"retry_interval"`
+	File          FileConfig    `yaml:"file"`
+	SQL           SQLConfig     `yaml:"sql"`
+}
+
+// FileConfig is the configuration of the file sink.
+type FileConfig struct {
+	// Path is the file to write the messages to.
+	// The rotated files are named {path}.{timestamp}.
+	Path string `yaml:"path"`
+	// Format is the format of the file.
+	// Possible values: json | csv
+	Format string `yaml:"format"`
+	// MaxSize is the maximum size in bytes of the file before it is rotated, 0 means never rotate.
+	MaxSize int64 `yaml:"max_size"`
+	// MaxBackups is the maximum number of rotated files to keep, 0 means keeping all of them.
+	MaxBackups int `yaml:"max_backups"`
+}
+
+// SQLConfig is the configuration of the sql sink.
+type SQLConfig struct {
+	// Driver is the database/sql driver name, the driver must be registered in the gmqttd binary.
+	// gmqttd contains the mysql, postgres and sqlite3 drivers.
+	Driver string `yaml:"driver"`
+	// DSN is the data source name.
+	DSN string `yaml:"dsn"`
+	// Table is the table to insert the messages into.
+	Table string `yaml:"table"`
+	// Placeholder is the placeholder style of the driver.
+	// Possible values: question (?) | dollar ($1)
+	Placeholder string `yaml:"placeholder"`
+}
+
+// Validate validates the configuration, and return an error if it is invalid.
+func (c *Config) Validate() error {
+	if len(c.Topics) == 0 {
+		return errors.New("topics cannot be empty")
+	}
+	for _, v := range c.Topics {
+		if !packets.ValidTopicFilter(true, []byte(v)) {
+			return fmt.Errorf("invalid topic filter: %s", v)
+		}
+	}
+	if c.QueueSize <= 0 {
+		return errors.New("queue_size must be greater than 0")
+	}
+	if c.BatchSize <= 0 {
+		return errors.New("batch_size must be greater than 0")
+	}
+	if c.FlushInterval <= 0 {
+		return errors.New("flush_interval must be greater than 0")
+	}
+	if c.MaxRetries < 0 {
+		return errors.New("max_retries cannot be negative")
+	}
+	switch c.Sink {
+	case SinkFile:
+		return c.File.validate()
+	case SinkSQL:
+		return c.SQL.validate()
+	}
+	if _, ok := sinks[c.Sink]; !ok {
+		return fmt.Errorf("sink not found: %s", c.Sink)
+	}
+	return nil
+}
+
+func (f *FileConfig) validate() error {
+	if f.Path == "" {
+		return errors.New("file.path must be set")
+	}
+	if f.Format != FormatJSON && f.Format != FormatCSV {
+		return fmt.Errorf("invalid file.format: %s", f.Format)
+	}
+	if f.MaxSize < 0 || f.MaxBackups < 0 {
+		return errors.New("file.max_size and file.max_backups cannot be negative")
+	}
+	return nil
+}
+
+func (s *SQLConfig) validate() error {
+	if s.Driver == "" || s.DSN == "" || s.Table == "" {
+		return errors.New("sql.driver, sql.dsn and sql.table must be set")
+	}
+	if s.Placeholder != placeholderQuestion && s.Placeholder != placeholderDollar {
+		return fmt.Errorf("invalid sql.placeholder: %s", s.Placeholder)
+	}
+	return nil
+}
+
+// DefaultConfig is the default configuration.
+var DefaultConfig = Config{
+	Topics:        []string{"#"},
+	Sink:          SinkFile,
+	QueueSize:     10000,
+	BatchSize:     100,
+	FlushInterval: time.Second,
+	MaxRetries:    3,
+	RetryInterval: time.Second,
+	File: FileConfig{
+		Format:     FormatJSON,
+		MaxSize:    100 << 20,
+		MaxBackups: 10,
+	},
+	SQL: SQLConfig{
+		Table:       "messages",
+		Placeholder: placeholderQuestion,
+	},
+}
+
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type cfg Config
+	var v = &struct {
+		Archive cfg `yaml:"archive"`
+	}{
+		Archive: cfg(DefaultConfig),
+	}
+	if err := unmarshal(v); err != nil {
+		return err
+	}
+	*c = Config(v.Archive)
+	return nil
+}
@@ -0,0 +1,260 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rotateTimeFormat is the time format of the rotated file suffix, which is sortable.
+const rotateTimeFormat = "20060102T150405.000000000"
+
+var csvHeader = []string{
+	"time", "client_id", "username", "topic", "qos", "retained", "payload", "content_type",
+	"correlation_data", "message_expiry", "payload_format", "response_topic", "user_properties",
+}
+
+type userProperty struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// jsonRecord is the JSON format of the record, the payload and correlation data are base64 encoded.
+type jsonRecord struct {
+	Time            time.Time      `json:"time"`
+	ClientID        string         `json:"client_id"`
+	Username        string         `json:"username"`
+	Topic           string         `json:"topic"`
+	QoS             uint8          `json:"qos"`
+	Retained        bool           `json:"retained"`
+	Payload         []byte         `json:"payload"`
+	ContentType     string         `json:"content_type,omitempty"`
+	CorrelationData []byte         `json:"correlation_data,omitempty"`
+	MessageExpiry   uint32         `json:"message_expiry,omitempty"`
+	PayloadFormat   uint8          `json:"payload_format,omitempty"`
+	ResponseTopic   string         `json:"response_topic,omitempty"`
+	UserProperties  []userProperty `json:"user_properties,omitempty"`
+}
+
+func userProperties(r *Record) []userProperty {
+	var rs []userProperty
+	for _, v := range r.Message.UserProperties {
+		rs = append(rs, userProperty{Key: string(v.K), Value: string(v.V)})
+	}
+	return rs
+}
+
+// userPropertiesJSON returns the user properties in JSON array, or an empty string if there are no user properties.
+func userPropertiesJSON(r *Record) string {
+	ppt := userProperties(r)
+	if len(ppt) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(ppt)
+	return string(b)
+}
+
+// fileSink writes the records to the file in JSON lines or CSV format, and rotates the file by size.
+type fileSink struct {
+	config FileConfig
+	f      *os.File
+	w      *bufio.Writer
+	size   int64
+	buf    bytes.Buffer
+}
+
+func newFileSink(config Config) (Sink, error) {
+	s := &fileSink{
+		config: config.File,
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *fileSink) open() error {
+	f, err := os.OpenFile(s.config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	s.f = f
+	s.w = bufio.NewWriter(f)
+	s.size = stat.Size()
+	if s.size == 0 && s.config.Format == FormatCSV {
+		s.buf.Reset()
+		w := csv.NewWriter(&s.buf)
+		_ = w.Write(csvHeader)
+		w.Flush()
+		return s.writeLine(s.buf.Bytes())
+	}
+	return nil
+}
+
+func (s *fileSink) writeLine(b []byte) error {
+	n, err := s.w.Write(b)
+	s.size += int64(n)
+	return err
+}
+
+// encode encodes the record into s.buf.
+func (s *fileSink) encode(r *Record) error {
+	s.buf.Reset()
+	msg := r.Message
+	if s.config.Format == FormatCSV {
+		w := csv.NewWriter(&s.buf)
+		_ = w.Write([]string{
+			r.Time.Format(time.RFC3339Nano),
+			r.ClientID,
+			r.Username,
+			msg.Topic,
+			strconv.Itoa(int(msg.QoS)),
+			strconv.FormatBool(msg.Retained),
+			base64.StdEncoding.EncodeToString(msg.Payload),
+			msg.ContentType,
+			base64.StdEncoding.EncodeToString(msg.CorrelationData),
+			strconv.FormatUint(uint64(msg.MessageExpiry), 10),
+			strconv.Itoa(int(msg.PayloadFormat)),
+			msg.ResponseTopic,
+			userPropertiesJSON(r),
+		})
+		w.Flush()
+		return w.Error()
+	}
+	b, err := json.Marshal(&jsonRecord{
+		Time:            r.Time,
+		ClientID:        r.ClientID,
+		Username:        r.Username,
+		Topic:           msg.Topic,
+		QoS:             msg.QoS,
+		Retained:        msg.Retained,
+		Payload:         msg.Payload,
+		ContentType:     msg.ContentType,
+		CorrelationData: msg.CorrelationData,
+		MessageExpiry:   msg.MessageExpiry,
+		PayloadFormat:   uint8(msg.PayloadFormat),
+		ResponseTopic:   msg.ResponseTopic,
+		UserProperties:  userProperties(r),
+	})
+	if err != nil {
+		return err
+	}
+	s.buf.Write(b)
+	s.buf.WriteByte('\n')
+	return nil
+}
+
+func (s *fileSink) Write(records []*Record) error {
+	if s.f == nil {
+		// the file was closed because of a failed rotation.
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+	for _, r := range records {
+		if err := s.encode(r); err != nil {
+			return err
+		}
+		if s.config.MaxSize > 0 && s.size > 0 && s.size+int64(s.buf.Len()) > s.config.MaxSize {
+			// keep the encoded record in s.buf, it will be overwritten by the CSV header.
+			line := append([]byte(nil), s.buf.Bytes()...)
+			if err := s.rotate(); err != nil {
+				return err
+			}
+			s.buf.Reset()
+			s.buf.Write(line)
+		}
+		if err := s.writeLine(s.buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	if err := s.w.Flush(); err != nil {
+		return err
+	}
+	return s.f.Sync()
+}
+
+// rotate renames the current file to {path}.{timestamp}, opens a new file and removes the old backups.
+func (s *fileSink) rotate() error {
+	if err := s.close(); err != nil {
+		return err
+	}
+	err := os.Rename(s.config.Path, s.config.Path+"."+time.Now().UTC().Format(rotateTimeFormat))
+	if err != nil {
+		return err
+	}
+	if err = s.open(); err != nil {
+		return err
+	}
+	return s.removeBackups()
+}
+
+// backups returns the rotated files, the oldest first.
+func (s *fileSink) backups() ([]string, error) {
+	dir, base := filepath.Split(s.config.Path)
+	if dir == "" {
+		dir = "."
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var rs []string
+	for _, v := range files {
+		if !v.IsDir() && strings.HasPrefix(v.Name(), base+".") {
+			if _, err := time.Parse(rotateTimeFormat, strings.TrimPrefix(v.Name(), base+".")); err == nil {
+				rs = append(rs, filepath.Join(dir, v.Name()))
+			}
+		}
+	}
+	sort.Strings(rs)
+	return rs, nil
+}
+
+func (s *fileSink) removeBackups() error {
+	if s.config.MaxBackups == 0 {
+		return nil
+	}
+	files, err := s.backups()
+	if err != nil {
+		return err
+	}
+	for len(files) > s.config.MaxBackups {
+		if err := os.Remove(files[0]); err != nil {
+			return err
+		}
+		files = files[1:]
+	}
+	return nil
+}
+
+func (s *fileSink) close() error {
+	if s.f == nil {
+		return nil
+	}
+	f := s.f
+	s.f = nil
+	if err := s.w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *fileSink) Close() error {
+	return s.close()
+}
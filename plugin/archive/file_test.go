@@ -0,0 +1,148 @@
+package archive
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "gmqtt_archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	return dir
+}
+
+func fullRecord() *Record {
+	return &Record{
+		Time:     time.Date(2021, 1, 2, 3, 4, 5, 6, time.UTC),
+		ClientID: "cid",
+		Username: "user",
+		Message: &gmqtt.Message{
+			QoS:             1,
+			Retained:        true,
+			Topic:           "a/b",
+			Payload:         []byte{0, 1, 2},
+			ContentType:     "application/octet-stream",
+			CorrelationData: []byte("cd"),
+			MessageExpiry:   10,
+			PayloadFormat:   packets.PayloadFormatBytes,
+			ResponseTopic:   "resp",
+			UserProperties: []packets.UserProperty{
+				{K: []byte("k"), V: []byte("v1")},
+				{K: []byte("k"), V: []byte("v2")},
+			},
+		},
+	}
+}
+
+func readLines(t *testing.T, file string) []string {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+}
+
+func TestFileSink_JSON(t *testing.T) {
+	a := assert.New(t)
+	cfg := DefaultConfig
+	cfg.File.Path = path.Join(tempDir(t), "archive.log")
+	s, err := newFileSink(cfg)
+	a.Nil(err)
+	a.Nil(s.Write([]*Record{fullRecord(), newRecord("c")}))
+	a.Nil(s.Close())
+
+	lines := readLines(t, cfg.File.Path)
+	a.Len(lines, 2)
+	var m map[string]interface{}
+	a.Nil(json.Unmarshal([]byte(lines[0]), &m))
+	a.Equal(map[string]interface{}{
+		"time":             "2021-01-02T03:04:05.000000006Z",
+		"client_id":        "cid",
+		"username":         "user",
+		"topic":            "a/b",
+		"qos":              float64(1),
+		"retained":         true,
+		"payload":          "AAEC",
+		"content_type":     "application/octet-stream",
+		"correlation_data": "Y2Q=",
+		"message_expiry":   float64(10),
+		"response_topic":   "resp",
+		"user_properties": []interface{}{
+			map[string]interface{}{"key": "k", "value": "v1"},
+			map[string]interface{}{"key": "k", "value": "v2"},
+		},
+	}, m)
+
+	// the file is appended after reopen.
+	s, err = newFileSink(cfg)
+	a.Nil(err)
+	a.Nil(s.Write([]*Record{newRecord("d")}))
+	a.Nil(s.Close())
+	a.Len(readLines(t, cfg.File.Path), 3)
+}
+
+func TestFileSink_CSV(t *testing.T) {
+	a := assert.New(t)
+	cfg := DefaultConfig
+	cfg.File.Path = path.Join(tempDir(t), "archive.csv")
+	cfg.File.Format = FormatCSV
+	s, err := newFileSink(cfg)
+	a.Nil(err)
+	a.Nil(s.Write([]*Record{fullRecord()}))
+	a.Nil(s.Close())
+
+	f, err := os.Open(cfg.File.Path)
+	a.Nil(err)
+	defer f.Close()
+	rows, err := csv.NewReader(bufio.NewReader(f)).ReadAll()
+	a.Nil(err)
+	a.Equal([][]string{csvHeader, {
+		"2021-01-02T03:04:05.000000006Z", "cid", "user", "a/b", "1", "true", "AAEC", "application/octet-stream",
+		"Y2Q=", "10", "0", "resp", `[{"key":"k","value":"v1"},{"key":"k","value":"v2"}]`,
+	}}, rows)
+}
+
+func TestFileSink_rotate(t *testing.T) {
+	a := assert.New(t)
+	dir := tempDir(t)
+	cfg := DefaultConfig
+	cfg.File.Path = path.Join(dir, "archive.csv")
+	cfg.File.Format = FormatCSV
+	cfg.File.MaxSize = 400
+	cfg.File.MaxBackups = 2
+	s, err := newFileSink(cfg)
+	a.Nil(err)
+	defer s.Close()
+	for i := 0; i < 10; i++ {
+		a.Nil(s.Write([]*Record{newRecord("topic")}))
+	}
+	files, err := s.(*fileSink).backups()
+	a.Nil(err)
+	a.Len(files, 2)
+	for _, v := range append(files, cfg.File.Path) {
+		stat, err := os.Stat(v)
+		a.Nil(err)
+		a.True(stat.Size() <= cfg.File.MaxSize, v)
+		lines := readLines(t, v)
+		// every file starts with the header.
+		a.Equal(strings.Join(csvHeader, ","), lines[0])
+		a.True(len(lines) > 1)
+	}
+}
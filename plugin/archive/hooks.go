@@ -0,0 +1,35 @@
+package archive
+
+import (
+	"context"
+	"time"
+
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func (a *Archive) HookWrapper() server.HookWrapper {
+	return server.HookWrapper{
+		OnMsgArrivedWrapper: a.OnMsgArrivedWrapper,
+	}
+}
+
+// OnMsgArrivedWrapper puts the messages that match the topic filters into the write queue.
+// It never blocks, the messages are dropped if the queue is full.
+func (a *Archive) OnMsgArrivedWrapper(pre server.OnMsgArrived) server.OnMsgArrived {
+	return func(ctx context.Context, client server.Client, req *server.MsgArrivedRequest) error {
+		err := pre(ctx, client, req)
+		if err != nil || req.Message == nil {
+			return err
+		}
+		if a.match(req.Message.Topic) {
+			opts := client.ClientOptions()
+			a.add(&Record{
+				Time:     time.Now(),
+				ClientID: opts.ClientID,
+				Username: opts.Username,
+				Message:  req.Message,
+			})
+		}
+		return nil
+	}
+}
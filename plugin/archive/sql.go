@@ -0,0 +1,94 @@
+package archive
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+const (
+	placeholderQuestion = "question"
+	placeholderDollar   = "dollar"
+)
+
+var sqlColumns = []string{
+	"time", "client_id", "username", "topic", "qos", "retained", "payload", "content_type",
+	"correlation_data", "message_expiry", "payload_format", "response_topic", "user_properties",
+}
+
+// sqlSink inserts the records into the table with database/sql, each batch is inserted in a transaction.
+type sqlSink struct {
+	db     *sql.DB
+	insert string
+}
+
+func newSQLSink(config Config) (Sink, error) {
+	cfg := config.SQL
+	db, err := sql.Open(cfg.Driver, cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &sqlSink{
+		db:     db,
+		insert: insertStatement(cfg.Table, cfg.Placeholder),
+	}, nil
+}
+
+func insertStatement(table string, placeholder string) string {
+	ph := make([]string, len(sqlColumns))
+	for k := range ph {
+		if placeholder == placeholderDollar {
+			ph[k] = fmt.Sprintf("$%d", k+1)
+		} else {
+			ph[k] = "?"
+		}
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(sqlColumns, ", "), strings.Join(ph, ", "))
+}
+
+func (s *sqlSink) Write(records []*Record) (err error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	stmt, err := tx.Prepare(s.insert)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, r := range records {
+		msg := r.Message
+		_, err = stmt.Exec(
+			r.Time.UTC(),
+			r.ClientID,
+			r.Username,
+			msg.Topic,
+			msg.QoS,
+			msg.Retained,
+			msg.Payload,
+			msg.ContentType,
+			msg.CorrelationData,
+			msg.MessageExpiry,
+			uint8(msg.PayloadFormat),
+			msg.ResponseTopic,
+			userPropertiesJSON(r),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *sqlSink) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,98 @@
+package archive
+
+import (
+	"database/sql"
+	"path"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+const sqliteSchema = `CREATE TABLE messages (
+	time TIMESTAMP NOT NULL,
+	client_id TEXT NOT NULL,
+	username TEXT NOT NULL,
+	topic TEXT NOT NULL,
+	qos INTEGER NOT NULL,
+	retained BOOLEAN NOT NULL,
+	payload BLOB,
+	content_type TEXT NOT NULL,
+	correlation_data BLOB,
+	message_expiry INTEGER NOT NULL,
+	payload_format INTEGER NOT NULL,
+	response_topic TEXT NOT NULL,
+	user_properties TEXT NOT NULL
+)`
+
+func TestInsertStatement(t *testing.T) {
+	a := assert.New(t)
+	a.Equal("INSERT INTO t (time, client_id, username, topic, qos, retained, payload, content_type, "+
+		"correlation_data, message_expiry, payload_format, response_topic, user_properties) "+
+		"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", insertStatement("t", placeholderQuestion))
+	a.Equal("INSERT INTO t (time, client_id, username, topic, qos, retained, payload, content_type, "+
+		"correlation_data, message_expiry, payload_format, response_topic, user_properties) "+
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)", insertStatement("t", placeholderDollar))
+}
+
+func TestSQLSink(t *testing.T) {
+	a := assert.New(t)
+	cfg := DefaultConfig
+	cfg.Sink = SinkSQL
+	cfg.SQL.Driver = "sqlite3"
+	cfg.SQL.DSN = path.Join(tempDir(t), "archive.db")
+	db, err := sql.Open(cfg.SQL.Driver, cfg.SQL.DSN)
+	a.Nil(err)
+	defer db.Close()
+	_, err = db.Exec(sqliteSchema)
+	a.Nil(err)
+
+	s, err := newSQLSink(cfg)
+	a.Nil(err)
+	a.Nil(s.Write([]*Record{fullRecord(), newRecord("c")}))
+	a.Nil(s.Close())
+
+	rows, err := db.Query("SELECT time, client_id, username, topic, qos, retained, payload, content_type, " +
+		"correlation_data, message_expiry, payload_format, response_topic, user_properties FROM messages ORDER BY topic")
+	a.Nil(err)
+	defer rows.Close()
+	var (
+		tm                                     time.Time
+		clientID, username, topic, contentType string
+		responseTopic, userProperties          string
+		qos, messageExpiry, payloadFormat      int
+		retained                               bool
+		payload, correlationData               []byte
+	)
+	a.True(rows.Next())
+	a.Nil(rows.Scan(&tm, &clientID, &username, &topic, &qos, &retained, &payload, &contentType,
+		&correlationData, &messageExpiry, &payloadFormat, &responseTopic, &userProperties))
+	r := fullRecord()
+	a.True(r.Time.Equal(tm))
+	a.Equal("cid", clientID)
+	a.Equal("user", username)
+	a.Equal("a/b", topic)
+	a.Equal(1, qos)
+	a.True(retained)
+	a.Equal([]byte{0, 1, 2}, payload)
+	a.Equal("application/octet-stream", contentType)
+	a.Equal([]byte("cd"), correlationData)
+	a.Equal(10, messageExpiry)
+	a.Equal(0, payloadFormat)
+	a.Equal("resp", responseTopic)
+	a.Equal(`[{"key":"k","value":"v1"},{"key":"k","value":"v2"}]`, userProperties)
+	a.True(rows.Next())
+	a.True(rows.Next() == false)
+
+	// the batch is rolled back if any insert fails.
+	_, err = db.Exec("CREATE UNIQUE INDEX topic ON messages (topic)")
+	a.Nil(err)
+	s, err = newSQLSink(cfg)
+	a.Nil(err)
+	defer s.Close()
+	a.NotNil(s.Write([]*Record{newRecord("d"), newRecord("a/b")}))
+	var n int
+	a.Nil(db.QueryRow("SELECT COUNT(*) FROM messages").Scan(&n))
+	a.Equal(2, n)
+}
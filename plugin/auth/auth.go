@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"crypto/md5"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
@@ -24,6 +25,7 @@ import (
 )
 
 var _ server.Plugin = (*Auth)(nil)
+var _ server.Reloadable = (*Auth)(nil)
 
 const Name = "auth"
 
@@ -33,9 +35,15 @@ func init() {
 }
 
 func New(config config.Config) (server.Plugin, error) {
+	saltSecret := make([]byte, 32)
+	_, err := rand.Read(saltSecret)
+	if err != nil {
+		return nil, err
+	}
 	a := &Auth{
-		config:  config.Plugins[Name].(*Config),
-		indexer: admin.NewIndexer(),
+		config:     config.Plugins[Name].(*Config),
+		indexer:    admin.NewIndexer(),
+		saltSecret: saltSecret,
 	}
 	a.saveFile = a.saveFileHandler
 	return a, nil
@@ -47,18 +55,27 @@ var log *zap.Logger
 // The authentication data is persist in config.PasswordFile.
 type Auth struct {
 	config *Config
-	// gard indexer
+	// gard indexer & config
 	mu sync.RWMutex
 	// store username/password
 	indexer *admin.Indexer
 	// saveFile persists the account data to password file.
 	saveFile func() error
+	// saltSecret is used to derive the SCRAM salts of the accounts stored in plain text.
+	saltSecret []byte
+}
+
+// getConfig returns the current config, the config will be replaced when reloading.
+func (a *Auth) getConfig() *Config {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.config
 }
 
 // generatePassword generates the hashed password for the plain password.
 func (a *Auth) generatePassword(password string) (hashedPassword string, err error) {
 	var h hash.Hash
-	switch a.config.Hash {
+	switch a.getConfig().Hash {
 	case Plain:
 		return password, nil
 	case MD5:
@@ -87,6 +104,7 @@ func (a *Auth) mustEmbedUnimplementedAccountServiceServer() {
 func (a *Auth) validate(username, password string) (permitted bool, err error) {
 	a.mu.RLock()
 	elem := a.indexer.GetByID(username)
+	hashType := a.config.Hash
 	a.mu.RUnlock()
 	var hashedPassword string
 	if elem == nil {
@@ -98,7 +116,7 @@ func (a *Auth) validate(username, password string) (permitted bool, err error) {
 		return true, nil
 	}
 	var h hash.Hash
-	switch a.config.Hash {
+	switch hashType {
 	case Plain:
 		return hashedPassword == password, nil
 	case MD5:
@@ -126,37 +144,45 @@ func (a *Auth) RegisterHTTP(ctx context.Context, mux *runtime.ServeMux, endpoint
 	return RegisterAccountServiceHandlerFromEndpoint(ctx, mux, endpoint, opts)
 }
 
-func (a *Auth) Load(service server.Server) error {
-	log = server.LoggerWithField(zap.String("plugin", Name))
-	f, err := os.OpenFile(a.config.PasswordFile, os.O_CREATE|os.O_RDONLY, 0666)
+// readFile reads and validates the accounts from the password file.
+func readFile(passwordFile string) ([]*Account, error) {
+	f, err := os.OpenFile(passwordFile, os.O_CREATE|os.O_RDONLY, 0666)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer f.Close()
 	b, err := ioutil.ReadAll(f)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	var acts []*Account
 	err = yaml.Unmarshal(b, &acts)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	log.Info("authentication data loaded",
-		zap.String("hash", a.config.Hash),
-		zap.Int("account_nums", len(acts)),
-		zap.String("password_file", a.config.PasswordFile))
-
 	dup := make(map[string]struct{})
 	for _, v := range acts {
 		if v.Username == "" {
-			return errors.New("detect empty username in password file")
+			return nil, errors.New("detect empty username in password file")
 		}
 		if _, ok := dup[v.Username]; ok {
-			return fmt.Errorf("detect duplicated username in password file: %s", v.Username)
+			return nil, fmt.Errorf("detect duplicated username in password file: %s", v.Username)
 		}
 		dup[v.Username] = struct{}{}
 	}
+	return acts, nil
+}
+
+func (a *Auth) Load(service server.Server) error {
+	log = server.LoggerWithField(zap.String("plugin", Name))
+	acts, err := readFile(a.config.PasswordFile)
+	if err != nil {
+		return err
+	}
+	log.Info("authentication data loaded",
+		zap.String("hash", a.config.Hash),
+		zap.Int("account_nums", len(acts)),
+		zap.String("password_file", a.config.PasswordFile))
 	a.mu.Lock()
 	defer a.mu.Unlock()
 	for _, v := range acts {
@@ -165,6 +191,29 @@ func (a *Auth) Load(service server.Server) error {
 	return nil
 }
 
+// Reload applies the new config and reloads the authentication data from the password file.
+// The current data will be kept if the password file is invalid.
+func (a *Auth) Reload(config config.Config) error {
+	cfg := config.Plugins[Name].(*Config)
+	acts, err := readFile(cfg.PasswordFile)
+	if err != nil {
+		return err
+	}
+	indexer := admin.NewIndexer()
+	for _, v := range acts {
+		indexer.Set(v.Username, v)
+	}
+	a.mu.Lock()
+	a.config = cfg
+	a.indexer = indexer
+	a.mu.Unlock()
+	log.Info("authentication data reloaded",
+		zap.String("hash", cfg.Hash),
+		zap.Int("account_nums", len(acts)),
+		zap.String("password_file", cfg.PasswordFile))
+	return nil
+}
+
 func (a *Auth) Unload() error {
 	return nil
 }
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"io/ioutil"
 	"os"
 	"testing"
 
@@ -127,3 +128,58 @@ func TestAuth_Load_OK(t *testing.T) {
 	a.True(p)
 	a.Nil(err)
 }
+
+func TestAuth_Reload(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := DefaultConfig
+	cfg.PasswordFile = "./testdata/gmqtt_password.yml"
+	cfg.Hash = Plain
+	auth, err := New(config.Config{
+		Plugins: map[string]config.Configuration{
+			"auth": &cfg,
+		},
+	})
+	a.Nil(err)
+	ms := server.NewMockServer(ctrl)
+	a.Nil(auth.Load(ms))
+	au := auth.(*Auth)
+
+	f, err := ioutil.TempFile("", "gmqtt_password")
+	a.Nil(err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("- username: u3\n  password: 7bc3ca68769437ce986455407dab2a1f\n")
+	a.Nil(err)
+	a.Nil(f.Close())
+
+	newCfg := cfg
+	newCfg.PasswordFile = f.Name()
+	newCfg.Hash = MD5
+	a.Nil(au.Reload(config.Config{
+		Plugins: map[string]config.Configuration{
+			"auth": &newCfg,
+		},
+	}))
+	a.Equal(&newCfg, au.getConfig())
+	p, err := au.validate("u1", "p1")
+	a.False(p)
+	a.Nil(err)
+	p, err = au.validate("u3", "p3")
+	a.True(p)
+	a.Nil(err)
+
+	// keep the current data if the password file is invalid.
+	invalidCfg := cfg
+	invalidCfg.PasswordFile = "./testdata/gmqtt_password_duplicated.yml"
+	a.Error(au.Reload(config.Config{
+		Plugins: map[string]config.Configuration{
+			"auth": &invalidCfg,
+		},
+	}))
+	a.Equal(&newCfg, au.getConfig())
+	p, err = au.validate("u3", "p3")
+	a.True(p)
+	a.Nil(err)
+}
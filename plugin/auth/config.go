@@ -36,6 +36,11 @@ type Config struct {
 	// Hash is the password hash algorithm.
 	// Possible values: plain | md5 | sha256 | bcrypt
 	Hash string `yaml:"hash"`
+	// Scram indicates whether to generate the SCRAM-SHA-1 and SCRAM-SHA-256 credentials
+	// when creating or updating accounts. The credentials are used by the scram plugin.
+	Scram bool `yaml:"scram"`
+	// ScramIterations is the iteration count to generate the SCRAM credentials.
+	ScramIterations int `yaml:"scram_iterations"`
 }
 
 // validate validates the configuration, and return an error if it is invalid.
@@ -43,6 +48,9 @@ func (c *Config) Validate() error {
 	if c.PasswordFile == "" {
 		return errors.New("password_file must be set")
 	}
+	if c.Scram && c.ScramIterations <= 0 {
+		return errors.New("scram_iterations must be greater than 0")
+	}
 	for _, v := range ValidateHashType {
 		if v == c.Hash {
 			return nil
@@ -53,7 +61,8 @@ func (c *Config) Validate() error {
 
 // DefaultConfig is the default configuration.
 var DefaultConfig = Config{
-	Hash: MD5,
+	Hash:            MD5,
+	ScramIterations: 4096,
 }
 
 func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
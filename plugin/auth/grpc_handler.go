@@ -48,6 +48,13 @@ func (a *Auth) Get(ctx context.Context, req *GetAccountRequest) (resp *GetAccoun
 	return nil, admin.ErrNotFound
 }
 
+// fileAccount is the account format in the password file.
+type fileAccount struct {
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	Scram    []string `yaml:"scram,omitempty"`
+}
+
 // saveFileHandler is the default handler for auth.saveFile, must call after auth.mu is locked
 func (a *Auth) saveFileHandler() error {
 	tmpfile, err := ioutil.TempFile("", "gmqtt_password")
@@ -58,9 +65,14 @@ func (a *Auth) saveFileHandler() error {
 
 	w := bufio.NewWriter(tmpfile)
 	// get all accounts
-	var accounts []*Account
+	var accounts []*fileAccount
 	a.indexer.Iterate(func(elem *list.Element) {
-		accounts = append(accounts, elem.Value.(*Account))
+		ac := elem.Value.(*Account)
+		accounts = append(accounts, &fileAccount{
+			Username: ac.Username,
+			Password: ac.Password,
+			Scram:    ac.Scram,
+		})
 	}, 0, uint(a.indexer.Len()))
 
 	b, err := yaml.Marshal(accounts)
@@ -91,6 +103,10 @@ func (a *Auth) Update(ctx context.Context, req *UpdateAccountRequest) (resp *emp
 	if err != nil {
 		return &empty.Empty{}, err
 	}
+	scram, err := a.generateScramCredentials(req.Password)
+	if err != nil {
+		return &empty.Empty{}, err
+	}
 	a.mu.Lock()
 	defer a.mu.Unlock()
 	var oact *Account
@@ -101,6 +117,7 @@ func (a *Auth) Update(ctx context.Context, req *UpdateAccountRequest) (resp *emp
 	a.indexer.Set(req.Username, &Account{
 		Username: req.Username,
 		Password: hashedPassword,
+		Scram:    scram,
 	})
 	err = a.saveFile()
 	if err != nil {
@@ -109,10 +126,7 @@ func (a *Auth) Update(ctx context.Context, req *UpdateAccountRequest) (resp *emp
 			a.indexer.Remove(req.Username)
 			return &empty.Empty{}, err
 		}
-		a.indexer.Set(req.Username, &Account{
-			Username: req.Username,
-			Password: oact.Password,
-		})
+		a.indexer.Set(req.Username, oact)
 	}
 	if oact == nil {
 		log.Info("new account created", zap.String("username", req.Username))
@@ -140,10 +154,7 @@ func (a *Auth) Delete(ctx context.Context, req *DeleteAccountRequest) (resp *emp
 	err = a.saveFile()
 	if err != nil {
 		// should rollback if failed to persist to file
-		a.indexer.Set(req.Username, &Account{
-			Username: req.Username,
-			Password: oact.(*Account).Password,
-		})
+		a.indexer.Set(req.Username, oact)
 		return &empty.Empty{}, err
 	}
 	log.Info("account deleted", zap.String("username", req.Username))
@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	ScramSHA1   = "SCRAM-SHA-1"
+	ScramSHA256 = "SCRAM-SHA-256"
+)
+
+// ScramMechanisms is the SCRAM mechanisms supported by the auth plugin.
+var ScramMechanisms = []string{
+	ScramSHA1, ScramSHA256,
+}
+
+// ScramHash returns the hash function of the SCRAM mechanism, or nil if the mechanism is not supported.
+func ScramHash(mechanism string) func() hash.Hash {
+	switch mechanism {
+	case ScramSHA1:
+		return sha1.New
+	case ScramSHA256:
+		return sha256.New
+	}
+	return nil
+}
+
+// ScramCredential is the salted credential of a SCRAM mechanism, see RFC 5802.
+type ScramCredential struct {
+	Mechanism  string
+	Iterations int
+	Salt       []byte
+	StoredKey  []byte
+	ServerKey  []byte
+}
+
+// NewScramCredential generates the SCRAM credential with a random salt for the password.
+func NewScramCredential(mechanism, password string, iterations int) (*ScramCredential, error) {
+	salt := make([]byte, 16)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, err
+	}
+	return newScramCredential(mechanism, password, salt, iterations)
+}
+
+func newScramCredential(mechanism, password string, salt []byte, iterations int) (*ScramCredential, error) {
+	h := ScramHash(mechanism)
+	if h == nil {
+		return nil, fmt.Errorf("unsupported scram mechanism: %s", mechanism)
+	}
+	if iterations <= 0 {
+		return nil, fmt.Errorf("invalid scram iterations: %d", iterations)
+	}
+	salted := pbkdf2.Key([]byte(password), salt, iterations, h().Size(), h)
+	clientKey := scramHMAC(h, salted, []byte("Client Key"))
+	storedKey := h()
+	storedKey.Write(clientKey)
+	return &ScramCredential{
+		Mechanism:  mechanism,
+		Iterations: iterations,
+		Salt:       salt,
+		StoredKey:  storedKey.Sum(nil),
+		ServerKey:  scramHMAC(h, salted, []byte("Server Key")),
+	}, nil
+}
+
+func scramHMAC(h func() hash.Hash, key, data []byte) []byte {
+	mac := hmac.New(h, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// String returns the credential in "<mechanism>$<iterations>:<salt>$<stored_key>:<server_key>" format,
+// the binary fields are base64 encoded.
+func (s *ScramCredential) String() string {
+	enc := base64.StdEncoding.EncodeToString
+	return fmt.Sprintf("%s$%d:%s$%s:%s", s.Mechanism, s.Iterations, enc(s.Salt), enc(s.StoredKey), enc(s.ServerKey))
+}
+
+// ParseScramCredential parses the credential returned by ScramCredential.String.
+func ParseScramCredential(s string) (*ScramCredential, error) {
+	parts := strings.Split(s, "$")
+	if len(parts) != 3 {
+		return nil, errors.New("invalid scram credential format")
+	}
+	h := ScramHash(parts[0])
+	if h == nil {
+		return nil, fmt.Errorf("unsupported scram mechanism: %s", parts[0])
+	}
+	iterSalt := strings.Split(parts[1], ":")
+	keys := strings.Split(parts[2], ":")
+	if len(iterSalt) != 2 || len(keys) != 2 {
+		return nil, errors.New("invalid scram credential format")
+	}
+	iter, err := strconv.Atoi(iterSalt[0])
+	if err != nil || iter <= 0 {
+		return nil, fmt.Errorf("invalid scram iterations: %s", iterSalt[0])
+	}
+	c := &ScramCredential{
+		Mechanism:  parts[0],
+		Iterations: iter,
+	}
+	for i, v := range []*[]byte{&c.Salt, &c.StoredKey, &c.ServerKey} {
+		var field string
+		if i == 0 {
+			field = iterSalt[1]
+		} else {
+			field = keys[i-1]
+		}
+		*v, err = base64.StdEncoding.DecodeString(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scram credential: %s", err)
+		}
+	}
+	if len(c.StoredKey) != h().Size() || len(c.ServerKey) != h().Size() {
+		return nil, errors.New("invalid scram credential key size")
+	}
+	return c, nil
+}
+
+// generateScramCredentials generates the credentials of all supported SCRAM mechanisms for the password.
+func (a *Auth) generateScramCredentials(password string) ([]string, error) {
+	cfg := a.getConfig()
+	if !cfg.Scram {
+		return nil, nil
+	}
+	var rs []string
+	for _, v := range ScramMechanisms {
+		c, err := NewScramCredential(v, password, cfg.ScramIterations)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, c.String())
+	}
+	return rs, nil
+}
+
+// ScramCredential returns the SCRAM credential of the account for the given mechanism.
+// It returns nil if the account does not exist or has no credential for the mechanism.
+// For accounts stored in plain text, the credential is derived from the password with a salt derived from the username,
+// so that the salt is stable across the attempts and the existence of the account is not disclosed.
+func (a *Auth) ScramCredential(username, mechanism string) (*ScramCredential, error) {
+	a.mu.RLock()
+	elem := a.indexer.GetByID(username)
+	cfg := a.config
+	a.mu.RUnlock()
+	if elem == nil {
+		return nil, nil
+	}
+	ac := elem.Value.(*Account)
+	for _, v := range ac.Scram {
+		if !strings.HasPrefix(v, mechanism+"$") {
+			continue
+		}
+		return ParseScramCredential(v)
+	}
+	if cfg.Hash == Plain {
+		return newScramCredential(mechanism, ac.Password, a.ScramSalt(username, mechanism), cfg.ScramIterations)
+	}
+	return nil, nil
+}
+
+// ScramSalt returns the salt derived from the username and mechanism under the server secret.
+// It is used for the accounts stored in plain text, and can be used as the fake salt for nonexistent accounts.
+func (a *Auth) ScramSalt(username, mechanism string) []byte {
+	mac := hmac.New(sha256.New, a.saltSecret)
+	mac.Write([]byte(mechanism + "," + username))
+	return mac.Sum(nil)[:16]
+}
+
+// ScramIterations returns the iteration count of the SCRAM credentials generated by the auth plugin.
+func (a *Auth) ScramIterations() int {
+	return a.getConfig().ScramIterations
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt/config"
+)
+
+func TestScramCredential_String(t *testing.T) {
+	a := assert.New(t)
+	for _, m := range ScramMechanisms {
+		c, err := NewScramCredential(m, "password", 4096)
+		a.Nil(err)
+		a.Equal(m, c.Mechanism)
+		a.Equal(4096, c.Iterations)
+		a.Len(c.StoredKey, ScramHash(m)().Size())
+		pc, err := ParseScramCredential(c.String())
+		a.Nil(err)
+		a.Equal(c, pc)
+	}
+	// RFC 5802 example
+	c, err := newScramCredential(ScramSHA1, "pencil", []byte{0x41, 0x25, 0xc2, 0x47, 0xe4, 0x3a, 0xb1, 0xe9, 0x3c, 0x6d, 0xff, 0x76}, 4096)
+	a.Nil(err)
+	a.Equal("SCRAM-SHA-1$4096:QSXCR+Q6sek8bf92$6dlGYMOdZcOPutkcNY8U2g7vK9Y=:D+CSWLOshSulAsxiupA+qs2/fTE=", c.String())
+
+	for _, v := range []string{
+		"SCRAM-SHA-1$4096:QSXCR+Q6sek8bf92$6dlGYMOdZcOPutkcNY8U2g7vK9Y=",
+		"SCRAM-MD5$4096:QSXCR+Q6sek8bf92$6dlGYMOdZcOPutkcNY8U2g7vK9Y=:D+CSWLOshSulAsxiupA+qs2/fTE=",
+		"SCRAM-SHA-1$0:QSXCR+Q6sek8bf92$6dlGYMOdZcOPutkcNY8U2g7vK9Y=:D+CSWLOshSulAsxiupA+qs2/fTE=",
+		"SCRAM-SHA-256$4096:QSXCR+Q6sek8bf92$6dlGYMOdZcOPutkcNY8U2g7vK9Y=:D+CSWLOshSulAsxiupA+qs2/fTE=",
+		"SCRAM-SHA-1$4096:QSXCR+Q6sek8bf92$!!!:D+CSWLOshSulAsxiupA+qs2/fTE=",
+	} {
+		_, err = ParseScramCredential(v)
+		a.NotNil(err, v)
+	}
+}
+
+func TestAuth_ScramCredential(t *testing.T) {
+	a := assert.New(t)
+	cfg := DefaultConfig
+	cfg.PasswordFile = "./testdata/gmqtt_password.yml"
+	cfg.Hash = SHA256
+	cfg.Scram = true
+	auth, err := New(config.Config{
+		Plugins: map[string]config.Configuration{
+			"auth": &cfg,
+		},
+	})
+	a.Nil(err)
+	a.Nil(auth.Load(nil))
+	au := auth.(*Auth)
+	au.saveFile = func() error {
+		return nil
+	}
+	// no credential for the accounts created before scram enabled.
+	c, err := au.ScramCredential("u1", ScramSHA256)
+	a.Nil(err)
+	a.Nil(c)
+
+	_, err = au.Update(context.Background(), &UpdateAccountRequest{
+		Username: "u1",
+		Password: "pencil",
+	})
+	a.Nil(err)
+	for _, m := range ScramMechanisms {
+		c, err = au.ScramCredential("u1", m)
+		a.Nil(err)
+		a.Equal(m, c.Mechanism)
+		expected, err := newScramCredential(m, "pencil", c.Salt, cfg.ScramIterations)
+		a.Nil(err)
+		a.Equal(expected, c)
+	}
+	c, err = au.ScramCredential("u3", ScramSHA256)
+	a.Nil(err)
+	a.Nil(c)
+
+	// plain passwords derive the credentials.
+	cfg.Hash = Plain
+	c, err = au.ScramCredential("u2", ScramSHA1)
+	a.Nil(err)
+	expected, err := newScramCredential(ScramSHA1, "p2", c.Salt, cfg.ScramIterations)
+	a.Nil(err)
+	a.Equal(expected, c)
+	// the salt is stable across the attempts, the same as the fake salt for nonexistent accounts.
+	a.Equal(au.ScramSalt("u2", ScramSHA1), c.Salt)
+	c2, err := au.ScramCredential("u2", ScramSHA1)
+	a.Nil(err)
+	a.Equal(c, c2)
+	a.NotEqual(au.ScramSalt("u2", ScramSHA1), au.ScramSalt("u3", ScramSHA1))
+	a.NotEqual(au.ScramSalt("u2", ScramSHA1), au.ScramSalt("u2", ScramSHA256))
+	a.Equal(cfg.ScramIterations, au.ScramIterations())
+}
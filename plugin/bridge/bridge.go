@@ -0,0 +1,106 @@
+package bridge
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+var _ server.Plugin = (*Bridge)(nil)
+
+const Name = "bridge"
+
+// LoopPropertyKey is the v5 user property key that used to prevent message loops.
+// Every bridge that forwards a message appends a property with its node name as the value,
+// and a bridge drops the messages that already contain its own node name.
+const LoopPropertyKey = "gmqtt-bridge"
+
+func init() {
+	server.RegisterPlugin(Name, New)
+	config.RegisterDefaultPluginConfig(Name, &DefaultConfig)
+}
+
+func New(config config.Config) (server.Plugin, error) {
+	cfg := config.Plugins[Name].(*Config)
+	nodeName := cfg.NodeName
+	if nodeName == "" {
+		var err error
+		nodeName, err = os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("bridge: cannot get node name: %s", err)
+		}
+	}
+	b := &Bridge{
+		nodeName: nodeName,
+	}
+	for _, v := range cfg.Remotes {
+		r, err := newRemote(nodeName, v)
+		if err != nil {
+			return nil, fmt.Errorf("bridge: invalid remote %s: %s", v.Name, err)
+		}
+		b.remotes = append(b.remotes, r)
+	}
+	return b, nil
+}
+
+var log *zap.Logger
+
+// Bridge forwards messages between the local broker and the remote brokers.
+type Bridge struct {
+	nodeName string
+	remotes  []*remote
+}
+
+func (b *Bridge) Load(service server.Server) error {
+	log = server.LoggerWithField(zap.String("plugin", Name))
+	for _, r := range b.remotes {
+		r.start(service.Publisher(), service.RetainedService())
+	}
+	return nil
+}
+
+func (b *Bridge) Unload() error {
+	for _, r := range b.remotes {
+		r.stop()
+	}
+	return nil
+}
+
+func (b *Bridge) Name() string {
+	return Name
+}
+
+// rewriteTopic replaces the prefix from with to.
+func rewriteTopic(topic, from, to string) string {
+	if strings.HasPrefix(topic, from) {
+		return to + topic[len(from):]
+	}
+	return topic
+}
+
+// matchRule returns the first rule that matches the topic.
+func matchRule(rules []*Rule, topic string) *Rule {
+	for _, v := range rules {
+		if packets.TopicMatch([]byte(topic), []byte(v.Filter)) {
+			return v
+		}
+	}
+	return nil
+}
+
+// hasLooped reports whether the message has been forwarded by the given node.
+func hasLooped(msg *gmqtt.Message, nodeName string) bool {
+	for _, v := range msg.UserProperties {
+		if string(v.K) == LoopPropertyKey && string(v.V) == nodeName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,335 @@
+package bridge
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/config"
+	_ "github.com/DrmagicE/gmqtt/persistence"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/retained"
+	"github.com/DrmagicE/gmqtt/server"
+	_ "github.com/DrmagicE/gmqtt/topicalias/fifo"
+)
+
+type testPublisher chan *gmqtt.Message
+
+func (t testPublisher) Publish(message *gmqtt.Message) {
+	t <- message
+}
+
+type testRetained struct {
+	retained.Store
+	added   []*gmqtt.Message
+	removed []string
+}
+
+func (t *testRetained) AddOrReplace(message *gmqtt.Message) {
+	t.added = append(t.added, message)
+}
+
+func (t *testRetained) Remove(topicName string) {
+	t.removed = append(t.removed, topicName)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	a := assert.New(t)
+	newCfg := func() *RemoteConfig {
+		c := DefaultRemoteConfig
+		c.Name = "remote"
+		c.Address = "127.0.0.1:1883"
+		c.In = []*Rule{{Filter: "a/#", MaxQoS: packets.Qos1}}
+		return &c
+	}
+	a.Nil((&Config{Remotes: []*RemoteConfig{newCfg()}}).Validate())
+	a.Error((&Config{Remotes: []*RemoteConfig{newCfg(), newCfg()}}).Validate())
+
+	c := newCfg()
+	c.ProtocolVersion = 3
+	a.Error(c.Validate())
+
+	// the v3 remotes can not be bidirectional.
+	c = newCfg()
+	c.ProtocolVersion = packets.Version311
+	a.Nil(c.Validate())
+	c.Out = []*Rule{{Filter: "b/#", MaxQoS: packets.Qos1}}
+	a.Error(c.Validate())
+
+	c = newCfg()
+	c.In[0].Filter = "a/#/b"
+	a.Error(c.Validate())
+
+	c = newCfg()
+	c.MaxBackoff = c.InitialBackoff / 2
+	a.Error(c.Validate())
+}
+
+func TestRewriteTopic(t *testing.T) {
+	a := assert.New(t)
+	a.Equal("edge1/sensors/a", rewriteTopic("sensors/a", "sensors/", "edge1/sensors/"))
+	a.Equal("sensors/a", rewriteTopic("sensors/a", "", ""))
+	a.Equal("other/a", rewriteTopic("other/a", "sensors/", "edge1/sensors/"))
+	a.Equal("prefix/other/a", rewriteTopic("other/a", "", "prefix/"))
+}
+
+func TestMatchRule(t *testing.T) {
+	a := assert.New(t)
+	rules := []*Rule{
+		{Filter: "a/+"},
+		{Filter: "#"},
+	}
+	a.Equal(rules[0], matchRule(rules, "a/b"))
+	a.Equal(rules[1], matchRule(rules, "a/b/c"))
+	a.Nil(matchRule(rules[:1], "b"))
+}
+
+func newTestRemote(nodeName string, cfg *RemoteConfig) *remote {
+	c := DefaultRemoteConfig
+	c.Name = "test"
+	c.Address = "127.0.0.1:1883"
+	c.QueueSize = 10
+	c.In = cfg.In
+	c.Out = cfg.Out
+	if cfg.Address != "" {
+		c.Address = cfg.Address
+	}
+	r, err := newRemote(nodeName, &c)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func TestRemote_forward(t *testing.T) {
+	a := assert.New(t)
+	log = zap.NewNop()
+	r := newTestRemote("node1", &RemoteConfig{
+		Out: []*Rule{
+			{Filter: "sensors/#", LocalPrefix: "sensors/", RemotePrefix: "edge1/sensors/", MaxQoS: packets.Qos1},
+		},
+	})
+	msg := &gmqtt.Message{
+		QoS:      packets.Qos2,
+		Topic:    "sensors/temp",
+		Payload:  []byte("1"),
+		PacketID: 10,
+	}
+	r.forward(msg)
+	r.forward(&gmqtt.Message{Topic: "other"})
+	a.Len(r.queue, 1)
+	m := <-r.queue
+	a.Equal("edge1/sensors/temp", m.Topic)
+	a.Equal(packets.Qos1, m.QoS)
+	a.EqualValues(0, m.PacketID)
+	a.True(hasLooped(m, "node1"))
+	// the original message is not modified
+	a.Equal("sensors/temp", msg.Topic)
+	a.Len(msg.UserProperties, 0)
+
+	// loop prevention
+	r.forward(m)
+	a.Len(r.queue, 0)
+}
+
+func TestBridge_OnMsgDispatchedWrapper(t *testing.T) {
+	a := assert.New(t)
+	log = zap.NewNop()
+	r := newTestRemote("node1", &RemoteConfig{
+		Out: []*Rule{
+			{Filter: "#", MaxQoS: packets.Qos1},
+		},
+	})
+	b := &Bridge{nodeName: "node1", remotes: []*remote{r}}
+	var called int
+	fn := b.OnMsgDispatchedWrapper(func(ctx context.Context, srcClientID string, msg *gmqtt.Message) {
+		called++
+	})
+	fn(context.Background(), "cid", &gmqtt.Message{Topic: "a"})
+	a.Equal(1, called)
+	a.Len(r.queue, 1)
+	a.Equal("a", (<-r.queue).Topic)
+
+	// the messages published by the plugins are not forwarded.
+	fn(context.Background(), "", &gmqtt.Message{Topic: "b"})
+	a.Equal(2, called)
+	a.Len(r.queue, 0)
+}
+
+func TestRemote_deliver(t *testing.T) {
+	a := assert.New(t)
+	log = zap.NewNop()
+	r := newTestRemote("node1", &RemoteConfig{
+		In: []*Rule{
+			{Filter: "edge1/cmd/#", LocalPrefix: "cmd/", RemotePrefix: "edge1/cmd/", MaxQoS: packets.Qos1},
+		},
+	})
+	pub := make(testPublisher, 10)
+	rt := &testRetained{}
+	r.publisher = pub
+	r.retained = rt
+
+	r.deliver(&gmqtt.Message{
+		QoS:                    packets.Qos2,
+		PacketID:               1,
+		Retained:               true,
+		Topic:                  "edge1/cmd/reboot",
+		Payload:                []byte("now"),
+		SubscriptionIdentifier: []uint32{1},
+	})
+	a.Len(pub, 1)
+	m := <-pub
+	a.Equal("cmd/reboot", m.Topic)
+	a.Equal(packets.Qos1, m.QoS)
+	a.EqualValues(0, m.PacketID)
+	a.Nil(m.SubscriptionIdentifier)
+	a.Len(rt.added, 1)
+
+	// retained message with empty payload removes the retained message.
+	r.deliver(&gmqtt.Message{
+		Retained: true,
+		Topic:    "edge1/cmd/reboot",
+	})
+	a.Len(pub, 1)
+	<-pub
+	a.Equal([]string{"cmd/reboot"}, rt.removed)
+
+	// loop prevention
+	r.deliver(&gmqtt.Message{
+		Topic:          "edge1/cmd/reboot",
+		UserProperties: []packets.UserProperty{{K: []byte(LoopPropertyKey), V: []byte("node1")}},
+	})
+	a.Len(pub, 0)
+}
+
+func TestRemote_stop(t *testing.T) {
+	log = zap.NewNop()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	// the remote can be stopped while it is retrying to connect.
+	r := newTestRemote("node1", &RemoteConfig{Address: addr})
+	r.start(make(testPublisher), &testRetained{})
+	time.Sleep(100 * time.Millisecond)
+	done := make(chan struct{})
+	go func() {
+		r.stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("remote not stopped")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	a := assert.New(t)
+	b := &backoff{initial: time.Second, max: 4 * time.Second}
+	for _, max := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		d := b.next()
+		a.True(d >= max/2 && d <= max, d)
+	}
+}
+
+func startBroker(t *testing.T, plugins ...server.Plugin) (string, func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := server.New(
+		server.WithConfig(config.DefaultConfig()),
+		server.WithTCPListener(ln),
+		server.WithPlugin(plugins...),
+	)
+	if err := srv.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return ln.Addr().String(), func() {
+		_ = srv.Stop(context.Background())
+	}
+}
+
+// waitMessage publishes the message through the client repeatedly until the message is received.
+func waitMessage(t *testing.T, client *remote, msg *gmqtt.Message, recv testPublisher) *gmqtt.Message {
+	timeout := time.After(5 * time.Second)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			client.forward(msg)
+		case m := <-recv:
+			return m
+		case <-timeout:
+			t.Fatal("timeout")
+		}
+	}
+}
+
+func TestBridge(t *testing.T) {
+	a := assert.New(t)
+	centralAddr, stopCentral := startBroker(t)
+	defer stopCentral()
+
+	cfg := config.DefaultConfig()
+	remoteCfg := DefaultRemoteConfig
+	remoteCfg.Name = "central"
+	remoteCfg.Address = centralAddr
+	remoteCfg.In = []*Rule{
+		{Filter: "edge1/cmd/#", LocalPrefix: "cmd/", RemotePrefix: "edge1/cmd/", MaxQoS: packets.Qos1},
+	}
+	remoteCfg.Out = []*Rule{
+		{Filter: "sensors/#", LocalPrefix: "sensors/", RemotePrefix: "edge1/sensors/", MaxQoS: packets.Qos1},
+	}
+	cfg.Plugins[Name] = &Config{
+		NodeName: "edge1",
+		Remotes:  []*RemoteConfig{&remoteCfg},
+	}
+	b, err := New(cfg)
+	a.Nil(err)
+	edgeAddr, stopEdge := startBroker(t, b)
+	defer stopEdge()
+
+	// clients that connected to the edge and the central broker.
+	newClient := func(addr string, in, out string) (*remote, testPublisher) {
+		r := newTestRemote("tester-"+addr, &RemoteConfig{
+			Address: addr,
+			In:      []*Rule{{Filter: in, MaxQoS: packets.Qos1}},
+			Out:     []*Rule{{Filter: out, MaxQoS: packets.Qos1}},
+		})
+		r.clientID = "tester-" + addr
+		recv := make(testPublisher, 100)
+		r.start(recv, &testRetained{})
+		return r, recv
+	}
+	edgeClient, edgeRecv := newClient(edgeAddr, "cmd/#", "sensors/#")
+	defer edgeClient.stop()
+	centralClient, centralRecv := newClient(centralAddr, "edge1/sensors/#", "edge1/cmd/#")
+	defer centralClient.stop()
+
+	m := waitMessage(t, edgeClient, &gmqtt.Message{
+		QoS:     packets.Qos1,
+		Topic:   "sensors/temp",
+		Payload: []byte("25"),
+	}, centralRecv)
+	a.Equal("edge1/sensors/temp", m.Topic)
+	a.Equal([]byte("25"), m.Payload)
+	a.True(hasLooped(m, "edge1"))
+
+	m = waitMessage(t, centralClient, &gmqtt.Message{
+		QoS:     packets.Qos1,
+		Topic:   "edge1/cmd/reboot",
+		Payload: []byte("now"),
+	}, edgeRecv)
+	a.Equal("cmd/reboot", m.Topic)
+	a.Equal([]byte("now"), m.Payload)
+}
@@ -0,0 +1,196 @@
+package bridge
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+// Config is the configuration for the bridge plugin.
+type Config struct {
+	// NodeName identifies this broker in the loop prevention user property.
+	// Default to the hostname.
+	NodeName string `yaml:"node_name"`
+	// Remotes is the list of remote brokers to bridge with.
+	Remotes []*RemoteConfig `yaml:"remotes"`
+}
+
+// RemoteConfig is the configuration of a single remote broker.
+type RemoteConfig struct {
+	// Name is the unique name of the remote.
+	Name string `yaml:"name"`
+	// Address is the tcp address of the remote broker, host:port.
+	Address string `yaml:"address"`
+	// ProtocolVersion is the MQTT protocol version used to connect to the remote. Possible values: 4 (v3.1.1), 5 (v5).
+	// Loop prevention is only available in v5, so the v3.1.1 remotes can only have either in or out rules.
+	ProtocolVersion packets.Version `yaml:"protocol_version"`
+	// ClientID is the client identifier used to connect to the remote.
+	// Default to {node_name}-bridge-{name}.
+	ClientID   string `yaml:"client_id"`
+	Username   string `yaml:"username"`
+	Password   string `yaml:"password"`
+	KeepAlive  uint16 `yaml:"keep_alive"`
+	CleanStart bool   `yaml:"clean_start"`
+	// SessionExpiry is the session expiry interval in v5.
+	SessionExpiry time.Duration `yaml:"session_expiry"`
+	// ConnectTimeout is the timeout of dialing, waiting for CONNACK and writing packets.
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+	TLS            *TLSConfig    `yaml:"tls"`
+	// InitialBackoff is the delay before the first reconnect attempt.
+	// The delay is doubled for every failed attempt until it reaches MaxBackoff.
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	// QueueSize is the size of the outbound message queue.
+	// Messages will be dropped if the queue is full.
+	QueueSize int `yaml:"queue_size"`
+	// MaxInflight is the maximum number of QoS 1 and QoS 2 messages that are waiting for acknowledgement.
+	// It should not exceed the receive maximum of the remote broker.
+	MaxInflight uint16 `yaml:"max_inflight"`
+	// In is the rules of the messages to be received from the remote.
+	In []*Rule `yaml:"in"`
+	// Out is the rules of the messages to be forwarded to the remote.
+	Out []*Rule `yaml:"out"`
+}
+
+// TLSConfig is the tls configuration for connecting to the remote.
+type TLSConfig struct {
+	// CAFile is the CA certificates used to verify the remote, use the system pool if empty.
+	CAFile string `yaml:"ca_file"`
+	// CertFile and KeyFile is the client certificate, optional.
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	ServerName         string `yaml:"server_name"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// Rule defines which messages are bridged and how their topics are rewritten.
+// For in rules, Filter is subscribed on the remote broker and the RemotePrefix of the received topic is replaced with LocalPrefix.
+// For out rules, Filter is matched against local topics and the LocalPrefix of the topic is replaced with RemotePrefix.
+type Rule struct {
+	Filter       string `yaml:"filter"`
+	LocalPrefix  string `yaml:"local_prefix"`
+	RemotePrefix string `yaml:"remote_prefix"`
+	// MaxQoS caps the QoS of the bridged messages.
+	MaxQoS uint8 `yaml:"max_qos"`
+}
+
+// Validate validates the configuration, and return an error if it is invalid.
+func (c *Config) Validate() error {
+	names := make(map[string]struct{})
+	for _, v := range c.Remotes {
+		if _, ok := names[v.Name]; ok {
+			return fmt.Errorf("duplicated remote name: %s", v.Name)
+		}
+		names[v.Name] = struct{}{}
+		if err := v.Validate(); err != nil {
+			return fmt.Errorf("invalid remote %s: %s", v.Name, err)
+		}
+	}
+	return nil
+}
+
+// Validate validates the remote configuration.
+func (r *RemoteConfig) Validate() error {
+	if r.Name == "" {
+		return errors.New("empty name")
+	}
+	if _, _, err := net.SplitHostPort(r.Address); err != nil {
+		return errors.New("invalid address")
+	}
+	if r.ProtocolVersion != packets.Version311 && r.ProtocolVersion != packets.Version5 {
+		return fmt.Errorf("invalid protocol_version: %d", r.ProtocolVersion)
+	}
+	// v3 has neither the No Local option nor user properties,
+	// the forwarded messages would be received again by the in rules and forwarded back and forth.
+	if r.ProtocolVersion == packets.Version311 && len(r.In) != 0 && len(r.Out) != 0 {
+		return errors.New("the remote with protocol_version 4 can not have both in and out rules, use protocol_version 5 for bidirectional bridging")
+	}
+	if r.TLS != nil && (r.TLS.CertFile == "") != (r.TLS.KeyFile == "") {
+		return errors.New("cert_file and key_file must be set together")
+	}
+	if r.InitialBackoff <= 0 || r.MaxBackoff < r.InitialBackoff {
+		return errors.New("invalid initial_backoff or max_backoff")
+	}
+	if r.QueueSize <= 0 {
+		return errors.New("queue_size must be greater than 0")
+	}
+	if r.MaxInflight == 0 {
+		return errors.New("max_inflight cannot be 0")
+	}
+	if r.ConnectTimeout <= 0 {
+		return errors.New("connect_timeout must be greater than 0")
+	}
+	for _, v := range r.In {
+		if err := v.validate(); err != nil {
+			return err
+		}
+	}
+	for _, v := range r.Out {
+		if err := v.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Rule) validate() error {
+	if !packets.ValidTopicFilter(true, []byte(r.Filter)) {
+		return fmt.Errorf("invalid filter: %s", r.Filter)
+	}
+	if r.MaxQoS > packets.Qos2 {
+		return fmt.Errorf("invalid max_qos: %d", r.MaxQoS)
+	}
+	return nil
+}
+
+// DefaultConfig is the default configuration.
+var DefaultConfig = Config{}
+
+// DefaultRemoteConfig is the default configuration of a remote.
+var DefaultRemoteConfig = RemoteConfig{
+	ProtocolVersion: packets.Version5,
+	KeepAlive:       60,
+	CleanStart:      true,
+	ConnectTimeout:  10 * time.Second,
+	InitialBackoff:  time.Second,
+	MaxBackoff:      time.Minute,
+	QueueSize:       1000,
+	MaxInflight:     100,
+}
+
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type cfg Config
+	var v = &struct {
+		Bridge cfg `yaml:"bridge"`
+	}{
+		Bridge: cfg(DefaultConfig),
+	}
+	if err := unmarshal(v); err != nil {
+		return err
+	}
+	*c = Config(v.Bridge)
+	return nil
+}
+
+func (r *RemoteConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type cfg RemoteConfig
+	v := cfg(DefaultRemoteConfig)
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	*r = RemoteConfig(v)
+	return nil
+}
+
+func (r *Rule) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type cfg Rule
+	v := cfg{MaxQoS: packets.Qos2}
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	*r = Rule(v)
+	return nil
+}
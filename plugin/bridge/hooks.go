@@ -0,0 +1,29 @@
+package bridge
+
+import (
+	"context"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func (b *Bridge) HookWrapper() server.HookWrapper {
+	return server.HookWrapper{
+		OnMsgDispatchedWrapper: b.OnMsgDispatchedWrapper,
+	}
+}
+
+// OnMsgDispatchedWrapper forwards the local messages that match the out rules to the remotes.
+// The hook is called when the message is delivered, so the delayed messages are forwarded when they are due.
+// The messages published by the plugins, including the messages received from the remotes, are not forwarded.
+func (b *Bridge) OnMsgDispatchedWrapper(pre server.OnMsgDispatched) server.OnMsgDispatched {
+	return func(ctx context.Context, srcClientID string, msg *gmqtt.Message) {
+		pre(ctx, srcClientID, msg)
+		if srcClientID == "" {
+			return
+		}
+		for _, r := range b.remotes {
+			r.forward(msg)
+		}
+	}
+}
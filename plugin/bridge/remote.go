@@ -0,0 +1,352 @@
+package bridge
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/pkg/client"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+// remote maintains the connection to a remote broker.
+// The connection is managed by the client, which reconnects automatically after the first connection succeeded.
+// The inflight messages survive reconnects and will be resent after the connection is re-established.
+type remote struct {
+	cfg       *RemoteConfig
+	nodeName  string
+	clientID  string
+	tlsConfig *tls.Config
+
+	publisher server.Publisher
+	retained  server.RetainedService
+	client    *client.Client
+
+	queue chan *gmqtt.Message
+	// slots limits the number of inflight messages, one slot for each inflight message.
+	slots  chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	// maxQoS is the maximum QoS that the remote supports.
+	maxQoS uint32
+}
+
+func newRemote(nodeName string, cfg *RemoteConfig) (*remote, error) {
+	r := &remote{
+		cfg:      cfg,
+		nodeName: nodeName,
+		clientID: cfg.ClientID,
+		queue:    make(chan *gmqtt.Message, cfg.QueueSize),
+		slots:    make(chan struct{}, cfg.MaxInflight),
+		maxQoS:   uint32(packets.Qos2),
+	}
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	if r.clientID == "" {
+		r.clientID = fmt.Sprintf("%s-bridge-%s", nodeName, cfg.Name)
+	}
+	if cfg.TLS != nil {
+		var err error
+		r.tlsConfig, err = newTLSConfig(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func newTLSConfig(cfg *TLSConfig) (*tls.Config, error) {
+	c := &tls.Config{
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+	if cfg.CAFile != "" {
+		b, err := ioutil.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		c.RootCAs = x509.NewCertPool()
+		if !c.RootCAs.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no valid certificates found in %s", cfg.CAFile)
+		}
+	}
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+	return c, nil
+}
+
+func (r *remote) clientOptions() []client.Options {
+	cfg := r.cfg
+	opts := []client.Options{
+		client.WithAddress(cfg.Address),
+		client.WithVersion(cfg.ProtocolVersion),
+		client.WithClientID(r.clientID),
+		client.WithCleanStart(cfg.CleanStart),
+		client.WithKeepAlive(cfg.KeepAlive),
+		client.WithConnectTimeout(cfg.ConnectTimeout),
+		client.WithWriteTimeout(cfg.ConnectTimeout),
+		client.WithAutoReconnect(cfg.InitialBackoff, cfg.MaxBackoff),
+		client.WithMessageHandler(func(c *client.Client, msg *gmqtt.Message) {
+			r.deliver(msg)
+		}),
+		client.WithConnectHandler(r.onConnected),
+		client.WithConnectionLostHandler(func(c *client.Client, err error) {
+			log.Warn("bridge connection lost, reconnecting",
+				zap.String("remote", cfg.Name),
+				zap.Error(err))
+		}),
+	}
+	if r.tlsConfig != nil {
+		opts = append(opts, client.WithTLSConfig(r.tlsConfig))
+	}
+	if cfg.Username != "" || cfg.Password != "" {
+		var password []byte
+		if cfg.Password != "" {
+			password = []byte(cfg.Password)
+		}
+		opts = append(opts, client.WithUsernamePassword(cfg.Username, password))
+	}
+	if cfg.ProtocolVersion == packets.Version5 && cfg.SessionExpiry != 0 {
+		opts = append(opts, client.WithSessionExpiry(uint32(cfg.SessionExpiry/time.Second)))
+	}
+	return opts
+}
+
+func (r *remote) start(publisher server.Publisher, retained server.RetainedService) {
+	r.publisher = publisher
+	r.retained = retained
+	r.client = client.New(r.clientOptions()...)
+	r.wg.Add(1)
+	go r.run()
+}
+
+func (r *remote) stop() {
+	r.cancel()
+	_ = r.client.Disconnect()
+	r.wg.Wait()
+}
+
+// forward puts the local message into the outbound queue if it matches any out rules.
+func (r *remote) forward(msg *gmqtt.Message) {
+	if hasLooped(msg, r.nodeName) {
+		return
+	}
+	rule := matchRule(r.cfg.Out, msg.Topic)
+	if rule == nil {
+		return
+	}
+	m := msg.Copy()
+	m.Topic = rewriteTopic(m.Topic, rule.LocalPrefix, rule.RemotePrefix)
+	if m.Topic == "" {
+		return
+	}
+	if m.QoS > rule.MaxQoS {
+		m.QoS = rule.MaxQoS
+	}
+	m.Dup = false
+	m.PacketID = 0
+	m.SubscriptionIdentifier = nil
+	if r.cfg.ProtocolVersion == packets.Version5 {
+		m.UserProperties = append(m.UserProperties, packets.UserProperty{
+			K: []byte(LoopPropertyKey),
+			V: []byte(r.nodeName),
+		})
+	}
+	select {
+	case r.queue <- m:
+	default:
+		log.Warn("message dropped, the outbound queue is full",
+			zap.String("remote", r.cfg.Name),
+			zap.String("topic", m.Topic))
+	}
+}
+
+// deliver injects the message received from the remote into the local broker.
+func (r *remote) deliver(msg *gmqtt.Message) {
+	if hasLooped(msg, r.nodeName) {
+		return
+	}
+	rule := matchRule(r.cfg.In, msg.Topic)
+	if rule == nil {
+		return
+	}
+	msg.Topic = rewriteTopic(msg.Topic, rule.RemotePrefix, rule.LocalPrefix)
+	if msg.Topic == "" {
+		return
+	}
+	if msg.QoS > rule.MaxQoS {
+		msg.QoS = rule.MaxQoS
+	}
+	msg.Dup = false
+	msg.PacketID = 0
+	msg.SubscriptionIdentifier = nil
+	if msg.Retained {
+		if len(msg.Payload) == 0 {
+			r.retained.Remove(msg.Topic)
+		} else {
+			r.retained.AddOrReplace(msg)
+		}
+	}
+	r.publisher.Publish(msg)
+}
+
+func (r *remote) run() {
+	defer r.wg.Done()
+	if !r.connect() {
+		return
+	}
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case msg := <-r.queue:
+			r.publish(msg)
+		}
+	}
+}
+
+// connect connects to the remote until it succeeds, and returns false if the remote is stopped.
+// The client reconnects by itself after the first connection succeeded.
+func (r *remote) connect() bool {
+	b := &backoff{initial: r.cfg.InitialBackoff, max: r.cfg.MaxBackoff}
+	for {
+		_, err := r.client.Connect(r.ctx)
+		if err == nil {
+			return true
+		}
+		d := b.next()
+		log.Warn("failed to connect the remote, retrying",
+			zap.String("remote", r.cfg.Name),
+			zap.Duration("backoff", d),
+			zap.Error(err))
+		t := time.NewTimer(d)
+		select {
+		case <-r.ctx.Done():
+			t.Stop()
+			return false
+		case <-t.C:
+		}
+	}
+}
+
+// onConnected subscribes the filters of the in rules after each connection.
+func (r *remote) onConnected(c *client.Client, connack *packets.Connack) {
+	maxQoS := packets.Qos2
+	if connack.Properties != nil && connack.Properties.MaximumQoS != nil {
+		maxQoS = *connack.Properties.MaximumQoS
+	}
+	atomic.StoreUint32(&r.maxQoS, uint32(maxQoS))
+	log.Info("bridge connected",
+		zap.String("remote", r.cfg.Name),
+		zap.String("address", r.cfg.Address),
+		zap.Bool("session_present", connack.SessionPresent))
+	if len(r.cfg.In) == 0 {
+		return
+	}
+	var topics []packets.Topic
+	for _, v := range r.cfg.In {
+		topics = append(topics, packets.Topic{
+			Name: v.Filter,
+			SubOptions: packets.SubOptions{
+				Qos: v.MaxQoS,
+				// Do not receive the messages forwarded by ourselves.
+				NoLocal:           true,
+				RetainAsPublished: true,
+			},
+		})
+	}
+	ctx, cancel := context.WithTimeout(r.ctx, r.cfg.ConnectTimeout)
+	defer cancel()
+	suback, err := c.Subscribe(ctx, topics...)
+	if err != nil {
+		log.Warn("failed to subscribe the remote",
+			zap.String("remote", r.cfg.Name),
+			zap.Error(err))
+		return
+	}
+	for k, v := range suback.Payload {
+		if v >= codes.UnspecifiedError && k < len(r.cfg.In) {
+			log.Warn("failed to subscribe the remote",
+				zap.String("remote", r.cfg.Name),
+				zap.String("filter", r.cfg.In[k].Filter),
+				zap.Uint8("code", v))
+		}
+	}
+}
+
+// publish sends the message to the remote, the QoS 1 and QoS 2 messages hold a slot until they are acknowledged.
+// The QoS 0 messages are dropped if the client is reconnecting.
+func (r *remote) publish(msg *gmqtt.Message) {
+	if maxQoS := uint8(atomic.LoadUint32(&r.maxQoS)); msg.QoS > maxQoS {
+		msg.QoS = maxQoS
+	}
+	if msg.QoS != packets.Qos0 {
+		select {
+		case <-r.ctx.Done():
+			return
+		case r.slots <- struct{}{}:
+		}
+	}
+	res, err := r.client.PublishAsync(r.ctx, msg)
+	if err != nil {
+		if msg.QoS != packets.Qos0 {
+			<-r.slots
+		}
+		log.Warn("failed to forward the message",
+			zap.String("remote", r.cfg.Name),
+			zap.String("topic", msg.Topic),
+			zap.Error(err))
+		return
+	}
+	if msg.QoS == packets.Qos0 {
+		return
+	}
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		<-res.Done()
+		<-r.slots
+		if err := res.Err(); err != nil && err != client.ErrClosed {
+			log.Warn("failed to forward the message",
+				zap.String("remote", r.cfg.Name),
+				zap.String("topic", msg.Topic),
+				zap.Error(err))
+		}
+	}()
+}
+
+// backoff calculates the reconnect delay with exponential backoff and jitter.
+type backoff struct {
+	initial time.Duration
+	max     time.Duration
+	cur     time.Duration
+}
+
+func (b *backoff) next() time.Duration {
+	if b.cur == 0 {
+		b.cur = b.initial
+	} else {
+		b.cur *= 2
+		if b.cur > b.max {
+			b.cur = b.max
+		}
+	}
+	half := b.cur / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
@@ -0,0 +1,103 @@
+package cluster
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// secretMetadataKey is the metadata key which carries the cluster secret.
+const secretMetadataKey = "gmqtt-cluster-secret"
+
+// secretCredentials attaches the cluster secret to every rpc call.
+type secretCredentials string
+
+func (s secretCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{secretMetadataKey: string(s)}, nil
+}
+
+func (s secretCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// checkSecret returns an Unauthenticated error if the rpc call does not carry the secret.
+func checkSecret(ctx context.Context, secret string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get(secretMetadataKey) {
+		if subtle.ConstantTimeCompare([]byte(v), []byte(secret)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid cluster secret")
+}
+
+func loadTLSConfig(cfg *TLSConfig) (*tls.Config, error) {
+	b, err := ioutil.ReadFile(cfg.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no valid certificates found in %s", cfg.CAFile)
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ServerName:   cfg.ServerName,
+	}, nil
+}
+
+// serverOptions returns the options of the cluster gRPC server which authenticate the other nodes.
+func (c *Config) serverOptions() ([]grpc.ServerOption, error) {
+	var opts []grpc.ServerOption
+	if c.TLS != nil {
+		tc, err := loadTLSConfig(c.TLS)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tc)))
+	}
+	if c.Secret != "" {
+		secret := c.Secret
+		opts = append(opts, grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := checkSecret(ctx, secret); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}))
+	}
+	return opts, nil
+}
+
+// dialOptions returns the options used to connect to the other nodes.
+func (c *Config) dialOptions() ([]grpc.DialOption, error) {
+	var opts []grpc.DialOption
+	if c.TLS != nil {
+		tc, err := loadTLSConfig(c.TLS)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tc)))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
+	}
+	if c.Secret != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(secretCredentials(c.Secret)))
+	}
+	return opts, nil
+}
@@ -0,0 +1,365 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/persistence/subscription"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+var _ server.Plugin = (*Cluster)(nil)
+
+const Name = "cluster"
+
+const (
+	// maxFailures is the number of consecutive sync failures after which a discovered peer will be removed.
+	// The static peers will never be removed.
+	maxFailures = 10
+	// fullRefreshRounds is the number of gossip rounds after which the local subscription table will be reloaded
+	// even if no subscription hooks have been called.
+	// It is used to catch up the subscriptions that are changed by the SubscriptionService.
+	fullRefreshRounds = 10
+)
+
+func init() {
+	server.RegisterPlugin(Name, New)
+	config.RegisterDefaultPluginConfig(Name, &DefaultConfig)
+}
+
+func New(config config.Config) (server.Plugin, error) {
+	cfg := config.Plugins[Name].(*Config)
+	if !cfg.authenticated() && !cfg.Insecure {
+		return nil, errors.New("cluster: either secret or tls must be set, set insecure to true to disable the authentication")
+	}
+	if err := cfg.ValidateConfig(config); err != nil {
+		return nil, err
+	}
+	serverOpts, err := cfg.serverOptions()
+	if err != nil {
+		return nil, fmt.Errorf("cluster: %s", err)
+	}
+	dialOpts, err := cfg.dialOptions()
+	if err != nil {
+		return nil, fmt.Errorf("cluster: %s", err)
+	}
+	nodeName := cfg.NodeName
+	if nodeName == "" {
+		nodeName, err = os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("cluster: cannot get node name: %s", err)
+		}
+	}
+	return &Cluster{
+		config:      *cfg,
+		serverOpts:  serverOpts,
+		dialOpts:    dialOpts,
+		nodeName:    nodeName,
+		incarnation: time.Now().UnixNano(),
+		peers:       make(map[string]*peer),
+		trigger:     make(chan struct{}, 1),
+		closing:     make(chan struct{}),
+	}, nil
+}
+
+var log *zap.Logger
+
+// Cluster joins the broker into a cluster of brokers.
+// Each node gossips its subscription table to the other nodes,
+// and forwards the incoming messages to the nodes that have matched subscriptions.
+type Cluster struct {
+	config        Config
+	serverOpts    []grpc.ServerOption
+	dialOpts      []grpc.DialOption
+	nodeName      string
+	advertiseAddr string
+	// incarnation identifies the lifetime of the node, it is changed every time the node restarts.
+	incarnation int64
+	grpcServer  *grpc.Server
+
+	publisher     server.LocalPublisher
+	retained      server.RetainedService
+	clientService server.ClientService
+	subStore      server.SubscriptionService
+
+	mu    sync.RWMutex
+	peers map[string]*peer
+
+	// stateMu guards the local subscription state.
+	stateMu sync.Mutex
+	filters []string
+	version uint64
+	dirty   bool
+
+	trigger chan struct{}
+	closing chan struct{}
+	wg      sync.WaitGroup
+}
+
+func (c *Cluster) mustEmbedUnimplementedClusterServer() {
+	return
+}
+
+func (c *Cluster) Load(service server.Server) error {
+	log = server.LoggerWithField(zap.String("plugin", Name))
+	lp, ok := service.Publisher().(server.LocalPublisher)
+	if !ok {
+		return errors.New("cluster: the publisher does not implement server.LocalPublisher")
+	}
+	c.publisher = lp
+	c.retained = service.RetainedService()
+	c.clientService = service.ClientService()
+	c.subStore = service.SubscriptionService()
+
+	l, err := net.Listen("tcp", c.config.ListenAddr)
+	if err != nil {
+		return err
+	}
+	c.advertiseAddr = c.config.AdvertiseAddr
+	if c.advertiseAddr == "" {
+		c.advertiseAddr = l.Addr().String()
+	}
+	c.grpcServer = grpc.NewServer(c.serverOpts...)
+	RegisterClusterServer(c.grpcServer, c)
+	go func() {
+		err := c.grpcServer.Serve(l)
+		if err != nil {
+			panic(err)
+		}
+	}()
+	c.mu.Lock()
+	for _, v := range c.config.Peers {
+		if p := c.addPeerLocked(v); p != nil {
+			p.static = true
+		}
+	}
+	c.mu.Unlock()
+	c.refreshLocalState(true)
+	c.wg.Add(1)
+	go c.gossipLoop()
+	return nil
+}
+
+func (c *Cluster) Unload() error {
+	close(c.closing)
+	c.wg.Wait()
+	c.mu.Lock()
+	for k, p := range c.peers {
+		p.close()
+		delete(c.peers, k)
+	}
+	c.mu.Unlock()
+	c.grpcServer.Stop()
+	return nil
+}
+
+func (c *Cluster) Name() string {
+	return Name
+}
+
+// addPeerLocked adds the peer with the given address if it does not exist, must call under c.mu.Lock.
+// It returns nil if the address is the node itself.
+func (c *Cluster) addPeerLocked(addr string) *peer {
+	if addr == c.advertiseAddr {
+		return nil
+	}
+	if p, ok := c.peers[addr]; ok {
+		return p
+	}
+	p, err := newPeer(addr, c.nodeName, c.config.ForwardQueueSize, c.config.RPCTimeout, c.dialOpts)
+	if err != nil {
+		log.Error("failed to create peer", zap.String("addr", addr), zap.Error(err))
+		return nil
+	}
+	c.peers[addr] = p
+	log.Info("peer added", zap.String("addr", addr))
+	return p
+}
+
+func (c *Cluster) removePeer(p *peer) {
+	c.mu.Lock()
+	if c.peers[p.addr] != p {
+		c.mu.Unlock()
+		return
+	}
+	delete(c.peers, p.addr)
+	c.mu.Unlock()
+	p.close()
+	log.Info("peer removed", zap.String("addr", p.addr))
+}
+
+// addMembers adds the unknown members as peers.
+func (c *Cluster) addMembers(members []*Member) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, v := range members {
+		if v.Name == c.nodeName || v.Addr == "" {
+			continue
+		}
+		c.addPeerLocked(v.Addr)
+	}
+}
+
+// members returns the known members, including the node itself.
+func (c *Cluster) members() []*Member {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	ms := []*Member{{Name: c.nodeName, Addr: c.advertiseAddr}}
+	for _, p := range c.peers {
+		if name := p.name(); name != "" {
+			ms = append(ms, &Member{Name: name, Addr: p.addr})
+		}
+	}
+	return ms
+}
+
+func (c *Cluster) peerList() []*peer {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	ps := make([]*peer, 0, len(c.peers))
+	for _, p := range c.peers {
+		ps = append(ps, p)
+	}
+	return ps
+}
+
+// notify marks the local subscription table as changed and triggers a gossip round.
+func (c *Cluster) notify() {
+	c.stateMu.Lock()
+	c.dirty = true
+	c.stateMu.Unlock()
+	select {
+	case c.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// refreshLocalState reloads the topic filters of the local subscriptions if they have been changed,
+// and increases the version if the filters are different from the previous ones.
+func (c *Cluster) refreshLocalState(force bool) {
+	c.stateMu.Lock()
+	if !c.dirty && !force {
+		c.stateMu.Unlock()
+		return
+	}
+	c.dirty = false
+	c.stateMu.Unlock()
+
+	set := make(map[string]struct{})
+	c.subStore.Iterate(func(clientID string, sub *gmqtt.Subscription) bool {
+		set[sub.TopicFilter] = struct{}{}
+		return true
+	}, subscription.IterationOptions{
+		Type: subscription.TypeAll,
+	})
+	filters := make([]string, 0, len(set))
+	for k := range set {
+		filters = append(filters, k)
+	}
+	sort.Strings(filters)
+
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	if equalStrings(filters, c.filters) {
+		return
+	}
+	c.filters = filters
+	c.version++
+}
+
+// localState returns the state of the node itself.
+func (c *Cluster) localState() *NodeState {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	return &NodeState{
+		Member: &Member{
+			Name: c.nodeName,
+			Addr: c.advertiseAddr,
+		},
+		Incarnation: c.incarnation,
+		Version:     c.version,
+		Filters:     c.filters,
+	}
+}
+
+func (c *Cluster) gossipLoop() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(c.config.GossipInterval)
+	defer ticker.Stop()
+	var rounds int
+	for {
+		select {
+		case <-c.closing:
+			return
+		case <-ticker.C:
+			rounds++
+		case <-c.trigger:
+		}
+		c.refreshLocalState(rounds >= fullRefreshRounds)
+		if rounds >= fullRefreshRounds {
+			rounds = 0
+		}
+		c.gossip()
+	}
+}
+
+// gossip exchanges the node state with all peers.
+func (c *Cluster) gossip() {
+	req := &SyncRequest{
+		State:   c.localState(),
+		Members: c.members(),
+	}
+	var wg sync.WaitGroup
+	for _, p := range c.peerList() {
+		wg.Add(1)
+		go func(p *peer) {
+			defer wg.Done()
+			c.syncPeer(p, req)
+		}(p)
+	}
+	wg.Wait()
+}
+
+func (c *Cluster) syncPeer(p *peer, req *SyncRequest) {
+	resp, err := p.sync(req)
+	if err != nil {
+		if p.fail() >= maxFailures && !p.static {
+			c.removePeer(p)
+			return
+		}
+		log.Debug("failed to sync with peer", zap.String("addr", p.addr), zap.Error(err))
+		return
+	}
+	if resp.State == nil || resp.State.Member == nil {
+		return
+	}
+	if resp.State.Member.Name == c.nodeName {
+		// The address points to the node itself.
+		c.removePeer(p)
+		return
+	}
+	p.update(resp.State)
+	c.addMembers(resp.Members)
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if a[k] != b[k] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,530 @@
+package cluster
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/config"
+	_ "github.com/DrmagicE/gmqtt/persistence"
+	"github.com/DrmagicE/gmqtt/persistence/subscription"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+	_ "github.com/DrmagicE/gmqtt/topicalias/fifo"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	a := assert.New(t)
+	c := DefaultConfig
+	a.Nil(c.Validate())
+
+	c.Peers = []string{"127.0.0.1"}
+	a.Error(c.Validate())
+
+	c = DefaultConfig
+	c.GossipInterval = 0
+	a.Error(c.Validate())
+
+	c = DefaultConfig
+	c.TLS = &TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem"}
+	a.Error(c.Validate())
+}
+
+func TestNew(t *testing.T) {
+	a := assert.New(t)
+	cfg := config.DefaultConfig()
+	c := DefaultConfig
+	cfg.Plugins[Name] = &c
+	cfg.MQTT.SharedSubAvailable = false
+	// the authentication is required by default.
+	_, err := New(cfg)
+	a.Error(err)
+
+	c.Insecure = true
+	_, err = New(cfg)
+	a.Nil(err)
+
+	c.Insecure = false
+	c.Secret = "secret"
+	_, err = New(cfg)
+	a.Nil(err)
+
+	cfg.MQTT.SharedSubAvailable = true
+	_, err = New(cfg)
+	a.Error(err)
+}
+
+func TestConfig_ValidateConfig(t *testing.T) {
+	a := assert.New(t)
+	cfg := config.DefaultConfig()
+	a.True(cfg.MQTT.SharedSubAvailable)
+	// not checked if the plugin is not enabled.
+	a.Nil(cfg.Validate())
+
+	cfg.PluginOrder = []string{Name}
+	a.EqualError(cfg.Validate(), "cluster: shared subscriptions are not supported, shared_subscription_available must be false")
+	cfg.MQTT.SharedSubAvailable = false
+	a.Nil(cfg.Validate())
+}
+
+func TestRoutes_match(t *testing.T) {
+	a := assert.New(t)
+	r := newRoutes([]string{"a/b", "c/+", "d/#"})
+	a.True(r.match("a/b"))
+	a.True(r.match("c/d"))
+	a.True(r.match("d/e/f"))
+	a.False(r.match("a/c"))
+	a.False(r.match("c/d/e"))
+
+	var nilRoutes *routes
+	a.False(nilRoutes.match("a/b"))
+}
+
+func TestPeer_update(t *testing.T) {
+	a := assert.New(t)
+	p := &peer{}
+	p.update(&NodeState{Member: &Member{Name: "a"}, Incarnation: 1, Version: 2, Filters: []string{"a"}})
+	a.True(p.match("a"))
+	// older version is ignored
+	p.update(&NodeState{Member: &Member{Name: "a"}, Incarnation: 1, Version: 1, Filters: []string{"b"}})
+	a.True(p.match("a"))
+	a.False(p.match("b"))
+	// the node has restarted
+	p.update(&NodeState{Member: &Member{Name: "a"}, Incarnation: 2, Version: 1, Filters: []string{"b"}})
+	a.False(p.match("a"))
+	a.True(p.match("b"))
+
+	a.Equal(1, p.fail())
+	a.False(p.match("b"))
+	p.update(&NodeState{Member: &Member{Name: "a"}, Incarnation: 2, Version: 1, Filters: []string{"b"}})
+	a.True(p.match("b"))
+}
+
+func TestMessageProto(t *testing.T) {
+	a := assert.New(t)
+	msg := &gmqtt.Message{
+		QoS:             packets.Qos1,
+		Retained:        true,
+		Topic:           "a/b",
+		Payload:         []byte("payload"),
+		ContentType:     "text/plain",
+		CorrelationData: []byte("cd"),
+		MessageExpiry:   10,
+		PayloadFormat:   packets.PayloadFormatString,
+		ResponseTopic:   "resp",
+		UserProperties: []packets.UserProperty{
+			{K: []byte("k"), V: []byte("v")},
+		},
+	}
+	a.Equal(msg, messageFromProto(messageToProto(msg)))
+
+	sub := &gmqtt.Subscription{
+		ShareName:         "share",
+		TopicFilter:       "a/#",
+		ID:                1,
+		QoS:               packets.Qos2,
+		NoLocal:           true,
+		RetainAsPublished: true,
+		RetainHandling:    2,
+	}
+	a.Equal(sub, subscriptionFromProto(subscriptionToProto(sub)))
+}
+
+type testNode struct {
+	addr    string
+	cluster *Cluster
+	srv     server.Server
+}
+
+func startNode(t *testing.T, name string, peers ...string) *testNode {
+	return startNodeWithConfig(t, &Config{
+		NodeName:         name,
+		ListenAddr:       "127.0.0.1:0",
+		Peers:            peers,
+		GossipInterval:   100 * time.Millisecond,
+		RPCTimeout:       time.Second,
+		ForwardQueueSize: 100,
+		Secret:           "secret",
+	})
+}
+
+func startNodeWithConfig(t *testing.T, c *Config) *testNode {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.MQTT.SharedSubAvailable = false
+	cfg.Plugins[Name] = c
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := server.New(
+		server.WithConfig(cfg),
+		server.WithTCPListener(ln),
+		server.WithPlugin(p),
+	)
+	if err := srv.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return &testNode{
+		addr:    ln.Addr().String(),
+		cluster: p.(*Cluster),
+		srv:     srv,
+	}
+}
+
+func (n *testNode) stop() {
+	_ = n.srv.Stop(context.Background())
+}
+
+// waitFor polls the condition until it is true.
+func waitFor(t *testing.T, cond func() bool) {
+	timeout := time.After(5 * time.Second)
+	for !cond() {
+		select {
+		case <-timeout:
+			t.Fatal("timeout")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
+
+// hasRoute returns whether the node knows that the peer named peerName subscribes the topic.
+func (n *testNode) hasRoute(peerName, topic string) bool {
+	for _, p := range n.cluster.peerList() {
+		if p.name() == peerName && p.match(topic) {
+			return true
+		}
+	}
+	return false
+}
+
+type testClient struct {
+	t      *testing.T
+	nc     net.Conn
+	reader *packets.Reader
+	writer *packets.Writer
+}
+
+func connect(t *testing.T, addr, clientID string, cleanStart bool) *testClient {
+	nc, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &testClient{
+		t:      t,
+		nc:     nc,
+		reader: packets.NewReader(nc),
+		writer: packets.NewWriter(nc),
+	}
+	c.reader.SetVersion(packets.Version5)
+	expiry := uint32(100)
+	c.write(&packets.Connect{
+		Version:       packets.Version5,
+		ProtocolName:  []byte("MQTT"),
+		ProtocolLevel: packets.Version5,
+		CleanStart:    cleanStart,
+		ClientID:      []byte(clientID),
+		Properties: &packets.Properties{
+			SessionExpiryInterval: &expiry,
+		},
+	})
+	connack, ok := c.read().(*packets.Connack)
+	if !ok || connack.Code != codes.Success {
+		t.Fatal("connect failed")
+	}
+	return c
+}
+
+func (c *testClient) write(p packets.Packet) {
+	if err := c.writer.WriteAndFlush(p); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *testClient) read() packets.Packet {
+	_ = c.nc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	p, err := c.reader.ReadPacket()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return p
+}
+
+func (c *testClient) subscribe(filter string) {
+	c.write(&packets.Subscribe{
+		Version:    packets.Version5,
+		PacketID:   1,
+		Topics:     []packets.Topic{{Name: filter}},
+		Properties: &packets.Properties{},
+	})
+	if _, ok := c.read().(*packets.Suback); !ok {
+		c.t.Fatal("subscribe failed")
+	}
+}
+
+func (c *testClient) publish(topic, payload string) {
+	c.write(&packets.Publish{
+		Version:    packets.Version5,
+		TopicName:  []byte(topic),
+		Payload:    []byte(payload),
+		Properties: &packets.Properties{},
+	})
+}
+
+func (c *testClient) close() {
+	_ = c.nc.Close()
+}
+
+func TestCluster(t *testing.T) {
+	a := assert.New(t)
+	n1 := startNode(t, "node1")
+	defer n1.stop()
+	n2 := startNode(t, "node2", n1.cluster.advertiseAddr)
+	defer n2.stop()
+	n3 := startNode(t, "node3", n1.cluster.advertiseAddr)
+	defer n3.stop()
+
+	// node2 and node3 are discovered by each other through node1.
+	waitFor(t, func() bool {
+		return len(n2.cluster.peerList()) == 2 && len(n3.cluster.peerList()) == 2
+	})
+
+	sub := connect(t, n2.addr, "sub", true)
+	defer sub.close()
+	sub.subscribe("a/#")
+	waitFor(t, func() bool {
+		return n1.hasRoute("node2", "a/b") && n3.hasRoute("node2", "a/b")
+	})
+	a.False(n1.hasRoute("node3", "a/b"))
+
+	pub := connect(t, n1.addr, "pub", true)
+	defer pub.close()
+	pub.publish("a/b", "hello")
+	p, ok := sub.read().(*packets.Publish)
+	a.True(ok)
+	a.Equal("a/b", string(p.TopicName))
+	a.Equal("hello", string(p.Payload))
+
+	sub.write(&packets.Unsubscribe{
+		Version:    packets.Version5,
+		PacketID:   2,
+		Topics:     []string{"a/#"},
+		Properties: &packets.Properties{},
+	})
+	waitFor(t, func() bool {
+		return !n1.hasRoute("node2", "a/b")
+	})
+}
+
+func TestCluster_takeover(t *testing.T) {
+	a := assert.New(t)
+	n1 := startNode(t, "node1")
+	defer n1.stop()
+	n2 := startNode(t, "node2", n1.cluster.advertiseAddr)
+	defer n2.stop()
+	waitFor(t, func() bool {
+		return len(n1.cluster.peerList()) == 1
+	})
+
+	c1 := connect(t, n1.addr, "client", false)
+	defer c1.close()
+	c1.subscribe("a/#")
+
+	// reconnect to node2 with the same client id.
+	c2 := connect(t, n2.addr, "client", false)
+	defer c2.close()
+	dis, ok := c1.read().(*packets.Disconnect)
+	a.True(ok)
+	a.Equal(codes.SessionTakenOver, dis.Code)
+
+	var subs []*gmqtt.Subscription
+	waitFor(t, func() bool {
+		subs = subs[:0]
+		n2.cluster.subStore.Iterate(func(clientID string, sub *gmqtt.Subscription) bool {
+			subs = append(subs, sub)
+			return true
+		}, subscription.IterationOptions{
+			Type:     subscription.TypeAll,
+			ClientID: "client",
+		})
+		return len(subs) == 1
+	})
+	a.Equal("a/#", subs[0].TopicFilter)
+
+	waitFor(t, func() bool {
+		sess, _ := n1.cluster.clientService.GetSession("client")
+		return sess == nil && n1.hasRoute("node2", "a/b")
+	})
+	pub := connect(t, n1.addr, "pub", true)
+	defer pub.close()
+	pub.publish("a/b", "hello")
+	p, ok := c2.read().(*packets.Publish)
+	a.True(ok)
+	a.Equal("hello", string(p.Payload))
+}
+
+func TestCluster_secret(t *testing.T) {
+	a := assert.New(t)
+	n1 := startNode(t, "node1")
+	defer n1.stop()
+
+	for _, secret := range []string{"", "wrong"} {
+		opts := []grpc.DialOption{grpc.WithInsecure()}
+		if secret != "" {
+			opts = append(opts, grpc.WithPerRPCCredentials(secretCredentials(secret)))
+		}
+		conn, err := grpc.Dial(n1.cluster.advertiseAddr, opts...)
+		a.Nil(err)
+		_, err = NewClusterClient(conn).Forward(context.Background(), &ForwardRequest{
+			From:     "evil",
+			Messages: []*Message{{Topic: "a/b"}},
+		})
+		a.Equal(grpccodes.Unauthenticated, status.Code(err))
+		conn.Close()
+	}
+}
+
+func TestCluster_publisher(t *testing.T) {
+	a := assert.New(t)
+	n1 := startNode(t, "node1")
+	defer n1.stop()
+	n2 := startNode(t, "node2", n1.cluster.advertiseAddr)
+	defer n2.stop()
+
+	sub := connect(t, n2.addr, "sub", true)
+	defer sub.close()
+	sub.subscribe("a/#")
+	waitFor(t, func() bool {
+		return n1.hasRoute("node2", "a/b")
+	})
+	// the messages published by the Publisher are forwarded too.
+	n1.srv.Publisher().Publish(&gmqtt.Message{
+		Topic:   "a/b",
+		Payload: []byte("hello"),
+	})
+	p, ok := sub.read().(*packets.Publish)
+	a.True(ok)
+	a.Equal("hello", string(p.Payload))
+}
+
+// writeTestCert creates a certificate signed by the parent, or a self-signed CA certificate if parent is nil.
+func writeTestCert(t *testing.T, dir, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, name+".pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestCluster_tls(t *testing.T) {
+	a := assert.New(t)
+	dir, err := ioutil.TempDir("", "gmqtt_cluster")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ca, caKey := writeTestCert(t, dir, "ca", nil, nil)
+	writeTestCert(t, dir, "node", ca, caKey)
+	// the certificate which is not signed by the cluster CA.
+	writeTestCert(t, dir, "other", nil, nil)
+
+	newConfig := func(name string, peers ...string) *Config {
+		return &Config{
+			NodeName:         name,
+			ListenAddr:       "127.0.0.1:0",
+			Peers:            peers,
+			GossipInterval:   100 * time.Millisecond,
+			RPCTimeout:       time.Second,
+			ForwardQueueSize: 100,
+			TLS: &TLSConfig{
+				CAFile:   filepath.Join(dir, "ca.pem"),
+				CertFile: filepath.Join(dir, "node.pem"),
+				KeyFile:  filepath.Join(dir, "node.key"),
+			},
+		}
+	}
+	n1 := startNodeWithConfig(t, newConfig("node1"))
+	defer n1.stop()
+	n2 := startNodeWithConfig(t, newConfig("node2", n1.cluster.advertiseAddr))
+	defer n2.stop()
+
+	sub := connect(t, n2.addr, "sub", true)
+	defer sub.close()
+	sub.subscribe("a/#")
+	waitFor(t, func() bool {
+		return n1.hasRoute("node2", "a/b")
+	})
+	pub := connect(t, n1.addr, "pub", true)
+	defer pub.close()
+	pub.publish("a/b", "hello")
+	p, ok := sub.read().(*packets.Publish)
+	a.True(ok)
+	a.Equal("hello", string(p.Payload))
+
+	// the nodes without a certificate signed by the CA are rejected.
+	other := newConfig("other")
+	other.TLS.CertFile = filepath.Join(dir, "other.pem")
+	other.TLS.KeyFile = filepath.Join(dir, "other.key")
+	opts, err := other.dialOptions()
+	a.Nil(err)
+	conn, err := grpc.Dial(n1.cluster.advertiseAddr, opts...)
+	a.Nil(err)
+	defer conn.Close()
+	_, err = NewClusterClient(conn).Forward(context.Background(), &ForwardRequest{From: "other"})
+	a.Error(err)
+}
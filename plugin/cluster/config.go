@@ -0,0 +1,126 @@
+package cluster
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/DrmagicE/gmqtt/config"
+)
+
+var _ config.ConfigValidator = (*Config)(nil)
+
+// Config is the configuration for the cluster plugin.
+type Config struct {
+	// NodeName is the unique name of the node in the cluster. Default to the hostname.
+	NodeName string `yaml:"node_name"`
+	// ListenAddr is the address that the cluster gRPC server listen on.
+	ListenAddr string `yaml:"listen_addr"`
+	// AdvertiseAddr is the address that other nodes use to connect to this node. Default to ListenAddr.
+	AdvertiseAddr string `yaml:"advertise_addr"`
+	// Peers is the static list of the cluster addresses of other nodes.
+	// It is not necessary to list all nodes, the nodes that not in the list will be discovered from the peers.
+	Peers []string `yaml:"peers"`
+	// GossipInterval is the interval of exchanging the subscription table with peers.
+	GossipInterval time.Duration `yaml:"gossip_interval"`
+	// RPCTimeout is the timeout of the rpc calls between nodes.
+	RPCTimeout time.Duration `yaml:"rpc_timeout"`
+	// ForwardQueueSize is the size of the forwarding queue for each peer.
+	// Messages will be dropped if the queue is full.
+	ForwardQueueSize int `yaml:"forward_queue_size"`
+	// Secret is the shared secret of the cluster, every node must be configured with the same secret.
+	// The rpc calls without the correct secret will be rejected.
+	Secret string `yaml:"secret"`
+	// TLS enables the mutual TLS authentication and encryption between nodes.
+	TLS *TLSConfig `yaml:"tls"`
+	// Insecure allows the nodes to communicate without any authentication if neither Secret nor TLS is set.
+	// It should only be used in a trusted network.
+	Insecure bool `yaml:"insecure"`
+}
+
+// TLSConfig is the mutual TLS configuration between nodes.
+type TLSConfig struct {
+	// CAFile is the CA certificates used to verify the certificates of other nodes.
+	CAFile string `yaml:"ca_file"`
+	// CertFile and KeyFile is the certificate of the node, it is used both as the server and the client certificate.
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// ServerName is used to verify the hostname of the server certificates, default to the host of the peer address.
+	ServerName string `yaml:"server_name"`
+}
+
+func (t *TLSConfig) validate() error {
+	if t.CAFile == "" || t.CertFile == "" || t.KeyFile == "" {
+		return errors.New("ca_file, cert_file and key_file are required")
+	}
+	return nil
+}
+
+// authenticated returns whether the communication between nodes is authenticated.
+func (c *Config) authenticated() bool {
+	return c.Secret != "" || c.TLS != nil
+}
+
+// Validate validates the configuration, and return an error if it is invalid.
+func (c *Config) Validate() error {
+	if _, _, err := net.SplitHostPort(c.ListenAddr); err != nil {
+		return errors.New("invalid listen_addr")
+	}
+	if c.AdvertiseAddr != "" {
+		if _, _, err := net.SplitHostPort(c.AdvertiseAddr); err != nil {
+			return errors.New("invalid advertise_addr")
+		}
+	}
+	for _, v := range c.Peers {
+		if _, _, err := net.SplitHostPort(v); err != nil {
+			return errors.New("invalid peer address: " + v)
+		}
+	}
+	if c.GossipInterval <= 0 {
+		return errors.New("gossip_interval must be greater than 0")
+	}
+	if c.RPCTimeout <= 0 {
+		return errors.New("rpc_timeout must be greater than 0")
+	}
+	if c.ForwardQueueSize <= 0 {
+		return errors.New("forward_queue_size must be greater than 0")
+	}
+	if c.TLS != nil {
+		if err := c.TLS.validate(); err != nil {
+			return errors.New("invalid tls: " + err.Error())
+		}
+	}
+	return nil
+}
+
+// ValidateConfig rejects the mqtt settings that are not supported in cluster mode.
+// The shared subscriptions are not supported because the shared subscription groups are not balanced across the nodes,
+// every node would deliver a copy of the message to its own group members.
+func (c *Config) ValidateConfig(cfg config.Config) error {
+	if cfg.MQTT.SharedSubAvailable {
+		return errors.New("cluster: shared subscriptions are not supported, shared_subscription_available must be false")
+	}
+	return nil
+}
+
+// DefaultConfig is the default configuration.
+var DefaultConfig = Config{
+	ListenAddr:       "127.0.0.1:8090",
+	GossipInterval:   time.Second,
+	RPCTimeout:       3 * time.Second,
+	ForwardQueueSize: 10000,
+}
+
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type cfg Config
+	var v = &struct {
+		Cluster cfg `yaml:"cluster"`
+	}{
+		Cluster: cfg(DefaultConfig),
+	}
+	if err := unmarshal(v); err != nil {
+		return err
+	}
+	*c = Config(v.Cluster)
+	return nil
+}
@@ -0,0 +1,72 @@
+package cluster
+
+import (
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+func messageToProto(msg *gmqtt.Message) *Message {
+	m := &Message{
+		Topic:           msg.Topic,
+		Payload:         msg.Payload,
+		Qos:             uint32(msg.QoS),
+		Retained:        msg.Retained,
+		ContentType:     msg.ContentType,
+		CorrelationData: msg.CorrelationData,
+		MessageExpiry:   msg.MessageExpiry,
+		PayloadFormat:   uint32(msg.PayloadFormat),
+		ResponseTopic:   msg.ResponseTopic,
+	}
+	for _, v := range msg.UserProperties {
+		m.UserProperties = append(m.UserProperties, &UserProperty{
+			K: v.K,
+			V: v.V,
+		})
+	}
+	return m
+}
+
+func messageFromProto(m *Message) *gmqtt.Message {
+	msg := &gmqtt.Message{
+		QoS:             uint8(m.Qos),
+		Retained:        m.Retained,
+		Topic:           m.Topic,
+		Payload:         m.Payload,
+		ContentType:     m.ContentType,
+		CorrelationData: m.CorrelationData,
+		MessageExpiry:   m.MessageExpiry,
+		PayloadFormat:   packets.PayloadFormat(m.PayloadFormat),
+		ResponseTopic:   m.ResponseTopic,
+	}
+	for _, v := range m.UserProperties {
+		msg.UserProperties = append(msg.UserProperties, packets.UserProperty{
+			K: v.K,
+			V: v.V,
+		})
+	}
+	return msg
+}
+
+func subscriptionToProto(sub *gmqtt.Subscription) *Subscription {
+	return &Subscription{
+		ShareName:         sub.ShareName,
+		TopicFilter:       sub.TopicFilter,
+		Id:                sub.ID,
+		Qos:               uint32(sub.QoS),
+		NoLocal:           sub.NoLocal,
+		RetainAsPublished: sub.RetainAsPublished,
+		RetainHandling:    uint32(sub.RetainHandling),
+	}
+}
+
+func subscriptionFromProto(sub *Subscription) *gmqtt.Subscription {
+	return &gmqtt.Subscription{
+		ShareName:         sub.ShareName,
+		TopicFilter:       sub.TopicFilter,
+		ID:                sub.Id,
+		QoS:               uint8(sub.Qos),
+		NoLocal:           sub.NoLocal,
+		RetainAsPublished: sub.RetainAsPublished,
+		RetainHandling:    byte(sub.RetainHandling),
+	}
+}
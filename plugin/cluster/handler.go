@@ -0,0 +1,129 @@
+package cluster
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/persistence/subscription"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+// disconnectTimeout is the maximum time to wait for the taken over client to be disconnected.
+const disconnectTimeout = 500 * time.Millisecond
+
+// Sync updates the state of the sender and returns the state of the node and the known members.
+func (c *Cluster) Sync(ctx context.Context, req *SyncRequest) (*SyncResponse, error) {
+	if req.State != nil && req.State.Member != nil && req.State.Member.Name != c.nodeName {
+		c.mu.Lock()
+		p := c.addPeerLocked(req.State.Member.Addr)
+		c.mu.Unlock()
+		if p != nil {
+			p.update(req.State)
+		}
+	}
+	c.addMembers(req.Members)
+	return &SyncResponse{
+		State:   c.localState(),
+		Members: c.members(),
+	}, nil
+}
+
+// Forward publishes the messages forwarded by other nodes to the local subscribers.
+func (c *Cluster) Forward(ctx context.Context, req *ForwardRequest) (*ForwardResponse, error) {
+	for _, v := range req.Messages {
+		msg := messageFromProto(v)
+		if msg.Retained {
+			if len(msg.Payload) == 0 {
+				c.retained.Remove(msg.Topic)
+			} else {
+				c.retained.AddOrReplace(msg)
+			}
+		}
+		// the forwarded messages will not be forwarded again.
+		c.publisher.PublishLocal(msg)
+	}
+	return &ForwardResponse{}, nil
+}
+
+// Takeover terminates the session of the client and returns its subscriptions.
+// If the local client connected later than the client on the sender, the session will not be terminated.
+func (c *Cluster) Takeover(ctx context.Context, req *TakeoverRequest) (*TakeoverResponse, error) {
+	resp := &TakeoverResponse{}
+	if client := c.clientService.GetClient(req.ClientId); client != nil {
+		if client.ConnectedAt().After(time.Unix(0, req.ConnectedAt)) {
+			return resp, nil
+		}
+		if client.Version() == packets.Version5 {
+			client.Disconnect(&packets.Disconnect{
+				Version: packets.Version5,
+				Code:    codes.SessionTakenOver,
+			})
+			c.waitClosed(req.ClientId, client)
+		}
+	}
+	c.subStore.Iterate(func(clientID string, sub *gmqtt.Subscription) bool {
+		resp.Subscriptions = append(resp.Subscriptions, subscriptionToProto(sub))
+		return true
+	}, subscription.IterationOptions{
+		Type:     subscription.TypeAll,
+		ClientID: req.ClientId,
+	})
+	c.clientService.TerminateSession(req.ClientId)
+	log.Info("session taken over",
+		zap.String("client_id", req.ClientId),
+		zap.String("by", req.From))
+	return resp, nil
+}
+
+// waitClosed waits for the client to close the connection after sending the DISCONNECT packet,
+// otherwise the packet may be discarded by the following TerminateSession call.
+func (c *Cluster) waitClosed(clientID string, client server.Client) {
+	timeout := time.After(disconnectTimeout)
+	for c.clientService.GetClient(clientID) == client {
+		select {
+		case <-timeout:
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// takeover terminates the sessions of the client on other nodes.
+// If cleanStart is false, the subscriptions of the terminated sessions will be added to the local session.
+func (c *Cluster) takeover(clientID string, cleanStart bool, connectedAt time.Time) {
+	req := &TakeoverRequest{
+		From:        c.nodeName,
+		ClientId:    clientID,
+		ConnectedAt: connectedAt.UnixNano(),
+	}
+	var subs []*gmqtt.Subscription
+	for _, p := range c.peerList() {
+		resp, err := p.takeover(req)
+		if err != nil {
+			log.Warn("failed to take over session",
+				zap.String("peer", p.addr),
+				zap.String("client_id", clientID),
+				zap.Error(err))
+			continue
+		}
+		for _, v := range resp.Subscriptions {
+			subs = append(subs, subscriptionFromProto(v))
+		}
+	}
+	if cleanStart || len(subs) == 0 {
+		return
+	}
+	_, err := c.subStore.Subscribe(clientID, subs...)
+	if err != nil {
+		log.Error("failed to restore subscriptions",
+			zap.String("client_id", clientID),
+			zap.Error(err))
+		return
+	}
+	c.notify()
+}
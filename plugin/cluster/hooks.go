@@ -0,0 +1,94 @@
+package cluster
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func (c *Cluster) HookWrapper() server.HookWrapper {
+	return server.HookWrapper{
+		OnSessionCreatedWrapper:    c.OnSessionCreatedWrapper,
+		OnSessionResumedWrapper:    c.OnSessionResumedWrapper,
+		OnSessionTerminatedWrapper: c.OnSessionTerminatedWrapper,
+		OnSubscribedWrapper:        c.OnSubscribedWrapper,
+		OnUnsubscribedWrapper:      c.OnUnsubscribedWrapper,
+		OnMsgDispatchedWrapper:     c.OnMsgDispatchedWrapper,
+	}
+}
+
+// startTakeover takes over the sessions of the client on other nodes asynchronously,
+// because the session hooks are called under the server lock.
+func (c *Cluster) startTakeover(client server.Client) {
+	opts := client.ClientOptions()
+	clientID, cleanStart, connectedAt := opts.ClientID, opts.CleanStart, time.Now()
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.takeover(clientID, cleanStart, connectedAt)
+	}()
+}
+
+func (c *Cluster) OnSessionCreatedWrapper(pre server.OnSessionCreated) server.OnSessionCreated {
+	return func(ctx context.Context, client server.Client) {
+		pre(ctx, client)
+		c.startTakeover(client)
+	}
+}
+
+func (c *Cluster) OnSessionResumedWrapper(pre server.OnSessionResumed) server.OnSessionResumed {
+	return func(ctx context.Context, client server.Client) {
+		pre(ctx, client)
+		c.startTakeover(client)
+	}
+}
+
+func (c *Cluster) OnSessionTerminatedWrapper(pre server.OnSessionTerminated) server.OnSessionTerminated {
+	return func(ctx context.Context, clientID string, reason server.SessionTerminatedReason) {
+		pre(ctx, clientID, reason)
+		c.notify()
+	}
+}
+
+func (c *Cluster) OnSubscribedWrapper(pre server.OnSubscribed) server.OnSubscribed {
+	return func(ctx context.Context, client server.Client, subscription *gmqtt.Subscription) {
+		pre(ctx, client, subscription)
+		// the shared subscriptions can still be enabled for the client by AuthOptions.SharedSubAvailable.
+		if subscription.ShareName != "" {
+			log.Warn("shared subscriptions are not balanced across the nodes, every node delivers a copy to its own members",
+				zap.String("client_id", client.ClientOptions().ClientID),
+				zap.String("share_name", subscription.ShareName),
+				zap.String("topic_filter", subscription.TopicFilter))
+		}
+		c.notify()
+	}
+}
+
+func (c *Cluster) OnUnsubscribedWrapper(pre server.OnUnsubscribed) server.OnUnsubscribed {
+	return func(ctx context.Context, client server.Client, topicName string) {
+		pre(ctx, client, topicName)
+		c.notify()
+	}
+}
+
+// OnMsgDispatchedWrapper forwards the message to the nodes that have matched subscriptions.
+// Retained messages are forwarded to all nodes, so that every node has a copy of them.
+func (c *Cluster) OnMsgDispatchedWrapper(pre server.OnMsgDispatched) server.OnMsgDispatched {
+	return func(ctx context.Context, srcClientID string, msg *gmqtt.Message) {
+		pre(ctx, srcClientID, msg)
+		var m *Message
+		for _, p := range c.peerList() {
+			if !msg.Retained && !p.match(msg.Topic) {
+				continue
+			}
+			if m == nil {
+				m = messageToProto(msg.Copy())
+			}
+			p.enqueue(m)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package cluster
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// maxBatchSize is the maximum number of messages in a Forward request.
+const maxBatchSize = 100
+
+// peer is another node of the cluster.
+type peer struct {
+	addr string
+	// from is the name of the local node.
+	from    string
+	static  bool
+	timeout time.Duration
+	conn    *grpc.ClientConn
+	client  ClusterClient
+	// queue is the forwarding queue, messages will be dropped if it is full.
+	queue   chan *Message
+	closing chan struct{}
+	wg      sync.WaitGroup
+
+	mu          sync.RWMutex
+	nodeName    string
+	incarnation int64
+	version     uint64
+	routes      *routes
+	failures    int
+}
+
+func newPeer(addr, from string, queueSize int, timeout time.Duration, opts []grpc.DialOption) (*peer, error) {
+	conn, err := grpc.Dial(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	p := &peer{
+		addr:    addr,
+		from:    from,
+		timeout: timeout,
+		conn:    conn,
+		client:  NewClusterClient(conn),
+		queue:   make(chan *Message, queueSize),
+		closing: make(chan struct{}),
+	}
+	p.wg.Add(1)
+	go p.forwardLoop()
+	return p, nil
+}
+
+func (p *peer) close() {
+	close(p.closing)
+	p.wg.Wait()
+	_ = p.conn.Close()
+}
+
+func (p *peer) name() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.nodeName
+}
+
+// match returns whether the peer has subscriptions that match the topic name.
+func (p *peer) match(topic string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.routes.match(topic)
+}
+
+// update updates the subscription table of the peer if the state is newer than the current one.
+func (p *peer) update(state *NodeState) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failures = 0
+	p.nodeName = state.Member.Name
+	if p.routes != nil && state.Incarnation == p.incarnation && state.Version <= p.version {
+		return
+	}
+	p.incarnation = state.Incarnation
+	p.version = state.Version
+	p.routes = newRoutes(state.Filters)
+}
+
+// fail records a sync failure and returns the number of consecutive failures.
+// The subscription table is cleared, so that no messages will be forwarded to the peer until it is recovered.
+func (p *peer) fail() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failures++
+	p.routes = nil
+	return p.failures
+}
+
+func (p *peer) sync(req *SyncRequest) (*SyncResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	return p.client.Sync(ctx, req)
+}
+
+func (p *peer) takeover(req *TakeoverRequest) (*TakeoverResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	return p.client.Takeover(ctx, req)
+}
+
+// enqueue puts the message into the forwarding queue.
+func (p *peer) enqueue(msg *Message) {
+	select {
+	case p.queue <- msg:
+	default:
+		log.Warn("message dropped, the forwarding queue is full",
+			zap.String("peer", p.addr),
+			zap.String("topic", msg.Topic))
+	}
+}
+
+// forwardLoop sends the messages in the queue to the peer in batches.
+func (p *peer) forwardLoop() {
+	defer p.wg.Done()
+	for {
+		var msgs []*Message
+		select {
+		case <-p.closing:
+			return
+		case msg := <-p.queue:
+			msgs = append(msgs, msg)
+		}
+	batch:
+		for len(msgs) < maxBatchSize {
+			select {
+			case msg := <-p.queue:
+				msgs = append(msgs, msg)
+			default:
+				break batch
+			}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+		_, err := p.client.Forward(ctx, &ForwardRequest{
+			From:     p.from,
+			Messages: msgs,
+		})
+		cancel()
+		if err != nil {
+			log.Warn("failed to forward messages",
+				zap.String("peer", p.addr),
+				zap.Int("count", len(msgs)),
+				zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,4 @@
+protoc -I. \
+--go-grpc_out=../ \
+--go_out=../ \
+*.proto
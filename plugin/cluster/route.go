@@ -0,0 +1,43 @@
+package cluster
+
+import (
+	"strings"
+
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+// routes is the topic filter set of a peer, it is used to decide whether a message should be forwarded to the peer.
+type routes struct {
+	exact     map[string]struct{}
+	wildcards []string
+}
+
+func newRoutes(filters []string) *routes {
+	r := &routes{
+		exact: make(map[string]struct{}),
+	}
+	for _, v := range filters {
+		if strings.ContainsAny(v, "+#") {
+			r.wildcards = append(r.wildcards, v)
+		} else {
+			r.exact[v] = struct{}{}
+		}
+	}
+	return r
+}
+
+// match returns whether the topic name matches any of the topic filters.
+func (r *routes) match(topic string) bool {
+	if r == nil {
+		return false
+	}
+	if _, ok := r.exact[topic]; ok {
+		return true
+	}
+	for _, v := range r.wildcards {
+		if packets.TopicMatch([]byte(topic), []byte(v)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package connlimit
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Config is the configuration for the connlimit plugin.
+type Config struct {
+	// IPRate is the number of new connections allowed per second for each source IP, 0 means unlimited.
+	IPRate float64 `yaml:"ip_rate"`
+	// IPBurst is the maximum number of new connections allowed at once for each source IP, default to IPRate.
+	IPBurst int `yaml:"ip_burst"`
+	// CIDRLimits are the connection rate limits shared by the source IPs in the given CIDRs.
+	CIDRLimits []CIDRLimit `yaml:"cidr_limits"`
+	// MaxConnectionsPerListener is the maximum number of connections of each listener, 0 means unlimited.
+	MaxConnectionsPerListener int `yaml:"max_connections_per_listener"`
+	// AuthFailure is the setting of banning the IPs and client ids after repeated authentication failures.
+	AuthFailure AuthFailure `yaml:"auth_failure"`
+}
+
+// CIDRLimit is the connection rate limit for the CIDR.
+type CIDRLimit struct {
+	CIDR  string  `yaml:"cidr"`
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+}
+
+// AuthFailure bans the IP or client id for BanDuration after MaxFailures failed OnBasicAuth attempts within Window.
+type AuthFailure struct {
+	// MaxFailures is the number of failures that triggers the ban, 0 means never ban.
+	MaxFailures int           `yaml:"max_failures"`
+	Window      time.Duration `yaml:"window"`
+	BanDuration time.Duration `yaml:"ban_duration"`
+}
+
+// Validate validates the configuration, and return an error if it is invalid.
+func (c *Config) Validate() error {
+	if c.IPRate < 0 || c.IPBurst < 0 {
+		return errors.New("ip_rate and ip_burst cannot be negative")
+	}
+	for _, v := range c.CIDRLimits {
+		if _, _, err := net.ParseCIDR(v.CIDR); err != nil {
+			return fmt.Errorf("invalid cidr: %s", v.CIDR)
+		}
+		if v.Rate <= 0 || v.Burst < 0 {
+			return fmt.Errorf("invalid rate or burst of cidr %s", v.CIDR)
+		}
+	}
+	if c.MaxConnectionsPerListener < 0 {
+		return errors.New("max_connections_per_listener cannot be negative")
+	}
+	if c.AuthFailure.MaxFailures < 0 {
+		return errors.New("auth_failure.max_failures cannot be negative")
+	}
+	if c.AuthFailure.MaxFailures > 0 && (c.AuthFailure.Window <= 0 || c.AuthFailure.BanDuration <= 0) {
+		return errors.New("auth_failure.window and auth_failure.ban_duration must be greater than 0")
+	}
+	return nil
+}
+
+// DefaultConfig is the default configuration.
+var DefaultConfig = Config{
+	IPRate:  10,
+	IPBurst: 20,
+	AuthFailure: AuthFailure{
+		MaxFailures: 5,
+		Window:      time.Minute,
+		BanDuration: 10 * time.Minute,
+	},
+}
+
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type cfg Config
+	var v = &struct {
+		ConnLimit cfg `yaml:"connlimit"`
+	}{
+		ConnLimit: cfg(DefaultConfig),
+	}
+	if err := unmarshal(v); err != nil {
+		return err
+	}
+	*c = Config(v.ConnLimit)
+	return nil
+}
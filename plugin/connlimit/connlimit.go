@@ -0,0 +1,397 @@
+package connlimit
+
+import (
+	"context"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+var _ server.Plugin = (*ConnLimit)(nil)
+
+const Name = "connlimit"
+
+// The ban types.
+const (
+	BanTypeIP       = "ip"
+	BanTypeClientID = "client_id"
+)
+
+// cleanupInterval is the interval of removing the idle rate limits, the stale failures and the expired bans.
+const cleanupInterval = time.Minute
+
+func init() {
+	server.RegisterPlugin(Name, New)
+	config.RegisterDefaultPluginConfig(Name, &DefaultConfig)
+}
+
+func New(config config.Config) (server.Plugin, error) {
+	cfg := config.Plugins[Name].(*Config)
+	c := &ConnLimit{
+		config:    cfg,
+		ips:       make(map[string]*tokenBucket),
+		listeners: make(map[string]int),
+		conns:     make(map[net.Conn]string),
+		failures:  make(map[banKey]*failure),
+		bans:      make(map[banKey]*ban),
+		exit:      make(chan struct{}),
+	}
+	for _, v := range cfg.CIDRLimits {
+		_, n, _ := net.ParseCIDR(v.CIDR)
+		c.cidrs = append(c.cidrs, &cidrLimit{
+			ipNet:  n,
+			bucket: newTokenBucket(v.Rate, v.Burst),
+		})
+	}
+	return c, nil
+}
+
+var log *zap.Logger
+
+// tokenBucket implements the token bucket algorithm.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	b := float64(burst)
+	if burst <= 0 {
+		b = math.Ceil(rate)
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  b,
+		tokens: b,
+	}
+}
+
+func (b *tokenBucket) advance(now time.Time) {
+	if !b.last.IsZero() {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+}
+
+// allow takes a token if available and reports whether the token is taken.
+func (b *tokenBucket) allow(now time.Time) bool {
+	b.advance(now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// full reports whether the bucket is full at the given time, which means the bucket is idle.
+func (b *tokenBucket) full(now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*b.rate >= b.burst
+}
+
+type cidrLimit struct {
+	ipNet  *net.IPNet
+	bucket *tokenBucket
+}
+
+type banKey struct {
+	typ   string
+	value string
+}
+
+type ban struct {
+	banKey
+	// ipNet is set if the type is ip.
+	ipNet  *net.IPNet
+	reason string
+	// expiresAt is zero if the ban never expires.
+	expiresAt time.Time
+}
+
+func (b *ban) expired(now time.Time) bool {
+	return !b.expiresAt.IsZero() && !now.Before(b.expiresAt)
+}
+
+// failure records the authentication failures in the current window.
+type failure struct {
+	count int
+	start time.Time
+}
+
+// ConnLimit limits the new connections per source IP and CIDR, the connections per listener,
+// and bans the IPs and client ids after repeated authentication failures.
+type ConnLimit struct {
+	config        *Config
+	clientService server.ClientService
+	// cidrs is immutable after created.
+	cidrs []*cidrLimit
+
+	// gard the following fields and the buckets in cidrs.
+	mu sync.Mutex
+	// ips stores the rate limits of the source IPs, key by IP.
+	ips map[string]*tokenBucket
+	// listeners stores the number of connections of each listener, key by listener address.
+	listeners map[string]int
+	// conns stores the connections counted in listeners and the listener address.
+	conns    map[net.Conn]string
+	failures map[banKey]*failure
+	bans     map[banKey]*ban
+
+	exit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func (c *ConnLimit) mustEmbedUnimplementedConnLimitServiceServer() {
+	return
+}
+
+// remoteIP returns the IP of the remote address, or the remote address if it is not an IP address.
+func remoteIP(addr net.Addr) string {
+	if tcp, ok := addr.(*net.TCPAddr); ok {
+		return tcp.IP.String()
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// listenerKey returns the key of the listener which accepts the connection.
+// The port is used as the key, because the local address of the connection is the specific interface address
+// if the listener listens on all interfaces.
+func listenerKey(addr net.Addr) string {
+	_, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return port
+}
+
+// allowConn reports whether the new connection is allowed, and counts the connection in its listener if allowed.
+func (c *ConnLimit) allowConn(conn net.Conn, now time.Time) bool {
+	ip := remoteIP(conn.RemoteAddr())
+	parsed := net.ParseIP(ip)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lk := listenerKey(conn.LocalAddr())
+	if max := c.config.MaxConnectionsPerListener; max > 0 && c.listeners[lk] >= max {
+		log.Warn("too many connections", zap.String("listener", lk), zap.String("remote_ip", ip))
+		return false
+	}
+	if c.config.IPRate > 0 {
+		b, ok := c.ips[ip]
+		if !ok {
+			b = newTokenBucket(c.config.IPRate, c.config.IPBurst)
+			c.ips[ip] = b
+		}
+		if !b.allow(now) {
+			log.Debug("connection rate limit exceeded", zap.String("remote_ip", ip))
+			return false
+		}
+	}
+	if parsed != nil {
+		for _, v := range c.cidrs {
+			if v.ipNet.Contains(parsed) && !v.bucket.allow(now) {
+				log.Debug("connection rate limit exceeded",
+					zap.String("remote_ip", ip),
+					zap.String("cidr", v.ipNet.String()))
+				return false
+			}
+		}
+	}
+	c.listeners[lk]++
+	c.conns[conn] = lk
+	return true
+}
+
+// releaseConn releases the connection counted by allowConn.
+func (c *ConnLimit) releaseConn(conn net.Conn) {
+	if conn == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lk, ok := c.conns[conn]
+	if !ok {
+		return
+	}
+	delete(c.conns, conn)
+	c.listeners[lk]--
+	if c.listeners[lk] <= 0 {
+		delete(c.listeners, lk)
+	}
+}
+
+// banned returns the ban that matches the IP or the client id, or nil if not banned.
+func (c *ConnLimit) banned(ip string, clientID string, now time.Time) *ban {
+	parsed := net.ParseIP(ip)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if b, ok := c.bans[banKey{typ: BanTypeClientID, value: clientID}]; ok && clientID != "" && !b.expired(now) {
+		return b
+	}
+	for _, b := range c.bans {
+		if b.typ == BanTypeIP && !b.expired(now) && (b.value == ip || (parsed != nil && b.ipNet != nil && b.ipNet.Contains(parsed))) {
+			return b
+		}
+	}
+	return nil
+}
+
+// authFailed records the authentication failure of the IP and the client id,
+// and bans them if the failures reach the max failures.
+func (c *ConnLimit) authFailed(ip string, clientID string, now time.Time) {
+	af := c.config.AuthFailure
+	if af.MaxFailures == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, k := range []banKey{{typ: BanTypeIP, value: ip}, {typ: BanTypeClientID, value: clientID}} {
+		if k.value == "" {
+			continue
+		}
+		f, ok := c.failures[k]
+		if !ok || now.Sub(f.start) >= af.Window {
+			f = &failure{start: now}
+			c.failures[k] = f
+		}
+		f.count++
+		if f.count < af.MaxFailures {
+			continue
+		}
+		delete(c.failures, k)
+		b := &ban{
+			banKey:    k,
+			reason:    "too many authentication failures",
+			expiresAt: now.Add(af.BanDuration),
+		}
+		if k.typ == BanTypeIP {
+			b.ipNet = ipNet(ip)
+		}
+		c.bans[k] = b
+		log.Warn("banned after authentication failures",
+			zap.String("type", k.typ),
+			zap.String("value", k.value),
+			zap.Duration("duration", af.BanDuration))
+	}
+}
+
+// ipNet returns the network of the IP or CIDR, or nil if it is invalid.
+func ipNet(v string) *net.IPNet {
+	if _, n, err := net.ParseCIDR(v); err == nil {
+		return n
+	}
+	ip := net.ParseIP(v)
+	if ip == nil {
+		return nil
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}
+}
+
+// disconnect disconnects the connected clients that match the ban.
+func (c *ConnLimit) disconnect(b *ban) {
+	var clients []server.Client
+	c.clientService.IterateClient(func(client server.Client) bool {
+		switch b.typ {
+		case BanTypeClientID:
+			if client.ClientOptions().ClientID == b.value {
+				clients = append(clients, client)
+			}
+		case BanTypeIP:
+			if conn := client.Connection(); conn != nil {
+				if ip := net.ParseIP(remoteIP(conn.RemoteAddr())); ip != nil && b.ipNet != nil && b.ipNet.Contains(ip) {
+					clients = append(clients, client)
+				}
+			}
+		}
+		return true
+	})
+	for _, client := range clients {
+		log.Info("disconnecting banned client", zap.String("client_id", client.ClientOptions().ClientID))
+		if client.Version() == packets.Version5 {
+			client.Disconnect(&packets.Disconnect{
+				Version: packets.Version5,
+				Code:    codes.Banned,
+			})
+			continue
+		}
+		client.Close()
+	}
+}
+
+// cleanup removes the idle rate limits, the stale failures and the expired bans.
+func (c *ConnLimit) cleanup(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, v := range c.ips {
+		if v.full(now) {
+			delete(c.ips, k)
+		}
+	}
+	for k, v := range c.failures {
+		if now.Sub(v.start) >= c.config.AuthFailure.Window {
+			delete(c.failures, k)
+		}
+	}
+	for k, v := range c.bans {
+		if v.expired(now) {
+			delete(c.bans, k)
+		}
+	}
+}
+
+func (c *ConnLimit) cleanupLoop() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.exit:
+			return
+		case now := <-ticker.C:
+			c.cleanup(now)
+		}
+	}
+}
+
+func (c *ConnLimit) RegisterGRPC(s grpc.ServiceRegistrar) {
+	RegisterConnLimitServiceServer(s, c)
+}
+
+func (c *ConnLimit) RegisterHTTP(ctx context.Context, mux *runtime.ServeMux, endpoint string, opts []grpc.DialOption) (err error) {
+	return RegisterConnLimitServiceHandlerFromEndpoint(ctx, mux, endpoint, opts)
+}
+
+func (c *ConnLimit) Load(service server.Server) error {
+	log = server.LoggerWithField(zap.String("plugin", Name))
+	c.clientService = service.ClientService()
+	c.wg.Add(1)
+	go c.cleanupLoop()
+	return nil
+}
+
+func (c *ConnLimit) Unload() error {
+	close(c.exit)
+	c.wg.Wait()
+	return nil
+}
+
+func (c *ConnLimit) Name() string {
+	return Name
+}
@@ -0,0 +1,188 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.13.0
+// source: connlimit.proto
+
+package connlimit
+
+import (
+	context "context"
+	empty "github.com/golang/protobuf/ptypes/empty"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// ConnLimitServiceClient is the client API for ConnLimitService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ConnLimitServiceClient interface {
+	// List the bans.
+	List(ctx context.Context, in *ListBansRequest, opts ...grpc.CallOption) (*ListBansResponse, error)
+	// Add a ban, the existing ban with the same type and value will be replaced.
+	// The connected clients that match the ban will be disconnected.
+	Add(ctx context.Context, in *AddBanRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	// Delete the ban with the given type and value.
+	// Return NotFound error when the ban does not exist.
+	Delete(ctx context.Context, in *DeleteBanRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+}
+
+type connLimitServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewConnLimitServiceClient(cc grpc.ClientConnInterface) ConnLimitServiceClient {
+	return &connLimitServiceClient{cc}
+}
+
+func (c *connLimitServiceClient) List(ctx context.Context, in *ListBansRequest, opts ...grpc.CallOption) (*ListBansResponse, error) {
+	out := new(ListBansResponse)
+	err := c.cc.Invoke(ctx, "/gmqtt.connlimit.api.ConnLimitService/List", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *connLimitServiceClient) Add(ctx context.Context, in *AddBanRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/gmqtt.connlimit.api.ConnLimitService/Add", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *connLimitServiceClient) Delete(ctx context.Context, in *DeleteBanRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/gmqtt.connlimit.api.ConnLimitService/Delete", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ConnLimitServiceServer is the server API for ConnLimitService service.
+// All implementations must embed UnimplementedConnLimitServiceServer
+// for forward compatibility
+type ConnLimitServiceServer interface {
+	// List the bans.
+	List(context.Context, *ListBansRequest) (*ListBansResponse, error)
+	// Add a ban, the existing ban with the same type and value will be replaced.
+	// The connected clients that match the ban will be disconnected.
+	Add(context.Context, *AddBanRequest) (*empty.Empty, error)
+	// Delete the ban with the given type and value.
+	// Return NotFound error when the ban does not exist.
+	Delete(context.Context, *DeleteBanRequest) (*empty.Empty, error)
+	mustEmbedUnimplementedConnLimitServiceServer()
+}
+
+// UnimplementedConnLimitServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedConnLimitServiceServer struct {
+}
+
+func (UnimplementedConnLimitServiceServer) List(context.Context, *ListBansRequest) (*ListBansResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedConnLimitServiceServer) Add(context.Context, *AddBanRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Add not implemented")
+}
+func (UnimplementedConnLimitServiceServer) Delete(context.Context, *DeleteBanRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedConnLimitServiceServer) mustEmbedUnimplementedConnLimitServiceServer() {}
+
+// UnsafeConnLimitServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ConnLimitServiceServer will
+// result in compilation errors.
+type UnsafeConnLimitServiceServer interface {
+	mustEmbedUnimplementedConnLimitServiceServer()
+}
+
+func RegisterConnLimitServiceServer(s grpc.ServiceRegistrar, srv ConnLimitServiceServer) {
+	s.RegisterService(&ConnLimitService_ServiceDesc, srv)
+}
+
+func _ConnLimitService_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListBansRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConnLimitServiceServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gmqtt.connlimit.api.ConnLimitService/List",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConnLimitServiceServer).List(ctx, req.(*ListBansRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConnLimitService_Add_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddBanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConnLimitServiceServer).Add(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gmqtt.connlimit.api.ConnLimitService/Add",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConnLimitServiceServer).Add(ctx, req.(*AddBanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConnLimitService_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteBanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConnLimitServiceServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gmqtt.connlimit.api.ConnLimitService/Delete",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConnLimitServiceServer).Delete(ctx, req.(*DeleteBanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ConnLimitService_ServiceDesc is the grpc.ServiceDesc for ConnLimitService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ConnLimitService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gmqtt.connlimit.api.ConnLimitService",
+	HandlerType: (*ConnLimitServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "List",
+			Handler:    _ConnLimitService_List_Handler,
+		},
+		{
+			MethodName: "Add",
+			Handler:    _ConnLimitService_Add_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _ConnLimitService_Delete_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "connlimit.proto",
+}
@@ -0,0 +1,131 @@
+package connlimit
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt/config"
+)
+
+func newTestConnLimit(t *testing.T, cfg Config) *ConnLimit {
+	log = zap.NewNop()
+	p, err := New(config.Config{
+		Plugins: map[string]config.Configuration{
+			Name: &cfg,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.(*ConnLimit)
+}
+
+type testConn struct {
+	net.Conn
+	local, remote net.Addr
+}
+
+func (c *testConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *testConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func newTestConn(local, remote string) *testConn {
+	l, _ := net.ResolveTCPAddr("tcp", local)
+	r, _ := net.ResolveTCPAddr("tcp", remote)
+	return &testConn{local: l, remote: r}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	a := assert.New(t)
+	cfg := DefaultConfig
+	a.Nil(cfg.Validate())
+
+	cfg.CIDRLimits = []CIDRLimit{{CIDR: "10.0.0.0/8", Rate: 1}}
+	a.Nil(cfg.Validate())
+	cfg.CIDRLimits = []CIDRLimit{{CIDR: "10.0.0.0", Rate: 1}}
+	a.NotNil(cfg.Validate())
+	cfg.CIDRLimits = []CIDRLimit{{CIDR: "10.0.0.0/8"}}
+	a.NotNil(cfg.Validate())
+
+	cfg = DefaultConfig
+	cfg.AuthFailure.Window = 0
+	a.NotNil(cfg.Validate())
+	cfg.AuthFailure.MaxFailures = 0
+	a.Nil(cfg.Validate())
+}
+
+func TestConnLimit_allowConn(t *testing.T) {
+	a := assert.New(t)
+	c := newTestConnLimit(t, Config{
+		IPRate:  1,
+		IPBurst: 2,
+		CIDRLimits: []CIDRLimit{
+			{CIDR: "10.0.0.0/8", Rate: 1},
+		},
+		MaxConnectionsPerListener: 3,
+	})
+	now := time.Now()
+	c1 := newTestConn("127.0.0.1:1883", "127.0.0.1:10000")
+	a.True(c.allowConn(c1, now))
+	a.True(c.allowConn(newTestConn("127.0.0.1:1883", "127.0.0.1:10001"), now))
+	// exceeds the ip rate limit
+	a.False(c.allowConn(newTestConn("127.0.0.1:1883", "127.0.0.1:10002"), now))
+	a.True(c.allowConn(newTestConn("127.0.0.1:1883", "127.0.0.1:10002"), now.Add(time.Second)))
+	// exceeds the max connections of the listener
+	a.False(c.allowConn(newTestConn("192.168.0.1:1883", "192.168.0.2:10000"), now))
+	// other listener
+	a.True(c.allowConn(newTestConn("127.0.0.1:1884", "192.168.0.2:10000"), now))
+
+	c.releaseConn(c1)
+	c.releaseConn(c1)
+	a.Equal(2, c.listeners["1883"])
+	a.True(c.allowConn(newTestConn("192.168.0.1:1883", "192.168.0.3:10000"), now))
+
+	// exceeds the cidr rate limit
+	a.True(c.allowConn(newTestConn("127.0.0.1:1884", "10.0.0.1:10000"), now))
+	a.False(c.allowConn(newTestConn("127.0.0.1:1884", "10.0.0.2:10000"), now))
+
+	c.cleanup(now.Add(time.Minute))
+	a.Empty(c.ips)
+}
+
+func TestConnLimit_authFailed(t *testing.T) {
+	a := assert.New(t)
+	c := newTestConnLimit(t, Config{
+		AuthFailure: AuthFailure{
+			MaxFailures: 2,
+			Window:      time.Minute,
+			BanDuration: time.Hour,
+		},
+	})
+	now := time.Now()
+	c.authFailed("127.0.0.1", "c1", now)
+	// the failures of the previous window are reset
+	c.authFailed("127.0.0.1", "c2", now.Add(time.Minute))
+	a.Nil(c.banned("127.0.0.1", "c3", now.Add(time.Minute)))
+
+	c.authFailed("127.0.0.1", "c1", now.Add(time.Minute))
+	b := c.banned("127.0.0.1", "c3", now.Add(time.Minute))
+	a.NotNil(b)
+	a.Equal(banKey{typ: BanTypeIP, value: "127.0.0.1"}, b.banKey)
+	a.Nil(c.banned("127.0.0.2", "c1", now.Add(time.Minute)))
+
+	c.authFailed("127.0.0.2", "c1", now.Add(time.Minute))
+	b = c.banned("127.0.0.3", "c1", now.Add(time.Minute))
+	a.NotNil(b)
+	a.Equal(banKey{typ: BanTypeClientID, value: "c1"}, b.banKey)
+
+	// expired
+	a.Nil(c.banned("127.0.0.1", "c1", now.Add(2*time.Hour)))
+	c.cleanup(now.Add(2 * time.Hour))
+	a.Empty(c.bans)
+	a.Empty(c.failures)
+}
@@ -0,0 +1,104 @@
+package connlimit
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/empty"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/DrmagicE/gmqtt/plugin/admin"
+)
+
+func banToProto(b *ban) *Ban {
+	rs := &Ban{
+		Type:   b.typ,
+		Value:  b.value,
+		Reason: b.reason,
+	}
+	if !b.expiresAt.IsZero() {
+		rs.ExpiresAt = timestamppb.New(b.expiresAt)
+	}
+	return rs
+}
+
+// List lists the bans which are not expired, sorted by type and value.
+func (c *ConnLimit) List(ctx context.Context, req *ListBansRequest) (resp *ListBansResponse, err error) {
+	page, pageSize := admin.GetPage(req.Page, req.PageSize)
+	offset, n := admin.GetOffsetN(page, pageSize)
+	now := time.Now()
+	c.mu.Lock()
+	bans := make([]*ban, 0, len(c.bans))
+	for _, v := range c.bans {
+		if !v.expired(now) {
+			bans = append(bans, v)
+		}
+	}
+	c.mu.Unlock()
+	sort.Slice(bans, func(i, j int) bool {
+		if bans[i].typ != bans[j].typ {
+			return bans[i].typ < bans[j].typ
+		}
+		return bans[i].value < bans[j].value
+	})
+	resp = &ListBansResponse{
+		Bans:       []*Ban{},
+		TotalCount: uint32(len(bans)),
+	}
+	for i := offset; i < offset+n && i < uint(len(bans)); i++ {
+		resp.Bans = append(resp.Bans, banToProto(bans[i]))
+	}
+	return resp, nil
+}
+
+// Add adds a ban and disconnects the connected clients that match the ban.
+func (c *ConnLimit) Add(ctx context.Context, req *AddBanRequest) (resp *empty.Empty, err error) {
+	b := &ban{
+		banKey: banKey{typ: req.Type, value: req.Value},
+		reason: req.Reason,
+	}
+	switch req.Type {
+	case BanTypeIP:
+		b.ipNet = ipNet(req.Value)
+		if b.ipNet == nil {
+			return nil, admin.ErrInvalidArgument("value", "invalid IP address or CIDR")
+		}
+	case BanTypeClientID:
+		if req.Value == "" {
+			return nil, admin.ErrInvalidArgument("value", "cannot be empty")
+		}
+	default:
+		return nil, admin.ErrInvalidArgument("type", "must be ip or client_id")
+	}
+	if b.reason == "" {
+		b.reason = "banned by administrator"
+	}
+	if req.Duration != 0 {
+		b.expiresAt = time.Now().Add(time.Duration(req.Duration) * time.Second)
+	}
+	c.mu.Lock()
+	c.bans[b.banKey] = b
+	c.mu.Unlock()
+	log.Info("ban added",
+		zap.String("type", b.typ),
+		zap.String("value", b.value),
+		zap.Uint32("duration", req.Duration))
+	c.disconnect(b)
+	return &empty.Empty{}, nil
+}
+
+// Delete deletes the ban with the given type and value.
+// Return NotFound error when the ban does not exist.
+func (c *ConnLimit) Delete(ctx context.Context, req *DeleteBanRequest) (resp *empty.Empty, err error) {
+	k := banKey{typ: req.Type, value: req.Value}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.bans[k]; !ok {
+		return nil, admin.ErrNotFound
+	}
+	delete(c.bans, k)
+	log.Info("ban deleted", zap.String("type", k.typ), zap.String("value", k.value))
+	return &empty.Empty{}, nil
+}
@@ -0,0 +1,64 @@
+package connlimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func TestConnLimit_Add_List_Delete(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	c := newTestConnLimit(t, DefaultConfig)
+	cs := server.NewMockClientService(ctrl)
+	c.clientService = cs
+
+	c1 := server.NewMockClient(ctrl)
+	c1.EXPECT().ClientOptions().Return(&server.ClientOptions{ClientID: "c1"}).AnyTimes()
+	c1.EXPECT().Connection().Return(newTestConn("127.0.0.1:1883", "10.0.0.1:10000")).AnyTimes()
+	c1.EXPECT().Version().Return(packets.Version311).AnyTimes()
+	c2 := server.NewMockClient(ctrl)
+	c2.EXPECT().ClientOptions().Return(&server.ClientOptions{ClientID: "c2"}).AnyTimes()
+	c2.EXPECT().Connection().Return(newTestConn("127.0.0.1:1883", "192.168.0.1:10000")).AnyTimes()
+	cs.EXPECT().IterateClient(gomock.Any()).DoAndReturn(func(fn server.ClientIterateFn) {
+		fn(c1)
+		fn(c2)
+	}).AnyTimes()
+
+	_, err := c.Add(context.Background(), &AddBanRequest{Type: BanTypeIP, Value: "10.0.0"})
+	a.Equal(codes.InvalidArgument, status.Code(err))
+	_, err = c.Add(context.Background(), &AddBanRequest{Type: "username", Value: "u"})
+	a.Equal(codes.InvalidArgument, status.Code(err))
+
+	// the matched clients are disconnected
+	c1.EXPECT().Close()
+	_, err = c.Add(context.Background(), &AddBanRequest{Type: BanTypeIP, Value: "10.0.0.0/8"})
+	a.Nil(err)
+	_, err = c.Add(context.Background(), &AddBanRequest{Type: BanTypeClientID, Value: "c3", Duration: 60, Reason: "test"})
+	a.Nil(err)
+
+	resp, err := c.List(context.Background(), &ListBansRequest{})
+	a.Nil(err)
+	a.EqualValues(2, resp.TotalCount)
+	a.Equal("c3", resp.Bans[0].Value)
+	a.Equal("test", resp.Bans[0].Reason)
+	a.NotNil(resp.Bans[0].ExpiresAt)
+	a.Equal("10.0.0.0/8", resp.Bans[1].Value)
+	a.Nil(resp.Bans[1].ExpiresAt)
+	a.NotNil(c.banned("10.1.1.1", "", time.Now()))
+
+	_, err = c.Delete(context.Background(), &DeleteBanRequest{Type: BanTypeIP, Value: "10.0.0.0/8"})
+	a.Nil(err)
+	_, err = c.Delete(context.Background(), &DeleteBanRequest{Type: BanTypeIP, Value: "10.0.0.0/8"})
+	a.Equal(codes.NotFound, status.Code(err))
+	a.Nil(c.banned("10.1.1.1", "", time.Now()))
+}
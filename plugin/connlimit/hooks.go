@@ -0,0 +1,116 @@
+package connlimit
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func (c *ConnLimit) HookWrapper() server.HookWrapper {
+	return server.HookWrapper{
+		OnAcceptWrapper:         c.OnAcceptWrapper,
+		OnAcceptRejectedWrapper: c.OnAcceptRejectedWrapper,
+		OnBasicAuthWrapper:      c.OnBasicAuthWrapper,
+		OnEnhancedAuthWrapper:   c.OnEnhancedAuthWrapper,
+		OnClosedWrapper:         c.OnClosedWrapper,
+	}
+}
+
+func (c *ConnLimit) OnAcceptWrapper(pre server.OnAccept) server.OnAccept {
+	return func(ctx context.Context, conn net.Conn) bool {
+		if !pre(ctx, conn) {
+			return false
+		}
+		return c.allowConn(conn, time.Now())
+	}
+}
+
+// OnAcceptRejectedWrapper releases the connection counted by OnAcceptWrapper,
+// if it is rejected by other plugins or the server fails to serve it.
+func (c *ConnLimit) OnAcceptRejectedWrapper(pre server.OnAcceptRejected) server.OnAcceptRejected {
+	return func(ctx context.Context, conn net.Conn) {
+		pre(ctx, conn)
+		c.releaseConn(conn)
+	}
+}
+
+// clientIP returns the remote IP of the client.
+func clientIP(client server.Client) string {
+	if conn := client.Connection(); conn != nil {
+		return remoteIP(conn.RemoteAddr())
+	}
+	return ""
+}
+
+// bannedError returns the error that rejects the banned client.
+func bannedError(client server.Client, b *ban) error {
+	log.Debug("banned client rejected",
+		zap.String("type", b.typ),
+		zap.String("value", b.value),
+		zap.String("remote_ip", clientIP(client)))
+	if client.Version() == packets.Version5 {
+		return &codes.Error{
+			Code: codes.Banned,
+			ErrorDetails: codes.ErrorDetails{
+				ReasonString: []byte(b.reason),
+			},
+		}
+	}
+	return &codes.Error{
+		Code: codes.V3NotAuthorized,
+	}
+}
+
+func (c *ConnLimit) OnBasicAuthWrapper(pre server.OnBasicAuth) server.OnBasicAuth {
+	return func(ctx context.Context, client server.Client, req *server.ConnectRequest) (err error) {
+		ip, clientID := clientIP(client), string(req.Connect.ClientID)
+		now := time.Now()
+		if b := c.banned(ip, clientID, now); b != nil {
+			return bannedError(client, b)
+		}
+		err = pre(ctx, client, req)
+		if err != nil {
+			c.authFailed(ip, clientID, now)
+		}
+		return err
+	}
+}
+
+func (c *ConnLimit) OnEnhancedAuthWrapper(pre server.OnEnhancedAuth) server.OnEnhancedAuth {
+	return func(ctx context.Context, client server.Client, req *server.ConnectRequest) (resp *server.EnhancedAuthResponse, err error) {
+		ip, clientID := clientIP(client), string(req.Connect.ClientID)
+		if b := c.banned(ip, clientID, time.Now()); b != nil {
+			return nil, bannedError(client, b)
+		}
+		resp, err = pre(ctx, client, req)
+		if err != nil {
+			c.authFailed(ip, clientID, time.Now())
+			return resp, err
+		}
+		if resp != nil && resp.OnAuth != nil {
+			// the failures in the following AUTH exchanges are counted as well.
+			onAuth := resp.OnAuth
+			resp.OnAuth = func(ctx context.Context, client server.Client, req *server.AuthRequest) (*server.AuthResponse, error) {
+				authResp, err := onAuth(ctx, client, req)
+				if err != nil {
+					c.authFailed(ip, clientID, time.Now())
+				}
+				return authResp, err
+			}
+		}
+		return resp, nil
+	}
+}
+
+func (c *ConnLimit) OnClosedWrapper(pre server.OnClosed) server.OnClosed {
+	return func(ctx context.Context, client server.Client, err error) {
+		pre(ctx, client, err)
+		c.releaseConn(client.Connection())
+	}
+}
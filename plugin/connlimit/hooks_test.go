@@ -0,0 +1,147 @@
+package connlimit
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func TestConnLimit_OnBasicAuthWrapper(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	c := newTestConnLimit(t, Config{
+		AuthFailure: AuthFailure{
+			MaxFailures: 2,
+			Window:      time.Minute,
+			BanDuration: time.Hour,
+		},
+	})
+	conn := newTestConn("127.0.0.1:1883", "127.0.0.1:10000")
+	newClient := func(version packets.Version) *server.MockClient {
+		client := server.NewMockClient(ctrl)
+		client.EXPECT().Connection().Return(conn).AnyTimes()
+		client.EXPECT().Version().Return(version).AnyTimes()
+		return client
+	}
+	authErr := errors.New("auth error")
+	var preCalled int
+	fn := c.OnBasicAuthWrapper(func(ctx context.Context, client server.Client, req *server.ConnectRequest) (err error) {
+		preCalled++
+		return authErr
+	})
+	req := &server.ConnectRequest{
+		Connect: &packets.Connect{
+			ClientID: []byte("cid"),
+		},
+	}
+	a.Equal(authErr, fn(context.Background(), newClient(packets.Version5), req))
+	a.Equal(authErr, fn(context.Background(), newClient(packets.Version5), req))
+	a.Equal(2, preCalled)
+
+	err := fn(context.Background(), newClient(packets.Version5), req)
+	a.Equal(codes.Banned, err.(*codes.Error).Code)
+	err = fn(context.Background(), newClient(packets.Version311), req)
+	a.Equal(codes.Code(codes.V3NotAuthorized), err.(*codes.Error).Code)
+	a.Equal(2, preCalled)
+}
+
+func TestConnLimit_OnAcceptWrapper_OnClosedWrapper(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	c := newTestConnLimit(t, Config{
+		MaxConnectionsPerListener: 1,
+	})
+	accept := c.OnAcceptWrapper(func(ctx context.Context, conn net.Conn) bool {
+		return true
+	})
+	closed := c.OnClosedWrapper(func(ctx context.Context, client server.Client, err error) {})
+	conn := newTestConn("127.0.0.1:1883", "127.0.0.1:10000")
+	a.True(accept(context.Background(), conn))
+	a.False(accept(context.Background(), newTestConn("127.0.0.1:1883", "127.0.0.1:10001")))
+
+	client := server.NewMockClient(ctrl)
+	client.EXPECT().Connection().Return(conn)
+	closed(context.Background(), client, nil)
+	a.True(accept(context.Background(), newTestConn("127.0.0.1:1883", "127.0.0.1:10001")))
+}
+
+func TestConnLimit_OnEnhancedAuthWrapper(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	c := newTestConnLimit(t, Config{
+		AuthFailure: AuthFailure{
+			MaxFailures: 2,
+			Window:      time.Minute,
+			BanDuration: time.Hour,
+		},
+	})
+	conn := newTestConn("127.0.0.1:1883", "127.0.0.1:10000")
+	client := server.NewMockClient(ctrl)
+	client.EXPECT().Connection().Return(conn).AnyTimes()
+	client.EXPECT().Version().Return(packets.Version5).AnyTimes()
+
+	authErr := errors.New("auth error")
+	fn := c.OnEnhancedAuthWrapper(func(ctx context.Context, client server.Client, req *server.ConnectRequest) (*server.EnhancedAuthResponse, error) {
+		if string(req.Connect.Properties.AuthMethod) == "continue" {
+			return &server.EnhancedAuthResponse{
+				Continue: true,
+				OnAuth: func(ctx context.Context, client server.Client, req *server.AuthRequest) (*server.AuthResponse, error) {
+					return nil, authErr
+				},
+			}, nil
+		}
+		return nil, authErr
+	})
+	newReq := func(method string) *server.ConnectRequest {
+		return &server.ConnectRequest{
+			Connect: &packets.Connect{
+				ClientID:   []byte("cid"),
+				Properties: &packets.Properties{AuthMethod: []byte(method)},
+			},
+		}
+	}
+	// the failure of the enhanced authentication is counted.
+	_, err := fn(context.Background(), client, newReq("fail"))
+	a.Equal(authErr, err)
+	// the failure in the following AUTH exchange is counted too.
+	resp, err := fn(context.Background(), client, newReq("continue"))
+	a.Nil(err)
+	_, err = resp.OnAuth(context.Background(), client, &server.AuthRequest{})
+	a.Equal(authErr, err)
+
+	_, err = fn(context.Background(), client, newReq("fail"))
+	a.Equal(codes.Banned, err.(*codes.Error).Code)
+}
+
+func TestConnLimit_OnAcceptRejectedWrapper(t *testing.T) {
+	a := assert.New(t)
+	c := newTestConnLimit(t, Config{
+		MaxConnectionsPerListener: 1,
+	})
+	accept := c.OnAcceptWrapper(func(ctx context.Context, conn net.Conn) bool {
+		return true
+	})
+	rejected := c.OnAcceptRejectedWrapper(func(ctx context.Context, conn net.Conn) {})
+	conn := newTestConn("127.0.0.1:1883", "127.0.0.1:10000")
+	a.True(accept(context.Background(), conn))
+	a.False(accept(context.Background(), newTestConn("127.0.0.1:1883", "127.0.0.1:10001")))
+
+	// the connection is rejected by other plugins after it is counted.
+	rejected(context.Background(), conn)
+	a.True(accept(context.Background(), newTestConn("127.0.0.1:1883", "127.0.0.1:10001")))
+	// the connections that are not counted are ignored.
+	rejected(context.Background(), conn)
+	a.False(accept(context.Background(), newTestConn("127.0.0.1:1883", "127.0.0.1:10002")))
+}
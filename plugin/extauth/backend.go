@@ -0,0 +1,250 @@
+package extauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+func newTLSConfig(cfg *TLSConfig) (*tls.Config, error) {
+	c := &tls.Config{
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+	if cfg.CAFile != "" {
+		b, err := ioutil.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		c.RootCAs = x509.NewCertPool()
+		if !c.RootCAs.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no valid certificates found in %s", cfg.CAFile)
+		}
+	}
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+	return c, nil
+}
+
+// httpBackend POSTs the check requests to the url in JSON.
+type httpBackend struct {
+	config *HTTPConfig
+	client *http.Client
+}
+
+// httpResponse is the JSON body of the http response.
+type httpResponse struct {
+	// Result is the decision. (allow | deny)
+	Result       string   `json:"result"`
+	Code         uint8    `json:"code"`
+	ReasonString string   `json:"reason_string"`
+	Options      *options `json:"options"`
+}
+
+func newHTTPBackend(cfg *HTTPConfig) (*httpBackend, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.TLS != nil {
+		tlsConfig, err := newTLSConfig(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	return &httpBackend{
+		config: cfg,
+		client: &http.Client{
+			Transport: transport,
+		},
+	}, nil
+}
+
+func (h *httpBackend) check(ctx context.Context, req *request) (*decision, error) {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, h.config.URL, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	for k, v := range h.config.Headers {
+		r.Header.Set(k, v)
+	}
+	resp, err := h.client.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		// drain the body to reuse the connection.
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	var body httpResponse
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid response: %s", err)
+	}
+	d := &decision{
+		code:         codes.Code(body.Code),
+		reasonString: body.ReasonString,
+		options:      body.Options,
+	}
+	switch body.Result {
+	case Allow:
+		d.allow = true
+	case Deny:
+	default:
+		return nil, fmt.Errorf("invalid result: %s", body.Result)
+	}
+	return d, nil
+}
+
+func (h *httpBackend) close() error {
+	h.client.CloseIdleConnections()
+	return nil
+}
+
+// grpcBackend sends the check requests to the Authorizer service.
+type grpcBackend struct {
+	conn   *grpc.ClientConn
+	client AuthorizerClient
+}
+
+func newGRPCBackend(cfg *GRPCConfig) (*grpcBackend, error) {
+	opt := grpc.WithInsecure()
+	if cfg.TLS != nil {
+		tlsConfig, err := newTLSConfig(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		opt = grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))
+	}
+	// the connection is established in the background.
+	conn, err := grpc.Dial(cfg.Target, opt)
+	if err != nil {
+		return nil, err
+	}
+	return &grpcBackend{
+		conn:   conn,
+		client: NewAuthorizerClient(conn),
+	}, nil
+}
+
+func (g *grpcBackend) check(ctx context.Context, req *request) (*decision, error) {
+	resp, err := g.client.Check(ctx, &CheckRequest{
+		Action:     req.Action,
+		ClientId:   req.ClientID,
+		Username:   req.Username,
+		Password:   []byte(req.Password),
+		RemoteAddr: req.RemoteAddr,
+		Topic:      req.Topic,
+		Qos:        uint32(req.QoS),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Code > 0xff {
+		return nil, fmt.Errorf("invalid code: %d", resp.Code)
+	}
+	d := &decision{
+		allow:        resp.Result == Result_ALLOW,
+		code:         codes.Code(resp.Code),
+		reasonString: resp.ReasonString,
+	}
+	if resp.Options != nil {
+		d.options, err = newOptions(resp.Options)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+func (g *grpcBackend) close() error {
+	return g.conn.Close()
+}
+
+// newOptions converts the AuthOptions message to options.
+func newOptions(o *AuthOptions) (*options, error) {
+	for name, v := range map[string]*wrappers.UInt32Value{
+		"receive_max":     o.ReceiveMax,
+		"topic_alias_max": o.TopicAliasMax,
+		"keep_alive":      o.KeepAlive,
+		"max_inflight":    o.MaxInflight,
+	} {
+		if v != nil && v.Value > math.MaxUint16 {
+			return nil, fmt.Errorf("invalid %s: %d", name, v.Value)
+		}
+	}
+	if o.MaximumQos != nil && o.MaximumQos.Value > uint32(packets.Qos2) {
+		return nil, fmt.Errorf("invalid maximum_qos: %d", o.MaximumQos.Value)
+	}
+	return &options{
+		SessionExpiry:        uint32Value(o.SessionExpiry),
+		ReceiveMax:           uint16Value(o.ReceiveMax),
+		MaximumQoS:           uint8Value(o.MaximumQos),
+		MaxPacketSize:        uint32Value(o.MaxPacketSize),
+		TopicAliasMax:        uint16Value(o.TopicAliasMax),
+		RetainAvailable:      boolValue(o.RetainAvailable),
+		WildcardSubAvailable: boolValue(o.WildcardSubAvailable),
+		SubIDAvailable:       boolValue(o.SubIdAvailable),
+		SharedSubAvailable:   boolValue(o.SharedSubAvailable),
+		KeepAlive:            uint16Value(o.KeepAlive),
+		MaxInflight:          uint16Value(o.MaxInflight),
+	}, nil
+}
+
+func uint32Value(v *wrappers.UInt32Value) *uint32 {
+	if v == nil {
+		return nil
+	}
+	return &v.Value
+}
+
+// uint16Value converts the value to uint16, the range is checked by newOptions.
+func uint16Value(v *wrappers.UInt32Value) *uint16 {
+	if v == nil {
+		return nil
+	}
+	u := uint16(v.Value)
+	return &u
+}
+
+// uint8Value converts the value to uint8, the range is checked by newOptions.
+func uint8Value(v *wrappers.UInt32Value) *uint8 {
+	if v == nil {
+		return nil
+	}
+	u := uint8(v.Value)
+	return &u
+}
+
+func boolValue(v *wrappers.BoolValue) *bool {
+	if v == nil {
+		return nil
+	}
+	return &v.Value
+}
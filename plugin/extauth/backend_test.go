@@ -0,0 +1,183 @@
+package extauth
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+)
+
+func uint16Ptr(v uint16) *uint16 {
+	return &v
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
+
+func TestHTTPBackend_check(t *testing.T) {
+	a := assert.New(t)
+	var got *request
+	var header http.Header
+	var resp string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = &request{}
+		header = r.Header
+		if err := json.NewDecoder(r.Body).Decode(got); err != nil {
+			t.Error(err)
+		}
+		if resp == "" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(resp))
+	}))
+	defer srv.Close()
+	b, err := newHTTPBackend(&HTTPConfig{
+		URL:     srv.URL,
+		Headers: map[string]string{"Authorization": "Bearer token"},
+	})
+	a.Nil(err)
+	defer b.close()
+
+	req := &request{
+		Action:     ActionConnect,
+		ClientID:   "cid",
+		Username:   "user",
+		Password:   "pass",
+		RemoteAddr: "127.0.0.1:1883",
+	}
+	resp = `{"result":"allow","options":{"keep_alive":30,"retain_available":false}}`
+	d, err := b.check(context.Background(), req)
+	a.Nil(err)
+	a.Equal(req, got)
+	a.Equal("application/json", header.Get("Content-Type"))
+	a.Equal("Bearer token", header.Get("Authorization"))
+	a.Equal(&decision{
+		allow: true,
+		options: &options{
+			KeepAlive:       uint16Ptr(30),
+			RetainAvailable: boolPtr(false),
+		},
+	}, d)
+
+	resp = `{"result":"deny","code":134,"reason_string":"bad password"}`
+	d, err = b.check(context.Background(), req)
+	a.Nil(err)
+	a.Equal(&decision{
+		code:         codes.BadUserNameOrPassword,
+		reasonString: "bad password",
+	}, d)
+
+	for _, v := range []string{"", `{"result":"ignore"}`, `{"result":"deny","code":256}`, "{"} {
+		resp = v
+		_, err = b.check(context.Background(), req)
+		a.NotNil(err, v)
+	}
+}
+
+func TestHTTPBackend_timeout(t *testing.T) {
+	a := assert.New(t)
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer srv.Close()
+	defer close(done)
+	b, err := newHTTPBackend(&HTTPConfig{URL: srv.URL})
+	a.Nil(err)
+	defer b.close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = b.check(ctx, &request{Action: ActionConnect})
+	a.NotNil(err)
+}
+
+// testAuthorizer is a grpc Authorizer which returns resp.
+type testAuthorizer struct {
+	UnimplementedAuthorizerServer
+	req  *CheckRequest
+	resp *CheckResponse
+}
+
+func (s *testAuthorizer) Check(ctx context.Context, req *CheckRequest) (*CheckResponse, error) {
+	s.req = req
+	return s.resp, nil
+}
+
+func TestGRPCBackend_check(t *testing.T) {
+	a := assert.New(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	a.Nil(err)
+	authorizer := &testAuthorizer{}
+	srv := grpc.NewServer()
+	RegisterAuthorizerServer(srv, authorizer)
+	go srv.Serve(ln)
+	defer srv.Stop()
+
+	b, err := newGRPCBackend(&GRPCConfig{Target: ln.Addr().String()})
+	a.Nil(err)
+	defer b.close()
+
+	authorizer.resp = &CheckResponse{
+		Result: Result_ALLOW,
+		Options: &AuthOptions{
+			KeepAlive:       &wrappers.UInt32Value{Value: 30},
+			RetainAvailable: &wrappers.BoolValue{Value: false},
+		},
+	}
+	d, err := b.check(context.Background(), &request{
+		Action:     ActionSubscribe,
+		ClientID:   "cid",
+		Username:   "user",
+		RemoteAddr: "127.0.0.1:1883",
+		Topic:      "a/b",
+		QoS:        1,
+	})
+	a.Nil(err)
+	a.Equal("subscribe", authorizer.req.Action)
+	a.Equal("cid", authorizer.req.ClientId)
+	a.Equal("user", authorizer.req.Username)
+	a.Equal("127.0.0.1:1883", authorizer.req.RemoteAddr)
+	a.Equal("a/b", authorizer.req.Topic)
+	a.EqualValues(1, authorizer.req.Qos)
+	a.Equal(&decision{
+		allow: true,
+		options: &options{
+			KeepAlive:       uint16Ptr(30),
+			RetainAvailable: boolPtr(false),
+		},
+	}, d)
+
+	authorizer.resp = &CheckResponse{
+		Result:       Result_DENY,
+		Code:         uint32(codes.QuotaExceeded),
+		ReasonString: "quota exceeded",
+	}
+	d, err = b.check(context.Background(), &request{Action: ActionPublish})
+	a.Nil(err)
+	a.Equal(&decision{
+		code:         codes.QuotaExceeded,
+		reasonString: "quota exceeded",
+	}, d)
+
+	for _, v := range []*CheckResponse{
+		{Code: 256},
+		{Result: Result_ALLOW, Options: &AuthOptions{KeepAlive: &wrappers.UInt32Value{Value: 65536}}},
+		{Result: Result_ALLOW, Options: &AuthOptions{MaximumQos: &wrappers.UInt32Value{Value: 3}}},
+	} {
+		authorizer.resp = v
+		_, err = b.check(context.Background(), &request{Action: ActionConnect})
+		a.NotNil(err)
+	}
+}
@@ -0,0 +1,87 @@
+package extauth
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// cache is a LRU cache of the decisions, the entries expire after ttl.
+type cache struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	ll    *list.List
+	items map[string]*list.Element
+	// now is used to mock the time in tests.
+	now func() time.Time
+}
+
+type cacheEntry struct {
+	key      string
+	decision *decision
+	expiry   time.Time
+}
+
+// newCache returns a new cache, the cache is disabled if size is 0.
+func newCache(size int, ttl time.Duration) *cache {
+	return &cache{
+		size:  size,
+		ttl:   ttl,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+		now:   time.Now,
+	}
+}
+
+func (c *cache) get(key string) (*decision, bool) {
+	if c.size == 0 {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if !c.now().Before(entry.expiry) {
+		c.ll.Remove(elem)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.ll.MoveToFront(elem)
+	return entry.decision, true
+}
+
+func (c *cache) set(key string, d *decision) {
+	if c.size == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expiry := c.now().Add(c.ttl)
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.decision = d
+		entry.expiry = expiry
+		c.ll.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&cacheEntry{
+		key:      key,
+		decision: d,
+		expiry:   expiry,
+	})
+	if c.ll.Len() > c.size {
+		elem := c.ll.Back()
+		c.ll.Remove(elem)
+		delete(c.items, elem.Value.(*cacheEntry).key)
+	}
+}
+
+func (c *cache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
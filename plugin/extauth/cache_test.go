@@ -0,0 +1,52 @@
+package extauth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	a := assert.New(t)
+	now := time.Now()
+	c := newCache(2, time.Minute)
+	c.now = func() time.Time {
+		return now
+	}
+	allow := &decision{allow: true}
+	deny := &decision{}
+
+	c.set("a", allow)
+	c.set("b", deny)
+	d, ok := c.get("a")
+	a.True(ok)
+	a.Equal(allow, d)
+	// b is the least recently used.
+	c.set("c", allow)
+	a.Equal(2, c.len())
+	_, ok = c.get("b")
+	a.False(ok)
+	_, ok = c.get("a")
+	a.True(ok)
+
+	// update the existing entry.
+	c.set("a", deny)
+	d, _ = c.get("a")
+	a.Equal(deny, d)
+	a.Equal(2, c.len())
+
+	now = now.Add(time.Minute)
+	_, ok = c.get("a")
+	a.False(ok)
+	a.Equal(1, c.len())
+}
+
+func TestCache_disabled(t *testing.T) {
+	a := assert.New(t)
+	c := newCache(0, 0)
+	c.set("a", &decision{allow: true})
+	_, ok := c.get("a")
+	a.False(ok)
+	a.Equal(0, c.len())
+}
@@ -0,0 +1,156 @@
+package extauth
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// The backends.
+const (
+	BackendHTTP = "http"
+	BackendGRPC = "grpc"
+)
+
+// The policies when the backend fails.
+const (
+	Allow = "allow"
+	Deny  = "deny"
+)
+
+// Config is the configuration for the extauth plugin.
+type Config struct {
+	// Backend is the type of the external service. (http | grpc)
+	Backend string     `yaml:"backend"`
+	HTTP    HTTPConfig `yaml:"http"`
+	GRPC    GRPCConfig `yaml:"grpc"`
+	// Timeout is the timeout of each check request.
+	Timeout time.Duration `yaml:"timeout"`
+	// OnFailure is the decision when the backend fails or times out. (allow | deny)
+	OnFailure string `yaml:"on_failure"`
+	// Connect indicates whether to authenticate the clients in OnBasicAuth.
+	Connect bool `yaml:"connect"`
+	// Subscribe indicates whether to authorize the subscriptions in OnSubscribe.
+	Subscribe bool `yaml:"subscribe"`
+	// Publish indicates whether to authorize the messages in OnMsgArrived.
+	Publish bool        `yaml:"publish"`
+	Cache   CacheConfig `yaml:"cache"`
+}
+
+// HTTPConfig is the configuration for the http backend.
+type HTTPConfig struct {
+	// URL is the endpoint to POST the check requests to.
+	URL string `yaml:"url"`
+	// Headers is the additional http headers of the requests.
+	Headers map[string]string `yaml:"headers"`
+	TLS     *TLSConfig        `yaml:"tls"`
+}
+
+// GRPCConfig is the configuration for the grpc backend.
+type GRPCConfig struct {
+	// Target is the address of the Authorizer service.
+	Target string `yaml:"target"`
+	// TLS is the tls configuration, the connection is insecure if it is nil.
+	TLS *TLSConfig `yaml:"tls"`
+}
+
+// TLSConfig is the tls configuration for connecting to the backend.
+type TLSConfig struct {
+	// CAFile is the CA certificates used to verify the backend, use the system pool if empty.
+	CAFile string `yaml:"ca_file"`
+	// CertFile and KeyFile is the client certificate, optional.
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	ServerName         string `yaml:"server_name"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// CacheConfig is the configuration for the decision cache.
+type CacheConfig struct {
+	// Size is the maximum number of cached decisions, 0 means disable the cache.
+	// The least recently used decision is evicted when the cache is full.
+	Size int `yaml:"size"`
+	// TTL is how long a decision is cached.
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// Validate validates the configuration, and return an error if it is invalid.
+func (c *Config) Validate() error {
+	switch c.Backend {
+	case BackendHTTP:
+		u, err := url.Parse(c.HTTP.URL)
+		if err != nil {
+			return fmt.Errorf("invalid http.url: %s", err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid http.url: %s", c.HTTP.URL)
+		}
+		if err := c.HTTP.TLS.validate(); err != nil {
+			return fmt.Errorf("invalid http.tls: %s", err)
+		}
+	case BackendGRPC:
+		if c.GRPC.Target == "" {
+			return errors.New("grpc.target must be set")
+		}
+		if err := c.GRPC.TLS.validate(); err != nil {
+			return fmt.Errorf("invalid grpc.tls: %s", err)
+		}
+	default:
+		return fmt.Errorf("invalid backend: %s", c.Backend)
+	}
+	if c.Timeout <= 0 {
+		return errors.New("timeout must be greater than 0")
+	}
+	if c.OnFailure != Allow && c.OnFailure != Deny {
+		return fmt.Errorf("invalid on_failure: %s", c.OnFailure)
+	}
+	if !c.Connect && !c.Subscribe && !c.Publish {
+		return errors.New("at least one of connect, subscribe and publish must be set")
+	}
+	if c.Cache.Size < 0 {
+		return errors.New("cache.size cannot be negative")
+	}
+	if c.Cache.Size > 0 && c.Cache.TTL <= 0 {
+		return errors.New("cache.ttl must be greater than 0")
+	}
+	return nil
+}
+
+func (t *TLSConfig) validate() error {
+	if t != nil && (t.CertFile == "") != (t.KeyFile == "") {
+		return errors.New("cert_file and key_file must be set together")
+	}
+	return nil
+}
+
+// DefaultConfig is the default configuration.
+var DefaultConfig = Config{
+	Backend: BackendHTTP,
+	HTTP: HTTPConfig{
+		URL: "http://127.0.0.1:8080/mqtt/auth",
+	},
+	Timeout:   3 * time.Second,
+	OnFailure: Deny,
+	Connect:   true,
+	Subscribe: true,
+	Publish:   true,
+	Cache: CacheConfig{
+		Size: 10000,
+		TTL:  time.Minute,
+	},
+}
+
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type cfg Config
+	var v = &struct {
+		ExtAuth cfg `yaml:"extauth"`
+	}{
+		ExtAuth: cfg(DefaultConfig),
+	}
+	if err := unmarshal(v); err != nil {
+		return err
+	}
+	*c = Config(v.ExtAuth)
+	return nil
+}
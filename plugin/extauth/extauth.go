@@ -0,0 +1,223 @@
+package extauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+var _ server.Plugin = (*ExtAuth)(nil)
+
+const Name = "extauth"
+
+func init() {
+	server.RegisterPlugin(Name, New)
+	config.RegisterDefaultPluginConfig(Name, &DefaultConfig)
+}
+
+func New(config config.Config) (server.Plugin, error) {
+	cfg := config.Plugins[Name].(*Config)
+	return &ExtAuth{
+		config: cfg,
+		cache:  newCache(cfg.Cache.Size, cfg.Cache.TTL),
+	}, nil
+}
+
+var log *zap.Logger
+
+// The actions of the check requests.
+const (
+	ActionConnect   = "connect"
+	ActionSubscribe = "subscribe"
+	ActionPublish   = "publish"
+)
+
+// ExtAuth delegates the authentication and authorization decisions to an external http or grpc service.
+type ExtAuth struct {
+	config  *Config
+	backend backend
+	cache   *cache
+}
+
+// backend sends the check requests to the external service.
+type backend interface {
+	check(ctx context.Context, req *request) (*decision, error)
+	close() error
+}
+
+// request is the check request, it is encoded as the JSON body of the http backend.
+type request struct {
+	Action     string `json:"action"`
+	ClientID   string `json:"client_id"`
+	Username   string `json:"username"`
+	Password   string `json:"password,omitempty"`
+	RemoteAddr string `json:"remote_addr"`
+	Topic      string `json:"topic,omitempty"`
+	QoS        uint8  `json:"qos"`
+}
+
+// key returns the cache key of the request.
+// The port of the remote address is ignored, and the password is hashed so that it is not kept in memory.
+func (r *request) key() string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	h := sha256.New()
+	for _, v := range []string{r.Action, r.ClientID, r.Username, r.Password, host, r.Topic} {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+	h.Write([]byte{r.QoS})
+	return string(h.Sum(nil))
+}
+
+// decision is the result of the check request.
+type decision struct {
+	allow bool
+	// code and reasonString are returned to the client if the request is denied.
+	code         codes.Code
+	reasonString string
+	// options overrides the server.AuthOptions of the client, only for the connect action.
+	options *options
+}
+
+// validate validates the decision returned by the backend.
+func (d *decision) validate() error {
+	if !d.allow && d.code != 0 && d.code < codes.UnspecifiedError {
+		return fmt.Errorf("invalid code: %d", d.code)
+	}
+	if d.options != nil {
+		return d.options.validate()
+	}
+	return nil
+}
+
+// options overrides the server.AuthOptions of the client.
+// The absent fields do not change the corresponding server.AuthOptions.
+type options struct {
+	SessionExpiry        *uint32 `json:"session_expiry"`
+	ReceiveMax           *uint16 `json:"receive_max"`
+	MaximumQoS           *uint8  `json:"maximum_qos"`
+	MaxPacketSize        *uint32 `json:"max_packet_size"`
+	TopicAliasMax        *uint16 `json:"topic_alias_max"`
+	RetainAvailable      *bool   `json:"retain_available"`
+	WildcardSubAvailable *bool   `json:"wildcard_sub_available"`
+	SubIDAvailable       *bool   `json:"sub_id_available"`
+	SharedSubAvailable   *bool   `json:"shared_sub_available"`
+	KeepAlive            *uint16 `json:"keep_alive"`
+	MaxInflight          *uint16 `json:"max_inflight"`
+}
+
+func (o *options) apply(opts *server.AuthOptions) {
+	if o.SessionExpiry != nil {
+		opts.SessionExpiry = *o.SessionExpiry
+	}
+	if o.ReceiveMax != nil {
+		opts.ReceiveMax = *o.ReceiveMax
+	}
+	if o.MaximumQoS != nil {
+		opts.MaximumQoS = *o.MaximumQoS
+	}
+	if o.MaxPacketSize != nil {
+		opts.MaxPacketSize = *o.MaxPacketSize
+	}
+	if o.TopicAliasMax != nil {
+		opts.TopicAliasMax = *o.TopicAliasMax
+	}
+	if o.RetainAvailable != nil {
+		opts.RetainAvailable = *o.RetainAvailable
+	}
+	if o.WildcardSubAvailable != nil {
+		opts.WildcardSubAvailable = *o.WildcardSubAvailable
+	}
+	if o.SubIDAvailable != nil {
+		opts.SubIDAvailable = *o.SubIDAvailable
+	}
+	if o.SharedSubAvailable != nil {
+		opts.SharedSubAvailable = *o.SharedSubAvailable
+	}
+	if o.KeepAlive != nil {
+		opts.KeepAlive = *o.KeepAlive
+	}
+	if o.MaxInflight != nil {
+		opts.MaxInflight = *o.MaxInflight
+	}
+}
+
+func (o *options) validate() error {
+	if o.MaximumQoS != nil && *o.MaximumQoS > packets.Qos2 {
+		return fmt.Errorf("invalid maximum_qos: %d", *o.MaximumQoS)
+	}
+	if o.ReceiveMax != nil && *o.ReceiveMax == 0 {
+		return errors.New("invalid receive_max: 0")
+	}
+	if o.MaxPacketSize != nil && *o.MaxPacketSize == 0 {
+		return errors.New("invalid max_packet_size: 0")
+	}
+	if o.MaxInflight != nil && *o.MaxInflight == 0 {
+		return errors.New("invalid max_inflight: 0")
+	}
+	return nil
+}
+
+func (e *ExtAuth) Load(service server.Server) (err error) {
+	log = server.LoggerWithField(zap.String("plugin", Name))
+	switch e.config.Backend {
+	case BackendHTTP:
+		e.backend, err = newHTTPBackend(&e.config.HTTP)
+	case BackendGRPC:
+		e.backend, err = newGRPCBackend(&e.config.GRPC)
+	}
+	return err
+}
+
+func (e *ExtAuth) Unload() error {
+	return e.backend.close()
+}
+
+func (e *ExtAuth) Name() string {
+	return Name
+}
+
+// check returns the decision of the request from the cache or the backend.
+// If the backend fails, the decision is made by the on_failure policy and is not cached.
+func (e *ExtAuth) check(ctx context.Context, req *request) *decision {
+	key := req.key()
+	if d, ok := e.cache.get(key); ok {
+		return d
+	}
+	ctx, cancel := context.WithTimeout(ctx, e.config.Timeout)
+	defer cancel()
+	d, err := e.backend.check(ctx, req)
+	if err == nil {
+		err = d.validate()
+	}
+	if err != nil {
+		log.Warn("check request failed",
+			zap.String("action", req.Action),
+			zap.String("client_id", req.ClientID),
+			zap.String("username", req.Username),
+			zap.String("on_failure", e.config.OnFailure),
+			zap.Error(err))
+		return &decision{allow: e.config.OnFailure == Allow}
+	}
+	e.cache.set(key, d)
+	return d
+}
+
+func remoteAddr(client server.Client) string {
+	if conn := client.Connection(); conn != nil {
+		return conn.RemoteAddr().String()
+	}
+	return ""
+}
@@ -0,0 +1,574 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.22.0
+// 	protoc        v3.13.0
+// source: extauth.proto
+
+package extauth
+
+import (
+	proto "github.com/golang/protobuf/proto"
+	wrappers "github.com/golang/protobuf/ptypes/wrappers"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// This is a compile-time assertion that a sufficiently up-to-date version
+// of the legacy proto package is being used.
+const _ = proto.ProtoPackageIsVersion4
+
+type Result int32
+
+const (
+	Result_DENY  Result = 0
+	Result_ALLOW Result = 1
+)
+
+// Enum value maps for Result.
+var (
+	Result_name = map[int32]string{
+		0: "DENY",
+		1: "ALLOW",
+	}
+	Result_value = map[string]int32{
+		"DENY":  0,
+		"ALLOW": 1,
+	}
+)
+
+func (x Result) Enum() *Result {
+	p := new(Result)
+	*p = x
+	return p
+}
+
+func (x Result) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Result) Descriptor() protoreflect.EnumDescriptor {
+	return file_extauth_proto_enumTypes[0].Descriptor()
+}
+
+func (Result) Type() protoreflect.EnumType {
+	return &file_extauth_proto_enumTypes[0]
+}
+
+func (x Result) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Result.Descriptor instead.
+func (Result) EnumDescriptor() ([]byte, []int) {
+	return file_extauth_proto_rawDescGZIP(), []int{0}
+}
+
+type CheckRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// connect | subscribe | publish
+	Action   string `protobuf:"bytes,1,opt,name=action,proto3" json:"action,omitempty"`
+	ClientId string `protobuf:"bytes,2,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	Username string `protobuf:"bytes,3,opt,name=username,proto3" json:"username,omitempty"`
+	// The password in the CONNECT packet, only set for the connect action.
+	Password   []byte `protobuf:"bytes,4,opt,name=password,proto3" json:"password,omitempty"`
+	RemoteAddr string `protobuf:"bytes,5,opt,name=remote_addr,json=remoteAddr,proto3" json:"remote_addr,omitempty"`
+	// The topic filter for the subscribe action, or the topic name for the publish action.
+	Topic string `protobuf:"bytes,6,opt,name=topic,proto3" json:"topic,omitempty"`
+	// The requested QoS of the subscription, or the QoS of the message.
+	Qos uint32 `protobuf:"varint,7,opt,name=qos,proto3" json:"qos,omitempty"`
+}
+
+func (x *CheckRequest) Reset() {
+	*x = CheckRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_extauth_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CheckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckRequest) ProtoMessage() {}
+
+func (x *CheckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_extauth_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckRequest.ProtoReflect.Descriptor instead.
+func (*CheckRequest) Descriptor() ([]byte, []int) {
+	return file_extauth_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CheckRequest) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *CheckRequest) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *CheckRequest) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *CheckRequest) GetPassword() []byte {
+	if x != nil {
+		return x.Password
+	}
+	return nil
+}
+
+func (x *CheckRequest) GetRemoteAddr() string {
+	if x != nil {
+		return x.RemoteAddr
+	}
+	return ""
+}
+
+func (x *CheckRequest) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *CheckRequest) GetQos() uint32 {
+	if x != nil {
+		return x.Qos
+	}
+	return 0
+}
+
+type CheckResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Result Result `protobuf:"varint,1,opt,name=result,proto3,enum=gmqtt.extauth.api.Result" json:"result,omitempty"`
+	// The reason code returned to the client if the result is DENY, 0 means the default NotAuthorized(0x87) code.
+	Code uint32 `protobuf:"varint,2,opt,name=code,proto3" json:"code,omitempty"`
+	// The reason string returned to v5 clients if the result is DENY.
+	ReasonString string `protobuf:"bytes,3,opt,name=reason_string,json=reasonString,proto3" json:"reason_string,omitempty"`
+	// The options that override the settings of the client, only used for the connect action.
+	Options *AuthOptions `protobuf:"bytes,4,opt,name=options,proto3" json:"options,omitempty"`
+}
+
+func (x *CheckResponse) Reset() {
+	*x = CheckResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_extauth_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CheckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CheckResponse) ProtoMessage() {}
+
+func (x *CheckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_extauth_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CheckResponse.ProtoReflect.Descriptor instead.
+func (*CheckResponse) Descriptor() ([]byte, []int) {
+	return file_extauth_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CheckResponse) GetResult() Result {
+	if x != nil {
+		return x.Result
+	}
+	return Result_DENY
+}
+
+func (x *CheckResponse) GetCode() uint32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *CheckResponse) GetReasonString() string {
+	if x != nil {
+		return x.ReasonString
+	}
+	return ""
+}
+
+func (x *CheckResponse) GetOptions() *AuthOptions {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+// AuthOptions overrides the settings of the client, the absent fields remain unchanged.
+type AuthOptions struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	SessionExpiry        *wrappers.UInt32Value `protobuf:"bytes,1,opt,name=session_expiry,json=sessionExpiry,proto3" json:"session_expiry,omitempty"`
+	ReceiveMax           *wrappers.UInt32Value `protobuf:"bytes,2,opt,name=receive_max,json=receiveMax,proto3" json:"receive_max,omitempty"`
+	MaximumQos           *wrappers.UInt32Value `protobuf:"bytes,3,opt,name=maximum_qos,json=maximumQos,proto3" json:"maximum_qos,omitempty"`
+	MaxPacketSize        *wrappers.UInt32Value `protobuf:"bytes,4,opt,name=max_packet_size,json=maxPacketSize,proto3" json:"max_packet_size,omitempty"`
+	TopicAliasMax        *wrappers.UInt32Value `protobuf:"bytes,5,opt,name=topic_alias_max,json=topicAliasMax,proto3" json:"topic_alias_max,omitempty"`
+	RetainAvailable      *wrappers.BoolValue   `protobuf:"bytes,6,opt,name=retain_available,json=retainAvailable,proto3" json:"retain_available,omitempty"`
+	WildcardSubAvailable *wrappers.BoolValue   `protobuf:"bytes,7,opt,name=wildcard_sub_available,json=wildcardSubAvailable,proto3" json:"wildcard_sub_available,omitempty"`
+	SubIdAvailable       *wrappers.BoolValue   `protobuf:"bytes,8,opt,name=sub_id_available,json=subIdAvailable,proto3" json:"sub_id_available,omitempty"`
+	SharedSubAvailable   *wrappers.BoolValue   `protobuf:"bytes,9,opt,name=shared_sub_available,json=sharedSubAvailable,proto3" json:"shared_sub_available,omitempty"`
+	KeepAlive            *wrappers.UInt32Value `protobuf:"bytes,10,opt,name=keep_alive,json=keepAlive,proto3" json:"keep_alive,omitempty"`
+	MaxInflight          *wrappers.UInt32Value `protobuf:"bytes,11,opt,name=max_inflight,json=maxInflight,proto3" json:"max_inflight,omitempty"`
+}
+
+func (x *AuthOptions) Reset() {
+	*x = AuthOptions{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_extauth_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AuthOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthOptions) ProtoMessage() {}
+
+func (x *AuthOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_extauth_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthOptions.ProtoReflect.Descriptor instead.
+func (*AuthOptions) Descriptor() ([]byte, []int) {
+	return file_extauth_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *AuthOptions) GetSessionExpiry() *wrappers.UInt32Value {
+	if x != nil {
+		return x.SessionExpiry
+	}
+	return nil
+}
+
+func (x *AuthOptions) GetReceiveMax() *wrappers.UInt32Value {
+	if x != nil {
+		return x.ReceiveMax
+	}
+	return nil
+}
+
+func (x *AuthOptions) GetMaximumQos() *wrappers.UInt32Value {
+	if x != nil {
+		return x.MaximumQos
+	}
+	return nil
+}
+
+func (x *AuthOptions) GetMaxPacketSize() *wrappers.UInt32Value {
+	if x != nil {
+		return x.MaxPacketSize
+	}
+	return nil
+}
+
+func (x *AuthOptions) GetTopicAliasMax() *wrappers.UInt32Value {
+	if x != nil {
+		return x.TopicAliasMax
+	}
+	return nil
+}
+
+func (x *AuthOptions) GetRetainAvailable() *wrappers.BoolValue {
+	if x != nil {
+		return x.RetainAvailable
+	}
+	return nil
+}
+
+func (x *AuthOptions) GetWildcardSubAvailable() *wrappers.BoolValue {
+	if x != nil {
+		return x.WildcardSubAvailable
+	}
+	return nil
+}
+
+func (x *AuthOptions) GetSubIdAvailable() *wrappers.BoolValue {
+	if x != nil {
+		return x.SubIdAvailable
+	}
+	return nil
+}
+
+func (x *AuthOptions) GetSharedSubAvailable() *wrappers.BoolValue {
+	if x != nil {
+		return x.SharedSubAvailable
+	}
+	return nil
+}
+
+func (x *AuthOptions) GetKeepAlive() *wrappers.UInt32Value {
+	if x != nil {
+		return x.KeepAlive
+	}
+	return nil
+}
+
+func (x *AuthOptions) GetMaxInflight() *wrappers.UInt32Value {
+	if x != nil {
+		return x.MaxInflight
+	}
+	return nil
+}
+
+var File_extauth_proto protoreflect.FileDescriptor
+
+var file_extauth_proto_rawDesc = []byte{
+	0x0a, 0x0d, 0x65, 0x78, 0x74, 0x61, 0x75, 0x74, 0x68, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12,
+	0x11, 0x67, 0x6d, 0x71, 0x74, 0x74, 0x2e, 0x65, 0x78, 0x74, 0x61, 0x75, 0x74, 0x68, 0x2e, 0x61,
+	0x70, 0x69, 0x1a, 0x1e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x62, 0x75, 0x66, 0x2f, 0x77, 0x72, 0x61, 0x70, 0x70, 0x65, 0x72, 0x73, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x22, 0xc4, 0x01, 0x0a, 0x0c, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1b, 0x0a, 0x09, 0x63,
+	0x6c, 0x69, 0x65, 0x6e, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08,
+	0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x49, 0x64, 0x12, 0x1a, 0x0a, 0x08, 0x75, 0x73, 0x65, 0x72,
+	0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x75, 0x73, 0x65, 0x72,
+	0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x70, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64,
+	0x12, 0x1f, 0x0a, 0x0b, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x41, 0x64, 0x64,
+	0x72, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f, 0x70, 0x69, 0x63, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x74, 0x6f, 0x70, 0x69, 0x63, 0x12, 0x10, 0x0a, 0x03, 0x71, 0x6f, 0x73, 0x18, 0x07,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x03, 0x71, 0x6f, 0x73, 0x22, 0xb5, 0x01, 0x0a, 0x0d, 0x43, 0x68,
+	0x65, 0x63, 0x6b, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x31, 0x0a, 0x06, 0x72,
+	0x65, 0x73, 0x75, 0x6c, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x19, 0x2e, 0x67, 0x6d,
+	0x71, 0x74, 0x74, 0x2e, 0x65, 0x78, 0x74, 0x61, 0x75, 0x74, 0x68, 0x2e, 0x61, 0x70, 0x69, 0x2e,
+	0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x52, 0x06, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x12,
+	0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x63, 0x6f,
+	0x64, 0x65, 0x12, 0x23, 0x0a, 0x0d, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x5f, 0x73, 0x74, 0x72,
+	0x69, 0x6e, 0x67, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x72, 0x65, 0x61, 0x73, 0x6f,
+	0x6e, 0x53, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x12, 0x38, 0x0a, 0x07, 0x6f, 0x70, 0x74, 0x69, 0x6f,
+	0x6e, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x67, 0x6d, 0x71, 0x74, 0x74,
+	0x2e, 0x65, 0x78, 0x74, 0x61, 0x75, 0x74, 0x68, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x41, 0x75, 0x74,
+	0x68, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x07, 0x6f, 0x70, 0x74, 0x69, 0x6f, 0x6e,
+	0x73, 0x22, 0x87, 0x06, 0x0a, 0x0b, 0x41, 0x75, 0x74, 0x68, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e,
+	0x73, 0x12, 0x43, 0x0a, 0x0e, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x65, 0x78, 0x70,
+	0x69, 0x72, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x67, 0x6f, 0x6f, 0x67,
+	0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x55, 0x49, 0x6e, 0x74,
+	0x33, 0x32, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x0d, 0x73, 0x65, 0x73, 0x73, 0x69, 0x6f, 0x6e,
+	0x45, 0x78, 0x70, 0x69, 0x72, 0x79, 0x12, 0x3d, 0x0a, 0x0b, 0x72, 0x65, 0x63, 0x65, 0x69, 0x76,
+	0x65, 0x5f, 0x6d, 0x61, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x55, 0x49,
+	0x6e, 0x74, 0x33, 0x32, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x0a, 0x72, 0x65, 0x63, 0x65, 0x69,
+	0x76, 0x65, 0x4d, 0x61, 0x78, 0x12, 0x3d, 0x0a, 0x0b, 0x6d, 0x61, 0x78, 0x69, 0x6d, 0x75, 0x6d,
+	0x5f, 0x71, 0x6f, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x67, 0x6f, 0x6f,
+	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x55, 0x49, 0x6e,
+	0x74, 0x33, 0x32, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x0a, 0x6d, 0x61, 0x78, 0x69, 0x6d, 0x75,
+	0x6d, 0x51, 0x6f, 0x73, 0x12, 0x44, 0x0a, 0x0f, 0x6d, 0x61, 0x78, 0x5f, 0x70, 0x61, 0x63, 0x6b,
+	0x65, 0x74, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e,
+	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e,
+	0x55, 0x49, 0x6e, 0x74, 0x33, 0x32, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x0d, 0x6d, 0x61, 0x78,
+	0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x44, 0x0a, 0x0f, 0x74, 0x6f,
+	0x70, 0x69, 0x63, 0x5f, 0x61, 0x6c, 0x69, 0x61, 0x73, 0x5f, 0x6d, 0x61, 0x78, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x55, 0x49, 0x6e, 0x74, 0x33, 0x32, 0x56, 0x61, 0x6c, 0x75,
+	0x65, 0x52, 0x0d, 0x74, 0x6f, 0x70, 0x69, 0x63, 0x41, 0x6c, 0x69, 0x61, 0x73, 0x4d, 0x61, 0x78,
+	0x12, 0x45, 0x0a, 0x10, 0x72, 0x65, 0x74, 0x61, 0x69, 0x6e, 0x5f, 0x61, 0x76, 0x61, 0x69, 0x6c,
+	0x61, 0x62, 0x6c, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f,
+	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x42, 0x6f, 0x6f,
+	0x6c, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x0f, 0x72, 0x65, 0x74, 0x61, 0x69, 0x6e, 0x41, 0x76,
+	0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x50, 0x0a, 0x16, 0x77, 0x69, 0x6c, 0x64, 0x63,
+	0x61, 0x72, 0x64, 0x5f, 0x73, 0x75, 0x62, 0x5f, 0x61, 0x76, 0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c,
+	0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x42, 0x6f, 0x6f, 0x6c, 0x56, 0x61,
+	0x6c, 0x75, 0x65, 0x52, 0x14, 0x77, 0x69, 0x6c, 0x64, 0x63, 0x61, 0x72, 0x64, 0x53, 0x75, 0x62,
+	0x41, 0x76, 0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x44, 0x0a, 0x10, 0x73, 0x75, 0x62,
+	0x5f, 0x69, 0x64, 0x5f, 0x61, 0x76, 0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x18, 0x08, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x42, 0x6f, 0x6f, 0x6c, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52,
+	0x0e, 0x73, 0x75, 0x62, 0x49, 0x64, 0x41, 0x76, 0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x12,
+	0x4c, 0x0a, 0x14, 0x73, 0x68, 0x61, 0x72, 0x65, 0x64, 0x5f, 0x73, 0x75, 0x62, 0x5f, 0x61, 0x76,
+	0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e,
+	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e,
+	0x42, 0x6f, 0x6f, 0x6c, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x12, 0x73, 0x68, 0x61, 0x72, 0x65,
+	0x64, 0x53, 0x75, 0x62, 0x41, 0x76, 0x61, 0x69, 0x6c, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x3b, 0x0a,
+	0x0a, 0x6b, 0x65, 0x65, 0x70, 0x5f, 0x61, 0x6c, 0x69, 0x76, 0x65, 0x18, 0x0a, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x1c, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x62, 0x75, 0x66, 0x2e, 0x55, 0x49, 0x6e, 0x74, 0x33, 0x32, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52,
+	0x09, 0x6b, 0x65, 0x65, 0x70, 0x41, 0x6c, 0x69, 0x76, 0x65, 0x12, 0x3f, 0x0a, 0x0c, 0x6d, 0x61,
+	0x78, 0x5f, 0x69, 0x6e, 0x66, 0x6c, 0x69, 0x67, 0x68, 0x74, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x1c, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
+	0x75, 0x66, 0x2e, 0x55, 0x49, 0x6e, 0x74, 0x33, 0x32, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x0b,
+	0x6d, 0x61, 0x78, 0x49, 0x6e, 0x66, 0x6c, 0x69, 0x67, 0x68, 0x74, 0x2a, 0x1d, 0x0a, 0x06, 0x52,
+	0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x08, 0x0a, 0x04, 0x44, 0x45, 0x4e, 0x59, 0x10, 0x00, 0x12,
+	0x09, 0x0a, 0x05, 0x41, 0x4c, 0x4c, 0x4f, 0x57, 0x10, 0x01, 0x32, 0x58, 0x0a, 0x0a, 0x41, 0x75,
+	0x74, 0x68, 0x6f, 0x72, 0x69, 0x7a, 0x65, 0x72, 0x12, 0x4a, 0x0a, 0x05, 0x43, 0x68, 0x65, 0x63,
+	0x6b, 0x12, 0x1f, 0x2e, 0x67, 0x6d, 0x71, 0x74, 0x74, 0x2e, 0x65, 0x78, 0x74, 0x61, 0x75, 0x74,
+	0x68, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x20, 0x2e, 0x67, 0x6d, 0x71, 0x74, 0x74, 0x2e, 0x65, 0x78, 0x74, 0x61, 0x75,
+	0x74, 0x68, 0x2e, 0x61, 0x70, 0x69, 0x2e, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x42, 0x0b, 0x5a, 0x09, 0x2e, 0x3b, 0x65, 0x78, 0x74, 0x61, 0x75, 0x74,
+	0x68, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_extauth_proto_rawDescOnce sync.Once
+	file_extauth_proto_rawDescData = file_extauth_proto_rawDesc
+)
+
+func file_extauth_proto_rawDescGZIP() []byte {
+	file_extauth_proto_rawDescOnce.Do(func() {
+		file_extauth_proto_rawDescData = protoimpl.X.CompressGZIP(file_extauth_proto_rawDescData)
+	})
+	return file_extauth_proto_rawDescData
+}
+
+var file_extauth_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_extauth_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_extauth_proto_goTypes = []interface{}{
+	(Result)(0),                  // 0: gmqtt.extauth.api.Result
+	(*CheckRequest)(nil),         // 1: gmqtt.extauth.api.CheckRequest
+	(*CheckResponse)(nil),        // 2: gmqtt.extauth.api.CheckResponse
+	(*AuthOptions)(nil),          // 3: gmqtt.extauth.api.AuthOptions
+	(*wrappers.UInt32Value)(nil), // 4: google.protobuf.UInt32Value
+	(*wrappers.BoolValue)(nil),   // 5: google.protobuf.BoolValue
+}
+var file_extauth_proto_depIdxs = []int32{
+	0,  // 0: gmqtt.extauth.api.CheckResponse.result:type_name -> gmqtt.extauth.api.Result
+	3,  // 1: gmqtt.extauth.api.CheckResponse.options:type_name -> gmqtt.extauth.api.AuthOptions
+	4,  // 2: gmqtt.extauth.api.AuthOptions.session_expiry:type_name -> google.protobuf.UInt32Value
+	4,  // 3: gmqtt.extauth.api.AuthOptions.receive_max:type_name -> google.protobuf.UInt32Value
+	4,  // 4: gmqtt.extauth.api.AuthOptions.maximum_qos:type_name -> google.protobuf.UInt32Value
+	4,  // 5: gmqtt.extauth.api.AuthOptions.max_packet_size:type_name -> google.protobuf.UInt32Value
+	4,  // 6: gmqtt.extauth.api.AuthOptions.topic_alias_max:type_name -> google.protobuf.UInt32Value
+	5,  // 7: gmqtt.extauth.api.AuthOptions.retain_available:type_name -> google.protobuf.BoolValue
+	5,  // 8: gmqtt.extauth.api.AuthOptions.wildcard_sub_available:type_name -> google.protobuf.BoolValue
+	5,  // 9: gmqtt.extauth.api.AuthOptions.sub_id_available:type_name -> google.protobuf.BoolValue
+	5,  // 10: gmqtt.extauth.api.AuthOptions.shared_sub_available:type_name -> google.protobuf.BoolValue
+	4,  // 11: gmqtt.extauth.api.AuthOptions.keep_alive:type_name -> google.protobuf.UInt32Value
+	4,  // 12: gmqtt.extauth.api.AuthOptions.max_inflight:type_name -> google.protobuf.UInt32Value
+	1,  // 13: gmqtt.extauth.api.Authorizer.Check:input_type -> gmqtt.extauth.api.CheckRequest
+	2,  // 14: gmqtt.extauth.api.Authorizer.Check:output_type -> gmqtt.extauth.api.CheckResponse
+	14, // [14:15] is the sub-list for method output_type
+	13, // [13:14] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
+}
+
+func init() { file_extauth_proto_init() }
+func file_extauth_proto_init() {
+	if File_extauth_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_extauth_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CheckRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_extauth_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CheckResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_extauth_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AuthOptions); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_extauth_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_extauth_proto_goTypes,
+		DependencyIndexes: file_extauth_proto_depIdxs,
+		EnumInfos:         file_extauth_proto_enumTypes,
+		MessageInfos:      file_extauth_proto_msgTypes,
+	}.Build()
+	File_extauth_proto = out.File
+	file_extauth_proto_rawDesc = nil
+	file_extauth_proto_goTypes = nil
+	file_extauth_proto_depIdxs = nil
+}
@@ -0,0 +1,105 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.13.0
+// source: extauth.proto
+
+package extauth
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// AuthorizerClient is the client API for Authorizer service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AuthorizerClient interface {
+	Check(ctx context.Context, in *CheckRequest, opts ...grpc.CallOption) (*CheckResponse, error)
+}
+
+type authorizerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAuthorizerClient(cc grpc.ClientConnInterface) AuthorizerClient {
+	return &authorizerClient{cc}
+}
+
+func (c *authorizerClient) Check(ctx context.Context, in *CheckRequest, opts ...grpc.CallOption) (*CheckResponse, error) {
+	out := new(CheckResponse)
+	err := c.cc.Invoke(ctx, "/gmqtt.extauth.api.Authorizer/Check", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AuthorizerServer is the server API for Authorizer service.
+// All implementations must embed UnimplementedAuthorizerServer
+// for forward compatibility
+type AuthorizerServer interface {
+	Check(context.Context, *CheckRequest) (*CheckResponse, error)
+	mustEmbedUnimplementedAuthorizerServer()
+}
+
+// UnimplementedAuthorizerServer must be embedded to have forward compatible implementations.
+type UnimplementedAuthorizerServer struct {
+}
+
+func (UnimplementedAuthorizerServer) Check(context.Context, *CheckRequest) (*CheckResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Check not implemented")
+}
+func (UnimplementedAuthorizerServer) mustEmbedUnimplementedAuthorizerServer() {}
+
+// UnsafeAuthorizerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AuthorizerServer will
+// result in compilation errors.
+type UnsafeAuthorizerServer interface {
+	mustEmbedUnimplementedAuthorizerServer()
+}
+
+func RegisterAuthorizerServer(s grpc.ServiceRegistrar, srv AuthorizerServer) {
+	s.RegisterService(&Authorizer_ServiceDesc, srv)
+}
+
+func _Authorizer_Check_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthorizerServer).Check(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/gmqtt.extauth.api.Authorizer/Check",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthorizerServer).Check(ctx, req.(*CheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Authorizer_ServiceDesc is the grpc.ServiceDesc for Authorizer service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Authorizer_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gmqtt.extauth.api.Authorizer",
+	HandlerType: (*AuthorizerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Check",
+			Handler:    _Authorizer_Check_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "extauth.proto",
+}
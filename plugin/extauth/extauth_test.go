@@ -0,0 +1,163 @@
+package extauth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+)
+
+// testBackend records the requests and returns the decision of fn.
+type testBackend struct {
+	mu   sync.Mutex
+	reqs []*request
+	fn   func(req *request) (*decision, error)
+}
+
+func (b *testBackend) check(ctx context.Context, req *request) (*decision, error) {
+	b.mu.Lock()
+	b.reqs = append(b.reqs, req)
+	b.mu.Unlock()
+	return b.fn(req)
+}
+
+func (b *testBackend) close() error {
+	return nil
+}
+
+func (b *testBackend) count() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.reqs)
+}
+
+func newTestExtAuth(cfg Config, fn func(req *request) (*decision, error)) (*ExtAuth, *testBackend) {
+	log = zap.NewNop()
+	b := &testBackend{fn: fn}
+	return &ExtAuth{
+		config:  &cfg,
+		backend: b,
+		cache:   newCache(cfg.Cache.Size, cfg.Cache.TTL),
+	}, b
+}
+
+func TestConfig_Validate(t *testing.T) {
+	a := assert.New(t)
+	cfg := DefaultConfig
+	a.Nil(cfg.Validate())
+
+	cfg.HTTP.URL = "127.0.0.1:8080"
+	a.NotNil(cfg.Validate())
+	cfg.HTTP.URL = "https://127.0.0.1:8080/auth"
+	a.Nil(cfg.Validate())
+	cfg.HTTP.TLS = &TLSConfig{CertFile: "cert.pem"}
+	a.NotNil(cfg.Validate())
+
+	cfg = DefaultConfig
+	cfg.Backend = BackendGRPC
+	a.NotNil(cfg.Validate())
+	cfg.GRPC.Target = "127.0.0.1:9090"
+	a.Nil(cfg.Validate())
+
+	cfg = DefaultConfig
+	cfg.Backend = "ldap"
+	a.NotNil(cfg.Validate())
+
+	cfg = DefaultConfig
+	cfg.OnFailure = "ignore"
+	a.NotNil(cfg.Validate())
+
+	cfg = DefaultConfig
+	cfg.Connect, cfg.Subscribe, cfg.Publish = false, false, false
+	a.NotNil(cfg.Validate())
+
+	cfg = DefaultConfig
+	cfg.Cache.TTL = 0
+	a.NotNil(cfg.Validate())
+	cfg.Cache.Size = 0
+	a.Nil(cfg.Validate())
+}
+
+func TestRequest_key(t *testing.T) {
+	a := assert.New(t)
+	r1 := &request{Action: ActionConnect, ClientID: "cid", Username: "user", Password: "pass", RemoteAddr: "127.0.0.1:1000"}
+	r2 := *r1
+	// the port is ignored.
+	r2.RemoteAddr = "127.0.0.1:2000"
+	a.Equal(r1.key(), r2.key())
+	r2.Password = "pass2"
+	a.NotEqual(r1.key(), r2.key())
+	// the fields are separated.
+	r3 := &request{Action: ActionConnect, ClientID: "cidu", Username: "ser", Password: "pass", RemoteAddr: "127.0.0.1:1000"}
+	a.NotEqual(r1.key(), r3.key())
+}
+
+func TestExtAuth_check(t *testing.T) {
+	a := assert.New(t)
+	cfg := DefaultConfig
+	var err error
+	e, b := newTestExtAuth(cfg, func(req *request) (*decision, error) {
+		if err != nil {
+			return nil, err
+		}
+		return &decision{allow: req.Username == "allow"}, nil
+	})
+
+	req := &request{Action: ActionConnect, Username: "allow"}
+	a.True(e.check(context.Background(), req).allow)
+	a.True(e.check(context.Background(), req).allow)
+	// the second one is cached.
+	a.Equal(1, b.count())
+	a.False(e.check(context.Background(), &request{Action: ActionConnect, Username: "deny"}).allow)
+	a.False(e.check(context.Background(), &request{Action: ActionConnect, Username: "deny"}).allow)
+	a.Equal(2, b.count())
+
+	// failures are not cached.
+	err = errors.New("backend error")
+	req = &request{Action: ActionConnect, Username: "allow", Password: "failure"}
+	a.False(e.check(context.Background(), req).allow)
+	e.config.OnFailure = Allow
+	a.True(e.check(context.Background(), req).allow)
+	a.Equal(4, b.count())
+	a.Equal(2, e.cache.len())
+}
+
+func TestExtAuth_check_invalidDecision(t *testing.T) {
+	a := assert.New(t)
+	e, _ := newTestExtAuth(DefaultConfig, func(req *request) (*decision, error) {
+		return &decision{code: codes.GrantedQoS1}, nil
+	})
+	d := e.check(context.Background(), &request{Action: ActionPublish})
+	a.Equal(&decision{}, d)
+	a.Equal(0, e.cache.len())
+}
+
+func TestExtAuth_check_timeout(t *testing.T) {
+	a := assert.New(t)
+	cfg := DefaultConfig
+	cfg.Timeout = 50 * time.Millisecond
+	cfg.OnFailure = Allow
+	e, _ := newTestExtAuth(cfg, nil)
+	e.backend = &blockingBackend{}
+	start := time.Now()
+	a.True(e.check(context.Background(), &request{Action: ActionPublish}).allow)
+	a.True(time.Since(start) < time.Second)
+}
+
+// blockingBackend returns when the context is done.
+type blockingBackend struct{}
+
+func (blockingBackend) check(ctx context.Context, req *request) (*decision, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (blockingBackend) close() error {
+	return nil
+}
@@ -0,0 +1,139 @@
+package extauth
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func (e *ExtAuth) HookWrapper() server.HookWrapper {
+	w := server.HookWrapper{}
+	if e.config.Connect {
+		w.OnBasicAuthWrapper = e.OnBasicAuthWrapper
+	}
+	if e.config.Subscribe {
+		w.OnSubscribeWrapper = e.OnSubscribeWrapper
+	}
+	if e.config.Publish {
+		w.OnMsgArrivedWrapper = e.OnMsgArrivedWrapper
+	}
+	return w
+}
+
+// codesError returns the error of the denied decision, the code is default to NotAuthorized.
+func (d *decision) codesError() *codes.Error {
+	code := d.code
+	if code == 0 {
+		code = codes.NotAuthorized
+	}
+	err := &codes.Error{
+		Code: code,
+	}
+	if d.reasonString != "" {
+		err.ReasonString = []byte(d.reasonString)
+	}
+	return err
+}
+
+func (e *ExtAuth) OnBasicAuthWrapper(pre server.OnBasicAuth) server.OnBasicAuth {
+	return func(ctx context.Context, client server.Client, req *server.ConnectRequest) (err error) {
+		err = pre(ctx, client, req)
+		if err != nil {
+			return err
+		}
+		d := e.check(ctx, &request{
+			Action:     ActionConnect,
+			ClientID:   string(req.Connect.ClientID),
+			Username:   string(req.Connect.Username),
+			Password:   string(req.Connect.Password),
+			RemoteAddr: remoteAddr(client),
+		})
+		if d.allow {
+			if d.options != nil {
+				d.options.apply(req.Options)
+			}
+			return nil
+		}
+		log.Debug("authentication failed",
+			zap.String("client_id", string(req.Connect.ClientID)),
+			zap.String("username", string(req.Connect.Username)))
+		if client.Version() == packets.Version5 {
+			return d.codesError()
+		}
+		if d.code == codes.BadUserNameOrPassword {
+			return &codes.Error{
+				Code: codes.V3BadUsernameorPassword,
+			}
+		}
+		return &codes.Error{
+			Code: codes.V3NotAuthorized,
+		}
+	}
+}
+
+func (e *ExtAuth) OnSubscribeWrapper(pre server.OnSubscribe) server.OnSubscribe {
+	return func(ctx context.Context, client server.Client, req *server.SubscribeRequest) error {
+		err := pre(ctx, client, req)
+		if err != nil {
+			return err
+		}
+		opts := client.ClientOptions()
+		addr := remoteAddr(client)
+		for k, v := range req.Subscriptions {
+			if v.Error != nil {
+				continue
+			}
+			d := e.check(ctx, &request{
+				Action:     ActionSubscribe,
+				ClientID:   opts.ClientID,
+				Username:   opts.Username,
+				RemoteAddr: addr,
+				Topic:      v.Sub.TopicFilter,
+				QoS:        v.Sub.QoS,
+			})
+			if !d.allow {
+				log.Debug("subscription not authorized",
+					zap.String("client_id", opts.ClientID),
+					zap.String("username", opts.Username),
+					zap.String("topic", k))
+				req.Reject(k, d.codesError())
+			}
+		}
+		return nil
+	}
+}
+
+func (e *ExtAuth) OnMsgArrivedWrapper(pre server.OnMsgArrived) server.OnMsgArrived {
+	return func(ctx context.Context, client server.Client, req *server.MsgArrivedRequest) error {
+		err := pre(ctx, client, req)
+		if err != nil || req.Message == nil {
+			return err
+		}
+		opts := client.ClientOptions()
+		d := e.check(ctx, &request{
+			Action:     ActionPublish,
+			ClientID:   opts.ClientID,
+			Username:   opts.Username,
+			RemoteAddr: remoteAddr(client),
+			Topic:      req.Message.Topic,
+			QoS:        req.Message.QoS,
+		})
+		if d.allow {
+			return nil
+		}
+		log.Debug("publish not authorized",
+			zap.String("client_id", opts.ClientID),
+			zap.String("username", opts.Username),
+			zap.String("topic", req.Message.Topic),
+			zap.Uint8("qos", req.Message.QoS))
+		req.Drop()
+		if client.Version() == packets.Version5 {
+			return d.codesError()
+		}
+		return nil
+	}
+}
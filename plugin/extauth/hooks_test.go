@@ -0,0 +1,196 @@
+package extauth
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/persistence/subscription"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func newSubscribeRequest(topics ...packets.Topic) *server.SubscribeRequest {
+	req := &server.SubscribeRequest{
+		Subscribe: &packets.Subscribe{
+			Topics: topics,
+		},
+		Subscriptions: make(map[string]*struct {
+			Sub   *gmqtt.Subscription
+			Error error
+		}),
+	}
+	for _, v := range topics {
+		req.Subscriptions[v.Name] = &struct {
+			Sub   *gmqtt.Subscription
+			Error error
+		}{Sub: subscription.FromTopic(v, 0)}
+	}
+	return req
+}
+
+func TestExtAuth_HookWrapper(t *testing.T) {
+	a := assert.New(t)
+	cfg := DefaultConfig
+	cfg.Subscribe = false
+	e, _ := newTestExtAuth(cfg, nil)
+	w := e.HookWrapper()
+	a.NotNil(w.OnBasicAuthWrapper)
+	a.Nil(w.OnSubscribeWrapper)
+	a.NotNil(w.OnMsgArrivedWrapper)
+}
+
+func TestExtAuth_OnBasicAuthWrapper(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	keepAlive := uint16(30)
+	e, b := newTestExtAuth(DefaultConfig, func(req *request) (*decision, error) {
+		switch req.Password {
+		case "pass":
+			return &decision{allow: true, options: &options{KeepAlive: &keepAlive}}, nil
+		case "bad":
+			return &decision{code: codes.BadUserNameOrPassword, reasonString: "bad password"}, nil
+		}
+		return &decision{}, nil
+	})
+	fn := e.OnBasicAuthWrapper(func(ctx context.Context, client server.Client, req *server.ConnectRequest) (err error) {
+		return nil
+	})
+	conn, _ := net.Pipe()
+	newRequest := func(password string) *server.ConnectRequest {
+		return &server.ConnectRequest{
+			Connect: &packets.Connect{
+				ClientID: []byte("cid"),
+				Username: []byte("user"),
+				Password: []byte(password),
+			},
+			Options: &server.AuthOptions{KeepAlive: 60},
+		}
+	}
+
+	for _, v := range []struct {
+		version   packets.Version
+		password  string
+		err       error
+		keepAlive uint16
+	}{
+		{version: packets.Version5, password: "pass", keepAlive: 30},
+		{version: packets.Version5, password: "bad", err: &codes.Error{
+			Code:         codes.BadUserNameOrPassword,
+			ErrorDetails: codes.ErrorDetails{ReasonString: []byte("bad password")},
+		}, keepAlive: 60},
+		{version: packets.Version5, password: "other", err: &codes.Error{Code: codes.NotAuthorized}, keepAlive: 60},
+		{version: packets.Version311, password: "bad", err: &codes.Error{Code: codes.V3BadUsernameorPassword}, keepAlive: 60},
+		{version: packets.Version311, password: "other", err: &codes.Error{Code: codes.V3NotAuthorized}, keepAlive: 60},
+	} {
+		client := server.NewMockClient(ctrl)
+		client.EXPECT().Connection().Return(conn).AnyTimes()
+		client.EXPECT().Version().Return(v.version).AnyTimes()
+		req := newRequest(v.password)
+		a.Equal(v.err, fn(context.Background(), client, req), v.password)
+		a.Equal(v.keepAlive, req.Options.KeepAlive)
+	}
+	a.Equal(&request{
+		Action:     ActionConnect,
+		ClientID:   "cid",
+		Username:   "user",
+		Password:   "pass",
+		RemoteAddr: "pipe",
+	}, b.reqs[0])
+}
+
+func TestExtAuth_OnSubscribeWrapper(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	e, b := newTestExtAuth(DefaultConfig, func(req *request) (*decision, error) {
+		return &decision{allow: req.Topic == "a"}, nil
+	})
+	client := server.NewMockClient(ctrl)
+	client.EXPECT().ClientOptions().Return(&server.ClientOptions{
+		ClientID: "cid",
+		Username: "user",
+	}).AnyTimes()
+	client.EXPECT().Connection().Return(nil).AnyTimes()
+
+	fn := e.OnSubscribeWrapper(func(ctx context.Context, client server.Client, req *server.SubscribeRequest) error {
+		req.Reject("c", &codes.Error{Code: codes.TopicFilterInvalid})
+		return nil
+	})
+	req := newSubscribeRequest(
+		packets.Topic{Name: "$share/g/a", SubOptions: packets.SubOptions{Qos: packets.Qos1}},
+		packets.Topic{Name: "b"},
+		packets.Topic{Name: "c"},
+	)
+	a.Nil(fn(context.Background(), client, req))
+	a.Nil(req.Subscriptions["$share/g/a"].Error)
+	a.Equal(&codes.Error{Code: codes.NotAuthorized}, req.Subscriptions["b"].Error)
+	// the rejected subscription is not checked.
+	a.Equal(&codes.Error{Code: codes.TopicFilterInvalid}, req.Subscriptions["c"].Error)
+	a.Len(b.reqs, 2)
+	for _, v := range b.reqs {
+		if v.Topic == "a" {
+			a.Equal(&request{
+				Action:   ActionSubscribe,
+				ClientID: "cid",
+				Username: "user",
+				Topic:    "a",
+				QoS:      packets.Qos1,
+			}, v)
+		}
+	}
+}
+
+func TestExtAuth_OnMsgArrivedWrapper(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	e, b := newTestExtAuth(DefaultConfig, func(req *request) (*decision, error) {
+		if req.Topic == "a" {
+			return &decision{allow: true}, nil
+		}
+		return &decision{code: codes.QuotaExceeded}, nil
+	})
+	fn := e.OnMsgArrivedWrapper(func(ctx context.Context, client server.Client, req *server.MsgArrivedRequest) error {
+		if req.Message.Topic == "dropped" {
+			req.Drop()
+		}
+		return nil
+	})
+	for _, v := range []struct {
+		version packets.Version
+		topic   string
+		err     error
+		dropped bool
+	}{
+		{version: packets.Version5, topic: "a"},
+		{version: packets.Version5, topic: "b", err: &codes.Error{Code: codes.QuotaExceeded}, dropped: true},
+		{version: packets.Version311, topic: "b", dropped: true},
+		{version: packets.Version5, topic: "dropped", dropped: true},
+	} {
+		client := server.NewMockClient(ctrl)
+		client.EXPECT().ClientOptions().Return(&server.ClientOptions{ClientID: "cid", Username: "user"}).AnyTimes()
+		client.EXPECT().Connection().Return(nil).AnyTimes()
+		client.EXPECT().Version().Return(v.version).AnyTimes()
+		req := &server.MsgArrivedRequest{
+			Message: &gmqtt.Message{Topic: v.topic, QoS: packets.Qos1},
+		}
+		a.Equal(v.err, fn(context.Background(), client, req), v.topic)
+		a.Equal(v.dropped, req.Message == nil, v.topic)
+	}
+	// the dropped message is not checked, and the second "b" is cached.
+	a.Len(b.reqs, 2)
+	a.Equal(&request{
+		Action:   ActionPublish,
+		ClientID: "cid",
+		Username: "user",
+		Topic:    "a",
+		QoS:      packets.Qos1,
+	}, b.reqs[0])
+}
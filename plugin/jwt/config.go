@@ -0,0 +1,62 @@
+package jwt
+
+import (
+	"errors"
+)
+
+// Config is the configuration for the jwt plugin.
+type Config struct {
+	// AuthMethod is the MQTT v5 authentication method handled by the plugin.
+	// V5 clients which set this auth method in the CONNECT packet send the token in the authentication data property.
+	AuthMethod string `yaml:"auth_method"`
+	// BasicAuth indicates whether to verify the token in the password field of the CONNECT packet.
+	BasicAuth bool `yaml:"basic_auth"`
+	// HMACSecretFiles is the list of files which contain the HMAC secrets.
+	HMACSecretFiles []string `yaml:"hmac_secret_files"`
+	// PublicKeyFiles is the list of PEM encoded RSA or ECDSA public key files.
+	PublicKeyFiles []string `yaml:"public_key_files"`
+	// JWKSFile is the local JSON Web Key Set file.
+	JWKSFile string `yaml:"jwks_file"`
+	// Issuer is the expected "iss" claim, empty means no check.
+	Issuer string `yaml:"issuer"`
+	// Audience is the expected "aud" claim, empty means no check.
+	Audience string `yaml:"audience"`
+	// RequireExpiry indicates whether tokens without "exp" claim are rejected.
+	RequireExpiry bool `yaml:"require_expiry"`
+	// OptionsClaim is the name of the claim which overrides the server.AuthOptions of the client.
+	OptionsClaim string `yaml:"options_claim"`
+	// DisconnectOnExpire indicates whether to disconnect the client when the token expires.
+	// V5 clients using AuthMethod can send a new token by re-authentication before the token expires.
+	DisconnectOnExpire bool `yaml:"disconnect_on_expire"`
+}
+
+// Validate validates the configuration, and return an error if it is invalid.
+func (c *Config) Validate() error {
+	if c.AuthMethod == "" && !c.BasicAuth {
+		return errors.New("at least one of auth_method and basic_auth must be set")
+	}
+	return nil
+}
+
+// DefaultConfig is the default configuration.
+var DefaultConfig = Config{
+	AuthMethod:         "JWT",
+	BasicAuth:          true,
+	RequireExpiry:      true,
+	OptionsClaim:       "gmqtt",
+	DisconnectOnExpire: true,
+}
+
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type cfg Config
+	var v = &struct {
+		JWT cfg `yaml:"jwt"`
+	}{
+		JWT: cfg(DefaultConfig),
+	}
+	if err := unmarshal(v); err != nil {
+		return err
+	}
+	*c = Config(v.JWT)
+	return nil
+}
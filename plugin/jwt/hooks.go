@@ -0,0 +1,121 @@
+package jwt
+
+import (
+	"bytes"
+	"context"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func (j *JWT) HookWrapper() server.HookWrapper {
+	return server.HookWrapper{
+		OnBasicAuthWrapper:    j.OnBasicAuthWrapper,
+		OnEnhancedAuthWrapper: j.OnEnhancedAuthWrapper,
+		OnReAuthWrapper:       j.OnReAuthWrapper,
+		OnConnectedWrapper:    j.OnConnectedWrapper,
+		OnClosedWrapper:       j.OnClosedWrapper,
+	}
+}
+
+func notAuthorized(version packets.Version) error {
+	if version == packets.Version311 {
+		return &codes.Error{
+			Code: codes.V3NotAuthorized,
+		}
+	}
+	return &codes.Error{
+		Code: codes.NotAuthorized,
+	}
+}
+
+// isAuthMethod returns whether the auth method is handled by the plugin.
+func (j *JWT) isAuthMethod(method []byte) bool {
+	return j.config.AuthMethod != "" && bytes.Equal(method, []byte(j.config.AuthMethod))
+}
+
+// OnBasicAuthWrapper verifies the token in the password field.
+func (j *JWT) OnBasicAuthWrapper(pre server.OnBasicAuth) server.OnBasicAuth {
+	return func(ctx context.Context, client server.Client, req *server.ConnectRequest) (err error) {
+		err = pre(ctx, client, req)
+		if err != nil || !j.config.BasicAuth {
+			return err
+		}
+		t, err := j.verify(string(req.Connect.Password))
+		if err != nil {
+			log.Debug("authentication failed",
+				zap.String("username", string(req.Connect.Username)),
+				zap.Error(err))
+			return notAuthorized(client.Version())
+		}
+		if t.options != nil {
+			t.options.apply(req.Options)
+		}
+		j.authenticated(client, t)
+		return nil
+	}
+}
+
+// OnEnhancedAuthWrapper verifies the token in the authentication data property if the auth method matches.
+func (j *JWT) OnEnhancedAuthWrapper(pre server.OnEnhancedAuth) server.OnEnhancedAuth {
+	return func(ctx context.Context, client server.Client, req *server.ConnectRequest) (resp *server.EnhancedAuthResponse, err error) {
+		if !j.isAuthMethod(req.Connect.Properties.AuthMethod) {
+			return pre(ctx, client, req)
+		}
+		t, err := j.verify(string(req.Connect.Properties.AuthData))
+		if err != nil {
+			log.Debug("authentication failed",
+				zap.String("client_id", string(req.Connect.ClientID)),
+				zap.Error(err))
+			return nil, notAuthorized(client.Version())
+		}
+		if t.options != nil {
+			t.options.apply(req.Options)
+		}
+		j.authenticated(client, t)
+		return &server.EnhancedAuthResponse{
+			Continue: false,
+		}, nil
+	}
+}
+
+// OnReAuthWrapper verifies the new token and extends the expiry if the auth method matches.
+func (j *JWT) OnReAuthWrapper(pre server.OnReAuth) server.OnReAuth {
+	return func(ctx context.Context, client server.Client, auth *packets.Auth) (*server.AuthResponse, error) {
+		if !j.isAuthMethod(client.ClientOptions().AuthMethod) {
+			return pre(ctx, client, auth)
+		}
+		t, err := j.verify(string(auth.Properties.AuthData))
+		if err == nil {
+			err = j.reAuthenticated(client, t)
+		}
+		if err != nil {
+			log.Debug("re-authentication failed",
+				zap.String("client_id", client.ClientOptions().ClientID),
+				zap.Error(err))
+			return nil, notAuthorized(client.Version())
+		}
+		return &server.AuthResponse{
+			Continue: false,
+		}, nil
+	}
+}
+
+// OnConnectedWrapper starts the expiry timer.
+func (j *JWT) OnConnectedWrapper(pre server.OnConnected) server.OnConnected {
+	return func(ctx context.Context, client server.Client) {
+		pre(ctx, client)
+		j.startTimer(client)
+	}
+}
+
+// OnClosedWrapper stops the expiry timer.
+func (j *JWT) OnClosedWrapper(pre server.OnClosed) server.OnClosed {
+	return func(ctx context.Context, client server.Client, err error) {
+		pre(ctx, client, err)
+		j.remove(client)
+	}
+}
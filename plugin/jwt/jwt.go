@@ -0,0 +1,296 @@
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+var _ server.Plugin = (*JWT)(nil)
+
+const Name = "jwt"
+
+func init() {
+	server.RegisterPlugin(Name, New)
+	config.RegisterDefaultPluginConfig(Name, &DefaultConfig)
+}
+
+func New(config config.Config) (server.Plugin, error) {
+	return &JWT{
+		config:  config.Plugins[Name].(*Config),
+		clients: make(map[server.Client]*clientToken),
+	}, nil
+}
+
+var log *zap.Logger
+
+// JWT provides the JSON Web Token authentication for gmqtt.
+type JWT struct {
+	config *Config
+	keys   keySet
+	// mu guards clients
+	mu sync.Mutex
+	// clients stores the token state of the authenticated clients.
+	clients map[server.Client]*clientToken
+}
+
+// clientToken is the token state of a client.
+type clientToken struct {
+	// subject is the "sub" claim of the token.
+	subject string
+	// expiry is the expiry time of the token, zero means never expire.
+	expiry time.Time
+	// timer disconnects the client when the token expires.
+	timer *time.Timer
+}
+
+// options is the structure of the options claim.
+// The absent fields do not change the corresponding server.AuthOptions.
+type options struct {
+	SessionExpiry        *uint32 `json:"session_expiry"`
+	ReceiveMax           *uint16 `json:"receive_max"`
+	MaximumQoS           *uint8  `json:"maximum_qos"`
+	MaxPacketSize        *uint32 `json:"max_packet_size"`
+	TopicAliasMax        *uint16 `json:"topic_alias_max"`
+	RetainAvailable      *bool   `json:"retain_available"`
+	WildcardSubAvailable *bool   `json:"wildcard_sub_available"`
+	SubIDAvailable       *bool   `json:"sub_id_available"`
+	SharedSubAvailable   *bool   `json:"shared_sub_available"`
+	KeepAlive            *uint16 `json:"keep_alive"`
+	MaxInflight          *uint16 `json:"max_inflight"`
+}
+
+func (o *options) apply(opts *server.AuthOptions) {
+	if o.SessionExpiry != nil {
+		opts.SessionExpiry = *o.SessionExpiry
+	}
+	if o.ReceiveMax != nil {
+		opts.ReceiveMax = *o.ReceiveMax
+	}
+	if o.MaximumQoS != nil {
+		opts.MaximumQoS = *o.MaximumQoS
+	}
+	if o.MaxPacketSize != nil {
+		opts.MaxPacketSize = *o.MaxPacketSize
+	}
+	if o.TopicAliasMax != nil {
+		opts.TopicAliasMax = *o.TopicAliasMax
+	}
+	if o.RetainAvailable != nil {
+		opts.RetainAvailable = *o.RetainAvailable
+	}
+	if o.WildcardSubAvailable != nil {
+		opts.WildcardSubAvailable = *o.WildcardSubAvailable
+	}
+	if o.SubIDAvailable != nil {
+		opts.SubIDAvailable = *o.SubIDAvailable
+	}
+	if o.SharedSubAvailable != nil {
+		opts.SharedSubAvailable = *o.SharedSubAvailable
+	}
+	if o.KeepAlive != nil {
+		opts.KeepAlive = *o.KeepAlive
+	}
+	if o.MaxInflight != nil {
+		opts.MaxInflight = *o.MaxInflight
+	}
+}
+
+// validate validates the options claim.
+func (o *options) validate() error {
+	if o.MaximumQoS != nil && *o.MaximumQoS > packets.Qos2 {
+		return fmt.Errorf("invalid maximum_qos: %d", *o.MaximumQoS)
+	}
+	if o.ReceiveMax != nil && *o.ReceiveMax == 0 {
+		return errors.New("invalid receive_max: 0")
+	}
+	if o.MaxPacketSize != nil && *o.MaxPacketSize == 0 {
+		return errors.New("invalid max_packet_size: 0")
+	}
+	if o.MaxInflight != nil && *o.MaxInflight == 0 {
+		return errors.New("invalid max_inflight: 0")
+	}
+	return nil
+}
+
+// token is a verified token.
+type token struct {
+	subject string
+	expiry  time.Time
+	options *options
+}
+
+// verify parses and verifies the token string.
+func (j *JWT) verify(tokenString string) (*token, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		return j.keys.lookup(t)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !claims.VerifyExpiresAt(time.Now().Unix(), j.config.RequireExpiry) {
+		return nil, errors.New("missing exp claim")
+	}
+	if j.config.Issuer != "" && !claims.VerifyIssuer(j.config.Issuer, true) {
+		return nil, errors.New("invalid iss claim")
+	}
+	if j.config.Audience != "" && !claims.VerifyAudience(j.config.Audience, true) {
+		return nil, errors.New("invalid aud claim")
+	}
+	t := &token{}
+	t.subject, _ = claims["sub"].(string)
+	switch exp := claims["exp"].(type) {
+	case float64:
+		t.expiry = time.Unix(int64(exp), 0)
+	case json.Number:
+		v, _ := exp.Int64()
+		t.expiry = time.Unix(v, 0)
+	}
+	if j.config.OptionsClaim != "" {
+		if v, ok := claims[j.config.OptionsClaim]; ok {
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			t.options = &options{}
+			err = json.Unmarshal(b, t.options)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s claim: %s", j.config.OptionsClaim, err)
+			}
+			err = t.options.validate()
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s claim: %s", j.config.OptionsClaim, err)
+			}
+		}
+	}
+	return t, nil
+}
+
+// authenticated records the token of the client, the expiry timer will be started when the client is connected.
+func (j *JWT) authenticated(client server.Client, t *token) {
+	if !j.config.DisconnectOnExpire || t.expiry.IsZero() {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.clients[client] = &clientToken{
+		subject: t.subject,
+		expiry:  t.expiry,
+	}
+}
+
+// startTimer starts the expiry timer of the client.
+func (j *JWT) startTimer(client server.Client) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	ct, ok := j.clients[client]
+	if !ok || ct.timer != nil {
+		return
+	}
+	ct.timer = time.AfterFunc(time.Until(ct.expiry), func() {
+		j.expire(client)
+	})
+}
+
+// reAuthenticated updates the token of the client.
+func (j *JWT) reAuthenticated(client server.Client, t *token) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	ct, ok := j.clients[client]
+	if ok && ct.subject != t.subject {
+		return errors.New("sub claim mismatch")
+	}
+	if !j.config.DisconnectOnExpire || t.expiry.IsZero() {
+		if ok {
+			if ct.timer != nil {
+				ct.timer.Stop()
+			}
+			delete(j.clients, client)
+		}
+		return nil
+	}
+	if !ok {
+		ct = &clientToken{subject: t.subject}
+		j.clients[client] = ct
+	}
+	if ct.timer != nil {
+		ct.timer.Stop()
+	}
+	ct.expiry = t.expiry
+	ct.timer = time.AfterFunc(time.Until(ct.expiry), func() {
+		j.expire(client)
+	})
+	return nil
+}
+
+// remove removes the token state of the client.
+func (j *JWT) remove(client server.Client) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if ct, ok := j.clients[client]; ok {
+		if ct.timer != nil {
+			ct.timer.Stop()
+		}
+		delete(j.clients, client)
+	}
+}
+
+// expire disconnects the client whose token is expired.
+func (j *JWT) expire(client server.Client) {
+	j.mu.Lock()
+	ct, ok := j.clients[client]
+	if !ok || time.Now().Before(ct.expiry) {
+		// removed or re-authenticated
+		j.mu.Unlock()
+		return
+	}
+	delete(j.clients, client)
+	j.mu.Unlock()
+	log.Info("token expired, disconnecting the client", zap.String("client_id", client.ClientOptions().ClientID))
+	if client.Version() == packets.Version5 {
+		client.Disconnect(&packets.Disconnect{
+			Version: packets.Version5,
+			Code:    codes.NotAuthorized,
+		})
+		return
+	}
+	client.Close()
+}
+
+func (j *JWT) Load(service server.Server) error {
+	log = server.LoggerWithField(zap.String("plugin", Name))
+	keys, err := loadKeys(j.config)
+	if err != nil {
+		return err
+	}
+	j.keys = keys
+	log.Info("verification keys loaded", zap.Int("key_nums", len(keys)))
+	return nil
+}
+
+func (j *JWT) Unload() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for k, v := range j.clients {
+		if v.timer != nil {
+			v.timer.Stop()
+		}
+		delete(j.clients, k)
+	}
+	return nil
+}
+
+func (j *JWT) Name() string {
+	return Name
+}
@@ -0,0 +1,336 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt/config"
+	_ "github.com/DrmagicE/gmqtt/persistence"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+	_ "github.com/DrmagicE/gmqtt/topicalias/fifo"
+)
+
+var testSecret = []byte("secret")
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.MapClaims) string {
+	s, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestJWT_verify(t *testing.T) {
+	a := assert.New(t)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	a.Nil(err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	a.Nil(err)
+	cfg := DefaultConfig
+	cfg.Issuer = "issuer"
+	cfg.Audience = "gmqtt"
+	j := &JWT{
+		config: &cfg,
+		keys: keySet{
+			{value: testSecret},
+			{value: &rsaKey.PublicKey},
+			{value: &ecKey.PublicKey},
+		},
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub": "device",
+			"iss": "issuer",
+			"aud": []string{"other", "gmqtt"},
+			"exp": exp,
+		}
+	}
+	for _, v := range []struct {
+		name   string
+		method jwt.SigningMethod
+		key    interface{}
+		claims func(c jwt.MapClaims)
+		ok     bool
+	}{
+		{name: "hmac", method: jwt.SigningMethodHS256, key: testSecret, ok: true},
+		{name: "rsa", method: jwt.SigningMethodRS256, key: rsaKey, ok: true},
+		{name: "ecdsa", method: jwt.SigningMethodES256, key: ecKey, ok: true},
+		{name: "wrong_secret", method: jwt.SigningMethodHS256, key: []byte("wrong")},
+		{name: "expired", method: jwt.SigningMethodHS256, key: testSecret, claims: func(c jwt.MapClaims) {
+			c["exp"] = time.Now().Add(-time.Minute).Unix()
+		}},
+		{name: "missing_exp", method: jwt.SigningMethodHS256, key: testSecret, claims: func(c jwt.MapClaims) {
+			delete(c, "exp")
+		}},
+		{name: "wrong_issuer", method: jwt.SigningMethodHS256, key: testSecret, claims: func(c jwt.MapClaims) {
+			c["iss"] = "other"
+		}},
+		{name: "wrong_audience", method: jwt.SigningMethodHS256, key: testSecret, claims: func(c jwt.MapClaims) {
+			c["aud"] = "other"
+		}},
+		{name: "invalid_options", method: jwt.SigningMethodHS256, key: testSecret, claims: func(c jwt.MapClaims) {
+			c["gmqtt"] = map[string]interface{}{"maximum_qos": 3}
+		}},
+		{name: "none", method: jwt.SigningMethodNone, key: jwt.UnsafeAllowNoneSignatureType},
+	} {
+		t.Run(v.name, func(t *testing.T) {
+			c := valid()
+			if v.claims != nil {
+				v.claims(c)
+			}
+			tk, err := j.verify(sign(t, v.method, v.key, c))
+			if v.ok {
+				a.Nil(err)
+				a.Equal("device", tk.subject)
+				a.Equal(time.Unix(exp, 0), tk.expiry)
+			} else {
+				a.NotNil(err)
+			}
+		})
+	}
+
+	c := valid()
+	c["gmqtt"] = map[string]interface{}{
+		"maximum_qos":    1,
+		"session_expiry": 100,
+		"keep_alive":     30,
+	}
+	tk, err := j.verify(sign(t, jwt.SigningMethodHS256, testSecret, c))
+	a.Nil(err)
+	opts := &server.AuthOptions{
+		MaximumQoS:    2,
+		SessionExpiry: 0,
+		KeepAlive:     60,
+		ReceiveMax:    10,
+	}
+	tk.options.apply(opts)
+	a.Equal(&server.AuthOptions{
+		MaximumQoS:    1,
+		SessionExpiry: 100,
+		KeepAlive:     30,
+		ReceiveMax:    10,
+	}, opts)
+
+	cfg.RequireExpiry = false
+	c = valid()
+	delete(c, "exp")
+	tk, err = j.verify(sign(t, jwt.SigningMethodHS256, testSecret, c))
+	a.Nil(err)
+	a.True(tk.expiry.IsZero())
+}
+
+type testClient struct {
+	t      *testing.T
+	nc     net.Conn
+	reader *packets.Reader
+	writer *packets.Writer
+}
+
+func dial(t *testing.T, addr string, version packets.Version) *testClient {
+	nc, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &testClient{
+		t:      t,
+		nc:     nc,
+		reader: packets.NewReader(nc),
+		writer: packets.NewWriter(nc),
+	}
+	c.reader.SetVersion(version)
+	return c
+}
+
+func (c *testClient) write(p packets.Packet) {
+	if err := c.writer.WriteAndFlush(p); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *testClient) read() packets.Packet {
+	_ = c.nc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	p, err := c.reader.ReadPacket()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return p
+}
+
+func (c *testClient) close() {
+	_ = c.nc.Close()
+}
+
+func startBroker(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "gmqtt_jwt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := path.Join(dir, "secret")
+	if err := ioutil.WriteFile(secret, testSecret, 0666); err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	jwtCfg := DefaultConfig
+	jwtCfg.HMACSecretFiles = []string{secret}
+	cfg.Plugins[Name] = &jwtCfg
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := server.New(
+		server.WithConfig(cfg),
+		server.WithTCPListener(ln),
+		server.WithPlugin(p),
+	)
+	if err := srv.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return ln.Addr().String(), func() {
+		_ = srv.Stop(context.Background())
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestJWT_basicAuth(t *testing.T) {
+	a := assert.New(t)
+	addr, stop := startBroker(t)
+	defer stop()
+
+	connect := func(password string) *packets.Connack {
+		c := dial(t, addr, packets.Version311)
+		defer c.close()
+		c.write(&packets.Connect{
+			Version:       packets.Version311,
+			ProtocolName:  []byte("MQTT"),
+			ProtocolLevel: packets.Version311,
+			CleanStart:    true,
+			ClientID:      []byte("client"),
+			UsernameFlag:  true,
+			Username:      []byte("device"),
+			PasswordFlag:  true,
+			Password:      []byte(password),
+		})
+		return c.read().(*packets.Connack)
+	}
+	a.EqualValues(codes.V3NotAuthorized, connect("invalid").Code)
+	a.Equal(codes.Success, connect(sign(t, jwt.SigningMethodHS256, testSecret, jwt.MapClaims{
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})).Code)
+}
+
+func TestJWT_enhancedAuth(t *testing.T) {
+	a := assert.New(t)
+	addr, stop := startBroker(t)
+	defer stop()
+
+	connect := func(method string, token string) (*testClient, *packets.Connack) {
+		c := dial(t, addr, packets.Version5)
+		c.write(&packets.Connect{
+			Version:       packets.Version5,
+			ProtocolName:  []byte("MQTT"),
+			ProtocolLevel: packets.Version5,
+			CleanStart:    true,
+			ClientID:      []byte("client"),
+			Properties: &packets.Properties{
+				AuthMethod: []byte(method),
+				AuthData:   []byte(token),
+			},
+		})
+		return c, c.read().(*packets.Connack)
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+	c, connack := connect("unknown", sign(t, jwt.SigningMethodHS256, testSecret, jwt.MapClaims{"exp": exp}))
+	c.close()
+	a.Equal(codes.BadAuthMethod, connack.Code)
+
+	c, connack = connect("JWT", "invalid")
+	c.close()
+	a.Equal(codes.NotAuthorized, connack.Code)
+
+	exp = time.Now().Unix() + 1
+	c, connack = connect("JWT", sign(t, jwt.SigningMethodHS256, testSecret, jwt.MapClaims{
+		"sub": "device",
+		"exp": exp,
+		"gmqtt": map[string]interface{}{
+			"maximum_qos": 1,
+			"keep_alive":  30,
+		},
+	}))
+	defer c.close()
+	a.Equal(codes.Success, connack.Code)
+	a.Equal([]byte("JWT"), connack.Properties.AuthMethod)
+	a.EqualValues(1, *connack.Properties.MaximumQoS)
+	a.EqualValues(30, *connack.Properties.ServerKeepAlive)
+
+	// extend the expiry by re-authentication.
+	reAuthExp := exp + 2
+	c.write(&packets.Auth{
+		Code: codes.ReAuthenticate,
+		Properties: &packets.Properties{
+			AuthMethod: []byte("JWT"),
+			AuthData: []byte(sign(t, jwt.SigningMethodHS256, testSecret, jwt.MapClaims{
+				"sub": "device",
+				"exp": reAuthExp,
+			})),
+		},
+	})
+	auth, ok := c.read().(*packets.Auth)
+	a.True(ok)
+	a.Equal(codes.Success, auth.Code)
+
+	dis, ok := c.read().(*packets.Disconnect)
+	a.True(ok)
+	a.Equal(codes.NotAuthorized, dis.Code)
+	a.False(time.Now().Before(time.Unix(reAuthExp, 0)))
+}
+
+func TestJWT_reAuthSubjectMismatch(t *testing.T) {
+	a := assert.New(t)
+	addr, stop := startBroker(t)
+	defer stop()
+
+	c := dial(t, addr, packets.Version5)
+	defer c.close()
+	exp := time.Now().Add(time.Hour).Unix()
+	c.write(&packets.Connect{
+		Version:       packets.Version5,
+		ProtocolName:  []byte("MQTT"),
+		ProtocolLevel: packets.Version5,
+		CleanStart:    true,
+		ClientID:      []byte("client"),
+		Properties: &packets.Properties{
+			AuthMethod: []byte("JWT"),
+			AuthData:   []byte(sign(t, jwt.SigningMethodHS256, testSecret, jwt.MapClaims{"sub": "device", "exp": exp})),
+		},
+	})
+	a.Equal(codes.Success, c.read().(*packets.Connack).Code)
+	c.write(&packets.Auth{
+		Code: codes.ReAuthenticate,
+		Properties: &packets.Properties{
+			AuthMethod: []byte("JWT"),
+			AuthData:   []byte(sign(t, jwt.SigningMethodHS256, testSecret, jwt.MapClaims{"sub": "other", "exp": exp})),
+		},
+	})
+	dis, ok := c.read().(*packets.Disconnect)
+	a.True(ok)
+	a.Equal(codes.NotAuthorized, dis.Code)
+}
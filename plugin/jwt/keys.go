@@ -0,0 +1,231 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// key is a verification key.
+type key struct {
+	// kid is the key id, only keys loaded from the JWKS file have it.
+	kid string
+	// value is []byte for HMAC, *rsa.PublicKey for RSA and *ecdsa.PublicKey for ECDSA.
+	value interface{}
+}
+
+// keySet is the set of verification keys.
+type keySet []*key
+
+// lookup returns the key for the token.
+// A key whose kid equals the kid header is preferred, otherwise the first key which fits the signing method is used.
+func (k keySet) lookup(token *jwt.Token) (interface{}, error) {
+	var match func(v interface{}) bool
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		match = func(v interface{}) bool {
+			_, ok := v.([]byte)
+			return ok
+		}
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		match = func(v interface{}) bool {
+			_, ok := v.(*rsa.PublicKey)
+			return ok
+		}
+	case *jwt.SigningMethodECDSA:
+		match = func(v interface{}) bool {
+			_, ok := v.(*ecdsa.PublicKey)
+			return ok
+		}
+	default:
+		return nil, fmt.Errorf("unsupported signing method: %s", token.Header["alg"])
+	}
+	kid, _ := token.Header["kid"].(string)
+	var fallback interface{}
+	for _, v := range k {
+		if !match(v.value) {
+			continue
+		}
+		if kid != "" && v.kid == kid {
+			return v.value, nil
+		}
+		if fallback == nil && (kid == "" || v.kid == "") {
+			fallback = v.value
+		}
+	}
+	if fallback == nil {
+		return nil, fmt.Errorf("no key for signing method: %s", token.Header["alg"])
+	}
+	return fallback, nil
+}
+
+// loadKeys loads all keys set in the configuration.
+func loadKeys(config *Config) (keySet, error) {
+	var keys keySet
+	for _, v := range config.HMACSecretFiles {
+		b, err := ioutil.ReadFile(v)
+		if err != nil {
+			return nil, err
+		}
+		b = bytes.TrimSpace(b)
+		if len(b) == 0 {
+			return nil, fmt.Errorf("empty hmac secret file: %s", v)
+		}
+		keys = append(keys, &key{value: b})
+	}
+	for _, v := range config.PublicKeyFiles {
+		b, err := ioutil.ReadFile(v)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := parsePublicKey(b)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key file %s: %s", v, err)
+		}
+		keys = append(keys, &key{value: pub})
+	}
+	if config.JWKSFile != "" {
+		b, err := ioutil.ReadFile(config.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		jwks, err := parseJWKS(b)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwks file %s: %s", config.JWKSFile, err)
+		}
+		keys = append(keys, jwks...)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no verification key, at least one of hmac_secret_files, public_key_files and jwks_file must be set")
+	}
+	return keys, nil
+}
+
+// parsePublicKey parses the PEM encoded RSA or ECDSA public key.
+func parsePublicKey(b []byte) (interface{}, error) {
+	if k, err := jwt.ParseRSAPublicKeyFromPEM(b); err == nil {
+		return k, nil
+	}
+	if k, err := jwt.ParseECPublicKeyFromPEM(b); err == nil {
+		return k, nil
+	}
+	return nil, errors.New("not a PEM encoded RSA or ECDSA public key")
+}
+
+// jwk is a JSON Web Key, see RFC 7517.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	// oct
+	K string `json:"k"`
+}
+
+// parseJWKS parses the JSON Web Key Set.
+// Keys whose "use" is not "sig" are ignored.
+func parseJWKS(b []byte) (keySet, error) {
+	var set struct {
+		Keys []*jwk `json:"keys"`
+	}
+	err := json.Unmarshal(b, &set)
+	if err != nil {
+		return nil, err
+	}
+	var keys keySet
+	for i, v := range set.Keys {
+		if v.Use != "" && v.Use != "sig" {
+			continue
+		}
+		value, err := v.key()
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %s", i, err)
+		}
+		keys = append(keys, &key{kid: v.Kid, value: value})
+	}
+	return keys, nil
+}
+
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := decodeBase64URL(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func (j *jwk) key() (interface{}, error) {
+	switch j.Kty {
+	case "oct":
+		k, err := decodeBase64URL(j.K)
+		if err != nil {
+			return nil, err
+		}
+		if len(k) == 0 {
+			return nil, errors.New("empty oct key")
+		}
+		return k, nil
+	case "RSA":
+		n, err := decodeBigInt(j.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid n: %s", err)
+		}
+		e, err := decodeBigInt(j.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid e: %s", err)
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid e: too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve: %s", j.Crv)
+		}
+		x, err := decodeBigInt(j.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x: %s", err)
+		}
+		y, err := decodeBigInt(j.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid y: %s", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", j.Kty)
+	}
+}
@@ -0,0 +1,151 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func rsaJWK(kid string, k *rsa.PublicKey) string {
+	return fmt.Sprintf(`{"kty":"RSA","kid":"%s","use":"sig","n":"%s","e":"%s"}`,
+		kid, b64(k.N.Bytes()), b64(big.NewInt(int64(k.E)).Bytes()))
+}
+
+func ecJWK(kid string, k *ecdsa.PublicKey) string {
+	return fmt.Sprintf(`{"kty":"EC","kid":"%s","crv":"P-256","x":"%s","y":"%s"}`,
+		kid, b64(k.X.Bytes()), b64(k.Y.Bytes()))
+}
+
+func writePublicKey(t *testing.T, dir, name string, k interface{}) string {
+	b, err := x509.MarshalPKIXPublicKey(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := path.Join(dir, name)
+	err = ioutil.WriteFile(p, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: b}), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestParseJWKS(t *testing.T) {
+	a := assert.New(t)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	a.Nil(err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	a.Nil(err)
+
+	keys, err := parseJWKS([]byte(fmt.Sprintf(`{"keys":[%s,%s,{"kty":"oct","kid":"hmac","k":"%s"},{"kty":"RSA","use":"enc","n":"AQAB","e":"AQAB"}]}`,
+		rsaJWK("rsa", &rsaKey.PublicKey), ecJWK("ec", &ecKey.PublicKey), b64([]byte("secret")))))
+	a.Nil(err)
+	a.Len(keys, 3)
+	a.Equal("rsa", keys[0].kid)
+	a.Equal(&rsaKey.PublicKey, keys[0].value)
+	a.Equal("ec", keys[1].kid)
+	a.Equal(&ecKey.PublicKey, keys[1].value)
+	a.Equal("hmac", keys[2].kid)
+	a.Equal([]byte("secret"), keys[2].value)
+
+	for _, v := range []string{
+		`{"keys":[{"kty":"unknown"}]}`,
+		`{"keys":[{"kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}]}`,
+		`{"keys":[{"kty":"EC","crv":"P-192","x":"AQ","y":"AQ"}]}`,
+		`{"keys":[{"kty":"RSA","n":"AQAB"}]}`,
+		`{"keys":[{"kty":"oct"}]}`,
+		`not json`,
+	} {
+		_, err = parseJWKS([]byte(v))
+		a.NotNil(err, v)
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	a := assert.New(t)
+	dir, err := ioutil.TempDir("", "gmqtt_jwt")
+	a.Nil(err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	a.Nil(err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	a.Nil(err)
+	secret := path.Join(dir, "secret")
+	a.Nil(ioutil.WriteFile(secret, []byte("secret\n"), 0666))
+	jwks := path.Join(dir, "jwks.json")
+	a.Nil(ioutil.WriteFile(jwks, []byte(fmt.Sprintf(`{"keys":[%s]}`, rsaJWK("rsa", &rsaKey.PublicKey))), 0666))
+
+	keys, err := loadKeys(&Config{
+		HMACSecretFiles: []string{secret},
+		PublicKeyFiles: []string{
+			writePublicKey(t, dir, "rsa.pem", &rsaKey.PublicKey),
+			writePublicKey(t, dir, "ec.pem", &ecKey.PublicKey),
+		},
+		JWKSFile: jwks,
+	})
+	a.Nil(err)
+	a.Len(keys, 4)
+	a.Equal([]byte("secret"), keys[0].value)
+	a.Equal(&rsaKey.PublicKey, keys[1].value)
+	a.Equal(&ecKey.PublicKey, keys[2].value)
+	a.Equal("rsa", keys[3].kid)
+
+	_, err = loadKeys(&Config{})
+	a.NotNil(err)
+	_, err = loadKeys(&Config{PublicKeyFiles: []string{secret}})
+	a.NotNil(err)
+}
+
+func TestKeySet_lookup(t *testing.T) {
+	a := assert.New(t)
+	rsa1, err := rsa.GenerateKey(rand.Reader, 2048)
+	a.Nil(err)
+	rsa2, err := rsa.GenerateKey(rand.Reader, 2048)
+	a.Nil(err)
+	keys := keySet{
+		{kid: "rsa1", value: &rsa1.PublicKey},
+		{kid: "rsa2", value: &rsa2.PublicKey},
+		{value: []byte("secret")},
+	}
+	newToken := func(method jwt.SigningMethod, kid string) *jwt.Token {
+		tk := jwt.New(method)
+		if kid != "" {
+			tk.Header["kid"] = kid
+		}
+		return tk
+	}
+	k, err := keys.lookup(newToken(jwt.SigningMethodRS256, "rsa2"))
+	a.Nil(err)
+	a.Equal(&rsa2.PublicKey, k)
+	k, err = keys.lookup(newToken(jwt.SigningMethodRS256, ""))
+	a.Nil(err)
+	a.Equal(&rsa1.PublicKey, k)
+	k, err = keys.lookup(newToken(jwt.SigningMethodPS256, "rsa2"))
+	a.Nil(err)
+	a.Equal(&rsa2.PublicKey, k)
+	// unknown kid
+	_, err = keys.lookup(newToken(jwt.SigningMethodRS256, "rsa3"))
+	a.NotNil(err)
+	// the HMAC secret is only used for HMAC tokens.
+	k, err = keys.lookup(newToken(jwt.SigningMethodHS256, ""))
+	a.Nil(err)
+	a.Equal([]byte("secret"), k)
+	_, err = keys.lookup(newToken(jwt.SigningMethodES256, ""))
+	a.NotNil(err)
+	_, err = keys.lookup(newToken(jwt.SigningMethodNone, ""))
+	a.NotNil(err)
+}
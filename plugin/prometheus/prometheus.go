@@ -2,7 +2,10 @@ package prometheus
 
 import (
 	"context"
+	"net"
 	"net/http"
+	"strings"
+	"sync"
 	"sync/atomic"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -15,6 +18,7 @@ import (
 )
 
 var _ server.Plugin = (*Prometheus)(nil)
+var _ server.Reloadable = (*Prometheus)(nil)
 
 const (
 	Name         = "prometheus"
@@ -28,12 +32,8 @@ func init() {
 
 func New(config config.Config) (server.Plugin, error) {
 	cfg := config.Plugins[Name].(*Config)
-	httpServer := &http.Server{
-		Addr: cfg.ListenAddress,
-	}
 	return &Prometheus{
-		httpServer: httpServer,
-		path:       cfg.Path,
+		config: *cfg,
 	}, nil
 }
 
@@ -42,8 +42,43 @@ var log *zap.Logger
 // Prometheus served as a prometheus exporter that exposes gmqtt metrics.
 type Prometheus struct {
 	statsManager server.StatsReader
-	httpServer   *http.Server
-	path         string
+	// gard httpServer & config
+	mu         sync.Mutex
+	httpServer *http.Server
+	config     Config
+	// path is the current exporter url path, it can be changed by Reload without restarting the http server.
+	path    atomic.Value
+	handler http.Handler
+}
+
+// ServeHTTP serves the metrics on the current path.
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := p.path.Load().(string)
+	// same as http.ServeMux, a path ending in a slash matches the whole subtree.
+	if r.URL.Path != path && !(strings.HasSuffix(path, "/") && strings.HasPrefix(r.URL.Path, path)) {
+		http.NotFound(w, r)
+		return
+	}
+	p.handler.ServeHTTP(w, r)
+}
+
+// serve starts the exporter http server on the given address.
+func (p *Prometheus) serve(address string) (*http.Server, error) {
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	httpServer := &http.Server{
+		Addr:    address,
+		Handler: p,
+	}
+	go func() {
+		err := httpServer.Serve(ln)
+		if err != nil && err != http.ErrServerClosed {
+			log.Error("exporter stopped", zap.Error(err))
+		}
+	}()
+	return httpServer, nil
 }
 
 func (p *Prometheus) Load(service server.Server) error {
@@ -51,19 +86,41 @@ func (p *Prometheus) Load(service server.Server) error {
 	p.statsManager = service.StatsManager()
 	r := prometheus.DefaultRegisterer
 	r.MustRegister(p)
-	mu := http.NewServeMux()
-	mu.Handle(p.path, promhttp.Handler())
-	p.httpServer.Handler = mu
-	go func() {
-		err := p.httpServer.ListenAndServe()
-		if err != nil && err != http.ErrServerClosed {
-			panic(err.Error())
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handler = promhttp.Handler()
+	p.path.Store(p.config.Path)
+	var err error
+	p.httpServer, err = p.serve(p.config.ListenAddress)
+	return err
+}
+
+// Reload applies the new path in place, and restarts the exporter http server if the listen address is changed.
+// The old http server keeps running if the new one fails to start.
+func (p *Prometheus) Reload(config config.Config) error {
+	cfg := *config.Plugins[Name].(*Config)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if cfg == p.config {
+		return nil
+	}
+	if cfg.ListenAddress != p.config.ListenAddress {
+		httpServer, err := p.serve(cfg.ListenAddress)
+		if err != nil {
+			return err
 		}
-	}()
+		_ = p.httpServer.Shutdown(context.Background())
+		p.httpServer = httpServer
+	}
+	p.path.Store(cfg.Path)
+	p.config = cfg
+	log.Info("exporter reloaded", zap.String("listen_address", cfg.ListenAddress), zap.String("path", cfg.Path))
 	return nil
 }
 
 func (p *Prometheus) Unload() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	return p.httpServer.Shutdown(context.Background())
 }
 
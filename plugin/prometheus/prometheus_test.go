@@ -0,0 +1,73 @@
+package prometheus
+
+import (
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt/config"
+)
+
+func freeAddress(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+func get(url string) int {
+	resp, err := http.Get(url)
+	if err != nil {
+		return 0
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestPrometheus_Reload(t *testing.T) {
+	a := assert.New(t)
+	log = zap.NewNop()
+	addr := freeAddress(t)
+	p := &Prometheus{
+		config: Config{ListenAddress: addr, Path: "/metrics"},
+	}
+	p.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	p.path.Store(p.config.Path)
+	var err error
+	p.httpServer, err = p.serve(addr)
+	a.Nil(err)
+	defer p.Unload()
+	a.Equal(http.StatusOK, get("http://"+addr+"/metrics"))
+
+	// path changed, the http server is not restarted.
+	old := p.httpServer
+	cfg := config.DefaultConfig()
+	cfg.Plugins[Name] = &Config{ListenAddress: addr, Path: "/new"}
+	a.Nil(p.Reload(cfg))
+	a.Equal(old, p.httpServer)
+	a.Equal(http.StatusNotFound, get("http://"+addr+"/metrics"))
+	a.Equal(http.StatusOK, get("http://"+addr+"/new"))
+
+	// the new address is in use, the old http server keeps running.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	a.Nil(err)
+	defer ln.Close()
+	cfg.Plugins[Name] = &Config{ListenAddress: ln.Addr().String(), Path: "/new"}
+	a.NotNil(p.Reload(cfg))
+	a.Equal(old, p.httpServer)
+	a.Equal(Config{ListenAddress: addr, Path: "/new"}, p.config)
+	a.Equal(http.StatusOK, get("http://"+addr+"/new"))
+
+	// address changed.
+	addr2 := freeAddress(t)
+	cfg.Plugins[Name] = &Config{ListenAddress: addr2, Path: "/new"}
+	a.Nil(p.Reload(cfg))
+	a.NotEqual(old, p.httpServer)
+	a.Equal(http.StatusOK, get("http://"+addr2+"/new"))
+	a.Equal(0, get("http://"+addr+"/new"))
+}
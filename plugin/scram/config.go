@@ -0,0 +1,47 @@
+package scram
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/DrmagicE/gmqtt/plugin/auth"
+)
+
+// Config is the configuration for the scram plugin.
+type Config struct {
+	// Mechanisms is the enabled SCRAM mechanisms, which are used as the MQTT v5 authentication methods.
+	// Possible values: SCRAM-SHA-1 | SCRAM-SHA-256
+	Mechanisms []string `yaml:"mechanisms"`
+}
+
+// Validate validates the configuration, and return an error if it is invalid.
+func (c *Config) Validate() error {
+	if len(c.Mechanisms) == 0 {
+		return errors.New("mechanisms must be set")
+	}
+	for _, v := range c.Mechanisms {
+		if auth.ScramHash(v) == nil {
+			return fmt.Errorf("invalid mechanism: %s", v)
+		}
+	}
+	return nil
+}
+
+// DefaultConfig is the default configuration.
+var DefaultConfig = Config{
+	Mechanisms: []string{auth.ScramSHA1, auth.ScramSHA256},
+}
+
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type cfg Config
+	var v = &struct {
+		Scram cfg `yaml:"scram"`
+	}{
+		Scram: cfg(DefaultConfig),
+	}
+	if err := unmarshal(v); err != nil {
+		return err
+	}
+	*c = Config(v.Scram)
+	return nil
+}
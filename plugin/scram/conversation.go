@@ -0,0 +1,131 @@
+package scram
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/DrmagicE/gmqtt/plugin/auth"
+)
+
+// conversation is the server side state of a SCRAM authentication exchange, see RFC 5802.
+type conversation struct {
+	hash       func() hash.Hash
+	credential *auth.ScramCredential
+	// username is the username in the client-first-message.
+	username        string
+	gs2Header       string
+	clientFirstBare string
+	serverFirst     string
+	// nonce is the combined client and server nonce.
+	nonce string
+}
+
+// decodeUsername decodes the saslname, see RFC 5802 section 5.1.
+func decodeUsername(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '=' {
+			b.WriteByte(s[i])
+			continue
+		}
+		switch {
+		case strings.HasPrefix(s[i:], "=2C"):
+			b.WriteByte(',')
+		case strings.HasPrefix(s[i:], "=3D"):
+			b.WriteByte('=')
+		default:
+			return "", errors.New("invalid username encoding")
+		}
+		i += 2
+	}
+	return b.String(), nil
+}
+
+// parseClientFirst parses the client-first-message.
+func (c *conversation) parseClientFirst(msg string) (clientNonce string, err error) {
+	parts := strings.SplitN(msg, ",", 3)
+	if len(parts) != 3 {
+		return "", errors.New("invalid client-first-message")
+	}
+	// channel binding is not supported
+	if parts[0] != "n" && parts[0] != "y" {
+		return "", fmt.Errorf("unsupported channel binding flag: %s", parts[0])
+	}
+	if parts[1] != "" {
+		return "", errors.New("authzid is not supported")
+	}
+	c.gs2Header = parts[0] + "," + parts[1] + ","
+	c.clientFirstBare = parts[2]
+	attrs := strings.Split(c.clientFirstBare, ",")
+	if len(attrs) < 2 || !strings.HasPrefix(attrs[0], "n=") || !strings.HasPrefix(attrs[1], "r=") {
+		return "", errors.New("invalid client-first-message")
+	}
+	c.username, err = decodeUsername(attrs[0][2:])
+	if err != nil {
+		return "", err
+	}
+	if c.username == "" {
+		return "", errors.New("empty username")
+	}
+	clientNonce = attrs[1][2:]
+	if clientNonce == "" {
+		return "", errors.New("empty nonce")
+	}
+	return clientNonce, nil
+}
+
+// serverFirstMessage returns the server-first-message.
+func (c *conversation) serverFirstMessage(clientNonce, serverNonce string) string {
+	c.nonce = clientNonce + serverNonce
+	c.serverFirst = fmt.Sprintf("r=%s,s=%s,i=%d",
+		c.nonce, base64.StdEncoding.EncodeToString(c.credential.Salt), c.credential.Iterations)
+	return c.serverFirst
+}
+
+// clientFinal verifies the client-final-message and returns the server-final-message.
+func (c *conversation) clientFinal(msg string) (serverFinal string, err error) {
+	i := strings.LastIndex(msg, ",p=")
+	if i < 0 {
+		return "", errors.New("invalid client-final-message")
+	}
+	withoutProof := msg[:i]
+	proof, err := base64.StdEncoding.DecodeString(msg[i+3:])
+	if err != nil {
+		return "", fmt.Errorf("invalid proof: %s", err)
+	}
+	attrs := strings.Split(withoutProof, ",")
+	if len(attrs) < 2 || !strings.HasPrefix(attrs[0], "c=") || !strings.HasPrefix(attrs[1], "r=") {
+		return "", errors.New("invalid client-final-message")
+	}
+	if attrs[0][2:] != base64.StdEncoding.EncodeToString([]byte(c.gs2Header)) {
+		return "", errors.New("channel binding mismatch")
+	}
+	if attrs[1][2:] != c.nonce {
+		return "", errors.New("nonce mismatch")
+	}
+	authMessage := []byte(c.clientFirstBare + "," + c.serverFirst + "," + withoutProof)
+	clientSignature := hmacSum(c.hash, c.credential.StoredKey, authMessage)
+	if len(proof) != len(clientSignature) {
+		return "", errors.New("invalid proof")
+	}
+	clientKey := make([]byte, len(proof))
+	for i := range proof {
+		clientKey[i] = proof[i] ^ clientSignature[i]
+	}
+	h := c.hash()
+	h.Write(clientKey)
+	if !hmac.Equal(h.Sum(nil), c.credential.StoredKey) {
+		return "", errors.New("invalid proof")
+	}
+	return "v=" + base64.StdEncoding.EncodeToString(hmacSum(c.hash, c.credential.ServerKey, authMessage)), nil
+}
+
+func hmacSum(h func() hash.Hash, key, data []byte) []byte {
+	mac := hmac.New(h, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
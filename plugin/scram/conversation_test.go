@@ -0,0 +1,190 @@
+package scram
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/pbkdf2"
+
+	"github.com/DrmagicE/gmqtt/plugin/auth"
+)
+
+// testCredential returns the credential for the password with the given base64 encoded salt.
+func testCredential(mechanism, password, salt string) *auth.ScramCredential {
+	h := auth.ScramHash(mechanism)
+	s, _ := base64.StdEncoding.DecodeString(salt)
+	salted := pbkdf2.Key([]byte(password), s, 4096, h().Size(), h)
+	stored := h()
+	stored.Write(hmacSum(h, salted, []byte("Client Key")))
+	return &auth.ScramCredential{
+		Mechanism:  mechanism,
+		Iterations: 4096,
+		Salt:       s,
+		StoredKey:  stored.Sum(nil),
+		ServerKey:  hmacSum(h, salted, []byte("Server Key")),
+	}
+}
+
+// scramClient is the client side of the SCRAM exchange.
+type scramClient struct {
+	hash            func() hash.Hash
+	username        string
+	password        string
+	nonce           string
+	clientFirstBare string
+	serverSignature string
+}
+
+func (c *scramClient) first() string {
+	c.clientFirstBare = fmt.Sprintf("n=%s,r=%s", strings.NewReplacer("=", "=3D", ",", "=2C").Replace(c.username), c.nonce)
+	return "n,," + c.clientFirstBare
+}
+
+func (c *scramClient) final(serverFirst string) (string, error) {
+	var nonce, salt string
+	var iter int
+	for _, v := range strings.Split(serverFirst, ",") {
+		switch {
+		case strings.HasPrefix(v, "r="):
+			nonce = v[2:]
+		case strings.HasPrefix(v, "s="):
+			salt = v[2:]
+		case strings.HasPrefix(v, "i="):
+			iter, _ = strconv.Atoi(v[2:])
+		}
+	}
+	if !strings.HasPrefix(nonce, c.nonce) {
+		return "", errors.New("invalid nonce")
+	}
+	s, err := base64.StdEncoding.DecodeString(salt)
+	if err != nil {
+		return "", err
+	}
+	salted := pbkdf2.Key([]byte(c.password), s, iter, c.hash().Size(), c.hash)
+	clientKey := hmacSum(c.hash, salted, []byte("Client Key"))
+	storedKey := c.hash()
+	storedKey.Write(clientKey)
+	withoutProof := "c=biws,r=" + nonce
+	authMessage := []byte(c.clientFirstBare + "," + serverFirst + "," + withoutProof)
+	clientSignature := hmacSum(c.hash, storedKey.Sum(nil), authMessage)
+	for i := range clientKey {
+		clientKey[i] ^= clientSignature[i]
+	}
+	c.serverSignature = "v=" + base64.StdEncoding.EncodeToString(hmacSum(c.hash, hmacSum(c.hash, salted, []byte("Server Key")), authMessage))
+	return withoutProof + ",p=" + base64.StdEncoding.EncodeToString(clientKey), nil
+}
+
+func (c *scramClient) verify(serverFinal string) bool {
+	return hmac.Equal([]byte(serverFinal), []byte(c.serverSignature))
+}
+
+func TestScram_rfcExamples(t *testing.T) {
+	for _, v := range []struct {
+		mechanism   string
+		salt        string
+		serverNonce string
+		clientFirst string
+		serverFirst string
+		clientFinal string
+		serverFinal string
+	}{
+		// RFC 5802
+		{
+			mechanism:   auth.ScramSHA1,
+			salt:        "QSXCR+Q6sek8bf92",
+			serverNonce: "3rfcNHYJY1ZVvWVs7j",
+			clientFirst: "n,,n=user,r=fyko+d2lbbFgONRv9qkxdawL",
+			serverFirst: "r=fyko+d2lbbFgONRv9qkxdawL3rfcNHYJY1ZVvWVs7j,s=QSXCR+Q6sek8bf92,i=4096",
+			clientFinal: "c=biws,r=fyko+d2lbbFgONRv9qkxdawL3rfcNHYJY1ZVvWVs7j,p=v0X8v3Bz2T0CJGbJQyF0X+HI4Ts=",
+			serverFinal: "v=rmF9pqV8S7suAoZWja4dJRkFsKQ=",
+		},
+		// RFC 7677
+		{
+			mechanism:   auth.ScramSHA256,
+			salt:        "W22ZaJ0SNY7soEsUEjb6gQ==",
+			serverNonce: "%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0",
+			clientFirst: "n,,n=user,r=rOprNGfwEbeRWgbNEkqO",
+			serverFirst: "r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,s=W22ZaJ0SNY7soEsUEjb6gQ==,i=4096",
+			clientFinal: "c=biws,r=rOprNGfwEbeRWgbNEkqO%hvYDpWUa2RaTCAfuxFIlj)hNlF$k0,p=dHzbZapWIk4jUhN+Ute9ytag9zjfMHgsqmmiz7AndVQ=",
+			serverFinal: "v=6rriTRBi23WpRR/wtup+mMhUZUn/dB5nLTJRsjl95G4=",
+		},
+	} {
+		t.Run(v.mechanism, func(t *testing.T) {
+			a := assert.New(t)
+			s := &Scram{
+				credential: func(username, mechanism string) (*auth.ScramCredential, error) {
+					a.Equal("user", username)
+					return testCredential(mechanism, "pencil", v.salt), nil
+				},
+				newNonce: func() (string, error) {
+					return v.serverNonce, nil
+				},
+			}
+			conv, serverFirst, err := s.start(v.mechanism, []byte(v.clientFirst))
+			a.Nil(err)
+			a.Equal(v.serverFirst, serverFirst)
+			serverFinal, err := conv.clientFinal(v.clientFinal)
+			a.Nil(err)
+			a.Equal(v.serverFinal, serverFinal)
+
+			// wrong proof
+			conv, _, err = s.start(v.mechanism, []byte(v.clientFirst))
+			a.Nil(err)
+			_, err = conv.clientFinal(v.clientFinal[:len(v.clientFinal)-5] + "AAAA=")
+			a.NotNil(err)
+		})
+	}
+}
+
+func TestScram_start(t *testing.T) {
+	a := assert.New(t)
+	s := &Scram{
+		secret: []byte("secret"),
+		credential: func(username, mechanism string) (*auth.ScramCredential, error) {
+			return nil, nil
+		},
+		salt: func(username, mechanism string) []byte {
+			return []byte(mechanism + "," + username)
+		},
+		iterations: func() int {
+			return 4096
+		},
+		newNonce: newNonce,
+	}
+	for _, v := range []string{
+		"p=tls-unique,,n=user,r=nonce",
+		"n,a=admin,n=user,r=nonce",
+		"n,,r=nonce,n=user",
+		"n,,n=,r=nonce",
+		"n,,n=us=er,r=nonce",
+		"n,,n=user,r=",
+		"n,,n=user",
+	} {
+		_, _, err := s.start(auth.ScramSHA256, []byte(v))
+		a.NotNil(err, v)
+	}
+	conv, _, err := s.start(auth.ScramSHA256, []byte("y,,n=a=2Cb=3Dc,r=nonce"))
+	a.Nil(err)
+	a.Equal("a,b=c", conv.username)
+
+	// nonexistent account gets a stable fake salt and fails in the final step.
+	c := &scramClient{hash: auth.ScramHash(auth.ScramSHA256), username: "nobody", password: "pencil", nonce: "nonce"}
+	conv, serverFirst, err := s.start(auth.ScramSHA256, []byte(c.first()))
+	a.Nil(err)
+	conv2, _, err := s.start(auth.ScramSHA256, []byte(c.first()))
+	a.Nil(err)
+	a.Equal(conv.credential.Salt, conv2.credential.Salt)
+	a.Equal([]byte(auth.ScramSHA256+",nobody"), conv.credential.Salt)
+	a.Equal(4096, conv.credential.Iterations)
+	clientFinal, err := c.final(serverFirst)
+	a.Nil(err)
+	_, err = conv.clientFinal(clientFinal)
+	a.NotNil(err)
+}
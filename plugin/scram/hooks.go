@@ -0,0 +1,124 @@
+package scram
+
+import (
+	"context"
+	"errors"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func (s *Scram) HookWrapper() server.HookWrapper {
+	return server.HookWrapper{
+		OnEnhancedAuthWrapper: s.OnEnhancedAuthWrapper,
+		OnReAuthWrapper:       s.OnReAuthWrapper,
+		OnClosedWrapper:       s.OnClosedWrapper,
+	}
+}
+
+var errNotAuthorized = &codes.Error{
+	Code: codes.NotAuthorized,
+}
+
+// OnEnhancedAuthWrapper starts the SCRAM exchange if the auth method is an enabled mechanism.
+func (s *Scram) OnEnhancedAuthWrapper(pre server.OnEnhancedAuth) server.OnEnhancedAuth {
+	return func(ctx context.Context, client server.Client, req *server.ConnectRequest) (resp *server.EnhancedAuthResponse, err error) {
+		method := req.Connect.Properties.AuthMethod
+		if !s.isMechanism(method) {
+			return pre(ctx, client, req)
+		}
+		conv, serverFirst, err := s.start(string(method), req.Connect.Properties.AuthData)
+		// The username field is used by other plugins, such as acl, so it must be the authenticated username.
+		if err == nil && conv.username != string(req.Connect.Username) {
+			err = errors.New("username mismatch")
+		}
+		if err != nil {
+			log.Debug("authentication failed",
+				zap.String("client_id", string(req.Connect.ClientID)),
+				zap.Error(err))
+			return nil, errNotAuthorized
+		}
+		return &server.EnhancedAuthResponse{
+			Continue: true,
+			OnAuth: func(ctx context.Context, client server.Client, req *server.AuthRequest) (*server.AuthResponse, error) {
+				serverFinal, err := conv.clientFinal(string(req.Auth.Properties.AuthData))
+				if err != nil {
+					log.Debug("authentication failed",
+						zap.String("username", conv.username),
+						zap.Error(err))
+					return nil, errNotAuthorized
+				}
+				return &server.AuthResponse{
+					Continue: false,
+					AuthData: []byte(serverFinal),
+				}, nil
+			},
+			AuthData: []byte(serverFirst),
+		}, nil
+	}
+}
+
+// OnReAuthWrapper handles the SCRAM exchange of the re-authentication.
+func (s *Scram) OnReAuthWrapper(pre server.OnReAuth) server.OnReAuth {
+	return func(ctx context.Context, client server.Client, auth *packets.Auth) (*server.AuthResponse, error) {
+		opts := client.ClientOptions()
+		if !s.isMechanism(opts.AuthMethod) {
+			return pre(ctx, client, auth)
+		}
+		s.mu.Lock()
+		pending := s.reAuth[client]
+		delete(s.reAuth, client)
+		s.mu.Unlock()
+
+		switch auth.Code {
+		case codes.ReAuthenticate:
+			conv, serverFirst, err := s.start(string(opts.AuthMethod), auth.Properties.AuthData)
+			if err == nil && conv.username != opts.Username {
+				err = errors.New("username mismatch")
+			}
+			if err != nil {
+				log.Debug("re-authentication failed",
+					zap.String("client_id", opts.ClientID),
+					zap.Error(err))
+				return nil, errNotAuthorized
+			}
+			s.mu.Lock()
+			s.reAuth[client] = conv
+			s.mu.Unlock()
+			return &server.AuthResponse{
+				Continue: true,
+				AuthData: []byte(serverFirst),
+			}, nil
+		case codes.ContinueAuthentication:
+			if pending == nil {
+				return nil, codes.ErrProtocol
+			}
+			serverFinal, err := pending.clientFinal(string(auth.Properties.AuthData))
+			if err != nil {
+				log.Debug("re-authentication failed",
+					zap.String("client_id", opts.ClientID),
+					zap.Error(err))
+				return nil, errNotAuthorized
+			}
+			return &server.AuthResponse{
+				Continue: false,
+				AuthData: []byte(serverFinal),
+			}, nil
+		default:
+			return nil, codes.ErrProtocol
+		}
+	}
+}
+
+// OnClosedWrapper removes the pending re-authentication conversation.
+func (s *Scram) OnClosedWrapper(pre server.OnClosed) server.OnClosed {
+	return func(ctx context.Context, client server.Client, err error) {
+		pre(ctx, client, err)
+		s.mu.Lock()
+		delete(s.reAuth, client)
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,130 @@
+package scram
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/plugin/auth"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+var _ server.Plugin = (*Scram)(nil)
+
+const Name = "scram"
+
+func init() {
+	server.RegisterPlugin(Name, New)
+	config.RegisterDefaultPluginConfig(Name, &DefaultConfig)
+}
+
+func New(config config.Config) (server.Plugin, error) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return nil, err
+	}
+	return &Scram{
+		config:   config.Plugins[Name].(*Config),
+		secret:   secret,
+		newNonce: newNonce,
+		reAuth:   make(map[server.Client]*conversation),
+	}, nil
+}
+
+var log *zap.Logger
+
+// Scram provides the SCRAM-SHA-1 and SCRAM-SHA-256 enhanced authentication for gmqtt.
+// The credentials are stored in the account store of the auth plugin.
+type Scram struct {
+	config *Config
+	// credential returns the credential for the username, it returns nil if the account does not exist.
+	credential func(username, mechanism string) (*auth.ScramCredential, error)
+	// salt and iterations return the parameters of the fake credential for nonexistent accounts,
+	// which are the same as the ones of the accounts stored in plain text.
+	salt       func(username, mechanism string) []byte
+	iterations func() int
+	// secret is used as the keys of the fake credential for nonexistent accounts.
+	secret []byte
+	// newNonce generates the server nonce.
+	newNonce func() (string, error)
+	// mu guards reAuth
+	mu sync.Mutex
+	// reAuth stores the re-authentication conversations which are waiting for the client-final-message.
+	reAuth map[server.Client]*conversation
+}
+
+func newNonce() (string, error) {
+	b := make([]byte, 18)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// isMechanism returns whether the auth method is an enabled mechanism.
+func (s *Scram) isMechanism(method []byte) bool {
+	for _, v := range s.config.Mechanisms {
+		if v == string(method) {
+			return true
+		}
+	}
+	return false
+}
+
+// start handles the client-first-message and returns the conversation and the server-first-message.
+func (s *Scram) start(mechanism string, clientFirst []byte) (*conversation, string, error) {
+	c := &conversation{
+		hash: auth.ScramHash(mechanism),
+	}
+	clientNonce, err := c.parseClientFirst(string(clientFirst))
+	if err != nil {
+		return nil, "", err
+	}
+	c.credential, err = s.credential(c.username, mechanism)
+	if err != nil {
+		return nil, "", err
+	}
+	if c.credential == nil {
+		// Do not disclose the nonexistence of the account, the authentication will fail in the final step.
+		c.credential = &auth.ScramCredential{
+			Mechanism:  mechanism,
+			Iterations: s.iterations(),
+			Salt:       s.salt(c.username, mechanism),
+			StoredKey:  s.secret,
+			ServerKey:  s.secret,
+		}
+	}
+	serverNonce, err := s.newNonce()
+	if err != nil {
+		return nil, "", err
+	}
+	return c, c.serverFirstMessage(clientNonce, serverNonce), nil
+}
+
+func (s *Scram) Load(service server.Server) error {
+	log = server.LoggerWithField(zap.String("plugin", Name))
+	for _, v := range service.Plugins() {
+		if a, ok := v.(*auth.Auth); ok {
+			s.credential = a.ScramCredential
+			s.salt = a.ScramSalt
+			s.iterations = a.ScramIterations
+			log.Info("scram mechanisms enabled", zap.Strings("mechanisms", s.config.Mechanisms))
+			return nil
+		}
+	}
+	return errors.New("the scram plugin requires the auth plugin to be enabled")
+}
+
+func (s *Scram) Unload() error {
+	return nil
+}
+
+func (s *Scram) Name() string {
+	return Name
+}
@@ -0,0 +1,223 @@
+package scram
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt/config"
+	_ "github.com/DrmagicE/gmqtt/persistence"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/plugin/auth"
+	"github.com/DrmagicE/gmqtt/server"
+	_ "github.com/DrmagicE/gmqtt/topicalias/fifo"
+)
+
+type testClient struct {
+	t      *testing.T
+	nc     net.Conn
+	reader *packets.Reader
+	writer *packets.Writer
+}
+
+func (c *testClient) write(p packets.Packet) {
+	if err := c.writer.WriteAndFlush(p); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *testClient) read() packets.Packet {
+	_ = c.nc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	p, err := c.reader.ReadPacket()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return p
+}
+
+func (c *testClient) close() {
+	_ = c.nc.Close()
+}
+
+// connect connects to the broker and sends the client-first-message of the SCRAM client.
+func connect(t *testing.T, addr, username string, sc *scramClient, mechanism string) *testClient {
+	nc, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &testClient{
+		t:      t,
+		nc:     nc,
+		reader: packets.NewReader(nc),
+		writer: packets.NewWriter(nc),
+	}
+	c.reader.SetVersion(packets.Version5)
+	c.write(&packets.Connect{
+		Version:       packets.Version5,
+		ProtocolName:  []byte("MQTT"),
+		ProtocolLevel: packets.Version5,
+		CleanStart:    true,
+		ClientID:      []byte("client"),
+		UsernameFlag:  true,
+		Username:      []byte(username),
+		Properties: &packets.Properties{
+			AuthMethod: []byte(mechanism),
+			AuthData:   []byte(sc.first()),
+		},
+	})
+	return c
+}
+
+func startBroker(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "gmqtt_scram")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	authCfg := auth.DefaultConfig
+	authCfg.PasswordFile = path.Join(dir, "gmqtt_password.yml")
+	authCfg.Scram = true
+	cfg.Plugins[auth.Name] = &authCfg
+	cfg.Plugins[Name] = &DefaultConfig
+	a, err := auth.New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := server.New(
+		server.WithConfig(cfg),
+		server.WithTCPListener(ln),
+		server.WithPlugin(a, s),
+	)
+	if err := srv.Run(); err != nil {
+		t.Fatal(err)
+	}
+	_, err = a.(*auth.Auth).Update(context.Background(), &auth.UpdateAccountRequest{
+		Username: "user",
+		Password: "pencil",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ln.Addr().String(), func() {
+		_ = srv.Stop(context.Background())
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestScram(t *testing.T) {
+	addr, stop := startBroker(t)
+	defer stop()
+	for _, mechanism := range DefaultConfig.Mechanisms {
+		t.Run(mechanism, func(t *testing.T) {
+			a := assert.New(t)
+			sc := &scramClient{hash: auth.ScramHash(mechanism), username: "user", password: "pencil", nonce: "nonce1"}
+			c := connect(t, addr, "user", sc, mechanism)
+			defer c.close()
+			serverFirst := c.read().(*packets.Auth)
+			a.Equal(codes.ContinueAuthentication, serverFirst.Code)
+			a.Equal([]byte(mechanism), serverFirst.Properties.AuthMethod)
+			clientFinal, err := sc.final(string(serverFirst.Properties.AuthData))
+			a.Nil(err)
+			c.write(&packets.Auth{
+				Code: codes.ContinueAuthentication,
+				Properties: &packets.Properties{
+					AuthMethod: []byte(mechanism),
+					AuthData:   []byte(clientFinal),
+				},
+			})
+			connack := c.read().(*packets.Connack)
+			a.Equal(codes.Success, connack.Code)
+			a.Equal([]byte(mechanism), connack.Properties.AuthMethod)
+			a.True(sc.verify(string(connack.Properties.AuthData)))
+
+			// re-authentication
+			sc = &scramClient{hash: auth.ScramHash(mechanism), username: "user", password: "pencil", nonce: "nonce2"}
+			c.write(&packets.Auth{
+				Code: codes.ReAuthenticate,
+				Properties: &packets.Properties{
+					AuthMethod: []byte(mechanism),
+					AuthData:   []byte(sc.first()),
+				},
+			})
+			serverFirst = c.read().(*packets.Auth)
+			a.Equal(codes.ContinueAuthentication, serverFirst.Code)
+			clientFinal, err = sc.final(string(serverFirst.Properties.AuthData))
+			a.Nil(err)
+			c.write(&packets.Auth{
+				Code: codes.ContinueAuthentication,
+				Properties: &packets.Properties{
+					AuthMethod: []byte(mechanism),
+					AuthData:   []byte(clientFinal),
+				},
+			})
+			serverFinal := c.read().(*packets.Auth)
+			a.Equal(codes.Success, serverFinal.Code)
+			a.True(sc.verify(string(serverFinal.Properties.AuthData)))
+
+			// re-authentication with wrong password disconnects the client.
+			sc = &scramClient{hash: auth.ScramHash(mechanism), username: "user", password: "wrong", nonce: "nonce3"}
+			c.write(&packets.Auth{
+				Code: codes.ReAuthenticate,
+				Properties: &packets.Properties{
+					AuthMethod: []byte(mechanism),
+					AuthData:   []byte(sc.first()),
+				},
+			})
+			serverFirst = c.read().(*packets.Auth)
+			clientFinal, err = sc.final(string(serverFirst.Properties.AuthData))
+			a.Nil(err)
+			c.write(&packets.Auth{
+				Code: codes.ContinueAuthentication,
+				Properties: &packets.Properties{
+					AuthMethod: []byte(mechanism),
+					AuthData:   []byte(clientFinal),
+				},
+			})
+			dis := c.read().(*packets.Disconnect)
+			a.Equal(codes.NotAuthorized, dis.Code)
+		})
+	}
+}
+
+func TestScram_notAuthorized(t *testing.T) {
+	a := assert.New(t)
+	addr, stop := startBroker(t)
+	defer stop()
+
+	// wrong password
+	sc := &scramClient{hash: auth.ScramHash(auth.ScramSHA256), username: "user", password: "wrong", nonce: "nonce"}
+	c := connect(t, addr, "user", sc, auth.ScramSHA256)
+	defer c.close()
+	serverFirst := c.read().(*packets.Auth)
+	clientFinal, err := sc.final(string(serverFirst.Properties.AuthData))
+	a.Nil(err)
+	c.write(&packets.Auth{
+		Code: codes.ContinueAuthentication,
+		Properties: &packets.Properties{
+			AuthMethod: []byte(auth.ScramSHA256),
+			AuthData:   []byte(clientFinal),
+		},
+	})
+	a.Equal(codes.NotAuthorized, c.read().(*packets.Connack).Code)
+
+	// the username field does not match the scram username.
+	sc = &scramClient{hash: auth.ScramHash(auth.ScramSHA256), username: "user", password: "pencil", nonce: "nonce"}
+	c2 := connect(t, addr, "admin", sc, auth.ScramSHA256)
+	defer c2.close()
+	a.Equal(codes.NotAuthorized, c2.read().(*packets.Connack).Code)
+}
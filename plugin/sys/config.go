@@ -0,0 +1,56 @@
+package sys
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Config is the configuration for the sys plugin.
+type Config struct {
+	// Prefix is the topic prefix of the published messages, it must be in the $SYS topic tree.
+	Prefix string `yaml:"prefix"`
+	// Interval is the interval of publishing the broker statistics.
+	Interval time.Duration `yaml:"interval"`
+	// ClientEvents indicates whether to publish the client connected and disconnected events.
+	ClientEvents bool `yaml:"client_events"`
+	// AllowedUsernames is the list of usernames that are allowed to subscribe to $SYS topics.
+	AllowedUsernames []string `yaml:"allowed_usernames"`
+	// AllowedClientIDs is the list of client ids that are allowed to subscribe to $SYS topics.
+	AllowedClientIDs []string `yaml:"allowed_client_ids"`
+}
+
+// Validate validates the configuration, and return an error if it is invalid.
+func (c *Config) Validate() error {
+	if c.Prefix != sysTopic && !strings.HasPrefix(c.Prefix, sysTopic+"/") {
+		return errors.New("prefix must start with $SYS/")
+	}
+	if strings.ContainsAny(c.Prefix, "#+") || strings.HasSuffix(c.Prefix, "/") {
+		return errors.New("invalid prefix")
+	}
+	if c.Interval < time.Second {
+		return errors.New("interval must be at least 1s")
+	}
+	return nil
+}
+
+// DefaultConfig is the default configuration.
+var DefaultConfig = Config{
+	Prefix:       "$SYS/broker",
+	Interval:     10 * time.Second,
+	ClientEvents: true,
+}
+
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type cfg Config
+	var v = &struct {
+		Sys cfg `yaml:"sys"`
+	}{
+		Sys: cfg(DefaultConfig),
+	}
+	if err := unmarshal(v); err != nil {
+		return err
+	}
+	*c = Config(v.Sys)
+	return nil
+}
@@ -0,0 +1,131 @@
+package sys
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func (s *Sys) HookWrapper() server.HookWrapper {
+	return server.HookWrapper{
+		OnSubscribeWrapper:  s.OnSubscribeWrapper,
+		OnMsgArrivedWrapper: s.OnMsgArrivedWrapper,
+		OnConnectedWrapper:  s.OnConnectedWrapper,
+		OnClosedWrapper:     s.OnClosedWrapper,
+	}
+}
+
+// isSysTopic returns whether the topic name or topic filter is in the $SYS topic tree.
+func isSysTopic(topic string) bool {
+	return topic == sysTopic || strings.HasPrefix(topic, sysTopic+"/")
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+// authorized returns whether the client is allowed to subscribe to $SYS topics.
+func (s *Sys) authorized(opts *server.ClientOptions) bool {
+	cfg := s.getConfig()
+	return contains(cfg.AllowedUsernames, opts.Username) || contains(cfg.AllowedClientIDs, opts.ClientID)
+}
+
+func (s *Sys) OnSubscribeWrapper(pre server.OnSubscribe) server.OnSubscribe {
+	return func(ctx context.Context, client server.Client, req *server.SubscribeRequest) error {
+		err := pre(ctx, client, req)
+		if err != nil {
+			return err
+		}
+		opts := client.ClientOptions()
+		for k, v := range req.Subscriptions {
+			if v.Error != nil || !isSysTopic(v.Sub.TopicFilter) || s.authorized(opts) {
+				continue
+			}
+			log.Debug("$SYS subscription not authorized",
+				zap.String("client_id", opts.ClientID),
+				zap.String("username", opts.Username),
+				zap.String("topic", k))
+			req.Reject(k, &codes.Error{
+				Code: codes.NotAuthorized,
+			})
+		}
+		return nil
+	}
+}
+
+// OnMsgArrivedWrapper drops the messages published to $SYS topics by clients, only the broker can publish to $SYS.
+func (s *Sys) OnMsgArrivedWrapper(pre server.OnMsgArrived) server.OnMsgArrived {
+	return func(ctx context.Context, client server.Client, req *server.MsgArrivedRequest) error {
+		err := pre(ctx, client, req)
+		if err != nil || req.Message == nil || !isSysTopic(req.Message.Topic) {
+			return err
+		}
+		req.Drop()
+		if client.Version() == packets.Version5 {
+			return &codes.Error{
+				Code: codes.NotAuthorized,
+			}
+		}
+		return nil
+	}
+}
+
+func (s *Sys) OnConnectedWrapper(pre server.OnConnected) server.OnConnected {
+	return func(ctx context.Context, client server.Client) {
+		pre(ctx, client)
+		s.mu.Lock()
+		s.connected[client] = struct{}{}
+		s.mu.Unlock()
+		if !s.getConfig().ClientEvents {
+			return
+		}
+		opts := client.ClientOptions()
+		e := &clientEvent{
+			ClientID:        opts.ClientID,
+			Username:        opts.Username,
+			ProtocolVersion: byte(client.Version()),
+			KeepAlive:       opts.KeepAlive,
+			CleanStart:      opts.CleanStart,
+			Timestamp:       client.ConnectedAt().Unix(),
+		}
+		if conn := client.Connection(); conn != nil {
+			e.RemoteAddr = conn.RemoteAddr().String()
+		}
+		s.publishClientEvent("connected", e)
+	}
+}
+
+func (s *Sys) OnClosedWrapper(pre server.OnClosed) server.OnClosed {
+	return func(ctx context.Context, client server.Client, err error) {
+		pre(ctx, client, err)
+		s.mu.Lock()
+		_, ok := s.connected[client]
+		delete(s.connected, client)
+		s.mu.Unlock()
+		// the client is closed before connected
+		if !ok || !s.getConfig().ClientEvents {
+			return
+		}
+		opts := client.ClientOptions()
+		e := &clientEvent{
+			ClientID:  opts.ClientID,
+			Username:  opts.Username,
+			Timestamp: time.Now().Unix(),
+		}
+		if err != nil {
+			e.Reason = err.Error()
+		}
+		s.publishClientEvent("disconnected", e)
+	}
+}
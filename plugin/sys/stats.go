@@ -0,0 +1,78 @@
+package sys
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func formatUint(v uint64) string {
+	return strconv.FormatUint(v, 10)
+}
+
+// packetTopics adds the per packet type values to rs, the topic is the prefix followed by the lower-case packet type.
+func packetTopics(rs map[string]string, prefix string, p *server.PacketBytes) {
+	rs[prefix+"/auth"] = formatUint(p.Auth)
+	rs[prefix+"/connect"] = formatUint(p.Connect)
+	rs[prefix+"/connack"] = formatUint(p.Connack)
+	rs[prefix+"/disconnect"] = formatUint(p.Disconnect)
+	rs[prefix+"/pingreq"] = formatUint(p.Pingreq)
+	rs[prefix+"/pingresp"] = formatUint(p.Pingresp)
+	rs[prefix+"/puback"] = formatUint(p.Puback)
+	rs[prefix+"/pubcomp"] = formatUint(p.Pubcomp)
+	rs[prefix+"/publish"] = formatUint(p.Publish)
+	rs[prefix+"/pubrec"] = formatUint(p.Pubrec)
+	rs[prefix+"/pubrel"] = formatUint(p.Pubrel)
+	rs[prefix+"/suback"] = formatUint(p.Suback)
+	rs[prefix+"/subscribe"] = formatUint(p.Subscribe)
+	rs[prefix+"/unsuback"] = formatUint(p.Unsuback)
+	rs[prefix+"/unsubscribe"] = formatUint(p.Unsubscribe)
+	rs[prefix+"/total"] = formatUint(p.Total)
+}
+
+// qosTopics adds the message statistics of one QoS level to rs.
+func qosTopics(rs map[string]string, prefix string, m *server.MessageQosStats) {
+	rs[prefix+"/received_total"] = formatUint(m.ReceivedTotal)
+	rs[prefix+"/sent_total"] = formatUint(m.SentTotal)
+	rs[prefix+"/dropped/internal"] = formatUint(m.DroppedTotal.Internal)
+	rs[prefix+"/dropped/exceeds_max_packet_size"] = formatUint(m.DroppedTotal.ExceedsMaxPacketSize)
+	rs[prefix+"/dropped/queue_full"] = formatUint(m.DroppedTotal.QueueFull)
+	rs[prefix+"/dropped/expired"] = formatUint(m.DroppedTotal.Expired)
+}
+
+// statsTopics flattens the global statistics into topic-value pairs, the topics are relative to the prefix.
+func statsTopics(st *server.GlobalStats, uptime time.Duration) map[string]string {
+	rs := make(map[string]string)
+	rs["uptime"] = formatUint(uint64(uptime / time.Second))
+
+	cs := &st.ConnectionStats
+	rs["clients/connected_total"] = formatUint(cs.ConnectedTotal)
+	rs["clients/disconnected_total"] = formatUint(cs.DisconnectedTotal)
+	rs["sessions/active"] = formatUint(cs.ActiveCurrent)
+	rs["sessions/inactive"] = formatUint(cs.InactiveCurrent)
+	rs["sessions/created_total"] = formatUint(cs.SessionCreatedTotal)
+	rs["sessions/terminated/taken_over"] = formatUint(cs.SessionTerminated.TakenOver)
+	rs["sessions/terminated/expired"] = formatUint(cs.SessionTerminated.Expired)
+	rs["sessions/terminated/normal"] = formatUint(cs.SessionTerminated.Normal)
+
+	rs["subscriptions/current"] = formatUint(st.SubscriptionStats.SubscriptionsCurrent)
+	rs["subscriptions/total"] = formatUint(st.SubscriptionStats.SubscriptionsTotal)
+
+	ms := &st.MessageStats
+	rs["messages/inflight"] = formatUint(ms.InflightCurrent)
+	rs["messages/queued"] = formatUint(ms.QueuedCurrent)
+	rs["messages/received_total"] = formatUint(ms.Qos0.ReceivedTotal + ms.Qos1.ReceivedTotal + ms.Qos2.ReceivedTotal)
+	rs["messages/sent_total"] = formatUint(ms.Qos0.SentTotal + ms.Qos1.SentTotal + ms.Qos2.SentTotal)
+	rs["messages/dropped_total"] = formatUint(ms.GetDroppedTotal())
+	qosTopics(rs, "messages/qos0", &ms.Qos0)
+	qosTopics(rs, "messages/qos1", &ms.Qos1)
+	qosTopics(rs, "messages/qos2", &ms.Qos2)
+
+	ps := &st.PacketStats
+	packetTopics(rs, "packets/received", &ps.ReceivedTotal)
+	packetTopics(rs, "packets/sent", &ps.SentTotal)
+	packetTopics(rs, "bytes/received", &ps.BytesReceived)
+	packetTopics(rs, "bytes/sent", &ps.BytesSent)
+	return rs
+}
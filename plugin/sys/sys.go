@@ -0,0 +1,201 @@
+package sys
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/retained"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+var _ server.Plugin = (*Sys)(nil)
+var _ server.Reloadable = (*Sys)(nil)
+
+const Name = "sys"
+
+// sysTopic is the first topic level of the $SYS topic tree.
+const sysTopic = "$SYS"
+
+// eventsBufferSize is the max number of the client events waiting to be published.
+const eventsBufferSize = 1024
+
+func init() {
+	server.RegisterPlugin(Name, New)
+	config.RegisterDefaultPluginConfig(Name, &DefaultConfig)
+}
+
+func New(config config.Config) (server.Plugin, error) {
+	return &Sys{
+		config:    config.Plugins[Name].(*Config),
+		connected: make(map[server.Client]struct{}),
+		reload:    make(chan *Config, 1),
+		events:    make(chan *gmqtt.Message, eventsBufferSize),
+		exit:      make(chan struct{}),
+	}, nil
+}
+
+var log *zap.Logger
+
+// Sys publishes the broker statistics and the client events to the $SYS topic tree.
+type Sys struct {
+	// gard config & connected
+	mu     sync.RWMutex
+	config *Config
+	// connected stores the clients that have passed OnConnected.
+	connected map[server.Client]struct{}
+
+	startedAt time.Time
+	publisher server.Publisher
+	retained  retained.Store
+	stats     server.StatsReader
+	// last stores the latest published statistics, key by the topic relative to the prefix.
+	// It is only accessed in the publish loop.
+	last map[string]string
+
+	reload chan *Config
+	// events is the queue of the client events.
+	// The hooks can not publish messages directly, because some of them are called under the server lock.
+	events chan *gmqtt.Message
+	exit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+func (s *Sys) getConfig() *Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config
+}
+
+// publishRetained stores the message as the retained message and publishes it to the subscribers.
+func (s *Sys) publishRetained(topic string, payload []byte) {
+	msg := &gmqtt.Message{
+		Topic:    topic,
+		Payload:  payload,
+		Retained: true,
+	}
+	s.retained.AddOrReplace(msg.Copy())
+	s.publisher.Publish(msg)
+}
+
+// publishStats publishes the statistics that have changed since the last publishing.
+func (s *Sys) publishStats(prefix string) {
+	st := s.stats.GetGlobalStats()
+	rs := statsTopics(&st, time.Since(s.startedAt))
+	for k, v := range rs {
+		if s.last[k] == v {
+			continue
+		}
+		s.publishRetained(prefix+"/"+k, []byte(v))
+	}
+	s.last = rs
+}
+
+// removeStats removes the retained statistics under the prefix.
+func (s *Sys) removeStats(prefix string) {
+	for k := range s.last {
+		s.retained.Remove(prefix + "/" + k)
+	}
+	s.last = nil
+}
+
+func (s *Sys) publishLoop() {
+	defer s.wg.Done()
+	cfg := s.getConfig()
+	ticker := time.NewTicker(cfg.Interval)
+	defer func() {
+		ticker.Stop()
+	}()
+	s.publishStats(cfg.Prefix)
+	for {
+		select {
+		case <-s.exit:
+			return
+		case c := <-s.reload:
+			ticker.Stop()
+			ticker = time.NewTicker(c.Interval)
+			if c.Prefix != cfg.Prefix {
+				s.removeStats(cfg.Prefix)
+			}
+			cfg = c
+			s.publishStats(cfg.Prefix)
+		case <-ticker.C:
+			s.publishStats(cfg.Prefix)
+		case msg := <-s.events:
+			s.publisher.Publish(msg)
+		}
+	}
+}
+
+// clientEvent is the payload of the client connected and disconnected events.
+type clientEvent struct {
+	ClientID        string `json:"client_id"`
+	Username        string `json:"username"`
+	RemoteAddr      string `json:"remote_addr,omitempty"`
+	ProtocolVersion byte   `json:"protocol_version,omitempty"`
+	KeepAlive       uint16 `json:"keep_alive,omitempty"`
+	CleanStart      bool   `json:"clean_start,omitempty"`
+	Reason          string `json:"reason,omitempty"`
+	Timestamp       int64  `json:"timestamp"`
+}
+
+// publishClientEvent queues the client event to be published to {prefix}/clients/{event}.
+// The event is dropped if the queue is full.
+func (s *Sys) publishClientEvent(event string, e *clientEvent) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		log.Error("fail to marshal client event", zap.Error(err))
+		return
+	}
+	msg := &gmqtt.Message{
+		Topic:   s.getConfig().Prefix + "/clients/" + event,
+		Payload: b,
+	}
+	select {
+	case s.events <- msg:
+	default:
+		log.Warn("client event queue is full, dropping event",
+			zap.String("event", event),
+			zap.String("client_id", e.ClientID))
+	}
+}
+
+func (s *Sys) Load(service server.Server) error {
+	log = server.LoggerWithField(zap.String("plugin", Name))
+	s.startedAt = time.Now()
+	s.publisher = service.Publisher()
+	s.retained = service.RetainedService()
+	s.stats = service.StatsManager()
+	s.wg.Add(1)
+	go s.publishLoop()
+	return nil
+}
+
+// Reload applies the new prefix, interval and the $SYS subscription permissions.
+func (s *Sys) Reload(config config.Config) error {
+	cfg := config.Plugins[Name].(*Config)
+	s.mu.Lock()
+	s.config = cfg
+	s.mu.Unlock()
+	// drop the pending config which has not been applied.
+	select {
+	case <-s.reload:
+	default:
+	}
+	s.reload <- cfg
+	return nil
+}
+
+func (s *Sys) Unload() error {
+	close(s.exit)
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Sys) Name() string {
+	return Name
+}
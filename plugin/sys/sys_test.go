@@ -0,0 +1,231 @@
+package sys
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt/config"
+	_ "github.com/DrmagicE/gmqtt/persistence"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+	_ "github.com/DrmagicE/gmqtt/topicalias/fifo"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	a := assert.New(t)
+	cfg := DefaultConfig
+	a.Nil(cfg.Validate())
+	cfg.Prefix = "$SYS"
+	a.Nil(cfg.Validate())
+	for _, v := range []string{"broker", "$SYSTEM/broker", "$SYS/broker/", "$SYS/#"} {
+		cfg.Prefix = v
+		a.NotNil(cfg.Validate(), v)
+	}
+	cfg = DefaultConfig
+	cfg.Interval = 0
+	a.NotNil(cfg.Validate())
+}
+
+func TestStatsTopics(t *testing.T) {
+	a := assert.New(t)
+	st := &server.GlobalStats{}
+	st.ConnectionStats.ConnectedTotal = 3
+	st.MessageStats.Qos1.ReceivedTotal = 2
+	st.MessageStats.Qos2.ReceivedTotal = 1
+	st.MessageStats.Qos0.DroppedTotal.QueueFull = 4
+	st.PacketStats.ReceivedTotal.Connect = 5
+	st.PacketStats.BytesSent.Total = 100
+	rs := statsTopics(st, 90*time.Second)
+	a.Equal("90", rs["uptime"])
+	a.Equal("3", rs["clients/connected_total"])
+	a.Equal("3", rs["messages/received_total"])
+	a.Equal("4", rs["messages/dropped_total"])
+	a.Equal("4", rs["messages/qos0/dropped/queue_full"])
+	a.Equal("5", rs["packets/received/connect"])
+	a.Equal("100", rs["bytes/sent/total"])
+}
+
+type testClient struct {
+	t       *testing.T
+	version packets.Version
+	nc      net.Conn
+	reader  *packets.Reader
+	writer  *packets.Writer
+}
+
+func connect(t *testing.T, addr string, version packets.Version, clientID, username string) *testClient {
+	nc, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &testClient{
+		t:       t,
+		version: version,
+		nc:      nc,
+		reader:  packets.NewReader(nc),
+		writer:  packets.NewWriter(nc),
+	}
+	c.reader.SetVersion(version)
+	c.write(&packets.Connect{
+		Version:       version,
+		ProtocolName:  []byte("MQTT"),
+		ProtocolLevel: version,
+		CleanStart:    true,
+		KeepAlive:     60,
+		ClientID:      []byte(clientID),
+		UsernameFlag:  true,
+		Username:      []byte(username),
+	})
+	if ack := c.read().(*packets.Connack); ack.Code != codes.Success {
+		t.Fatalf("connect fail: %d", ack.Code)
+	}
+	return c
+}
+
+func (c *testClient) write(p packets.Packet) {
+	if err := c.writer.WriteAndFlush(p); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *testClient) read() packets.Packet {
+	_ = c.nc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	p, err := c.reader.ReadPacket()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return p
+}
+
+// readPublish reads packets until receiving a PUBLISH packet with the given topic.
+func (c *testClient) readPublish(topic string) *packets.Publish {
+	for {
+		if p, ok := c.read().(*packets.Publish); ok && string(p.TopicName) == topic {
+			return p
+		}
+	}
+}
+
+// waitPayloads reads packets until the latest payloads of the given topics are equal to the expected values.
+func (c *testClient) waitPayloads(expected map[string]string) {
+	latest := make(map[string]string)
+	for {
+		if p, ok := c.read().(*packets.Publish); ok {
+			latest[string(p.TopicName)] = string(p.Payload)
+		}
+		matched := true
+		for k, v := range expected {
+			if latest[k] != v {
+				matched = false
+			}
+		}
+		if matched {
+			return
+		}
+	}
+}
+
+func (c *testClient) subscribe(topic string) *packets.Suback {
+	c.write(&packets.Subscribe{
+		Version:  c.version,
+		PacketID: 1,
+		Topics:   []packets.Topic{{Name: topic}},
+	})
+	for {
+		if p, ok := c.read().(*packets.Suback); ok {
+			return p
+		}
+	}
+}
+
+func (c *testClient) close() {
+	_ = c.nc.Close()
+}
+
+func startBroker(t *testing.T) (string, func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	sysCfg := DefaultConfig
+	sysCfg.Interval = time.Second
+	sysCfg.AllowedUsernames = []string{"admin"}
+	cfg.Plugins[Name] = &sysCfg
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := server.New(
+		server.WithConfig(cfg),
+		server.WithTCPListener(ln),
+		server.WithPlugin(p),
+	)
+	if err := srv.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return ln.Addr().String(), func() {
+		_ = srv.Stop(context.Background())
+	}
+}
+
+func TestSys(t *testing.T) {
+	a := assert.New(t)
+	addr, stop := startBroker(t)
+	defer stop()
+
+	// unauthorized
+	user := connect(t, addr, packets.Version5, "user", "user")
+	defer user.close()
+	a.Equal([]codes.Code{codes.NotAuthorized}, user.subscribe("$SYS/#").Payload)
+	a.Equal([]codes.Code{codes.GrantedQoS0}, user.subscribe("#").Payload)
+	user.write(&packets.Publish{
+		Version:   packets.Version5,
+		Qos:       packets.Qos1,
+		PacketID:  2,
+		TopicName: []byte("$SYS/broker/uptime"),
+		Payload:   []byte("0"),
+	})
+	a.Equal(codes.NotAuthorized, user.read().(*packets.Puback).Code)
+
+	admin := connect(t, addr, packets.Version311, "admin", "admin")
+	defer admin.close()
+	a.Equal([]codes.Code{codes.GrantedQoS0}, admin.subscribe("$SYS/broker/#").Payload)
+	admin.waitPayloads(map[string]string{
+		"$SYS/broker/clients/connected_total":    "2",
+		"$SYS/broker/packets/received/subscribe": "3",
+	})
+
+	c := connect(t, addr, packets.Version311, "client", "name")
+	p := admin.readPublish("$SYS/broker/clients/connected")
+	var e clientEvent
+	a.Nil(json.Unmarshal(p.Payload, &e))
+	a.Equal("client", e.ClientID)
+	a.Equal("name", e.Username)
+	a.EqualValues(packets.Version311, e.ProtocolVersion)
+	a.Equal(c.nc.LocalAddr().String(), e.RemoteAddr)
+
+	c.write(&packets.Disconnect{Version: packets.Version311})
+	c.close()
+	p = admin.readPublish("$SYS/broker/clients/disconnected")
+	e = clientEvent{}
+	a.Nil(json.Unmarshal(p.Payload, &e))
+	a.Equal("client", e.ClientID)
+	a.Equal("name", e.Username)
+
+	// the wildcard subscription does not match $SYS topics.
+	user.write(&packets.Pingreq{})
+	for {
+		pkt := user.read()
+		if _, ok := pkt.(*packets.Pingresp); ok {
+			break
+		}
+		a.IsType(&packets.Puback{}, pkt)
+	}
+}
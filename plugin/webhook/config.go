@@ -0,0 +1,145 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+// The events that can be sent to the webhooks, each event maps to a hook.
+const (
+	// EventConnected maps to OnConnected.
+	EventConnected = "connected"
+	// EventClosed maps to OnClosed.
+	EventClosed = "closed"
+	// EventSubscribed maps to OnSubscribed.
+	EventSubscribed = "subscribed"
+	// EventUnsubscribed maps to OnUnsubscribed.
+	EventUnsubscribed = "unsubscribed"
+	// EventMsgArrived maps to OnMsgArrived.
+	EventMsgArrived = "msg_arrived"
+	// EventSessionTerminated maps to OnSessionTerminated.
+	EventSessionTerminated = "session_terminated"
+	// EventMsgDropped maps to OnMsgDropped.
+	EventMsgDropped = "msg_dropped"
+)
+
+var validEvents = []string{
+	EventConnected, EventClosed, EventSubscribed, EventUnsubscribed, EventMsgArrived, EventSessionTerminated, EventMsgDropped,
+}
+
+// Config is the configuration for the webhook plugin.
+type Config struct {
+	// Rules is the list of webhook rules.
+	Rules []*Rule `yaml:"rules"`
+	// QueueSize is the size of the event queue of each URL.
+	// Events will be dropped if the queue is full.
+	QueueSize int `yaml:"queue_size"`
+	// Concurrency is the number of concurrent requests of each URL.
+	Concurrency int `yaml:"concurrency"`
+	// Timeout is the timeout of each request.
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxRetries is the number of retries when the request fails or the response status is not 2xx.
+	// The event is dropped after all retries failed.
+	MaxRetries int `yaml:"max_retries"`
+	// InitialBackoff is the delay before the first retry.
+	// The delay is doubled for every retry until it reaches MaxBackoff.
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+}
+
+// Rule defines which events are sent to the URL.
+type Rule struct {
+	// URL is the http or https endpoint to POST the events to.
+	URL string `yaml:"url"`
+	// Events is the list of events to send.
+	Events []string `yaml:"events"`
+	// TopicFilter filters the subscribed, unsubscribed, msg_arrived and msg_dropped events by topic.
+	// Empty means all topics. It does not apply to other events.
+	TopicFilter string `yaml:"topic_filter"`
+	// Headers is the additional http headers of the requests.
+	Headers map[string]string `yaml:"headers"`
+}
+
+func isValidEvent(event string) bool {
+	for _, v := range validEvents {
+		if v == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate validates the configuration, and return an error if it is invalid.
+func (c *Config) Validate() error {
+	for k, v := range c.Rules {
+		if err := v.validate(); err != nil {
+			return fmt.Errorf("invalid rules[%d]: %s", k, err)
+		}
+	}
+	if c.QueueSize <= 0 {
+		return errors.New("queue_size must be greater than 0")
+	}
+	if c.Concurrency <= 0 {
+		return errors.New("concurrency must be greater than 0")
+	}
+	if c.Timeout <= 0 {
+		return errors.New("timeout must be greater than 0")
+	}
+	if c.MaxRetries < 0 {
+		return errors.New("max_retries cannot be negative")
+	}
+	if c.InitialBackoff <= 0 || c.MaxBackoff < c.InitialBackoff {
+		return errors.New("invalid initial_backoff or max_backoff")
+	}
+	return nil
+}
+
+func (r *Rule) validate() error {
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %s", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid url: %s", r.URL)
+	}
+	if len(r.Events) == 0 {
+		return errors.New("events cannot be empty")
+	}
+	for _, v := range r.Events {
+		if !isValidEvent(v) {
+			return fmt.Errorf("invalid event: %s", v)
+		}
+	}
+	if r.TopicFilter != "" && !packets.ValidTopicFilter(true, []byte(r.TopicFilter)) {
+		return fmt.Errorf("invalid topic_filter: %s", r.TopicFilter)
+	}
+	return nil
+}
+
+// DefaultConfig is the default configuration.
+var DefaultConfig = Config{
+	QueueSize:      1000,
+	Concurrency:    4,
+	Timeout:        5 * time.Second,
+	MaxRetries:     3,
+	InitialBackoff: time.Second,
+	MaxBackoff:     30 * time.Second,
+}
+
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type cfg Config
+	var v = &struct {
+		Webhook cfg `yaml:"webhook"`
+	}{
+		Webhook: cfg(DefaultConfig),
+	}
+	if err := unmarshal(v); err != nil {
+		return err
+	}
+	*c = Config(v.Webhook)
+	return nil
+}
@@ -0,0 +1,226 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func (w *Webhook) HookWrapper() server.HookWrapper {
+	return server.HookWrapper{
+		OnConnectedWrapper:         w.OnConnectedWrapper,
+		OnClosedWrapper:            w.OnClosedWrapper,
+		OnSubscribedWrapper:        w.OnSubscribedWrapper,
+		OnUnsubscribedWrapper:      w.OnUnsubscribedWrapper,
+		OnMsgArrivedWrapper:        w.OnMsgArrivedWrapper,
+		OnSessionTerminatedWrapper: w.OnSessionTerminatedWrapper,
+		OnMsgDroppedWrapper:        w.OnMsgDroppedWrapper,
+	}
+}
+
+type userProperty struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// message is the JSON format of the message in msg_arrived and msg_dropped events.
+// The payload and correlation data are base64 encoded.
+type message struct {
+	Topic           string         `json:"topic"`
+	QoS             uint8          `json:"qos"`
+	Retained        bool           `json:"retained"`
+	Payload         []byte         `json:"payload"`
+	ContentType     string         `json:"content_type,omitempty"`
+	CorrelationData []byte         `json:"correlation_data,omitempty"`
+	MessageExpiry   uint32         `json:"message_expiry,omitempty"`
+	PayloadFormat   uint8          `json:"payload_format,omitempty"`
+	ResponseTopic   string         `json:"response_topic,omitempty"`
+	UserProperties  []userProperty `json:"user_properties,omitempty"`
+}
+
+func newMessage(msg *gmqtt.Message) *message {
+	m := &message{
+		Topic:           msg.Topic,
+		QoS:             msg.QoS,
+		Retained:        msg.Retained,
+		Payload:         msg.Payload,
+		ContentType:     msg.ContentType,
+		CorrelationData: msg.CorrelationData,
+		MessageExpiry:   msg.MessageExpiry,
+		PayloadFormat:   uint8(msg.PayloadFormat),
+		ResponseTopic:   msg.ResponseTopic,
+	}
+	for _, v := range msg.UserProperties {
+		m.UserProperties = append(m.UserProperties, userProperty{Key: string(v.K), Value: string(v.V)})
+	}
+	return m
+}
+
+// subscription is the JSON format of the subscription in subscribed events.
+type subscription struct {
+	TopicFilter       string `json:"topic_filter"`
+	ShareName         string `json:"share_name,omitempty"`
+	QoS               uint8  `json:"qos"`
+	NoLocal           bool   `json:"no_local"`
+	RetainAsPublished bool   `json:"retain_as_published"`
+	RetainHandling    byte   `json:"retain_handling"`
+	ID                uint32 `json:"id,omitempty"`
+}
+
+// event is the JSON body of the webhook requests.
+type event struct {
+	Event string `json:"event"`
+	// Timestamp is the unix timestamp in milliseconds.
+	Timestamp       int64         `json:"timestamp"`
+	ClientID        string        `json:"client_id"`
+	Username        string        `json:"username,omitempty"`
+	RemoteAddr      string        `json:"remote_addr,omitempty"`
+	ProtocolVersion byte          `json:"protocol_version,omitempty"`
+	KeepAlive       uint16        `json:"keep_alive,omitempty"`
+	CleanStart      bool          `json:"clean_start,omitempty"`
+	Topic           string        `json:"topic,omitempty"`
+	Subscription    *subscription `json:"subscription,omitempty"`
+	Message         *message      `json:"message,omitempty"`
+	// Reason is the reason of closed, session_terminated and msg_dropped events.
+	Reason string `json:"reason,omitempty"`
+}
+
+func (e *event) marshal() []byte {
+	b, _ := json.Marshal(e)
+	return b
+}
+
+// newEvent creates the event with the client information.
+func newEvent(name string, client server.Client) *event {
+	opts := client.ClientOptions()
+	e := &event{
+		Event:     name,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+		ClientID:  opts.ClientID,
+		Username:  opts.Username,
+	}
+	if conn := client.Connection(); conn != nil {
+		e.RemoteAddr = conn.RemoteAddr().String()
+	}
+	return e
+}
+
+func (w *Webhook) OnConnectedWrapper(pre server.OnConnected) server.OnConnected {
+	return func(ctx context.Context, client server.Client) {
+		pre(ctx, client)
+		if !w.enabled(EventConnected) {
+			return
+		}
+		e := newEvent(EventConnected, client)
+		opts := client.ClientOptions()
+		e.ProtocolVersion = byte(client.Version())
+		e.KeepAlive = opts.KeepAlive
+		e.CleanStart = opts.CleanStart
+		w.send(e, "")
+	}
+}
+
+func (w *Webhook) OnClosedWrapper(pre server.OnClosed) server.OnClosed {
+	return func(ctx context.Context, client server.Client, err error) {
+		pre(ctx, client, err)
+		// the client is closed before connected.
+		if !w.enabled(EventClosed) || client.ConnectedAt().Unix() == 0 {
+			return
+		}
+		e := newEvent(EventClosed, client)
+		if err != nil {
+			e.Reason = err.Error()
+		}
+		w.send(e, "")
+	}
+}
+
+func (w *Webhook) OnSubscribedWrapper(pre server.OnSubscribed) server.OnSubscribed {
+	return func(ctx context.Context, client server.Client, sub *gmqtt.Subscription) {
+		pre(ctx, client, sub)
+		if !w.enabled(EventSubscribed) {
+			return
+		}
+		e := newEvent(EventSubscribed, client)
+		e.Subscription = &subscription{
+			TopicFilter:       sub.TopicFilter,
+			ShareName:         sub.ShareName,
+			QoS:               sub.QoS,
+			NoLocal:           sub.NoLocal,
+			RetainAsPublished: sub.RetainAsPublished,
+			RetainHandling:    sub.RetainHandling,
+			ID:                sub.ID,
+		}
+		w.send(e, sub.TopicFilter)
+	}
+}
+
+func (w *Webhook) OnUnsubscribedWrapper(pre server.OnUnsubscribed) server.OnUnsubscribed {
+	return func(ctx context.Context, client server.Client, topicName string) {
+		pre(ctx, client, topicName)
+		if !w.enabled(EventUnsubscribed) {
+			return
+		}
+		e := newEvent(EventUnsubscribed, client)
+		e.Topic = topicName
+		w.send(e, topicName)
+	}
+}
+
+func (w *Webhook) OnMsgArrivedWrapper(pre server.OnMsgArrived) server.OnMsgArrived {
+	return func(ctx context.Context, client server.Client, req *server.MsgArrivedRequest) error {
+		err := pre(ctx, client, req)
+		if err != nil || req.Message == nil || !w.enabled(EventMsgArrived) {
+			return err
+		}
+		e := newEvent(EventMsgArrived, client)
+		e.Message = newMessage(req.Message)
+		w.send(e, req.Message.Topic)
+		return nil
+	}
+}
+
+func (w *Webhook) OnSessionTerminatedWrapper(pre server.OnSessionTerminated) server.OnSessionTerminated {
+	return func(ctx context.Context, clientID string, reason server.SessionTerminatedReason) {
+		pre(ctx, clientID, reason)
+		if !w.enabled(EventSessionTerminated) {
+			return
+		}
+		e := &event{
+			Event:     EventSessionTerminated,
+			Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+			ClientID:  clientID,
+		}
+		switch reason {
+		case server.NormalTermination:
+			e.Reason = "normal"
+		case server.TakenOverTermination:
+			e.Reason = "taken_over"
+		case server.ExpiredTermination:
+			e.Reason = "expired"
+		}
+		w.send(e, "")
+	}
+}
+
+func (w *Webhook) OnMsgDroppedWrapper(pre server.OnMsgDropped) server.OnMsgDropped {
+	return func(ctx context.Context, clientID string, msg *gmqtt.Message, err error) {
+		pre(ctx, clientID, msg, err)
+		if !w.enabled(EventMsgDropped) {
+			return
+		}
+		e := &event{
+			Event:     EventMsgDropped,
+			Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+			ClientID:  clientID,
+			Message:   newMessage(msg),
+		}
+		if err != nil {
+			e.Reason = err.Error()
+		}
+		w.send(e, msg.Topic)
+	}
+}
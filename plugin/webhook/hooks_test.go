@@ -0,0 +1,244 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+// newHookWebhook returns a Webhook without workers, so that the queued requests can be inspected.
+func newHookWebhook(events ...string) *Webhook {
+	log = zap.NewNop()
+	cfg := DefaultConfig
+	cfg.Rules = []*Rule{{URL: "http://127.0.0.1/hook", Events: events, TopicFilter: "a/#"}}
+	return newWebhook(cfg)
+}
+
+// recv returns the decoded body of the next request in the queue, or nil if the queue is empty.
+func recv(t *testing.T, w *Webhook) map[string]interface{} {
+	select {
+	case req := <-w.endpoints["http://127.0.0.1/hook"].queue:
+		var m map[string]interface{}
+		if err := json.Unmarshal(req.body, &m); err != nil {
+			t.Fatal(err)
+		}
+		ts, ok := m["timestamp"].(float64)
+		if !ok || ts <= 0 {
+			t.Fatalf("invalid timestamp: %v", m["timestamp"])
+		}
+		delete(m, "timestamp")
+		return m
+	default:
+		return nil
+	}
+}
+
+func TestWebhook_OnConnectedWrapper(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	w := newHookWebhook(EventConnected)
+	client := server.NewMockClient(ctrl)
+	client.EXPECT().ClientOptions().Return(&server.ClientOptions{
+		ClientID:   "cid",
+		Username:   "user",
+		KeepAlive:  60,
+		CleanStart: true,
+	}).AnyTimes()
+	conn, _ := net.Pipe()
+	client.EXPECT().Connection().Return(conn)
+	client.EXPECT().Version().Return(packets.Version5)
+
+	w.OnConnectedWrapper(func(ctx context.Context, client server.Client) {})(context.Background(), client)
+	a.Equal(map[string]interface{}{
+		"event":            EventConnected,
+		"client_id":        "cid",
+		"username":         "user",
+		"remote_addr":      "pipe",
+		"protocol_version": float64(packets.Version5),
+		"keep_alive":       float64(60),
+		"clean_start":      true,
+	}, recv(t, w))
+}
+
+func TestWebhook_OnClosedWrapper(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	w := newHookWebhook(EventClosed)
+	client := server.NewMockClient(ctrl)
+	client.EXPECT().ClientOptions().Return(&server.ClientOptions{ClientID: "cid"}).AnyTimes()
+	client.EXPECT().Connection().Return(nil).AnyTimes()
+	fn := w.OnClosedWrapper(func(ctx context.Context, client server.Client, err error) {})
+
+	// the client is closed before connected.
+	client.EXPECT().ConnectedAt().Return(time.Unix(0, 0))
+	fn(context.Background(), client, errors.New("connect error"))
+	a.Nil(recv(t, w))
+
+	client.EXPECT().ConnectedAt().Return(time.Now())
+	fn(context.Background(), client, errors.New("EOF"))
+	a.Equal(map[string]interface{}{
+		"event":     EventClosed,
+		"client_id": "cid",
+		"reason":    "EOF",
+	}, recv(t, w))
+}
+
+func TestWebhook_OnSubscribedWrapper(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	w := newHookWebhook(EventSubscribed, EventUnsubscribed)
+	client := server.NewMockClient(ctrl)
+	client.EXPECT().ClientOptions().Return(&server.ClientOptions{ClientID: "cid"}).AnyTimes()
+	client.EXPECT().Connection().Return(nil).AnyTimes()
+
+	sub := w.OnSubscribedWrapper(func(ctx context.Context, client server.Client, subscription *gmqtt.Subscription) {})
+	// b does not match the topic filter of the rule.
+	sub(context.Background(), client, &gmqtt.Subscription{TopicFilter: "b"})
+	sub(context.Background(), client, &gmqtt.Subscription{
+		ShareName:         "share",
+		TopicFilter:       "a/b",
+		ID:                1,
+		QoS:               1,
+		NoLocal:           true,
+		RetainAsPublished: true,
+		RetainHandling:    2,
+	})
+	a.Equal(map[string]interface{}{
+		"event":     EventSubscribed,
+		"client_id": "cid",
+		"subscription": map[string]interface{}{
+			"topic_filter":        "a/b",
+			"share_name":          "share",
+			"qos":                 float64(1),
+			"no_local":            true,
+			"retain_as_published": true,
+			"retain_handling":     float64(2),
+			"id":                  float64(1),
+		},
+	}, recv(t, w))
+	a.Nil(recv(t, w))
+
+	unsub := w.OnUnsubscribedWrapper(func(ctx context.Context, client server.Client, topicName string) {})
+	unsub(context.Background(), client, "a/b")
+	a.Equal(map[string]interface{}{
+		"event":     EventUnsubscribed,
+		"client_id": "cid",
+		"topic":     "a/b",
+	}, recv(t, w))
+}
+
+func TestWebhook_OnMsgArrivedWrapper(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	w := newHookWebhook(EventMsgArrived)
+	client := server.NewMockClient(ctrl)
+	client.EXPECT().ClientOptions().Return(&server.ClientOptions{ClientID: "cid", Username: "user"}).AnyTimes()
+	client.EXPECT().Connection().Return(nil).AnyTimes()
+
+	preErr := errors.New("pre error")
+	fn := w.OnMsgArrivedWrapper(func(ctx context.Context, client server.Client, req *server.MsgArrivedRequest) error {
+		switch req.Message.Topic {
+		case "a/err":
+			return preErr
+		case "a/drop":
+			req.Message = nil
+		}
+		return nil
+	})
+	a.Equal(preErr, fn(context.Background(), client, &server.MsgArrivedRequest{
+		Message: &gmqtt.Message{Topic: "a/err"},
+	}))
+	for _, v := range []string{"a/drop", "b"} {
+		a.Nil(fn(context.Background(), client, &server.MsgArrivedRequest{
+			Message: &gmqtt.Message{Topic: v},
+		}))
+	}
+	a.Nil(recv(t, w))
+
+	a.Nil(fn(context.Background(), client, &server.MsgArrivedRequest{
+		Message: &gmqtt.Message{
+			QoS:             1,
+			Retained:        true,
+			Topic:           "a/b",
+			Payload:         []byte{0, 1, 2},
+			ContentType:     "application/octet-stream",
+			CorrelationData: []byte("cd"),
+			MessageExpiry:   10,
+			ResponseTopic:   "resp",
+			UserProperties: []packets.UserProperty{
+				{K: []byte("k"), V: []byte("v")},
+			},
+		},
+	}))
+	a.Equal(map[string]interface{}{
+		"event":     EventMsgArrived,
+		"client_id": "cid",
+		"username":  "user",
+		"message": map[string]interface{}{
+			"topic":            "a/b",
+			"qos":              float64(1),
+			"retained":         true,
+			"payload":          "AAEC",
+			"content_type":     "application/octet-stream",
+			"correlation_data": "Y2Q=",
+			"message_expiry":   float64(10),
+			"response_topic":   "resp",
+			"user_properties": []interface{}{
+				map[string]interface{}{"key": "k", "value": "v"},
+			},
+		},
+	}, recv(t, w))
+}
+
+func TestWebhook_OnSessionTerminatedWrapper(t *testing.T) {
+	a := assert.New(t)
+	w := newHookWebhook(EventSessionTerminated)
+	fn := w.OnSessionTerminatedWrapper(func(ctx context.Context, clientID string, reason server.SessionTerminatedReason) {})
+	for reason, expected := range map[server.SessionTerminatedReason]string{
+		server.NormalTermination:    "normal",
+		server.TakenOverTermination: "taken_over",
+		server.ExpiredTermination:   "expired",
+	} {
+		fn(context.Background(), "cid", reason)
+		a.Equal(map[string]interface{}{
+			"event":     EventSessionTerminated,
+			"client_id": "cid",
+			"reason":    expected,
+		}, recv(t, w))
+	}
+}
+
+func TestWebhook_OnMsgDroppedWrapper(t *testing.T) {
+	a := assert.New(t)
+	w := newHookWebhook(EventMsgDropped)
+	fn := w.OnMsgDroppedWrapper(func(ctx context.Context, clientID string, msg *gmqtt.Message, err error) {})
+	fn(context.Background(), "cid", &gmqtt.Message{Topic: "b"}, errors.New("queue full"))
+	a.Nil(recv(t, w))
+	fn(context.Background(), "cid", &gmqtt.Message{Topic: "a", Payload: []byte("a")}, errors.New("queue full"))
+	a.Equal(map[string]interface{}{
+		"event":     EventMsgDropped,
+		"client_id": "cid",
+		"reason":    "queue full",
+		"message": map[string]interface{}{
+			"topic":    "a",
+			"qos":      float64(0),
+			"retained": false,
+			"payload":  "YQ==",
+		},
+	}, recv(t, w))
+}
@@ -0,0 +1,221 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+var _ server.Plugin = (*Webhook)(nil)
+
+const Name = "webhook"
+
+func init() {
+	server.RegisterPlugin(Name, New)
+	config.RegisterDefaultPluginConfig(Name, &DefaultConfig)
+}
+
+func New(config config.Config) (server.Plugin, error) {
+	cfg := config.Plugins[Name].(*Config)
+	return newWebhook(*cfg), nil
+}
+
+var log *zap.Logger
+
+// Webhook sends the broker events to the http endpoints.
+type Webhook struct {
+	config Config
+	// rules groups the rules by event.
+	rules map[string][]*rule
+	// endpoints groups the delivery queues by url.
+	endpoints map[string]*endpoint
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+type rule struct {
+	*Rule
+	endpoint *endpoint
+}
+
+// match reports whether the topic matches the topic filter of the rule.
+func (r *rule) match(topic string) bool {
+	return r.TopicFilter == "" || packets.TopicMatch([]byte(topic), []byte(r.TopicFilter))
+}
+
+// request is a pending http request.
+type request struct {
+	event   string
+	body    []byte
+	headers map[string]string
+}
+
+// endpoint delivers the requests to the url with a bounded queue and concurrent workers.
+type endpoint struct {
+	url    string
+	queue  chan *request
+	client *http.Client
+}
+
+func newWebhook(cfg Config) *Webhook {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Webhook{
+		config:    cfg,
+		rules:     make(map[string][]*rule),
+		endpoints: make(map[string]*endpoint),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = cfg.Concurrency
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   cfg.Timeout,
+	}
+	for _, v := range cfg.Rules {
+		ep := w.endpoints[v.URL]
+		if ep == nil {
+			ep = &endpoint{
+				url:    v.URL,
+				queue:  make(chan *request, cfg.QueueSize),
+				client: client,
+			}
+			w.endpoints[v.URL] = ep
+		}
+		for _, e := range v.Events {
+			w.rules[e] = append(w.rules[e], &rule{Rule: v, endpoint: ep})
+		}
+	}
+	return w
+}
+
+func (w *Webhook) Load(service server.Server) error {
+	log = server.LoggerWithField(zap.String("plugin", Name))
+	for _, ep := range w.endpoints {
+		for i := 0; i < w.config.Concurrency; i++ {
+			w.wg.Add(1)
+			go func(ep *endpoint) {
+				defer w.wg.Done()
+				w.worker(ep)
+			}(ep)
+		}
+	}
+	return nil
+}
+
+// Unload stops the workers, the events in the queues are discarded.
+func (w *Webhook) Unload() error {
+	w.cancel()
+	w.wg.Wait()
+	for _, ep := range w.endpoints {
+		ep.client.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (w *Webhook) Name() string {
+	return Name
+}
+
+// enabled reports whether any rules send the event.
+func (w *Webhook) enabled(event string) bool {
+	return len(w.rules[event]) != 0
+}
+
+// send puts the event into the queues of the rules that match the topic without blocking.
+// The topic is ignored if it is empty.
+func (w *Webhook) send(e *event, topic string) {
+	var body []byte
+	for _, r := range w.rules[e.Event] {
+		if topic != "" && !r.match(topic) {
+			continue
+		}
+		if body == nil {
+			body = e.marshal()
+		}
+		select {
+		case r.endpoint.queue <- &request{event: e.Event, body: body, headers: r.Headers}:
+		default:
+			log.Warn("event dropped, the queue is full",
+				zap.String("url", r.endpoint.url),
+				zap.String("event", e.Event))
+		}
+	}
+}
+
+func (w *Webhook) worker(ep *endpoint) {
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case req := <-ep.queue:
+			w.deliver(ep, req)
+		}
+	}
+}
+
+// deliver posts the request to the endpoint, retries with exponential backoff if failed.
+func (w *Webhook) deliver(ep *endpoint, req *request) {
+	backoff := w.config.InitialBackoff
+	for i := 0; ; i++ {
+		err := ep.post(w.ctx, req)
+		if err == nil {
+			return
+		}
+		if i == w.config.MaxRetries || w.ctx.Err() != nil {
+			log.Error("event dropped, failed to send webhook",
+				zap.String("url", ep.url),
+				zap.String("event", req.event),
+				zap.Int("retries", i),
+				zap.Error(err))
+			return
+		}
+		log.Warn("failed to send webhook, retrying",
+			zap.String("url", ep.url),
+			zap.String("event", req.event),
+			zap.Duration("backoff", backoff),
+			zap.Error(err))
+		select {
+		case <-w.ctx.Done():
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > w.config.MaxBackoff {
+			backoff = w.config.MaxBackoff
+		}
+	}
+}
+
+func (ep *endpoint) post(ctx context.Context, req *request) error {
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.url, bytes.NewReader(req.body))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	for k, v := range req.headers {
+		r.Header.Set(k, v)
+	}
+	resp, err := ep.client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// drain the body to reuse the connection.
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return nil
+}
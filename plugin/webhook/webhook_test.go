@@ -0,0 +1,255 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// testEndpoint is a http server that records the received events.
+type testEndpoint struct {
+	*httptest.Server
+	mu      sync.Mutex
+	events  []map[string]interface{}
+	headers []http.Header
+	// handler returns the status code of the response, 200 if it is nil.
+	handler func() int
+}
+
+func newTestEndpoint(t *testing.T) *testEndpoint {
+	ep := &testEndpoint{}
+	ep.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code := http.StatusOK
+		if ep.handler != nil {
+			code = ep.handler()
+		}
+		if code == http.StatusOK {
+			b, _ := ioutil.ReadAll(r.Body)
+			var m map[string]interface{}
+			if err := json.Unmarshal(b, &m); err != nil {
+				t.Error(err)
+			}
+			ep.mu.Lock()
+			ep.events = append(ep.events, m)
+			ep.headers = append(ep.headers, r.Header)
+			ep.mu.Unlock()
+		}
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(ep.Close)
+	return ep
+}
+
+func (ep *testEndpoint) getEvents() []map[string]interface{} {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	return append([]map[string]interface{}(nil), ep.events...)
+}
+
+func newTestWebhook(t *testing.T, cfg Config) *Webhook {
+	log = zap.NewNop()
+	w := newWebhook(cfg)
+	for _, ep := range w.endpoints {
+		for i := 0; i < cfg.Concurrency; i++ {
+			w.wg.Add(1)
+			go func(ep *endpoint) {
+				defer w.wg.Done()
+				w.worker(ep)
+			}(ep)
+		}
+	}
+	t.Cleanup(func() {
+		w.Unload()
+	})
+	return w
+}
+
+func TestConfig_Validate(t *testing.T) {
+	a := assert.New(t)
+	cfg := DefaultConfig
+	a.Nil(cfg.Validate())
+
+	cfg.Rules = []*Rule{{URL: "http://127.0.0.1:8080/hook", Events: []string{EventConnected}, TopicFilter: "a/#"}}
+	a.Nil(cfg.Validate())
+
+	for _, v := range []*Rule{
+		{URL: "ftp://127.0.0.1/hook", Events: []string{EventConnected}},
+		{URL: "/hook", Events: []string{EventConnected}},
+		{URL: "http://127.0.0.1/hook"},
+		{URL: "http://127.0.0.1/hook", Events: []string{"unknown"}},
+		{URL: "http://127.0.0.1/hook", Events: []string{EventMsgArrived}, TopicFilter: "a/#/b"},
+	} {
+		cfg.Rules = []*Rule{v}
+		a.NotNil(cfg.Validate(), v.URL)
+	}
+
+	cfg = DefaultConfig
+	cfg.Concurrency = 0
+	a.NotNil(cfg.Validate())
+
+	cfg = DefaultConfig
+	cfg.MaxBackoff = cfg.InitialBackoff / 2
+	a.NotNil(cfg.Validate())
+}
+
+func TestWebhook_send(t *testing.T) {
+	a := assert.New(t)
+	ep1 := newTestEndpoint(t)
+	ep2 := newTestEndpoint(t)
+	cfg := DefaultConfig
+	cfg.Rules = []*Rule{
+		{
+			URL:     ep1.URL,
+			Events:  []string{EventConnected, EventMsgArrived},
+			Headers: map[string]string{"Authorization": "Bearer token"},
+		},
+		{
+			URL:         ep2.URL,
+			Events:      []string{EventMsgArrived},
+			TopicFilter: "a/+",
+		},
+	}
+	w := newTestWebhook(t, cfg)
+	a.True(w.enabled(EventConnected))
+	a.False(w.enabled(EventClosed))
+
+	w.send(&event{Event: EventConnected, ClientID: "cid"}, "")
+	w.send(&event{Event: EventMsgArrived, ClientID: "cid"}, "a/b")
+	w.send(&event{Event: EventMsgArrived, ClientID: "cid"}, "b")
+	a.Eventually(func() bool {
+		return len(ep1.getEvents()) == 3 && len(ep2.getEvents()) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	ep1.mu.Lock()
+	for _, v := range ep1.headers {
+		a.Equal("application/json", v.Get("Content-Type"))
+		a.Equal("Bearer token", v.Get("Authorization"))
+	}
+	ep1.mu.Unlock()
+	a.Equal(map[string]interface{}{
+		"event":     EventMsgArrived,
+		"timestamp": float64(0),
+		"client_id": "cid",
+	}, ep2.getEvents()[0])
+	a.Empty(ep2.headers[0].Get("Authorization"))
+}
+
+func TestWebhook_retry(t *testing.T) {
+	a := assert.New(t)
+	ep := newTestEndpoint(t)
+	var calls int32
+	// fail the first 2 requests.
+	ep.handler = func() int {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			return http.StatusInternalServerError
+		}
+		return http.StatusOK
+	}
+	cfg := DefaultConfig
+	cfg.Concurrency = 1
+	cfg.MaxRetries = 2
+	cfg.InitialBackoff = 10 * time.Millisecond
+	cfg.MaxBackoff = 20 * time.Millisecond
+	cfg.Rules = []*Rule{{URL: ep.URL, Events: []string{EventConnected}}}
+	w := newTestWebhook(t, cfg)
+
+	w.send(&event{Event: EventConnected, ClientID: "a"}, "")
+	a.Eventually(func() bool {
+		return len(ep.getEvents()) == 1
+	}, time.Second, 10*time.Millisecond)
+	a.EqualValues(3, atomic.LoadInt32(&calls))
+
+	// b is dropped after 1 retry.
+	w.config.MaxRetries = 1
+	atomic.StoreInt32(&calls, 0)
+	w.send(&event{Event: EventConnected, ClientID: "b"}, "")
+	w.send(&event{Event: EventConnected, ClientID: "c"}, "")
+	a.Eventually(func() bool {
+		return len(ep.getEvents()) == 2
+	}, time.Second, 10*time.Millisecond)
+	a.EqualValues(3, atomic.LoadInt32(&calls))
+	a.Equal("c", ep.getEvents()[1]["client_id"])
+}
+
+func TestWebhook_concurrency(t *testing.T) {
+	a := assert.New(t)
+	ep := newTestEndpoint(t)
+	var running, max int32
+	release := make(chan struct{})
+	ep.handler = func() int {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		<-release
+		atomic.AddInt32(&running, -1)
+		return http.StatusOK
+	}
+	cfg := DefaultConfig
+	cfg.Concurrency = 2
+	cfg.Rules = []*Rule{{URL: ep.URL, Events: []string{EventConnected}}}
+	w := newTestWebhook(t, cfg)
+
+	for i := 0; i < 5; i++ {
+		w.send(&event{Event: EventConnected}, "")
+	}
+	a.Eventually(func() bool {
+		return atomic.LoadInt32(&running) == 2
+	}, time.Second, 10*time.Millisecond)
+	close(release)
+	a.Eventually(func() bool {
+		return len(ep.getEvents()) == 5
+	}, time.Second, 10*time.Millisecond)
+	a.EqualValues(2, atomic.LoadInt32(&max))
+}
+
+func TestWebhook_dropWhenFull(t *testing.T) {
+	a := assert.New(t)
+	ep := newTestEndpoint(t)
+	release := make(chan struct{})
+	ep.handler = func() int {
+		<-release
+		return http.StatusOK
+	}
+	cfg := DefaultConfig
+	cfg.Concurrency = 1
+	cfg.QueueSize = 1
+	cfg.Rules = []*Rule{{URL: ep.URL, Events: []string{EventConnected}}}
+	w := newTestWebhook(t, cfg)
+	queue := w.endpoints[ep.URL].queue
+
+	w.send(&event{Event: EventConnected, ClientID: "a"}, "")
+	// wait until a is taken by the worker.
+	a.Eventually(func() bool {
+		return len(queue) == 0
+	}, time.Second, time.Millisecond)
+	w.send(&event{Event: EventConnected, ClientID: "b"}, "")
+	done := make(chan struct{})
+	go func() {
+		// the queue is full, c must be dropped without blocking.
+		w.send(&event{Event: EventConnected, ClientID: "c"}, "")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("send blocked")
+	}
+	close(release)
+	a.Eventually(func() bool {
+		return len(ep.getEvents()) == 2
+	}, time.Second, 10*time.Millisecond)
+	a.Equal("a", ep.getEvents()[0]["client_id"])
+	a.Equal("b", ep.getEvents()[1]["client_id"])
+}
@@ -0,0 +1,28 @@
+// Command gmqttd is the gmqttd broker with the QUIC listener enabled.
+// It is built from the quic module because quic-go requires a newer Go version than gmqtt:
+//
+//	cd quic && go build -o gmqttd ./cmd/gmqttd
+package main
+
+import (
+	"fmt"
+	"net/http"
+	_ "net/http/pprof"
+	"os"
+
+	"github.com/DrmagicE/gmqtt/cmd/gmqttd/command"
+	_ "github.com/DrmagicE/gmqtt/quic"
+)
+
+// Version is the version number of the binary, it can be set by -ldflags "-X main.Version=...".
+var Version string
+
+func main() {
+	go func() {
+		http.ListenAndServe(":6060", nil)
+	}()
+	if err := command.NewRootCmd(Version).Execute(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
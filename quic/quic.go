@@ -0,0 +1,214 @@
+// Package quic provides the QUIC listener which serves MQTT on the first stream of each QUIC connection.
+// It is a separate module because of the minimum Go version required by quic-go,
+// import it to enable the listeners with the quic option:
+//
+//	import _ "github.com/DrmagicE/gmqtt/quic"
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+var _ server.CertIdentityListener = (*Listener)(nil)
+var _ server.TLSConn = (*Conn)(nil)
+
+func init() {
+	server.RegisterQUICListenerFactory(func(c *config.ListenerConfig) (net.Listener, error) {
+		return NewListener(c)
+	})
+}
+
+// NextProto is the ALPN protocol which must be negotiated by the QUIC clients.
+const NextProto = "mqtt"
+
+// quicCloseTimeout is the maximum time to wait for the client to close the QUIC connection after
+// the server closes the stream, so that the data sent before closing, e.g. the DISCONNECT packet, can be delivered.
+const quicCloseTimeout = time.Second
+
+// acceptStreamTimeout is the maximum time to wait for the first stream after the handshake,
+// the same as the time to wait for the CONNECT packet.
+var acceptStreamTimeout = server.ConnectTimeout
+
+// tlsConfig holds the TLS config of the listener, which can be replaced when reloading.
+type tlsConfig struct {
+	config       atomic.Value // *tls.Config
+	certIdentity atomic.Value // string
+}
+
+// reload re-reads the certificates, the new config only applies to new connections.
+func (t *tlsConfig) reload(opts *config.TLSOptions) error {
+	c, err := opts.TLSConfig()
+	if err != nil {
+		return err
+	}
+	t.config.Store(c)
+	t.certIdentity.Store(opts.CertIdentity)
+	return nil
+}
+
+// quicConfig returns the *tls.Config of the QUIC listener, which negotiates the NextProto.
+func (t *tlsConfig) quicConfig() *tls.Config {
+	return &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			c := t.config.Load().(*tls.Config).Clone()
+			c.NextProtos = []string{NextProto}
+			return c, nil
+		},
+	}
+}
+
+// Listener is a QUIC listener which adapts the first bidirectional stream of each QUIC connection to net.Conn.
+// The QUIC connection keeps alive when the client changes its network address (connection migration),
+// so that the MQTT session is not interrupted.
+type Listener struct {
+	ln     *quic.Listener
+	config *tlsConfig
+	conns  chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+}
+
+// NewListener listens on the UDP address of the listener config.
+func NewListener(c *config.ListenerConfig) (*Listener, error) {
+	if c.TLSOptions == nil {
+		return nil, errors.New("quic listener requires the tls options")
+	}
+	tc := &tlsConfig{}
+	if err := tc.reload(c.TLSOptions); err != nil {
+		return nil, err
+	}
+	ln, err := quic.ListenAddr(c.Address, tc.quicConfig(), &quic.Config{
+		HandshakeIdleTimeout: c.QUIC.HandshakeIdleTimeout,
+		MaxIdleTimeout:       c.QUIC.MaxIdleTimeout,
+		KeepAlivePeriod:      c.QUIC.KeepAlivePeriod,
+		// only the first stream is used.
+		MaxIncomingStreams:    1,
+		MaxIncomingUniStreams: -1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	l := &Listener{
+		ln:     ln,
+		config: tc,
+		conns:  make(chan net.Conn),
+		closed: make(chan struct{}),
+	}
+	go l.serve()
+	return l, nil
+}
+
+func (l *Listener) serve() {
+	for {
+		conn, err := l.ln.Accept(context.Background())
+		if err != nil {
+			l.Close()
+			return
+		}
+		// accepting the stream blocks, so it is done in a new goroutine.
+		go l.acceptStream(conn)
+	}
+}
+
+// acceptStream waits for the first stream of the connection,
+// the connection is closed if the client does not open a stream within acceptStreamTimeout.
+func (l *Listener) acceptStream(conn *quic.Conn) {
+	ctx, cancel := context.WithTimeout(conn.Context(), acceptStreamTimeout)
+	defer cancel()
+	stream, err := conn.AcceptStream(ctx)
+	if err != nil {
+		server.LoggerWithField(zap.String("listener", "quic")).Warn("fail to accept the QUIC stream",
+			zap.String("remote_addr", conn.RemoteAddr().String()),
+			zap.Error(err))
+		conn.CloseWithError(0, "")
+		return
+	}
+	select {
+	case l.conns <- &Conn{Stream: stream, conn: conn}:
+	case <-l.closed:
+		conn.CloseWithError(0, "")
+	}
+}
+
+// Accept returns the first stream of the next QUIC connection.
+func (l *Listener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close stops accepting new connections, the established connections are not affected.
+func (l *Listener) Close() error {
+	var err error
+	l.once.Do(func() {
+		close(l.closed)
+		err = l.ln.Close()
+	})
+	return err
+}
+
+// Addr returns the UDP address of the listener.
+func (l *Listener) Addr() net.Addr {
+	return l.ln.Addr()
+}
+
+// CertIdentity indicates whether to use the common name of the verified client certificate
+// as the username or client id. Possible values: config.CertIdentityUsername | config.CertIdentityClientID
+func (l *Listener) CertIdentity() string {
+	return l.config.certIdentity.Load().(string)
+}
+
+// Reload re-reads the certificates and applies the new TLS options.
+// The established connections are not affected.
+func (l *Listener) Reload(opts *config.TLSOptions) error {
+	return l.config.reload(opts)
+}
+
+// Conn is the net.Conn returned by Client.Connection() for the QUIC clients.
+// RemoteAddr returns the current address of the client, which may change after connection migration.
+type Conn struct {
+	*quic.Stream
+	conn *quic.Conn
+}
+
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// Close closes the stream, and then closes the QUIC connection once the client closes it or quicCloseTimeout elapses.
+func (c *Conn) Close() error {
+	c.Stream.CancelRead(0)
+	err := c.Stream.Close()
+	go func() {
+		select {
+		case <-c.conn.Context().Done():
+		case <-time.After(quicCloseTimeout):
+		}
+		c.conn.CloseWithError(0, "")
+	}()
+	return err
+}
+
+// ConnectionState returns the TLS connection state of the QUIC connection.
+func (c *Conn) ConnectionState() tls.ConnectionState {
+	return c.conn.ConnectionState().TLS
+}
@@ -0,0 +1,240 @@
+package quic
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt/config"
+	_ "github.com/DrmagicE/gmqtt/persistence"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+	_ "github.com/DrmagicE/gmqtt/topicalias/fifo"
+)
+
+// newTestCert creates a certificate signed by parent, or a self-signed certificate if parent is nil.
+func newTestCert(t *testing.T, cn string, parent *tls.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{cn + ".example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	}
+	signer, signerKey := tmpl, interface{}(key)
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// writeTestCert writes the certificate and private key into dir.
+func writeTestCert(t *testing.T, dir string, cert tls.Certificate) (certFile, keyFile string) {
+	keyDer, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = path.Join(dir, "cert.pem")
+	keyFile = path.Join(dir, "key.pem")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// newTestQUICListener creates a QUIC listener on the loopback with a certificate signed by a self-signed CA,
+// and returns the client TLS config trusting the CA.
+func newTestQUICListener(t *testing.T) (net.Listener, *tls.Config) {
+	dir, err := ioutil.TempDir("", "gmqtt_quic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ca := newTestCert(t, "ca", nil)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+	certFile, keyFile := writeTestCert(t, dir, newTestCert(t, "server", &ca))
+	// the listener is created by the factory registered in init.
+	ln, _, err := server.NewListener(&config.ListenerConfig{
+		Address: "127.0.0.1:0",
+		TLSOptions: &config.TLSOptions{
+			CertFile: certFile,
+			KeyFile:  keyFile,
+		},
+		QUIC: &config.QUICOptions{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ln, &tls.Config{
+		RootCAs:    pool,
+		ServerName: "server.example.com",
+		NextProtos: []string{NextProto},
+	}
+}
+
+func TestQUICListener(t *testing.T) {
+	a := assert.New(t)
+	ln, tlsConfig := newTestQUICListener(t)
+	defer ln.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	udp, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	a.Nil(err)
+	tr := &quic.Transport{Conn: udp}
+	defer tr.Close()
+	conn, err := tr.Dial(ctx, ln.Addr(), tlsConfig, &quic.Config{})
+	a.Nil(err)
+	defer conn.CloseWithError(0, "")
+	stream, err := conn.OpenStreamSync(ctx)
+	a.Nil(err)
+	stream.SetDeadline(time.Now().Add(5 * time.Second))
+	// the stream is accepted after the client sends data on it.
+	_, err = stream.Write([]byte("ping"))
+	a.Nil(err)
+
+	c, err := ln.Accept()
+	a.Nil(err)
+	c.SetDeadline(time.Now().Add(5 * time.Second))
+	a.Equal(udp.LocalAddr().String(), c.RemoteAddr().String())
+	state, ok := server.ConnectionState(c)
+	a.True(ok)
+	a.Equal(NextProto, state.NegotiatedProtocol)
+	b := make([]byte, 4)
+	_, err = io.ReadFull(c, b)
+	a.Nil(err)
+	a.Equal("ping", string(b))
+
+	// the connection keeps alive after the client changes its network address.
+	udp2, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	a.Nil(err)
+	tr2 := &quic.Transport{Conn: udp2}
+	defer tr2.Close()
+	path, err := conn.AddPath(tr2)
+	a.Nil(err)
+	a.Nil(path.Probe(ctx))
+	a.Nil(path.Switch())
+	_, err = stream.Write([]byte("pong"))
+	a.Nil(err)
+	_, err = io.ReadFull(c, b)
+	a.Nil(err)
+	a.Equal("pong", string(b))
+	a.Equal(udp2.LocalAddr().String(), c.RemoteAddr().String())
+
+	// the data written before closing is delivered.
+	_, err = c.Write([]byte("bye"))
+	a.Nil(err)
+	a.Nil(c.Close())
+	rest, err := ioutil.ReadAll(stream)
+	a.Nil(err)
+	a.Equal("bye", string(rest))
+
+	// the clients must negotiate the mqtt ALPN protocol.
+	h3 := tlsConfig.Clone()
+	h3.NextProtos = []string{"h3"}
+	_, err = quic.DialAddr(ctx, ln.Addr().String(), h3, &quic.Config{})
+	a.NotNil(err)
+}
+
+func TestQUICListener_acceptStreamTimeout(t *testing.T) {
+	a := assert.New(t)
+	acceptStreamTimeout = 100 * time.Millisecond
+	defer func() {
+		acceptStreamTimeout = server.ConnectTimeout
+	}()
+	ln, tlsConfig := newTestQUICListener(t)
+	defer ln.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// the connection without any stream is closed by the server.
+	conn, err := quic.DialAddr(ctx, ln.Addr().String(), tlsConfig, &quic.Config{})
+	a.Nil(err)
+	select {
+	case <-conn.Context().Done():
+	case <-ctx.Done():
+		t.Fatal("the connection is not closed")
+	}
+}
+
+func TestServer_quic(t *testing.T) {
+	a := assert.New(t)
+	ln, tlsConfig := newTestQUICListener(t)
+	srv := server.New(
+		server.WithConfig(config.DefaultConfig()),
+		server.WithTCPListener(ln),
+		server.WithHook(server.Hooks{
+			OnBasicAuth: func(ctx context.Context, client server.Client, req *server.ConnectRequest) error {
+				return &codes.Error{Code: codes.NotAuthorized}
+			},
+		}),
+	)
+	a.Nil(srv.Run())
+	defer srv.Stop(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := quic.DialAddr(ctx, ln.Addr().String(), tlsConfig, &quic.Config{})
+	a.Nil(err)
+	defer conn.CloseWithError(0, "")
+	stream, err := conn.OpenStreamSync(ctx)
+	a.Nil(err)
+	stream.SetDeadline(time.Now().Add(5 * time.Second))
+
+	buf := &bytes.Buffer{}
+	connect := &packets.Connect{
+		Version:       packets.Version5,
+		ProtocolName:  []byte("MQTT"),
+		ProtocolLevel: packets.Version5,
+		ClientID:      []byte("cid"),
+		CleanStart:    true,
+		KeepAlive:     60,
+		Properties:    &packets.Properties{},
+	}
+	a.Nil(connect.Pack(buf))
+	_, err = stream.Write(buf.Bytes())
+	a.Nil(err)
+	p, err := packets.NewReader(stream).ReadPacket()
+	a.Nil(err)
+	a.Equal(codes.NotAuthorized, p.(*packets.Connack).Code)
+}
@@ -0,0 +1,108 @@
+package bolt
+
+import (
+	"sync"
+
+	"go.etcd.io/bbolt"
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/persistence/encoding"
+	"github.com/DrmagicE/gmqtt/retained"
+	"github.com/DrmagicE/gmqtt/retained/trie"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+// retainedBucket is the bucket that stores all retained messages, the key is the topic name.
+var retainedBucket = []byte("retained")
+
+var _ retained.Store = (*Store)(nil)
+
+// Store is the bolt implementation of retained.Store.
+// All retained messages are loaded into an in-memory trie when opening,
+// read operations are served by the trie and write operations are written through to bolt.
+type Store struct {
+	mu       sync.Mutex
+	memStore retained.Store
+	db       *bbolt.DB
+	log      *zap.Logger
+}
+
+// New returns a bolt retained store and loads the retained messages from the database.
+func New(db *bbolt.DB) (*Store, error) {
+	s := &Store{
+		memStore: trie.NewStore(),
+		db:       db,
+		log:      server.LoggerWithField(zap.String("retained", "bolt")),
+	}
+	err := db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(retainedBucket)
+		if err != nil {
+			return err
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			msg, err := encoding.DecodeRetainedMessage(v)
+			if err != nil {
+				return err
+			}
+			s.memStore.AddOrReplace(msg)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) GetRetainedMessage(topicName string) *gmqtt.Message {
+	return s.memStore.GetRetainedMessage(topicName)
+}
+
+func (s *Store) ClearAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		if err := tx.DeleteBucket(retainedBucket); err != nil && err != bbolt.ErrBucketNotFound {
+			return err
+		}
+		_, err := tx.CreateBucket(retainedBucket)
+		return err
+	})
+	if err != nil {
+		s.log.Error("failed to clear retained messages", zap.Error(err))
+	}
+	s.memStore.ClearAll()
+}
+
+func (s *Store) AddOrReplace(message *gmqtt.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(retainedBucket).Put([]byte(message.Topic), encoding.EncodeRetainedMessage(message))
+	})
+	if err != nil {
+		s.log.Error("failed to store retained message", zap.String("topic", message.Topic), zap.Error(err))
+	}
+	s.memStore.AddOrReplace(message)
+}
+
+func (s *Store) Remove(topicName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(retainedBucket).Delete([]byte(topicName))
+	})
+	if err != nil {
+		s.log.Error("failed to remove retained message", zap.String("topic", topicName), zap.Error(err))
+	}
+	s.memStore.Remove(topicName)
+}
+
+func (s *Store) GetMatchedMessages(topicFilter string) []*gmqtt.Message {
+	return s.memStore.GetMatchedMessages(topicFilter)
+}
+
+func (s *Store) Iterate(fn retained.IterateFn) {
+	s.memStore.Iterate(fn)
+}
@@ -0,0 +1,237 @@
+package file
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"os"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/persistence/encoding"
+	"github.com/DrmagicE/gmqtt/retained"
+	"github.com/DrmagicE/gmqtt/retained/trie"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+// The operations of the log records.
+const (
+	opAdd byte = iota + 1
+	opRemove
+	opClear
+)
+
+// compactThreshold is the minimum number of records that trigger a compaction.
+const compactThreshold = 1024
+
+var _ retained.Store = (*Store)(nil)
+
+// Store is the file implementation of retained.Store.
+// All changes are appended to a log file, and the log file is replayed into an in-memory trie when opening.
+// Read operations are served by the trie.
+// The log file will be compacted when opening and when the number of records grows too large.
+//
+// Each record is encoded as: op (1 byte) | data length (4 bytes, big endian) | data.
+// Notice:
+// The records are not synced to disk on every write, the last few records may be lost if the OS crashes.
+type Store struct {
+	mu       sync.Mutex
+	path     string
+	f        *os.File
+	memStore retained.Store
+	// records is the number of records written since the last compaction.
+	records int
+	// live is the number of retained messages after the last compaction.
+	live int
+	log  *zap.Logger
+}
+
+// New opens the log file in the given path and loads the retained messages from it.
+// The file will be created if it does not exist.
+func New(path string) (*Store, error) {
+	s := &Store{
+		path:     path,
+		memStore: trie.NewStore(),
+		log:      server.LoggerWithField(zap.String("retained", "file")),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if err := s.compactLocked(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load replays the log file into the trie.
+// A truncated or corrupted record at the tail of the file, which may be caused by a crash, is ignored.
+func (s *Store) load() error {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	header := make([]byte, 5)
+	for {
+		_, err = io.ReadFull(r, header)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			s.log.Warn("ignore the truncated record", zap.String("path", s.path), zap.Error(err))
+			return nil
+		}
+		data := make([]byte, binary.BigEndian.Uint32(header[1:]))
+		_, err = io.ReadFull(r, data)
+		if err != nil {
+			s.log.Warn("ignore the truncated record", zap.String("path", s.path), zap.Error(err))
+			return nil
+		}
+		if err = s.apply(header[0], data); err != nil {
+			s.log.Warn("ignore the corrupted record", zap.String("path", s.path), zap.Error(err))
+			return nil
+		}
+	}
+}
+
+func (s *Store) apply(op byte, data []byte) error {
+	switch op {
+	case opAdd:
+		msg, err := encoding.DecodeRetainedMessage(data)
+		if err != nil {
+			return err
+		}
+		s.memStore.AddOrReplace(msg)
+	case opRemove:
+		s.memStore.Remove(string(data))
+	case opClear:
+		s.memStore.ClearAll()
+	default:
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+func writeRecord(w io.Writer, op byte, data []byte) error {
+	b := make([]byte, 5+len(data))
+	b[0] = op
+	binary.BigEndian.PutUint32(b[1:], uint32(len(data)))
+	copy(b[5:], data)
+	_, err := w.Write(b)
+	return err
+}
+
+// compactLocked rewrites the log file with the retained messages in the trie, must call under s.mu.Lock.
+func (s *Store) compactLocked() error {
+	tmp := s.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	var live int
+	s.memStore.Iterate(func(message *gmqtt.Message) bool {
+		err = writeRecord(w, opAdd, encoding.EncodeRetainedMessage(message))
+		live++
+		return err == nil
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if s.f != nil {
+		_ = s.f.Close()
+		s.f = nil
+	}
+	if err = os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+	s.f, err = os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	s.records = 0
+	s.live = live
+	return nil
+}
+
+// appendLocked appends a record to the log file, must call under s.mu.Lock.
+func (s *Store) appendLocked(op byte, data []byte) {
+	if s.f == nil {
+		s.log.Error("failed to write retained message", zap.String("path", s.path), zap.Error(os.ErrClosed))
+		return
+	}
+	if err := writeRecord(s.f, op, data); err != nil {
+		s.log.Error("failed to write retained message", zap.String("path", s.path), zap.Error(err))
+		return
+	}
+	s.records++
+	if s.records >= compactThreshold+2*s.live {
+		if err := s.compactLocked(); err != nil {
+			s.log.Error("failed to compact retained message file", zap.String("path", s.path), zap.Error(err))
+		}
+	}
+}
+
+func (s *Store) GetRetainedMessage(topicName string) *gmqtt.Message {
+	return s.memStore.GetRetainedMessage(topicName)
+}
+
+func (s *Store) ClearAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.memStore.ClearAll()
+	s.appendLocked(opClear, nil)
+}
+
+func (s *Store) AddOrReplace(message *gmqtt.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.memStore.AddOrReplace(message)
+	s.appendLocked(opAdd, encoding.EncodeRetainedMessage(message))
+}
+
+func (s *Store) Remove(topicName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.memStore.Remove(topicName)
+	s.appendLocked(opRemove, []byte(topicName))
+}
+
+func (s *Store) GetMatchedMessages(topicFilter string) []*gmqtt.Message {
+	return s.memStore.GetMatchedMessages(topicFilter)
+}
+
+func (s *Store) Iterate(fn retained.IterateFn) {
+	s.memStore.Iterate(fn)
+}
+
+// Close syncs and closes the log file.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Sync()
+	if cerr := s.f.Close(); err == nil {
+		err = cerr
+	}
+	s.f = nil
+	return err
+}
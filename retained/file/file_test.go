@@ -0,0 +1,108 @@
+package file
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/retained"
+	"github.com/DrmagicE/gmqtt/retained/test"
+)
+
+func tempPath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "gmqtt_retained")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "retained.db"), func() {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func mustNew(t *testing.T, path string) *Store {
+	s, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSuite(t *testing.T) {
+	path, clean := tempPath(t)
+	defer clean()
+	s := mustNew(t, path)
+	defer s.Close()
+	test.TestSuite(t, s)
+}
+
+func TestPersistence(t *testing.T) {
+	path, clean := tempPath(t)
+	defer clean()
+	var s *Store
+	test.TestPersistence(t, func() retained.Store {
+		if s != nil {
+			assert.Nil(t, s.Close())
+		}
+		s = mustNew(t, path)
+		return s
+	})
+	assert.Nil(t, s.Close())
+}
+
+func TestStore_truncated(t *testing.T) {
+	a := assert.New(t)
+	path, clean := tempPath(t)
+	defer clean()
+	s := mustNew(t, path)
+	s.AddOrReplace(&gmqtt.Message{Topic: "a", Payload: []byte("a")})
+	s.AddOrReplace(&gmqtt.Message{Topic: "b", Payload: []byte("b")})
+	a.Nil(s.Close())
+
+	// simulate a partial write of the last record.
+	fi, err := os.Stat(path)
+	a.Nil(err)
+	a.Nil(os.Truncate(path, fi.Size()-1))
+
+	s = mustNew(t, path)
+	defer s.Close()
+	a.NotNil(s.GetRetainedMessage("a"))
+	a.Nil(s.GetRetainedMessage("b"))
+}
+
+func TestStore_compact(t *testing.T) {
+	a := assert.New(t)
+	path, clean := tempPath(t)
+	defer clean()
+	s := mustNew(t, path)
+	var payload []byte
+	for i := 0; i < compactThreshold+10; i++ {
+		payload = []byte(strconv.Itoa(i))
+		s.AddOrReplace(&gmqtt.Message{Topic: "a", Payload: payload})
+	}
+	a.Equal(10, s.records)
+	a.Nil(s.Close())
+
+	s = mustNew(t, path)
+	defer s.Close()
+	a.Equal(payload, s.GetRetainedMessage("a").Payload)
+}
+
+func TestStore_largePayload(t *testing.T) {
+	a := assert.New(t)
+	path, clean := tempPath(t)
+	defer clean()
+	s := mustNew(t, path)
+	payload := bytes.Repeat([]byte("a"), 70000)
+	s.AddOrReplace(&gmqtt.Message{Topic: "a", Payload: payload})
+	a.Nil(s.Close())
+
+	s = mustNew(t, path)
+	defer s.Close()
+	a.Equal(payload, s.GetRetainedMessage("a").Payload)
+}
@@ -0,0 +1,97 @@
+package redis
+
+import (
+	"sync"
+
+	redigo "github.com/gomodule/redigo/redis"
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/persistence/encoding"
+	"github.com/DrmagicE/gmqtt/retained"
+	"github.com/DrmagicE/gmqtt/retained/trie"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+// retainedKey is the redis hash key that stores all retained messages, the hash field is the topic name.
+const retainedKey = "retained"
+
+var _ retained.Store = (*Store)(nil)
+
+// Store is the redis implementation of retained.Store.
+// All retained messages are loaded into an in-memory trie when opening,
+// read operations are served by the trie and write operations are written through to redis.
+type Store struct {
+	mu       sync.Mutex
+	memStore retained.Store
+	pool     *redigo.Pool
+	log      *zap.Logger
+}
+
+// New returns a redis retained store and loads the retained messages from redis.
+func New(pool *redigo.Pool) (*Store, error) {
+	s := &Store{
+		memStore: trie.NewStore(),
+		pool:     pool,
+		log:      server.LoggerWithField(zap.String("retained", "redis")),
+	}
+	c := pool.Get()
+	defer c.Close()
+	rs, err := redigo.ByteSlices(c.Do("hvals", retainedKey))
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range rs {
+		msg, err := encoding.DecodeRetainedMessage(v)
+		if err != nil {
+			return nil, err
+		}
+		s.memStore.AddOrReplace(msg)
+	}
+	return s, nil
+}
+
+func (s *Store) GetRetainedMessage(topicName string) *gmqtt.Message {
+	return s.memStore.GetRetainedMessage(topicName)
+}
+
+func (s *Store) ClearAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.pool.Get()
+	defer c.Close()
+	if _, err := c.Do("del", retainedKey); err != nil {
+		s.log.Error("failed to clear retained messages", zap.Error(err))
+	}
+	s.memStore.ClearAll()
+}
+
+func (s *Store) AddOrReplace(message *gmqtt.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.pool.Get()
+	defer c.Close()
+	if _, err := c.Do("hset", retainedKey, message.Topic, encoding.EncodeRetainedMessage(message)); err != nil {
+		s.log.Error("failed to store retained message", zap.String("topic", message.Topic), zap.Error(err))
+	}
+	s.memStore.AddOrReplace(message)
+}
+
+func (s *Store) Remove(topicName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.pool.Get()
+	defer c.Close()
+	if _, err := c.Do("hdel", retainedKey, topicName); err != nil {
+		s.log.Error("failed to remove retained message", zap.String("topic", topicName), zap.Error(err))
+	}
+	s.memStore.Remove(topicName)
+}
+
+func (s *Store) GetMatchedMessages(topicFilter string) []*gmqtt.Message {
+	return s.memStore.GetMatchedMessages(topicFilter)
+}
+
+func (s *Store) Iterate(fn retained.IterateFn) {
+	s.memStore.Iterate(fn)
+}
@@ -0,0 +1,134 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/retained"
+)
+
+var testMsgs = []*gmqtt.Message{
+	{
+		QoS:      packets.Qos1,
+		Retained: true,
+		Topic:    "a/b/c",
+		Payload:  []byte("abc"),
+	},
+	{
+		QoS:      packets.Qos2,
+		Retained: true,
+		Topic:    "a/b",
+		Payload:  []byte("ab"),
+		// v5 properties
+		ContentType:     "text/plain",
+		CorrelationData: []byte("correlation"),
+		MessageExpiry:   10,
+		PayloadFormat:   packets.PayloadFormatString,
+		ResponseTopic:   "response",
+		UserProperties: []packets.UserProperty{
+			{K: []byte("k"), V: []byte("v")},
+		},
+	},
+	{
+		Retained: true,
+		Topic:    "a",
+		Payload:  []byte("a"),
+	},
+	{
+		Retained: true,
+		Topic:    "$SYS/a",
+		Payload:  []byte("sys"),
+	},
+}
+
+func topics(msgs []*gmqtt.Message) []string {
+	var rs []string
+	for _, v := range msgs {
+		rs = append(rs, v.Topic)
+	}
+	return rs
+}
+
+func iterate(store retained.Store) []*gmqtt.Message {
+	var msgs []*gmqtt.Message
+	store.Iterate(func(message *gmqtt.Message) bool {
+		msgs = append(msgs, message)
+		return true
+	})
+	return msgs
+}
+
+// TestSuite tests the basic operations of the retained.Store implementation.
+// The store must be empty.
+func TestSuite(t *testing.T, store retained.Store) {
+	a := assert.New(t)
+	for _, v := range testMsgs {
+		store.AddOrReplace(v.Copy())
+	}
+	for _, v := range testMsgs {
+		a.Equal(v, store.GetRetainedMessage(v.Topic))
+	}
+	a.Nil(store.GetRetainedMessage("a/b/c/d"))
+	a.ElementsMatch(topics(testMsgs), topics(iterate(store)))
+
+	a.ElementsMatch([]string{"a/b/c", "a/b", "a"}, topics(store.GetMatchedMessages("#")))
+	a.ElementsMatch([]string{"a/b"}, topics(store.GetMatchedMessages("a/+")))
+	a.ElementsMatch([]string{"a/b/c", "a/b", "a"}, topics(store.GetMatchedMessages("a/#")))
+	a.ElementsMatch([]string{"$SYS/a"}, topics(store.GetMatchedMessages("$SYS/#")))
+	a.Len(store.GetMatchedMessages("b/#"), 0)
+
+	// replace
+	replaced := testMsgs[0].Copy()
+	replaced.Payload = []byte("replaced")
+	store.AddOrReplace(replaced)
+	a.Equal(replaced, store.GetRetainedMessage(replaced.Topic))
+	a.Len(iterate(store), len(testMsgs))
+
+	// stop iteration
+	var n int
+	store.Iterate(func(message *gmqtt.Message) bool {
+		n++
+		return false
+	})
+	a.Equal(1, n)
+
+	store.Remove("a/b")
+	a.Nil(store.GetRetainedMessage("a/b"))
+	a.ElementsMatch([]string{"a/b/c", "a"}, topics(store.GetMatchedMessages("#")))
+	// remove a not exist topic
+	store.Remove("not/exist")
+
+	store.ClearAll()
+	a.Len(iterate(store), 0)
+	for _, v := range testMsgs {
+		a.Nil(store.GetRetainedMessage(v.Topic))
+	}
+}
+
+// TestPersistence tests whether the retained messages can be restored after reopening.
+// The open function should release the store returned by the previous call (if any),
+// and return a new store which is backed by the same storage. The storage must be empty.
+func TestPersistence(t *testing.T, open func() retained.Store) {
+	a := assert.New(t)
+	store := open()
+	for _, v := range testMsgs {
+		store.AddOrReplace(v.Copy())
+	}
+	replaced := testMsgs[0].Copy()
+	replaced.Payload = []byte("replaced")
+	store.AddOrReplace(replaced)
+	store.Remove("a/b")
+
+	store = open()
+	a.Equal(replaced, store.GetRetainedMessage(replaced.Topic))
+	a.Nil(store.GetRetainedMessage("a/b"))
+	a.ElementsMatch([]string{"a/b/c", "a", "$SYS/a"}, topics(iterate(store)))
+	a.ElementsMatch([]string{"a/b/c", "a"}, topics(store.GetMatchedMessages("a/#")))
+
+	store.ClearAll()
+	store = open()
+	a.Len(iterate(store), 0)
+}
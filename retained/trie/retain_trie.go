@@ -140,9 +140,10 @@ func (t *topicTrie) remove(topicName string) {
 	}
 }
 
+// preOrderTraverse calls the fn callback for each message in the trie, and returns false if the traverse is stopped by fn.
 func (t *topicTrie) preOrderTraverse(fn retained.IterateFn) bool {
 	if t == nil {
-		return false
+		return true
 	}
 	if t.msg != nil {
 		if !fn(t.msg) {
@@ -150,7 +151,9 @@ func (t *topicTrie) preOrderTraverse(fn retained.IterateFn) bool {
 		}
 	}
 	for _, c := range t.children {
-		c.preOrderTraverse(fn)
+		if !c.preOrderTraverse(fn) {
+			return false
+		}
 	}
-	return false
+	return true
 }
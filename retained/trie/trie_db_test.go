@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/retained/test"
 )
 
 func TestTrieDB_ClearAll(t *testing.T) {
@@ -211,3 +212,7 @@ func TestTrieDB_Iterate(t *testing.T) {
 	a.ElementsMatch(msgs, rs)
 
 }
+
+func TestTrieDB_Suite(t *testing.T) {
+	test.TestSuite(t, NewStore())
+}
@@ -15,6 +15,7 @@ import (
 	"net"
 	"os"
 	"reflect"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -36,6 +37,9 @@ var (
 	ErrConnectTimeOut = errors.New("connect time out")
 )
 
+// ConnectTimeout is the maximum time to wait for the CONNECT packet after the connection is accepted.
+const ConnectTimeout = 5 * time.Second
+
 // Client status
 const (
 	Connecting = iota
@@ -100,6 +104,8 @@ type ClientOptions struct {
 	ClientID  string
 	Username  string
 	KeepAlive uint16
+	// CleanStart is the Clean Start flag (Clean Session in v3.1.1) in the CONNECT packet.
+	CleanStart bool
 	// SessionExpiry is the session expiry interval in seconds.
 	// If the client version is v5, this value will be set into connack Session Expiry Interval property.
 	// See: https://docs.oasis-open.org/mqtt/mqtt/v5.0/os/mqtt-v5.0-os.html#_Toc3901082
@@ -165,6 +171,10 @@ type client struct {
 	out          chan packets.Packet
 	close        chan struct{}
 	connected    chan struct{}
+	// certIdentity is the CertIdentity of the TLSListener or WsServer which accepts the client.
+	certIdentity string
+	// continueAuth notifies the read loop to read the next AUTH packet during the enhanced authentication.
+	continueAuth chan struct{}
 	status       int32
 	// if 1, when client close, the session expiry interval will be ignored and the session will be removed.
 	forceRemoveSession int32
@@ -188,10 +198,16 @@ type client struct {
 	serverReceiveMaximumQuota uint16
 
 	config config.Config
+	// sharedLimiter holds the rate limits shared by the clients, it is used to create rateLimiter.
+	sharedLimiter *publishRateLimiter
+	// rateLimiter is the publish rate limiter, it is set after authentication success.
+	rateLimiter *clientRateLimiter
 
 	queueStore queue.Store
 	unackStore unack.Store
 	pl         *packetIDLimiter
+	// draining is 1 if the client is being disconnected by Server.Drain.
+	draining int32
 }
 
 func (client *client) SessionInfo() *gmqtt.Session {
@@ -319,7 +335,7 @@ func (client *client) writeLoop() {
 }
 
 func (client *client) writePacket(packet packets.Packet) error {
-	if client.server.config.Log.DumpPacket {
+	if client.config.Log.DumpPacket {
 		if ce := zaplog.Check(zapcore.DebugLevel, "sending packet"); ce != nil {
 			ce.Write(
 				zap.String("packet", packet.String()),
@@ -363,6 +379,12 @@ func (client *client) readLoop() {
 		client.setError(err)
 		close(client.in)
 	}()
+	// aliases stores the topic aliases to resolve the topic names for the rate limiting,
+	// the aliases are validated in publishHandler.
+	aliases := make(map[uint16][]byte)
+	// qos2 stores the packet ids of the QoS 2 messages which have been charged by the rate limiting,
+	// so that the retransmitted messages will not be charged again.
+	qos2 := make(map[packets.PacketID]struct{})
 	for {
 		var packet packets.Packet
 		if client.IsConnected() {
@@ -386,11 +408,31 @@ func (client *client) readLoop() {
 					return
 				}
 			}
+			if client.rateLimiter != nil && client.rateLimiter.backpressure() {
+				// stop reading from the connection until the message is allowed.
+				if topic, ok := client.backpressureTopic(pub, aliases, qos2); ok {
+					if d := client.rateLimiter.reserve(topic, len(pub.Payload)); d > 0 {
+						t := time.NewTimer(d)
+						select {
+						case <-t.C:
+						case <-srv.exitChan:
+							t.Stop()
+							return
+						}
+					}
+				}
+			}
+		}
+		if pubrel, ok := packet.(*packets.Pubrel); ok {
+			delete(qos2, pubrel.PacketID)
 		}
 		client.in <- packet
-		<-client.connected
+		select {
+		case <-client.connected:
+		case <-client.continueAuth:
+		}
 		srv.statsManager.packetReceived(packet, client.opts.ClientID)
-		if client.server.config.Log.DumpPacket {
+		if client.config.Log.DumpPacket {
 			if ce := zaplog.Check(zapcore.DebugLevel, "received packet"); ce != nil {
 				ce.Write(
 					zap.String("packet", packet.String()),
@@ -483,7 +525,7 @@ func (client *client) connectWithTimeOut() (ok bool) {
 		}
 		close(client.connected)
 	}()
-	timeout := time.NewTimer(5 * time.Second)
+	timeout := time.NewTimer(ConnectTimeout)
 	defer timeout.Stop()
 	var conn *packets.Connect
 	var authOpts *AuthOptions
@@ -503,6 +545,8 @@ func (client *client) connectWithTimeOut() (ok bool) {
 					err = codes.ErrProtocol
 					return
 				}
+				conn = p.(*packets.Connect)
+				client.applyCertIdentity(conn)
 				if !client.config.MQTT.AllowZeroLenClientID && len(conn.ClientID) == 0 {
 					err = &codes.Error{
 						Code: codes.ClientIdentifierNotValid,
@@ -510,7 +554,6 @@ func (client *client) connectWithTimeOut() (ok bool) {
 					return
 				}
 
-				conn = p.(*packets.Connect)
 				client.version = conn.Version
 				// default auth options
 				authOpts = client.defaultAuthOptions(conn)
@@ -548,7 +591,7 @@ func (client *client) connectWithTimeOut() (ok bool) {
 							Connect: conn,
 							Options: authOpts,
 						})
-						if err != nil {
+						if err == nil && resp != nil {
 							if resp.Continue {
 								code = codes.ContinueAuthentication
 							} else {
@@ -557,19 +600,41 @@ func (client *client) connectWithTimeOut() (ok bool) {
 							authData = resp.AuthData
 							onAuth = resp.OnAuth
 						}
+					} else {
+						err = &codes.Error{
+							Code: codes.BadAuthMethod,
+						}
 					}
 				}
+				// The client id must be determined before sending any packets to the client,
+				// so the AssignedClientID can only be set in OnBasicAuth or OnEnhancedAuth.
+				if len(conn.ClientID) == 0 {
+					if client.version == packets.Version5 && len(authOpts.AssignedClientID) != 0 {
+						client.opts.ClientID = string(authOpts.AssignedClientID)
+					} else {
+						client.opts.ClientID = getRandomUUID()
+					}
+				} else {
+					client.opts.ClientID = string(conn.ClientID)
+				}
 			case *packets.Auth:
 				if conn == nil || client.version == packets.Version311 {
 					err = codes.ErrProtocol
 					return
 				}
+				auth := p.(*packets.Auth)
+				if auth.Code != codes.ContinueAuthentication || auth.Properties == nil ||
+					!bytes.Equal(auth.Properties.AuthMethod, conn.Properties.AuthMethod) {
+					err = codes.ErrProtocol
+					return
+				}
 				if onAuth != nil {
-					authResp, err := onAuth(context.Background(), client, &AuthRequest{
-						Auth:    p.(*packets.Auth),
+					var authResp *AuthResponse
+					authResp, err = onAuth(context.Background(), client, &AuthRequest{
+						Auth:    auth,
 						Options: authOpts,
 					})
-					if err != nil {
+					if err == nil && authResp != nil {
 						if authResp.Continue {
 							code = codes.ContinueAuthentication
 						}
@@ -596,7 +661,7 @@ func (client *client) connectWithTimeOut() (ok bool) {
 				return
 			}
 			// authentication success
-			if code == codes.ContinueAuthentication && authData != nil {
+			if code == codes.ContinueAuthentication {
 				client.out <- &packets.Auth{
 					Code: code,
 					Properties: &packets.Properties{
@@ -604,6 +669,7 @@ func (client *client) connectWithTimeOut() (ok bool) {
 						AuthData:   authData,
 					},
 				}
+				client.continueAuth <- struct{}{}
 				continue
 			}
 
@@ -629,20 +695,12 @@ func (client *client) connectWithTimeOut() (ok bool) {
 				client.aliasMapper = make([][]byte, client.opts.ReceiveMax+1)
 
 				if len(conn.ClientID) == 0 {
-					if len(authOpts.AssignedClientID) != 0 {
-						client.opts.ClientID = string(authOpts.AssignedClientID)
-					} else {
-						client.opts.ClientID = getRandomUUID()
-						authOpts.AssignedClientID = []byte(client.opts.ClientID)
-					}
-				} else {
-					client.opts.ClientID = string(conn.ClientID)
+					authOpts.AssignedClientID = []byte(client.opts.ClientID)
 				}
 				client.opts.KeepAlive = authOpts.KeepAlive
 				connackPpt = &packets.Properties{
 					SessionExpiryInterval: &authOpts.SessionExpiry,
 					ReceiveMaximum:        &authOpts.ReceiveMax,
-					MaximumQoS:            &authOpts.MaximumQoS,
 					RetainAvailable:       bool2Byte(authOpts.RetainAvailable),
 					TopicAliasMaximum:     &authOpts.TopicAliasMax,
 					WildcardSubAvailable:  bool2Byte(authOpts.WildcardSubAvailable),
@@ -653,12 +711,15 @@ func (client *client) connectWithTimeOut() (ok bool) {
 					AssignedClientID:      authOpts.AssignedClientID,
 					ResponseInfo:          authOpts.ResponseInfo,
 				}
-			} else {
-				if len(conn.ClientID) == 0 {
-					client.opts.ClientID = getRandomUUID()
-				} else {
-					client.opts.ClientID = string(conn.ClientID)
+				if len(client.opts.AuthMethod) != 0 {
+					connackPpt.AuthMethod = client.opts.AuthMethod
+					connackPpt.AuthData = authData
 				}
+				// The Maximum QoS property can only be 0 or 1, absent means 2. [MQTT-3.2.2.3.4]
+				if authOpts.MaximumQoS < packets.Qos2 {
+					connackPpt.MaximumQoS = &authOpts.MaximumQoS
+				}
+			} else {
 				client.opts.KeepAlive = conn.KeepAlive
 			}
 
@@ -666,6 +727,8 @@ func (client *client) connectWithTimeOut() (ok bool) {
 				_ = client.rwc.SetReadDeadline(time.Now().Add(time.Duration(keepAlive/2+keepAlive) * time.Second))
 			}
 			client.opts.Username = string(conn.Username)
+			client.opts.CleanStart = conn.CleanStart
+			client.rateLimiter = client.sharedLimiter.newClientRateLimiter(client.opts.Username, authOpts.RateLimit)
 			client.newPacketIDLimiter(client.opts.MaxInflight)
 
 			err = client.server.registerClient(conn, connackPpt, client)
@@ -700,6 +763,7 @@ func (client *client) defaultAuthOptions(connect *packets.Connect) *AuthOptions
 		SharedSubAvailable:   client.config.MQTT.SharedSubAvailable,
 		KeepAlive:            client.config.MQTT.MaxKeepAlive,
 		MaxInflight:          client.config.MQTT.MaxInflight,
+		RateLimit:            client.config.MQTT.RateLimit.Client,
 	}
 	if connect.KeepAlive < opts.KeepAlive {
 		opts.KeepAlive = connect.KeepAlive
@@ -720,6 +784,9 @@ func (client *client) internalClose() {
 		client.server.hooks.OnClosed(context.Background(), client, client.err)
 	}
 	client.server.unregisterClient(client)
+	if client.rateLimiter != nil {
+		client.rateLimiter.release()
+	}
 	putBufioReader(client.bufr)
 	putBufioWriter(client.bufw)
 	client.server.statsManager.clientDisconnected(client.opts.ClientID)
@@ -936,6 +1003,15 @@ func (client *client) publishHandler(pub *packets.Publish) *codes.Error {
 		}
 	}
 
+	var err error
+	// isDelayed indicates whether the message is published to $delayed/{seconds}/{topic}.
+	// The topic of the delayed message is replaced with the real topic.
+	isDelayed := strings.HasPrefix(msg.Topic, delayedTopicPrefix) && srv.delayedQueue.enabled()
+	var delay time.Duration
+	if isDelayed {
+		msg.Topic, delay, err = srv.delayedQueue.parseTopic(msg.Topic)
+	}
+
 	if pub.Qos == packets.Qos2 {
 		exist, err := client.unackStore.Set(pub.PacketID)
 		if err != nil {
@@ -946,7 +1022,15 @@ func (client *client) publishHandler(pub *packets.Publish) *codes.Error {
 		}
 	}
 
-	if pub.Retain {
+	// the invalid messages and the retransmitted QoS 2 messages are not charged.
+	if !dup && err == nil && client.rateLimiter != nil && !client.rateLimiter.backpressure() {
+		if ok, code := client.rateLimiter.allow(msg.Topic, len(msg.Payload)); !ok {
+			return client.rejectPublish(pub, code)
+		}
+	}
+
+	// the retained delayed message is stored when it is delivered.
+	if pub.Retain && !isDelayed {
 		if len(pub.Payload) == 0 {
 			srv.retainedDB.Remove(string(pub.TopicName))
 		} else {
@@ -954,9 +1038,8 @@ func (client *client) publishHandler(pub *packets.Publish) *codes.Error {
 		}
 	}
 
-	var err error
 	var topicMatched bool
-	if !dup {
+	if !dup && err == nil {
 		if srv.hooks.OnMsgArrived != nil {
 			req := &MsgArrivedRequest{
 				Publish: pub,
@@ -966,9 +1049,14 @@ func (client *client) publishHandler(pub *packets.Publish) *codes.Error {
 			msg = req.Message
 		}
 		if msg != nil && err == nil {
-			srv.mu.Lock()
-			topicMatched = srv.deliverMessageHandler(client.opts.ClientID, msg)
-			srv.mu.Unlock()
+			if isDelayed {
+				err = srv.delayedQueue.add(client.opts.ClientID, msg, delay)
+				topicMatched = true
+			} else {
+				srv.mu.Lock()
+				topicMatched = srv.dispatchMessageLocked(client.opts.ClientID, msg)
+				srv.mu.Unlock()
+			}
 		}
 	}
 
@@ -1005,6 +1093,46 @@ func (client *client) publishHandler(pub *packets.Publish) *codes.Error {
 
 }
 
+// rejectPublish rejects the message which exceeds the rate limit.
+// The v5 client will receive the code in PUBACK/PUBREC, and the QoS 0 message is dropped silently.
+// The v3 client will be disconnected because v3 does not support negative acknowledgements,
+// unless config.RateLimit.V3AckAndDrop is set, in which case the message is acknowledged and dropped.
+func (client *client) rejectPublish(pub *packets.Publish, code codes.Code) *codes.Error {
+	zaplog.Debug("publish rate limit exceeded",
+		zap.String("client_id", client.opts.ClientID),
+		zap.String("topic", string(pub.TopicName)),
+		zap.Uint8("code", code))
+	if client.version != packets.Version5 {
+		if !client.rateLimiter.v3AckAndDrop() {
+			// the message will be retransmitted as a new one after reconnecting.
+			if pub.Qos == packets.Qos2 {
+				err := client.unackStore.Remove(pub.PacketID)
+				if err != nil {
+					return converError(err)
+				}
+			}
+			return &codes.Error{
+				Code: code,
+			}
+		}
+		code = codes.Success
+	}
+	if pub.Qos == packets.Qos1 {
+		client.write(pub.NewPuback(code, nil))
+	}
+	if pub.Qos == packets.Qos2 {
+		// the packet id can be reused once the error PUBREC is sent.
+		if code >= codes.UnspecifiedError {
+			err := client.unackStore.Remove(pub.PacketID)
+			if err != nil {
+				return converError(err)
+			}
+		}
+		client.write(pub.NewPubrec(code, nil))
+	}
+	return nil
+}
+
 func converError(err error) *codes.Error {
 	if err == nil {
 		return nil
@@ -1171,6 +1299,9 @@ func (client *client) reAuthHandler(auth *packets.Auth) *codes.Error {
 	} else {
 		return codes.ErrProtocol
 	}
+	if resp == nil {
+		resp = &AuthResponse{}
+	}
 	if resp.Continue {
 		code = codes.ContinueAuthentication
 	}
@@ -1260,8 +1391,9 @@ func (client *client) readHandle() {
 				err = codes.ErrProtocol
 				return
 			}
-			if !bytes.Equal(client.opts.AuthMethod, auth.Properties.AuthData) {
-				codeErr = codes.ErrProtocol
+			if auth.Properties == nil || len(client.opts.AuthMethod) == 0 ||
+				!bytes.Equal(client.opts.AuthMethod, auth.Properties.AuthMethod) {
+				err = codes.ErrProtocol
 				return
 			}
 			codeErr = client.reAuthHandler(auth)
@@ -1360,6 +1492,10 @@ func (client *client) pollMessageHandler() {
 	}
 	var ids []packets.PacketID
 	for {
+		// stop delivering new messages to the draining client, the messages are kept in the queue.
+		if client.isDraining() {
+			return
+		}
 		max := uint16(100)
 		if client.opts.MaxInflight < max {
 			max = client.opts.MaxInflight
@@ -3,6 +3,7 @@ package server
 import (
 	"bytes"
 	"container/list"
+	"context"
 	"io"
 	"net"
 	"reflect"
@@ -1447,3 +1448,163 @@ func TestMsg_TotalBytes(t *testing.T) {
 	}
 
 }
+
+func TestClient_connectWithTimeOut_zeroLenClientID(t *testing.T) {
+	a := assert.New(t)
+	srv := defaultServer()
+	srv.config.MQTT.AllowZeroLenClientID = false
+	c, er := srv.newClient(noopConn{})
+	a.Nil(er)
+	c.in <- &packets.Connect{
+		Version:       packets.Version5,
+		ProtocolName:  []byte("MQTT"),
+		ProtocolLevel: packets.Version5,
+		Properties:    &packets.Properties{},
+	}
+	a.False(c.connectWithTimeOut())
+	a.Equal(codes.ClientIdentifierNotValid, c.err.(*codes.Error).Code)
+}
+
+func TestClient_connectWithTimeOut_enhancedAuth(t *testing.T) {
+	a := assert.New(t)
+	newConnect := func() *packets.Connect {
+		return &packets.Connect{
+			Version:       packets.Version5,
+			ProtocolName:  []byte("MQTT"),
+			ProtocolLevel: packets.Version5,
+			ClientID:      []byte("cid"),
+			Properties: &packets.Properties{
+				AuthMethod: []byte("method"),
+				AuthData:   []byte("data"),
+			},
+		}
+	}
+	newAuth := func(method string) *packets.Auth {
+		return &packets.Auth{
+			Code: codes.ContinueAuthentication,
+			Properties: &packets.Properties{
+				AuthMethod: []byte(method),
+				AuthData:   []byte("data"),
+			},
+		}
+	}
+	var tt = []struct {
+		name string
+		// in is the packets sent by the client.
+		in         []packets.Packet
+		enhanceErr error
+		authErr    error
+		// out is the reason codes of the packets sent by the server.
+		out []codes.Code
+		err codes.Code
+	}{
+		{
+			name:       "enhanced_auth_error",
+			in:         []packets.Packet{newConnect()},
+			enhanceErr: &codes.Error{Code: codes.NotAuthorized},
+			out:        []codes.Code{codes.NotAuthorized},
+			err:        codes.NotAuthorized,
+		},
+		{
+			name:    "on_auth_error",
+			in:      []packets.Packet{newConnect(), newAuth("method")},
+			authErr: &codes.Error{Code: codes.BadUserNameOrPassword},
+			out:     []codes.Code{codes.ContinueAuthentication, codes.BadUserNameOrPassword},
+			err:     codes.BadUserNameOrPassword,
+		},
+		{
+			name: "auth_method_mismatch",
+			in:   []packets.Packet{newConnect(), newAuth("other")},
+			out:  []codes.Code{codes.ContinueAuthentication},
+			err:  codes.ProtocolError,
+		},
+	}
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			srv := defaultServer()
+			srv.hooks.OnEnhancedAuth = func(ctx context.Context, client Client, req *ConnectRequest) (*EnhancedAuthResponse, error) {
+				if v.enhanceErr != nil {
+					return nil, v.enhanceErr
+				}
+				return &EnhancedAuthResponse{
+					Continue: true,
+					OnAuth: func(ctx context.Context, client Client, req *AuthRequest) (*AuthResponse, error) {
+						return nil, v.authErr
+					},
+				}, nil
+			}
+			c, er := srv.newClient(noopConn{})
+			a.Nil(er)
+			for _, p := range v.in {
+				c.in <- p
+			}
+			a.False(c.connectWithTimeOut())
+			a.Equal(v.err, c.err.(*codes.Error).Code)
+			for _, code := range v.out {
+				select {
+				case p := <-c.out:
+					switch p := p.(type) {
+					case *packets.Auth:
+						a.Equal(code, p.Code)
+						a.Equal([]byte("method"), p.Properties.AuthMethod)
+					case *packets.Connack:
+						a.Equal(code, p.Code)
+					default:
+						t.Fatalf("unexpected packet: %v", p)
+					}
+				default:
+					t.Fatal("missing output")
+				}
+			}
+		})
+	}
+}
+
+func TestClient_readHandle_reAuth(t *testing.T) {
+	a := assert.New(t)
+	srv := defaultServer()
+	srv.hooks.OnReAuth = func(ctx context.Context, client Client, auth *packets.Auth) (*AuthResponse, error) {
+		return &AuthResponse{AuthData: []byte("resp")}, nil
+	}
+	newClient := func() *client {
+		c, er := srv.newClient(noopConn{})
+		a.Nil(er)
+		c.version = packets.Version5
+		c.opts.AuthMethod = []byte("method")
+		return c
+	}
+
+	c := newClient()
+	c.in <- &packets.Auth{
+		Code: codes.ReAuthenticate,
+		Properties: &packets.Properties{
+			AuthMethod: []byte("method"),
+			AuthData:   []byte("data"),
+		},
+	}
+	close(c.in)
+	c.readHandle()
+	a.Nil(c.err)
+	select {
+	case p := <-c.out:
+		auth := p.(*packets.Auth)
+		a.Equal(codes.Success, auth.Code)
+		a.Equal([]byte("method"), auth.Properties.AuthMethod)
+		a.Equal([]byte("resp"), auth.Properties.AuthData)
+	default:
+		t.Fatal("missing output")
+	}
+
+	// the auth method must be the same as the one in the CONNECT packet.
+	c = newClient()
+	c.in <- &packets.Auth{
+		Code: codes.ReAuthenticate,
+		Properties: &packets.Properties{
+			AuthMethod: []byte("other"),
+			AuthData:   []byte("method"),
+		},
+	}
+	close(c.in)
+	c.readHandle()
+	a.Equal(codes.ErrProtocol, c.err)
+}
@@ -0,0 +1,294 @@
+package server
+
+import (
+	"container/heap"
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/persistence/delayed"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+)
+
+// delayedTopicPrefix is the topic prefix of the delayed messages, the format is $delayed/{seconds}/{topic}.
+const delayedTopicPrefix = "$delayed/"
+
+var _ DelayedService = (*delayedQueue)(nil)
+
+type delayedItem struct {
+	msg *delayed.Message
+	// index is the index of the item in the heap, it is maintained by the heap.Interface methods.
+	index int
+}
+
+func delayedLess(a, b *delayed.Message) bool {
+	if a.DeliverAt.Equal(b.DeliverAt) {
+		return a.ID < b.ID
+	}
+	return a.DeliverAt.Before(b.DeliverAt)
+}
+
+// delayedHeap is a min-heap of the delayed messages ordered by the delivery time.
+type delayedHeap []*delayedItem
+
+func (h delayedHeap) Len() int {
+	return len(h)
+}
+
+func (h delayedHeap) Less(i, j int) bool {
+	return delayedLess(h[i].msg, h[j].msg)
+}
+
+func (h delayedHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *delayedHeap) Push(x interface{}) {
+	item := x.(*delayedItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *delayedHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
+
+// delayedQueue keeps the pending delayed messages in memory and writes them through to the delayed.Store.
+// The messages are delivered by the run loop when they are due.
+type delayedQueue struct {
+	srv   *server
+	store delayed.Store
+	mu    sync.Mutex
+	// items key by message id
+	items map[string]*delayedItem
+	heap  delayedHeap
+	// wakeup notifies the run loop that the earliest delivery time is changed.
+	wakeup chan struct{}
+}
+
+// newDelayedQueue returns the delayedQueue which is restored from the store.
+func newDelayedQueue(srv *server, store delayed.Store) (*delayedQueue, error) {
+	d := &delayedQueue{
+		srv:    srv,
+		store:  store,
+		items:  make(map[string]*delayedItem),
+		wakeup: make(chan struct{}, 1),
+	}
+	err := store.Iterate(func(msg *delayed.Message) bool {
+		d.pushLocked(msg)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// newDelayedID returns a random id for the delayed message.
+func newDelayedID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func invalidDelayedTopic(reason string) *codes.Error {
+	return &codes.Error{
+		Code: codes.TopicNameInvalid,
+		ErrorDetails: codes.ErrorDetails{
+			ReasonString: []byte(reason),
+		},
+	}
+}
+
+// enabled returns whether the delayed publish is enabled.
+func (d *delayedQueue) enabled() bool {
+	return d != nil && d.srv.GetConfig().MQTT.DelayedPublish.Enable
+}
+
+// parseTopic parses the topic in $delayed/{seconds}/{topic} format, returns the real topic and the delay interval.
+func (d *delayedQueue) parseTopic(topic string) (string, time.Duration, error) {
+	s := strings.SplitN(strings.TrimPrefix(topic, delayedTopicPrefix), "/", 2)
+	if len(s) != 2 || s[1] == "" {
+		return topic, 0, invalidDelayedTopic("invalid delayed topic")
+	}
+	seconds, err := strconv.ParseUint(s[0], 10, 32)
+	if err != nil {
+		return topic, 0, invalidDelayedTopic("invalid delay interval")
+	}
+	delay := time.Duration(seconds) * time.Second
+	if max := d.srv.GetConfig().MQTT.DelayedPublish.MaxDelay; max != 0 && delay > max {
+		return topic, 0, invalidDelayedTopic("delay interval exceeds the maximum")
+	}
+	return s[1], delay, nil
+}
+
+func (d *delayedQueue) pushLocked(msg *delayed.Message) {
+	item := &delayedItem{msg: msg}
+	heap.Push(&d.heap, item)
+	d.items[msg.ID] = item
+}
+
+// add adds the message which is published by the client into the queue, the message will be delivered after the delay.
+func (d *delayedQueue) add(clientID string, msg *gmqtt.Message, delay time.Duration) error {
+	now := time.Now()
+	dm := &delayed.Message{
+		ID:          newDelayedID(),
+		ClientID:    clientID,
+		Message:     msg,
+		PublishedAt: now,
+		DeliverAt:   now.Add(delay),
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if max := d.srv.GetConfig().MQTT.DelayedPublish.MaxMessages; max != 0 && len(d.items) >= max {
+		return &codes.Error{
+			Code: codes.QuotaExceeded,
+		}
+	}
+	err := d.store.Add(dm)
+	if err != nil {
+		return err
+	}
+	d.pushLocked(dm)
+	if d.heap[0].msg == dm {
+		select {
+		case d.wakeup <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// deliverDue delivers the messages which are due at now,
+// returns the duration until the next delivery time and false if there is no pending message.
+func (d *delayedQueue) deliverDue(now time.Time) (next time.Duration, ok bool) {
+	var due []*delayed.Message
+	d.mu.Lock()
+	for len(d.heap) != 0 {
+		msg := d.heap[0].msg
+		if msg.DeliverAt.After(now) {
+			next, ok = msg.DeliverAt.Sub(now), true
+			break
+		}
+		heap.Pop(&d.heap)
+		delete(d.items, msg.ID)
+		due = append(due, msg)
+	}
+	d.mu.Unlock()
+	for _, v := range due {
+		d.deliver(v)
+	}
+	return
+}
+
+func (d *delayedQueue) deliver(dm *delayed.Message) {
+	srv := d.srv
+	msg := dm.Message
+	if msg.Retained {
+		if len(msg.Payload) == 0 {
+			srv.retainedDB.Remove(msg.Topic)
+		} else {
+			srv.retainedDB.AddOrReplace(msg.Copy())
+		}
+	}
+	srv.mu.Lock()
+	srv.dispatchMessageLocked(dm.ClientID, msg)
+	srv.mu.Unlock()
+	err := d.store.Remove(dm.ID)
+	if err != nil {
+		zaplog.Error("fail to remove delayed message",
+			zap.String("id", dm.ID),
+			zap.Error(err))
+	}
+}
+
+// run delivers the delayed messages when they are due until the server stops.
+func (d *delayedQueue) run() {
+	defer d.srv.wg.Done()
+	for {
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if next, ok := d.deliverDue(time.Now()); ok {
+			timer = time.NewTimer(next)
+			timeout = timer.C
+		}
+		select {
+		case <-d.srv.exitChan:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case <-d.wakeup:
+		case <-timeout:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// Iterate iterates the pending delayed messages in the order of the delivery time.
+func (d *delayedQueue) Iterate(fn delayed.IterateFn) {
+	d.mu.Lock()
+	msgs := make([]*delayed.Message, len(d.heap))
+	for k, v := range d.heap {
+		msgs[k] = v.msg
+	}
+	d.mu.Unlock()
+	sort.Slice(msgs, func(i, j int) bool {
+		return delayedLess(msgs[i], msgs[j])
+	})
+	for _, v := range msgs {
+		if !fn(v) {
+			return
+		}
+	}
+}
+
+// Get returns the pending delayed message for the given id.
+func (d *delayedQueue) Get(id string) *delayed.Message {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if item, ok := d.items[id]; ok {
+		return item.msg
+	}
+	return nil
+}
+
+// Cancel removes the pending delayed message for the given id.
+func (d *delayedQueue) Cancel(id string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	item, ok := d.items[id]
+	if !ok {
+		return false, nil
+	}
+	err := d.store.Remove(id)
+	if err != nil {
+		return false, err
+	}
+	heap.Remove(&d.heap, item.index)
+	delete(d.items, id)
+	return true, nil
+}
+
+// Len returns the number of pending delayed messages.
+func (d *delayedQueue) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.items)
+}
@@ -0,0 +1,252 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/persistence/delayed"
+	delayed_mem "github.com/DrmagicE/gmqtt/persistence/delayed/mem"
+	"github.com/DrmagicE/gmqtt/persistence/unack"
+	unack_mem "github.com/DrmagicE/gmqtt/persistence/unack/mem"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/retained"
+)
+
+func newTestDelayedQueue(t *testing.T, srv *server, store delayed.Store) *delayedQueue {
+	d, err := newDelayedQueue(srv, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.delayedQueue = d
+	return d
+}
+
+func TestDelayedQueue_parseTopic(t *testing.T) {
+	a := assert.New(t)
+	srv := &server{
+		config: config.DefaultConfig(),
+	}
+	d := newTestDelayedQueue(t, srv, delayed_mem.New())
+	for _, v := range []struct {
+		topic string
+		real  string
+		delay time.Duration
+		valid bool
+	}{
+		{topic: "$delayed/10/a/b", real: "a/b", delay: 10 * time.Second, valid: true},
+		{topic: "$delayed/0/a", real: "a", valid: true},
+		{topic: "$delayed/86400/a", real: "a", delay: 24 * time.Hour, valid: true},
+		{topic: "$delayed/86401/a"},
+		{topic: "$delayed/10"},
+		{topic: "$delayed/10/"},
+		{topic: "$delayed//a"},
+		{topic: "$delayed/-1/a"},
+		{topic: "$delayed/1s/a"},
+	} {
+		real, delay, err := d.parseTopic(v.topic)
+		if !v.valid {
+			a.Equal(codes.TopicNameInvalid, err.(*codes.Error).Code, v.topic)
+			continue
+		}
+		a.Nil(err, v.topic)
+		a.Equal(v.real, real)
+		a.Equal(v.delay, delay)
+	}
+	srv.config.MQTT.DelayedPublish.MaxDelay = 0
+	_, delay, err := d.parseTopic("$delayed/86401/a")
+	a.Nil(err)
+	a.Equal(86401*time.Second, delay)
+}
+
+func TestDelayedQueue(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	retainedDB := retained.NewMockStore(ctrl)
+	srv := &server{
+		config:     config.DefaultConfig(),
+		retainedDB: retainedDB,
+	}
+	srv.config.MQTT.DelayedPublish.MaxMessages = 3
+	var delivered []*gmqtt.Message
+	srv.deliverMessageHandler = func(srcClientID string, msg *gmqtt.Message) (matched bool) {
+		a.Equal("cid", srcClientID)
+		delivered = append(delivered, msg)
+		return true
+	}
+	now := time.Now()
+	store := delayed_mem.New()
+	// the message which is restored from the store.
+	restored := &delayed.Message{
+		ID:          "restored",
+		ClientID:    "cid",
+		Message:     &gmqtt.Message{Topic: "restored", Retained: true, Payload: []byte("a")},
+		PublishedAt: now.Add(-time.Second),
+		DeliverAt:   now.Add(-time.Millisecond),
+	}
+	a.Nil(store.Add(restored))
+	d := newTestDelayedQueue(t, srv, store)
+	a.Equal(1, d.Len())
+
+	a.Nil(d.add("cid", &gmqtt.Message{Topic: "b"}, 2*time.Second))
+	a.Nil(d.add("cid", &gmqtt.Message{Topic: "a"}, time.Second))
+	err := d.add("cid", &gmqtt.Message{Topic: "c"}, time.Second)
+	a.Equal(codes.QuotaExceeded, err.(*codes.Error).Code)
+
+	var topics []string
+	var ids []string
+	d.Iterate(func(msg *delayed.Message) bool {
+		topics = append(topics, msg.Message.Topic)
+		ids = append(ids, msg.ID)
+		return true
+	})
+	a.Equal([]string{"restored", "a", "b"}, topics)
+	a.Equal("a", d.Get(ids[1]).Message.Topic)
+	a.Nil(d.Get("not_exist"))
+
+	// deliver the restored message.
+	retainedDB.EXPECT().AddOrReplace(restored.Message)
+	next, ok := d.deliverDue(now)
+	a.True(ok)
+	a.True(next >= time.Second && next < 2*time.Second)
+	a.Equal([]*gmqtt.Message{restored.Message}, delivered)
+
+	ok, err = d.Cancel(ids[1])
+	a.True(ok)
+	a.Nil(err)
+	ok, err = d.Cancel(ids[1])
+	a.False(ok)
+	a.Nil(err)
+	a.Equal(1, d.Len())
+
+	delivered = nil
+	_, ok = d.deliverDue(now.Add(3 * time.Second))
+	a.False(ok)
+	a.Len(delivered, 1)
+	a.Equal("b", delivered[0].Topic)
+	a.Equal(0, d.Len())
+	// the delivered and cancelled messages are removed from the store.
+	a.Nil(store.Iterate(func(msg *delayed.Message) bool {
+		t.Fatalf("unexpected message in store: %s", msg.ID)
+		return true
+	}))
+}
+
+func TestDelayedQueue_run(t *testing.T) {
+	a := assert.New(t)
+	srv := &server{
+		config:   config.DefaultConfig(),
+		exitChan: make(chan struct{}),
+	}
+	delivered := make(chan *gmqtt.Message, 1)
+	srv.deliverMessageHandler = func(srcClientID string, msg *gmqtt.Message) (matched bool) {
+		delivered <- msg
+		return true
+	}
+	// the OnMsgDispatched hook is called when the message is delivered.
+	dispatched := make(chan string, 1)
+	srv.hooks.OnMsgDispatched = func(ctx context.Context, srcClientID string, msg *gmqtt.Message) {
+		dispatched <- srcClientID
+	}
+	d := newTestDelayedQueue(t, srv, delayed_mem.New())
+	srv.wg.Add(1)
+	go d.run()
+	a.Nil(d.add("cid", &gmqtt.Message{Topic: "a"}, time.Hour))
+	a.Nil(d.add("cid", &gmqtt.Message{Topic: "b"}, 0))
+	select {
+	case msg := <-delivered:
+		a.Equal("b", msg.Topic)
+		a.Equal("cid", <-dispatched)
+	case <-time.After(time.Second):
+		t.Fatal("delayed message not delivered")
+	}
+	close(srv.exitChan)
+	srv.wg.Wait()
+	a.Equal(1, d.Len())
+}
+
+func TestClient_publishHandler_delayed(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	// retained messages are not stored until they are delivered.
+	retainedDB := retained.NewMockStore(ctrl)
+	srv := &server{
+		config:     config.DefaultConfig(),
+		retainedDB: retainedDB,
+	}
+	srv.deliverMessageHandler = func(srcClientID string, msg *gmqtt.Message) (matched bool) {
+		t.Fatal("delayed message delivered immediately")
+		return false
+	}
+	srv.hooks.OnMsgDispatched = func(ctx context.Context, srcClientID string, msg *gmqtt.Message) {
+		t.Fatal("delayed message dispatched immediately")
+	}
+	d := newTestDelayedQueue(t, srv, delayed_mem.New())
+	c, err := srv.newClient(noopConn{})
+	a.Nil(err)
+	c.opts.ClientID = "cid"
+	c.opts.RetainAvailable = true
+	c.version = packets.Version5
+	c.unackStore = unack_mem.New(unack_mem.Options{
+		ClientID: "cid",
+	})
+	srv.unackStore = map[string]unack.Store{"cid": c.unackStore}
+
+	pub := &packets.Publish{
+		Version:   packets.Version5,
+		Qos:       packets.Qos1,
+		Retain:    true,
+		TopicName: []byte("$delayed/10/a/b"),
+		PacketID:  1,
+		Payload:   []byte("payload"),
+		Properties: &packets.Properties{
+			ContentType: []byte("text/plain"),
+			User: []packets.UserProperty{
+				{K: []byte("K"), V: []byte("V")},
+			},
+		},
+	}
+	now := time.Now()
+	a.Nil(c.publishHandler(pub))
+	a.Equal(pub.NewPuback(codes.Success, nil), <-c.out)
+
+	var msgs []*delayed.Message
+	d.Iterate(func(msg *delayed.Message) bool {
+		msgs = append(msgs, msg)
+		return true
+	})
+	a.Len(msgs, 1)
+	want := gmqtt.MessageFromPublish(pub)
+	want.Topic = "a/b"
+	a.Equal(want, msgs[0].Message)
+	a.Equal("cid", msgs[0].ClientID)
+	a.WithinDuration(now.Add(10*time.Second), msgs[0].DeliverAt, time.Second)
+
+	pub.TopicName = []byte("$delayed/abc/a/b")
+	a.Nil(c.publishHandler(pub))
+	a.Equal(codes.TopicNameInvalid, (<-c.out).(*packets.Puback).Code)
+	a.Equal(1, d.Len())
+
+	// the $delayed/ topics are treated as normal topics if the delayed publish is disabled.
+	srv.config.MQTT.DelayedPublish.Enable = false
+	srv.hooks.OnMsgDispatched = nil
+	var delivered *gmqtt.Message
+	srv.deliverMessageHandler = func(srcClientID string, msg *gmqtt.Message) (matched bool) {
+		delivered = msg
+		return true
+	}
+	pub.TopicName = []byte("$delayed/10/a/b")
+	retainedDB.EXPECT().AddOrReplace(gmqtt.MessageFromPublish(pub))
+	a.Nil(c.publishHandler(pub))
+	a.Equal(pub.NewPuback(codes.Success, nil), <-c.out)
+	a.Equal("$delayed/10/a/b", delivered.Topic)
+	a.Equal(1, d.Len())
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+var (
+	// ErrDraining is returned by Drain if the server is already draining.
+	ErrDraining = errors.New("server is draining")
+	// ErrNotRunning is returned by Drain if the server is not started or has been stopped.
+	ErrNotRunning = errors.New("server is not running")
+)
+
+// drainCheckInterval is the interval of checking the in-flight messages of the draining client.
+const drainCheckInterval = 100 * time.Millisecond
+
+func (srv *server) isDraining() bool {
+	return atomic.LoadInt32(&srv.draining) == 1
+}
+
+// Done returns a channel that is closed after the server is stopped.
+func (srv *server) Done() <-chan struct{} {
+	return srv.stopped
+}
+
+// Drain gracefully stops the server without making all clients reconnect at once:
+//  1. Closing all listeners, the listeners are not reloaded anymore.
+//  2. Disconnecting the connected clients one by one over the config.Drain.Window.
+//  3. Calling Stop after all clients are disconnected.
+//
+// Drain returns immediately, use Done to wait for the server to stop.
+func (srv *server) Drain() error {
+	if srv.Status() != serverStatusStarted {
+		return ErrNotRunning
+	}
+	select {
+	case <-srv.exitChan:
+		return ErrNotRunning
+	default:
+	}
+	if !atomic.CompareAndSwapInt32(&srv.draining, 0, 1) {
+		return ErrDraining
+	}
+	cfg := srv.GetConfig().Drain
+	zaplog.Info("draining gmqtt server", zap.Duration("window", cfg.Window))
+	srv.closeListeners(context.Background())
+	go srv.drain(cfg)
+	return nil
+}
+
+func (srv *server) drain(cfg config.Drain) {
+	srv.mu.Lock()
+	clients := make([]*client, 0, len(srv.clients))
+	for _, c := range srv.clients {
+		clients = append(clients, c)
+	}
+	srv.mu.Unlock()
+
+	var interval time.Duration
+	if len(clients) != 0 {
+		interval = cfg.Window / time.Duration(len(clients))
+	}
+	wg := &sync.WaitGroup{}
+	for k, c := range clients {
+		if k != 0 && interval != 0 {
+			select {
+			case <-srv.exitChan:
+				// stopped by Stop, the remaining clients are closed by Stop.
+				wg.Wait()
+				return
+			case <-time.After(interval):
+			}
+		}
+		wg.Add(1)
+		go func(c *client) {
+			defer wg.Done()
+			c.drain(cfg)
+		}(c)
+	}
+	wg.Wait()
+	zaplog.Info("all clients are drained")
+	// Stop waits for the disconnected clients to be unregistered, and then closes the persistence.
+	_ = srv.Stop(context.Background())
+}
+
+// inflightLen returns the number of the outgoing QoS 1/2 messages which are not acknowledged.
+func (client *client) inflightLen() int {
+	if client.pl == nil {
+		return 0
+	}
+	client.pl.lock()
+	defer client.pl.unlock()
+	return int(client.pl.used)
+}
+
+func (client *client) isDraining() bool {
+	return atomic.LoadInt32(&client.draining) == 1
+}
+
+// drain stops delivering new messages to the client, waits for the in-flight messages to be acknowledged
+// for at most cfg.InflightTimeout, and then disconnects the client.
+// The undelivered messages are kept in the session queue.
+func (client *client) drain(cfg config.Drain) {
+	atomic.StoreInt32(&client.draining, 1)
+	timeout := time.NewTimer(cfg.InflightTimeout)
+	defer timeout.Stop()
+wait:
+	for client.inflightLen() != 0 {
+		select {
+		case <-client.close:
+			return
+		case <-timeout.C:
+			zaplog.Warn("in-flight messages are not acknowledged before drain",
+				zap.String("client_id", client.opts.ClientID),
+				zap.Int("inflight", client.inflightLen()))
+			break wait
+		case <-time.After(drainCheckInterval):
+		}
+	}
+	if client.version != packets.Version5 {
+		client.Close()
+		return
+	}
+	code := codes.UseAnotherServer
+	if cfg.Code == config.DrainCodeServerMoved {
+		code = codes.ServerMoved
+	}
+	disconnect := &packets.Disconnect{
+		Version:    packets.Version5,
+		Code:       code,
+		Properties: &packets.Properties{},
+	}
+	if cfg.ServerReference != "" {
+		disconnect.Properties.ServerReference = []byte(cfg.ServerReference)
+	}
+	client.Disconnect(disconnect)
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+func TestClient_drain(t *testing.T) {
+	a := assert.New(t)
+	srv := &server{
+		config: config.DefaultConfig(),
+	}
+	c, err := srv.newClient(noopConn{})
+	a.Nil(err)
+	c.version = packets.Version5
+	c.opts.MaxInflight = 10
+	c.newPacketIDLimiter(c.opts.MaxInflight)
+	ids := c.pl.pollPacketIDs(2)
+	a.Equal(2, c.inflightLen())
+
+	cfg := config.Drain{
+		InflightTimeout: time.Second,
+		Code:            config.DrainCodeServerMoved,
+		ServerReference: "mqtt2.example.com:1883",
+	}
+	done := make(chan struct{})
+	go func() {
+		c.drain(cfg)
+		close(done)
+	}()
+	// the client is disconnected after the in-flight messages are acknowledged.
+	time.Sleep(2 * drainCheckInterval)
+	a.True(c.isDraining())
+	a.Len(c.out, 0)
+	c.pl.batchRelease(ids)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("client not drained")
+	}
+	a.Equal(&packets.Disconnect{
+		Version: packets.Version5,
+		Code:    codes.ServerMoved,
+		Properties: &packets.Properties{
+			ServerReference: []byte("mqtt2.example.com:1883"),
+		},
+	}, <-c.out)
+
+	// the client is disconnected after the inflight timeout.
+	c.pl.pollPacketIDs(1)
+	cfg.InflightTimeout = 50 * time.Millisecond
+	cfg.Code = config.DrainCodeUseAnotherServer
+	cfg.ServerReference = ""
+	c.drain(cfg)
+	a.Equal(&packets.Disconnect{
+		Version:    packets.Version5,
+		Code:       codes.UseAnotherServer,
+		Properties: &packets.Properties{},
+	}, <-c.out)
+}
+
+func TestServer_Drain(t *testing.T) {
+	a := assert.New(t)
+	srv := New()
+	srv.config.Drain.Window = 400 * time.Millisecond
+	a.Equal(ErrNotRunning, srv.Drain())
+
+	disconnectedAt := make(chan time.Time, 2)
+	for _, cid := range []string{"a", "b"} {
+		c, err := srv.newClient(noopConn{})
+		a.Nil(err)
+		c.version = packets.Version5
+		c.opts.ClientID = cid
+		srv.clients[cid] = c
+		go func() {
+			a.IsType(&packets.Disconnect{}, <-c.out)
+			disconnectedAt <- time.Now()
+		}()
+	}
+	srv.status = serverStatusStarted
+	start := time.Now()
+	a.Nil(srv.Drain())
+	a.Equal(ErrDraining, srv.Drain())
+	select {
+	case <-srv.Done():
+	case <-time.After(time.Second):
+		t.Fatal("server not stopped")
+	}
+	// the clients are disconnected one by one over the window.
+	t1, t2 := <-disconnectedAt, <-disconnectedAt
+	a.True(t1.Sub(start) < 100*time.Millisecond)
+	a.True(t2.Sub(start) >= 200*time.Millisecond)
+	a.Equal(ErrNotRunning, srv.Drain())
+}
@@ -5,17 +5,20 @@ import (
 	"net"
 
 	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/config"
 	"github.com/DrmagicE/gmqtt/pkg/packets"
 )
 
 type Hooks struct {
 	OnAccept
+	OnAcceptRejected
 	OnStop
 	OnSubscribe
 	OnSubscribed
 	OnUnsubscribe
 	OnUnsubscribed
 	OnMsgArrived
+	OnMsgDispatched
 	OnBasicAuth
 	OnEnhancedAuth
 	OnReAuth
@@ -34,6 +37,14 @@ type OnAccept func(ctx context.Context, conn net.Conn) bool
 
 type OnAcceptWrapper func(OnAccept) OnAccept
 
+// OnAcceptRejected will be called after the connection is closed without being served,
+// because the OnAccept hook returns false or the server fails to create the client.
+// It is the counterpart of OnClosed for the connections that never become clients,
+// the plugins that count the connections in OnAccept should release them here.
+type OnAcceptRejected func(ctx context.Context, conn net.Conn)
+
+type OnAcceptRejectedWrapper func(OnAcceptRejected) OnAcceptRejected
+
 // OnStop will be called on server.Stop()
 type OnStop func(ctx context.Context)
 
@@ -152,6 +163,14 @@ func (m *MsgArrivedRequest) Drop() {
 
 type OnMsgArrivedWrapper func(OnMsgArrived) OnMsgArrived
 
+// OnMsgDispatched will be called when a message is dispatched to the local subscribers,
+// including the messages published by clients, will messages, delayed messages when they are due
+// and the messages published by Publisher.Publish.
+// It is called under the server lock, so it must not block. The msg must not be modified.
+type OnMsgDispatched func(ctx context.Context, srcClientID string, msg *gmqtt.Message)
+
+type OnMsgDispatchedWrapper func(OnMsgDispatched) OnMsgDispatched
+
 // OnClosed will be called after the tcp connection of the client has been closed
 type OnClosed func(ctx context.Context, client Client, err error)
 
@@ -172,6 +191,8 @@ type AuthOptions struct {
 	AssignedClientID     []byte
 	ResponseInfo         []byte
 	MaxInflight          uint16
+	// RateLimit is the publish rate limit of the client, default to config.MQTT.RateLimit.Client.
+	RateLimit config.RateLimitRule
 }
 
 // OnBasicAuth will be called when receive v311 connect packet or v5 connect packet with empty auth method property.
@@ -183,12 +204,15 @@ type ConnectRequest struct {
 	Connect *packets.Connect
 	// Options represents the setting which will be applied to the current client if auth success.
 	// Caller can edit this property to change the setting.
+	// Note that the AssignedClientID can only be changed in OnBasicAuth and OnEnhancedAuth.
 	Options *AuthOptions
 }
 
 type OnBasicAuthWrapper func(OnBasicAuth) OnBasicAuth
 
 // OnEnhancedAuth will be called when receive v5 connect packet with auth method property.
+// The wrappers should pass the request to the next one if they do not support the auth method,
+// the connection will be rejected with BadAuthMethod if no plugin supports it.
 type OnEnhancedAuth func(ctx context.Context, client Client, req *ConnectRequest) (resp *EnhancedAuthResponse, err error)
 
 type EnhancedAuthResponse struct {
@@ -216,6 +240,9 @@ type OnAuth func(ctx context.Context, client Client, req *AuthRequest) (*AuthRes
 
 type OnReAuthWrapper func(OnReAuth) OnReAuth
 
+// OnReAuth will be called when receive an AUTH packet after the client connected.
+// The wrappers should pass the request to the next one if they do not support the auth method,
+// the client will be disconnected with BadAuthMethod if no plugin supports it.
 type OnReAuth func(ctx context.Context, client Client, auth *packets.Auth) (*AuthResponse, error)
 
 type OnAuthWrapper func(OnAuth) OnAuth
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt/config"
+)
+
+// listener is a tcp listener, QUIC listener or websocket server created from the listener config.
+type listener struct {
+	config *config.ListenerConfig
+	tcp    net.Listener
+	ws     *WsServer
+}
+
+// NewQUICListener creates the QUIC listener for the listener config.
+type NewQUICListener func(c *config.ListenerConfig) (net.Listener, error)
+
+var quicListenerFactory NewQUICListener
+
+// RegisterQUICListenerFactory registers the factory of the QUIC listeners.
+// It is called by importing github.com/DrmagicE/gmqtt/quic, which is a separate module.
+func RegisterQUICListenerFactory(new NewQUICListener) {
+	if quicListenerFactory != nil {
+		panic("duplicated quic listener factory")
+	}
+	quicListenerFactory = new
+}
+
+// NewListener creates the tcp listener, QUIC listener or the websocket server for the given listener config.
+// Only one of the returned ln and ws is not nil.
+func NewListener(c *config.ListenerConfig) (ln net.Listener, ws *WsServer, err error) {
+	if c.QUIC != nil {
+		if quicListenerFactory == nil {
+			return nil, nil, errors.New("quic listener is not supported, import github.com/DrmagicE/gmqtt/quic to enable it")
+		}
+		ln, err = quicListenerFactory(c)
+		if err != nil {
+			return nil, nil, err
+		}
+		return ln, nil, nil
+	}
+	var tc *tlsConfig
+	if c.TLSOptions != nil {
+		tc, err = newTLSConfig(c.TLSOptions)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if _, ok := config.UnixSocketPath(c.Address); ok {
+		ln, err = NewUnixListener(c.Address, c.UnixSocket)
+	} else {
+		ln, err = net.Listen("tcp", c.Address)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.Websocket != nil {
+		ws = &WsServer{
+			Server:         &http.Server{Addr: c.Address},
+			Path:           c.Websocket.Path,
+			ln:             ln,
+			tlsConfig:      tc,
+			upgrader:       newUpgrader(c.Websocket),
+			maxMessageSize: c.Websocket.MaxMessageSize,
+		}
+		if tc != nil {
+			ws.Server.TLSConfig = tc.serverConfig()
+		}
+		if c.ProxyProtocol != nil {
+			ws.proxyProtocol = true
+			ws.trustedProxies, err = c.ProxyProtocol.TrustedNets()
+			if err != nil {
+				ln.Close()
+				return nil, nil, err
+			}
+		}
+		return nil, ws, nil
+	}
+	if c.ProxyProtocol != nil {
+		// the PROXY header is sent before the TLS handshake, so ProxyListener performs the TLS handshake.
+		pl, err := NewProxyListener(ln, c.ProxyProtocol, c.TLSOptions)
+		if err != nil {
+			ln.Close()
+			return nil, nil, err
+		}
+		return pl, nil, nil
+	}
+	if tc != nil {
+		return &TLSListener{
+			Listener: tls.NewListener(ln, tc.serverConfig()),
+			config:   tc,
+		}, nil, nil
+	}
+	return ln, nil, nil
+}
+
+// tlsReloader is implemented by TLSListener, ProxyListener and QUICListener.
+type tlsReloader interface {
+	Reload(opts *config.TLSOptions) error
+}
+
+// sameListenerKind returns whether the listener can be reused for the new listener config.
+func sameListenerKind(old, new *config.ListenerConfig) bool {
+	if (old.TLSOptions == nil) != (new.TLSOptions == nil) {
+		return false
+	}
+	if !reflect.DeepEqual(old.ProxyProtocol, new.ProxyProtocol) || !reflect.DeepEqual(old.QUIC, new.QUIC) ||
+		!reflect.DeepEqual(old.UnixSocket, new.UnixSocket) {
+		return false
+	}
+	if old.Websocket == nil || new.Websocket == nil {
+		return old.Websocket == new.Websocket
+	}
+	return reflect.DeepEqual(old.Websocket, new.Websocket)
+}
+
+// listenerKey returns the key of srv.listeners for the listener config.
+func listenerKey(c *config.ListenerConfig) string {
+	return c.Network() + " " + c.Address
+}
+
+// listenLocked creates and serves the listener for the given config, must call under srv.listenerMu.Lock
+func (srv *server) listenLocked(c *config.ListenerConfig) error {
+	ln, ws, err := NewListener(c)
+	if err != nil {
+		return err
+	}
+	l := &listener{config: c, tcp: ln, ws: ws}
+	srv.listeners[listenerKey(c)] = l
+	if ln != nil {
+		srv.tcpListener = append(srv.tcpListener, ln)
+	} else {
+		srv.websocketServer = append(srv.websocketServer, ws)
+	}
+	if srv.Status() == serverStatusStarted {
+		srv.serveListener(l.tcp, l.ws)
+	}
+	return nil
+}
+
+// closeListenerLocked stops accepting new connections on the listener, the established connections are not affected.
+// Must call under srv.listenerMu.Lock
+func (srv *server) closeListenerLocked(l *listener) {
+	delete(srv.listeners, listenerKey(l.config))
+	if l.tcp != nil {
+		l.tcp.Close()
+		for k, v := range srv.tcpListener {
+			if v == l.tcp {
+				srv.tcpListener = append(srv.tcpListener[:k], srv.tcpListener[k+1:]...)
+				break
+			}
+		}
+		return
+	}
+	l.ws.Server.Close()
+	// the server may not have started serving the listener.
+	l.ws.ln.Close()
+	for k, v := range srv.websocketServer {
+		if v == l.ws {
+			srv.websocketServer = append(srv.websocketServer[:k], srv.websocketServer[k+1:]...)
+			break
+		}
+	}
+}
+
+// reloadListeners adds, removes or restarts the listeners according to the new listener configs,
+// and re-reads the certificates of the TLS listeners.
+func (srv *server) reloadListeners(cfgs []*config.ListenerConfig) error {
+	srv.listenerMu.Lock()
+	defer srv.listenerMu.Unlock()
+	var errs []string
+	newCfgs := make(map[string]*config.ListenerConfig)
+	for _, v := range cfgs {
+		newCfgs[listenerKey(v)] = v
+	}
+	for key, l := range srv.listeners {
+		addr := l.config.Address
+		c, ok := newCfgs[key]
+		if !ok || !sameListenerKind(l.config, c) {
+			zaplog.Info("closing listener", zap.String("address", addr))
+			srv.closeListenerLocked(l)
+			continue
+		}
+		delete(newCfgs, key)
+		if c.TLSOptions != nil {
+			var err error
+			if l.tcp != nil {
+				err = l.tcp.(tlsReloader).Reload(c.TLSOptions)
+			} else {
+				err = l.ws.tlsConfig.reload(c.TLSOptions)
+			}
+			// keep the old tls config if fail to reload.
+			if err != nil {
+				errs = append(errs, "fail to reload tls config of "+addr+": "+err.Error())
+				continue
+			}
+		}
+		l.config = c
+	}
+	for _, c := range cfgs {
+		if _, ok := newCfgs[listenerKey(c)]; !ok {
+			continue
+		}
+		zaplog.Info("adding listener", zap.String("address", c.Address))
+		if err := srv.listenLocked(c); err != nil {
+			errs = append(errs, "fail to listen on "+c.Address+": "+err.Error())
+		}
+	}
+	if errs != nil {
+		return errors.New(strings.Join(errs, ";"))
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt/config"
+)
+
+func TestNewListener_quic(t *testing.T) {
+	a := assert.New(t)
+	c := &config.ListenerConfig{
+		Address:    "127.0.0.1:0",
+		TLSOptions: &config.TLSOptions{},
+		QUIC:       &config.QUICOptions{},
+	}
+	// the quic module is not imported.
+	ln, ws, err := NewListener(c)
+	a.Error(err)
+	a.Nil(ln)
+	a.Nil(ws)
+
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	a.Nil(err)
+	defer tcp.Close()
+	RegisterQUICListenerFactory(func(lc *config.ListenerConfig) (net.Listener, error) {
+		a.Equal(c, lc)
+		return tcp, nil
+	})
+	defer func() {
+		quicListenerFactory = nil
+	}()
+	a.Panics(func() {
+		RegisterQUICListenerFactory(nil)
+	})
+	ln, ws, err = NewListener(c)
+	a.Nil(err)
+	a.Equal(tcp, ln)
+	a.Nil(ws)
+}
+
+func TestServer_reloadListeners_sameAddress(t *testing.T) {
+	a := assert.New(t)
+	addr := freeAddress(t)
+	quicLn, err := net.Listen("tcp", "127.0.0.1:0")
+	a.Nil(err)
+	RegisterQUICListenerFactory(func(lc *config.ListenerConfig) (net.Listener, error) {
+		return quicLn, nil
+	})
+	defer func() {
+		quicListenerFactory = nil
+	}()
+	tcp := &config.ListenerConfig{Address: addr}
+	quic := &config.ListenerConfig{Address: addr, TLSOptions: &config.TLSOptions{}, QUIC: &config.QUICOptions{}}
+
+	srv := New(WithConfig(config.DefaultConfig()), WithConfigListeners())
+	defer srv.Stop(context.Background())
+	srv.listenerMu.Lock()
+	a.Nil(srv.listenLocked(tcp))
+	a.Nil(srv.listenLocked(quic))
+	a.Len(srv.listeners, 2)
+	srv.listenerMu.Unlock()
+
+	// remove the QUIC listener, the tcp listener on the same address is kept.
+	a.Nil(srv.reloadListeners([]*config.ListenerConfig{tcp}))
+	srv.listenerMu.Lock()
+	a.Len(srv.listeners, 1)
+	a.Equal(tcp, srv.listeners["tcp "+addr].config)
+	a.Equal([]net.Listener{srv.listeners["tcp "+addr].tcp}, srv.tcpListener)
+	srv.listenerMu.Unlock()
+	a.False(canListen(addr))
+	_, err = quicLn.Accept()
+	a.Error(err)
+}
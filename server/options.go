@@ -18,12 +18,21 @@ func WithConfig(config config.Config) Options {
 }
 
 // WithTCPListener set  tcp listener(s) of the server. Default listen on  :1883.
+// Use NewUnixListener to create the unix domain socket listener, e.g. unix:///run/gmqtt.sock.
 func WithTCPListener(lns ...net.Listener) Options {
 	return func(srv *server) {
 		srv.tcpListener = append(srv.tcpListener, lns...)
 	}
 }
 
+// WithConfigListeners makes the server create the tcp listeners and websocket servers from config.Listeners.
+// The listeners will be added, removed or reloaded when the config changes, see Server.ApplyConfig.
+func WithConfigListeners() Options {
+	return func(srv *server) {
+		srv.configListeners = true
+	}
+}
+
 // WithWebsocketServer set  websocket server(s) of the server.
 func WithWebsocketServer(ws ...*WsServer) Options {
 	return func(srv *server) {
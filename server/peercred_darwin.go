@@ -0,0 +1,37 @@
+// +build darwin
+
+package server
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+func peerCredentials(c *net.UnixConn) (*PeerCredentials, error) {
+	raw, err := c.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+	var cred *unix.Xucred
+	var pid int
+	var serr error
+	err = raw.Control(func(fd uintptr) {
+		cred, serr = unix.GetsockoptXucred(int(fd), unix.SOL_LOCAL, unix.LOCAL_PEERCRED)
+		if serr != nil {
+			return
+		}
+		pid, serr = unix.GetsockoptInt(int(fd), unix.SOL_LOCAL, unix.LOCAL_PEERPID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if serr != nil {
+		return nil, serr
+	}
+	cr := &PeerCredentials{PID: int32(pid), UID: cred.Uid}
+	if cred.Ngroups > 0 {
+		cr.GID = cred.Groups[0]
+	}
+	return cr, nil
+}
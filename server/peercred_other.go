@@ -0,0 +1,11 @@
+// +build !linux,!darwin
+
+package server
+
+import (
+	"net"
+)
+
+func peerCredentials(c *net.UnixConn) (*PeerCredentials, error) {
+	return nil, ErrPeerCredentialsNotSupported
+}
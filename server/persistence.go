@@ -2,10 +2,14 @@ package server
 
 import (
 	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/persistence/delayed"
+	delayed_mem "github.com/DrmagicE/gmqtt/persistence/delayed/mem"
 	"github.com/DrmagicE/gmqtt/persistence/queue"
 	"github.com/DrmagicE/gmqtt/persistence/session"
 	"github.com/DrmagicE/gmqtt/persistence/subscription"
 	"github.com/DrmagicE/gmqtt/persistence/unack"
+	"github.com/DrmagicE/gmqtt/retained"
+	retained_trie "github.com/DrmagicE/gmqtt/retained/trie"
 )
 
 type NewPersistence func(config config.Config, hooks Hooks) (Persistence, error)
@@ -18,3 +22,31 @@ type Persistence interface {
 	NewUnackStore(config config.Config, clientID string) (unack.Store, error)
 	Close() error
 }
+
+// RetainedPersistence is an optional interface of the Persistence which stores the retained messages.
+// The retained messages are stored in memory if the Persistence does not implement it.
+type RetainedPersistence interface {
+	NewRetainedStore(config config.Config) (retained.Store, error)
+}
+
+// newRetainedStore returns the retained store of the persistence, or a memory store if it is not supported.
+func newRetainedStore(pe Persistence, config config.Config) (retained.Store, error) {
+	if rp, ok := pe.(RetainedPersistence); ok {
+		return rp.NewRetainedStore(config)
+	}
+	return retained_trie.NewStore(), nil
+}
+
+// DelayedPersistence is an optional interface of the Persistence which stores the pending delayed messages.
+// The delayed messages are stored in memory if the Persistence does not implement it.
+type DelayedPersistence interface {
+	NewDelayedStore(config config.Config) (delayed.Store, error)
+}
+
+// newDelayedStore returns the delayed store of the persistence, or a memory store if it is not supported.
+func newDelayedStore(pe Persistence, config config.Config) (delayed.Store, error) {
+	if dp, ok := pe.(DelayedPersistence); ok {
+		return dp.NewDelayedStore(config)
+	}
+	return delayed_mem.New(), nil
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/persistence/delayed"
+	delayed_mem "github.com/DrmagicE/gmqtt/persistence/delayed/mem"
+	"github.com/DrmagicE/gmqtt/retained"
+	retained_trie "github.com/DrmagicE/gmqtt/retained/trie"
+)
+
+// testDelayedPersistence is the Persistence which supports the delayed store.
+type testDelayedPersistence struct {
+	*MockPersistence
+	store delayed.Store
+}
+
+func (t *testDelayedPersistence) NewDelayedStore(config config.Config) (delayed.Store, error) {
+	return t.store, nil
+}
+
+func TestNewDelayedStore(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// fallback to the memory store
+	st, err := newDelayedStore(NewMockPersistence(ctrl), config.DefaultConfig())
+	a.Nil(err)
+	a.IsType(&delayed_mem.Store{}, st)
+
+	store := delayed.NewMockStore(ctrl)
+	st, err = newDelayedStore(&testDelayedPersistence{MockPersistence: NewMockPersistence(ctrl), store: store}, config.DefaultConfig())
+	a.Nil(err)
+	a.Equal(store, st)
+}
+
+// testRetainedPersistence is the Persistence which supports the retained store.
+type testRetainedPersistence struct {
+	*MockPersistence
+	store retained.Store
+}
+
+func (t *testRetainedPersistence) NewRetainedStore(config config.Config) (retained.Store, error) {
+	return t.store, nil
+}
+
+func TestNewRetainedStore(t *testing.T) {
+	a := assert.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// fallback to the memory store
+	st, err := newRetainedStore(NewMockPersistence(ctrl), config.DefaultConfig())
+	a.Nil(err)
+	a.IsType(retained_trie.NewStore(), st)
+
+	store := retained.NewMockStore(ctrl)
+	st, err = newRetainedStore(&testRetainedPersistence{MockPersistence: NewMockPersistence(ctrl), store: store}, config.DefaultConfig())
+	a.Nil(err)
+	a.Equal(store, st)
+}
@@ -18,10 +18,12 @@ type HookWrapper struct {
 	OnUnsubscribeWrapper       OnUnsubscribeWrapper
 	OnUnsubscribedWrapper      OnUnsubscribedWrapper
 	OnMsgArrivedWrapper        OnMsgArrivedWrapper
+	OnMsgDispatchedWrapper     OnMsgDispatchedWrapper
 	OnMsgDroppedWrapper        OnMsgDroppedWrapper
 	OnDeliveredWrapper         OnDeliveredWrapper
 	OnClosedWrapper            OnClosedWrapper
 	OnAcceptWrapper            OnAcceptWrapper
+	OnAcceptRejectedWrapper    OnAcceptRejectedWrapper
 	OnStopWrapper              OnStopWrapper
 }
 
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+// testAuthPlugin supports the "known" auth method and passes the others to the next wrapper.
+type testAuthPlugin struct {
+	testPlugin
+}
+
+func (t *testAuthPlugin) HookWrapper() HookWrapper {
+	return HookWrapper{
+		OnEnhancedAuthWrapper: func(pre OnEnhancedAuth) OnEnhancedAuth {
+			return func(ctx context.Context, client Client, req *ConnectRequest) (*EnhancedAuthResponse, error) {
+				if !bytes.Equal(req.Connect.Properties.AuthMethod, []byte("known")) {
+					return pre(ctx, client, req)
+				}
+				return &EnhancedAuthResponse{}, nil
+			}
+		},
+		OnReAuthWrapper: func(pre OnReAuth) OnReAuth {
+			return func(ctx context.Context, client Client, auth *packets.Auth) (*AuthResponse, error) {
+				if !bytes.Equal(auth.Properties.AuthMethod, []byte("known")) {
+					return pre(ctx, client, auth)
+				}
+				return &AuthResponse{}, nil
+			}
+		},
+	}
+}
+
+func TestServer_initPluginHooks_unsupportedAuthMethod(t *testing.T) {
+	a := assert.New(t)
+	srv := defaultServer()
+	srv.plugins = []Plugin{&testAuthPlugin{testPlugin{name: "auth"}}}
+	a.Nil(srv.initPluginHooks())
+
+	for _, v := range []struct {
+		method string
+		code   codes.Code
+	}{
+		{method: "known"},
+		{method: "unknown", code: codes.BadAuthMethod},
+	} {
+		var expected error
+		if v.code != codes.Success {
+			expected = &codes.Error{Code: v.code}
+		}
+		_, err := srv.hooks.OnEnhancedAuth(context.Background(), nil, &ConnectRequest{
+			Connect: &packets.Connect{
+				Properties: &packets.Properties{AuthMethod: []byte(v.method)},
+			},
+		})
+		a.Equal(expected, err, v.method)
+
+		_, err = srv.hooks.OnReAuth(context.Background(), nil, &packets.Auth{
+			Properties: &packets.Properties{AuthMethod: []byte(v.method)},
+		})
+		a.Equal(expected, err, v.method)
+	}
+}
+
+// testRejectPlugin rejects all connections in OnAccept and records the rejected connections.
+type testRejectPlugin struct {
+	testPlugin
+	rejected []net.Conn
+}
+
+func (t *testRejectPlugin) HookWrapper() HookWrapper {
+	return HookWrapper{
+		OnAcceptWrapper: func(pre OnAccept) OnAccept {
+			return func(ctx context.Context, conn net.Conn) bool {
+				return false
+			}
+		},
+		OnAcceptRejectedWrapper: func(pre OnAcceptRejected) OnAcceptRejected {
+			return func(ctx context.Context, conn net.Conn) {
+				pre(ctx, conn)
+				t.rejected = append(t.rejected, conn)
+			}
+		},
+	}
+}
+
+func TestServer_serveConn_rejected(t *testing.T) {
+	a := assert.New(t)
+	srv := defaultServer()
+	p := &testRejectPlugin{testPlugin: testPlugin{name: "reject"}}
+	srv.plugins = []Plugin{p}
+	a.Nil(srv.initPluginHooks())
+
+	conn := noopConn{}
+	a.Nil(srv.serveConn(conn, ""))
+	a.Equal([]net.Conn{conn}, p.rejected)
+}
@@ -0,0 +1,376 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DrmagicE/gmqtt/config"
+)
+
+// The PROXY protocol v2 TLV types, see https://www.haproxy.org/download/2.4/doc/proxy-protocol.txt
+const (
+	ProxyTLVTypeALPN      byte = 0x01
+	ProxyTLVTypeAuthority byte = 0x02
+	ProxyTLVTypeCRC32C    byte = 0x03
+	ProxyTLVTypeNoop      byte = 0x04
+	ProxyTLVTypeUniqueID  byte = 0x05
+	ProxyTLVTypeSSL       byte = 0x20
+	ProxyTLVTypeNetNS     byte = 0x30
+)
+
+var (
+	proxyV1Prefix    = []byte("PROXY ")
+	proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+const (
+	// proxyV1MaxLength is the maximum length of the v1 header including the CRLF.
+	proxyV1MaxLength = 107
+	// proxyV2HeaderLength is the length of the fixed part of the v2 header.
+	proxyV2HeaderLength = 16
+)
+
+var errInvalidProxyHeader = errors.New("invalid PROXY protocol header")
+
+// ProxyTLV is a type-length-value field of the PROXY protocol v2 header.
+type ProxyTLV struct {
+	Type  byte
+	Value []byte
+}
+
+// ProxyHeader is the PROXY protocol header sent by the proxy.
+type ProxyHeader struct {
+	// Version is the version of the PROXY protocol, 1 or 2.
+	Version byte
+	// SourceAddr is the address of the client.
+	// It is nil if the proxy does not provide the addresses, e.g. the LOCAL command of v2 or the UNKNOWN protocol of v1.
+	SourceAddr net.Addr
+	// DestinationAddr is the address the client connected to.
+	DestinationAddr net.Addr
+	// TLVs is the TLV fields of the v2 header.
+	TLVs []ProxyTLV
+}
+
+// TLV returns the value of the first TLV field of the given type.
+func (h *ProxyHeader) TLV(typ byte) ([]byte, bool) {
+	for _, v := range h.TLVs {
+		if v.Type == typ {
+			return v.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Authority returns the host name sent by the client, which is usually the TLS SNI if the proxy terminates the TLS.
+func (h *ProxyHeader) Authority() string {
+	v, _ := h.TLV(ProxyTLVTypeAuthority)
+	return string(v)
+}
+
+// readProxyHeader reads the PROXY protocol v1 or v2 header from r.
+func readProxyHeader(r *bufio.Reader) (*ProxyHeader, error) {
+	b, err := r.Peek(len(proxyV1Prefix))
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(b, proxyV1Prefix) {
+		return readProxyHeaderV1(r)
+	}
+	b, err = r.Peek(len(proxyV2Signature))
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(b, proxyV2Signature) {
+		return readProxyHeaderV2(r)
+	}
+	return nil, errInvalidProxyHeader
+}
+
+func readProxyHeaderV1(r *bufio.Reader) (*ProxyHeader, error) {
+	var line []byte
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, c)
+		if c == '\n' {
+			break
+		}
+		if len(line) == proxyV1MaxLength {
+			return nil, errInvalidProxyHeader
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, errInvalidProxyHeader
+	}
+	fields := strings.Split(string(line[len(proxyV1Prefix):len(line)-2]), " ")
+	h := &ProxyHeader{Version: 1}
+	switch fields[0] {
+	case "UNKNOWN":
+		return h, nil
+	case "TCP4", "TCP6":
+	default:
+		return nil, errInvalidProxyHeader
+	}
+	if len(fields) != 5 {
+		return nil, errInvalidProxyHeader
+	}
+	src, err := parseProxyV1Addr(fields[0], fields[1], fields[3])
+	if err != nil {
+		return nil, err
+	}
+	dst, err := parseProxyV1Addr(fields[0], fields[2], fields[4])
+	if err != nil {
+		return nil, err
+	}
+	h.SourceAddr, h.DestinationAddr = src, dst
+	return h, nil
+}
+
+func parseProxyV1Addr(proto, ip, port string) (*net.TCPAddr, error) {
+	addr := &net.TCPAddr{IP: net.ParseIP(ip)}
+	if addr.IP == nil || (proto == "TCP4") != (addr.IP.To4() != nil) {
+		return nil, errInvalidProxyHeader
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, errInvalidProxyHeader
+	}
+	addr.Port = int(p)
+	return addr, nil
+}
+
+func readProxyHeaderV2(r *bufio.Reader) (*ProxyHeader, error) {
+	fixed := make([]byte, proxyV2HeaderLength)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, err
+	}
+	if fixed[12]>>4 != 2 {
+		return nil, fmt.Errorf("unsupported PROXY protocol version: %d", fixed[12]>>4)
+	}
+	cmd := fixed[12] & 0x0f
+	if cmd > 1 {
+		return nil, errInvalidProxyHeader
+	}
+	payload := make([]byte, binary.BigEndian.Uint16(fixed[14:]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	h := &ProxyHeader{Version: 2}
+	// the address length of the address family.
+	var addrLen int
+	switch fixed[13] >> 4 {
+	case 1: // AF_INET
+		addrLen = 12
+	case 2: // AF_INET6
+		addrLen = 36
+	case 3: // AF_UNIX
+		addrLen = 216
+	}
+	if len(payload) < addrLen {
+		return nil, errInvalidProxyHeader
+	}
+	// the addresses are ignored for the LOCAL command, and only TCP over IPv4 and IPv6 is supported.
+	if cmd == 1 && fixed[13] == 0x11 {
+		h.SourceAddr = &net.TCPAddr{IP: net.IP(payload[0:4]), Port: int(binary.BigEndian.Uint16(payload[8:]))}
+		h.DestinationAddr = &net.TCPAddr{IP: net.IP(payload[4:8]), Port: int(binary.BigEndian.Uint16(payload[10:]))}
+	} else if cmd == 1 && fixed[13] == 0x21 {
+		h.SourceAddr = &net.TCPAddr{IP: net.IP(payload[0:16]), Port: int(binary.BigEndian.Uint16(payload[32:]))}
+		h.DestinationAddr = &net.TCPAddr{IP: net.IP(payload[16:32]), Port: int(binary.BigEndian.Uint16(payload[34:]))}
+	}
+	tlvs := payload[addrLen:]
+	for len(tlvs) > 0 {
+		if len(tlvs) < 3 {
+			return nil, errInvalidProxyHeader
+		}
+		l := int(binary.BigEndian.Uint16(tlvs[1:]))
+		if len(tlvs) < 3+l {
+			return nil, errInvalidProxyHeader
+		}
+		h.TLVs = append(h.TLVs, ProxyTLV{Type: tlvs[0], Value: tlvs[3 : 3+l]})
+		tlvs = tlvs[3+l:]
+	}
+	return h, nil
+}
+
+// bufferedConn reads from the buffered reader which may contain the data after the PROXY header.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// ProxyConn is the connection accepted by the ProxyListener from a trusted proxy.
+// RemoteAddr and LocalAddr return the addresses in the PROXY header.
+type ProxyConn struct {
+	net.Conn
+	header *ProxyHeader
+}
+
+// RemoteAddr returns the address of the client.
+func (c *ProxyConn) RemoteAddr() net.Addr {
+	if c.header.SourceAddr != nil {
+		return c.header.SourceAddr
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// LocalAddr returns the address the client connected to.
+func (c *ProxyConn) LocalAddr() net.Addr {
+	if c.header.DestinationAddr != nil {
+		return c.header.DestinationAddr
+	}
+	return c.Conn.LocalAddr()
+}
+
+// ProxyAddr returns the address of the proxy.
+func (c *ProxyConn) ProxyAddr() net.Addr {
+	return c.Conn.RemoteAddr()
+}
+
+// Header returns the PROXY header.
+func (c *ProxyConn) Header() *ProxyHeader {
+	return c.header
+}
+
+// ProxyHeaderFromConn returns the PROXY header of the connection returned by Client.Connection()
+// or passed to the OnAccept hook. It returns nil if the connection is not from a trusted proxy.
+func ProxyHeaderFromConn(conn net.Conn) *ProxyHeader {
+	if c, ok := conn.(*ProxyConn); ok {
+		return c.header
+	}
+	return nil
+}
+
+// ProxyListener is a TCP listener which accepts the connections from the proxies using the PROXY protocol.
+// The connections returned by Accept are the raw connections,
+// the server reads the PROXY header and performs the TLS handshake on them if needed.
+type ProxyListener struct {
+	net.Listener
+	trustedProxies []*net.IPNet
+	headerTimeout  time.Duration
+	// tlsConfig is nil if TLS is not enabled.
+	tlsConfig *tlsConfig
+}
+
+// NewProxyListener creates a ProxyListener for the given PROXY protocol options. tlsOpts can be nil if TLS is not enabled.
+func NewProxyListener(inner net.Listener, opts *config.ProxyProtocolOptions, tlsOpts *config.TLSOptions) (*ProxyListener, error) {
+	nets, err := opts.TrustedNets()
+	if err != nil {
+		return nil, err
+	}
+	l := &ProxyListener{
+		Listener:       inner,
+		trustedProxies: nets,
+		headerTimeout:  opts.HeaderTimeout,
+	}
+	if tlsOpts != nil {
+		l.tlsConfig, err = newTLSConfig(tlsOpts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// CertIdentity is the same as TLSListener.CertIdentity, it returns empty string if TLS is not enabled.
+func (l *ProxyListener) CertIdentity() string {
+	if l.tlsConfig == nil {
+		return ""
+	}
+	return l.tlsConfig.getCertIdentity()
+}
+
+// Reload re-reads the certificates and applies the new TLS options.
+// The established connections are not affected.
+func (l *ProxyListener) Reload(opts *config.TLSOptions) error {
+	if l.tlsConfig == nil {
+		return errors.New("tls is not enabled")
+	}
+	return l.tlsConfig.reload(opts)
+}
+
+// handshake reads the PROXY header if the connection is from a trusted proxy,
+// and wraps the connection with TLS if needed.
+func (l *ProxyListener) handshake(conn net.Conn) (net.Conn, error) {
+	var header *ProxyHeader
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok && trustedProxy(l.trustedProxies, addr.IP) {
+		err := conn.SetReadDeadline(time.Now().Add(l.headerTimeout))
+		if err != nil {
+			return nil, err
+		}
+		r := bufio.NewReader(conn)
+		header, err = readProxyHeader(r)
+		if err != nil {
+			return nil, err
+		}
+		err = conn.SetReadDeadline(time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		conn = &bufferedConn{Conn: conn, r: r}
+	}
+	if l.tlsConfig != nil {
+		conn = tls.Server(conn, l.tlsConfig.serverConfig())
+	}
+	if header != nil {
+		conn = &ProxyConn{Conn: conn, header: header}
+	}
+	return conn, nil
+}
+
+// trustedProxy returns whether the ip is one of the trusted proxies, empty nets means to trust none.
+func trustedProxy(nets []*net.IPNet, ip net.IP) bool {
+	for _, v := range nets {
+		if v.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedAddr returns the client address in the X-Forwarded-For header if the request is from a trusted proxy.
+// The addresses are checked from right to left, and the first one which is not a trusted proxy is the client.
+// It returns nil if the header is absent or invalid.
+func forwardedAddr(nets []*net.IPNet, r *http.Request) net.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip == nil || !trustedProxy(nets, ip) {
+		return nil
+	}
+	var ips []net.IP
+	for _, v := range r.Header.Values("X-Forwarded-For") {
+		for _, s := range strings.Split(v, ",") {
+			ip := net.ParseIP(strings.TrimSpace(s))
+			if ip == nil {
+				return nil
+			}
+			ips = append(ips, ip)
+		}
+	}
+	if len(ips) == 0 {
+		return nil
+	}
+	for i := len(ips) - 1; i > 0; i-- {
+		if !trustedProxy(nets, ips[i]) {
+			return &net.TCPAddr{IP: ips[i]}
+		}
+	}
+	return &net.TCPAddr{IP: ips[0]}
+}
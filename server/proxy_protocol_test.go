@@ -0,0 +1,255 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt/config"
+)
+
+// proxyV2Header returns the v2 header of the TCP over IPv4 connection from src to dst with the given TLVs.
+func proxyV2Header(src, dst *net.TCPAddr, tlvs ...ProxyTLV) []byte {
+	payload := &bytes.Buffer{}
+	payload.Write(src.IP.To4())
+	payload.Write(dst.IP.To4())
+	binary.Write(payload, binary.BigEndian, uint16(src.Port))
+	binary.Write(payload, binary.BigEndian, uint16(dst.Port))
+	for _, v := range tlvs {
+		payload.WriteByte(v.Type)
+		binary.Write(payload, binary.BigEndian, uint16(len(v.Value)))
+		payload.Write(v.Value)
+	}
+	b := append([]byte{}, proxyV2Signature...)
+	b = append(b, 0x21, 0x11)
+	b = append(b, byte(payload.Len()>>8), byte(payload.Len()))
+	return append(b, payload.Bytes()...)
+}
+
+func TestReadProxyHeader(t *testing.T) {
+	a := assert.New(t)
+	src := &net.TCPAddr{IP: net.IPv4(192, 168, 1, 1).To4(), Port: 56324}
+	dst := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1).To4(), Port: 1883}
+	local := append([]byte{}, proxyV2Signature...)
+	local = append(local, 0x20, 0x00, 0x00, 0x00)
+
+	var tt = []struct {
+		name   string
+		in     []byte
+		header *ProxyHeader
+	}{
+		{
+			name: "v1_tcp4",
+			in:   []byte("PROXY TCP4 192.168.1.1 10.0.0.1 56324 1883\r\n"),
+			header: &ProxyHeader{
+				Version:         1,
+				SourceAddr:      &net.TCPAddr{IP: net.ParseIP("192.168.1.1"), Port: 56324},
+				DestinationAddr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1883},
+			},
+		},
+		{
+			name: "v1_tcp6",
+			in:   []byte("PROXY TCP6 ::1 ::2 56324 1883\r\n"),
+			header: &ProxyHeader{
+				Version:         1,
+				SourceAddr:      &net.TCPAddr{IP: net.ParseIP("::1"), Port: 56324},
+				DestinationAddr: &net.TCPAddr{IP: net.ParseIP("::2"), Port: 1883},
+			},
+		},
+		{
+			name:   "v1_unknown",
+			in:     []byte("PROXY UNKNOWN\r\n"),
+			header: &ProxyHeader{Version: 1},
+		},
+		{
+			name: "v2",
+			in: proxyV2Header(src, dst,
+				ProxyTLV{Type: ProxyTLVTypeAuthority, Value: []byte("mqtt.example.com")},
+				ProxyTLV{Type: ProxyTLVTypeALPN, Value: []byte("mqtt")}),
+			header: &ProxyHeader{
+				Version:         2,
+				SourceAddr:      src,
+				DestinationAddr: dst,
+				TLVs: []ProxyTLV{
+					{Type: ProxyTLVTypeAuthority, Value: []byte("mqtt.example.com")},
+					{Type: ProxyTLVTypeALPN, Value: []byte("mqtt")},
+				},
+			},
+		},
+		{
+			name:   "v2_local",
+			in:     local,
+			header: &ProxyHeader{Version: 2},
+		},
+		{name: "v1_ipv6_in_tcp4", in: []byte("PROXY TCP4 ::1 ::2 56324 1883\r\n")},
+		{name: "v1_invalid_port", in: []byte("PROXY TCP4 192.168.1.1 10.0.0.1 65536 1883\r\n")},
+		{name: "v1_without_crlf", in: []byte("PROXY TCP4 192.168.1.1 10.0.0.1 56324 1883\n")},
+		{name: "v1_too_long", in: append([]byte("PROXY UNKNOWN"), bytes.Repeat([]byte(" "), 200)...)},
+		{name: "v2_invalid_tlv_length", in: func() []byte {
+			b := proxyV2Header(src, dst, ProxyTLV{Type: ProxyTLVTypeNoop, Value: []byte("abc")})
+			// the length of the TLV exceeds the header.
+			b[30] = 10
+			return b
+		}()},
+		{name: "no_header", in: []byte("\x10\x0c\x00\x04MQTT\x04\x02\x00\x3c\x00\x00")},
+	}
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			r := bufio.NewReader(bytes.NewReader(append(v.in, "data"...)))
+			h, err := readProxyHeader(r)
+			if v.header == nil {
+				a.NotNil(err)
+				return
+			}
+			a.Nil(err)
+			a.Equal(v.header, h)
+			rest, _ := ioutil.ReadAll(r)
+			a.Equal("data", string(rest))
+		})
+	}
+}
+
+func TestProxyHeader_Authority(t *testing.T) {
+	a := assert.New(t)
+	h := &ProxyHeader{TLVs: []ProxyTLV{{Type: ProxyTLVTypeAuthority, Value: []byte("mqtt.example.com")}}}
+	a.Equal("mqtt.example.com", h.Authority())
+	_, ok := h.TLV(ProxyTLVTypeSSL)
+	a.False(ok)
+	a.Equal("", (&ProxyHeader{}).Authority())
+}
+
+func TestForwardedAddr(t *testing.T) {
+	a := assert.New(t)
+	nets, err := (&config.ProxyProtocolOptions{TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1"}}).TrustedNets()
+	a.Nil(err)
+	var tt = []struct {
+		remoteAddr string
+		xff        []string
+		addr       net.Addr
+	}{
+		{remoteAddr: "10.0.0.1:1000", xff: []string{"1.1.1.1"}, addr: &net.TCPAddr{IP: net.ParseIP("1.1.1.1")}},
+		{remoteAddr: "10.0.0.1:1000", xff: []string{"2.2.2.2, 1.1.1.1, 192.168.1.1"}, addr: &net.TCPAddr{IP: net.ParseIP("1.1.1.1")}},
+		{remoteAddr: "10.0.0.1:1000", xff: []string{"2.2.2.2", "1.1.1.1"}, addr: &net.TCPAddr{IP: net.ParseIP("1.1.1.1")}},
+		{remoteAddr: "10.0.0.1:1000", xff: []string{"10.0.0.3, 10.0.0.2"}, addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.3")}},
+		{remoteAddr: "10.0.0.1:1000"},
+		{remoteAddr: "10.0.0.1:1000", xff: []string{"unknown"}},
+		// not from a trusted proxy
+		{remoteAddr: "1.1.1.1:1000", xff: []string{"2.2.2.2"}},
+	}
+	for _, v := range tt {
+		r := &http.Request{RemoteAddr: v.remoteAddr, Header: http.Header{}}
+		for _, h := range v.xff {
+			r.Header.Add("X-Forwarded-For", h)
+		}
+		a.Equal(v.addr, forwardedAddr(nets, r), v.xff)
+	}
+	// trust none
+	r := &http.Request{RemoteAddr: "1.1.1.1:1000", Header: http.Header{"X-Forwarded-For": []string{"2.2.2.2, 3.3.3.3"}}}
+	a.Nil(forwardedAddr(nil, r))
+	// trust all
+	nets, err = (&config.ProxyProtocolOptions{TrustedProxies: []string{"0.0.0.0/0", "::/0"}}).TrustedNets()
+	a.Nil(err)
+	a.Equal(&net.TCPAddr{IP: net.ParseIP("2.2.2.2")}, forwardedAddr(nets, r))
+}
+
+func TestServer_serveTCP_proxyProtocol(t *testing.T) {
+	a := assert.New(t)
+	ln, _, err := NewListener(&config.ListenerConfig{
+		Address: "127.0.0.1:0",
+		ProxyProtocol: &config.ProxyProtocolOptions{
+			TrustedProxies: []string{"127.0.0.1"},
+			HeaderTimeout:  100 * time.Millisecond,
+		},
+	})
+	a.Nil(err)
+	srv := New()
+	accepted := make(chan net.Conn, 1)
+	srv.hooks.OnAccept = func(ctx context.Context, conn net.Conn) bool {
+		accepted <- conn
+		return false
+	}
+	go srv.serveTCP(ln)
+	defer ln.Close()
+
+	src := &net.TCPAddr{IP: net.IPv4(192, 168, 1, 1).To4(), Port: 56324}
+	dst := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1).To4(), Port: 1883}
+	c, err := net.Dial("tcp", ln.Addr().String())
+	a.Nil(err)
+	defer c.Close()
+	_, err = c.Write(proxyV2Header(src, dst, ProxyTLV{Type: ProxyTLVTypeAuthority, Value: []byte("mqtt.example.com")}))
+	a.Nil(err)
+	select {
+	case conn := <-accepted:
+		a.Equal(src, conn.RemoteAddr())
+		a.Equal(dst, conn.LocalAddr())
+		a.Equal(c.LocalAddr().String(), conn.(*ProxyConn).ProxyAddr().String())
+		a.Equal("mqtt.example.com", ProxyHeaderFromConn(conn).Authority())
+	case <-time.After(time.Second):
+		t.Fatal("connection not accepted")
+	}
+
+	// the connection is closed if the trusted proxy does not send the header in time.
+	c2, err := net.Dial("tcp", ln.Addr().String())
+	a.Nil(err)
+	defer c2.Close()
+	c2.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = c2.Read(make([]byte, 1))
+	a.NotNil(err)
+	a.NotContains(err.Error(), "timeout")
+	select {
+	case <-accepted:
+		t.Fatal("unexpected connection")
+	default:
+	}
+}
+
+func TestServer_serveTCP_proxyProtocol_untrusted(t *testing.T) {
+	a := assert.New(t)
+	ln, _, err := NewListener(&config.ListenerConfig{
+		Address: "127.0.0.1:0",
+		ProxyProtocol: &config.ProxyProtocolOptions{
+			TrustedProxies: []string{"10.0.0.0/8"},
+			HeaderTimeout:  time.Second,
+		},
+	})
+	a.Nil(err)
+	srv := New()
+	// the spoofed PROXY header sent by an untrusted peer is not parsed, it is passed to the connection as is.
+	spoofed := proxyV2Header(&net.TCPAddr{IP: net.IPv4(10, 0, 0, 2).To4(), Port: 56324}, &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1).To4(), Port: 1883})
+	accepted := make(chan net.Conn, 1)
+	received := make(chan []byte, 1)
+	srv.hooks.OnAccept = func(ctx context.Context, conn net.Conn) bool {
+		b := make([]byte, len(spoofed))
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		_, err := io.ReadFull(conn, b)
+		a.Nil(err)
+		received <- b
+		accepted <- conn
+		return false
+	}
+	go srv.serveTCP(ln)
+	defer ln.Close()
+
+	c, err := net.Dial("tcp", ln.Addr().String())
+	a.Nil(err)
+	defer c.Close()
+	_, err = c.Write(spoofed)
+	a.Nil(err)
+	select {
+	case conn := <-accepted:
+		a.Equal(c.LocalAddr().String(), conn.RemoteAddr().String())
+		a.Nil(ProxyHeaderFromConn(conn))
+		a.Equal(spoofed, <-received)
+	case <-time.After(2 * time.Second):
+		t.Fatal("connection not accepted")
+	}
+}
@@ -7,6 +7,12 @@ type publishService struct {
 }
 
 func (p *publishService) Publish(message *gmqtt.Message) {
+	p.server.mu.Lock()
+	p.server.dispatchMessageLocked("", message)
+	p.server.mu.Unlock()
+}
+
+func (p *publishService) PublishLocal(message *gmqtt.Message) {
 	p.server.mu.Lock()
 	p.server.deliverMessageHandler("", message)
 	p.server.mu.Unlock()
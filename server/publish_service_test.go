@@ -0,0 +1,36 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt"
+)
+
+func TestPublishService(t *testing.T) {
+	a := assert.New(t)
+	srv := &server{}
+	var delivered, dispatched []*gmqtt.Message
+	srv.deliverMessageHandler = func(srcClientID string, msg *gmqtt.Message) (matched bool) {
+		delivered = append(delivered, msg)
+		return true
+	}
+	srv.hooks.OnMsgDispatched = func(ctx context.Context, srcClientID string, msg *gmqtt.Message) {
+		dispatched = append(dispatched, msg)
+	}
+	p := &publishService{server: srv}
+
+	msg := &gmqtt.Message{Topic: "a"}
+	p.Publish(msg)
+	a.Equal([]*gmqtt.Message{msg}, delivered)
+	a.Equal([]*gmqtt.Message{msg}, dispatched)
+
+	// PublishLocal does not trigger the OnMsgDispatched hook.
+	local := &gmqtt.Message{Topic: "b"}
+	var lp LocalPublisher = p
+	lp.PublishLocal(local)
+	a.Equal([]*gmqtt.Message{msg, local}, delivered)
+	a.Equal([]*gmqtt.Message{msg}, dispatched)
+}
@@ -0,0 +1,309 @@
+package server
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+// tokenBucket implements the token bucket algorithm.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket returns a token bucket which is filled at rate tokens per second.
+// It returns nil if the rate is not positive, which means unlimited.
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	b := float64(burst)
+	if burst <= 0 {
+		b = math.Ceil(rate)
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  b,
+		tokens: b,
+	}
+}
+
+// advanceLocked refills the bucket to the given time.
+func (b *tokenBucket) advanceLocked(now time.Time) {
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+}
+
+// allow takes n tokens if they are available and reports whether they are taken.
+// If n is greater than the burst, the tokens are taken when the bucket is full.
+func (b *tokenBucket) allow(now time.Time, n float64) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advanceLocked(now)
+	if b.tokens < math.Min(n, b.burst) {
+		return false
+	}
+	b.tokens -= n
+	return true
+}
+
+// refund puts back the tokens taken by allow.
+func (b *tokenBucket) refund(n float64) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	b.tokens = math.Min(b.tokens+n, b.burst)
+	b.mu.Unlock()
+}
+
+// reserve takes n tokens and returns the duration to wait until the tokens become available.
+func (b *tokenBucket) reserve(now time.Time, n float64) time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advanceLocked(now)
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// rateLimit limits the number of messages and the payload bytes.
+type rateLimit struct {
+	messages *tokenBucket
+	bytes    *tokenBucket
+}
+
+// newRateLimit returns the rateLimit of the rule, or nil if the rule is unlimited.
+func newRateLimit(rule config.RateLimitRule) *rateLimit {
+	r := &rateLimit{
+		messages: newTokenBucket(rule.MessageRate, rule.MessageBurst),
+		bytes:    newTokenBucket(rule.ByteRate, rule.ByteBurst),
+	}
+	if r.messages == nil && r.bytes == nil {
+		return nil
+	}
+	return r
+}
+
+// allow reports whether a message with the given payload size is allowed.
+// If not allowed, no tokens are taken and the returned code indicates which limit is exceeded.
+func (r *rateLimit) allow(now time.Time, size int) (bool, codes.Code) {
+	if !r.messages.allow(now, 1) {
+		return false, codes.MessageRateTooHigh
+	}
+	if !r.bytes.allow(now, float64(size)) {
+		r.messages.refund(1)
+		return false, codes.QuotaExceeded
+	}
+	return true, codes.Success
+}
+
+func (r *rateLimit) refund(size int) {
+	r.messages.refund(1)
+	r.bytes.refund(float64(size))
+}
+
+func (r *rateLimit) reserve(now time.Time, size int) time.Duration {
+	d := r.messages.reserve(now, 1)
+	if bd := r.bytes.reserve(now, float64(size)); bd > d {
+		d = bd
+	}
+	return d
+}
+
+type topicRateLimit struct {
+	prefix string
+	limit  *rateLimit
+}
+
+type userRateLimit struct {
+	limit *rateLimit
+	// refs is the number of the clients that are using the limit.
+	refs int
+}
+
+// publishRateLimiter holds the rate limits shared by the clients.
+// It is recreated when config.MQTT.RateLimit is changed, the connected clients keep using the old one.
+type publishRateLimiter struct {
+	config config.RateLimit
+	topics []topicRateLimit
+	// gard users
+	mu    sync.Mutex
+	users map[string]*userRateLimit
+}
+
+func newPublishRateLimiter(cfg config.RateLimit) *publishRateLimiter {
+	p := &publishRateLimiter{
+		config: cfg,
+		users:  make(map[string]*userRateLimit),
+	}
+	for _, v := range cfg.Topics {
+		if l := newRateLimit(v.RateLimitRule); l != nil {
+			p.topics = append(p.topics, topicRateLimit{prefix: v.Prefix, limit: l})
+		}
+	}
+	return p
+}
+
+// newClientRateLimiter returns the rate limiter for the client with the given username and client limit.
+// The returned limiter must be released by calling release after the client is closed.
+func (p *publishRateLimiter) newClientRateLimiter(username string, rule config.RateLimitRule) *clientRateLimiter {
+	c := &clientRateLimiter{
+		shared:   p,
+		username: username,
+		client:   newRateLimit(rule),
+	}
+	if username == "" {
+		return c
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	u, ok := p.users[username]
+	if !ok {
+		u = &userRateLimit{limit: newRateLimit(p.config.Username)}
+		if u.limit == nil {
+			return c
+		}
+		p.users[username] = u
+	}
+	u.refs++
+	c.user = u.limit
+	return c
+}
+
+// clientRateLimiter is the publish rate limiter of a client.
+type clientRateLimiter struct {
+	shared   *publishRateLimiter
+	username string
+	client   *rateLimit
+	user     *rateLimit
+}
+
+// limits returns the rate limits that apply to the topic.
+func (c *clientRateLimiter) limits(topic string) []*rateLimit {
+	var rs []*rateLimit
+	if c.client != nil {
+		rs = append(rs, c.client)
+	}
+	if c.user != nil {
+		rs = append(rs, c.user)
+	}
+	for _, v := range c.shared.topics {
+		if strings.HasPrefix(topic, v.prefix) {
+			rs = append(rs, v.limit)
+		}
+	}
+	return rs
+}
+
+// allow reports whether the message is allowed, and returns the code which indicates the exceeded limit if not allowed.
+func (c *clientRateLimiter) allow(topic string, size int) (bool, codes.Code) {
+	now := time.Now()
+	ls := c.limits(topic)
+	for k, v := range ls {
+		if ok, code := v.allow(now, size); !ok {
+			for _, l := range ls[:k] {
+				l.refund(size)
+			}
+			return false, code
+		}
+	}
+	return true, codes.Success
+}
+
+// reserve takes the tokens of the message and returns the duration to wait before processing the message.
+func (c *clientRateLimiter) reserve(topic string, size int) time.Duration {
+	now := time.Now()
+	var d time.Duration
+	for _, v := range c.limits(topic) {
+		if rd := v.reserve(now, size); rd > d {
+			d = rd
+		}
+	}
+	return d
+}
+
+func (c *clientRateLimiter) backpressure() bool {
+	return c.shared.config.Backpressure
+}
+
+func (c *clientRateLimiter) v3AckAndDrop() bool {
+	return c.shared.config.V3AckAndDrop
+}
+
+func (c *clientRateLimiter) release() {
+	if c.user == nil {
+		return
+	}
+	p := c.shared
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if u, ok := p.users[c.username]; ok {
+		u.refs--
+		if u.refs == 0 {
+			delete(p.users, c.username)
+		}
+	}
+}
+
+// backpressureTopic returns the topic name used by the rate limiting in the read loop,
+// and whether the message should be charged.
+// The invalid messages are not charged, they will be rejected by publishHandler.
+// The retransmitted QoS 2 messages whose packet ids are in qos2 are not charged again.
+func (client *client) backpressureTopic(pub *packets.Publish, aliases map[uint16][]byte, qos2 map[packets.PacketID]struct{}) (string, bool) {
+	if !client.opts.RetainAvailable && pub.Retain {
+		return "", false
+	}
+	topic := pub.TopicName
+	if client.version == packets.Version5 && pub.Properties.TopicAlias != nil {
+		alias := *pub.Properties.TopicAlias
+		if alias >= client.opts.ServerTopicAliasMax {
+			return "", false
+		}
+		if len(topic) == 0 {
+			topic = aliases[alias]
+			if len(topic) == 0 {
+				return "", false
+			}
+		} else {
+			aliases[alias] = topic
+		}
+	}
+	name := string(topic)
+	if srv := client.server; strings.HasPrefix(name, delayedTopicPrefix) && srv.delayedQueue.enabled() {
+		var err error
+		name, _, err = srv.delayedQueue.parseTopic(name)
+		if err != nil {
+			return "", false
+		}
+	}
+	if pub.Qos == packets.Qos2 {
+		if _, ok := qos2[pub.PacketID]; ok && pub.Dup {
+			return "", false
+		}
+		qos2[pub.PacketID] = struct{}{}
+	}
+	return name, true
+}
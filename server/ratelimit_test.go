@@ -0,0 +1,350 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/config"
+	delayed_mem "github.com/DrmagicE/gmqtt/persistence/delayed/mem"
+	unack_mem "github.com/DrmagicE/gmqtt/persistence/unack/mem"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+func TestTokenBucket(t *testing.T) {
+	a := assert.New(t)
+	a.Nil(newTokenBucket(0, 10))
+
+	now := time.Unix(0, 0)
+	b := newTokenBucket(2, 0)
+	a.True(b.allow(now, 1))
+	a.True(b.allow(now, 1))
+	a.False(b.allow(now, 1))
+	// refill 1 token after 500ms
+	now = now.Add(500 * time.Millisecond)
+	a.True(b.allow(now, 1))
+	a.False(b.allow(now, 1))
+	b.refund(1)
+	a.True(b.allow(now, 1))
+
+	// the tokens which are greater than the burst are allowed when the bucket is full.
+	now = now.Add(time.Hour)
+	a.True(b.allow(now, 10))
+	a.Equal(4*time.Second+500*time.Millisecond, b.reserve(now, 1))
+
+	b = newTokenBucket(10, 5)
+	a.Zero(b.reserve(now, 5))
+	a.Equal(100*time.Millisecond, b.reserve(now, 1))
+}
+
+func TestPublishRateLimiter(t *testing.T) {
+	a := assert.New(t)
+	p := newPublishRateLimiter(config.RateLimit{
+		Client:   config.RateLimitRule{MessageRate: 10},
+		Username: config.RateLimitRule{ByteRate: 10},
+		Topics: []config.TopicRateLimit{
+			{Prefix: "a/", RateLimitRule: config.RateLimitRule{MessageRate: 1}},
+		},
+	})
+	c1 := p.newClientRateLimiter("user", config.RateLimitRule{})
+	c2 := p.newClientRateLimiter("user", p.config.Client)
+	a.Nil(c1.client)
+	a.NotNil(c2.client)
+	a.Same(c1.user, c2.user)
+	a.Equal(2, p.users["user"].refs)
+	a.Len(c2.limits("a/b"), 3)
+	a.Len(c2.limits("b/a"), 2)
+
+	ok, code := c1.allow("b", 10)
+	a.True(ok)
+	a.Equal(codes.Success, code)
+	// exceeds the shared username limit
+	ok, code = c2.allow("b", 1)
+	a.False(ok)
+	a.Equal(codes.QuotaExceeded, code)
+
+	ok, _ = c1.allow("a/b", 0)
+	a.True(ok)
+	// exceeds the shared topic limit
+	ok, code = c2.allow("a/c", 0)
+	a.False(ok)
+	a.Equal(codes.MessageRateTooHigh, code)
+
+	c1.release()
+	a.Equal(1, p.users["user"].refs)
+	c2.release()
+	a.Empty(p.users)
+
+	// no username limit for the clients without username
+	c3 := p.newClientRateLimiter("", config.RateLimitRule{})
+	a.Nil(c3.user)
+	a.Empty(p.users)
+}
+
+func TestClient_publishHandler_rateLimit(t *testing.T) {
+	var tt = []struct {
+		name         string
+		version      packets.Version
+		qos          uint8
+		v3AckAndDrop bool
+		out          packets.Packet
+		err          *codes.Error
+	}{
+		{
+			name:    "v5_qos1",
+			version: packets.Version5,
+			qos:     packets.Qos1,
+			out: &packets.Puback{
+				Version:  packets.Version5,
+				PacketID: 2,
+				Code:     codes.MessageRateTooHigh,
+			},
+		},
+		{
+			name:    "v5_qos2",
+			version: packets.Version5,
+			qos:     packets.Qos2,
+			out: &packets.Pubrec{
+				Version:  packets.Version5,
+				PacketID: 2,
+				Code:     codes.MessageRateTooHigh,
+			},
+		},
+		{
+			name:    "v5_qos0",
+			version: packets.Version5,
+			qos:     packets.Qos0,
+		},
+		{
+			// v3 does not support negative acknowledgements, the client is disconnected.
+			name:    "v311_qos1",
+			version: packets.Version311,
+			qos:     packets.Qos1,
+			err: &codes.Error{
+				Code: codes.MessageRateTooHigh,
+			},
+		},
+		{
+			name:    "v311_qos2",
+			version: packets.Version311,
+			qos:     packets.Qos2,
+			err: &codes.Error{
+				Code: codes.MessageRateTooHigh,
+			},
+		},
+		{
+			name:         "v311_qos1_ack_and_drop",
+			version:      packets.Version311,
+			qos:          packets.Qos1,
+			v3AckAndDrop: true,
+			out: &packets.Puback{
+				Version:  packets.Version311,
+				PacketID: 2,
+				Code:     codes.Success,
+			},
+		},
+		{
+			name:         "v311_qos2_ack_and_drop",
+			version:      packets.Version311,
+			qos:          packets.Qos2,
+			v3AckAndDrop: true,
+			out: &packets.Pubrec{
+				Version:  packets.Version311,
+				PacketID: 2,
+				Code:     codes.Success,
+			},
+		},
+	}
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			a := assert.New(t)
+			cfg := config.DefaultConfig()
+			cfg.MQTT.RateLimit.Client.MessageRate = 1
+			cfg.MQTT.RateLimit.V3AckAndDrop = v.v3AckAndDrop
+			srv := &server{
+				config: cfg,
+			}
+			var delivered int
+			srv.deliverMessageHandler = func(srcClientID string, msg *gmqtt.Message) (matched bool) {
+				delivered++
+				return true
+			}
+			c, err := srv.newClient(noopConn{})
+			a.Nil(err)
+			c.version = v.version
+			c.opts.ClientID = "cid"
+			c.unackStore = unack_mem.New(unack_mem.Options{
+				ClientID: "cid",
+			})
+			c.rateLimiter = c.sharedLimiter.newClientRateLimiter("", cfg.MQTT.RateLimit.Client)
+
+			newPublish := func(id packets.PacketID) *packets.Publish {
+				return &packets.Publish{
+					Version:    v.version,
+					Qos:        v.qos,
+					TopicName:  []byte("topic"),
+					PacketID:   id,
+					Payload:    []byte("b"),
+					Properties: &packets.Properties{},
+				}
+			}
+			a.Nil(c.publishHandler(newPublish(1)))
+			if v.qos != packets.Qos0 {
+				<-c.out
+			}
+			a.Equal(v.err, c.publishHandler(newPublish(2)))
+			a.Equal(1, delivered)
+			select {
+			case p := <-c.out:
+				a.Equal(v.out, p)
+			default:
+				a.Nil(v.out)
+			}
+			if v.qos == packets.Qos2 {
+				// the packet id is kept only after the successful PUBREC.
+				exist, err := c.unackStore.Set(2)
+				a.Nil(err)
+				a.Equal(v.v3AckAndDrop, exist)
+			}
+		})
+	}
+}
+
+func TestClient_publishHandler_rateLimitNotCharged(t *testing.T) {
+	a := assert.New(t)
+	cfg := config.DefaultConfig()
+	cfg.MQTT.RateLimit.Client.MessageRate = 1
+	srv := &server{
+		config: cfg,
+	}
+	var delivered int
+	srv.deliverMessageHandler = func(srcClientID string, msg *gmqtt.Message) (matched bool) {
+		delivered++
+		return true
+	}
+	newTestDelayedQueue(t, srv, delayed_mem.New())
+	c, err := srv.newClient(noopConn{})
+	a.Nil(err)
+	c.version = packets.Version5
+	c.opts.ClientID = "cid"
+	c.unackStore = unack_mem.New(unack_mem.Options{
+		ClientID: "cid",
+	})
+	c.rateLimiter = c.sharedLimiter.newClientRateLimiter("", cfg.MQTT.RateLimit.Client)
+
+	// the invalid message is not charged.
+	a.Nil(c.publishHandler(&packets.Publish{
+		Version:    packets.Version5,
+		Qos:        packets.Qos1,
+		TopicName:  []byte("$delayed/invalid/topic"),
+		PacketID:   1,
+		Properties: &packets.Properties{},
+	}))
+	a.Equal(codes.TopicNameInvalid, (<-c.out).(*packets.Puback).Code)
+
+	pub := &packets.Publish{
+		Version:    packets.Version5,
+		Qos:        packets.Qos2,
+		TopicName:  []byte("topic"),
+		PacketID:   2,
+		Properties: &packets.Properties{},
+	}
+	a.Nil(c.publishHandler(pub))
+	a.Equal(codes.Success, (<-c.out).(*packets.Pubrec).Code)
+	// the retransmitted QoS 2 message is not charged again.
+	pub.Dup = true
+	a.Nil(c.publishHandler(pub))
+	a.NotEqual(codes.MessageRateTooHigh, (<-c.out).(*packets.Pubrec).Code)
+	a.Equal(1, delivered)
+
+	// the tokens are used up by the first QoS 2 message.
+	a.Nil(c.publishHandler(&packets.Publish{
+		Version:    packets.Version5,
+		Qos:        packets.Qos1,
+		TopicName:  []byte("topic"),
+		PacketID:   3,
+		Properties: &packets.Properties{},
+	}))
+	a.Equal(codes.MessageRateTooHigh, (<-c.out).(*packets.Puback).Code)
+}
+
+func TestClient_backpressureTopic(t *testing.T) {
+	a := assert.New(t)
+	srv := &server{
+		config: config.DefaultConfig(),
+	}
+	newTestDelayedQueue(t, srv, delayed_mem.New())
+	c, err := srv.newClient(noopConn{})
+	a.Nil(err)
+	c.version = packets.Version5
+	c.opts.RetainAvailable = true
+	c.opts.ServerTopicAliasMax = 10
+	aliases := make(map[uint16][]byte)
+	qos2 := make(map[packets.PacketID]struct{})
+	alias := func(v uint16) *uint16 {
+		return &v
+	}
+	for _, v := range []struct {
+		name  string
+		pub   *packets.Publish
+		topic string
+		ok    bool
+	}{
+		{
+			name:  "topic",
+			pub:   &packets.Publish{TopicName: []byte("a"), Properties: &packets.Properties{}},
+			topic: "a",
+			ok:    true,
+		},
+		{
+			name:  "set_alias",
+			pub:   &packets.Publish{TopicName: []byte("b"), Properties: &packets.Properties{TopicAlias: alias(1)}},
+			topic: "b",
+			ok:    true,
+		},
+		{
+			name:  "use_alias",
+			pub:   &packets.Publish{Properties: &packets.Properties{TopicAlias: alias(1)}},
+			topic: "b",
+			ok:    true,
+		},
+		{
+			name: "unknown_alias",
+			pub:  &packets.Publish{Properties: &packets.Properties{TopicAlias: alias(2)}},
+		},
+		{
+			name: "alias_too_large",
+			pub:  &packets.Publish{TopicName: []byte("c"), Properties: &packets.Properties{TopicAlias: alias(10)}},
+		},
+		{
+			name:  "delayed",
+			pub:   &packets.Publish{TopicName: []byte("$delayed/10/d"), Properties: &packets.Properties{}},
+			topic: "d",
+			ok:    true,
+		},
+		{
+			name: "invalid_delayed",
+			pub:  &packets.Publish{TopicName: []byte("$delayed/d"), Properties: &packets.Properties{}},
+		},
+		{
+			name:  "qos2",
+			pub:   &packets.Publish{Qos: packets.Qos2, PacketID: 1, TopicName: []byte("e"), Properties: &packets.Properties{}},
+			topic: "e",
+			ok:    true,
+		},
+		{
+			name: "qos2_dup",
+			pub:  &packets.Publish{Qos: packets.Qos2, Dup: true, PacketID: 1, TopicName: []byte("e"), Properties: &packets.Properties{}},
+		},
+	} {
+		topic, ok := c.backpressureTopic(v.pub, aliases, qos2)
+		a.Equal(v.topic, topic, v.name)
+		a.Equal(v.ok, ok, v.name)
+	}
+	c.opts.RetainAvailable = false
+	_, ok := c.backpressureTopic(&packets.Publish{Retain: true, TopicName: []byte("a"), Properties: &packets.Properties{}}, aliases, qos2)
+	a.False(ok)
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt/config"
+)
+
+// Reloadable is the interface that a plugin can implement to apply the new config without restart.
+// Reload is called in every Server.ApplyConfig (e.g. gmqttd reload) after the server config is replaced.
+// If the plugin does not implement Reloadable, changes to its config take effect only after restart.
+type Reloadable interface {
+	Reload(config config.Config) error
+}
+
+// ReloadResult reports the changed config fields that are not applied to all clients by Server.ApplyConfig.
+// The field names are the same as the yaml keys, e.g. "mqtt.max_inflight", "plugins.admin".
+type ReloadResult struct {
+	// RestartRequired contains the changed fields which take effect only after restart.
+	RestartRequired []string
+	// NewConnectionsOnly contains the changed fields which only apply to new connections,
+	// the connected clients keep the settings that were negotiated when they connected.
+	NewConnectionsOnly []string
+}
+
+// restartRequiredFields are the config fields which take effect only after restart.
+var restartRequiredFields = map[string]struct{}{
+	"listeners":                          {},
+	"log":                                {},
+	"pid_file":                           {},
+	"plugin_order":                       {},
+	"persistence":                        {},
+	"topic_alias_manager":                {},
+	"mqtt.session_expiry_check_Interval": {},
+}
+
+// immediateFields are the config fields which apply to all clients immediately.
+var immediateFields = map[string]struct{}{
+	"mqtt.queue_qos0_messages": {},
+	"mqtt.delivery_mode":       {},
+	"mqtt.shared_subscription": {},
+	"mqtt.delayed_publish":     {},
+	"drain":                    {},
+}
+
+// changedFields returns the yaml names of the fields that are different in the given structs.
+func changedFields(prefix string, old, new interface{}) (fields []string) {
+	ov, nv := reflect.ValueOf(old), reflect.ValueOf(new)
+	t := ov.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			continue
+		}
+		fields = append(fields, prefix+strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0])
+	}
+	return fields
+}
+
+func (srv *server) ApplyConfig(cfg config.Config) (rs ReloadResult, err error) {
+	srv.reloadMu.Lock()
+	defer srv.reloadMu.Unlock()
+	err = cfg.Validate()
+	if err != nil {
+		return rs, err
+	}
+	err = checkSharedSubStrategies(cfg.MQTT.SharedSubscription)
+	if err != nil {
+		return rs, err
+	}
+	srv.configMu.Lock()
+	old := srv.config
+	srv.config = cfg
+	srv.configMu.Unlock()
+
+	var changed []string
+	for _, v := range changedFields("", old, cfg) {
+		switch v {
+		case "mqtt":
+			changed = append(changed, changedFields("mqtt.", old.MQTT, cfg.MQTT)...)
+		case "listeners":
+			if !srv.configListeners {
+				changed = append(changed, v)
+			}
+		case "plugins":
+		default:
+			changed = append(changed, v)
+		}
+	}
+	for _, v := range changed {
+		if _, ok := restartRequiredFields[v]; ok {
+			rs.RestartRequired = append(rs.RestartRequired, v)
+			continue
+		}
+		if _, ok := immediateFields[v]; ok {
+			continue
+		}
+		rs.NewConnectionsOnly = append(rs.NewConnectionsOnly, v)
+	}
+	if srv.Status() != serverStatusStarted {
+		return rs, nil
+	}
+
+	var errs []string
+	if !reflect.DeepEqual(old.MQTT.SharedSubscription, cfg.MQTT.SharedSubscription) {
+		// the balancers will be recreated with the new strategies.
+		srv.mu.Lock()
+		srv.sharedSubBalancers = make(map[string]SharedSubBalancer)
+		srv.mu.Unlock()
+	}
+	// the listeners have been closed by Drain.
+	if srv.configListeners && !srv.isDraining() {
+		err = srv.reloadListeners(cfg.Listeners)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	for _, p := range srv.Plugins() {
+		name := p.Name()
+		if r, ok := p.(Reloadable); ok {
+			zaplog.Info("reloading plugin", zap.String("name", name))
+			err = r.Reload(cfg)
+			if err != nil {
+				errs = append(errs, "fail to reload plugin "+name+": "+err.Error())
+			}
+			continue
+		}
+		if !reflect.DeepEqual(old.Plugins[name], cfg.Plugins[name]) {
+			rs.RestartRequired = append(rs.RestartRequired, "plugins."+name)
+		}
+	}
+	if errs != nil {
+		return rs, errors.New(strings.Join(errs, ";"))
+	}
+	return rs, nil
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt/config"
+)
+
+type testPluginConfig struct {
+	Value int
+}
+
+func (t *testPluginConfig) Validate() error {
+	return nil
+}
+
+func (t *testPluginConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	return nil
+}
+
+type testPlugin struct {
+	name string
+}
+
+func (t *testPlugin) Load(service Server) error {
+	return nil
+}
+
+func (t *testPlugin) Unload() error {
+	return nil
+}
+
+func (t *testPlugin) HookWrapper() HookWrapper {
+	return HookWrapper{}
+}
+
+func (t *testPlugin) Name() string {
+	return t.name
+}
+
+type testReloadablePlugin struct {
+	testPlugin
+	reloaded []config.Config
+}
+
+func (t *testReloadablePlugin) Reload(config config.Config) error {
+	t.reloaded = append(t.reloaded, config)
+	return nil
+}
+
+// freeAddress returns a local address that can be listened on.
+func freeAddress(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// canListen returns whether the address is not in use.
+func canListen(addr string) bool {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return false
+	}
+	ln.Close()
+	return true
+}
+
+func TestServer_ApplyConfig(t *testing.T) {
+	a := assert.New(t)
+	addr1, addr2 := freeAddress(t), freeAddress(t)
+
+	cfg := config.DefaultConfig()
+	cfg.Listeners = []*config.ListenerConfig{{Address: addr1}}
+	cfg.Plugins["plugin"] = &testPluginConfig{}
+	cfg.Plugins["reloadable"] = &testPluginConfig{}
+	plg := &testPlugin{name: "plugin"}
+	rplg := &testReloadablePlugin{testPlugin: testPlugin{name: "reloadable"}}
+
+	srv := New(WithConfig(cfg), WithConfigListeners(), WithPlugin(plg, rplg))
+	srv.hooks.OnAccept = func(ctx context.Context, conn net.Conn) bool {
+		return false
+	}
+	srv.listenerMu.Lock()
+	a.Nil(srv.listenLocked(cfg.Listeners[0]))
+	atomic.StoreInt32(&srv.status, serverStatusStarted)
+	srv.listenerMu.Unlock()
+	defer srv.Stop(context.Background())
+	a.False(canListen(addr1))
+
+	newCfg := config.DefaultConfig()
+	newCfg.Listeners = []*config.ListenerConfig{
+		{Address: addr1, Websocket: &config.WebsocketOptions{Path: "/"}},
+		{Address: addr2},
+	}
+	newCfg.Plugins["plugin"] = &testPluginConfig{Value: 1}
+	newCfg.Plugins["reloadable"] = &testPluginConfig{Value: 1}
+	newCfg.MQTT.MaxInflight = 10
+	newCfg.MQTT.DeliveryMode = config.Overlap
+	newCfg.Persistence.Type = config.PersistenceTypeRedis
+
+	rs, err := srv.ApplyConfig(newCfg)
+	a.Nil(err)
+	a.ElementsMatch([]string{"persistence", "plugins.plugin"}, rs.RestartRequired)
+	a.Equal([]string{"mqtt.max_inflight"}, rs.NewConnectionsOnly)
+	a.Equal(newCfg, srv.GetConfig())
+	a.Equal([]config.Config{newCfg}, rplg.reloaded)
+
+	srv.listenerMu.Lock()
+	a.Len(srv.listeners, 2)
+	a.NotNil(srv.listeners["tcp "+addr1].ws)
+	a.NotNil(srv.listeners["tcp "+addr2].tcp)
+	a.Len(srv.tcpListener, 1)
+	a.Len(srv.websocketServer, 1)
+	srv.listenerMu.Unlock()
+	a.False(canListen(addr1))
+	a.False(canListen(addr2))
+
+	// remove listeners
+	newCfg2 := newCfg
+	newCfg2.Listeners = []*config.ListenerConfig{{Address: addr2}}
+	rs, err = srv.ApplyConfig(newCfg2)
+	a.Nil(err)
+	a.Empty(rs.NewConnectionsOnly)
+	srv.listenerMu.Lock()
+	a.Len(srv.listeners, 1)
+	a.Len(srv.websocketServer, 0)
+	srv.listenerMu.Unlock()
+	a.True(canListen(addr1))
+	a.False(canListen(addr2))
+
+	// invalid config
+	invalid := newCfg2
+	invalid.MQTT.MaxInflight = 0
+	_, err = srv.ApplyConfig(invalid)
+	a.NotNil(err)
+	a.Equal(newCfg2, srv.GetConfig())
+	// unknown shared subscription strategy
+	unknown := newCfg2
+	unknown.MQTT.SharedSubscription.ShareNames = map[string]config.SharedSubStrategy{"group": "unknown"}
+	_, err = srv.ApplyConfig(unknown)
+	a.EqualError(err, "shared subscription strategy: unknown not found")
+	a.Equal(newCfg2, srv.GetConfig())
+}
+
+func TestServer_ApplyConfig_withoutConfigListeners(t *testing.T) {
+	a := assert.New(t)
+	srv := New(WithConfig(config.DefaultConfig()))
+	newCfg := config.DefaultConfig()
+	newCfg.Listeners = []*config.ListenerConfig{{Address: "127.0.0.1:1883"}}
+	newCfg.Log.Level = "debug"
+	rs, err := srv.ApplyConfig(newCfg)
+	a.Nil(err)
+	a.ElementsMatch([]string{"listeners", "log"}, rs.RestartRequired)
+	a.Empty(rs.NewConnectionsOnly)
+}
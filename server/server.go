@@ -2,11 +2,14 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
-	"math/rand"
+	"io"
 	"net"
 	"net/http"
+	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -35,6 +38,10 @@ var (
 	plugins              = make(map[string]NewPlugin)
 	topicAliasMgrFactory = make(map[string]NewTopicAliasManager)
 	persistenceFactories = make(map[string]NewPersistence)
+	// sharedSubBalancerFactories key by strategy name, the default random strategy is built in.
+	sharedSubBalancerFactories = map[string]NewSharedSubBalancer{
+		config.SharedSubStrategyRandom: newRandomBalancer,
+	}
 )
 
 func RegisterPersistenceFactory(name string, new NewPersistence) {
@@ -51,6 +58,15 @@ func RegisterTopicAliasMgrFactory(name string, new NewTopicAliasManager) {
 	topicAliasMgrFactory[name] = new
 }
 
+// RegisterSharedSubBalancerFactory registers the load balancing strategy of shared subscriptions.
+// The name can be used in config.MQTT.SharedSubscription.
+func RegisterSharedSubBalancerFactory(name string, new NewSharedSubBalancer) {
+	if _, ok := sharedSubBalancerFactories[name]; ok {
+		panic("duplicated shared subscription balancer factory: " + name)
+	}
+	sharedSubBalancerFactories[name] = new
+}
+
 func RegisterPlugin(name string, new NewPlugin) {
 	if _, ok := plugins[name]; ok {
 		panic("duplicated plugin: " + name)
@@ -86,14 +102,22 @@ type Server interface {
 	StatsManager() StatsReader
 	// Stop stop the server gracefully
 	Stop(ctx context.Context) error
-	// ApplyConfig will replace the config of the server
-	ApplyConfig(config config.Config)
+	// Drain stops accepting new connections, disconnects the connected clients over the configured drain window,
+	// and then stops the server. It returns immediately, use Done to wait for the server to stop.
+	Drain() error
+	// Done returns a channel that is closed after the server is stopped.
+	Done() <-chan struct{}
+	// ApplyConfig replaces the config of the server and reloads the listeners and plugins.
+	// The returned ReloadResult reports the changed fields that are not applied to all clients.
+	ApplyConfig(config config.Config) (ReloadResult, error)
 
 	ClientService() ClientService
 
 	SubscriptionService() SubscriptionService
 
 	RetainedService() RetainedService
+	// DelayedService returns the pending messages published to $delayed/{seconds}/{topic}.
+	DelayedService() DelayedService
 	// Plugins returns all enabled plugins
 	Plugins() []Plugin
 }
@@ -121,7 +145,10 @@ func (c *clientService) IterateClient(fn ClientIterateFn) {
 func (c *clientService) GetClient(clientID string) Client {
 	c.srv.mu.Lock()
 	defer c.srv.mu.Unlock()
-	return c.srv.clients[clientID]
+	if cli, ok := c.srv.clients[clientID]; ok {
+		return cli
+	}
+	return nil
 }
 
 func (c *clientService) GetSession(clientID string) (*gmqtt.Session, error) {
@@ -161,7 +188,19 @@ type server struct {
 	willMessage     map[string]*willMsg
 	tcpListener     []net.Listener //tcp listeners
 	websocketServer []*WsServer    //websocket serverStop
-	exitChan        chan struct{}
+	// listeners stores the listeners created from config.Listeners, key by network and address.
+	listeners map[string]*listener
+	// configListeners indicates whether to create the listeners from config.Listeners.
+	configListeners bool
+	// gard tcpListener, websocketServer & listeners
+	listenerMu sync.Mutex
+	// serialize ApplyConfig
+	reloadMu sync.Mutex
+	exitChan chan struct{}
+	// stopped is closed after the server is stopped.
+	stopped chan struct{}
+	// draining is 1 if the server is draining.
+	draining int32
 
 	retainedDB      retained.Store
 	subscriptionsDB subscription.Store //store subscriptions
@@ -170,10 +209,13 @@ type server struct {
 	queueStore   map[string]queue.Store
 	unackStore   map[string]unack.Store
 	sessionStore session.Store
+	delayedQueue *delayedQueue
 
-	// gard config
+	// gard config & publishRateLimiter
 	configMu sync.RWMutex
 	config   config.Config
+	// publishRateLimiter is created from config.MQTT.RateLimit when the first client is created after the config changes.
+	publishRateLimiter *publishRateLimiter
 	hooks    Hooks
 	plugins  []Plugin
 
@@ -181,6 +223,8 @@ type server struct {
 	publishService Publisher
 
 	newTopicAliasManager NewTopicAliasManager
+	// sharedSubBalancers key by share name, gard by mu
+	sharedSubBalancers map[string]SharedSubBalancer
 	// for testing
 	deliverMessageHandler func(srcClientID string, msg *gmqtt.Message) (matched bool)
 
@@ -200,15 +244,12 @@ func (srv *server) RetainedService() RetainedService {
 	return srv.retainedDB
 }
 
-func (srv *server) ClientService() ClientService {
-	return srv.clientService
+func (srv *server) DelayedService() DelayedService {
+	return srv.delayedQueue
 }
 
-func (srv *server) ApplyConfig(config config.Config) {
-	srv.configMu.Lock()
-	defer srv.configMu.Unlock()
-	srv.config = config
-
+func (srv *server) ClientService() ClientService {
+	return srv.clientService
 }
 
 func (srv *server) SubscriptionService() SubscriptionService {
@@ -238,6 +279,8 @@ const (
 
 // GetConfig returns the config of the server
 func (srv *server) GetConfig() config.Config {
+	srv.configMu.RLock()
+	defer srv.configMu.RUnlock()
 	return srv.config
 }
 
@@ -346,7 +389,7 @@ func (srv *server) registerClient(connect *packets.Connect, connackPpt *packets.
 			}
 			// use default expiry if the client version is version3.1.1
 			if client.version == packets.Version311 && !connect.CleanStart {
-				expiryInterval = uint32(srv.config.MQTT.SessionExpiry.Seconds())
+				expiryInterval = uint32(client.config.MQTT.SessionExpiry.Seconds())
 			} else if connect.Properties != nil {
 				willDelayInterval = convertUint32(connect.WillProperties.WillDelayInterval, 0)
 				expiryInterval = client.opts.SessionExpiry
@@ -454,7 +497,7 @@ func (srv *server) registerClient(connect *packets.Connect, connackPpt *packets.
 	}
 	if !sessionResume {
 		// create new session
-		qs, err = srv.persistence.NewQueueStore(srv.config, client.opts.ClientID)
+		qs, err = srv.persistence.NewQueueStore(client.config, client.opts.ClientID)
 		if err != nil {
 			return err
 		}
@@ -467,7 +510,7 @@ func (srv *server) registerClient(connect *packets.Connect, connackPpt *packets.
 			return err
 		}
 
-		ua, err = srv.persistence.NewUnackStore(srv.config, client.opts.ClientID)
+		ua, err = srv.persistence.NewUnackStore(client.config, client.opts.ClientID)
 		if err != nil {
 			return err
 		}
@@ -540,12 +583,12 @@ func (srv *server) unregisterClient(client *client) {
 					srv.mu.Lock()
 					defer srv.mu.Unlock()
 					if send {
-						srv.deliverMessageHandler(clientID, msg)
+						srv.dispatchMessageLocked(clientID, msg)
 					}
 					delete(srv.willMessage,clientID)
 				}(client.opts.ClientID)
 			} else {
-				srv.deliverMessageHandler(client.opts.ClientID, msg)
+				srv.dispatchMessageLocked(client.opts.ClientID, msg)
 			}
 		}
 		if storeSession {
@@ -573,7 +616,7 @@ func (srv *server) unregisterClient(client *client) {
 }
 
 func (srv *server) addMsgToQueueLocked(now time.Time, clientID string, msg *gmqtt.Message, sub *gmqtt.Subscription, ids []uint32, q queue.Store) {
-	if !srv.config.MQTT.QueueQos0Msg {
+	if !srv.GetConfig().MQTT.QueueQos0Msg {
 		// If the client with the clientID is not connected, skip qos0 messages.
 		if c := srv.clients[clientID]; c == nil && msg.QoS == packets.Qos0 {
 			return
@@ -610,6 +653,15 @@ func (srv *server) addMsgToQueueLocked(now time.Time, clientID string, msg *gmqt
 
 }
 
+// dispatchMessageLocked calls the OnMsgDispatched hook and then delivers the msg to the matched clients,
+// must call under srv.mu.Lock
+func (srv *server) dispatchMessageLocked(srcClientID string, msg *gmqtt.Message) (matched bool) {
+	if srv.hooks.OnMsgDispatched != nil {
+		srv.hooks.OnMsgDispatched(context.Background(), srcClientID, msg)
+	}
+	return srv.deliverMessageHandler(srcClientID, msg)
+}
+
 // deliverMessage send msg to matched client, must call under srv.mu.Lock
 func (srv *server) deliverMessage(srcClientID string, msg *gmqtt.Message) (matched bool) {
 	// subscriber (client id) list of shared subscriptions, key by share name.
@@ -623,6 +675,7 @@ func (srv *server) deliverMessage(srcClientID string, msg *gmqtt.Message) (match
 		subIDs []uint32
 	})
 	now := time.Now()
+	deliveryMode := srv.GetConfig().MQTT.DeliveryMode
 	// Iterate all matched topics
 	srv.subscriptionsDB.Iterate(func(clientID string, sub *gmqtt.Subscription) bool {
 		if sub.NoLocal && clientID == srcClientID {
@@ -637,7 +690,7 @@ func (srv *server) deliverMessage(srcClientID string, msg *gmqtt.Message) (match
 					sub      *gmqtt.Subscription
 				}{clientID: clientID, sub: sub})
 			} else {
-				if srv.config.MQTT.DeliveryMode == Overlap {
+				if deliveryMode == Overlap {
 					srv.addMsgToQueueLocked(now, clientID, msg.Copy(), sub, []uint32{sub.ID}, qs)
 				} else {
 					// OnlyOnce
@@ -662,7 +715,7 @@ func (srv *server) deliverMessage(srcClientID string, msg *gmqtt.Message) (match
 		MatchType: subscription.MatchFilter,
 		TopicName: msg.Topic,
 	})
-	if srv.config.MQTT.DeliveryMode == OnlyOnce {
+	if deliveryMode == OnlyOnce {
 		for clientID, v := range maxQos {
 			if qs := srv.queueStore[clientID]; qs != nil {
 				srv.addMsgToQueueLocked(now, clientID, msg.Copy(), v.sub, v.subIDs, qs)
@@ -670,16 +723,23 @@ func (srv *server) deliverMessage(srcClientID string, msg *gmqtt.Message) (match
 		}
 	}
 	// shared subscription
-	// TODO enable customize balance strategy of shared subscription
-	for _, v := range sharedList {
-		var rs struct {
-			clientID string
-			sub      *gmqtt.Subscription
+	for shareName, v := range sharedList {
+		members := make([]SharedSubMember, len(v))
+		for k, m := range v {
+			members[k] = SharedSubMember{
+				ClientID:     m.clientID,
+				Subscription: m.sub,
+				Connected:    srv.clients[m.clientID] != nil,
+				QueueLen:     srv.statsManager.getQueueLen(m.clientID),
+			}
 		}
-		// random
-		rs = v[rand.Intn(len(v))]
-		if c, ok := srv.queueStore[rs.clientID]; ok {
-			srv.addMsgToQueueLocked(now, rs.clientID, msg.Copy(), rs.sub, []uint32{rs.sub.ID}, c)
+		// the iteration order of subscriptionsDB is undefined.
+		sort.Slice(members, func(i, j int) bool {
+			return members[i].ClientID < members[j].ClientID
+		})
+		rs := members[srv.sharedSubBalancerLocked(shareName).Select(msg, members)]
+		if c, ok := srv.queueStore[rs.ClientID]; ok {
+			srv.addMsgToQueueLocked(now, rs.ClientID, msg.Copy(), rs.Subscription, []uint32{rs.Subscription.ID}, c)
 		}
 	}
 	return
@@ -763,19 +823,43 @@ type WsServer struct {
 	Path     string // Url path
 	CertFile string //TLS configration
 	KeyFile  string //TLS configration
+	// CertIdentity is the same as TLSListener.CertIdentity,
+	// it takes effect when the Server.TLSConfig verifies the client certificates.
+	CertIdentity string
+
+	// ln and tlsConfig are set if the server is created by NewListener.
+	ln        net.Listener
+	tlsConfig *tlsConfig
+	// proxyProtocol indicates whether to honor the X-Forwarded-For header sent by the trustedProxies.
+	proxyProtocol  bool
+	trustedProxies []*net.IPNet
+	// upgrader and maxMessageSize are set from the websocket options if the server is created by NewListener.
+	upgrader       *websocket.Upgrader
+	maxMessageSize int64
+}
+
+func (ws *WsServer) certIdentity() string {
+	if ws.tlsConfig != nil {
+		return ws.tlsConfig.getCertIdentity()
+	}
+	return ws.CertIdentity
 }
 
 func defaultServer() *server {
 	srv := &server{
 		status:         serverStatusInit,
 		exitChan:       make(chan struct{}),
+		stopped:        make(chan struct{}),
 		clients:        make(map[string]*client),
 		offlineClients: make(map[string]time.Time),
 		willMessage:    make(map[string]*willMsg),
+		listeners:      make(map[string]*listener),
 		retainedDB:     retained_trie.NewStore(),
 		config:         config.DefaultConfig(),
 		queueStore:     make(map[string]queue.Store),
 		unackStore:     make(map[string]unack.Store),
+
+		sharedSubBalancers: make(map[string]SharedSubBalancer),
 	}
 
 	srv.deliverMessageHandler = srv.deliverMessage
@@ -827,6 +911,19 @@ func (srv *server) init(opts ...Options) (err error) {
 		return err
 	}
 	srv.sessionStore = st
+	srv.retainedDB, err = newRetainedStore(srv.persistence, srv.config)
+	if err != nil {
+		return err
+	}
+	ds, err := newDelayedStore(srv.persistence, srv.config)
+	if err != nil {
+		return err
+	}
+	srv.delayedQueue, err = newDelayedQueue(srv, ds)
+	if err != nil {
+		return err
+	}
+	zaplog.Info("init delayed store succeeded", zap.String("type", peType), zap.Int("delayed_total", srv.delayedQueue.Len()))
 	var sts []*gmqtt.Session
 	var cids []string
 
@@ -874,6 +971,10 @@ func (srv *server) init(opts ...Options) (err error) {
 	} else {
 		return fmt.Errorf("topic alias manager : %s not found", srv.config.TopicAliasManager.Type)
 	}
+	err = checkSharedSubStrategies(srv.config.MQTT.SharedSubscription)
+	if err != nil {
+		return err
+	}
 
 	return srv.loadPlugins()
 }
@@ -915,28 +1016,70 @@ func (srv *server) serveTCP(l net.Listener) {
 			}
 			return
 		}
-		if srv.hooks.OnAccept != nil {
-			if !srv.hooks.OnAccept(context.Background(), rw) {
-				rw.Close()
-				continue
-			}
+		if pl, ok := l.(*ProxyListener); ok {
+			// reading the PROXY header blocks, so it is done in a new goroutine.
+			go srv.serveProxyConn(pl, rw)
+			continue
 		}
-		client, err := srv.newClient(rw)
-		if err != nil {
+		var certIdentity string
+		if tl, ok := l.(CertIdentityListener); ok {
+			certIdentity = tl.CertIdentity()
+		}
+		if err := srv.serveConn(rw, certIdentity); err != nil {
 			zaplog.Error("new client fail", zap.Error(err))
 			return
 		}
-		go client.serve()
 	}
 }
 
+// serveProxyConn reads the PROXY header of the connection accepted by the ProxyListener, and then serves it.
+func (srv *server) serveProxyConn(l *ProxyListener, rw net.Conn) {
+	conn, err := l.handshake(rw)
+	if err != nil {
+		zaplog.Warn("fail to read the PROXY protocol header",
+			zap.String("remote_addr", rw.RemoteAddr().String()),
+			zap.Error(err))
+		rw.Close()
+		return
+	}
+	if err := srv.serveConn(conn, l.CertIdentity()); err != nil {
+		zaplog.Error("new client fail", zap.Error(err))
+	}
+}
+
+// rejectConn closes the connection which will not be served and calls the OnAcceptRejected hook.
+func (srv *server) rejectConn(rw net.Conn) {
+	rw.Close()
+	if srv.hooks.OnAcceptRejected != nil {
+		srv.hooks.OnAcceptRejected(context.Background(), rw)
+	}
+}
+
+// serveConn calls the OnAccept hook and serves the accepted connection.
+func (srv *server) serveConn(rw net.Conn, certIdentity string) error {
+	if srv.hooks.OnAccept != nil {
+		if !srv.hooks.OnAccept(context.Background(), rw) {
+			srv.rejectConn(rw)
+			return nil
+		}
+	}
+	client, err := srv.newClient(rw)
+	if err != nil {
+		srv.rejectConn(rw)
+		return err
+	}
+	client.certIdentity = certIdentity
+	go client.serve()
+	return nil
+}
+
 var defaultUpgrader = &websocket.Upgrader{
 	ReadBufferSize:  readBufferSize,
 	WriteBufferSize: writeBufferSize,
 	CheckOrigin: func(r *http.Request) bool {
 		return true
 	},
-	Subprotocols: []string{"mqtt"},
+	Subprotocols: config.DefaultWebsocketSubprotocols,
 }
 
 //实现io.ReadWriter接口
@@ -944,6 +1087,22 @@ var defaultUpgrader = &websocket.Upgrader{
 type wsConn struct {
 	net.Conn
 	c *websocket.Conn
+	// tlsState is the TLS connection state of the underlying connection, nil if it is not TLS.
+	tlsState *tls.ConnectionState
+	// remoteAddr is the client address in the X-Forwarded-For header, nil if the request is not forwarded.
+	remoteAddr net.Addr
+	// request is the HTTP upgrade request.
+	request *http.Request
+	// r is the reader of the current message, a message may be read by several Read calls.
+	r io.Reader
+}
+
+// RemoteAddr returns the client address in the X-Forwarded-For header if the request is from a trusted proxy.
+func (ws *wsConn) RemoteAddr() net.Addr {
+	if ws.remoteAddr != nil {
+		return ws.remoteAddr
+	}
+	return ws.Conn.RemoteAddr()
 }
 
 func (ws *wsConn) Close() error {
@@ -951,14 +1110,27 @@ func (ws *wsConn) Close() error {
 }
 
 func (ws *wsConn) Read(p []byte) (n int, err error) {
-	msgType, r, err := ws.c.NextReader()
-	if err != nil {
-		return 0, err
-	}
-	if msgType != websocket.BinaryMessage {
-		return 0, ErrInvalWsMsgType
+	for {
+		if ws.r == nil {
+			var msgType int
+			msgType, ws.r, err = ws.c.NextReader()
+			if err != nil {
+				return 0, err
+			}
+			if msgType != websocket.BinaryMessage {
+				return 0, ErrInvalWsMsgType
+			}
+		}
+		n, err = ws.r.Read(p)
+		if err == io.EOF {
+			ws.r = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
 	}
-	return r.Read(p)
 }
 
 func (ws *wsConn) Write(p []byte) (n int, err error) {
@@ -971,7 +1143,13 @@ func (ws *wsConn) Write(p []byte) (n int, err error) {
 
 func (srv *server) serveWebSocket(ws *WsServer) {
 	var err error
-	if ws.CertFile != "" && ws.KeyFile != "" {
+	if ws.ln != nil && ws.tlsConfig != nil {
+		err = ws.Server.ServeTLS(ws.ln, "", "")
+	} else if ws.ln != nil {
+		err = ws.Server.Serve(ws.ln)
+	} else if ws.Server.TLSConfig != nil && len(ws.Server.TLSConfig.Certificates) != 0 {
+		err = ws.Server.ListenAndServeTLS("", "")
+	} else if ws.CertFile != "" && ws.KeyFile != "" {
 		err = ws.Server.ListenAndServeTLS(ws.CertFile, ws.KeyFile)
 	} else {
 		err = ws.Server.ListenAndServe()
@@ -984,6 +1162,10 @@ func (srv *server) serveWebSocket(ws *WsServer) {
 func (srv *server) newClient(c net.Conn) (*client, error) {
 	srv.configMu.Lock()
 	cfg := srv.config
+	if srv.publishRateLimiter == nil || !reflect.DeepEqual(srv.publishRateLimiter.config, cfg.MQTT.RateLimit) {
+		srv.publishRateLimiter = newPublishRateLimiter(cfg.MQTT.RateLimit)
+	}
+	rl := srv.publishRateLimiter
 	srv.configMu.Unlock()
 	client := &client{
 		server:        srv,
@@ -992,6 +1174,7 @@ func (srv *server) newClient(c net.Conn) (*client, error) {
 		bufw:          newBufioWriterSize(c, writeBufferSize),
 		close:         make(chan struct{}),
 		connected:     make(chan struct{}),
+		continueAuth:  make(chan struct{}, 1),
 		error:         make(chan error, 1),
 		in:            make(chan packets.Packet, readBufferSize),
 		out:           make(chan packets.Packet, writeBufferSize),
@@ -999,6 +1182,7 @@ func (srv *server) newClient(c net.Conn) (*client, error) {
 		opts:          &ClientOptions{},
 		cleanWillFlag: false,
 		config:        cfg,
+		sharedLimiter: rl,
 	}
 	client.packetReader = packets.NewReader(client.bufr)
 	client.packetWriter = packets.NewWriter(client.bufw)
@@ -1011,6 +1195,7 @@ func (srv *server) initPluginHooks() error {
 	zaplog.Info("init plugin hook wrappers")
 	var (
 		onAcceptWrappers           []OnAcceptWrapper
+		onAcceptRejectedWrappers   []OnAcceptRejectedWrapper
 		onBasicAuthWrappers        []OnBasicAuthWrapper
 		onEnhancedAuthWrappers     []OnEnhancedAuthWrapper
 		onReAuthWrappers           []OnReAuthWrapper
@@ -1023,6 +1208,7 @@ func (srv *server) initPluginHooks() error {
 		onUnsubscribeWrappers      []OnUnsubscribeWrapper
 		onUnsubscribedWrappers     []OnUnsubscribedWrapper
 		onMsgArrivedWrappers       []OnMsgArrivedWrapper
+		onMsgDispatchedWrappers    []OnMsgDispatchedWrapper
 		OnDeliveredWrappers        []OnDeliveredWrapper
 		OnClosedWrappers           []OnClosedWrapper
 		onStopWrappers             []OnStopWrapper
@@ -1048,6 +1234,9 @@ func (srv *server) initPluginHooks() error {
 		if hooks.OnAcceptWrapper != nil {
 			onAcceptWrappers = append(onAcceptWrappers, hooks.OnAcceptWrapper)
 		}
+		if hooks.OnAcceptRejectedWrapper != nil {
+			onAcceptRejectedWrappers = append(onAcceptRejectedWrappers, hooks.OnAcceptRejectedWrapper)
+		}
 		if hooks.OnBasicAuthWrapper != nil {
 			onBasicAuthWrappers = append(onBasicAuthWrappers, hooks.OnBasicAuthWrapper)
 		}
@@ -1084,6 +1273,9 @@ func (srv *server) initPluginHooks() error {
 		if hooks.OnMsgArrivedWrapper != nil {
 			onMsgArrivedWrappers = append(onMsgArrivedWrappers, hooks.OnMsgArrivedWrapper)
 		}
+		if hooks.OnMsgDispatchedWrapper != nil {
+			onMsgDispatchedWrappers = append(onMsgDispatchedWrappers, hooks.OnMsgDispatchedWrapper)
+		}
 		if hooks.OnMsgDroppedWrapper != nil {
 			onMsgDroppedWrappers = append(onMsgDroppedWrappers, hooks.OnMsgDroppedWrapper)
 		}
@@ -1106,6 +1298,13 @@ func (srv *server) initPluginHooks() error {
 		}
 		srv.hooks.OnAccept = onAccept
 	}
+	if onAcceptRejectedWrappers != nil {
+		onAcceptRejected := func(ctx context.Context, conn net.Conn) {}
+		for i := len(onAcceptRejectedWrappers); i > 0; i-- {
+			onAcceptRejected = onAcceptRejectedWrappers[i-1](onAcceptRejected)
+		}
+		srv.hooks.OnAcceptRejected = onAcceptRejected
+	}
 	if onBasicAuthWrappers != nil {
 		onBasicAuth := func(ctx context.Context, client Client, req *ConnectRequest) error {
 			return nil
@@ -1116,16 +1315,29 @@ func (srv *server) initPluginHooks() error {
 		srv.hooks.OnBasicAuth = onBasicAuth
 	}
 	if onEnhancedAuthWrappers != nil {
+		// no plugin supports the auth method.
 		onEnhancedAuth := func(ctx context.Context, client Client, req *ConnectRequest) (resp *EnhancedAuthResponse, err error) {
-			return &EnhancedAuthResponse{
-				Continue: false,
-			}, nil
+			return nil, &codes.Error{
+				Code: codes.BadAuthMethod,
+			}
 		}
 		for i := len(onEnhancedAuthWrappers); i > 0; i-- {
 			onEnhancedAuth = onEnhancedAuthWrappers[i-1](onEnhancedAuth)
 		}
 		srv.hooks.OnEnhancedAuth = onEnhancedAuth
 	}
+	if onReAuthWrappers != nil {
+		// no plugin supports the auth method.
+		onReAuth := func(ctx context.Context, client Client, auth *packets.Auth) (*AuthResponse, error) {
+			return nil, &codes.Error{
+				Code: codes.BadAuthMethod,
+			}
+		}
+		for i := len(onReAuthWrappers); i > 0; i-- {
+			onReAuth = onReAuthWrappers[i-1](onReAuth)
+		}
+		srv.hooks.OnReAuth = onReAuth
+	}
 
 	if onConnectedWrappers != nil {
 		onConnected := func(ctx context.Context, client Client) {}
@@ -1196,6 +1408,13 @@ func (srv *server) initPluginHooks() error {
 		}
 		srv.hooks.OnMsgArrived = onMsgArrived
 	}
+	if onMsgDispatchedWrappers != nil {
+		onMsgDispatched := func(ctx context.Context, srcClientID string, msg *gmqtt.Message) {}
+		for i := len(onMsgDispatchedWrappers); i > 0; i-- {
+			onMsgDispatched = onMsgDispatchedWrappers[i-1](onMsgDispatched)
+		}
+		srv.hooks.OnMsgDispatched = onMsgDispatched
+	}
 	if OnDeliveredWrappers != nil {
 		OnDelivered := func(ctx context.Context, client Client, msg *gmqtt.Message) {}
 		for i := len(OnDeliveredWrappers); i > 0; i-- {
@@ -1238,20 +1457,31 @@ func (srv *server) loadPlugins() error {
 	return nil
 }
 
-func (srv *server) wsHandler() http.HandlerFunc {
+func (srv *server) wsHandler(ws *WsServer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		c, err := defaultUpgrader.Upgrade(w, r, nil)
+		upgrader := defaultUpgrader
+		if ws.upgrader != nil {
+			upgrader = ws.upgrader
+		}
+		c, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
 			zaplog.Error("websocket upgrade error", zap.String("Msg", err.Error()))
 			return
 		}
 		defer c.Close()
-		conn := &wsConn{c.UnderlyingConn(), c}
+		if ws.maxMessageSize != 0 {
+			c.SetReadLimit(ws.maxMessageSize)
+		}
+		conn := &wsConn{Conn: c.UnderlyingConn(), c: c, tlsState: r.TLS, request: r}
+		if ws.proxyProtocol {
+			conn.remoteAddr = forwardedAddr(ws.trustedProxies, r)
+		}
 		client, err := srv.newClient(conn)
 		if err != nil {
 			zaplog.Error("new client fail", zap.Error(err))
 			return
 		}
+		client.certIdentity = ws.certIdentity()
 		client.serve()
 	}
 }
@@ -1262,6 +1492,16 @@ func (srv *server) Run() (err error) {
 	if err != nil {
 		return err
 	}
+	srv.listenerMu.Lock()
+	defer srv.listenerMu.Unlock()
+	if srv.configListeners {
+		for _, v := range srv.GetConfig().Listeners {
+			err = srv.listenLocked(v)
+			if err != nil {
+				return err
+			}
+		}
+	}
 	var tcps []string
 	var ws []string
 	for _, v := range srv.tcpListener {
@@ -1273,20 +1513,42 @@ func (srv *server) Run() (err error) {
 	zaplog.Info("starting gmqtt server", zap.Strings("tcp server listen on", tcps), zap.Strings("websocket server listen on", ws))
 
 	srv.status = serverStatusStarted
-	srv.wg.Add(1)
+	srv.wg.Add(2)
 	go srv.eventLoop()
+	go srv.delayedQueue.run()
 	for _, ln := range srv.tcpListener {
-		go srv.serveTCP(ln)
+		srv.serveListener(ln, nil)
 	}
 	for _, server := range srv.websocketServer {
-		mux := http.NewServeMux()
-		mux.Handle(server.Path, srv.wsHandler())
-		server.Server.Handler = mux
-		go srv.serveWebSocket(server)
+		srv.serveListener(nil, server)
 	}
 	return nil
 }
 
+// serveListener starts serving the tcp listener or the websocket server.
+func (srv *server) serveListener(ln net.Listener, ws *WsServer) {
+	if ln != nil {
+		go srv.serveTCP(ln)
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle(ws.Path, srv.wsHandler(ws))
+	ws.Server.Handler = mux
+	go srv.serveWebSocket(ws)
+}
+
+// closeListeners closes all opening TCP listeners and shuts down all opening websocket servers.
+func (srv *server) closeListeners(ctx context.Context) {
+	srv.listenerMu.Lock()
+	defer srv.listenerMu.Unlock()
+	for _, l := range srv.tcpListener {
+		l.Close()
+	}
+	for _, ws := range srv.websocketServer {
+		ws.Server.Shutdown(ctx)
+	}
+}
+
 // Stop gracefully stops the mqtt server by the following steps:
 //  1. Closing all opening TCP listeners and shutting down all opening websocket servers
 //  2. Closing all idle connections
@@ -1304,13 +1566,9 @@ func (srv *server) Stop(ctx context.Context) error {
 	default:
 		close(srv.exitChan)
 	}
+	defer close(srv.stopped)
 	srv.wg.Wait()
-	for _, l := range srv.tcpListener {
-		l.Close()
-	}
-	for _, ws := range srv.websocketServer {
-		ws.Server.Shutdown(ctx)
-	}
+	srv.closeListeners(ctx)
 
 	//关闭所有的client
 	//closing all idle clients
@@ -1350,6 +1608,12 @@ func (srv *server) Stop(ctx context.Context) error {
 		if srv.hooks.OnStop != nil {
 			srv.hooks.OnStop(context.Background())
 		}
+		if srv.persistence != nil {
+			err := srv.persistence.Close()
+			if err != nil {
+				zaplog.Warn("persistence close error", zap.String("error", err.Error()))
+			}
+		}
 		return nil
 	}
 
@@ -90,10 +90,41 @@ func (mr *MockServerMockRecorder) Stop(ctx interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stop", reflect.TypeOf((*MockServer)(nil).Stop), ctx)
 }
 
+// Drain mocks base method
+func (m *MockServer) Drain() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Drain")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Drain indicates an expected call of Drain
+func (mr *MockServerMockRecorder) Drain() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Drain", reflect.TypeOf((*MockServer)(nil).Drain))
+}
+
+// Done mocks base method
+func (m *MockServer) Done() <-chan struct{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Done")
+	ret0, _ := ret[0].(<-chan struct{})
+	return ret0
+}
+
+// Done indicates an expected call of Done
+func (mr *MockServerMockRecorder) Done() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Done", reflect.TypeOf((*MockServer)(nil).Done))
+}
+
 // ApplyConfig mocks base method
-func (m *MockServer) ApplyConfig(config config.Config) {
+func (m *MockServer) ApplyConfig(config config.Config) (ReloadResult, error) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "ApplyConfig", config)
+	ret := m.ctrl.Call(m, "ApplyConfig", config)
+	ret0, _ := ret[0].(ReloadResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // ApplyConfig indicates an expected call of ApplyConfig
@@ -144,6 +175,20 @@ func (mr *MockServerMockRecorder) RetainedService() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetainedService", reflect.TypeOf((*MockServer)(nil).RetainedService))
 }
 
+// DelayedService mocks base method
+func (m *MockServer) DelayedService() DelayedService {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DelayedService")
+	ret0, _ := ret[0].(DelayedService)
+	return ret0
+}
+
+// DelayedService indicates an expected call of DelayedService
+func (mr *MockServerMockRecorder) DelayedService() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DelayedService", reflect.TypeOf((*MockServer)(nil).DelayedService))
+}
+
 // Plugins mocks base method
 func (m *MockServer) Plugins() []Plugin {
 	m.ctrl.T.Helper()
@@ -2,6 +2,7 @@ package server
 
 import (
 	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/persistence/delayed"
 	"github.com/DrmagicE/gmqtt/persistence/session"
 	"github.com/DrmagicE/gmqtt/persistence/subscription"
 	"github.com/DrmagicE/gmqtt/retained"
@@ -10,10 +11,18 @@ import (
 // Publisher provides the ability to Publish messages to the broker.
 type Publisher interface {
 	// Publish Publish a message to broker.
-	// Calling this method will not trigger OnMsgArrived hook.
+	// Calling this method will not trigger OnMsgArrived hook, but will trigger OnMsgDispatched hook.
 	Publish(message *gmqtt.Message)
 }
 
+// LocalPublisher is implemented by the Publisher returned by Server.Publisher().
+// It is used by the plugins which forward messages to other brokers to publish the received messages
+// without forwarding them again.
+type LocalPublisher interface {
+	// PublishLocal publishes a message to the local subscribers without triggering the OnMsgDispatched hook.
+	PublishLocal(message *gmqtt.Message)
+}
+
 // ClientIterateFn is the callback function used by ClientService.IterateClient
 // Return false means to stop the iteration.
 type ClientIterateFn = func(client Client) bool
@@ -52,3 +61,20 @@ type SubscriptionService interface {
 type RetainedService interface {
 	retained.Store
 }
+
+// DelayedService provides the ability to query and cancel the pending delayed messages
+// which are published to $delayed/{seconds}/{topic}.
+type DelayedService interface {
+	// Iterate iterates the pending delayed messages in the order of the delivery time.
+	// If callback return false, the iteration will be stopped.
+	// The messages must not be modified.
+	Iterate(fn delayed.IterateFn)
+	// Get returns the pending delayed message for the given id, returns nil if not found.
+	// The message must not be modified.
+	Get(id string) *delayed.Message
+	// Cancel removes the pending delayed message for the given id, the message will not be delivered.
+	// It returns false if the message is not found.
+	Cancel(id string) (bool, error)
+	// Len returns the number of pending delayed messages.
+	Len() int
+}
@@ -6,6 +6,7 @@ package server
 
 import (
 	gmqtt "github.com/DrmagicE/gmqtt"
+	delayed "github.com/DrmagicE/gmqtt/persistence/delayed"
 	session "github.com/DrmagicE/gmqtt/persistence/session"
 	subscription "github.com/DrmagicE/gmqtt/persistence/subscription"
 	retained "github.com/DrmagicE/gmqtt/retained"
@@ -353,3 +354,81 @@ func (mr *MockRetainedServiceMockRecorder) Iterate(fn interface{}) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Iterate", reflect.TypeOf((*MockRetainedService)(nil).Iterate), fn)
 }
+
+// MockDelayedService is a mock of DelayedService interface
+type MockDelayedService struct {
+	ctrl     *gomock.Controller
+	recorder *MockDelayedServiceMockRecorder
+}
+
+// MockDelayedServiceMockRecorder is the mock recorder for MockDelayedService
+type MockDelayedServiceMockRecorder struct {
+	mock *MockDelayedService
+}
+
+// NewMockDelayedService creates a new mock instance
+func NewMockDelayedService(ctrl *gomock.Controller) *MockDelayedService {
+	mock := &MockDelayedService{ctrl: ctrl}
+	mock.recorder = &MockDelayedServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockDelayedService) EXPECT() *MockDelayedServiceMockRecorder {
+	return m.recorder
+}
+
+// Iterate mocks base method
+func (m *MockDelayedService) Iterate(fn delayed.IterateFn) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Iterate", fn)
+}
+
+// Iterate indicates an expected call of Iterate
+func (mr *MockDelayedServiceMockRecorder) Iterate(fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Iterate", reflect.TypeOf((*MockDelayedService)(nil).Iterate), fn)
+}
+
+// Get mocks base method
+func (m *MockDelayedService) Get(id string) *delayed.Message {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", id)
+	ret0, _ := ret[0].(*delayed.Message)
+	return ret0
+}
+
+// Get indicates an expected call of Get
+func (mr *MockDelayedServiceMockRecorder) Get(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockDelayedService)(nil).Get), id)
+}
+
+// Cancel mocks base method
+func (m *MockDelayedService) Cancel(id string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Cancel", id)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Cancel indicates an expected call of Cancel
+func (mr *MockDelayedServiceMockRecorder) Cancel(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Cancel", reflect.TypeOf((*MockDelayedService)(nil).Cancel), id)
+}
+
+// Len mocks base method
+func (m *MockDelayedService) Len() int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Len")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// Len indicates an expected call of Len
+func (mr *MockDelayedServiceMockRecorder) Len() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Len", reflect.TypeOf((*MockDelayedService)(nil).Len))
+}
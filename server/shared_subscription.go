@@ -0,0 +1,80 @@
+package server
+
+import (
+	"fmt"
+	"math/rand"
+
+	"go.uber.org/zap"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/config"
+)
+
+// NewSharedSubBalancer is the constructor of SharedSubBalancer.
+// It will be called once for each share name.
+type NewSharedSubBalancer func(config config.Config, shareName string) SharedSubBalancer
+
+// SharedSubMember represents a subscriber of a shared subscription group.
+type SharedSubMember struct {
+	ClientID     string
+	Subscription *gmqtt.Subscription
+	// Connected indicates whether the client is online.
+	Connected bool
+	// QueueLen is the current length of the message queue of the client.
+	QueueLen uint64
+}
+
+// SharedSubBalancer decides which member of a shared subscription group will receive the message.
+// see sharedsub for more details.
+type SharedSubBalancer interface {
+	// Select returns the index of the member who will receive the message.
+	// The members are sorted by client id and never empty.
+	// Calls of Select for the same share name are serialized by the server.
+	Select(msg *gmqtt.Message, members []SharedSubMember) int
+}
+
+func newRandomBalancer(config config.Config, shareName string) SharedSubBalancer {
+	return randomBalancer{}
+}
+
+// randomBalancer is the default strategy which selects a member randomly.
+type randomBalancer struct{}
+
+func (randomBalancer) Select(msg *gmqtt.Message, members []SharedSubMember) int {
+	return rand.Intn(len(members))
+}
+
+// checkSharedSubStrategies returns an error if the default strategy or any of the per share name strategies is not registered.
+func checkSharedSubStrategies(c config.SharedSubscription) error {
+	strategies := []string{c.Strategy}
+	for _, v := range c.ShareNames {
+		if v != "" {
+			strategies = append(strategies, v)
+		}
+	}
+	for _, st := range strategies {
+		if sharedSubBalancerFactories[st] == nil {
+			return fmt.Errorf("shared subscription strategy: %s not found", st)
+		}
+	}
+	return nil
+}
+
+// sharedSubBalancerLocked returns the balancer for the given share name, must call under srv.mu.Lock
+func (srv *server) sharedSubBalancerLocked(shareName string) SharedSubBalancer {
+	if b, ok := srv.sharedSubBalancers[shareName]; ok {
+		return b
+	}
+	cfg := srv.GetConfig()
+	st := cfg.MQTT.SharedSubscription.GetStrategy(shareName)
+	newBalancer := sharedSubBalancerFactories[st]
+	if newBalancer == nil {
+		zaplog.Warn("shared subscription strategy not found, fallback to random",
+			zap.String("strategy", st),
+			zap.String("share_name", shareName))
+		newBalancer = newRandomBalancer
+	}
+	b := newBalancer(cfg, shareName)
+	srv.sharedSubBalancers[shareName] = b
+	return b
+}
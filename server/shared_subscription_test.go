@@ -0,0 +1,36 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/config"
+)
+
+func TestRandomBalancer_builtIn(t *testing.T) {
+	a := assert.New(t)
+	// the default strategy is available without importing the sharedsub package.
+	srv := &server{config: config.DefaultConfig(), sharedSubBalancers: make(map[string]SharedSubBalancer)}
+	b := srv.sharedSubBalancerLocked("group")
+	a.IsType(randomBalancer{}, b)
+	members := []SharedSubMember{{ClientID: "a"}, {ClientID: "b"}}
+	for i := 0; i < 10; i++ {
+		idx := b.Select(&gmqtt.Message{}, members)
+		a.True(idx >= 0 && idx < len(members))
+	}
+}
+
+func TestServer_sharedSubBalancerLocked_unregistered(t *testing.T) {
+	a := assert.New(t)
+	cfg := config.DefaultConfig()
+	cfg.MQTT.SharedSubscription.ShareNames = map[string]config.SharedSubStrategy{"group": "unregistered"}
+	srv := &server{config: cfg, sharedSubBalancers: make(map[string]SharedSubBalancer)}
+	var b SharedSubBalancer
+	a.NotPanics(func() {
+		b = srv.sharedSubBalancerLocked("group")
+	})
+	a.IsType(randomBalancer{}, b)
+	a.Equal(b, srv.sharedSubBalancers["group"])
+}
@@ -271,6 +271,7 @@ type PacketBytes struct {
 
 func (p *PacketBytes) copy() PacketBytes {
 	return PacketBytes{
+		Auth:        atomic.LoadUint64(&p.Auth),
 		Connect:     atomic.LoadUint64(&p.Connect),
 		Connack:     atomic.LoadUint64(&p.Connack),
 		Disconnect:  atomic.LoadUint64(&p.Disconnect),
@@ -400,6 +401,16 @@ func (s *statsManager) decQueueLen(clientID string, delta uint64) {
 	atomic.AddUint64(&s.totalStats.MessageStats.QueuedCurrent, ^uint64(delta-1))
 }
 
+// getQueueLen returns the current queue length of the client.
+func (s *statsManager) getQueueLen(clientID string) uint64 {
+	s.clientMu.Lock()
+	defer s.clientMu.Unlock()
+	if stats := s.clientStats[clientID]; stats != nil {
+		return atomic.LoadUint64(&stats.MessageStats.QueuedCurrent)
+	}
+	return 0
+}
+
 func (m *MessageStats) copy() *MessageStats {
 	return &MessageStats{
 		Qos0: MessageQosStats{
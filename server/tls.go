@@ -0,0 +1,139 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"sync/atomic"
+
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+// tlsConfig holds the TLS config of a listener, which can be replaced when reloading.
+type tlsConfig struct {
+	config       atomic.Value // *tls.Config
+	certIdentity atomic.Value // string
+}
+
+func newTLSConfig(opts *config.TLSOptions) (*tlsConfig, error) {
+	t := &tlsConfig{}
+	return t, t.reload(opts)
+}
+
+// reload re-reads the certificates, the new config only applies to new connections.
+func (t *tlsConfig) reload(opts *config.TLSOptions) error {
+	c, err := opts.TLSConfig()
+	if err != nil {
+		return err
+	}
+	t.config.Store(c)
+	t.certIdentity.Store(opts.CertIdentity)
+	return nil
+}
+
+func (t *tlsConfig) getCertIdentity() string {
+	return t.certIdentity.Load().(string)
+}
+
+// serverConfig returns the *tls.Config which always uses the latest loaded config for handshakes.
+func (t *tlsConfig) serverConfig() *tls.Config {
+	return &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return t.config.Load().(*tls.Config), nil
+		},
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return &t.config.Load().(*tls.Config).Certificates[0], nil
+		},
+	}
+}
+
+// CertIdentityListener is implemented by the TLS listeners which can map the verified client certificate
+// to the client identity, e.g. TLSListener and the QUIC listener.
+type CertIdentityListener interface {
+	net.Listener
+	// CertIdentity returns config.CertIdentityUsername, config.CertIdentityClientID or empty.
+	CertIdentity() string
+}
+
+// TLSConn is implemented by the connections which carry the TLS connection state, e.g. *tls.Conn and the QUIC connection.
+type TLSConn interface {
+	net.Conn
+	ConnectionState() tls.ConnectionState
+}
+
+// TLSListener is a TLS listener which can map the verified client certificate to the client identity.
+type TLSListener struct {
+	net.Listener
+	config *tlsConfig
+}
+
+// NewTLSListener creates a TLS listener for the given listener config.
+func NewTLSListener(inner net.Listener, opts *config.TLSOptions) (*TLSListener, error) {
+	c, err := newTLSConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &TLSListener{
+		Listener: tls.NewListener(inner, c.serverConfig()),
+		config:   c,
+	}, nil
+}
+
+// CertIdentity indicates whether to use the common name of the verified client certificate
+// as the username or client id. Possible values: config.CertIdentityUsername | config.CertIdentityClientID
+func (l *TLSListener) CertIdentity() string {
+	return l.config.getCertIdentity()
+}
+
+// Reload re-reads the certificates and applies the new TLS options.
+// The established connections are not affected.
+func (l *TLSListener) Reload(opts *config.TLSOptions) error {
+	return l.config.reload(opts)
+}
+
+// ConnectionState returns the TLS connection state of the connection returned by Client.Connection().
+// The second return value is false if the connection is not a TLS connection.
+func ConnectionState(conn net.Conn) (tls.ConnectionState, bool) {
+	switch c := conn.(type) {
+	case *wsConn:
+		if c.tlsState != nil {
+			return *c.tlsState, true
+		}
+	case *ProxyConn:
+		return ConnectionState(c.Conn)
+	case TLSConn:
+		return c.ConnectionState(), true
+	}
+	return tls.ConnectionState{}, false
+}
+
+// VerifiedCertificate returns the verified client certificate of the connection returned by Client.Connection().
+// It returns nil if the client does not present a certificate or the certificate is not verified.
+// The subject and SAN of the certificate can be used to identify the client.
+func VerifiedCertificate(conn net.Conn) *x509.Certificate {
+	state, ok := ConnectionState(conn)
+	if !ok || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return state.VerifiedChains[0][0]
+}
+
+// applyCertIdentity overwrites the username or client id of the CONNECT packet
+// with the common name of the verified client certificate.
+func (client *client) applyCertIdentity(conn *packets.Connect) {
+	if client.certIdentity == "" {
+		return
+	}
+	cert := VerifiedCertificate(client.rwc)
+	if cert == nil || cert.Subject.CommonName == "" {
+		return
+	}
+	switch client.certIdentity {
+	case config.CertIdentityUsername:
+		conn.UsernameFlag = true
+		conn.Username = []byte(cert.Subject.CommonName)
+	case config.CertIdentityClientID:
+		conn.ClientID = []byte(cert.Subject.CommonName)
+	}
+}
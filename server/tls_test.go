@@ -0,0 +1,223 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+// newTestCert creates a certificate signed by parent, or a self-signed certificate if parent is nil.
+func newTestCert(t *testing.T, cn string, parent *tls.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{cn + ".example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	}
+	signer, signerKey := tmpl, interface{}(key)
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// tlsPipe returns the server side of a TLS connection after the handshake.
+func tlsPipe(t *testing.T, serverConfig, clientConfig *tls.Config) *tls.Conn {
+	c, s := net.Pipe()
+	sc := tls.Server(s, serverConfig)
+	cc := tls.Client(c, clientConfig)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- cc.Handshake()
+	}()
+	if err := sc.Handshake(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	return sc
+}
+
+func TestVerifiedCertificate(t *testing.T) {
+	a := assert.New(t)
+	ca := newTestCert(t, "ca", nil)
+	serverCert := newTestCert(t, "server", &ca)
+	clientCert := newTestCert(t, "device1", &ca)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+	serverConfig := &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+	}
+
+	conn := tlsPipe(t, serverConfig, &tls.Config{
+		RootCAs:      pool,
+		ServerName:   "server.example.com",
+		Certificates: []tls.Certificate{clientCert},
+	})
+	defer conn.Close()
+	cert := VerifiedCertificate(conn)
+	a.NotNil(cert)
+	a.Equal("device1", cert.Subject.CommonName)
+	a.Equal([]string{"device1.example.com"}, cert.DNSNames)
+
+	state := conn.ConnectionState()
+	a.Equal(cert, VerifiedCertificate(&wsConn{tlsState: &state}))
+
+	// no client certificate
+	conn2 := tlsPipe(t, serverConfig, &tls.Config{
+		RootCAs:    pool,
+		ServerName: "server.example.com",
+	})
+	defer conn2.Close()
+	a.Nil(VerifiedCertificate(conn2))
+	a.Nil(VerifiedCertificate(&wsConn{}))
+	a.Nil(VerifiedCertificate(noopConn{}))
+
+	for _, v := range []struct {
+		certIdentity string
+		conn         net.Conn
+		expected     *packets.Connect
+	}{
+		{
+			certIdentity: config.CertIdentityUsername,
+			conn:         conn,
+			expected:     &packets.Connect{ClientID: []byte("cid"), UsernameFlag: true, Username: []byte("device1")},
+		},
+		{
+			certIdentity: config.CertIdentityClientID,
+			conn:         conn,
+			expected:     &packets.Connect{ClientID: []byte("device1"), UsernameFlag: true, Username: []byte("user")},
+		},
+		{
+			certIdentity: "",
+			conn:         conn,
+			expected:     &packets.Connect{ClientID: []byte("cid"), UsernameFlag: true, Username: []byte("user")},
+		},
+		{
+			certIdentity: config.CertIdentityUsername,
+			conn:         conn2,
+			expected:     &packets.Connect{ClientID: []byte("cid"), UsernameFlag: true, Username: []byte("user")},
+		},
+	} {
+		c := &client{rwc: v.conn, certIdentity: v.certIdentity}
+		connect := &packets.Connect{ClientID: []byte("cid"), UsernameFlag: true, Username: []byte("user")}
+		c.applyCertIdentity(connect)
+		a.Equal(v.expected, connect)
+	}
+}
+
+// writeTestCert writes the certificate and private key into dir.
+func writeTestCert(t *testing.T, dir string, cert tls.Certificate) (certFile, keyFile string) {
+	keyDer, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = path.Join(dir, "cert.pem")
+	keyFile = path.Join(dir, "key.pem")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestTLSListener_Reload(t *testing.T) {
+	a := assert.New(t)
+	dir, err := ioutil.TempDir("", "gmqtt_tls")
+	a.Nil(err)
+	defer os.RemoveAll(dir)
+
+	ca := newTestCert(t, "ca", nil)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+	certFile, keyFile := writeTestCert(t, dir, newTestCert(t, "server1", &ca))
+	opts := &config.TLSOptions{
+		CertFile: certFile,
+		KeyFile:  keyFile,
+	}
+	ln, _, err := NewListener(&config.ListenerConfig{
+		Address:    "127.0.0.1:0",
+		TLSOptions: opts,
+	})
+	a.Nil(err)
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				c.(*tls.Conn).Handshake()
+				c.Close()
+			}()
+		}
+	}()
+	peerCN := func(serverName string) string {
+		c, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{
+			RootCAs:    pool,
+			ServerName: serverName,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		return c.ConnectionState().PeerCertificates[0].Subject.CommonName
+	}
+	a.Equal("server1", peerCN("server1.example.com"))
+
+	opts.CertIdentity = config.CertIdentityUsername
+	writeTestCert(t, dir, newTestCert(t, "server2", &ca))
+	tl := ln.(*TLSListener)
+	a.Nil(tl.Reload(opts))
+	a.Equal(config.CertIdentityUsername, tl.CertIdentity())
+	a.Equal("server2", peerCN("server2.example.com"))
+
+	// keep the current config if fail to reload.
+	a.NotNil(tl.Reload(&config.TLSOptions{
+		CertFile: path.Join(dir, "not_exist"),
+		KeyFile:  keyFile,
+	}))
+	a.Equal(config.CertIdentityUsername, tl.CertIdentity())
+	a.Equal("server2", peerCN("server2.example.com"))
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+
+	"github.com/DrmagicE/gmqtt/config"
+)
+
+var (
+	// ErrNotUnixSocket is returned by PeerCredentialsFromConn if the connection is not a unix domain socket connection.
+	ErrNotUnixSocket = errors.New("not a unix domain socket connection")
+	// ErrPeerCredentialsNotSupported is returned by PeerCredentialsFromConn if the platform does not support
+	// reading the peer credentials.
+	ErrPeerCredentialsNotSupported = errors.New("peer credentials are not supported on this platform")
+)
+
+// PeerCredentials is the credentials of the peer process of a unix domain socket connection.
+type PeerCredentials struct {
+	PID int32
+	UID uint32
+	GID uint32
+}
+
+// PeerCredentialsFromConn returns the peer credentials (SO_PEERCRED on Linux, LOCAL_PEERCRED on macOS) of the
+// unix domain socket connection, the conn can be the one passed to OnAccept or returned by Client.Connection().
+// The credentials can be used to trust the local services in OnBasicAuth without passwords.
+func PeerCredentialsFromConn(conn net.Conn) (*PeerCredentials, error) {
+	switch c := conn.(type) {
+	case *net.UnixConn:
+		return peerCredentials(c)
+	case *wsConn:
+		return PeerCredentialsFromConn(c.Conn)
+	}
+	return nil, ErrNotUnixSocket
+}
+
+// NewUnixListener listens on the unix domain socket address, e.g. unix:///run/gmqtt.sock,
+// and applies the permission and ownership of the socket file. opts can be nil.
+// The stale socket file left by the previous process is removed.
+// The returned listener can be passed to WithTCPListener.
+func NewUnixListener(address string, opts *config.UnixSocketOptions) (*net.UnixListener, error) {
+	path, ok := config.UnixSocketPath(address)
+	if !ok {
+		return nil, fmt.Errorf("invalid unix domain socket address: %s", address)
+	}
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if c, err := net.Dial("unix", path); err == nil {
+			c.Close()
+			return nil, fmt.Errorf("unix domain socket %s is in use", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	if opts != nil {
+		if err = chmodSocket(path, opts); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+	return ln, nil
+}
+
+func chmodSocket(path string, opts *config.UnixSocketOptions) error {
+	mode, err := opts.FileMode()
+	if err != nil {
+		return err
+	}
+	if mode != 0 {
+		if err = os.Chmod(path, mode); err != nil {
+			return err
+		}
+	}
+	if opts.User == "" && opts.Group == "" {
+		return nil
+	}
+	uid, gid := -1, -1
+	if opts.User != "" {
+		if uid, err = lookupID(opts.User, func(name string) (string, error) {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return "", err
+			}
+			return u.Uid, nil
+		}); err != nil {
+			return err
+		}
+	}
+	if opts.Group != "" {
+		if gid, err = lookupID(opts.Group, func(name string) (string, error) {
+			g, err := user.LookupGroup(name)
+			if err != nil {
+				return "", err
+			}
+			return g.Gid, nil
+		}); err != nil {
+			return err
+		}
+	}
+	return os.Chown(path, uid, gid)
+}
+
+// lookupID returns the numeric id of the user or group, which can be a name or an id.
+func lookupID(nameOrID string, lookup func(name string) (string, error)) (int, error) {
+	if id, err := strconv.Atoi(nameOrID); err == nil {
+		return id, nil
+	}
+	id, err := lookup(nameOrID)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(id)
+}
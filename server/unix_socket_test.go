@@ -0,0 +1,96 @@
+// +build !windows
+
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"runtime"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt/config"
+)
+
+func TestNewUnixListener(t *testing.T) {
+	a := assert.New(t)
+	dir, err := ioutil.TempDir("", "gmqtt_unix")
+	a.Nil(err)
+	defer os.RemoveAll(dir)
+	sock := path.Join(dir, "gmqtt.sock")
+	address := config.UnixAddressPrefix + sock
+
+	ln, err := NewUnixListener(address, &config.UnixSocketOptions{
+		Mode:  "0600",
+		User:  strconv.Itoa(os.Getuid()),
+		Group: strconv.Itoa(os.Getgid()),
+	})
+	a.Nil(err)
+	fi, err := os.Stat(sock)
+	a.Nil(err)
+	a.Equal(os.FileMode(0600), fi.Mode().Perm())
+	a.Equal(uint32(os.Getgid()), fi.Sys().(*syscall.Stat_t).Gid)
+
+	// the socket is in use.
+	_, err = NewUnixListener(address, nil)
+	a.NotNil(err)
+
+	// the stale socket file is removed.
+	ln.SetUnlinkOnClose(false)
+	ln.Close()
+	_, err = os.Stat(sock)
+	a.Nil(err)
+	ln, err = NewUnixListener(address, nil)
+	a.Nil(err)
+	ln.Close()
+	_, err = os.Stat(sock)
+	a.True(os.IsNotExist(err))
+
+	_, err = NewUnixListener("127.0.0.1:1883", nil)
+	a.NotNil(err)
+}
+
+func TestServer_serveTCP_unixSocket(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("peer credentials are not supported")
+	}
+	a := assert.New(t)
+	dir, err := ioutil.TempDir("", "gmqtt_unix")
+	a.Nil(err)
+	defer os.RemoveAll(dir)
+	ln, _, err := NewListener(&config.ListenerConfig{
+		Address: config.UnixAddressPrefix + path.Join(dir, "gmqtt.sock"),
+	})
+	a.Nil(err)
+	srv := New()
+	creds := make(chan *PeerCredentials, 1)
+	srv.hooks.OnAccept = func(ctx context.Context, conn net.Conn) bool {
+		cred, err := PeerCredentialsFromConn(conn)
+		a.Nil(err)
+		creds <- cred
+		return false
+	}
+	go srv.serveTCP(ln)
+	defer ln.Close()
+
+	c, err := net.Dial("unix", path.Join(dir, "gmqtt.sock"))
+	a.Nil(err)
+	defer c.Close()
+	select {
+	case cred := <-creds:
+		a.Equal(int32(os.Getpid()), cred.PID)
+		a.Equal(uint32(os.Getuid()), cred.UID)
+	case <-time.After(time.Second):
+		t.Fatal("connection not accepted")
+	}
+
+	_, err = PeerCredentialsFromConn(noopConn{})
+	a.Equal(ErrNotUnixSocket, err)
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/DrmagicE/gmqtt/config"
+)
+
+// newUpgrader creates the websocket upgrader for the given websocket options.
+func newUpgrader(opts *config.WebsocketOptions) *websocket.Upgrader {
+	u := &websocket.Upgrader{
+		ReadBufferSize:    opts.ReadBufferSize,
+		WriteBufferSize:   opts.WriteBufferSize,
+		EnableCompression: opts.Compression,
+		Subprotocols:      opts.Subprotocols,
+		CheckOrigin:       checkOrigin(opts.AllowedOrigins),
+	}
+	if u.ReadBufferSize == 0 {
+		u.ReadBufferSize = readBufferSize
+	}
+	if u.WriteBufferSize == 0 {
+		u.WriteBufferSize = writeBufferSize
+	}
+	return u
+}
+
+// checkOrigin returns the CheckOrigin function of the upgrader which only allows the given origins.
+// The requests without the Origin header are not from browsers, so they are always allowed.
+func checkOrigin(allowed []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if len(allowed) == 0 || origin == "" {
+			return true
+		}
+		for _, v := range allowed {
+			if matchOrigin(v, origin) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// matchOrigin reports whether the origin matches the pattern, "*" in the pattern matches any characters except "/".
+func matchOrigin(pattern, origin string) bool {
+	if pattern == "*" {
+		return true
+	}
+	ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(origin))
+	return ok
+}
+
+// UpgradeRequest returns the HTTP upgrade request of the websocket connection returned by Client.Connection().
+// It returns nil if the connection is not a websocket connection.
+// The headers and cookies of the request can be used to authenticate the browser sessions in OnBasicAuth.
+func UpgradeRequest(conn net.Conn) *http.Request {
+	if c, ok := conn.(*wsConn); ok {
+		return c.request
+	}
+	return nil
+}
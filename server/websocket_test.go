@@ -0,0 +1,181 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/persistence/subscription/mem"
+	"github.com/DrmagicE/gmqtt/pkg/codes"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+)
+
+func TestMatchOrigin(t *testing.T) {
+	a := assert.New(t)
+	var tt = []struct {
+		pattern string
+		origin  string
+		match   bool
+	}{
+		{pattern: "https://dashboard.example.com", origin: "https://dashboard.example.com", match: true},
+		{pattern: "https://dashboard.example.com", origin: "HTTPS://Dashboard.Example.com", match: true},
+		{pattern: "https://dashboard.example.com", origin: "http://dashboard.example.com"},
+		{pattern: "https://*.example.com", origin: "https://a.example.com", match: true},
+		{pattern: "https://*.example.com", origin: "https://example.com"},
+		{pattern: "https://*.example.com", origin: "https://a.example.com.evil.com"},
+		{pattern: "http://localhost:*", origin: "http://localhost:8080", match: true},
+		{pattern: "*", origin: "https://a.example.com", match: true},
+	}
+	for _, v := range tt {
+		a.Equal(v.match, matchOrigin(v.pattern, v.origin), v.pattern+" "+v.origin)
+	}
+
+	check := checkOrigin([]string{"https://*.example.com"})
+	r := &http.Request{Header: http.Header{}}
+	// not from browsers
+	a.True(check(r))
+	r.Header.Set("Origin", "https://evil.com")
+	a.False(check(r))
+	a.True(checkOrigin(nil)(r))
+}
+
+func TestServer_wsHandler(t *testing.T) {
+	a := assert.New(t)
+	_, ws, err := NewListener(&config.ListenerConfig{
+		Address: "127.0.0.1:0",
+		Websocket: &config.WebsocketOptions{
+			Path:           "/mqtt",
+			AllowedOrigins: []string{"https://*.example.com"},
+			Compression:    true,
+			MaxMessageSize: 1024,
+			Subprotocols:   config.DefaultWebsocketSubprotocols,
+		},
+	})
+	a.Nil(err)
+	// the listener is served by httptest.
+	ws.ln.Close()
+
+	srv := New()
+	srv.statsManager = newStatsManager(mem.NewStore())
+	cookie := make(chan string, 1)
+	srv.hooks.OnBasicAuth = func(ctx context.Context, client Client, req *ConnectRequest) error {
+		c, err := UpgradeRequest(client.Connection()).Cookie("session")
+		a.Nil(err)
+		cookie <- c.Value
+		return &codes.Error{Code: codes.NotAuthorized}
+	}
+	hs := httptest.NewServer(srv.wsHandler(ws))
+	defer hs.Close()
+	url := "ws" + strings.TrimPrefix(hs.URL, "http")
+
+	dialer := &websocket.Dialer{
+		Subprotocols:      []string{"mqttv3.1"},
+		EnableCompression: true,
+	}
+	_, resp, err := dialer.Dial(url, http.Header{"Origin": []string{"https://evil.com"}})
+	a.Equal(websocket.ErrBadHandshake, err)
+	a.Equal(http.StatusForbidden, resp.StatusCode)
+
+	c, resp, err := dialer.Dial(url, http.Header{
+		"Origin": []string{"https://dashboard.example.com"},
+		"Cookie": []string{"session=abc"},
+	})
+	a.Nil(err)
+	defer c.Close()
+	a.Equal("mqttv3.1", c.Subprotocol())
+	a.Contains(resp.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate")
+
+	buf := &bytes.Buffer{}
+	connect := &packets.Connect{
+		Version:       packets.Version5,
+		ProtocolName:  []byte("MQTT"),
+		ProtocolLevel: packets.Version5,
+		ClientID:      []byte("cid"),
+		CleanStart:    true,
+		KeepAlive:     60,
+		Properties:    &packets.Properties{},
+	}
+	a.Nil(connect.Pack(buf))
+	a.Nil(c.WriteMessage(websocket.BinaryMessage, buf.Bytes()))
+	select {
+	case v := <-cookie:
+		a.Equal("abc", v)
+	case <-time.After(time.Second):
+		t.Fatal("OnBasicAuth not called")
+	}
+	_, b, err := c.ReadMessage()
+	a.Nil(err)
+	p, err := packets.NewReader(bytes.NewReader(b)).ReadPacket()
+	a.Nil(err)
+	a.Equal(codes.NotAuthorized, p.(*packets.Connack).Code)
+
+	// the connection is closed if the message exceeds the max_message_size.
+	c2, _, err := dialer.Dial(url, nil)
+	a.Nil(err)
+	defer c2.Close()
+	a.Nil(c2.WriteMessage(websocket.BinaryMessage, make([]byte, 2048)))
+	c2.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err = c2.ReadMessage()
+	a.NotNil(err)
+	a.False(strings.Contains(err.Error(), "timeout"), err)
+}
+
+func TestServer_wsHandler_forwardedFor(t *testing.T) {
+	a := assert.New(t)
+	_, ws, err := NewListener(&config.ListenerConfig{
+		Address: "127.0.0.1:0",
+		Websocket: &config.WebsocketOptions{
+			Path:         "/mqtt",
+			Subprotocols: config.DefaultWebsocketSubprotocols,
+		},
+		ProxyProtocol: &config.ProxyProtocolOptions{
+			TrustedProxies: []string{"10.0.0.0/8"},
+			HeaderTimeout:  config.DefaultProxyHeaderTimeout,
+		},
+	})
+	a.Nil(err)
+	ws.ln.Close()
+
+	srv := New()
+	srv.statsManager = newStatsManager(mem.NewStore())
+	remoteAddr := make(chan string, 1)
+	srv.hooks.OnBasicAuth = func(ctx context.Context, client Client, req *ConnectRequest) error {
+		remoteAddr <- client.Connection().RemoteAddr().String()
+		return &codes.Error{Code: codes.NotAuthorized}
+	}
+	hs := httptest.NewServer(srv.wsHandler(ws))
+	defer hs.Close()
+	url := "ws" + strings.TrimPrefix(hs.URL, "http")
+
+	dialer := &websocket.Dialer{Subprotocols: []string{"mqtt"}}
+	// the spoofed X-Forwarded-For header sent by an untrusted peer is ignored.
+	c, _, err := dialer.Dial(url, http.Header{"X-Forwarded-For": []string{"1.2.3.4"}})
+	a.Nil(err)
+	defer c.Close()
+	buf := &bytes.Buffer{}
+	connect := &packets.Connect{
+		Version:       packets.Version5,
+		ProtocolName:  []byte("MQTT"),
+		ProtocolLevel: packets.Version5,
+		ClientID:      []byte("cid"),
+		CleanStart:    true,
+		KeepAlive:     60,
+		Properties:    &packets.Properties{},
+	}
+	a.Nil(connect.Pack(buf))
+	a.Nil(c.WriteMessage(websocket.BinaryMessage, buf.Bytes()))
+	select {
+	case v := <-remoteAddr:
+		a.Equal(c.LocalAddr().String(), v)
+	case <-time.After(time.Second):
+		t.Fatal("OnBasicAuth not called")
+	}
+}
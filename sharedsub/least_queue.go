@@ -0,0 +1,34 @@
+package sharedsub
+
+import (
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+var _ server.SharedSubBalancer = (*LeastQueue)(nil)
+
+// NewLeastQueue is the constructor of LeastQueue.
+func NewLeastQueue(config config.Config, shareName string) server.SharedSubBalancer {
+	return &LeastQueue{}
+}
+
+// LeastQueue selects the member with the shortest message queue.
+// Ties are broken in round-robin order.
+type LeastQueue struct {
+	next uint64
+}
+
+func (l *LeastQueue) Select(msg *gmqtt.Message, members []server.SharedSubMember) int {
+	n := len(members)
+	start := int(l.next % uint64(n))
+	l.next++
+	selected := start
+	for i := 1; i < n; i++ {
+		k := (start + i) % n
+		if members[k].QueueLen < members[selected].QueueLen {
+			selected = k
+		}
+	}
+	return selected
+}
@@ -0,0 +1,34 @@
+package sharedsub
+
+import (
+	"math/rand"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+var _ server.SharedSubBalancer = (*PreferConnected)(nil)
+
+// NewPreferConnected is the constructor of PreferConnected.
+func NewPreferConnected(config config.Config, shareName string) server.SharedSubBalancer {
+	return &PreferConnected{}
+}
+
+// PreferConnected selects a connected member randomly.
+// If there is no connected member, it selects one of the offline members randomly,
+// the message will be delivered when the member reconnects.
+type PreferConnected struct{}
+
+func (p *PreferConnected) Select(msg *gmqtt.Message, members []server.SharedSubMember) int {
+	var connected []int
+	for k, v := range members {
+		if v.Connected {
+			connected = append(connected, k)
+		}
+	}
+	if len(connected) == 0 {
+		return rand.Intn(len(members))
+	}
+	return connected[rand.Intn(len(connected))]
+}
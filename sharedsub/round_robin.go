@@ -0,0 +1,25 @@
+package sharedsub
+
+import (
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+var _ server.SharedSubBalancer = (*RoundRobin)(nil)
+
+// NewRoundRobin is the constructor of RoundRobin.
+func NewRoundRobin(config config.Config, shareName string) server.SharedSubBalancer {
+	return &RoundRobin{}
+}
+
+// RoundRobin selects members in turn.
+type RoundRobin struct {
+	next uint64
+}
+
+func (r *RoundRobin) Select(msg *gmqtt.Message, members []server.SharedSubMember) int {
+	i := int(r.next % uint64(len(members)))
+	r.next++
+	return i
+}
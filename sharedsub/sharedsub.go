@@ -0,0 +1,17 @@
+// Package sharedsub provides the optional load balancing strategies of shared subscriptions.
+// The default random strategy is built in the server, import this package to register the others:
+//
+//	import _ "github.com/DrmagicE/gmqtt/sharedsub"
+package sharedsub
+
+import (
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func init() {
+	server.RegisterSharedSubBalancerFactory(config.SharedSubStrategyRoundRobin, NewRoundRobin)
+	server.RegisterSharedSubBalancerFactory(config.SharedSubStrategySticky, NewSticky)
+	server.RegisterSharedSubBalancerFactory(config.SharedSubStrategyLeastQueue, NewLeastQueue)
+	server.RegisterSharedSubBalancerFactory(config.SharedSubStrategyPreferConnected, NewPreferConnected)
+}
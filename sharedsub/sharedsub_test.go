@@ -0,0 +1,116 @@
+package sharedsub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/pkg/packets"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+func newMembers(clientIDs ...string) []server.SharedSubMember {
+	m := make([]server.SharedSubMember, len(clientIDs))
+	for k, v := range clientIDs {
+		m[k] = server.SharedSubMember{
+			ClientID:     v,
+			Subscription: &gmqtt.Subscription{ShareName: "group", TopicFilter: "a/b"},
+			Connected:    true,
+		}
+	}
+	return m
+}
+
+func TestRoundRobin(t *testing.T) {
+	a := assert.New(t)
+	b := NewRoundRobin(config.DefaultConfig(), "group")
+	members := newMembers("a", "b", "c")
+	msg := &gmqtt.Message{Topic: "a/b"}
+	for i := 0; i < 6; i++ {
+		a.Equal(i%3, b.Select(msg, members))
+	}
+}
+
+func TestSticky(t *testing.T) {
+	a := assert.New(t)
+	b := NewSticky(config.DefaultConfig(), "group")
+	members := newMembers("a", "b", "c", "d")
+	msg := &gmqtt.Message{Topic: "a/b"}
+	i := b.Select(msg, members)
+	for j := 0; j < 10; j++ {
+		a.Equal(i, b.Select(msg, members))
+	}
+
+	// removing other members does not change the selection.
+	selected := members[i].ClientID
+	var rest []server.SharedSubMember
+	for k, v := range members {
+		if k == i || len(rest) == 0 {
+			rest = append(rest, v)
+		}
+	}
+	a.Equal(selected, rest[b.Select(msg, rest)].ClientID)
+}
+
+func TestSticky_UserProperty(t *testing.T) {
+	a := assert.New(t)
+	cfg := config.DefaultConfig()
+	cfg.MQTT.SharedSubscription.StickyUserProperty = "device"
+	b := NewSticky(cfg, "group")
+	members := newMembers("a", "b", "c", "d", "e", "f")
+
+	msg := func(topic string) *gmqtt.Message {
+		return &gmqtt.Message{
+			Topic: topic,
+			UserProperties: []packets.UserProperty{
+				{K: []byte("device"), V: []byte("sensor-1")},
+			},
+		}
+	}
+	i := b.Select(msg("a"), members)
+	for _, topic := range []string{"b", "c", "d", "e"} {
+		a.Equal(i, b.Select(msg(topic), members))
+	}
+}
+
+func TestLeastQueue(t *testing.T) {
+	a := assert.New(t)
+	b := NewLeastQueue(config.DefaultConfig(), "group")
+	members := newMembers("a", "b", "c")
+	members[0].QueueLen = 10
+	members[1].QueueLen = 1
+	members[2].QueueLen = 5
+	msg := &gmqtt.Message{Topic: "a/b"}
+	for i := 0; i < 3; i++ {
+		a.Equal(1, b.Select(msg, members))
+	}
+
+	// ties are broken in turn
+	members[2].QueueLen = 1
+	rs := make(map[int]int)
+	for i := 0; i < 3; i++ {
+		rs[b.Select(msg, members)]++
+	}
+	a.Zero(rs[0])
+	a.NotZero(rs[1])
+	a.NotZero(rs[2])
+}
+
+func TestPreferConnected(t *testing.T) {
+	a := assert.New(t)
+	b := NewPreferConnected(config.DefaultConfig(), "group")
+	members := newMembers("a", "b", "c")
+	members[0].Connected = false
+	members[2].Connected = false
+	msg := &gmqtt.Message{Topic: "a/b"}
+	for i := 0; i < 10; i++ {
+		a.Equal(1, b.Select(msg, members))
+	}
+
+	members[1].Connected = false
+	for i := 0; i < 10; i++ {
+		a.True(b.Select(msg, members) < 3)
+	}
+}
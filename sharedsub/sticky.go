@@ -0,0 +1,53 @@
+package sharedsub
+
+import (
+	"bytes"
+	"hash/fnv"
+
+	"github.com/DrmagicE/gmqtt"
+	"github.com/DrmagicE/gmqtt/config"
+	"github.com/DrmagicE/gmqtt/server"
+)
+
+var _ server.SharedSubBalancer = (*Sticky)(nil)
+
+// NewSticky is the constructor of Sticky.
+func NewSticky(config config.Config, shareName string) server.SharedSubBalancer {
+	return &Sticky{
+		userProperty: []byte(config.MQTT.SharedSubscription.StickyUserProperty),
+	}
+}
+
+// Sticky sends messages with the same key to the same member as long as the member is in the group.
+// The key is the value of the configured user property, or the topic name if the property is absent.
+// It uses rendezvous hashing, so only the keys belong to a leaving member will be remapped.
+type Sticky struct {
+	userProperty []byte
+}
+
+func (s *Sticky) key(msg *gmqtt.Message) []byte {
+	if len(s.userProperty) != 0 {
+		for _, v := range msg.UserProperties {
+			if bytes.Equal(v.K, s.userProperty) {
+				return v.V
+			}
+		}
+	}
+	return []byte(msg.Topic)
+}
+
+func (s *Sticky) Select(msg *gmqtt.Message, members []server.SharedSubMember) int {
+	key := s.key(msg)
+	var selected int
+	var max uint64
+	for k, v := range members {
+		h := fnv.New64a()
+		_, _ = h.Write(key)
+		_, _ = h.Write([]byte(v.ClientID))
+		if w := h.Sum64(); k == 0 || w > max {
+			max = w
+			selected = k
+		}
+	}
+	return selected
+}